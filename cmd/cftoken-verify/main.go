@@ -0,0 +1,267 @@
+// Command cftoken-verify checks a Cloudflare API token and nothing else. It
+// is the verify-only companion of cftoken for containers that need to
+// health-check the token they were given: it cannot create, roll, or revoke
+// tokens, and it is built from the standard library alone, so it stays
+// small and static.
+//
+// It exits 0 when the token is active and not about to expire, 1 when it is
+// not, and 2 on a usage or network error.
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"cftoken/internal/clock"
+	"cftoken/internal/duration"
+)
+
+// apiBase is the Cloudflare API root, swappable in tests.
+var apiBase = "https://api.cloudflare.com/client/v4"
+
+// version is set at release time.
+var version = "dev"
+
+// errUnhealthy marks a token that verified but must not be relied on.
+var errUnhealthy = errors.New("token is not usable")
+
+func main() {
+	err := run(context.Background(), os.Args[1:], os.Stdin, os.Stdout)
+	switch {
+	case err == nil:
+	case errors.Is(err, errUnhealthy):
+		fmt.Fprintf(os.Stderr, "cftoken-verify: %v\n", err)
+		os.Exit(1)
+	case errors.Is(err, flag.ErrHelp):
+		os.Exit(2)
+	default:
+		fmt.Fprintf(os.Stderr, "cftoken-verify: %v\n", err)
+		os.Exit(2)
+	}
+}
+
+func run(ctx context.Context, args []string, stdin io.Reader, out io.Writer) error {
+	fs := flag.NewFlagSet("cftoken-verify", flag.ContinueOnError)
+	value := fs.String("token-value", "", "Token to verify; use - to read it from stdin (default $CLOUDFLARE_API_TOKEN)")
+	account := fs.String("token-account", "", "Verify an account-owned token of this account ID")
+	inspect := fs.Bool("inspect", false, "Also print the token's policies, if it may read its own configuration")
+	minRemaining := fs.String("min-remaining", "0", "Fail when the token expires within this duration, e.g. 24h or 2d")
+	output := fs.String("output", "text", "Output format: text or json")
+	timeout := fs.Duration("timeout", 10*time.Second, "Time limit for the API requests")
+	showVersion := fs.Bool("version", false, "Print the version and exit")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: cftoken-verify [flags]")
+		fmt.Fprintln(fs.Output(), "Verifies a Cloudflare API token; exits 0 when it is usable, 1 when not, 2 on errors.")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *showVersion {
+		fmt.Fprintf(out, "cftoken-verify %s\n", version)
+		return nil
+	}
+	if fs.NArg() > 0 {
+		return fmt.Errorf("unexpected argument %q", fs.Arg(0))
+	}
+	if *output != "text" && *output != "json" {
+		return fmt.Errorf("unknown -output %q; must be text or json", *output)
+	}
+	margin, err := duration.Parse(*minRemaining)
+	if err != nil {
+		return fmt.Errorf("-min-remaining: %w", err)
+	}
+	token, err := readToken(*value, stdin)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, *timeout)
+	defer cancel()
+	c := apiClient{token: token, account: strings.TrimSpace(*account)}
+	v, err := c.verify(ctx)
+	if err != nil {
+		return err
+	}
+	if *inspect && v.ID != "" {
+		if v.Policies, err = c.policies(ctx, v.ID); err != nil {
+			fmt.Fprintf(os.Stderr, "cftoken-verify: policies not shown: %v\n", err)
+		}
+	}
+
+	if *output == "json" {
+		enc := json.NewEncoder(out)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(v); err != nil {
+			return err
+		}
+	} else {
+		printVerification(out, v, clock.Now())
+	}
+	return v.check(clock.Now(), margin)
+}
+
+// readToken returns the token from -token-value, stdin for "-", or
+// CLOUDFLARE_API_TOKEN.
+func readToken(value string, stdin io.Reader) (string, error) {
+	switch value {
+	case "-":
+		line, err := bufio.NewReader(stdin).ReadString('\n')
+		if err != nil && !errors.Is(err, io.EOF) {
+			return "", fmt.Errorf("read token from stdin: %w", err)
+		}
+		value = line
+	case "":
+		value = os.Getenv("CLOUDFLARE_API_TOKEN")
+	}
+	if value = strings.TrimSpace(value); value == "" {
+		return "", errors.New("no token: pass -token-value or set CLOUDFLARE_API_TOKEN")
+	}
+	return value, nil
+}
+
+// verification is the API's answer about a token, plus its policies with
+// -inspect.
+type verification struct {
+	ID        string   `json:"id"`
+	Status    string   `json:"status"`
+	ExpiresOn string   `json:"expires_on,omitempty"`
+	NotBefore string   `json:"not_before,omitempty"`
+	Policies  []policy `json:"policies,omitempty"`
+}
+
+type policy struct {
+	Effect           string         `json:"effect"`
+	Resources        map[string]any `json:"resources"`
+	PermissionGroups []struct {
+		ID   string `json:"id"`
+		Name string `json:"name"`
+	} `json:"permission_groups"`
+}
+
+// check reports errUnhealthy unless the token is active, already valid,
+// and valid for at least margin more.
+func (v *verification) check(now time.Time, margin time.Duration) error {
+	if v.Status != "active" {
+		return fmt.Errorf("%w: status is %s", errUnhealthy, v.Status)
+	}
+	if t, err := time.Parse(time.RFC3339, v.NotBefore); err == nil && now.Before(t) {
+		return fmt.Errorf("%w: not valid before %s", errUnhealthy, v.NotBefore)
+	}
+	if t, err := time.Parse(time.RFC3339, v.ExpiresOn); err == nil && t.Sub(now) < margin {
+		return fmt.Errorf("%w: expires at %s", errUnhealthy, v.ExpiresOn)
+	}
+	return nil
+}
+
+func printVerification(w io.Writer, v *verification, now time.Time) {
+	fmt.Fprintf(w, "ID: %s\n", v.ID)
+	fmt.Fprintf(w, "Status: %s\n", v.Status)
+	expires := "none"
+	if t, err := time.Parse(time.RFC3339, v.ExpiresOn); err == nil {
+		expires = v.ExpiresOn
+		if left := t.Sub(now); left > 0 {
+			expires += fmt.Sprintf(" (in %s)", left.Truncate(time.Minute))
+		} else {
+			expires += " (expired)"
+		}
+	}
+	fmt.Fprintf(w, "Expires: %s\n", expires)
+	if v.NotBefore != "" {
+		fmt.Fprintf(w, "Not before: %s\n", v.NotBefore)
+	}
+	for i, p := range v.Policies {
+		names := make([]string, len(p.PermissionGroups))
+		for j, pg := range p.PermissionGroups {
+			names[j] = pg.Name
+			if names[j] == "" {
+				names[j] = pg.ID
+			}
+		}
+		resources := make([]string, 0, len(p.Resources))
+		for key := range p.Resources {
+			resources = append(resources, key)
+		}
+		sort.Strings(resources)
+		fmt.Fprintf(w, "Policy %d: %s %s on %s\n", i+1, p.Effect, strings.Join(names, ", "), strings.Join(resources, ", "))
+	}
+}
+
+// apiClient makes the two read-only calls cftoken-verify needs.
+type apiClient struct {
+	token, account string
+}
+
+func (c apiClient) tokensPath() string {
+	if c.account != "" {
+		return "/accounts/" + c.account + "/tokens"
+	}
+	return "/user/tokens"
+}
+
+func (c apiClient) verify(ctx context.Context) (*verification, error) {
+	var v verification
+	if err := c.get(ctx, c.tokensPath()+"/verify", &v); err != nil {
+		return nil, fmt.Errorf("verify token: %w", err)
+	}
+	return &v, nil
+}
+
+func (c apiClient) policies(ctx context.Context, id string) ([]policy, error) {
+	var token struct {
+		Policies []policy `json:"policies"`
+	}
+	if err := c.get(ctx, c.tokensPath()+"/"+id, &token); err != nil {
+		return nil, err
+	}
+	return token.Policies, nil
+}
+
+// get fetches path and decodes the result of the API envelope into dst.
+// A rejected token is reported as errUnhealthy.
+func (c apiClient) get(ctx context.Context, path string, dst any) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, apiBase+path, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+c.token)
+	req.Header.Set("User-Agent", "cftoken-verify")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	var envelope struct {
+		Success bool            `json:"success"`
+		Result  json.RawMessage `json:"result"`
+		Errors  []struct {
+			Message string `json:"message"`
+		} `json:"errors"`
+	}
+	if err := json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(&envelope); err != nil {
+		return fmt.Errorf("%s: %w", resp.Status, err)
+	}
+	if !envelope.Success || resp.StatusCode != http.StatusOK {
+		msgs := make([]string, len(envelope.Errors))
+		for i, e := range envelope.Errors {
+			msgs[i] = e.Message
+		}
+		err := fmt.Errorf("%s: %s", resp.Status, strings.Join(msgs, "; "))
+		if path == c.tokensPath()+"/verify" && (resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden) {
+			err = fmt.Errorf("%w: %w", errUnhealthy, err)
+		}
+		return err
+	}
+	return json.Unmarshal(envelope.Result, dst)
+}
@@ -0,0 +1,188 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newTestAPI(t *testing.T, handler http.HandlerFunc) {
+	t.Helper()
+	srv := httptest.NewServer(handler)
+	t.Cleanup(srv.Close)
+	old := apiBase
+	apiBase = srv.URL
+	t.Cleanup(func() { apiBase = old })
+}
+
+func writeResult(t *testing.T, w http.ResponseWriter, status int, result any) {
+	t.Helper()
+	w.WriteHeader(status)
+	body := map[string]any{"success": status == http.StatusOK, "result": result, "errors": []any{}}
+	if status != http.StatusOK {
+		body["errors"] = []any{map[string]any{"code": 1000, "message": "Invalid API Token"}}
+	}
+	if err := json.NewEncoder(w).Encode(body); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestRun(t *testing.T) {
+	soon := time.Now().Add(time.Hour).UTC().Format(time.RFC3339)
+	later := time.Now().Add(72 * time.Hour).UTC().Format(time.RFC3339)
+	tests := []struct {
+		name      string
+		args      []string
+		status    int
+		result    map[string]any
+		wantPath  string
+		wantOut   string
+		unhealthy bool
+	}{
+		{
+			name:     "active",
+			args:     []string{"-token-value", "secret"},
+			status:   http.StatusOK,
+			result:   map[string]any{"id": "tok-1", "status": "active"},
+			wantPath: "/user/tokens/verify",
+			wantOut:  "ID: tok-1\nStatus: active\nExpires: none\n",
+		},
+		{
+			name:     "account token",
+			args:     []string{"-token-value", "secret", "-token-account", "acc-1"},
+			status:   http.StatusOK,
+			result:   map[string]any{"id": "tok-1", "status": "active"},
+			wantPath: "/accounts/acc-1/tokens/verify",
+			wantOut:  "Status: active",
+		},
+		{
+			name:      "disabled",
+			args:      []string{"-token-value", "secret"},
+			status:    http.StatusOK,
+			result:    map[string]any{"id": "tok-1", "status": "disabled"},
+			wantPath:  "/user/tokens/verify",
+			wantOut:   "Status: disabled",
+			unhealthy: true,
+		},
+		{
+			name:      "expires within margin",
+			args:      []string{"-token-value", "secret", "-min-remaining", "1d"},
+			status:    http.StatusOK,
+			result:    map[string]any{"id": "tok-1", "status": "active", "expires_on": soon},
+			wantPath:  "/user/tokens/verify",
+			wantOut:   "Expires: " + soon,
+			unhealthy: true,
+		},
+		{
+			name:     "expires after margin",
+			args:     []string{"-token-value", "secret", "-min-remaining", "1d"},
+			status:   http.StatusOK,
+			result:   map[string]any{"id": "tok-1", "status": "active", "expires_on": later},
+			wantPath: "/user/tokens/verify",
+			wantOut:  "Expires: " + later,
+		},
+		{
+			name:      "not yet valid",
+			args:      []string{"-token-value", "secret"},
+			status:    http.StatusOK,
+			result:    map[string]any{"id": "tok-1", "status": "active", "not_before": later},
+			wantPath:  "/user/tokens/verify",
+			wantOut:   "Not before: " + later,
+			unhealthy: true,
+		},
+		{
+			name:      "rejected",
+			args:      []string{"-token-value", "secret"},
+			status:    http.StatusUnauthorized,
+			wantPath:  "/user/tokens/verify",
+			unhealthy: true,
+		},
+		{
+			name:     "json",
+			args:     []string{"-token-value", "secret", "-output", "json"},
+			status:   http.StatusOK,
+			result:   map[string]any{"id": "tok-1", "status": "active"},
+			wantPath: "/user/tokens/verify",
+			wantOut:  `"status": "active"`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			newTestAPI(t, func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Path != tt.wantPath {
+					t.Errorf("path = %s, want %s", r.URL.Path, tt.wantPath)
+				}
+				if got := r.Header.Get("Authorization"); got != "Bearer secret" {
+					t.Errorf("Authorization = %q, want Bearer secret", got)
+				}
+				writeResult(t, w, tt.status, tt.result)
+			})
+
+			var out bytes.Buffer
+			err := run(context.Background(), tt.args, strings.NewReader(""), &out)
+			if got := errors.Is(err, errUnhealthy); got != tt.unhealthy {
+				t.Fatalf("run() error = %v, want unhealthy %v", err, tt.unhealthy)
+			}
+			if !tt.unhealthy && err != nil {
+				t.Fatalf("run() error = %v", err)
+			}
+			if !strings.Contains(out.String(), tt.wantOut) {
+				t.Errorf("output = %q, want it to contain %q", out.String(), tt.wantOut)
+			}
+		})
+	}
+}
+
+func TestRunInspect(t *testing.T) {
+	newTestAPI(t, func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/user/tokens/verify":
+			writeResult(t, w, http.StatusOK, map[string]any{"id": "tok-1", "status": "active"})
+		case "/user/tokens/tok-1":
+			writeResult(t, w, http.StatusOK, map[string]any{"policies": []any{map[string]any{
+				"effect":            "allow",
+				"resources":         map[string]any{"com.cloudflare.api.account.zone.z1": "*"},
+				"permission_groups": []any{map[string]any{"id": "pg-1", "name": "DNS Write"}},
+			}}})
+		default:
+			t.Errorf("unexpected path %s", r.URL.Path)
+		}
+	})
+
+	var out bytes.Buffer
+	if err := run(context.Background(), []string{"-token-value", "-", "-inspect"}, strings.NewReader("secret\n"), &out); err != nil {
+		t.Fatalf("run() error = %v", err)
+	}
+	want := "Policy 1: allow DNS Write on com.cloudflare.api.account.zone.z1\n"
+	if !strings.Contains(out.String(), want) {
+		t.Errorf("output = %q, want it to contain %q", out.String(), want)
+	}
+}
+
+func TestRunUsage(t *testing.T) {
+	t.Setenv("CLOUDFLARE_API_TOKEN", "")
+	tests := []struct {
+		name string
+		args []string
+		want string
+	}{
+		{name: "no token", args: nil, want: "no token"},
+		{name: "bad output", args: []string{"-token-value", "x", "-output", "yaml"}, want: "unknown -output"},
+		{name: "bad margin", args: []string{"-token-value", "x", "-min-remaining", "soon"}, want: "-min-remaining"},
+		{name: "argument", args: []string{"create"}, want: "unexpected argument"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := run(context.Background(), tt.args, strings.NewReader(""), &bytes.Buffer{})
+			if err == nil || !strings.Contains(err.Error(), tt.want) || errors.Is(err, errUnhealthy) {
+				t.Fatalf("run() error = %v, want usage error containing %q", err, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,30 @@
+package main
+
+import "strings"
+
+// accessible is set by -accessible or CFTOKEN_ACCESSIBLE=1. Text output
+// then suits screen readers: no color, no box drawing, and words instead of
+// symbols where a symbol alone carries meaning, such as the +, -, and ~ of
+// diffs.
+var accessible bool
+
+// diffMarkers names the changes the leading symbols of diff lines stand for.
+var diffMarkers = []struct{ symbol, label string }{
+	{"+ ", "added: "},
+	{"- ", "removed: "},
+	{"~ ", "changed: "},
+}
+
+// diffLine returns line, with its leading +, -, or ~ spelled out in
+// accessible mode.
+func diffLine(line string) string {
+	if !accessible {
+		return line
+	}
+	for _, m := range diffMarkers {
+		if rest, ok := strings.CutPrefix(line, m.symbol); ok {
+			return m.label + rest
+		}
+	}
+	return line
+}
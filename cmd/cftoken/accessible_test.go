@@ -0,0 +1,42 @@
+package main
+
+import (
+	"bytes"
+	"testing"
+
+	"cftoken/internal/cloudflare"
+)
+
+// TestAccessibleOutput is not parallel: it sets the package's accessible
+// mode.
+func TestAccessibleOutput(t *testing.T) {
+	accessible = true
+	t.Cleanup(func() { accessible = false })
+
+	policies := []cloudflare.TokenPolicyInspection{
+		{Effect: "allow", Resources: []string{"zone.z1=*"}, PermissionGroups: []cloudflare.PermissionGroupSummary{{ID: "g1", Name: "DNS Write"}}},
+		{Effect: "deny", Resources: []string{"zone.z2=*"}},
+	}
+	var buf bytes.Buffer
+	printPolicies(&buf, policies, viewTree, false)
+	want := `Policies:
+  Resource: zone.z1=*, 1 permission group(s)
+    allow: DNS Write, ID g1
+  Resource: zone.z2=*, 0 permission group(s)
+`
+	if got := buf.String(); got != want {
+		t.Errorf("tree =\n%s\nwant\n%s", got, want)
+	}
+
+	tests := map[string]string{
+		"+ allow: DNS Write":        "added: allow: DNS Write",
+		"- name: ci":                "removed: name: ci",
+		"~ DNS Write (g1) renamed":  "changed: DNS Write (g1) renamed",
+		"  expires_on: 2024-01-01Z": "  expires_on: 2024-01-01Z",
+	}
+	for in, want := range tests {
+		if got := diffLine(in); got != want {
+			t.Errorf("diffLine(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
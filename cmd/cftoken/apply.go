@@ -0,0 +1,430 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/fs"
+	"log"
+	"os"
+	"strings"
+	"time"
+
+	"cftoken/internal/clock"
+	"cftoken/internal/cloudflare"
+	"cftoken/internal/config"
+	"cftoken/internal/duration"
+	"cftoken/internal/guardrail"
+	"cftoken/internal/httpmw"
+	"cftoken/internal/journal"
+	"cftoken/internal/naming"
+	"cftoken/internal/revision"
+	"cftoken/internal/template"
+)
+
+// defaultTTL is the TTL of new tokens, the -ttl flag default.
+// default_ttl in config.json replaces it.
+const defaultTTL = 8 * time.Hour
+
+// tokenCreator is the part of the Cloudflare client apply-template needs.
+type tokenCreator interface {
+	CreateToken(ctx context.Context, name string, policies []cloudflare.Policy, opts ...cloudflare.CreateOption) (*cloudflare.TokenResult, error)
+	DeleteToken(ctx context.Context, tokenID string) error
+}
+
+// plannedToken is a token from a token set, resolved and ready to create.
+type plannedToken struct {
+	name         string
+	ttl          time.Duration
+	expiresOn    *time.Time
+	allowedCIDRs []string
+	deniedCIDRs  []string
+	conditions   []cloudflare.Condition
+	policies     []template.Policy
+	// uniqueName is set when name ends in a random suffix, which lets a
+	// create whose response was lost be retried.
+	uniqueName bool
+}
+
+func runApplyTemplate(ctx context.Context, client *cloudflare.Client, args []string) error {
+	fset := flag.NewFlagSet("apply-template", flag.ContinueOnError)
+	source := addTokenSetFlags(fset)
+	dryRun := fset.Bool("dry-run", false, "Preview every token without creating any")
+	yes := fset.Bool("yes", false, "Do not ask for confirmation before creating risky tokens")
+	if err := fset.Parse(args); err != nil {
+		return err
+	}
+	specs, zoneConfig, err := source.render(ctx, client, "apply-template")
+	if err != nil {
+		return err
+	}
+	defaultCIDRs, err := config.LoadDefaultAllowedCIDRs()
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("load default CIDRs: %w", err)
+	}
+	plans, err := planTokenSet(specs, source.zone, zoneConfig, defaultCIDRs, clock.Now().UTC(), *dryRun)
+	if err != nil {
+		return err
+	}
+	if err := singleTokenOutput(len(plans)); err != nil {
+		return err
+	}
+	if err := guardTokenSet(plans, zoneConfig); err != nil {
+		return err
+	}
+
+	if err := requireValueSink(false); err != nil {
+		return err
+	}
+
+	if *dryRun {
+		for i, p := range plans {
+			if i > 0 {
+				fmt.Fprintln(reportOutput())
+			}
+			if err := printDryRun(reportOutput(), p.name, stringOrDefault(zoneConfig.ZoneID, "none"), source.zone, p.expiresOn, p.allowedCIDRs, p.policies); err != nil {
+				return fmt.Errorf("dry run failed: %w", err)
+			}
+		}
+		return nil
+	}
+
+	hold, err := checkBudget(len(plans))
+	if err != nil {
+		return err
+	}
+	defer hold.release()
+	ctx, cancel, err := approveTokenSet(ctx, plans, stringOrDefault(zoneConfig.ZoneID, "none"), source.zone, *yes)
+	if err != nil {
+		return err
+	}
+	defer cancel()
+	j := beginJournal(ctx, "apply-template")
+	results, err := createTokenSet(ctx, client, j, plans)
+	if err != nil {
+		return journalError(err, j)
+	}
+	recordIssued(hold, results...)
+	labelIssued(results...)
+	for i, result := range results {
+		recordRevision(source.zone, zoneConfig.ZoneID, templateSource(source.templatePath), plans[i].policies, revision.Issuance{
+			TokenID:       result.ID,
+			TokenName:     result.Name,
+			AllowedCIDRs:  plans[i].allowedCIDRs,
+			CorrelationID: httpmw.RequestID(ctx),
+		})
+	}
+	for i, result := range results {
+		if i > 0 && !structuredOutput() {
+			fmt.Fprintln(reportOutput())
+		}
+		printTokenResult(os.Stdout, result, source.zone, plans[i].ttl)
+	}
+	return nil
+}
+
+// tokenSetFlags are the flags apply-template and export-request render a
+// token set from.
+type tokenSetFlags struct {
+	templatePath string
+	varFile      string
+	zone         string
+	vars         varFlag
+}
+
+func addTokenSetFlags(fset *flag.FlagSet) *tokenSetFlags {
+	f := &tokenSetFlags{}
+	fset.StringVar(&f.templatePath, "template", "", "Path to a template that renders a token set, or - to read it from stdin (required)")
+	fset.StringVar(&f.varFile, "var-file", "", "JSON object of template variables, or - to read it from stdin; -var overrides it")
+	fset.StringVar(&f.zone, "zone", "", "Configured zone whose zone_id, variables, CIDRs, and guardrails apply")
+	fset.Var(&f.vars, "var", "Template variable in key=value format (can be specified multiple times)")
+	return f
+}
+
+// render renders the token set with the zone's configuration. client is
+// only used to look up AccountID and resource groups and may be nil.
+func (f *tokenSetFlags) render(ctx context.Context, client *cloudflare.Client, command string) ([]template.TokenSpec, *config.ZoneConfig, error) {
+	if f.templatePath == "" {
+		return nil, nil, fmt.Errorf("%s requires -template", command)
+	}
+	if f.templatePath == "-" && f.varFile == "-" {
+		return nil, nil, withCode(codeInvalidArgument, errors.New("only one of -template and -var-file can read stdin"), nil)
+	}
+	// A template piped on stdin is rendered like an inline one.
+	templateFile, inlineTemplate := f.templatePath, ""
+	if templateFile == "-" {
+		data, err := io.ReadAll(os.Stdin)
+		if err != nil {
+			return nil, nil, fmt.Errorf("read template from stdin: %w", err)
+		}
+		if strings.TrimSpace(string(data)) == "" {
+			return nil, nil, withCode(codeInvalidArgument, errors.New("-template -: stdin is empty"), nil)
+		}
+		templateFile, inlineTemplate = "", string(data)
+	}
+	fileVars, err := readVarFile(f.varFile, os.Stdin)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	zoneConfig := &config.ZoneConfig{}
+	if f.zone != "" {
+		zoneID, loaded, err := config.LoadZoneConfig(f.zone)
+		if err != nil {
+			return nil, nil, fmt.Errorf("resolve zone %q: %w", f.zone, err)
+		}
+		if loaded != nil {
+			zoneConfig = loaded
+		}
+		zoneConfig.ZoneID = zoneID
+		if err := checkFrozen(f.zone, zoneID, loaded); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	if err := resolveAccountID(ctx, client, zoneConfig, templateFile, inlineTemplate); err != nil {
+		return nil, nil, err
+	}
+	specs, err := template.RenderTokenSet(templateFile, inlineTemplate, mergeVariables(templateVariables(zoneConfig, nil), fileVars, f.vars))
+	if err != nil {
+		return nil, nil, fmt.Errorf("render token set: %w", err)
+	}
+	for i := range specs {
+		if specs[i].Policies, err = resolveResourceGroups(ctx, client, zoneConfig, specs[i].Policies); err != nil {
+			return nil, nil, fmt.Errorf("token %q: %w", specs[i].Name, err)
+		}
+	}
+	return specs, zoneConfig, nil
+}
+
+// guardTokenSet rejects the set when guardrails forbid any token in it, and
+// embeds the change ticket in the names when they ask for it.
+func guardTokenSet(plans []plannedToken, zoneConfig *config.ZoneConfig) error {
+	rules, err := loadGuardrails(zoneConfig)
+	if err != nil {
+		return err
+	}
+	for i, p := range plans {
+		if violations := rules.Evaluate(guardrailRequest(p)); len(violations) > 0 {
+			err := fmt.Errorf("guardrails rejected token %q:\n  - %s", p.name, strings.Join(violations, "\n  - "))
+			return withCode(codeGuardrail, err, map[string]any{"token": p.name, "violations": violations})
+		}
+		plans[i].name = ticketedName(p.name, rules)
+	}
+	return nil
+}
+
+// readVarFile decodes a JSON object of template variables from path, or
+// from stdin when path is "-". An empty path yields no variables.
+func readVarFile(path string, stdin io.Reader) (map[string]interface{}, error) {
+	if path == "" {
+		return nil, nil
+	}
+	var (
+		data []byte
+		err  error
+	)
+	if path == "-" {
+		data, err = io.ReadAll(stdin)
+		path = "stdin"
+	} else {
+		data, err = os.ReadFile(path)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("read var file %s: %w", path, err)
+	}
+	var vars map[string]interface{}
+	if err := json.Unmarshal(data, &vars); err != nil {
+		return nil, withCode(codeInvalidArgument, fmt.Errorf("var file %s: want a JSON object of variables: %w", path, err), nil)
+	}
+	return vars, nil
+}
+
+// mergeVariables layers var-file values and then -var flags over base.
+func mergeVariables(base template.Variables, fileVars map[string]interface{}, cliVars map[string]string) template.Variables {
+	for k, v := range fileVars {
+		base[k] = v
+	}
+	for k, v := range cliVars {
+		base[k] = v
+	}
+	return base
+}
+
+// planTokenSet resolves names, expiry, and CIDRs for every token in a set
+// for the configured zone named zone. TTLs fall back to the zone's, then to
+// default_ttl, and CIDRs to the zone's, then to default_allowed_cidrs. A
+// dry run does not take sequence numbers for the names.
+func planTokenSet(specs []template.TokenSpec, zone string, zoneConfig *config.ZoneConfig, defaultCIDRs []string, now time.Time, dryRun bool) ([]plannedToken, error) {
+	fallbackTTL, err := tokenDefaultTTL()
+	if err != nil {
+		return nil, err
+	}
+	if fallbackTTL, err = zoneTTL(zone, zoneConfig, fallbackTTL); err != nil {
+		return nil, err
+	}
+	plans := make([]plannedToken, 0, len(specs))
+	for _, spec := range specs {
+		name, err := generateName(zoneConfig, spec.Name, now, dryRun)
+		if err != nil {
+			return nil, fmt.Errorf("token %q: %w", spec.Name, err)
+		}
+		p := plannedToken{
+			name:       name,
+			ttl:        fallbackTTL,
+			policies:   mergePolicies(spec.Name, spec.Policies),
+			uniqueName: uniqueNames(zoneConfig),
+		}
+		if spec.TTL != "" {
+			ttl, err := duration.Parse(spec.TTL)
+			if err != nil {
+				return nil, fmt.Errorf("token %q: invalid ttl: %w", spec.Name, err)
+			}
+			p.ttl = ttl
+		}
+		if p.ttl > 0 {
+			exp := now.Add(p.ttl)
+			p.expiresOn = &exp
+		}
+
+		cidrs := spec.AllowedCIDRs
+		if len(cidrs) == 0 {
+			cidrs = zoneConfig.AllowedCIDRs
+		}
+		if len(cidrs) == 0 {
+			cidrs = defaultCIDRs
+		}
+		allowed, disabled, err := normalizeCIDRList(cidrs)
+		if err != nil {
+			return nil, fmt.Errorf("token %q: %w", spec.Name, err)
+		}
+		if len(allowed) == 0 && !disabled {
+			return nil, fmt.Errorf("token %q: no allowed CIDRs; set allowed_cidrs in the template or config.json", spec.Name)
+		}
+		p.allowedCIDRs = allowed
+		if p.conditions, err = templateConditions(spec.Conditions); err != nil {
+			return nil, fmt.Errorf("token %q: %w", spec.Name, err)
+		}
+		plans = append(plans, p)
+	}
+	return plans, nil
+}
+
+// mergePolicies reduces policies to a minimal set and warns about
+// permission groups that are both allowed and denied.
+func mergePolicies(tokenName string, policies []template.Policy) []template.Policy {
+	merged, warnings := template.MergePolicies(policies)
+	for _, w := range warnings {
+		log.Printf("warning: token %q: %s", tokenName, w)
+	}
+	return merged
+}
+
+// tokenDefaultTTL returns default_ttl from config.json, or defaultTTL when
+// none is set.
+func tokenDefaultTTL() (time.Duration, error) {
+	ttl, err := config.LoadDefaultTTL()
+	if errors.Is(err, fs.ErrNotExist) {
+		return defaultTTL, nil
+	}
+	return ttl, err
+}
+
+// defaultTokenPrefix names tokens created without -token-prefix: the
+// default_token_prefix_template from config.json, or else the zone name.
+func defaultTokenPrefix(zoneName, zoneID string) (string, error) {
+	tmpl, err := config.LoadTokenPrefixTemplate()
+	if errors.Is(err, fs.ErrNotExist) {
+		return zoneName, nil
+	}
+	if err != nil {
+		return "", err
+	}
+	return naming.Prefix(tmpl, naming.PrefixData{Zone: zoneName, ZoneID: zoneID})
+}
+
+// generateName appends the suffix chosen by the zone's name_suffix to base.
+func generateName(zoneConfig *config.ZoneConfig, base string, now time.Time, dryRun bool) (string, error) {
+	var strategy string
+	if zoneConfig != nil {
+		strategy = zoneConfig.NameSuffix
+	}
+	return naming.Name(strategy, base, now, dryRun)
+}
+
+// uniqueNames reports whether generateName gives the zone's tokens names
+// no other run produces.
+func uniqueNames(zoneConfig *config.ZoneConfig) bool {
+	return zoneConfig != nil && naming.Random(zoneConfig.NameSuffix)
+}
+
+func guardrailRequest(p plannedToken) guardrail.Request {
+	return guardrail.Request{
+		TTL:          p.ttl,
+		AllowedCIDRs: p.allowedCIDRs,
+		Permissions:  policyPermissions(p.policies),
+		Ticket:       changeTicket,
+	}
+}
+
+// createTokenSet creates every planned token in order. If one fails, the
+// tokens created so far are deleted again so the set is all or nothing. Each
+// creation is recorded in j so a crash mid-way can be rolled back later.
+func createTokenSet(ctx context.Context, client tokenCreator, j *journal.Journal, plans []plannedToken) ([]*cloudflare.TokenResult, error) {
+	results := make([]*cloudflare.TokenResult, 0, len(plans))
+	for _, p := range plans {
+		var opts []cloudflare.CreateOption
+		if p.expiresOn != nil {
+			opts = append(opts, cloudflare.WithExpiry(*p.expiresOn))
+		}
+		if len(p.allowedCIDRs) > 0 {
+			opts = append(opts, cloudflare.WithAllowedCIDRs(p.allowedCIDRs...))
+		}
+		if len(p.deniedCIDRs) > 0 {
+			opts = append(opts, cloudflare.WithDeniedCIDRs(p.deniedCIDRs...))
+		}
+		for _, c := range p.conditions {
+			opts = append(opts, cloudflare.WithCondition(c))
+		}
+		if p.uniqueName {
+			opts = append(opts, cloudflare.WithUniqueName())
+		}
+		result, err := client.CreateToken(ctx, p.name, toCloudflarePolicies(p.policies), opts...)
+		if err == nil {
+			results = append(results, result)
+			if err := j.Record(stepCreateToken, map[string]string{"token_id": result.ID, "name": result.Name}); err != nil {
+				log.Printf("warning: %v", err)
+			}
+			continue
+		}
+
+		createErr := fmt.Errorf("create token %q: %w", p.name, err)
+		var rollbackErrs []error
+		for i := len(results) - 1; i >= 0; i-- {
+			if err := client.DeleteToken(ctx, results[i].ID); err != nil {
+				rollbackErrs = append(rollbackErrs, fmt.Errorf("roll back %s (%s): %w", results[i].Name, results[i].ID, err))
+				continue
+			}
+			if err := j.MarkUndone(i); err != nil {
+				log.Printf("warning: %v", err)
+			}
+		}
+		if len(rollbackErrs) > 0 {
+			return nil, errors.Join(append([]error{createErr}, rollbackErrs...)...)
+		}
+		if err := j.Complete(); err != nil {
+			log.Printf("warning: %v", err)
+		}
+		if len(results) > 0 {
+			return nil, fmt.Errorf("%w (rolled back %d token(s))", createErr, len(results))
+		}
+		return nil, createErr
+	}
+	if err := j.Complete(); err != nil {
+		log.Printf("warning: %v", err)
+	}
+	return results, nil
+}
@@ -0,0 +1,157 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"cftoken/internal/cloudflare"
+	"cftoken/internal/config"
+	"cftoken/internal/template"
+)
+
+type fakeCreator struct {
+	failOn  string
+	created []string
+	deleted []string
+}
+
+func (f *fakeCreator) CreateToken(_ context.Context, name string, _ []cloudflare.Policy, _ ...cloudflare.CreateOption) (*cloudflare.TokenResult, error) {
+	if name == f.failOn {
+		return nil, errors.New("boom")
+	}
+	f.created = append(f.created, name)
+	return &cloudflare.TokenResult{ID: "id-" + name, Name: name}, nil
+}
+
+func (f *fakeCreator) DeleteToken(_ context.Context, id string) error {
+	f.deleted = append(f.deleted, id)
+	return nil
+}
+
+func TestCreateTokenSetRollsBack(t *testing.T) {
+	t.Parallel()
+
+	plans := []plannedToken{{name: "a"}, {name: "b"}, {name: "c"}}
+	fake := &fakeCreator{failOn: "c"}
+
+	_, err := createTokenSet(context.Background(), fake, nil, plans)
+	if err == nil || !strings.Contains(err.Error(), "rolled back 2 token(s)") {
+		t.Fatalf("createTokenSet() error = %v, want rollback error", err)
+	}
+	if want := []string{"id-b", "id-a"}; !reflect.DeepEqual(fake.deleted, want) {
+		t.Fatalf("deleted = %v, want %v", fake.deleted, want)
+	}
+
+	fake = &fakeCreator{}
+	results, err := createTokenSet(context.Background(), fake, nil, plans)
+	if err != nil || len(results) != 3 || len(fake.deleted) != 0 {
+		t.Fatalf("createTokenSet() = %d results, err %v, deleted %v", len(results), err, fake.deleted)
+	}
+}
+
+func TestPlanTokenSet(t *testing.T) {
+	t.Parallel()
+
+	now := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	specs := []template.TokenSpec{
+		{Name: "deploy", TTL: "4h", AllowedCIDRs: []string{"10.0.0.1/32"}},
+		{Name: "purge"},
+		{Name: "forever", TTL: "0s", Conditions: map[string]map[string][]string{"request_ip": {"not_in": {"10.0.1.9"}}}},
+	}
+	zone := &config.ZoneConfig{AllowedCIDRs: []string{"10.0.1.0/24"}}
+
+	plans, err := planTokenSet(specs, "prod", zone, nil, now, false)
+	if err != nil {
+		t.Fatalf("planTokenSet() error = %v", err)
+	}
+	if plans[0].name != "deploy-20240102T030405Z" || plans[0].ttl != 4*time.Hour || plans[0].allowedCIDRs[0] != "10.0.0.1/32" {
+		t.Errorf("unexpected first plan: %+v", plans[0])
+	}
+	if plans[1].ttl != defaultTTL || plans[1].allowedCIDRs[0] != "10.0.1.0/24" {
+		t.Errorf("second plan should use default TTL and zone CIDRs: %+v", plans[1])
+	}
+	if plans[2].expiresOn != nil {
+		t.Errorf("ttl 0 should not expire: %+v", plans[2])
+	}
+	if want := []cloudflare.Condition{{Type: "request_ip", Operator: "not_in", Values: []string{"10.0.1.9"}}}; !reflect.DeepEqual(plans[2].conditions, want) {
+		t.Errorf("conditions = %+v, want %+v", plans[2].conditions, want)
+	}
+
+	zone.TTL = "2d"
+	plans, err = planTokenSet(specs, "prod", zone, nil, now, false)
+	if err != nil {
+		t.Fatalf("planTokenSet() with zone ttl error = %v", err)
+	}
+	if plans[0].ttl != 4*time.Hour || plans[1].ttl != 48*time.Hour {
+		t.Errorf("TTLs = %s, %s, want the token's 4h and the zone's 48h", plans[0].ttl, plans[1].ttl)
+	}
+	zone.TTL = "soon"
+	if _, err := planTokenSet(specs, "prod", zone, nil, now, false); err == nil || !strings.Contains(err.Error(), `zone "prod": ttl`) {
+		t.Fatalf("planTokenSet() with invalid zone ttl error = %v", err)
+	}
+	zone.TTL = ""
+
+	if _, err := planTokenSet([]template.TokenSpec{{Name: "x"}}, "prod", &config.ZoneConfig{}, nil, now, false); err == nil {
+		t.Fatalf("planTokenSet() without CIDRs error = nil, want error")
+	}
+	allowed := template.TokenSpec{Name: "x", Conditions: map[string]map[string][]string{"request_ip": {"in": {"10.0.0.0/8"}}}}
+	if _, err := planTokenSet([]template.TokenSpec{allowed}, "prod", zone, nil, now, false); err == nil || !strings.Contains(err.Error(), "use allowed_cidrs") {
+		t.Fatalf("planTokenSet() with request_ip.in condition error = %v, want allowed_cidrs hint", err)
+	}
+}
+
+func TestReadVarFile(t *testing.T) {
+	t.Parallel()
+
+	vars, err := readVarFile("-", strings.NewReader(`{"Team": "edge", "Zones": ["a", "b"]}`))
+	if err != nil {
+		t.Fatalf("readVarFile() error = %v", err)
+	}
+	merged := mergeVariables(template.Variables{"ZoneID": "z1", "Team": "web"}, vars, map[string]string{"Zones": "c"})
+	want := template.Variables{"ZoneID": "z1", "Team": "edge", "Zones": "c"}
+	if !reflect.DeepEqual(merged, want) {
+		t.Fatalf("mergeVariables() = %v, want %v", merged, want)
+	}
+
+	if vars, err := readVarFile("", nil); err != nil || vars != nil {
+		t.Fatalf("readVarFile(\"\") = %v, %v; want nil, nil", vars, err)
+	}
+	if _, err := readVarFile("-", strings.NewReader(`["not", "an", "object"]`)); err == nil || !strings.Contains(err.Error(), "var file stdin") {
+		t.Fatalf("readVarFile() with an array error = %v", err)
+	}
+}
+
+func TestTokenDefaults(t *testing.T) {
+	tests := []struct {
+		name       string
+		config     string
+		wantTTL    time.Duration
+		wantPrefix string
+		wantErr    bool
+	}{
+		{"built in", `{}`, defaultTTL, "prod", false},
+		{"configured", `{"default_ttl": "2d", "default_token_prefix_template": "{{ .Zone }}-ci"}`, 48 * time.Hour, "prod-ci", false},
+		{"zone id", `{"default_token_prefix_template": "z-{{ .ZoneID }}"}`, defaultTTL, "z-abc", false},
+		{"bad ttl", `{"default_ttl": "two days"}`, 0, "prod", true},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			root := t.TempDir()
+			t.Setenv("XDG_CONFIG_HOME", root)
+			writeConfig(t, root, tc.config)
+
+			ttl, err := tokenDefaultTTL()
+			if (err != nil) != tc.wantErr || ttl != tc.wantTTL {
+				t.Errorf("tokenDefaultTTL() = %s, %v, want %s", ttl, err, tc.wantTTL)
+			}
+			prefix, err := defaultTokenPrefix("prod", "abc")
+			if err != nil || prefix != tc.wantPrefix {
+				t.Errorf("defaultTokenPrefix() = %q, %v, want %q", prefix, err, tc.wantPrefix)
+			}
+		})
+	}
+}
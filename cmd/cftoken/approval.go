@@ -0,0 +1,121 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"cftoken/internal/approval"
+	"cftoken/internal/config"
+	"cftoken/internal/duration"
+)
+
+// requestApproval holds a high-risk creation until a second operator
+// approves it through the link configured by approval_link, and returns
+// the approver named by approver_header. Without approval_link it returns
+// at once; with it, a link that cannot be served or gets no decision in
+// time is an error, so the token is never created unapproved. summary is
+// the preview the approver sees.
+func requestApproval(ctx context.Context, out io.Writer, summary string, risks []string) (string, error) {
+	cfg, err := config.LoadApprovalLink()
+	if errors.Is(err, fs.ErrNotExist) {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+	timeout, err := duration.Parse(cfg.Timeout)
+	if err != nil {
+		return "", err
+	}
+	link, err := approval.NewLink(summary + "\nRisks: " + strings.Join(risks, ", ") + "\n")
+	if err != nil {
+		return "", err
+	}
+	link.ApproverHeader = cfg.ApproverHeader
+	ln, err := net.Listen("tcp", cfg.Listen)
+	if err != nil {
+		return "", fmt.Errorf("approval_link: %w", err)
+	}
+	fmt.Fprintf(out, "This token is high-risk (%s). Waiting up to %s for a second operator to approve it at:\n  %s\n",
+		strings.Join(risks, ", "), cfg.Timeout, cfg.BaseURL+link.Path())
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	decision, err := link.Wait(ctx, ln)
+	if err != nil {
+		return "", err
+	}
+	if !decision.Approved {
+		return "", withCode(codeApprovalDenied, fmt.Errorf("token creation denied by %s", decision.Approver), map[string]any{"approver": decision.Approver})
+	}
+	fmt.Fprintf(out, "Approved by %s.\n", decision.Approver)
+	return decision.Approver, nil
+}
+
+// approveTokenSet confirms and approves plans before commands such as
+// apply-template create them, as create does: risky tokens are confirmed on
+// a terminal unless yes (-yes) is set, and high-risk ones wait for
+// requestApproval; zoneID and zone label the preview. Like the prompts of
+// create, neither counts against -timeout: the returned context gives the
+// creation the time ctx had left.
+func approveTokenSet(ctx context.Context, plans []plannedToken, zoneID, zone string, yes bool) (context.Context, context.CancelFunc, error) {
+	started := time.Now()
+	var confirm []string
+	for _, p := range plans {
+		for _, r := range confirmationRisks(p.expiresOn, len(p.allowedCIDRs) == 0, p.policies) {
+			if !slices.Contains(confirm, r) {
+				confirm = append(confirm, r)
+			}
+		}
+	}
+	asked := len(confirm) > 0 && !yes
+	if asked {
+		ok, err := promptRisks(confirm)
+		if err != nil {
+			return nil, nil, err
+		}
+		if !ok {
+			return nil, nil, errDeclined
+		}
+	}
+
+	var risks []string
+	var preview strings.Builder
+	for _, p := range plans {
+		planRisks := issuanceRisks(p.expiresOn, len(p.allowedCIDRs) == 0)
+		if len(planRisks) == 0 {
+			continue
+		}
+		if preview.Len() > 0 {
+			preview.WriteString("\n")
+		}
+		if err := printDryRun(&preview, p.name, zoneID, zone, p.expiresOn, p.allowedCIDRs, p.policies); err != nil {
+			return nil, nil, err
+		}
+		for _, r := range planRisks {
+			if !slices.Contains(risks, r) {
+				risks = append(risks, r)
+			}
+		}
+	}
+	if len(risks) > 0 {
+		asked = true
+		if _, err := requestApproval(context.WithoutCancel(ctx), os.Stderr, preview.String(), risks); err != nil {
+			return nil, nil, err
+		}
+	}
+	deadline, ok := ctx.Deadline()
+	if !asked || !ok {
+		return ctx, func() {}, nil
+	}
+	ctx, cancel := context.WithDeadline(context.WithoutCancel(ctx), deadline.Add(time.Since(started)))
+	return ctx, cancel, nil
+}
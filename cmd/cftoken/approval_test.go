@@ -0,0 +1,113 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"cftoken/internal/cloudflare"
+)
+
+func TestRequestApprovalUnconfigured(t *testing.T) {
+	root := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", root)
+	writeConfig(t, root, `{}`)
+	approver, err := requestApproval(context.Background(), io.Discard, "preview", []string{"token never expires"})
+	if err != nil || approver != "" {
+		t.Fatalf("requestApproval() = %q, %v; want no approval asked", approver, err)
+	}
+}
+
+// TestCreateWaitsForApproval checks that a high-risk create needs the
+// approval link even without -interactive or a terminal, and that no
+// token is created when nobody decides in time.
+func TestCreateWaitsForApproval(t *testing.T) {
+	root := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", root)
+	t.Setenv("XDG_CACHE_HOME", root)
+	writeConfig(t, root, `{
+		"zones": {"prod": "0123456789abcdef0123456789abcdef"},
+		"default_allowed_cidrs": ["192.0.2.0/24"],
+		"approval_link": {"listen": "127.0.0.1:0", "base_url": "https://approve.example.com", "approver_header": "X-Auth-Request-Email", "timeout": "50ms"}
+	}`)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPost {
+			t.Errorf("%s %s before approval", r.Method, r.URL.Path)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]any{"success": true, "errors": []any{}, "messages": []any{}, "result": []any{
+			map[string]any{"id": "pg-dns", "name": "DNS Write", "scopes": []string{"com.cloudflare.api.account.zone"}},
+		}})
+	}))
+	defer srv.Close()
+	defer func(prev []cloudflare.Option) { testClientOptions = prev }(testClientOptions)
+	testClientOptions = []cloudflare.Option{cloudflare.WithBaseURL(srv.URL)}
+	defer func(orig func(context.Context) string) { resolveManagementToken = orig }(resolveManagementToken)
+	resolveManagementToken = func(context.Context) string { return "tok" }
+
+	err := runCLI(t, "create", "-zone", "prod", "-permissions", "DNS Write", "-ttl", "0", "-yes")
+	if err == nil || !strings.Contains(err.Error(), "no decision on the approval link") {
+		t.Fatalf("create error = %v, want it to wait for approval and time out", err)
+	}
+}
+
+func TestApproveTokenSet(t *testing.T) {
+	root := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", root)
+	writeConfig(t, root, `{"approval_link": {"listen": "127.0.0.1:0", "base_url": "https://approve.example.com", "approver_header": "X-Auth-Request-Email", "timeout": "50ms"}}`)
+	expires := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	safe := plannedToken{name: "ci", expiresOn: &expires, allowedCIDRs: []string{"192.0.2.0/24"}}
+	forever := plannedToken{name: "forever", allowedCIDRs: []string{"192.0.2.0/24"}}
+
+	ctx, cancel, err := approveTokenSet(context.Background(), []plannedToken{safe}, "z1", "prod", false)
+	if err != nil {
+		t.Fatalf("approveTokenSet(safe) error = %v, want no approval asked", err)
+	}
+	cancel()
+	if ctx.Err() != nil {
+		t.Fatalf("approveTokenSet(safe) context error = %v", ctx.Err())
+	}
+
+	parent, stop := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer stop()
+	if _, _, err := approveTokenSet(parent, []plannedToken{safe, forever}, "z1", "prod", true); err == nil || !strings.Contains(err.Error(), "no decision on the approval link") {
+		t.Fatalf("approveTokenSet(forever) error = %v, want the approval to time out", err)
+	}
+}
+
+func TestApproveTokenSetConfirmsRisks(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	expires := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	safe := plannedToken{name: "ci", expiresOn: &expires, allowedCIDRs: []string{"192.0.2.0/24"}}
+	open := plannedToken{name: "open", expiresOn: &expires}
+
+	var asked [][]string
+	answer := false
+	defer func(orig func([]string) (bool, error)) { promptRisks = orig }(promptRisks)
+	promptRisks = func(risks []string) (bool, error) {
+		asked = append(asked, risks)
+		return answer, nil
+	}
+
+	if _, _, err := approveTokenSet(context.Background(), []plannedToken{safe}, "z1", "prod", false); err != nil || len(asked) != 0 {
+		t.Fatalf("approveTokenSet(safe) = %v after %d prompts, want no prompt", err, len(asked))
+	}
+	_, _, err := approveTokenSet(context.Background(), []plannedToken{safe, open}, "z1", "prod", false)
+	if !errors.Is(err, errDeclined) || len(asked) != 1 || !slices.Equal(asked[0], []string{"IP restrictions disabled"}) {
+		t.Fatalf("approveTokenSet(open) declined = %v after prompts %q, want errDeclined", err, asked)
+	}
+	if _, _, err := approveTokenSet(context.Background(), []plannedToken{open}, "z1", "prod", true); err != nil || len(asked) != 1 {
+		t.Fatalf("approveTokenSet(open, yes) = %v after %d prompts, want no prompt", err, len(asked))
+	}
+	answer = true
+	if _, _, err := approveTokenSet(context.Background(), []plannedToken{open}, "z1", "prod", false); err != nil || len(asked) != 2 {
+		t.Fatalf("approveTokenSet(open) confirmed = %v after %d prompts", err, len(asked))
+	}
+}
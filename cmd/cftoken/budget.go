@@ -0,0 +1,70 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"log"
+
+	"cftoken/internal/budget"
+	"cftoken/internal/clock"
+	"cftoken/internal/cloudflare"
+	"cftoken/internal/config"
+)
+
+// forceBudget is set by -force-budget. A non-empty reason lets a run exceed
+// the issuance budget; the reason is kept in the ledger next to each token.
+var forceBudget string
+
+// budgetHold is the budget a run reserved for the tokens it is about to
+// create, and the -force-budget reason they are recorded with.
+type budgetHold struct {
+	reservation *budget.Reservation
+	forced      string
+}
+
+// checkBudget refuses to issue n tokens beyond the configured budget unless
+// -force-budget gives a reason, and otherwise holds places for them in the
+// ledger so that concurrent runs count them. Callers pass the hold to
+// recordIssued once the tokens exist and release it in any case.
+func checkBudget(n int) (*budgetHold, error) {
+	limits, err := config.LoadBudget()
+	if errors.Is(err, fs.ErrNotExist) {
+		return &budgetHold{reservation: &budget.Reservation{}}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	reservation, err := budget.Reserve(*limits, n, clock.Now())
+	if err == nil {
+		return &budgetHold{reservation: reservation}, nil
+	}
+	if !errors.Is(err, budget.ErrExceeded) {
+		return nil, err
+	}
+	if forceBudget == "" {
+		return nil, withCode(codeBudgetExceeded, fmt.Errorf("%w; pass -force-budget REASON to override", err), nil)
+	}
+	log.Printf("warning: %v; continuing because of -force-budget: %s", err, forceBudget)
+	return &budgetHold{reservation: &budget.Reservation{}, forced: forceBudget}, nil
+}
+
+// release gives back the places of tokens that were never created.
+func (h *budgetHold) release() {
+	if err := h.reservation.Release(clock.Now()); err != nil {
+		log.Printf("warning: release issuance budget: %v", err)
+	}
+}
+
+// recordIssued adds newly created tokens to the issuance ledger in place of
+// those held for them. Failing to record never fails the issuance.
+func recordIssued(hold *budgetHold, results ...*cloudflare.TokenResult) {
+	now := clock.Now().UTC()
+	entries := make([]budget.Entry, 0, len(results))
+	for _, r := range results {
+		entries = append(entries, budget.Entry{At: now, TokenID: r.ID, TokenName: r.Name, Ticket: changeTicket, Forced: hold.forced})
+	}
+	if err := hold.reservation.Commit(now, entries...); err != nil {
+		log.Printf("warning: record issuance: %v", err)
+	}
+}
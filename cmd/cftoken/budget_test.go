@@ -0,0 +1,52 @@
+package main
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"cftoken/internal/cloudflare"
+)
+
+func TestCheckBudget(t *testing.T) {
+	root := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", root)
+	t.Setenv("XDG_STATE_HOME", filepath.Join(root, "state"))
+	writeConfig(t, root, `{"budget": {"per_run": 5, "per_day": 2}}`)
+
+	hold, err := checkBudget(2)
+	if err != nil {
+		t.Fatalf("checkBudget(2) error = %v", err)
+	}
+	if _, err := checkBudget(1); err == nil {
+		t.Fatal("checkBudget(1) while 2 are held succeeded")
+	}
+	recordIssued(hold, &cloudflare.TokenResult{ID: "t1"}, &cloudflare.TokenResult{ID: "t2"})
+	hold.release()
+
+	_, err = checkBudget(1)
+	var coded *codedError
+	if !errors.As(err, &coded) || coded.code != codeBudgetExceeded {
+		t.Fatalf("checkBudget(1) after 2 issued error = %v, want %s", err, codeBudgetExceeded)
+	}
+
+	forceBudget = "incident 42"
+	t.Cleanup(func() { forceBudget = "" })
+	hold, err = checkBudget(1)
+	if err != nil || hold.forced != "incident 42" {
+		t.Fatalf("checkBudget(1) with -force-budget = %+v, %v", hold, err)
+	}
+	if _, err := checkBudget(6); err != nil {
+		t.Fatalf("checkBudget(6) with -force-budget error = %v", err)
+	}
+}
+
+func TestCheckBudgetUnconfigured(t *testing.T) {
+	root := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", root)
+	t.Setenv("XDG_STATE_HOME", filepath.Join(root, "state"))
+
+	if hold, err := checkBudget(1000); err != nil || hold.forced != "" {
+		t.Fatalf("checkBudget() without config = %+v, %v", hold, err)
+	}
+}
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io/fs"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"cftoken/internal/cache"
+	"cftoken/internal/clock"
+	"cftoken/internal/config"
+	"cftoken/internal/duration"
+)
+
+func runCache(args []string) error {
+	if len(args) == 0 {
+		return errors.New("cache requires a subcommand: status or clear")
+	}
+	fset := flag.NewFlagSet("cache "+args[0], flag.ContinueOnError)
+	if err := fset.Parse(args[1:]); err != nil {
+		return err
+	}
+	store, err := openCache()
+	if err != nil {
+		return err
+	}
+
+	switch sub := args[0]; sub {
+	case "status":
+		return printCacheStatus(store, clock.Now())
+	case "clear":
+		n, err := store.Clear()
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Removed %d cache entries from %s.\n", n, store.Dir())
+		return nil
+	default:
+		return fmt.Errorf("unknown cache subcommand %q; available: status, clear", sub)
+	}
+}
+
+// openCache returns the on-disk cache configured by the cache section of
+// config.json, with defaults when it is absent.
+func openCache() (*cache.Store, error) {
+	dir, err := cache.Dir()
+	if err != nil {
+		return nil, err
+	}
+	cfg, err := config.LoadCacheConfig()
+	if errors.Is(err, fs.ErrNotExist) {
+		return cache.New(dir, 0, 0), nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("load cache settings: %w", err)
+	}
+	var ttl time.Duration
+	if cfg.TTL != "" {
+		if ttl, err = duration.Parse(cfg.TTL); err != nil || ttl <= 0 {
+			return nil, fmt.Errorf("cache ttl %q: must be a positive duration", cfg.TTL)
+		}
+	}
+	if cfg.MaxBytes < 0 {
+		return nil, fmt.Errorf("cache max_bytes %d: must not be negative", cfg.MaxBytes)
+	}
+	return cache.New(dir, ttl, cfg.MaxBytes), nil
+}
+
+func printCacheStatus(store *cache.Store, now time.Time) error {
+	entries, err := store.Entries()
+	if err != nil {
+		return err
+	}
+	var total int64
+	for _, e := range entries {
+		total += e.Size
+	}
+	fmt.Printf("Directory: %s\n", store.Dir())
+	fmt.Printf("TTL:       %s\n", store.TTL())
+	fmt.Printf("Size:      %d of %d bytes in %d entries\n", total, store.MaxBytes(), len(entries))
+	if len(entries) == 0 {
+		return nil
+	}
+	fmt.Println()
+	tw := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "KEY\tBYTES\tAGE\tSTATE")
+	for _, e := range entries {
+		state := "fresh"
+		if e.Expired {
+			state = "expired"
+		}
+		fmt.Fprintf(tw, "%s\t%d\t%s\t%s\n", e.Key, e.Size, now.Sub(e.ModTime).Truncate(time.Second), state)
+	}
+	return tw.Flush()
+}
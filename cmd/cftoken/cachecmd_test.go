@@ -0,0 +1,52 @@
+package main
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"cftoken/internal/cache"
+)
+
+func TestOpenCache(t *testing.T) {
+	tests := []struct {
+		name     string
+		config   string
+		wantTTL  time.Duration
+		wantMax  int64
+		wantFail bool
+	}{
+		{name: "no config", wantTTL: cache.DefaultTTL, wantMax: cache.DefaultMaxBytes},
+		{name: "no cache section", config: `{}`, wantTTL: cache.DefaultTTL, wantMax: cache.DefaultMaxBytes},
+		{name: "configured", config: `{"cache": {"ttl": "10m", "max_bytes": 4096}}`, wantTTL: 10 * time.Minute, wantMax: 4096},
+		{name: "bad ttl", config: `{"cache": {"ttl": "soon"}}`, wantFail: true},
+		{name: "negative size", config: `{"cache": {"max_bytes": -1}}`, wantFail: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			root := t.TempDir()
+			t.Setenv("XDG_CONFIG_HOME", root)
+			t.Setenv("XDG_CACHE_HOME", filepath.Join(root, "cache"))
+			if tt.config != "" {
+				writeConfig(t, root, tt.config)
+			}
+
+			store, err := openCache()
+			if tt.wantFail {
+				if err == nil {
+					t.Fatal("openCache() error = nil, want failure")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("openCache() error = %v", err)
+			}
+			if store.TTL() != tt.wantTTL || store.MaxBytes() != tt.wantMax {
+				t.Errorf("openCache() ttl %s max %d, want %s %d", store.TTL(), store.MaxBytes(), tt.wantTTL, tt.wantMax)
+			}
+			if want := filepath.Join(root, "cache", "cftoken"); store.Dir() != want {
+				t.Errorf("openCache() dir = %s, want %s", store.Dir(), want)
+			}
+		})
+	}
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"cftoken/internal/clock"
+	"cftoken/internal/cloudflare"
+	"cftoken/internal/template"
+)
+
+const (
+	canaryRecord   = "_cftoken-canary"
+	canaryAttempts = 3
+	// canaryBackoff gives a freshly created token time to propagate.
+	canaryBackoff = 2 * time.Second
+)
+
+// dnsWriteGroupID and dnsWriteGroupName identify the permission group that
+// writes DNS records, the one the canary exercises.
+const (
+	dnsWriteGroupID   = "4755a26eedb94da69e1066d98aa820be"
+	dnsWriteGroupName = "DNS Write"
+)
+
+// grantsDNSWrite reports whether any allow policy includes the DNS record
+// write permission group, matched by ID or exact name. Groups that merely
+// mention DNS, such as Zone DNS Settings Write, do not count.
+func grantsDNSWrite(policies []template.Policy) bool {
+	for _, p := range policies {
+		if p.Effect != "" && !strings.EqualFold(p.Effect, "allow") {
+			continue
+		}
+		for _, pg := range p.PermissionGroups {
+			if pg.ID == dnsWriteGroupID || strings.EqualFold(pg.Name, dnsWriteGroupName) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// runDNSCanary proves the new token can write DNS by creating and deleting a
+// TXT record in the zone with it. The management client only looks up the
+// zone name and cleans up if the new token cannot delete its own record.
+func runDNSCanary(ctx context.Context, management *cloudflare.Client, tokenValue, zoneID string, verbose bool) error {
+	zoneName, err := management.ZoneName(ctx, zoneID)
+	if err != nil {
+		return fmt.Errorf("dns canary: %w", err)
+	}
+	name := canaryRecord + "." + zoneName
+	probe := newClient(tokenValue, verbose)
+
+	var recordID string
+	for attempt := 1; ; attempt++ {
+		recordID, err = probe.CreateTXTRecord(ctx, zoneID, name, "cftoken canary "+clock.Now().UTC().Format(time.RFC3339))
+		if err == nil || attempt == canaryAttempts {
+			break
+		}
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("dns canary: %w", ctx.Err())
+		case <-time.After(canaryBackoff):
+		}
+	}
+	if err != nil {
+		return fmt.Errorf("dns canary: new token could not create %s: %w", name, err)
+	}
+
+	if err := probe.DeleteDNSRecord(ctx, zoneID, recordID); err != nil {
+		if cleanupErr := management.DeleteDNSRecord(ctx, zoneID, recordID); cleanupErr != nil {
+			return fmt.Errorf("dns canary: new token could not delete %s (%v), and cleanup failed; remove it manually: %w", name, err, cleanupErr)
+		}
+		return fmt.Errorf("dns canary: new token could not delete %s: %w", name, err)
+	}
+	fmt.Fprintf(reportOutput(), "DNS canary passed: created and deleted TXT %s with the new token.\n", name)
+	return nil
+}
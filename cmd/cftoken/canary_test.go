@@ -0,0 +1,90 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"cftoken/internal/cloudflare"
+	"cftoken/internal/template"
+)
+
+func TestGrantsDNSWrite(t *testing.T) {
+	t.Parallel()
+
+	policy := func(effect string, names ...string) template.Policy {
+		p := template.Policy{Effect: effect}
+		for _, n := range names {
+			p.PermissionGroups = append(p.PermissionGroups, template.PermissionGroup{Name: n})
+		}
+		return p
+	}
+
+	tests := []struct {
+		name     string
+		policies []template.Policy
+		want     bool
+	}{
+		{"dns write", []template.Policy{policy("allow", "Zone Read", "DNS Write")}, true},
+		{"dns write by id", []template.Policy{{Effect: "allow", PermissionGroups: []template.PermissionGroup{{ID: dnsWriteGroupID}}}}, true},
+		{"dns settings", []template.Policy{policy("allow", "Zone DNS Settings Write")}, false},
+		{"dns firewall", []template.Policy{policy("allow", "DNS Firewall Write")}, false},
+		{"read only", []template.Policy{policy("allow", "Zone Read", "DNS Read")}, false},
+		{"denied", []template.Policy{policy("deny", "DNS Write")}, false},
+		{"no policies", nil, false},
+	}
+	for _, tc := range tests {
+		if got := grantsDNSWrite(tc.policies); got != tc.want {
+			t.Errorf("%s: grantsDNSWrite() = %v, want %v", tc.name, got, tc.want)
+		}
+	}
+}
+
+// TestDNSCanaryValueOutput checks that under -output value the canary's
+// status joins the report on stderr and stdout carries only the token.
+func TestDNSCanaryValueOutput(t *testing.T) {
+	root := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", root)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		result := map[string]any{"id": "rec-1"}
+		if r.Method == http.MethodGet {
+			result = map[string]any{"id": "z1", "name": "example.com"}
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]any{"success": true, "errors": []any{}, "messages": []any{}, "result": result})
+	}))
+	defer srv.Close()
+	defer func(prev []cloudflare.Option) { testClientOptions = prev }(testClientOptions)
+	testClientOptions = []cloudflare.Option{cloudflare.WithBaseURL(srv.URL), cloudflare.WithCacheTTL(0)}
+	defer func(prev string) { outputFormat = prev }(outputFormat)
+	outputFormat = outputValue
+
+	defer func(stdout, stderr *os.File) { os.Stdout, os.Stderr = stdout, stderr }(os.Stdout, os.Stderr)
+	stdout, err := os.Create(filepath.Join(root, "stdout"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	stderr, err := os.Create(filepath.Join(root, "stderr"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	os.Stdout, os.Stderr = stdout, stderr
+
+	result := &cloudflare.TokenResult{ID: "t1", Name: "prod", Status: "active", Value: "secret", ZoneID: "z1"}
+	printTokenResult(os.Stdout, result, "example.com", 0)
+	if err := runDNSCanary(context.Background(), newClient("management", false), result.Value, "z1", false); err != nil {
+		t.Fatalf("runDNSCanary() error = %v", err)
+	}
+
+	if out, _ := os.ReadFile(stdout.Name()); string(out) != "secret\n" {
+		t.Errorf("stdout = %q, want only the token", out)
+	}
+	if report, _ := os.ReadFile(stderr.Name()); !strings.Contains(string(report), "DNS canary passed") {
+		t.Errorf("stderr = %q, want the canary status", report)
+	}
+}
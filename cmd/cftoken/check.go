@@ -0,0 +1,85 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"strings"
+
+	"cftoken/internal/clock"
+	"cftoken/internal/config"
+	"cftoken/internal/guardrail"
+)
+
+// runCheck renders a token set manifest and evaluates every token in it
+// against the guardrails, as apply-template would before creating
+// anything. It never calls the API, so it can run as a pull request check
+// in repositories that keep manifests; any violation fails the run.
+func runCheck(args []string) error {
+	fset := flag.NewFlagSet("check", flag.ContinueOnError)
+	source := addTokenSetFlags(fset)
+	fset.StringVar(&source.templatePath, "f", "", "Manifest to check: a template that renders a token set, or - to read it from stdin (same as -template)")
+	if err := fset.Parse(args); err != nil {
+		return err
+	}
+	if source.templatePath == "" || fset.NArg() > 0 {
+		return withCode(codeInvalidArgument, errors.New("usage: check -f FILE|- [-zone NAME] [-var k=v] [-var-file FILE|-]"), nil)
+	}
+
+	specs, zoneConfig, err := source.render(context.Background(), nil, "check")
+	if err != nil {
+		return err
+	}
+	defaultCIDRs, err := config.LoadDefaultAllowedCIDRs()
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("load default CIDRs: %w", err)
+	}
+	plans, err := planTokenSet(specs, source.zone, zoneConfig, defaultCIDRs, clock.Now().UTC(), true)
+	if err != nil {
+		return withCode(codeInvalidArgument, err, nil)
+	}
+	rules, err := loadGuardrails(zoneConfig)
+	if err != nil {
+		return err
+	}
+
+	violations := tokenSetViolations(plans, rules)
+	if len(violations) > 0 {
+		err := fmt.Errorf("%s: %d guardrail violation(s):\n  - %s", source.templatePath, len(violations), strings.Join(violations, "\n  - "))
+		return withCode(codeGuardrail, err, map[string]any{"violations": violations})
+	}
+	printCheckPassed(os.Stdout, source.templatePath, plans)
+	return nil
+}
+
+// tokenSetViolations evaluates every planned token against rules and
+// returns the violations, each prefixed with the token's name. The change
+// ticket is given when tokens are issued rather than in the manifest, so
+// ticket rules only apply when -ticket is passed.
+func tokenSetViolations(plans []plannedToken, rules guardrail.Rules) []string {
+	if changeTicket == "" {
+		rules.RequireTicket, rules.TicketPatterns = false, nil
+	}
+	var out []string
+	for _, p := range plans {
+		for _, v := range rules.Evaluate(guardrailRequest(p)) {
+			out = append(out, fmt.Sprintf("token %q: %s", p.name, v))
+		}
+	}
+	return out
+}
+
+func printCheckPassed(w io.Writer, path string, plans []plannedToken) {
+	fmt.Fprintf(w, "%s: %d token(s) pass the guardrails\n", path, len(plans))
+	for _, p := range plans {
+		expires := "never expires"
+		if p.ttl > 0 {
+			expires = "expires after " + p.ttl.String()
+		}
+		fmt.Fprintf(w, "  %s  %s, %s\n", p.name, expires, joinOrDefault(p.allowedCIDRs, "any address"))
+	}
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"cftoken/internal/clock"
+)
+
+func TestRunCheck(t *testing.T) {
+	root := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", root)
+	t.Setenv("XDG_STATE_HOME", filepath.Join(root, "state"))
+	t.Cleanup(clock.Freeze(time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)))
+	writeConfig(t, root, `{
+		"default_allowed_cidrs": ["10.0.0.0/8"],
+		"guardrails": {"require_ticket": true, "denied_permissions": ["API Tokens Write"]},
+		"zones": {"prod": {"zone_id": "zone-prod", "guardrails": {"max_ttl": "4h"}}}
+	}`)
+	policy := `{"effect": "allow", "resources": {"com.cloudflare.api.account.zone.{{.ZoneID}}": "*"}, "permission_groups": [{"id": "g1", "name": "%s"}]}`
+	manifest := func(name, ttl, group string) string {
+		path := filepath.Join(root, name+".json.tmpl")
+		data := `{"tokens": [{"name": "` + name + `", "ttl": "` + ttl + `", "policies": [` + strings.Replace(policy, "%s", group, 1) + `]}]}`
+		if err := os.WriteFile(path, []byte(data), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+
+	tests := []struct {
+		name    string
+		args    []string
+		wantErr []string
+	}{
+		{"passes", []string{"-f", manifest("deploy", "2h", "DNS Write"), "-zone", "prod"}, nil},
+		{"too long", []string{"-f", manifest("slow", "8h", "DNS Write"), "-zone", "prod"}, []string{`token "slow-20240102T030405Z"`, "exceeds the maximum of 4h"}},
+		{"denied permission", []string{"-f", manifest("admin", "1h", "API Tokens Write")}, []string{`permission "API Tokens Write" is not allowed`}},
+		{"no manifest", nil, []string{"usage: check -f FILE"}},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			err := runCheck(tc.args)
+			if len(tc.wantErr) == 0 {
+				if err != nil {
+					t.Fatalf("runCheck() error = %v", err)
+				}
+				return
+			}
+			if err == nil {
+				t.Fatal("runCheck() error = nil, want a violation")
+			}
+			for _, want := range tc.wantErr {
+				if !strings.Contains(err.Error(), want) {
+					t.Errorf("runCheck() error = %v, want it to contain %q", err, want)
+				}
+			}
+		})
+	}
+}
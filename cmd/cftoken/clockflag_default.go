@@ -0,0 +1,11 @@
+//go:build !testclock
+
+package main
+
+import "flag"
+
+// registerClockFlag defines nothing in regular builds: a frozen clock would
+// let anyone get around TTL limits, issuance budgets, request expiry, and
+// prune ages, so -clock exists only with -tags testclock; see
+// clockflag_testclock.go.
+func registerClockFlag(*flag.FlagSet) {}
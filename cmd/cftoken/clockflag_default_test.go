@@ -0,0 +1,17 @@
+//go:build !testclock
+
+package main
+
+import (
+	"flag"
+	"testing"
+)
+
+func TestNoClockFlag(t *testing.T) {
+	var f runFlags
+	fs := flag.NewFlagSet("cftoken", flag.ContinueOnError)
+	f.register(fs)
+	if fs.Lookup("clock") != nil {
+		t.Fatal("-clock is defined in a regular build, want it only with -tags testclock")
+	}
+}
@@ -0,0 +1,16 @@
+//go:build testclock
+
+package main
+
+import (
+	"flag"
+
+	"cftoken/internal/clock"
+)
+
+// registerClockFlag defines the hidden -clock flag in binaries built with
+// -tags testclock. It pins the time names, expiries, and cache freshness
+// are computed from, for reproducible test runs.
+func registerClockFlag(fs *flag.FlagSet) {
+	fs.Var(clock.Value{}, "clock", "Freeze the clock at this RFC 3339 time (testing)")
+}
@@ -0,0 +1,156 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"slices"
+	"sort"
+	"strings"
+
+	"cftoken/internal/config"
+)
+
+// completionScripts are the shell scripts `cftoken completion SHELL`
+// prints. Each asks `cftoken __complete` for the candidates, passing the
+// words before the cursor followed by the word being completed.
+var completionScripts = map[string]string{
+	"bash": `# bash completion for cftoken
+_cftoken() {
+	local cur=${COMP_WORDS[COMP_CWORD]} candidate
+	COMPREPLY=()
+	while IFS= read -r candidate; do
+		COMPREPLY+=("$(printf '%q' "$candidate")")
+	done < <(cftoken __complete "${COMP_WORDS[@]:1:COMP_CWORD-1}" "$cur" 2>/dev/null)
+}
+complete -F _cftoken cftoken
+`,
+	"zsh": `#compdef cftoken
+_cftoken() {
+	local -a candidates
+	candidates=(${(f)"$(cftoken __complete "${(@)words[2,CURRENT-1]}" "${words[CURRENT]}" 2>/dev/null)"})
+	compadd -- "${candidates[@]}"
+}
+if [ "$funcstack[1]" = "_cftoken" ]; then
+	_cftoken "$@"
+else
+	compdef _cftoken cftoken
+fi
+`,
+	"fish": `complete -c cftoken -f -a '(cftoken __complete (commandline -opc)[2..-1] (commandline -ct))'
+`,
+}
+
+func runCompletion(args []string) error {
+	shells := sortedKeys(completionScripts)
+	if len(args) != 1 {
+		return withCode(codeInvalidArgument, fmt.Errorf("usage: completion %s", strings.Join(shells, "|")), nil)
+	}
+	script, ok := completionScripts[args[0]]
+	if !ok {
+		return withCode(codeInvalidArgument, fmt.Errorf("unknown shell %q; available: %s", args[0], strings.Join(shells, ", ")), nil)
+	}
+	_, err := fmt.Print(script)
+	return err
+}
+
+// completionSource supplies the candidates completions offers.
+type completionSource struct {
+	flags       func() []string
+	zones       func() ([]string, error)
+	permissions func() []string
+}
+
+// runComplete implements the protocol of the completion scripts: args are
+// the words before the cursor, without the program name, and then the
+// word being completed. The candidates are printed one per line. Nothing
+// is fetched from the API, so permission groups only complete once a run
+// has cached the catalog. Completion runs on every TAB, so it never runs
+// token_source: the catalog is only found for CLOUDFLARE_API_TOKEN.
+func runComplete(args []string) error {
+	if len(args) == 0 {
+		return nil
+	}
+	token := os.Getenv("CLOUDFLARE_API_TOKEN")
+	src := completionSource{
+		flags: globalFlagNames,
+		zones: config.ZoneNames,
+		permissions: func() []string {
+			if token == "" {
+				return nil
+			}
+			groups, _ := newClient(token, false).CachedPermissionGroups()
+			names := make([]string, 0, len(groups))
+			for _, g := range groups {
+				names = append(names, g.Name)
+			}
+			return names
+		},
+	}
+	for _, c := range completions(args[:len(args)-1], args[len(args)-1], src) {
+		fmt.Println(c)
+	}
+	return nil
+}
+
+// completions returns the sorted candidates for cur: configured zones
+// after -zone, cached permission group names after -permissions (for the
+// last item of the comma-separated list), flag names for a word starting
+// with a dash, and command names for the first word.
+func completions(words []string, cur string, src completionSource) []string {
+	if cur == "=" {
+		words, cur = append(words, cur), ""
+	}
+	var prev string
+	if n := len(words); n > 0 {
+		prev = words[n-1]
+		// bash splits -zone=prod into "-zone", "=", and "prod".
+		if prev == "=" && n > 1 {
+			prev = words[n-2]
+		}
+	}
+	var prefix string
+	if name, value, ok := strings.Cut(cur, "="); ok && strings.HasPrefix(name, "-") {
+		prev, prefix, cur = name, name+"=", value
+	}
+
+	var candidates []string
+	switch {
+	case prev == "-zone" || prev == "--zone":
+		if zones, err := src.zones(); err == nil {
+			candidates = zones
+		}
+	case prev == "-permissions" || prev == "--permissions":
+		head := cur[:strings.LastIndex(cur, ",")+1]
+		for _, name := range src.permissions() {
+			candidates = append(candidates, head+name)
+		}
+	case prefix != "":
+	case strings.HasPrefix(cur, "-"):
+		candidates = src.flags()
+	case len(words) == 0:
+		for _, c := range commands {
+			candidates = append(candidates, c.name)
+		}
+	}
+
+	var out []string
+	for _, c := range candidates {
+		if strings.HasPrefix(c, cur) {
+			out = append(out, prefix+c)
+		}
+	}
+	sort.Strings(out)
+	return slices.Compact(out)
+}
+
+// globalFlagNames lists the global flags usage shows, with a leading dash.
+func globalFlagNames() []string {
+	var names []string
+	flag.CommandLine.VisitAll(func(f *flag.Flag) {
+		if !hiddenFlags[f.Name] {
+			names = append(names, "-"+f.Name)
+		}
+	})
+	return names
+}
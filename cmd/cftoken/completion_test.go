@@ -0,0 +1,51 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestCompletions(t *testing.T) {
+	t.Parallel()
+
+	src := completionSource{
+		flags: func() []string { return []string{"-zone", "-zone-id", "-permissions", "-ttl"} },
+		zones: func() ([]string, error) { return []string{"prod", "preview", "staging"}, nil },
+		permissions: func() []string {
+			return []string{"DNS Write", "DNS Read", "Zone Read", "DNS Write"}
+		},
+	}
+	tests := []struct {
+		words []string
+		cur   string
+		want  []string
+	}{
+		{words: []string{"-zone"}, cur: "pr", want: []string{"preview", "prod"}},
+		{words: []string{"create", "--zone"}, cur: "", want: []string{"preview", "prod", "staging"}},
+		{cur: "-zone=st", want: []string{"-zone=staging"}},
+		{words: []string{"-zone", "="}, cur: "s", want: []string{"staging"}},
+		{words: []string{"-zone"}, cur: "=", want: []string{"preview", "prod", "staging"}},
+		{words: []string{"-permissions"}, cur: "DNS", want: []string{"DNS Read", "DNS Write"}},
+		{words: []string{"-permissions"}, cur: "Zone Read,DNS W", want: []string{"Zone Read,DNS Write"}},
+		{cur: "-zo", want: []string{"-zone", "-zone-id"}},
+		{cur: "-ttl=", want: nil},
+		{cur: "compl", want: []string{"completion"}},
+		{words: []string{"create"}, cur: "x", want: nil},
+	}
+	for _, tc := range tests {
+		if got := completions(tc.words, tc.cur, src); !reflect.DeepEqual(got, tc.want) {
+			t.Errorf("completions(%q, %q) = %q, want %q", tc.words, tc.cur, got, tc.want)
+		}
+	}
+}
+
+func TestRunCompletion(t *testing.T) {
+	t.Parallel()
+
+	if err := runCompletion([]string{"powershell"}); err == nil {
+		t.Errorf("runCompletion(powershell) error = nil")
+	}
+	if err := runCompletion(nil); err == nil {
+		t.Errorf("runCompletion() error = nil")
+	}
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"cftoken/internal/cloudflare"
+)
+
+// conditionFlag collects repeated -condition type.operator=values flags.
+type conditionFlag []cloudflare.Condition
+
+func (f *conditionFlag) String() string {
+	parts := make([]string, 0, len(*f))
+	for _, c := range *f {
+		parts = append(parts, fmt.Sprintf("%s.%s=%s", c.Type, c.Operator, strings.Join(c.Values, ",")))
+	}
+	return strings.Join(parts, " ")
+}
+
+func (f *conditionFlag) Set(value string) error {
+	c, err := cloudflare.ParseCondition(value)
+	if err != nil {
+		return err
+	}
+	if err := checkAllowedCIDRsCondition(c, "-allow-cidrs"); err != nil {
+		return err
+	}
+	*f = append(*f, c)
+	return nil
+}
+
+// checkAllowedCIDRsCondition refuses request_ip.in, which is set from the
+// allowed CIDRs so the defaults in config.json and the guardrails apply.
+func checkAllowedCIDRsCondition(c cloudflare.Condition, instead string) error {
+	if c.Type == "request_ip" && c.Operator == "in" {
+		return fmt.Errorf("request_ip.in is set from the allowed CIDRs; use %s", instead)
+	}
+	return nil
+}
+
+// templateConditions turns the conditions object of a token set entry into
+// conditions, in a stable order.
+func templateConditions(obj map[string]map[string][]string) ([]cloudflare.Condition, error) {
+	var out []cloudflare.Condition
+	for _, typ := range sortedKeys(obj) {
+		for _, op := range sortedKeys(obj[typ]) {
+			c := cloudflare.Condition{Type: typ, Operator: op, Values: obj[typ][op]}
+			if err := c.Validate(); err != nil {
+				return nil, err
+			}
+			if err := checkAllowedCIDRsCondition(c, "allowed_cidrs"); err != nil {
+				return nil, err
+			}
+			out = append(out, c)
+		}
+	}
+	return out, nil
+}
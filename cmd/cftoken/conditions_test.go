@@ -0,0 +1,24 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestConditionFlag(t *testing.T) {
+	var f conditionFlag
+	for _, v := range []string{"request_ip.not_in=10.9.0.0/16", "request_ip.not_in=10.8.0.1"} {
+		if err := f.Set(v); err != nil {
+			t.Fatalf("Set(%q) error = %v", v, err)
+		}
+	}
+	if got, want := f.String(), "request_ip.not_in=10.9.0.0/16 request_ip.not_in=10.8.0.1"; got != want {
+		t.Errorf("String() = %q, want %q", got, want)
+	}
+	if err := f.Set("request_ip.in=10.0.0.0/8"); err == nil || !strings.Contains(err.Error(), "use -allow-cidrs") {
+		t.Errorf("Set(request_ip.in) error = %v, want -allow-cidrs hint", err)
+	}
+	if len(f) != 2 {
+		t.Errorf("len = %d after a rejected value, want 2", len(f))
+	}
+}
@@ -0,0 +1,280 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+
+	"cftoken/internal/cloudflare"
+	"cftoken/internal/config"
+	"cftoken/internal/duration"
+	"cftoken/internal/labels"
+	"cftoken/internal/naming"
+)
+
+func runConfig(ctx context.Context, token func() string, verbose bool, args []string) error {
+	if len(args) == 0 {
+		return errors.New("config requires a subcommand: lint, set-zone, or remove-zone")
+	}
+	switch sub := args[0]; sub {
+	case "lint":
+		return runConfigLint(args[1:])
+	case "set-zone":
+		return runConfigSetZone(args[1:])
+	case "remove-zone":
+		return runConfigRemoveZone(ctx, token, verbose, args[1:])
+	default:
+		return fmt.Errorf("unknown config subcommand %q; available: lint, set-zone, remove-zone", sub)
+	}
+}
+
+func runConfigLint(args []string) error {
+	fset := flag.NewFlagSet("config lint", flag.ContinueOnError)
+	if err := fset.Parse(args); err != nil {
+		return err
+	}
+
+	path, err := config.DefaultPath()
+	if err != nil {
+		return err
+	}
+	findings, err := config.Lint()
+	if err != nil {
+		return fmt.Errorf("lint %s: %w", path, err)
+	}
+	if len(findings) == 0 {
+		fmt.Printf("%s: no problems found\n", path)
+		return nil
+	}
+	for _, f := range findings {
+		fmt.Println(f)
+	}
+	return fmt.Errorf("%s: %d problem(s) found", path, len(findings))
+}
+
+func runConfigSetZone(args []string) error {
+	fset := flag.NewFlagSet("config set-zone", flag.ContinueOnError)
+	zoneID := fset.String("zone-id", "", "Zone identifier (required for new zones)")
+	accountID := fset.String("account-id", "", "Account identifier for templates")
+	ttl := fset.String("ttl", "", "Token lifetime, e.g. 4h or 2d")
+	templateFile := fset.String("template-file", "", "Policy template path")
+	extends := fset.String("extends", "", "Profile the zone extends")
+	permissions := fset.String("permissions", "", "Comma-separated permission group names or IDs")
+	allowCIDRs := fset.String("allow-cidrs", "", "Comma-separated CIDR ranges allowed to use tokens")
+	name, err := parseZoneArgs(fset, args, "usage: config set-zone NAME [-zone-id ID] [-ttl D] [-permissions LIST] [-allow-cidrs LIST] [-template-file PATH] [-extends PROFILE] [-account-id ID]")
+	if err != nil {
+		return err
+	}
+
+	// Only flags given on the command line change the zone; passing one
+	// with an empty value removes that field.
+	var edit config.ZoneEdit
+	fset.Visit(func(f *flag.Flag) {
+		switch f.Name {
+		case "zone-id":
+			edit.ZoneID = zoneID
+		case "account-id":
+			edit.AccountID = accountID
+		case "ttl":
+			edit.TTL = ttl
+		case "template-file":
+			edit.TemplateFile = templateFile
+		case "extends":
+			edit.Extends = extends
+		case "permissions":
+			edit.Permissions = splitList(*permissions)
+		case "allow-cidrs":
+			edit.AllowedCIDRs = splitList(*allowCIDRs)
+		}
+	})
+	if err := validateZoneEdit(edit); err != nil {
+		return withCode(codeInvalidArgument, err, nil)
+	}
+
+	if err := config.SetZone(name, edit); err != nil {
+		return err
+	}
+	path, err := config.DefaultPath()
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Updated zone %s in %s\n", name, path)
+	return nil
+}
+
+// runConfigRemoveZone removes a zone from config.json. Tokens cftoken
+// issued for the zone keep working after that, so they are listed first
+// and, with -revoke or a yes at the prompt, revoked once the zone is gone.
+func runConfigRemoveZone(ctx context.Context, token func() string, verbose bool, args []string) error {
+	fset := flag.NewFlagSet("config remove-zone", flag.ContinueOnError)
+	revoke := fset.Bool("revoke", false, "Revoke the tokens cftoken issued for the zone without asking")
+	keepTokens := fset.Bool("keep-tokens", false, "Do not look for tokens that still grant access to the zone")
+	name, err := parseZoneArgs(fset, args, "usage: config remove-zone [-revoke | -keep-tokens] NAME")
+	if err != nil {
+		return err
+	}
+	if *revoke && *keepTokens {
+		return withCode(codeInvalidArgument, errors.New("-revoke and -keep-tokens cannot be combined"), nil)
+	}
+
+	var (
+		client *cloudflare.Client
+		scoped []cloudflare.Token
+	)
+	zoneID, _, err := config.LoadZoneConfig(name)
+	switch {
+	case err != nil || zoneID == "" || *keepTokens:
+		// RemoveZone reports zones that are not configured.
+	case token() == "":
+		log.Printf("warning: no management token; not checking for tokens that still grant access to zone %s", name)
+	default:
+		client = newClient(token(), verbose)
+		if scoped, err = zoneTokens(ctx, client, zoneID); err != nil {
+			return fmt.Errorf("list tokens of zone %s (pass -keep-tokens to remove it anyway): %w", name, err)
+		}
+	}
+
+	if len(scoped) > 0 {
+		reg, err := labels.Load()
+		if err != nil {
+			return err
+		}
+		fmt.Printf("%d token(s) cftoken issued still grant access to zone %s (%s):\n", len(scoped), name, zoneID)
+		printRevokeCandidates(os.Stdout, scoped, reg)
+		if !*revoke && isTerminal(os.Stdin) {
+			answer, err := newPrompter(os.Stdin, os.Stdout)("Revoke them once the zone is removed? (y/N)", "n")
+			if err != nil {
+				return err
+			}
+			*revoke = strings.EqualFold(answer, "y") || strings.EqualFold(answer, "yes")
+		}
+	}
+
+	if err := config.RemoveZone(name); err != nil {
+		return err
+	}
+	path, err := config.DefaultPath()
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Removed zone %s from %s\n", name, path)
+	if len(scoped) == 0 {
+		return nil
+	}
+	if !*revoke {
+		fmt.Println("Its tokens were left in place; revoke them with `cftoken revoke ID...` or rerun with -revoke.")
+		return nil
+	}
+	return revokeSelected(ctx, client, scoped)
+}
+
+// zoneTokens returns the tokens cftoken issued that grant access to
+// zoneID, other than the management token itself.
+func zoneTokens(ctx context.Context, client *cloudflare.Client, zoneID string) ([]cloudflare.Token, error) {
+	self, err := client.VerifyToken(ctx)
+	if err != nil {
+		return nil, err
+	}
+	tokens, err := client.ListTokens(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return selectZoneTokens(tokens, zoneID, self.ID), nil
+}
+
+// selectZoneTokens returns the tokens with a cftoken-generated name whose
+// allow policies name zoneID as a resource, skipping the token with ID
+// selfID.
+func selectZoneTokens(tokens []cloudflare.Token, zoneID, selfID string) []cloudflare.Token {
+	var selected []cloudflare.Token
+	for _, token := range tokens {
+		if token.ID == selfID || naming.Trim(token.Name) == token.Name {
+			continue
+		}
+		if grantsZone(token.Policies, zoneID) {
+			selected = append(selected, token)
+		}
+	}
+	return selected
+}
+
+// grantsZone reports whether an allow policy lists zoneID as a resource,
+// either directly or nested under an account.
+func grantsZone(policies []cloudflare.TokenPolicyInspection, zoneID string) bool {
+	for _, p := range policies {
+		if p.Effect != "allow" {
+			continue
+		}
+		for _, r := range p.Resources {
+			key, _, _ := strings.Cut(r, "=")
+			if strings.HasSuffix(key, "com.cloudflare.api.account.zone."+zoneID) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// parseZoneArgs parses fset and returns the single zone name, which may come
+// before or after the flags.
+func parseZoneArgs(fset *flag.FlagSet, args []string, usage string) (string, error) {
+	var name string
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		name, args = args[0], args[1:]
+	}
+	if err := fset.Parse(args); err != nil {
+		return "", err
+	}
+	if name == "" && fset.NArg() > 0 {
+		name = fset.Arg(0)
+		if err := fset.Parse(fset.Args()[1:]); err != nil {
+			return "", err
+		}
+	}
+	if name == "" || fset.NArg() > 0 {
+		return "", withCode(codeInvalidArgument, errors.New(usage), nil)
+	}
+	return name, nil
+}
+
+// validateZoneEdit checks the values set-zone writes and rewrites the TTL in
+// its shortest form, so 48h is stored as 2d. Values holding ${...}
+// references are only checked once the config is loaded.
+func validateZoneEdit(edit config.ZoneEdit) error {
+	isRef := func(s string) bool { return strings.Contains(s, "${") }
+	if v := edit.ZoneID; v != nil && *v != "" && !isRef(*v) && !looksLikeZoneID(*v) {
+		return fmt.Errorf("invalid -zone-id %q: want 32 hex characters", *v)
+	}
+	if v := edit.TTL; v != nil && *v != "" && !isRef(*v) {
+		d, err := duration.Parse(*v)
+		if err != nil {
+			return fmt.Errorf("invalid -ttl: %w", err)
+		}
+		*v = duration.Format(d)
+	}
+	for _, cidr := range edit.AllowedCIDRs {
+		if isRef(cidr) {
+			continue
+		}
+		if _, _, err := normalizeCIDRList([]string{cidr}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// splitList splits a comma-separated flag value, dropping empty items. An
+// empty value yields an empty, non-nil list.
+func splitList(s string) []string {
+	out := []string{}
+	for _, part := range strings.Split(s, ",") {
+		if part = strings.TrimSpace(part); part != "" {
+			out = append(out, part)
+		}
+	}
+	return out
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"flag"
+	"reflect"
+	"testing"
+
+	"cftoken/internal/cloudflare"
+	"cftoken/internal/config"
+)
+
+func TestParseZoneArgs(t *testing.T) {
+	tests := []struct {
+		args    []string
+		want    string
+		ttl     string
+		wantErr bool
+	}{
+		{[]string{"example.com", "-ttl", "4h"}, "example.com", "4h", false},
+		{[]string{"-ttl", "4h", "example.com"}, "example.com", "4h", false},
+		{[]string{"-ttl", "4h"}, "", "", true},
+		{[]string{"a.com", "b.com"}, "", "", true},
+	}
+	for _, tc := range tests {
+		fset := flag.NewFlagSet("test", flag.ContinueOnError)
+		ttl := fset.String("ttl", "", "")
+		name, err := parseZoneArgs(fset, tc.args, "usage")
+		if (err != nil) != tc.wantErr {
+			t.Fatalf("parseZoneArgs(%q) error = %v, wantErr %v", tc.args, err, tc.wantErr)
+		}
+		if !tc.wantErr && (name != tc.want || *ttl != tc.ttl) {
+			t.Errorf("parseZoneArgs(%q) = %q, ttl %q; want %q, %q", tc.args, name, *ttl, tc.want, tc.ttl)
+		}
+	}
+}
+
+func TestValidateZoneEdit(t *testing.T) {
+	s := func(v string) *string { return &v }
+	tests := []struct {
+		name    string
+		edit    config.ZoneEdit
+		wantErr bool
+	}{
+		{"valid", config.ZoneEdit{ZoneID: s("aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa"), TTL: s("4h"), AllowedCIDRs: []string{"10.0.0.0/8"}}, false},
+		{"reference", config.ZoneEdit{ZoneID: s("${env:ZONE_ID}"), TTL: s("${zones.base.ttl}")}, false},
+		{"clear", config.ZoneEdit{TTL: s("")}, false},
+		{"bad zone id", config.ZoneEdit{ZoneID: s("example.com")}, true},
+		{"days", config.ZoneEdit{TTL: s("7d")}, false},
+		{"bad ttl", config.ZoneEdit{TTL: s("4 hours")}, true},
+		{"bad cidr", config.ZoneEdit{AllowedCIDRs: []string{"10.0.0.0/99"}}, true},
+	}
+	for _, tc := range tests {
+		if err := validateZoneEdit(tc.edit); (err != nil) != tc.wantErr {
+			t.Errorf("%s: validateZoneEdit() error = %v, wantErr %v", tc.name, err, tc.wantErr)
+		}
+	}
+	edit := config.ZoneEdit{TTL: s("48h")}
+	if err := validateZoneEdit(edit); err != nil || *edit.TTL != "2d" {
+		t.Errorf("validateZoneEdit(ttl 48h) = %v, ttl %q, want 2d", err, *edit.TTL)
+	}
+}
+
+func TestSelectZoneTokens(t *testing.T) {
+	t.Parallel()
+
+	zone := "023e105f4ecef8ad9ca31a8372d0c353"
+	allow := func(resources ...string) []cloudflare.TokenPolicyInspection {
+		return []cloudflare.TokenPolicyInspection{{Effect: "allow", Resources: resources}}
+	}
+	tokens := []cloudflare.Token{
+		{ID: "t1", Name: "prod-20240102T030405Z", Policies: allow("com.cloudflare.api.account.zone." + zone + "=*")},
+		{ID: "t2", Name: "prod-20240102T030406Z", Policies: allow("com.cloudflare.api.account.a1.com.cloudflare.api.account.zone." + zone + "=*")},
+		{ID: "t3", Name: "other-20240102T030405Z", Policies: allow("com.cloudflare.api.account.zone.ffffffffffffffffffffffffffffffff=*")},
+		{ID: "t4", Name: "hand-made", Policies: allow("com.cloudflare.api.account.zone." + zone + "=*")},
+		{ID: "t5", Name: "deny-20240102T030405Z", Policies: []cloudflare.TokenPolicyInspection{{Effect: "deny", Resources: []string{"com.cloudflare.api.account.zone." + zone + "=*"}}}},
+		{ID: "self", Name: "admin-20240102T030405Z", Policies: allow("com.cloudflare.api.account.zone." + zone + "=*")},
+	}
+
+	var got []string
+	for _, token := range selectZoneTokens(tokens, zone, "self") {
+		got = append(got, token.ID)
+	}
+	if want := []string{"t1", "t2"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("selectZoneTokens() = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"cftoken/internal/template"
+)
+
+// accountResourcePrefix starts the resource keys of an account and of
+// everything in it.
+const accountResourcePrefix = "com.cloudflare.api.account."
+
+// confirmationRisks lists why a token needs confirmation before it is
+// created: the high-risk reasons of issuanceRisks, plus write access to
+// account-wide resources.
+func confirmationRisks(expiresOn *time.Time, ipRestrictionDisabled bool, policies []template.Policy) []string {
+	return append(issuanceRisks(expiresOn, ipRestrictionDisabled), accountWideWrites(policies)...)
+}
+
+// accountWideWrites describes the write or edit permission groups that
+// allow policies grant on a whole account or on every zone. Groups are
+// recognized by name, so those given only by ID are not reported.
+func accountWideWrites(policies []template.Policy) []string {
+	var risks []string
+	for _, p := range policies {
+		if !strings.EqualFold(p.Effect, "allow") {
+			continue
+		}
+		var writes []string
+		for _, pg := range p.PermissionGroups {
+			words := strings.Fields(strings.ToLower(pg.Name))
+			if slices.Contains(words, "write") || slices.Contains(words, "edit") {
+				writes = append(writes, pg.Name)
+			}
+		}
+		if len(writes) == 0 {
+			continue
+		}
+		for _, key := range sortedKeys(p.Resources) {
+			if accountWide(key, p.Resources[key]) {
+				risks = append(risks, fmt.Sprintf("%s on %s", strings.Join(writes, ", "), key))
+			}
+		}
+	}
+	return risks
+}
+
+// accountWide reports whether a resource covers an account as a whole,
+// e.g. com.cloudflare.api.account.<id> or all zones. An account key that
+// maps to a nested set of zones is limited to those zones.
+func accountWide(key string, value any) bool {
+	switch {
+	case key == zoneResourcePrefix+"*":
+		return true
+	case strings.HasPrefix(key, zoneResourcePrefix):
+		return false
+	case !strings.HasPrefix(key, accountResourcePrefix):
+		return false
+	}
+	_, nested := value.(map[string]any)
+	return !nested
+}
+
+// errDeclined is returned when the operator answers no to a confirmation,
+// so that scripts can tell a declined run from a created token.
+var errDeclined = errors.New("token not created: declined at the confirmation prompt")
+
+// promptRisks asks on a terminal whether to create tokens with risks, for
+// the commands that create token sets. Without a terminal on stdin it asks
+// nothing and agrees.
+var promptRisks = func(risks []string) (bool, error) {
+	if !isTerminal(os.Stdin) {
+		return true, nil
+	}
+	return confirmRisks(newPrompter(os.Stdin, os.Stderr), os.Stderr, risks)
+}
+
+// confirmRisks lists risks on out and asks whether to create the token
+// anyway.
+func confirmRisks(ask func(question, def string) (string, error), out io.Writer, risks []string) (bool, error) {
+	fmt.Fprintln(out, "This token is risky:")
+	for _, r := range risks {
+		fmt.Fprintf(out, "  - %s\n", r)
+	}
+	answer, err := ask("Create it anyway? (y/N)", "n")
+	if err != nil {
+		return false, err
+	}
+	return strings.EqualFold(answer, "y") || strings.EqualFold(answer, "yes"), nil
+}
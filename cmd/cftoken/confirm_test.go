@@ -0,0 +1,80 @@
+package main
+
+import (
+	"bytes"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"cftoken/internal/template"
+)
+
+func TestConfirmationRisks(t *testing.T) {
+	dnsWrite := []template.PermissionGroup{{ID: "g1", Name: "DNS Write"}, {ID: "g2", Name: "Zone Read"}}
+	expires := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	tests := []struct {
+		name      string
+		expiresOn *time.Time
+		noIP      bool
+		policies  []template.Policy
+		want      []string
+	}{
+		{
+			name:      "zone scoped write",
+			expiresOn: &expires,
+			policies:  []template.Policy{{Effect: "allow", Resources: map[string]any{"com.cloudflare.api.account.zone.z1": "*"}, PermissionGroups: dnsWrite}},
+		},
+		{
+			name:     "no expiry and no IP restriction",
+			noIP:     true,
+			policies: []template.Policy{{Effect: "allow", Resources: map[string]any{"com.cloudflare.api.account.zone.z1": "*"}, PermissionGroups: dnsWrite}},
+			want:     []string{"token never expires", "IP restrictions disabled"},
+		},
+		{
+			name:      "account wide",
+			expiresOn: &expires,
+			policies: []template.Policy{
+				{Effect: "allow", Resources: map[string]any{"com.cloudflare.api.account.a1": "*"}, PermissionGroups: []template.PermissionGroup{{Name: "Workers Scripts Edit"}, {Name: "Account Settings Read"}}},
+				{Effect: "allow", Resources: map[string]any{"com.cloudflare.api.account.zone.*": "*"}, PermissionGroups: dnsWrite},
+			},
+			want: []string{"Workers Scripts Edit on com.cloudflare.api.account.a1", "DNS Write on com.cloudflare.api.account.zone.*"},
+		},
+		{
+			name:      "nested zones, read only, and deny",
+			expiresOn: &expires,
+			policies: []template.Policy{
+				{Effect: "allow", Resources: map[string]any{"com.cloudflare.api.account.a1": map[string]any{"com.cloudflare.api.account.zone.z1": "*"}}, PermissionGroups: dnsWrite},
+				{Effect: "allow", Resources: map[string]any{"com.cloudflare.api.account.a1": "*"}, PermissionGroups: []template.PermissionGroup{{Name: "Account Settings Read"}}},
+				{Effect: "deny", Resources: map[string]any{"com.cloudflare.api.account.a1": "*"}, PermissionGroups: dnsWrite},
+			},
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := confirmationRisks(tc.expiresOn, tc.noIP, tc.policies); !slices.Equal(got, tc.want) {
+				t.Errorf("confirmationRisks() = %q, want %q", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestConfirmRisks(t *testing.T) {
+	for _, tc := range []struct {
+		input string
+		want  bool
+	}{{"y\n", true}, {"YES\n", true}, {"\n", false}, {"no\n", false}} {
+		var out bytes.Buffer
+		got, err := confirmRisks(newPrompter(strings.NewReader(tc.input), &out), &out, []string{"token never expires"})
+		if err != nil {
+			t.Fatalf("confirmRisks(%q) error = %v", tc.input, err)
+		}
+		if got != tc.want {
+			t.Errorf("confirmRisks(%q) = %v, want %v", tc.input, got, tc.want)
+		}
+		if !strings.Contains(out.String(), "This token is risky:\n  - token never expires\n") {
+			t.Errorf("output = %q", out.String())
+		}
+	}
+}
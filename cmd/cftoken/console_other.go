@@ -0,0 +1,11 @@
+//go:build !windows
+
+package main
+
+import "os"
+
+// enableVirtualTerminal is a no-op outside Windows, where terminals handle
+// ANSI escape sequences natively.
+func enableVirtualTerminal(*os.File) error {
+	return nil
+}
@@ -0,0 +1,28 @@
+package main
+
+import (
+	"os"
+	"syscall"
+)
+
+const enableVirtualTerminalProcessing = 0x0004
+
+var procSetConsoleMode = syscall.NewLazyDLL("kernel32.dll").NewProc("SetConsoleMode")
+
+// enableVirtualTerminal turns on ANSI escape sequence handling for a Windows
+// console. Windows Terminal has it on already; the classic console host
+// used by older PowerShell windows needs it requested.
+func enableVirtualTerminal(f *os.File) error {
+	handle := syscall.Handle(f.Fd())
+	var mode uint32
+	if err := syscall.GetConsoleMode(handle, &mode); err != nil {
+		return err
+	}
+	if mode&enableVirtualTerminalProcessing != 0 {
+		return nil
+	}
+	if r, _, err := procSetConsoleMode.Call(uintptr(handle), uintptr(mode|enableVirtualTerminalProcessing)); r == 0 {
+		return err
+	}
+	return nil
+}
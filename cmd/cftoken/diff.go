@@ -0,0 +1,150 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"time"
+
+	"cftoken/internal/cloudflare"
+	"cftoken/internal/template"
+)
+
+// payloadLines renders a would-be token as canonical lines for diffing.
+func payloadLines(name string, expiresOn *time.Time, allowedCIDRs []string, policies []template.Policy, names map[string]string) []string {
+	expires := "none"
+	if expiresOn != nil {
+		expires = expiresOn.UTC().Format(time.RFC3339)
+	}
+	lines := []string{
+		"name: " + name,
+		"expires: " + expires,
+	}
+	lines = append(lines, prefixedSorted("allowed_cidr: ", allowedCIDRs)...)
+	for idx, policy := range policies {
+		prefix := fmt.Sprintf("policy %d ", idx+1)
+		lines = append(lines, prefix+"effect: "+stringOrDefault(policy.Effect, "allow"))
+		lines = append(lines, prefixedSorted(prefix+"resource: ", cloudflare.ResourceStrings(policy.Resources))...)
+		groups := make([]string, 0, len(policy.PermissionGroups))
+		for _, pg := range policy.PermissionGroups {
+			groups = append(groups, permissionLabel(pg.ID, coalesce(names[pg.ID], pg.Name)))
+		}
+		lines = append(lines, prefixedSorted(prefix+"permission: ", groups)...)
+	}
+	return lines
+}
+
+// inspectionLines renders a live token in the same canonical form as payloadLines.
+func inspectionLines(desc *cloudflare.TokenInspection, names map[string]string) []string {
+	lines := []string{
+		"name: " + desc.Name,
+		"expires: " + stringOrDefault(desc.ExpiresOn, "none"),
+	}
+	lines = append(lines, prefixedSorted("allowed_cidr: ", desc.AllowedCIDRs)...)
+	for idx, policy := range desc.Policies {
+		prefix := fmt.Sprintf("policy %d ", idx+1)
+		lines = append(lines, prefix+"effect: "+policy.Effect)
+		lines = append(lines, prefixedSorted(prefix+"resource: ", policy.Resources)...)
+		groups := make([]string, 0, len(policy.PermissionGroups))
+		for _, pg := range policy.PermissionGroups {
+			groups = append(groups, permissionLabel(pg.ID, coalesce(names[pg.ID], pg.Name)))
+		}
+		lines = append(lines, prefixedSorted(prefix+"permission: ", groups)...)
+	}
+	return lines
+}
+
+// permissionNames collects permission group display names from both sides so
+// the same group renders identically whichever side supplied its name.
+func permissionNames(desc *cloudflare.TokenInspection, policies []template.Policy) map[string]string {
+	names := make(map[string]string)
+	for _, policy := range policies {
+		for _, pg := range policy.PermissionGroups {
+			if pg.Name != "" {
+				names[pg.ID] = pg.Name
+			}
+		}
+	}
+	for _, policy := range desc.Policies {
+		for _, pg := range policy.PermissionGroups {
+			if pg.Name != "" {
+				names[pg.ID] = pg.Name
+			}
+		}
+	}
+	return names
+}
+
+func permissionLabel(id, name string) string {
+	if name == "" {
+		return id
+	}
+	return fmt.Sprintf("%s (%s)", name, id)
+}
+
+func prefixedSorted(prefix string, values []string) []string {
+	sorted := append([]string(nil), values...)
+	sort.Strings(sorted)
+	out := make([]string, 0, len(sorted))
+	for _, v := range sorted {
+		out = append(out, prefix+v)
+	}
+	return out
+}
+
+// diffLines returns a line diff of a against b. Each line is prefixed with
+// "  " when unchanged, "- " when only in a, and "+ " when only in b.
+func diffLines(a, b []string) []string {
+	// lcs[i][j] holds the longest common subsequence length of a[i:] and b[j:].
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	out := make([]string, 0, len(a)+len(b))
+	i, j := 0, 0
+	for i < len(a) && j < len(b) {
+		switch {
+		case a[i] == b[j]:
+			out = append(out, "  "+a[i])
+			i++
+			j++
+		case lcs[i+1][j] >= lcs[i][j+1]:
+			out = append(out, "- "+a[i])
+			i++
+		default:
+			out = append(out, "+ "+b[j])
+			j++
+		}
+	}
+	for ; i < len(a); i++ {
+		out = append(out, "- "+a[i])
+	}
+	for ; j < len(b); j++ {
+		out = append(out, "+ "+b[j])
+	}
+	return out
+}
+
+func printPayloadDiff(w io.Writer, desc *cloudflare.TokenInspection, name string, expiresOn *time.Time, allowedCIDRs []string, policies []template.Policy) {
+	names := permissionNames(desc, policies)
+	diff := diffLines(inspectionLines(desc, names), payloadLines(name, expiresOn, allowedCIDRs, policies, names))
+
+	legend := "- current, + would be created"
+	if accessible {
+		legend = "removed: only in the current token, added: only in the new one"
+	}
+	fmt.Fprintf(w, "Changes against token %s (%s):\n", desc.ID, legend)
+	for _, line := range diff {
+		fmt.Fprintln(w, diffLine(line))
+	}
+}
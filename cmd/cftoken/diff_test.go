@@ -0,0 +1,77 @@
+package main
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+	"time"
+
+	"cftoken/internal/cloudflare"
+	"cftoken/internal/template"
+)
+
+func TestDiffLines(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name string
+		a, b []string
+		want []string
+	}{
+		{"equal", []string{"x", "y"}, []string{"x", "y"}, []string{"  x", "  y"}},
+		{"added", []string{"x"}, []string{"x", "y"}, []string{"  x", "+ y"}},
+		{"removed", []string{"x", "y"}, []string{"y"}, []string{"- x", "  y"}},
+		{"replaced", []string{"a", "b", "c"}, []string{"a", "B", "c"}, []string{"  a", "- b", "+ B", "  c"}},
+		{"empty", nil, nil, []string{}},
+	}
+
+	for _, tc := range tests {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			if got := diffLines(tc.a, tc.b); !reflect.DeepEqual(got, tc.want) {
+				t.Fatalf("diffLines() = %q, want %q", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestPrintPayloadDiff(t *testing.T) {
+	t.Parallel()
+
+	live := &cloudflare.TokenInspection{
+		ID:           "tok-1",
+		Name:         "dev-20240101T000000Z",
+		ExpiresOn:    "2024-01-01T08:00:00Z",
+		AllowedCIDRs: []string{"10.0.0.1/32"},
+		Policies: []cloudflare.TokenPolicyInspection{{
+			Effect:           "allow",
+			Resources:        []string{"com.cloudflare.api.account.zone.aaaa=*"},
+			PermissionGroups: []cloudflare.PermissionGroupSummary{{ID: "p1", Name: "Zone Read"}, {ID: "p2", Name: "DNS Write"}},
+		}},
+	}
+	expires := time.Date(2024, 1, 2, 8, 0, 0, 0, time.UTC)
+	policies := []template.Policy{{
+		Effect:           "allow",
+		Resources:        map[string]interface{}{"com.cloudflare.api.account.zone.aaaa": "*"},
+		PermissionGroups: []template.PermissionGroup{{ID: "p1"}},
+	}}
+
+	var buf bytes.Buffer
+	printPayloadDiff(&buf, live, "dev-20240102T000000Z", &expires, []string{"10.0.0.1/32"}, policies)
+
+	want := `Changes against token tok-1 (- current, + would be created):
+- name: dev-20240101T000000Z
+- expires: 2024-01-01T08:00:00Z
++ name: dev-20240102T000000Z
++ expires: 2024-01-02T08:00:00Z
+  allowed_cidr: 10.0.0.1/32
+  policy 1 effect: allow
+  policy 1 resource: com.cloudflare.api.account.zone.aaaa=*
+- policy 1 permission: DNS Write (p2)
+  policy 1 permission: Zone Read (p1)
+`
+	if got := buf.String(); got != want {
+		t.Fatalf("printPayloadDiff() =\n%s\nwant\n%s", got, want)
+	}
+}
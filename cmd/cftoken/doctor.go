@@ -0,0 +1,239 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io/fs"
+	"net/http"
+	"os"
+	"time"
+
+	"cftoken/internal/cache"
+	"cftoken/internal/config"
+	"cftoken/internal/template"
+)
+
+const (
+	apiBaseURL       = "https://api.cloudflare.com/client/v4"
+	maxClockSkew     = 30 * time.Second
+	doctorStatusOK   = "OK"
+	doctorStatusWarn = "WARN"
+	doctorStatusFail = "FAIL"
+)
+
+// doctorCheck is the outcome of a single health check.
+type doctorCheck struct {
+	name   string
+	status string
+	detail string
+	fix    string
+}
+
+func runDoctor(ctx context.Context, token string, verbose bool, args []string) error {
+	fset := flag.NewFlagSet("doctor", flag.ContinueOnError)
+	if err := fset.Parse(args); err != nil {
+		return err
+	}
+
+	var checks []doctorCheck
+	checks = append(checks, checkTokenPresent(token))
+	checks = append(checks, checkConfig()...)
+	checks = append(checks, checkCacheDir())
+	checks = append(checks, checkAPI(ctx)...)
+	if token != "" {
+		checks = append(checks, checkTokenVerifies(ctx, token, verbose))
+	}
+
+	failures := 0
+	for _, c := range checks {
+		fmt.Printf("[%-4s] %s", c.status, c.name)
+		if c.detail != "" {
+			fmt.Printf(": %s", c.detail)
+		}
+		fmt.Println()
+		if c.fix != "" && c.status != doctorStatusOK {
+			fmt.Printf("       fix: %s\n", c.fix)
+		}
+		if c.status == doctorStatusFail {
+			failures++
+		}
+	}
+	if failures > 0 {
+		return fmt.Errorf("doctor found %d problem(s)", failures)
+	}
+	return nil
+}
+
+// checkTokenPresent reports a missing management token with the sources
+// that were tried, as token_source may replace the keyring.
+func checkTokenPresent(token string) doctorCheck {
+	c := doctorCheck{name: "API token present"}
+	if token == "" {
+		c.status = doctorStatusFail
+		c.fix = "export CLOUDFLARE_API_TOKEN or set token_source in config.json to a token that can manage API tokens"
+		provider, err := tokenProvider()
+		if err != nil {
+			c.detail = err.Error()
+			return c
+		}
+		c.detail = "no token from " + provider.String()
+		return c
+	}
+	c.status = doctorStatusOK
+	return c
+}
+
+func checkTokenVerifies(ctx context.Context, token string, verbose bool) doctorCheck {
+	c := doctorCheck{name: "API token verifies"}
+	verification, err := newClient(token, verbose).VerifyToken(ctx)
+	if err != nil {
+		c.status = doctorStatusFail
+		c.detail = err.Error()
+		c.fix = "check that CLOUDFLARE_API_TOKEN or token_source yields a valid, unexpired token"
+		return c
+	}
+	if verification.Status != "active" {
+		c.status = doctorStatusFail
+		c.detail = fmt.Sprintf("token status is %q", verification.Status)
+		c.fix = "re-enable the token in the Cloudflare dashboard or create a new management token"
+		return c
+	}
+	c.status = doctorStatusOK
+	c.detail = fmt.Sprintf("token %s is active", verification.ID)
+	if verification.ExpiresOn != "" {
+		c.detail += ", expires " + verification.ExpiresOn
+	}
+	return c
+}
+
+// checkConfig verifies the config file parses and every zone template renders.
+func checkConfig() []doctorCheck {
+	c := doctorCheck{name: "Config file"}
+	path, err := config.DefaultPath()
+	if err != nil {
+		c.status = doctorStatusFail
+		c.detail = err.Error()
+		c.fix = "set XDG_CONFIG_HOME or HOME so the config directory can be located"
+		return []doctorCheck{c}
+	}
+
+	names, err := config.ZoneNames()
+	switch {
+	case errors.Is(err, fs.ErrNotExist):
+		c.status = doctorStatusWarn
+		c.detail = path + " not found"
+		c.fix = "create " + path + " (see examples/config.json) to configure zones and defaults"
+		return []doctorCheck{c}
+	case err != nil:
+		c.status = doctorStatusFail
+		c.detail = err.Error()
+		c.fix = "fix the JSON syntax in " + path
+		return []doctorCheck{c}
+	}
+	c.status = doctorStatusOK
+	c.detail = fmt.Sprintf("%s (%d zones)", path, len(names))
+	checks := []doctorCheck{c}
+
+	for _, name := range names {
+		zc := doctorCheck{name: fmt.Sprintf("Zone %q", name)}
+		_, zoneConfig, err := config.LoadZoneConfig(name)
+		if err != nil {
+			zc.status = doctorStatusFail
+			zc.detail = err.Error()
+			zc.fix = "fix the zone entry in " + path
+			checks = append(checks, zc)
+			continue
+		}
+		if zoneConfig == nil || (zoneConfig.TemplateFile == "" && zoneConfig.TemplateInline == "") {
+			continue
+		}
+		if _, err := template.RenderPolicies(zoneConfig.TemplateFile, zoneConfig.TemplateInline, templateVariables(zoneConfig, nil)); err != nil {
+			zc.status = doctorStatusFail
+			zc.detail = err.Error()
+			zc.fix = "fix the template or its variables; render it with -dry-run to iterate"
+		} else {
+			zc.status = doctorStatusOK
+			zc.detail = "template renders"
+		}
+		checks = append(checks, zc)
+	}
+	return checks
+}
+
+func checkCacheDir() doctorCheck {
+	c := doctorCheck{name: "Cache directory writable"}
+	dir, err := cache.Dir()
+	if err != nil {
+		c.status = doctorStatusWarn
+		c.detail = err.Error()
+		c.fix = "set XDG_CACHE_HOME or HOME"
+		return c
+	}
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		c.status = doctorStatusWarn
+		c.detail = err.Error()
+		c.fix = "make " + dir + " writable or point XDG_CACHE_HOME elsewhere"
+		return c
+	}
+	f, err := os.CreateTemp(dir, ".doctor-*")
+	if err != nil {
+		c.status = doctorStatusWarn
+		c.detail = err.Error()
+		c.fix = "make " + dir + " writable or point XDG_CACHE_HOME elsewhere"
+		return c
+	}
+	f.Close()
+	os.Remove(f.Name())
+	c.status = doctorStatusOK
+	c.detail = dir
+	return c
+}
+
+// checkAPI confirms the API is reachable and compares the server clock with ours.
+func checkAPI(ctx context.Context) []doctorCheck {
+	reach := doctorCheck{name: "Network reachability"}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, apiBaseURL+"/user/tokens/verify", nil)
+	if err != nil {
+		reach.status = doctorStatusFail
+		reach.detail = err.Error()
+		return []doctorCheck{reach}
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		reach.status = doctorStatusFail
+		reach.detail = err.Error()
+		reach.fix = "check DNS, proxy settings (HTTPS_PROXY), and firewall rules for api.cloudflare.com:443"
+		return []doctorCheck{reach}
+	}
+	resp.Body.Close()
+	reach.status = doctorStatusOK
+	reach.detail = "api.cloudflare.com answered " + resp.Status
+
+	return []doctorCheck{reach, checkClockSkew(resp.Header.Get("Date"), time.Now())}
+}
+
+func checkClockSkew(serverDate string, now time.Time) doctorCheck {
+	c := doctorCheck{name: "Clock skew"}
+	serverTime, err := http.ParseTime(serverDate)
+	if err != nil {
+		c.status = doctorStatusWarn
+		c.detail = "server did not return a usable Date header"
+		return c
+	}
+	skew := now.Sub(serverTime)
+	if skew < 0 {
+		skew = -skew
+	}
+	skew = skew.Truncate(time.Second)
+	if skew > maxClockSkew {
+		c.status = doctorStatusFail
+		c.detail = fmt.Sprintf("local clock differs from Cloudflare by %s", skew)
+		c.fix = "enable NTP time synchronisation; token expiry and not-before times depend on it"
+		return c
+	}
+	c.status = doctorStatusOK
+	c.detail = fmt.Sprintf("within %s", maxClockSkew)
+	return c
+}
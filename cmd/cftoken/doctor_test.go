@@ -0,0 +1,113 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCheckClockSkew(t *testing.T) {
+	t.Parallel()
+
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name   string
+		date   string
+		status string
+	}{
+		{"in sync", "Wed, 01 May 2024 12:00:05 GMT", doctorStatusOK},
+		{"behind", "Wed, 01 May 2024 11:58:00 GMT", doctorStatusFail},
+		{"ahead", "Wed, 01 May 2024 12:01:00 GMT", doctorStatusFail},
+		{"missing header", "", doctorStatusWarn},
+	}
+
+	for _, tc := range tests {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			if got := checkClockSkew(tc.date, now); got.status != tc.status {
+				t.Fatalf("checkClockSkew(%q) status = %s, want %s (%s)", tc.date, got.status, tc.status, got.detail)
+			}
+		})
+	}
+}
+
+func TestCheckConfigTemplates(t *testing.T) {
+	tmp := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", tmp)
+	writeConfig(t, tmp, `{
+  "zones": {
+    "simple": "aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa",
+    "good": {
+      "zone_id": "bbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbb",
+      "template_inline": "[{\"effect\": \"allow\", \"resources\": {\"com.cloudflare.api.account.zone.{{ .ZoneID }}\": \"*\"}, \"permission_groups\": [{\"id\": \"x\"}]}]"
+    },
+    "broken": {
+      "zone_id": "cccccccccccccccccccccccccccccccc",
+      "template_inline": "[{ not json }]"
+    }
+  }
+}`)
+
+	checks := checkConfig()
+	got := make(map[string]string, len(checks))
+	for _, c := range checks {
+		got[c.name] = c.status
+	}
+
+	want := map[string]string{
+		"Config file":   doctorStatusOK,
+		`Zone "good"`:   doctorStatusOK,
+		`Zone "broken"`: doctorStatusFail,
+	}
+	for name, status := range want {
+		if got[name] != status {
+			t.Errorf("check %s status = %q, want %q", name, got[name], status)
+		}
+	}
+	if _, ok := got[`Zone "simple"`]; ok {
+		t.Errorf("simple zone without template should not be reported")
+	}
+}
+
+func TestCheckConfigMissing(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+
+	checks := checkConfig()
+	if len(checks) != 1 || checks[0].status != doctorStatusWarn {
+		t.Fatalf("checkConfig() = %+v, want single warning", checks)
+	}
+}
+
+func TestCheckTokenPresent(t *testing.T) {
+	root := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", root)
+	missing := filepath.Join(root, "missing-token")
+	writeConfig(t, root, `{"token_source": "file:`+filepath.ToSlash(missing)+`"}`)
+
+	c := checkTokenPresent("")
+	if c.status != doctorStatusFail || !strings.Contains(c.detail, "environment variable CLOUDFLARE_API_TOKEN, then file "+filepath.ToSlash(missing)) || !strings.Contains(c.fix, "token_source") {
+		t.Fatalf("checkTokenPresent() = %+v, want the token_source chain reported", c)
+	}
+
+	writeConfig(t, root, `{"token_source": "nope"}`)
+	if c := checkTokenPresent(""); c.status != doctorStatusFail || !strings.Contains(c.detail, "nope") {
+		t.Fatalf("checkTokenPresent() with a broken token_source = %+v, want its error", c)
+	}
+	if c := checkTokenPresent("tok"); c.status != doctorStatusOK {
+		t.Fatalf("checkTokenPresent(tok) = %+v, want ok", c)
+	}
+}
+
+func writeConfig(t *testing.T, root, contents string) {
+	t.Helper()
+	dir := filepath.Join(root, "cftoken")
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		t.Fatalf("mkdir %s: %v", dir, err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "config.json"), []byte(contents), 0o600); err != nil {
+		t.Fatalf("write config: %v", err)
+	}
+}
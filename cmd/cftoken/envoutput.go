@@ -0,0 +1,115 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"strconv"
+	"strings"
+
+	"cftoken/internal/cloudflare"
+)
+
+// Formats of -output that print a created token as variable assignments
+// instead of the text report: shell for eval "$(cftoken ...)", dotenv for
+// .env files, and gitlab-dotenv for GitLab CI's artifacts:reports:dotenv.
+const (
+	outputShell        = "shell"
+	outputDotenv       = "dotenv"
+	outputGitLabDotenv = "gitlab-dotenv"
+)
+
+// envOutput reports whether -output asks for variable assignments.
+func envOutput() bool {
+	return outputFormat == outputShell || outputFormat == outputDotenv || outputFormat == outputGitLabDotenv
+}
+
+// singleTokenOutput refuses variable assignments and value output for a run that
+// creates n tokens, since every token would set the same variables or the
+// values could not be told apart.
+func singleTokenOutput(n int) error {
+	if (envOutput() || outputFormat == outputValue) && n > 1 {
+		return withCode(codeInvalidArgument, fmt.Errorf("-output %s prints a single token, but this run creates %d tokens", outputFormat, n), nil)
+	}
+	return nil
+}
+
+// writeTokenEnv writes the variables describing result as shell exports or
+// dotenv lines, every value quoted so that no token, name, or zone can
+// break out of its assignment. A value print_token_values withholds, or
+// one delivered to a sink, is left unset and noted in a comment. GitLab's
+// format is written by writeGitLabDotenv.
+func writeTokenEnv(w io.Writer, result *cloudflare.TokenResult, zoneName, format string) {
+	if format == outputGitLabDotenv {
+		writeGitLabDotenv(w, result, zoneName)
+		return
+	}
+	quote, prefix := dotenvQuote, ""
+	if format == outputShell {
+		quote, prefix = shellQuote, "export "
+	}
+	if value := displayedValue(result.Value); strings.HasPrefix(value, "<") {
+		fmt.Fprintf(w, "# CLOUDFLARE_API_TOKEN is not set: %s\n", strconv.Quote(value))
+	} else {
+		fmt.Fprintf(w, "%sCLOUDFLARE_API_TOKEN=%s\n", prefix, quote(value))
+	}
+	for _, v := range tokenEnvVars(result, zoneName) {
+		fmt.Fprintf(w, "%s%s=%s\n", prefix, v.name, quote(v.value))
+	}
+}
+
+type envVar struct{ name, value string }
+
+// tokenEnvVars lists the variables besides CLOUDFLARE_API_TOKEN that
+// describe result.
+func tokenEnvVars(result *cloudflare.TokenResult, zoneName string) []envVar {
+	return []envVar{
+		{"CFTOKEN_TOKEN_ID", result.ID},
+		{"CFTOKEN_TOKEN_NAME", result.Name},
+		{"CFTOKEN_ZONE", zoneName},
+		{"CFTOKEN_ZONE_ID", result.ZoneID},
+		{"CFTOKEN_EXPIRES_ON", result.ExpiresOn},
+	}
+}
+
+// writeGitLabDotenv writes result in the format GitLab reads from an
+// artifacts:reports:dotenv file. GitLab takes values literally, without
+// unquoting or escapes, and allows neither comments nor multiline values,
+// so values are written as they are; a variable that cannot be written
+// that way, or a value that is withheld, is left out with a warning on
+// stderr.
+func writeGitLabDotenv(w io.Writer, result *cloudflare.TokenResult, zoneName string) {
+	vars := tokenEnvVars(result, zoneName)
+	if value := displayedValue(result.Value); strings.HasPrefix(value, "<") {
+		log.Printf("warning: CLOUDFLARE_API_TOKEN is not set: %s", strconv.Quote(value))
+	} else {
+		vars = append([]envVar{{"CLOUDFLARE_API_TOKEN", value}}, vars...)
+	}
+	for _, v := range vars {
+		if strings.ContainsAny(v.value, "\r\n") {
+			log.Printf("warning: %s is not set: GitLab dotenv values cannot span lines", v.name)
+			continue
+		}
+		fmt.Fprintf(w, "%s=%s\n", v.name, v.value)
+	}
+}
+
+// shellQuote quotes s for POSIX shells. Nothing is special inside single
+// quotes, so only a single quote itself needs care: it closes the quote,
+// adds an escaped quote, and opens a new one.
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// dotenvEscaper escapes what double-quoted dotenv values interpret.
+var dotenvEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`, "\r", `\r`, "$", `\$`, "`", "\\`")
+
+// dotenvQuote quotes s for .env files. Single quotes keep the value
+// literal in the common parsers, including against $ expansion; values
+// holding a single quote or line break use escaped double quotes instead.
+func dotenvQuote(s string) string {
+	if !strings.ContainsAny(s, "'\n\r") {
+		return "'" + s + "'"
+	}
+	return `"` + dotenvEscaper.Replace(s) + `"`
+}
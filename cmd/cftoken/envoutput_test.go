@@ -0,0 +1,122 @@
+package main
+
+import (
+	"bytes"
+	"os/exec"
+	"strings"
+	"testing"
+
+	"cftoken/internal/cloudflare"
+)
+
+// hostile holds values that would run commands or split assignments if
+// they were not quoted.
+var hostile = []string{
+	"plain",
+	"it's",
+	`"$(touch pwned)"`,
+	"`id`; rm -rf /",
+	"$HOME ${PATH}",
+	"line\nbreak",
+	`back\slash\'`,
+	"",
+}
+
+func TestShellQuoteRoundTrips(t *testing.T) {
+	sh, err := exec.LookPath("sh")
+	if err != nil {
+		t.Skip("no sh to evaluate the output with")
+	}
+	for _, value := range hostile {
+		out, err := exec.Command(sh, "-c", "v="+shellQuote(value)+`; printf '%s' "$v"`).Output()
+		if err != nil {
+			t.Errorf("sh rejected shellQuote(%q) = %s: %v", value, shellQuote(value), err)
+			continue
+		}
+		if string(out) != value {
+			t.Errorf("sh read shellQuote(%q) = %s as %q", value, shellQuote(value), out)
+		}
+	}
+}
+
+func TestDotenvQuote(t *testing.T) {
+	tests := map[string]string{
+		"plain":            `'plain'`,
+		`"$(touch pwned)"`: `'"$(touch pwned)"'`,
+		"it's":             `"it's"`,
+		"it's $HOME":       `"it's \$HOME"`,
+		"line\nbreak":      `"line\nbreak"`,
+		"a'\"\\`b":         "\"a'\\\"\\\\\\`b\"",
+		"":                 `''`,
+	}
+	for in, want := range tests {
+		if got := dotenvQuote(in); got != want {
+			t.Errorf("dotenvQuote(%q) = %s, want %s", in, got, want)
+		}
+	}
+}
+
+func TestWriteTokenEnv(t *testing.T) {
+	root := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", root)
+
+	result := &cloudflare.TokenResult{ID: "t1", Name: "ci-'deploy'", Value: "se'cret", ZoneID: "z1"}
+	var buf bytes.Buffer
+	writeTokenEnv(&buf, result, "ex$ample.com", outputShell)
+	want := `export CLOUDFLARE_API_TOKEN='se'\''cret'
+export CFTOKEN_TOKEN_ID='t1'
+export CFTOKEN_TOKEN_NAME='ci-'\''deploy'\'''
+export CFTOKEN_ZONE='ex$ample.com'
+export CFTOKEN_ZONE_ID='z1'
+export CFTOKEN_EXPIRES_ON=''
+`
+	if buf.String() != want {
+		t.Errorf("shell output =\n%s\nwant\n%s", buf.String(), want)
+	}
+
+	if sh, err := exec.LookPath("sh"); err == nil {
+		script := buf.String() + `printf '%s|%s|%s' "$CLOUDFLARE_API_TOKEN" "$CFTOKEN_TOKEN_NAME" "$CFTOKEN_ZONE"`
+		out, err := exec.Command(sh, "-c", script).Output()
+		if err != nil || string(out) != "se'cret|ci-'deploy'|ex$ample.com" {
+			t.Errorf("eval of shell output = %q, %v", out, err)
+		}
+	}
+
+	delivered := *result
+	delivered.Value = "<delivered to vault\nrm -rf />"
+	buf.Reset()
+	writeTokenEnv(&buf, &delivered, "", outputDotenv)
+	first, _, _ := strings.Cut(buf.String(), "\n")
+	if first != `# CLOUDFLARE_API_TOKEN is not set: "<delivered to vault\nrm -rf />"` {
+		t.Errorf("dotenv output for a delivered value starts %q", first)
+	}
+	if !strings.Contains(buf.String(), "\nCFTOKEN_TOKEN_NAME=\"ci-'deploy'\"\n") {
+		t.Errorf("dotenv output =\n%s", buf.String())
+	}
+}
+
+func TestWriteGitLabDotenv(t *testing.T) {
+	root := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", root)
+
+	result := &cloudflare.TokenResult{ID: "t1", Name: "ci 'deploy'", Value: "se$cret", ZoneID: "z1", ExpiresOn: "2024-01-01T08:00:00Z"}
+	var buf bytes.Buffer
+	writeTokenEnv(&buf, result, "example.com\nEVIL=1", outputGitLabDotenv)
+	want := `CLOUDFLARE_API_TOKEN=se$cret
+CFTOKEN_TOKEN_ID=t1
+CFTOKEN_TOKEN_NAME=ci 'deploy'
+CFTOKEN_ZONE_ID=z1
+CFTOKEN_EXPIRES_ON=2024-01-01T08:00:00Z
+`
+	if buf.String() != want {
+		t.Errorf("gitlab-dotenv output =\n%s\nwant\n%s", buf.String(), want)
+	}
+
+	delivered := *result
+	delivered.Value = "<delivered to vault>"
+	buf.Reset()
+	writeTokenEnv(&buf, &delivered, "", outputGitLabDotenv)
+	if strings.Contains(buf.String(), "CLOUDFLARE_API_TOKEN") || strings.Contains(buf.String(), "#") {
+		t.Errorf("gitlab-dotenv output for a delivered value =\n%s", buf.String())
+	}
+}
@@ -0,0 +1,141 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+
+	cf "github.com/cloudflare/cloudflare-go/v6"
+
+	"cftoken/internal/cloudflare"
+	"cftoken/internal/journal"
+	"cftoken/internal/request"
+)
+
+// Error codes written by -output json. Orchestrators branch on them, so a
+// code must never be renamed or reused for a different failure; add new ones
+// instead.
+const (
+	codeUnknown          = "unknown"
+	codeInvalidArgument  = "invalid_argument"
+	codeMissingToken     = "missing_token"
+	codeReadOnly         = "read_only"
+	codeGuardrail        = "guardrail_violation"
+	codeBudgetExceeded   = "budget_exceeded"
+	codeZoneFrozen       = "zone_frozen"
+	codeUntrustedRequest = "untrusted_request"
+	codeAuth             = "auth_failed"
+	codeNotFound         = "not_found"
+	codeRateLimited      = "rate_limited"
+	codeAPI              = "api_error"
+	codeNetwork          = "network_error"
+	codeTimeout          = "timeout"
+	codeCanceled         = "canceled"
+	codeIncomplete       = "incomplete_operation"
+	codeApprovalDenied   = "approval_denied"
+	codeDeclined         = "declined"
+)
+
+// codedError attaches a stable code and machine-readable details to err
+// without changing its message.
+type codedError struct {
+	code    string
+	details map[string]any
+	err     error
+}
+
+func (e *codedError) Error() string { return e.err.Error() }
+func (e *codedError) Unwrap() error { return e.err }
+
+func withCode(code string, err error, details map[string]any) error {
+	return &codedError{code: code, details: details, err: err}
+}
+
+// journalError appends the rollback hint for j to err. While j is unfinished
+// the failure is reported as incomplete_operation with the journal ID, since
+// that is what a caller has to act on.
+func journalError(err error, j *journal.Journal) error {
+	err = fmt.Errorf("%w%s", err, journalHint(j))
+	if j.Completed() {
+		return err
+	}
+	return withCode(codeIncomplete, err, map[string]any{"journal_id": j.ID})
+}
+
+// errorOutput is the JSON document -output json writes for a failed run.
+type errorOutput struct {
+	Code          string         `json:"code"`
+	Message       string         `json:"message"`
+	Details       map[string]any `json:"details,omitempty"`
+	CorrelationID string         `json:"correlation_id,omitempty"`
+}
+
+// describeError maps err to its stable code. An explicit code anywhere in the
+// chain wins; otherwise the code is inferred from well-known causes.
+func describeError(err error, correlationID string) errorOutput {
+	out := errorOutput{Code: codeUnknown, Message: err.Error(), CorrelationID: correlationID}
+
+	var coded *codedError
+	if errors.As(err, &coded) {
+		out.Code = coded.code
+		out.Details = coded.details
+		return out
+	}
+
+	var apiErr *cf.Error
+	var netErr net.Error
+	switch {
+	case errors.Is(err, errMissingToken):
+		out.Code = codeMissingToken
+	case errors.Is(err, errDeclined):
+		out.Code = codeDeclined
+	case errors.Is(err, cloudflare.ErrReadOnly):
+		out.Code = codeReadOnly
+	case errors.Is(err, request.ErrUntrusted):
+		out.Code = codeUntrustedRequest
+	case errors.Is(err, context.DeadlineExceeded):
+		out.Code = codeTimeout
+	case errors.Is(err, context.Canceled):
+		out.Code = codeCanceled
+	case errors.As(err, &apiErr):
+		out.Code = apiErrorCode(apiErr.StatusCode)
+		out.Details = apiErrorDetails(apiErr)
+	case errors.As(err, &netErr):
+		out.Code = codeNetwork
+	}
+	return out
+}
+
+func apiErrorCode(status int) string {
+	switch status {
+	case http.StatusUnauthorized, http.StatusForbidden:
+		return codeAuth
+	case http.StatusNotFound:
+		return codeNotFound
+	case http.StatusTooManyRequests:
+		return codeRateLimited
+	default:
+		return codeAPI
+	}
+}
+
+func apiErrorDetails(apiErr *cf.Error) map[string]any {
+	details := map[string]any{"status": apiErr.StatusCode}
+	if len(apiErr.Errors) > 0 {
+		errs := make([]map[string]any, 0, len(apiErr.Errors))
+		for _, e := range apiErr.Errors {
+			errs = append(errs, map[string]any{"code": e.Code, "message": e.Message})
+		}
+		details["cloudflare_errors"] = errs
+	}
+	return details
+}
+
+// writeErrorJSON writes err as a single-line JSON document.
+func writeErrorJSON(w io.Writer, err error, correlationID string) error {
+	return json.NewEncoder(w).Encode(describeError(err, correlationID))
+}
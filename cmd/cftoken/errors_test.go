@@ -0,0 +1,92 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	cf "github.com/cloudflare/cloudflare-go/v6"
+	"github.com/cloudflare/cloudflare-go/v6/shared"
+
+	"cftoken/internal/cloudflare"
+	"cftoken/internal/journal"
+)
+
+func apiError(status int) error {
+	return fmt.Errorf("create token: %w", &cf.Error{
+		StatusCode: status,
+		Errors:     []shared.ErrorData{{Code: 9109, Message: "Unauthorized to access requested resource"}},
+		Request:    httptest.NewRequest(http.MethodPost, "https://api.cloudflare.com/client/v4/user/tokens", nil),
+		Response:   &http.Response{StatusCode: status},
+	})
+}
+
+func TestDescribeError(t *testing.T) {
+	t.Setenv("XDG_STATE_HOME", t.TempDir())
+	j, err := journal.Begin("create", "corr-1")
+	if err != nil {
+		t.Fatalf("journal.Begin() error = %v", err)
+	}
+
+	tests := []struct {
+		name string
+		err  error
+		code string
+	}{
+		{"missing token", errMissingToken, codeMissingToken},
+		{"declined", errDeclined, codeDeclined},
+		{"read only", fmt.Errorf("create token: %w", cloudflare.ErrReadOnly), codeReadOnly},
+		{"timeout", fmt.Errorf("list tokens: %w", context.DeadlineExceeded), codeTimeout},
+		{"canceled", context.Canceled, codeCanceled},
+		{"unauthorized", apiError(http.StatusForbidden), codeAuth},
+		{"not found", apiError(http.StatusNotFound), codeNotFound},
+		{"rate limited", apiError(http.StatusTooManyRequests), codeRateLimited},
+		{"server error", apiError(http.StatusInternalServerError), codeAPI},
+		{"network", fmt.Errorf("request: %w", &net.OpError{Op: "dial", Err: errors.New("connection refused")}), codeNetwork},
+		{"explicit code", withCode(codeGuardrail, errors.New("guardrails rejected the token"), nil), codeGuardrail},
+		{"incomplete", journalError(apiError(http.StatusInternalServerError), j), codeIncomplete},
+		{"unknown", errors.New("boom"), codeUnknown},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got := describeError(tc.err, "corr-1")
+			if got.Code != tc.code || got.Message != tc.err.Error() || got.CorrelationID != "corr-1" {
+				t.Fatalf("describeError() = %+v, want code %s", got, tc.code)
+			}
+		})
+	}
+}
+
+func TestWriteErrorJSON(t *testing.T) {
+	var buf bytes.Buffer
+	if err := writeErrorJSON(&buf, apiError(http.StatusForbidden), "corr-1"); err != nil {
+		t.Fatalf("writeErrorJSON() error = %v", err)
+	}
+
+	var got struct {
+		Code    string `json:"code"`
+		Message string `json:"message"`
+		Details struct {
+			Status           int `json:"status"`
+			CloudflareErrors []struct {
+				Code int `json:"code"`
+			} `json:"cloudflare_errors"`
+		} `json:"details"`
+		CorrelationID string `json:"correlation_id"`
+	}
+	if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+		t.Fatalf("output %q is not JSON: %v", buf.String(), err)
+	}
+	if got.Code != codeAuth || got.Message == "" || got.CorrelationID != "corr-1" {
+		t.Errorf("output = %+v", got)
+	}
+	if got.Details.Status != http.StatusForbidden || len(got.Details.CloudflareErrors) != 1 || got.Details.CloudflareErrors[0].Code != 9109 {
+		t.Errorf("details = %+v", got.Details)
+	}
+}
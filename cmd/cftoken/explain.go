@@ -0,0 +1,189 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"cftoken/internal/config"
+	"cftoken/internal/duration"
+)
+
+const sourceDefault = "default"
+
+// settingOrigin is one effective setting and where it came from: a flag,
+// an environment variable, a build tag, config.json, the zone's entry in
+// config.json, or the built-in default.
+type settingOrigin struct {
+	name   string
+	value  string
+	source string
+}
+
+// configTrace collects the origins of a run's settings for -explain-config.
+type configTrace []settingOrigin
+
+func (t *configTrace) add(name, value, source string) {
+	*t = append(*t, settingOrigin{name: name, value: value, source: source})
+}
+
+// write prints the trace as a table, headed by the config.json it read.
+func (t configTrace) write(w io.Writer, configPath string) error {
+	if _, err := os.Stat(configPath); errors.Is(err, fs.ErrNotExist) {
+		configPath += " (not found)"
+	}
+	fmt.Fprintf(w, "Config file: %s\n", configPath)
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "SETTING\tVALUE\tSOURCE")
+	for _, s := range t {
+		fmt.Fprintf(tw, "%s\t%s\t%s\n", s.name, s.value, s.source)
+	}
+	return tw.Flush()
+}
+
+// flagSource names flag as the source when it was set on the command line.
+func flagSource(set map[string]bool, name string) string {
+	if set[name] {
+		return "flag -" + name
+	}
+	return sourceDefault
+}
+
+// globalOrigins traces the settings that apply to every command. The
+// environment and build tag win over flags, as in run.
+func globalOrigins(set map[string]bool, timeout time.Duration) configTrace {
+	var t configTrace
+	outputSource := flagSource(set, "output")
+	if set["quiet"] {
+		outputSource = "flag -quiet"
+	}
+	t.add("output", outputFormat, outputSource)
+
+	readOnlySource := flagSource(set, "read-only")
+	switch {
+	case buildReadOnly:
+		readOnlySource = "build tag readonly"
+	case os.Getenv("CFTOKEN_READ_ONLY") == "1":
+		readOnlySource = "env CFTOKEN_READ_ONLY"
+	}
+	t.add("read-only", strconv.FormatBool(readOnly), readOnlySource)
+
+	accessibleSource := flagSource(set, "accessible")
+	if os.Getenv("CFTOKEN_ACCESSIBLE") == "1" {
+		accessibleSource = "env CFTOKEN_ACCESSIBLE"
+	}
+	t.add("accessible", strconv.FormatBool(accessible), accessibleSource)
+
+	t.add("timeout", timeout.String(), flagSource(set, "timeout"))
+	t.add("token-account", stringOrDefault(tokenAccount, "(user tokens)"), flagSource(set, "token-account"))
+	t.add("no-cache", strconv.FormatBool(noCache), flagSource(set, "no-cache"))
+	return t
+}
+
+// tokenOrigins are the resolved token settings traced by tokenTrace.
+type tokenOrigins struct {
+	zoneName     string
+	zoneConfig   *config.ZoneConfig
+	tokenPrefix  string
+	ttl          time.Duration
+	permissions  []string
+	usesTemplate bool
+	// configuredPermissions is default_permissions from config.json,
+	// loaded when -permissions was not given.
+	configuredPermissions []string
+	allowedCIDRs          []string
+	ipRestrictionDisabled bool
+}
+
+// tokenTrace traces the settings of the token being created, following
+// the precedence of the creation path: a zone's ttl replaces even -ttl,
+// and default_permissions replaces a zone's permissions list.
+func tokenTrace(set map[string]bool, o tokenOrigins) (configTrace, error) {
+	var t configTrace
+	zoneSource := fmt.Sprintf("zone %q in config.json", o.zoneName)
+	zc := o.zoneConfig
+	if zc == nil {
+		zc = &config.ZoneConfig{}
+	}
+
+	ttlSource := flagSource(set, "ttl")
+	switch {
+	case zc.TTL != "":
+		ttlSource = zoneSource + " (ttl)"
+	case set["ttl"]:
+	default:
+		if _, err := config.LoadDefaultTTL(); err == nil {
+			ttlSource = "config.json (default_ttl)"
+		} else if !errors.Is(err, fs.ErrNotExist) {
+			return nil, err
+		}
+	}
+	ttl := duration.Format(o.ttl)
+	if o.ttl == 0 {
+		ttl = "0 (no expiry)"
+	}
+	t.add("ttl", ttl, ttlSource)
+
+	prefixSource := flagSource(set, "token-prefix")
+	if !set["token-prefix"] {
+		prefixSource = "zone name"
+		if _, err := config.LoadTokenPrefixTemplate(); err == nil {
+			prefixSource = "config.json (default_token_prefix_template)"
+		} else if !errors.Is(err, fs.ErrNotExist) {
+			return nil, err
+		}
+	}
+	t.add("token-prefix", o.tokenPrefix, prefixSource)
+
+	switch {
+	case set["permissions"]:
+		t.add("permissions", strings.Join(o.permissions, ", "), "flag -permissions")
+	case o.usesTemplate:
+		template := zc.TemplateFile
+		if template == "" {
+			template = "(inline)"
+		}
+		t.add("permissions", "template "+template, zoneSource+" (template)")
+	case len(o.configuredPermissions) > 0:
+		t.add("permissions", strings.Join(o.permissions, ", "), "config.json (default_permissions)")
+	case len(zc.Permissions) > 0:
+		t.add("permissions", strings.Join(o.permissions, ", "), zoneSource+" (permissions)")
+	default:
+		t.add("permissions", strings.Join(o.permissions, ", "), sourceDefault)
+	}
+
+	cidrs := strings.Join(o.allowedCIDRs, ", ")
+	if o.ipRestrictionDisabled {
+		cidrs = "none (IP restriction disabled)"
+	}
+	switch {
+	case set["allow-cidrs"]:
+		t.add("allow-cidrs", cidrs, "flag -allow-cidrs")
+	case len(zc.AllowedCIDRs) > 0:
+		t.add("allow-cidrs", cidrs, zoneSource+" (allowed_cidrs)")
+	default:
+		t.add("allow-cidrs", cidrs, "config.json (default_allowed_cidrs)")
+	}
+	return t, nil
+}
+
+// explainTokenConfig writes the -explain-config trace of a token creation
+// to stderr.
+func explainTokenConfig(set map[string]bool, timeout time.Duration, o tokenOrigins) error {
+	trace := globalOrigins(set, timeout)
+	tokenSettings, err := tokenTrace(set, o)
+	if err != nil {
+		return err
+	}
+	path, err := config.DefaultPath()
+	if err != nil {
+		return err
+	}
+	return append(trace, tokenSettings...).write(os.Stderr, path)
+}
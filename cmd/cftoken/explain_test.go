@@ -0,0 +1,107 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"cftoken/internal/config"
+)
+
+func TestTokenTrace(t *testing.T) {
+	root := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", root)
+	writeConfig(t, root, `{"default_ttl": "2h", "default_permissions": ["DNS Write"], "default_allowed_cidrs": ["10.0.0.0/8"]}`)
+
+	tests := []struct {
+		name string
+		set  []string
+		o    tokenOrigins
+		want map[string]string
+	}{
+		{
+			name: "config defaults",
+			o:    tokenOrigins{zoneName: "prod", tokenPrefix: "prod", ttl: 2 * time.Hour, permissions: []string{"DNS Write"}, configuredPermissions: []string{"DNS Write"}, allowedCIDRs: []string{"10.0.0.0/8"}},
+			want: map[string]string{
+				"ttl":          "config.json (default_ttl)",
+				"token-prefix": "zone name",
+				"permissions":  "config.json (default_permissions)",
+				"allow-cidrs":  "config.json (default_allowed_cidrs)",
+			},
+		},
+		{
+			name: "zone config beats -ttl",
+			set:  []string{"ttl", "token-prefix", "allow-cidrs"},
+			o: tokenOrigins{
+				zoneName:     "prod",
+				zoneConfig:   &config.ZoneConfig{TTL: "8h", TemplateFile: "prod.json", AllowedCIDRs: []string{"192.0.2.0/24"}},
+				tokenPrefix:  "ci",
+				ttl:          8 * time.Hour,
+				usesTemplate: true,
+				allowedCIDRs: []string{"198.51.100.0/24"},
+			},
+			want: map[string]string{
+				"ttl":          `zone "prod" in config.json (ttl)`,
+				"token-prefix": "flag -token-prefix",
+				"permissions":  `zone "prod" in config.json (template)`,
+				"allow-cidrs":  "flag -allow-cidrs",
+			},
+		},
+		{
+			name: "zone lists",
+			set:  []string{"ttl"},
+			o:    tokenOrigins{zoneName: "prod", zoneConfig: &config.ZoneConfig{Permissions: []string{"Zone Read"}, AllowedCIDRs: []string{"192.0.2.0/24"}}, ttl: 0, permissions: []string{"Zone Read"}, allowedCIDRs: []string{"192.0.2.0/24"}},
+			want: map[string]string{
+				"ttl":         "flag -ttl",
+				"permissions": `zone "prod" in config.json (permissions)`,
+				"allow-cidrs": `zone "prod" in config.json (allowed_cidrs)`,
+			},
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			set := make(map[string]bool)
+			for _, name := range tc.set {
+				set[name] = true
+			}
+			trace, err := tokenTrace(set, tc.o)
+			if err != nil {
+				t.Fatalf("tokenTrace() error = %v", err)
+			}
+			got := make(map[string]string)
+			for _, s := range trace {
+				got[s.name] = s.source
+			}
+			for name, want := range tc.want {
+				if got[name] != want {
+					t.Errorf("%s source = %q, want %q", name, got[name], want)
+				}
+			}
+		})
+	}
+}
+
+func TestGlobalOriginsEnvironment(t *testing.T) {
+	t.Setenv("CFTOKEN_ACCESSIBLE", "1")
+	accessible = true
+	t.Cleanup(func() { accessible = false })
+
+	trace := globalOrigins(map[string]bool{"quiet": true, "timeout": true}, time.Minute)
+	var buf bytes.Buffer
+	if err := trace.write(&buf, "/nonexistent/cftoken/config.json"); err != nil {
+		t.Fatalf("write() error = %v", err)
+	}
+	out := buf.String()
+	for _, want := range []string{
+		"Config file: /nonexistent/cftoken/config.json (not found)",
+		"accessible     true           env CFTOKEN_ACCESSIBLE",
+		"timeout        1m0s           flag -timeout",
+		"output         text           flag -quiet",
+		"token-account  (user tokens)  default",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("trace missing %q:\n%s", want, out)
+		}
+	}
+}
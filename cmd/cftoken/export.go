@@ -0,0 +1,239 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"cftoken/internal/cloudflare"
+	"cftoken/internal/template"
+)
+
+// exportFormats lists the formats export renders tokens in.
+var exportFormats = []string{"terraform"}
+
+// exportOptions are the flags of the export command.
+type exportOptions struct {
+	format  string
+	tokenID string
+}
+
+// parseExportFlags parses the flags of the export command. The flags of
+// token creation describe the token to render, so they are defined on the
+// same set and stored in f; the set is returned so the creation path can
+// tell which were given.
+func parseExportFlags(f *runFlags, args []string) (exportOptions, *flag.FlagSet, error) {
+	var opts exportOptions
+	fset := flag.NewFlagSet("export", flag.ContinueOnError)
+	fset.StringVar(&opts.format, "format", "terraform", "Format to render the token in: "+strings.Join(exportFormats, ", "))
+	fset.StringVar(&opts.tokenID, "token-id", "", "Render this existing token instead of the one the flags of token creation describe")
+	f.registerCreate(fset)
+	if err := fset.Parse(args); err != nil {
+		return opts, nil, withCode(codeInvalidArgument, err, nil)
+	}
+	if fset.NArg() > 0 {
+		return opts, nil, withCode(codeInvalidArgument, errors.New("usage: export [-format terraform] [-token-id ID | token creation flags]"), nil)
+	}
+	if opts.format != "terraform" {
+		return opts, nil, withCode(codeInvalidArgument, fmt.Errorf("unknown -format %q; available: %s", opts.format, strings.Join(exportFormats, ", ")), nil)
+	}
+	opts.tokenID = strings.TrimSpace(opts.tokenID)
+	if opts.tokenID != "" {
+		var describing []string
+		fset.Visit(func(fl *flag.Flag) {
+			if fl.Name != "format" && fl.Name != "token-id" {
+				describing = append(describing, "-"+fl.Name)
+			}
+		})
+		if len(describing) > 0 {
+			return opts, nil, withCode(codeInvalidArgument, fmt.Errorf("-token-id renders an existing token; it cannot be combined with %s", strings.Join(describing, ", ")), nil)
+		}
+	}
+	return opts, fset, nil
+}
+
+// runExport renders an existing token as a Terraform resource on stdout.
+// The token the flags of token creation describe is rendered by the creation path
+// instead, in place of its dry-run preview.
+func runExport(ctx context.Context, client *cloudflare.Client, opts exportOptions) error {
+	desc, err := client.DescribeToken(ctx, opts.tokenID)
+	if err != nil {
+		return fmt.Errorf("fetch token %s: %w", opts.tokenID, err)
+	}
+	tf := terraformToken{
+		name:      desc.Name,
+		expiresOn: desc.ExpiresOn,
+		conditions: conditionValues(
+			cloudflare.Condition{Type: "request_ip", Operator: "in", Values: desc.AllowedCIDRs},
+			cloudflare.Condition{Type: "request_ip", Operator: "not_in", Values: desc.DeniedCIDRs},
+		),
+	}
+	for _, pol := range desc.Policies {
+		if len(pol.ResourceMap) == 0 {
+			return fmt.Errorf("token %s has a policy whose resources cannot be read", opts.tokenID)
+		}
+		policy := template.Policy{Effect: pol.Effect, Resources: pol.ResourceMap}
+		for _, pg := range pol.PermissionGroups {
+			policy.PermissionGroups = append(policy.PermissionGroups, template.PermissionGroup{ID: pg.ID, Name: pg.Name})
+		}
+		tf.policies = append(tf.policies, policy)
+	}
+	return writeTerraform(os.Stdout, tf)
+}
+
+// terraformToken is a token as writeTerraform renders it.
+type terraformToken struct {
+	name string
+	// expiresOn is RFC 3339, or empty for a token that does not expire.
+	expiresOn  string
+	conditions map[string]map[string][]string
+	policies   []template.Policy
+}
+
+// plannedTerraformToken describes the token the creation path would create.
+func plannedTerraformToken(name string, expiresOn *time.Time, allowedCIDRs []string, conditions []cloudflare.Condition, policies []template.Policy) terraformToken {
+	tf := terraformToken{
+		name:       name,
+		conditions: conditionValues(append([]cloudflare.Condition{{Type: "request_ip", Operator: "in", Values: allowedCIDRs}}, conditions...)...),
+		policies:   policies,
+	}
+	if expiresOn != nil {
+		tf.expiresOn = expiresOn.UTC().Format(time.RFC3339)
+	}
+	return tf
+}
+
+// conditionValues groups the values of conditions by type and operator,
+// leaving out conditions without values.
+func conditionValues(conditions ...cloudflare.Condition) map[string]map[string][]string {
+	out := make(map[string]map[string][]string)
+	for _, c := range conditions {
+		if len(c.Values) == 0 {
+			continue
+		}
+		if out[c.Type] == nil {
+			out[c.Type] = make(map[string][]string)
+		}
+		out[c.Type][c.Operator] = append(out[c.Type][c.Operator], c.Values...)
+	}
+	return out
+}
+
+var terraformLabelInvalid = regexp.MustCompile(`[^A-Za-z0-9_-]+`)
+
+// terraformLabel turns a token name into a resource name Terraform accepts:
+// letters, digits, underscores, and dashes, not starting with a digit or
+// dash.
+func terraformLabel(name string) string {
+	label := strings.Trim(terraformLabelInvalid.ReplaceAllString(name, "_"), "_")
+	if label == "" || !(label[0] == '_' || label[0] >= 'A' && label[0] <= 'Z' || label[0] >= 'a' && label[0] <= 'z') {
+		label = "token_" + label
+	}
+	return label
+}
+
+// hclString quotes s as an HCL string literal. Go's escapes are valid in
+// HCL; template sequences are escaped so ${ and %{ stay literal.
+func hclString(s string) string {
+	return strings.NewReplacer("${", "$${", "%{", "%%{").Replace(strconv.Quote(s))
+}
+
+func hclList(values []string) string {
+	quoted := make([]string, len(values))
+	for i, v := range values {
+		quoted[i] = hclString(v)
+	}
+	return "[" + strings.Join(quoted, ", ") + "]"
+}
+
+// writeTerraform writes tf as a cloudflare_api_token resource in the
+// schema of version 5 of the Cloudflare provider.
+func writeTerraform(w io.Writer, tf terraformToken) error {
+	fmt.Fprintf(w, "resource \"cloudflare_api_token\" %s {\n", hclString(terraformLabel(tf.name)))
+	fmt.Fprintf(w, "  name = %s\n\n", hclString(tf.name))
+	fmt.Fprintln(w, "  policies = [")
+	for _, policy := range tf.policies {
+		fmt.Fprintln(w, "    {")
+		fmt.Fprintf(w, "      effect = %s\n", hclString(stringOrDefault(policy.Effect, "allow")))
+		fmt.Fprintln(w, "      permission_groups = [")
+		for _, pg := range policy.PermissionGroups {
+			fmt.Fprintf(w, "        { id = %s },", hclString(pg.ID))
+			if pg.Name != "" {
+				fmt.Fprintf(w, " # %s", pg.Name)
+			}
+			fmt.Fprintln(w)
+		}
+		fmt.Fprintln(w, "      ]")
+		fmt.Fprintln(w, "      resources = {")
+		for _, key := range sortedKeys(policy.Resources) {
+			value, err := hclResourceValue(policy.Resources[key])
+			if err != nil {
+				return fmt.Errorf("resource %s: %w", key, err)
+			}
+			fmt.Fprintf(w, "        %s = %s\n", hclString(key), value)
+		}
+		fmt.Fprintln(w, "      }")
+		fmt.Fprintln(w, "    },")
+	}
+	fmt.Fprintln(w, "  ]")
+	if len(tf.conditions) > 0 {
+		fmt.Fprintln(w, "\n  condition = {")
+		for _, typ := range sortedKeys(tf.conditions) {
+			fmt.Fprintf(w, "    %s = {\n", typ)
+			for _, op := range sortedKeys(tf.conditions[typ]) {
+				fmt.Fprintf(w, "      %s = %s\n", op, hclList(tf.conditions[typ][op]))
+			}
+			fmt.Fprintln(w, "    }")
+		}
+		fmt.Fprintln(w, "  }")
+	}
+	if tf.expiresOn != "" {
+		fmt.Fprintf(w, "\n  expires_on = %s\n", hclString(tf.expiresOn))
+	}
+	_, err := fmt.Fprintln(w, "}")
+	return err
+}
+
+// hclResourceValue renders a policy resource value: a string, or the
+// objects and lists of any depth finer-grained scopes use, such as the
+// zones nested under an account.
+func hclResourceValue(value any) (string, error) {
+	switch v := value.(type) {
+	case string:
+		return hclString(v), nil
+	case bool, float64:
+		return fmt.Sprint(v), nil
+	case map[string]any:
+		if len(v) == 0 {
+			return "{}", nil
+		}
+		parts := make([]string, 0, len(v))
+		for _, key := range sortedKeys(v) {
+			s, err := hclResourceValue(v[key])
+			if err != nil {
+				return "", err
+			}
+			parts = append(parts, hclString(key)+" = "+s)
+		}
+		return "{ " + strings.Join(parts, ", ") + " }", nil
+	case []any:
+		parts := make([]string, 0, len(v))
+		for _, item := range v {
+			s, err := hclResourceValue(item)
+			if err != nil {
+				return "", err
+			}
+			parts = append(parts, s)
+		}
+		return "[" + strings.Join(parts, ", ") + "]", nil
+	default:
+		return "", fmt.Errorf("unsupported value %v", value)
+	}
+}
@@ -0,0 +1,142 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"cftoken/internal/cloudflare"
+	"cftoken/internal/template"
+)
+
+func TestWriteTerraform(t *testing.T) {
+	t.Parallel()
+
+	expires := time.Date(2024, 1, 2, 11, 4, 5, 0, time.FixedZone("CET", 3600))
+	policies := []template.Policy{
+		{Effect: "allow", Resources: map[string]interface{}{"com.cloudflare.api.account.zone.z1": "*"}, PermissionGroups: []template.PermissionGroup{{ID: "g1", Name: "DNS Write"}, {ID: "g2"}}},
+		{Effect: "deny", Resources: map[string]interface{}{"com.cloudflare.api.account.a1": map[string]interface{}{"com.cloudflare.api.account.zone.*": "*"}}, PermissionGroups: []template.PermissionGroup{{ID: "g3"}}},
+	}
+	conditions := []cloudflare.Condition{{Type: "request_ip", Operator: "not_in", Values: []string{"10.9.0.0/16"}}}
+	tf := plannedTerraformToken("prod ${x}-20240102T100405Z", &expires, []string{"10.0.0.0/8"}, conditions, policies)
+
+	var buf bytes.Buffer
+	if err := writeTerraform(&buf, tf); err != nil {
+		t.Fatalf("writeTerraform() error = %v", err)
+	}
+	want := `resource "cloudflare_api_token" "prod_x_-20240102T100405Z" {
+  name = "prod $${x}-20240102T100405Z"
+
+  policies = [
+    {
+      effect = "allow"
+      permission_groups = [
+        { id = "g1" }, # DNS Write
+        { id = "g2" },
+      ]
+      resources = {
+        "com.cloudflare.api.account.zone.z1" = "*"
+      }
+    },
+    {
+      effect = "deny"
+      permission_groups = [
+        { id = "g3" },
+      ]
+      resources = {
+        "com.cloudflare.api.account.a1" = { "com.cloudflare.api.account.zone.*" = "*" }
+      }
+    },
+  ]
+
+  condition = {
+    request_ip = {
+      in = ["10.0.0.0/8"]
+      not_in = ["10.9.0.0/16"]
+    }
+  }
+
+  expires_on = "2024-01-02T10:04:05Z"
+}
+`
+	if got := buf.String(); got != want {
+		t.Errorf("writeTerraform() =\n%s\nwant\n%s", got, want)
+	}
+
+	buf.Reset()
+	if err := writeTerraform(&buf, terraformToken{name: "forever", policies: policies[:1]}); err != nil {
+		t.Fatalf("writeTerraform() error = %v", err)
+	}
+	if strings.Contains(buf.String(), "condition") || strings.Contains(buf.String(), "expires_on") {
+		t.Errorf("token without conditions or expiry =\n%s", buf.String())
+	}
+}
+
+func TestTerraformLabel(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]string{
+		"prod-20240102T030405Z": "prod-20240102T030405Z",
+		"edge api (ci)":         "edge_api_ci",
+		"2024-ci":               "token_2024-ci",
+		"-ci":                   "token_-ci",
+		"***":                   "token_",
+	}
+	for in, want := range tests {
+		if got := terraformLabel(in); got != want {
+			t.Errorf("terraformLabel(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestParseExportFlags(t *testing.T) {
+	t.Parallel()
+
+	parse := func(args ...string) (exportOptions, *runFlags, error) {
+		f := &runFlags{templateVars: &varFlag{}}
+		opts, _, err := parseExportFlags(f, args)
+		return opts, f, err
+	}
+	opts, _, err := parse("-token-id", " t1 ")
+	if err != nil || opts.format != "terraform" || opts.tokenID != "t1" {
+		t.Errorf("parseExportFlags() = %+v, %v", opts, err)
+	}
+	if _, f, err := parse("-zone", "prod", "-ttl", "2h"); err != nil || f.zoneName != "prod" || f.ttl != 2*time.Hour {
+		t.Errorf("parseExportFlags(-zone prod -ttl 2h) = %+v, %v", f, err)
+	}
+	if _, _, err := parse("-format", "pulumi"); err == nil || !strings.Contains(err.Error(), "available: terraform") {
+		t.Errorf("parseExportFlags(-format pulumi) error = %v", err)
+	}
+	if _, _, err := parse("t1"); err == nil {
+		t.Errorf("parseExportFlags(t1) error = nil, want usage error")
+	}
+	if _, _, err := parse("-token-id", "t1", "-zone", "prod"); err == nil || !strings.Contains(err.Error(), "-zone") {
+		t.Errorf("parseExportFlags(-token-id t1 -zone prod) error = %v, want a conflict", err)
+	}
+	if _, _, err := parse("-list-zones"); err == nil {
+		t.Errorf("parseExportFlags(-list-zones) error = nil, want unknown flag")
+	}
+}
+
+func TestHCLResourceValue(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		in   any
+		want string
+	}{
+		{"*", `"*"`},
+		{map[string]any{"b": "*", "a": map[string]any{}}, `{ "a" = {}, "b" = "*" }`},
+		{map[string]any{"com.cloudflare.api.dns.record.*": []any{"r1", map[string]any{"id": "r2"}, 2.5, true}}, `{ "com.cloudflare.api.dns.record.*" = ["r1", { "id" = "r2" }, 2.5, true] }`},
+	}
+	for _, tc := range tests {
+		got, err := hclResourceValue(tc.in)
+		if err != nil || got != tc.want {
+			t.Errorf("hclResourceValue(%v) = %s, %v, want %s", tc.in, got, err, tc.want)
+		}
+	}
+	if _, err := hclResourceValue(nil); err == nil {
+		t.Errorf("hclResourceValue(nil) error = nil")
+	}
+}
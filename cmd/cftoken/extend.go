@@ -0,0 +1,89 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"strings"
+	"time"
+
+	"cftoken/internal/clock"
+	"cftoken/internal/cloudflare"
+	"cftoken/internal/duration"
+	"cftoken/internal/guardrail"
+)
+
+// runExtend moves the expiry of an existing token forward by a TTL. The
+// token keeps its value, so nothing has to be redistributed.
+func runExtend(ctx context.Context, client *cloudflare.Client, args []string) error {
+	fset := flag.NewFlagSet("extend", flag.ContinueOnError)
+	ttl, err := tokenDefaultTTL()
+	if err != nil {
+		return err
+	}
+	fset.Var((*duration.Value)(&ttl), "ttl", "How far to push the expiry forward, e.g. 8h or 2d")
+	dryRun := fset.Bool("dry-run", false, "Show the new expiry without updating the token")
+	if err := fset.Parse(args); err != nil {
+		return err
+	}
+	if fset.NArg() != 1 {
+		return withCode(codeInvalidArgument, errors.New("extend takes exactly one token ID or name"), nil)
+	}
+
+	tokens, err := client.ListTokens(ctx)
+	if err != nil {
+		return err
+	}
+	selected, err := selectTokenRefs(tokens, fset.Args(), "")
+	if err != nil {
+		return withCode(codeNotFound, err, map[string]any{"token": fset.Arg(0)})
+	}
+	token := selected[0]
+	now := clock.Now().UTC()
+	expiresOn, err := extendedExpiry(token.ExpiresOn, ttl, now)
+	if err != nil {
+		return withCode(codeInvalidArgument, fmt.Errorf("token %s: %w", token.ID, err), nil)
+	}
+
+	rules, err := loadGuardrails(nil)
+	if err != nil {
+		return err
+	}
+	if violations := rules.Evaluate(guardrail.Request{
+		TTL:          expiresOn.Sub(now),
+		AllowedCIDRs: token.AllowedCIDRs,
+		Ticket:       changeTicket,
+	}); len(violations) > 0 {
+		err := fmt.Errorf("guardrails rejected extending token %q:\n  - %s", token.Name, strings.Join(violations, "\n  - "))
+		return withCode(codeGuardrail, err, map[string]any{"token": token.Name, "violations": violations})
+	}
+
+	fmt.Printf("%s (%s): expires %s -> %s\n", token.Name, token.ID, token.ExpiresOn.UTC().Format(time.RFC3339), expiresOn.Format(time.RFC3339))
+	if *dryRun {
+		fmt.Println("DRY RUN: the token was not updated.")
+		return nil
+	}
+	desc, err := client.UpdateToken(ctx, token.ID, cloudflare.WithExpiry(expiresOn))
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Token extended; it now expires %s.\n", stringOrDefault(desc.ExpiresOn, expiresOn.Format(time.RFC3339)))
+	return nil
+}
+
+// extendedExpiry returns expiresOn pushed forward by ttl. A token that has
+// already expired is extended from now instead, and one without an expiry
+// cannot be extended.
+func extendedExpiry(expiresOn time.Time, ttl time.Duration, now time.Time) (time.Time, error) {
+	if expiresOn.IsZero() {
+		return time.Time{}, errors.New("the token never expires; there is nothing to extend")
+	}
+	if ttl <= 0 {
+		return time.Time{}, errors.New("-ttl must be positive")
+	}
+	if expiresOn.Before(now) {
+		expiresOn = now
+	}
+	return expiresOn.Add(ttl).UTC().Truncate(time.Second), nil
+}
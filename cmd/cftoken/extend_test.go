@@ -0,0 +1,34 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestExtendedExpiry(t *testing.T) {
+	t.Parallel()
+
+	now := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name      string
+		expiresOn time.Time
+		ttl       time.Duration
+		want      time.Time
+		wantErr   bool
+	}{
+		{"from current expiry", now.Add(2 * time.Hour), 8 * time.Hour, now.Add(10 * time.Hour), false},
+		{"expired counts from now", now.Add(-time.Hour), 8 * time.Hour, now.Add(8 * time.Hour), false},
+		{"never expires", time.Time{}, 8 * time.Hour, time.Time{}, true},
+		{"zero ttl", now.Add(time.Hour), 0, time.Time{}, true},
+	}
+	for _, tc := range tests {
+		got, err := extendedExpiry(tc.expiresOn, tc.ttl, now)
+		if (err != nil) != tc.wantErr {
+			t.Errorf("%s: error = %v, wantErr %v", tc.name, err, tc.wantErr)
+			continue
+		}
+		if !got.Equal(tc.want) {
+			t.Errorf("%s: extendedExpiry() = %s, want %s", tc.name, got, tc.want)
+		}
+	}
+}
@@ -0,0 +1,193 @@
+package main
+
+import (
+	"embed"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"strings"
+)
+
+// exampleFiles holds the examples `cftoken help COMMAND` prints, one file
+// per command. TestHelpExamples parses every example against the flags and
+// commands it names, so they fail the build instead of going stale.
+//
+//go:embed examples/*.txt
+var exampleFiles embed.FS
+
+// programName is how usage and help refer to the binary. It does not
+// depend on os.Args, so the text reads the same however cftoken is invoked.
+const programName = "cftoken"
+
+// commandHelp documents one command for usage and `cftoken help`.
+type commandHelp struct {
+	name string
+	// synopses are the command's forms, each starting with its name.
+	synopses []string
+	summary  string
+}
+
+// commands lists every command in the order usage shows them.
+var commands = []commandHelp{
+	{"create", []string{"create [flags]"}, "Create a token for a zone; the default when only flags are given."},
+	{"list-permissions", []string{"list-permissions"}, "List the permission groups available to the management token."},
+	{"zones", []string{"zones"}, "List configured zones; zones describe adds their live status."},
+	{"whoami", []string{"whoami"}, "Verify the management token and show its expiry, owner, accounts, token store, and permissions."},
+	{"doctor", []string{"doctor"}, "Check environment and config health and suggest fixes."},
+	{"config", []string{
+		"config lint",
+		"config set-zone NAME [-zone-id ID] [-ttl D] [-permissions LIST] [-allow-cidrs LIST] ...",
+		"config remove-zone [-revoke | -keep-tokens] NAME",
+	}, "Report shadowed zones, unused variables and profiles, and unreachable defaults; add, update, or remove a zone in config.json, offering to revoke a removed zone's tokens."},
+	{"template", []string{"template describe (-zone NAME | TEMPLATE)"}, "Print the variables a template declares and reads."},
+	{"check", []string{"check -f FILE|- [-zone NAME] [-var k=v] [-var-file FILE|-]"}, "Check that every token a manifest describes passes the guardrails, without calling the API; fails on any violation."},
+	{"apply-template", []string{"apply-template -template FILE|- [-zone NAME] [-var k=v] [-var-file FILE|-] [-dry-run] [-yes]"}, "Create every token a template describes, rolling back if any fails."},
+	{"permissions", []string{
+		"permissions lock [-check]",
+		"permissions snapshot [-file PATH]",
+		"permissions diff [-file PATH] [-exit-code]",
+		"permissions export [-output json|csv] [-file PATH]",
+	}, "Pin the permission groups config.json uses to their IDs in permissions.lock.json; save, diff, or export the permission group catalog."},
+	{"export", []string{"export [-format terraform] [-token-id ID | -zone NAME [-permissions LIST] [-ttl D] ...]"}, "Render the token the token creation flags describe, or an existing token, as a Terraform cloudflare_api_token resource."},
+	{"portal", []string{"portal [-file PATH] [-title TEXT]"}, "Generate a static HTML page documenting the zones, profiles, and permission groups tokens can use."},
+	{"revoke", []string{"revoke [-dry-run] (ID|NAME... | -match PATTERN | -label k=v [-older-than AGE])"}, "Revoke tokens by ID or exact name, or every token whose name matches a glob or that carries labels."},
+	{"prune", []string{"prune [-prefix NAME] [-older-than AGE] [-dry-run]"}, "Revoke expired tokens cftoken created, and with -older-than those issued before an age."},
+	{"extend", []string{"extend [-ttl D] [-dry-run] ID|NAME"}, "Push a token's expiry forward by a TTL, keeping its value."},
+	{"roll", []string{"roll ID|NAME"}, "Give a token a new secret, keeping its ID and policies, and print it."},
+	{"journal", []string{"journal list|rollback ID|discard ID"}, "List operations that failed part-way; roll them back or discard them."},
+	{"inspect", []string{"inspect -token-value VALUE|- [-view list|tree|wide] [-raw]"}, "Check a token's status and expiry using only the token itself, no management token needed."},
+	{"labels", []string{"labels list [-label k=v] | set ID k=v... | unset ID [KEY...]"}, "List, set, or remove the local labels (team, service, ticket) of tokens."},
+	{"history", []string{"history -zone NAME"}, "Show how the policies issued for a zone have changed over time."},
+	{"zone", []string{"zone describe [NAME...] | onboard [-issue] DOMAIN | freeze [-note TEXT] NAME | unfreeze NAME | frozen"}, "Show configured zones' live status; onboard a new zone; freeze or unfreeze issuance."},
+	{"narrow", []string{"narrow -from-token-id ID [-drop LIST] [-drop-resource LIST] [-ttl D] [-allow-cidrs LIST] [-dry-run] [-yes]"}, "Mint a replacement for an existing token with some permission groups or resources removed."},
+	{"reissue", []string{"reissue -revision ID [-ttl D] [-allow-cidrs LIST] [-dry-run] [-yes]"}, "Mint a new token from a stored policy revision, bypassing current templates."},
+	{"export-request", []string{"export-request -key FILE [-keygen] -template PATH|- [-zone NAME] [-var k=v] [-valid-for D] [-file PATH]"}, "Render a token set into a signed request on a machine without the management token."},
+	{"fulfill-request", []string{"fulfill-request [-dry-run] [-yes] FILE|-"}, "Verify a signed request and create its tokens with the management token."},
+	{"schema", []string{"schema [NAME]"}, "List the embedded JSON Schemas, or print one, for validating inputs and outputs."},
+	{"quota", []string{"quota"}, "Show the API rate-limit budget Cloudflare reports for the management token."},
+	{"cache", []string{"cache status|clear"}, "Show or clear cached permission groups and zones."},
+	{"completion", []string{"completion bash|zsh|fish"}, "Print a shell completion script; -zone completes configured zones and -permissions cached permission groups."},
+	{"help", []string{"help [COMMAND]"}, "Show a command's usage and examples."},
+}
+
+func usage() {
+	writeUsage(flag.CommandLine.Output(), flag.CommandLine)
+}
+
+// runHelp prints the full usage, or one command's usage and examples, on
+// stdout.
+func runHelp(args []string) error {
+	switch len(args) {
+	case 0:
+		writeUsage(os.Stdout, flag.CommandLine)
+		return nil
+	case 1:
+		return writeCommandHelp(os.Stdout, args[0])
+	default:
+		return withCode(codeInvalidArgument, errors.New("usage: help [COMMAND]"), nil)
+	}
+}
+
+// writeUsage writes every command's synopsis and summary, the environment,
+// and the flags defined on fs, global ones first and then those of token
+// creation.
+func writeUsage(w io.Writer, fs *flag.FlagSet) {
+	prefix := "Usage:"
+	for _, c := range commands {
+		for _, synopsis := range c.synopses {
+			fmt.Fprintf(w, "%-6s %s [flags] %s\n", prefix, programName, synopsis)
+			prefix = ""
+		}
+	}
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "Commands:")
+	for _, c := range commands {
+		fmt.Fprintf(w, "  %-22s %s\n", c.name, c.summary)
+	}
+	fmt.Fprintln(w)
+	fmt.Fprintf(w, "Run '%s help COMMAND' for a command's examples.\n", programName)
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "Environment:")
+	fmt.Fprintln(w, "  CLOUDFLARE_API_TOKEN   Cloudflare API token with permission to create tokens (required).")
+	fmt.Fprintln(w)
+	createOnly := createFlagNames()
+	global := flag.NewFlagSet(fs.Name(), flag.ContinueOnError)
+	create := flag.NewFlagSet(fs.Name(), flag.ContinueOnError)
+	fs.VisitAll(func(f *flag.Flag) {
+		if hiddenFlags[f.Name] {
+			return
+		}
+		visible := global
+		if createOnly[f.Name] {
+			visible = create
+		}
+		visible.Var(f.Value, f.Name, f.Usage)
+		visible.Lookup(f.Name).DefValue = f.DefValue
+	})
+	fmt.Fprintln(w, "Global flags, before the command:")
+	global.SetOutput(w)
+	global.PrintDefaults()
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "Token creation flags, after create or export, or without a command:")
+	create.SetOutput(w)
+	create.PrintDefaults()
+}
+
+// hiddenFlags are global flags usage leaves out because they exist for
+// testing, such as -clock in -tags testclock builds.
+var hiddenFlags = map[string]bool{"clock": true}
+
+// writeCommandHelp writes the synopses, summary, and examples of the
+// command called name.
+func writeCommandHelp(w io.Writer, name string) error {
+	c, ok := lookupCommand(name)
+	if !ok {
+		names := make([]string, len(commands))
+		for i, c := range commands {
+			names[i] = c.name
+		}
+		return withCode(codeInvalidArgument, fmt.Errorf("unknown command %q; available: %s", name, strings.Join(names, ", ")), map[string]any{"command": name})
+	}
+	examples, err := commandExamples(c.name)
+	if err != nil {
+		return err
+	}
+	prefix := "Usage:"
+	for _, synopsis := range c.synopses {
+		fmt.Fprintf(w, "%-6s %s [flags] %s\n", prefix, programName, synopsis)
+		prefix = ""
+	}
+	fmt.Fprintf(w, "\n%s\n\nExamples:\n", c.summary)
+	for _, line := range examples {
+		if line == "" {
+			fmt.Fprintln(w)
+			continue
+		}
+		fmt.Fprintf(w, "  %s\n", line)
+	}
+	fmt.Fprintf(w, "\nRun '%s -h' for the global and token creation flags.\n", programName)
+	return nil
+}
+
+func lookupCommand(name string) (commandHelp, bool) {
+	for _, c := range commands {
+		if c.name == name {
+			return c, true
+		}
+	}
+	return commandHelp{}, false
+}
+
+// commandExamples returns the lines of the examples file of the command
+// called name: comments starting with "#", blank separators, and commands
+// starting with "cftoken ".
+func commandExamples(name string) ([]string, error) {
+	data, err := exampleFiles.ReadFile(path.Join("examples", name+".txt"))
+	if err != nil {
+		return nil, fmt.Errorf("examples for %s: %w", name, err)
+	}
+	return strings.Split(strings.TrimRight(string(data), "\n"), "\n"), nil
+}
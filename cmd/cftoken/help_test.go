@@ -0,0 +1,157 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+// TestHelpExamples parses every example `cftoken help` prints: flags
+// before a command must be global ones, the command must be one usage
+// documents, and the command's own flags must appear in its synopses.
+// Without a command and after create, the token creation flags apply.
+func TestHelpExamples(t *testing.T) {
+	flags := runFlags{templateVars: &varFlag{}}
+	global := flag.NewFlagSet(programName, flag.ContinueOnError)
+	flags.register(global)
+	create := flag.NewFlagSet("create", flag.ContinueOnError)
+	flags.registerCreate(create)
+	standalone := flag.NewFlagSet(programName, flag.ContinueOnError)
+	flags.register(standalone)
+	flags.registerCreate(standalone)
+	flags.registerLegacy(standalone)
+
+	entries, err := exampleFiles.ReadDir("examples")
+	if err != nil {
+		t.Fatalf("ReadDir(examples) error = %v", err)
+	}
+	if len(entries) != len(commands) {
+		t.Errorf("%d example files for %d commands", len(entries), len(commands))
+	}
+
+	for _, c := range commands {
+		examples, err := commandExamples(c.name)
+		if err != nil {
+			t.Errorf("commandExamples(%q) error = %v", c.name, err)
+			continue
+		}
+		synopses := strings.Join(c.synopses, "\n")
+		own := false
+		for _, line := range examples {
+			if line == "" || strings.HasPrefix(line, "#") {
+				continue
+			}
+			i := strings.Index(line, programName+" ")
+			if i < 0 {
+				t.Errorf("%s example %q does not run %s", c.name, line, programName)
+				continue
+			}
+			args := shellFields(line[i:])[1:]
+			cmd, rest, err := splitGlobalFlags(standalone, args)
+			if err != nil {
+				t.Errorf("%s example %q: %v", c.name, line, err)
+				continue
+			}
+			own = own || cmd == c.name || (cmd == "" && c.name == "create")
+			if cmd == "" {
+				continue
+			}
+			if _, _, err := splitGlobalFlags(global, args); err != nil {
+				t.Errorf("%s example %q: only global flags go before a command: %v", c.name, line, err)
+				continue
+			}
+			if cmd == "create" {
+				if _, extra, err := splitGlobalFlags(create, rest); err != nil || len(extra) > 0 {
+					t.Errorf("%s example %q: create takes only token creation flags, got error %v, arguments %q", c.name, line, err, extra)
+				}
+				continue
+			}
+			if _, ok := lookupCommand(cmd); !ok {
+				t.Errorf("%s example %q runs unknown command %q", c.name, line, cmd)
+			}
+			for _, arg := range rest {
+				name, _, _ := strings.Cut(strings.TrimLeft(arg, "-"), "=")
+				if !strings.HasPrefix(arg, "-") || arg == "-" {
+					continue
+				}
+				if !regexp.MustCompile(`-` + regexp.QuoteMeta(name) + `([\s\]|)]|$)`).MatchString(synopses) {
+					t.Errorf("%s example %q uses -%s, which its usage does not show", c.name, line, name)
+				}
+			}
+		}
+		if !own {
+			t.Errorf("%s has no example of itself", c.name)
+		}
+
+		var buf bytes.Buffer
+		if err := writeCommandHelp(&buf, c.name); err != nil || !strings.Contains(buf.String(), "Examples:\n") {
+			t.Errorf("writeCommandHelp(%q) = %q, %v", c.name, buf.String(), err)
+		}
+	}
+
+	if err := writeCommandHelp(&bytes.Buffer{}, "nope"); err == nil || !strings.Contains(err.Error(), "available: create,") {
+		t.Errorf("writeCommandHelp(nope) error = %v", err)
+	}
+}
+
+// splitGlobalFlags returns the first argument after the global flags in
+// args, and those after it.
+func splitGlobalFlags(fs *flag.FlagSet, args []string) (string, []string, error) {
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		if !strings.HasPrefix(arg, "-") || arg == "-" {
+			return arg, args[i+1:], nil
+		}
+		name, _, hasValue := strings.Cut(strings.TrimLeft(arg, "-"), "=")
+		f := fs.Lookup(name)
+		if f == nil {
+			return "", nil, fmt.Errorf("unknown global flag -%s", name)
+		}
+		if b, ok := f.Value.(interface{ IsBoolFlag() bool }); !hasValue && !(ok && b.IsBoolFlag()) {
+			i++
+		}
+	}
+	return "", nil, nil
+}
+
+// shellFields splits line into words as a POSIX shell would for the
+// quoting the examples use, stopping at a comment, redirection, or pipe.
+func shellFields(line string) []string {
+	var fields []string
+	var word strings.Builder
+	inWord := false
+	var quote rune
+	for _, r := range line {
+		switch {
+		case quote != 0 && r == quote:
+			quote = 0
+		case quote != 0:
+			word.WriteRune(r)
+		case r == '\'' || r == '"':
+			quote, inWord = r, true
+		case r == '#' && !inWord:
+			return fields
+		case r == '>' || r == '|':
+			if inWord {
+				fields = append(fields, word.String())
+			}
+			return fields
+		case r == ' ' || r == '\t':
+			if inWord {
+				fields = append(fields, word.String())
+				word.Reset()
+				inWord = false
+			}
+		default:
+			word.WriteRune(r)
+			inWord = true
+		}
+	}
+	if inWord {
+		fields = append(fields, word.String())
+	}
+	return fields
+}
@@ -0,0 +1,125 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"cftoken/internal/clock"
+	"cftoken/internal/revision"
+	"cftoken/internal/template"
+)
+
+func runHistory(args []string) error {
+	fset := flag.NewFlagSet("history", flag.ContinueOnError)
+	zoneName := fset.String("zone", "", "Zone whose policy revisions to show (required)")
+	if err := fset.Parse(args); err != nil {
+		return err
+	}
+	if *zoneName == "" {
+		return withCode(codeInvalidArgument, errors.New("history requires -zone"), nil)
+	}
+	revisions, err := revision.History(*zoneName)
+	if err != nil {
+		return fmt.Errorf("load revisions: %w", err)
+	}
+	if len(revisions) == 0 {
+		fmt.Printf("No policy revisions recorded for %s.\n", *zoneName)
+		return nil
+	}
+	return printHistory(os.Stdout, revisions)
+}
+
+// printHistory lists revisions oldest first with the permission groups each
+// one added or dropped relative to the revision before it.
+func printHistory(w io.Writer, revisions []*revision.Revision) error {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "REVISION\tFIRST ISSUED\tLAST ISSUED\tTOKENS\tCHANGES")
+	var previous []string
+	for i, rev := range revisions {
+		groups := permissionGroupNames(rev.Policies)
+		changes := "initial"
+		if i > 0 {
+			changes = describeGroupChanges(previous, groups)
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%d\t%s\n", rev.ID,
+			rev.FirstIssued().Format(time.RFC3339), rev.LastIssued().Format(time.RFC3339),
+			len(rev.Issuances), changes)
+		previous = groups
+	}
+	return tw.Flush()
+}
+
+// permissionGroupNames returns the sorted, de-duplicated permission groups
+// granted by policies, preferring names over IDs.
+func permissionGroupNames(policies []template.Policy) []string {
+	seen := make(map[string]bool)
+	var names []string
+	for _, p := range policies {
+		for _, g := range p.PermissionGroups {
+			name := stringOrDefault(g.Name, g.ID)
+			if p.Effect == "deny" {
+				name = "deny " + name
+			}
+			if !seen[name] {
+				seen[name] = true
+				names = append(names, name)
+			}
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+func describeGroupChanges(before, after []string) string {
+	had := make(map[string]bool, len(before))
+	for _, name := range before {
+		had[name] = true
+	}
+	has := make(map[string]bool, len(after))
+	var changes []string
+	for _, name := range after {
+		has[name] = true
+		if !had[name] {
+			changes = append(changes, "+"+name)
+		}
+	}
+	for _, name := range before {
+		if !has[name] {
+			changes = append(changes, "-"+name)
+		}
+	}
+	if len(changes) == 0 {
+		return "resources changed"
+	}
+	return strings.Join(changes, ", ")
+}
+
+// templateSource names a template for a revision: its path, "stdin", or
+// "inline".
+func templateSource(path string) string {
+	if path == "-" {
+		return "stdin"
+	}
+	return stringOrDefault(path, "inline")
+}
+
+// recordRevision stores the rendered policies a token was issued with.
+// Failing to record never fails the issuance.
+func recordRevision(zone, zoneID, source string, policies []template.Policy, issued revision.Issuance) {
+	if zone == "" || len(policies) == 0 {
+		return
+	}
+	issued.At = clock.Now()
+	issued.Ticket = changeTicket
+	if _, err := revision.Record(zone, zoneID, source, policies, issued); err != nil {
+		log.Printf("warning: record policy revision: %v", err)
+	}
+}
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"cftoken/internal/revision"
+	"cftoken/internal/template"
+)
+
+func TestPrintHistory(t *testing.T) {
+	day := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	rev := func(id string, resources map[string]interface{}, groups ...string) *revision.Revision {
+		var pgs []template.PermissionGroup
+		for _, g := range groups {
+			pgs = append(pgs, template.PermissionGroup{ID: "id-" + g, Name: g})
+		}
+		return &revision.Revision{
+			ID:        id,
+			Policies:  []template.Policy{{Effect: "allow", Resources: resources, PermissionGroups: pgs}},
+			Issuances: []revision.Issuance{{At: day}, {At: day.Add(time.Hour)}},
+		}
+	}
+	zone := map[string]interface{}{"zone": "*"}
+
+	var buf bytes.Buffer
+	if err := printHistory(&buf, []*revision.Revision{
+		rev("r1", zone, "Zone Read"),
+		rev("r2", zone, "Zone Read", "DNS Write"),
+		rev("r3", zone, "DNS Write"),
+		rev("r4", map[string]interface{}{"other": "*"}, "DNS Write"),
+	}); err != nil {
+		t.Fatalf("printHistory() error = %v", err)
+	}
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 5 {
+		t.Fatalf("got %d lines, want 5:\n%s", len(lines), buf.String())
+	}
+	for i, want := range []string{"initial", "+DNS Write", "-Zone Read", "resources changed"} {
+		line := lines[i+1]
+		if !strings.HasSuffix(line, want) || !strings.Contains(line, "2024-01-02T04:04:05Z") {
+			t.Errorf("line %d = %q, want changes %q", i+1, line, want)
+		}
+	}
+}
@@ -0,0 +1,124 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	cf "github.com/cloudflare/cloudflare-go/v6"
+
+	"cftoken/internal/clock"
+	"cftoken/internal/cloudflare"
+)
+
+// runInspect checks a token using nothing but the token itself, so holders
+// without the management credential can see its status and expiry. The
+// full configuration is shown too when the token may read it.
+func runInspect(ctx context.Context, verbose bool, args []string) error {
+	fset := flag.NewFlagSet("inspect", flag.ContinueOnError)
+	value := fset.String("token-value", "", "Token to inspect; use - to read it from stdin and keep it out of shell history (required)")
+	raw := fset.Bool("raw", false, "Print the token's configuration exactly as the API returned it, as JSON")
+	view := fset.String("view", viewList, "Policy layout: list, tree (resources with their permission groups), or wide (a table)")
+	if err := fset.Parse(args); err != nil {
+		return err
+	}
+	if err := validPolicyView(*view); err != nil {
+		return withCode(codeInvalidArgument, err, nil)
+	}
+	tokenValue, err := readTokenValue(*value, os.Stdin)
+	if err != nil {
+		return err
+	}
+
+	logger := func(string, ...interface{}) {}
+	if verbose {
+		logger = log.Printf
+	}
+	client := cloudflare.NewClient(tokenValue,
+		cloudflare.WithUserAgent("cftoken-cli/0.1"),
+		cloudflare.WithLogger(logger),
+		cloudflare.WithTokenAccount(tokenAccount),
+		cloudflare.WithReadOnly(),
+		cloudflare.WithCacheTTL(0),
+	)
+
+	verification, err := client.VerifyToken(ctx)
+	if err != nil {
+		return err
+	}
+	if *raw {
+		return printRawToken(ctx, os.Stdout, client, verification.ID)
+	}
+	structured := structuredOutput()
+	if !structured {
+		printVerification(os.Stdout, verification, clock.Now())
+	}
+
+	desc, err := client.DescribeToken(ctx, verification.ID)
+	switch {
+	case deniedSelfRead(err) && structured:
+		return writeDocument(os.Stdout, verification)
+	case deniedSelfRead(err):
+		fmt.Println()
+		fmt.Println("Permissions and restrictions are not shown: the token may not read its own configuration (that needs API Tokens Read).")
+		return nil
+	case err != nil:
+		return fmt.Errorf("describe token: %w", err)
+	}
+	if !structured {
+		fmt.Println()
+	}
+	printTokenInspection(os.Stdout, desc, *view, useColor(os.Stdout))
+	return nil
+}
+
+// deniedSelfRead reports whether err is the API refusing to let a token
+// read its own configuration.
+func deniedSelfRead(err error) bool {
+	var apiErr *cf.Error
+	return errors.As(err, &apiErr) && (apiErr.StatusCode == http.StatusForbidden || apiErr.StatusCode == http.StatusUnauthorized)
+}
+
+// readTokenValue returns value, or the first line of stdin when value is "-".
+func readTokenValue(value string, stdin io.Reader) (string, error) {
+	if value == "-" {
+		line, err := bufio.NewReader(stdin).ReadString('\n')
+		if err != nil && !errors.Is(err, io.EOF) {
+			return "", fmt.Errorf("read token from stdin: %w", err)
+		}
+		value = line
+	}
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return "", withCode(codeInvalidArgument, errors.New("inspect requires -token-value"), nil)
+	}
+	return value, nil
+}
+
+func printVerification(w io.Writer, v *cloudflare.TokenVerification, now time.Time) {
+	fmt.Fprintf(w, "ID: %s\n", stringOrDefault(v.ID, "<unknown>"))
+	fmt.Fprintf(w, "Status: %s\n", stringOrDefault(v.Status, "<unknown>"))
+	expires := "none"
+	if v.ExpiresOn != "" {
+		expires = v.ExpiresOn
+		if t, err := time.Parse(time.RFC3339, v.ExpiresOn); err == nil {
+			if left := t.Sub(now); left > 0 {
+				expires += fmt.Sprintf(" (in %s)", left.Truncate(time.Minute))
+			} else {
+				expires += " (expired)"
+			}
+		}
+	}
+	fmt.Fprintf(w, "Expires: %s\n", expires)
+	if v.NotBefore != "" {
+		fmt.Fprintf(w, "Not Before: %s\n", v.NotBefore)
+	}
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"cftoken/internal/cloudflare"
+)
+
+func TestReadTokenValue(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		value   string
+		stdin   string
+		want    string
+		wantErr bool
+	}{
+		{"flag value", " abc ", "", "abc", false},
+		{"stdin", "-", "from-stdin\nignored\n", "from-stdin", false},
+		{"stdin without newline", "-", "tok", "tok", false},
+		{"missing", "", "", "", true},
+		{"empty stdin", "-", "", "", true},
+	}
+	for _, tc := range tests {
+		got, err := readTokenValue(tc.value, strings.NewReader(tc.stdin))
+		if (err != nil) != tc.wantErr || got != tc.want {
+			t.Errorf("%s: readTokenValue() = %q, %v; want %q, error %v", tc.name, got, err, tc.want, tc.wantErr)
+		}
+	}
+}
+
+func TestPrintVerification(t *testing.T) {
+	t.Parallel()
+
+	now := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	tests := []struct {
+		name string
+		v    cloudflare.TokenVerification
+		want string
+	}{
+		{"valid", cloudflare.TokenVerification{ID: "t1", Status: "active", ExpiresOn: "2024-01-02T11:04:05Z"}, "Expires: 2024-01-02T11:04:05Z (in 8h0m0s)"},
+		{"expired", cloudflare.TokenVerification{ID: "t1", Status: "expired", ExpiresOn: "2024-01-01T00:00:00Z"}, "(expired)"},
+		{"no expiry", cloudflare.TokenVerification{ID: "t1", Status: "active"}, "Expires: none"},
+	}
+	for _, tc := range tests {
+		var buf bytes.Buffer
+		printVerification(&buf, &tc.v, now)
+		if !strings.Contains(buf.String(), tc.want) {
+			t.Errorf("%s: printVerification() =\n%s\nwant %q", tc.name, buf.String(), tc.want)
+		}
+	}
+}
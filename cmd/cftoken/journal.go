@@ -0,0 +1,148 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"cftoken/internal/httpmw"
+	"cftoken/internal/journal"
+	"cftoken/internal/labels"
+)
+
+const stepCreateToken = "create_token"
+
+// tokenDeleter is the part of the Cloudflare client rollback needs.
+type tokenDeleter interface {
+	DeleteToken(ctx context.Context, tokenID string) error
+}
+
+func runJournal(ctx context.Context, token func() string, verbose bool, args []string) error {
+	if len(args) == 0 {
+		return errors.New("journal requires a subcommand: list, rollback, or discard")
+	}
+	fset := flag.NewFlagSet("journal "+args[0], flag.ContinueOnError)
+	if err := fset.Parse(args[1:]); err != nil {
+		return err
+	}
+
+	switch sub := args[0]; sub {
+	case "list":
+		return listJournals()
+	case "rollback", "discard":
+		if fset.NArg() != 1 {
+			return fmt.Errorf("journal %s requires a journal ID", sub)
+		}
+		j, err := journal.Load(fset.Arg(0))
+		if err != nil {
+			return fmt.Errorf("load journal: %w", err)
+		}
+		if sub == "discard" {
+			if err := j.Complete(); err != nil {
+				return err
+			}
+			fmt.Printf("Discarded journal %s; its steps were left in place.\n", j.ID)
+			return nil
+		}
+		if token() == "" {
+			return errMissingToken
+		}
+		if err := rollbackJournal(ctx, newClient(token(), verbose), j); err != nil {
+			return err
+		}
+		fmt.Printf("Rolled back journal %s.\n", j.ID)
+		return nil
+	default:
+		return fmt.Errorf("unknown journal subcommand %q; available: list, rollback, discard", sub)
+	}
+}
+
+func listJournals() error {
+	pending, err := journal.Pending()
+	if err != nil {
+		return err
+	}
+	if len(pending) == 0 {
+		fmt.Println("No unfinished operations.")
+		return nil
+	}
+	tw := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "ID\tOPERATION\tSTARTED\tCORRELATION ID\tSTEPS")
+	for _, j := range pending {
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\n", j.ID, j.Operation, j.StartedAt.Format(time.RFC3339), stringOrDefault(j.CorrelationID, "-"), describeSteps(j.Steps))
+	}
+	return tw.Flush()
+}
+
+func describeSteps(steps []journal.Step) string {
+	if len(steps) == 0 {
+		return "none"
+	}
+	out := ""
+	for i, s := range steps {
+		if i > 0 {
+			out += ", "
+		}
+		out += s.Name
+		if id := s.Resource["token_id"]; id != "" {
+			out += " " + id
+		}
+		if s.Undone {
+			out += " (undone)"
+		}
+	}
+	return out
+}
+
+// rollbackJournal undoes completed steps newest first and removes the journal
+// once nothing is left to undo. Steps without an undo action are skipped.
+func rollbackJournal(ctx context.Context, client tokenDeleter, j *journal.Journal) error {
+	var errs []error
+	for i := len(j.Steps) - 1; i >= 0; i-- {
+		step := j.Steps[i]
+		if step.Undone || step.Name != stepCreateToken {
+			continue
+		}
+		if err := client.DeleteToken(ctx, step.Resource["token_id"]); err != nil {
+			errs = append(errs, fmt.Errorf("roll back %s (%s): %w", step.Resource["name"], step.Resource["token_id"], err))
+			continue
+		}
+		if err := j.MarkUndone(i); err != nil {
+			errs = append(errs, err)
+		}
+		if err := labels.Remove(step.Resource["token_id"]); err != nil {
+			log.Printf("warning: forget labels of %s: %v", step.Resource["token_id"], err)
+		}
+	}
+	if len(errs) > 0 {
+		return errors.Join(errs...)
+	}
+	return j.Complete()
+}
+
+// beginJournal starts a journal for operation. Failing to journal is not
+// fatal; the operation then runs without crash recovery.
+func beginJournal(ctx context.Context, operation string) *journal.Journal {
+	if pending, err := journal.Pending(); err == nil && len(pending) > 0 {
+		log.Printf("warning: %d unfinished operation(s) from earlier runs; see `cftoken journal list`", len(pending))
+	}
+	j, err := journal.Begin(operation, httpmw.RequestID(ctx))
+	if err != nil {
+		log.Printf("warning: journal unavailable, continuing without rollback support: %v", err)
+		return nil
+	}
+	return j
+}
+
+// journalHint tells the user how to deal with a journal left behind by a failure.
+func journalHint(j *journal.Journal) string {
+	if j.Completed() {
+		return ""
+	}
+	return fmt.Sprintf("; run `cftoken journal rollback %s` to revoke what was created, or `cftoken journal discard %s` to keep it", j.ID, j.ID)
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"cftoken/internal/journal"
+)
+
+func TestRollbackJournal(t *testing.T) {
+	t.Setenv("XDG_STATE_HOME", t.TempDir())
+
+	j, err := journal.Begin("create", "")
+	if err != nil {
+		t.Fatalf("Begin() error = %v", err)
+	}
+	for _, step := range []struct {
+		name     string
+		resource map[string]string
+	}{
+		{stepCreateToken, map[string]string{"token_id": "t1", "name": "a"}},
+		{"deliver", map[string]string{"sink": "vault"}},
+		{stepCreateToken, map[string]string{"token_id": "t2", "name": "b"}},
+	} {
+		if err := j.Record(step.name, step.resource); err != nil {
+			t.Fatalf("Record() error = %v", err)
+		}
+	}
+	if err := j.MarkUndone(2); err != nil {
+		t.Fatalf("MarkUndone() error = %v", err)
+	}
+
+	fake := &fakeCreator{}
+	if err := rollbackJournal(context.Background(), fake, j); err != nil {
+		t.Fatalf("rollbackJournal() error = %v", err)
+	}
+	if want := []string{"t1"}; !reflect.DeepEqual(fake.deleted, want) {
+		t.Fatalf("deleted = %v, want %v", fake.deleted, want)
+	}
+	pending, err := journal.Pending()
+	if err != nil || len(pending) != 0 {
+		t.Fatalf("Pending() = %v, %v; want journal removed", pending, err)
+	}
+	if hint := journalHint(j); hint != "" {
+		t.Fatalf("journalHint() = %q after rollback, want empty", hint)
+	}
+}
+
+func TestCreateTokenSetJournal(t *testing.T) {
+	t.Setenv("XDG_STATE_HOME", t.TempDir())
+
+	j, err := journal.Begin("apply-template", "")
+	if err != nil {
+		t.Fatalf("Begin() error = %v", err)
+	}
+	fake := &fakeCreator{failOn: "b"}
+	if _, err := createTokenSet(context.Background(), fake, j, []plannedToken{{name: "a"}, {name: "b"}}); err == nil {
+		t.Fatal("createTokenSet() error = nil, want failure")
+	}
+	if !j.Completed() {
+		t.Fatal("journal left behind after a clean rollback")
+	}
+	if len(j.Steps) != 1 || !j.Steps[0].Undone || j.Steps[0].Resource["token_id"] != "id-a" {
+		t.Fatalf("steps = %+v", j.Steps)
+	}
+}
@@ -0,0 +1,140 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"sort"
+	"text/tabwriter"
+
+	"cftoken/internal/cloudflare"
+	"cftoken/internal/labels"
+)
+
+// tokenLabels is set by repeatable -label key=value flags and is attached to
+// every token the run creates.
+var tokenLabels varFlag
+
+func runLabels(args []string) error {
+	if len(args) == 0 {
+		return errors.New("labels requires a subcommand: list, set, or unset")
+	}
+	switch args[0] {
+	case "list":
+		var filter varFlag
+		fset := flag.NewFlagSet("labels list", flag.ContinueOnError)
+		fset.Var(&filter, "label", "Only list tokens with this key=value label (can be specified multiple times)")
+		if err := fset.Parse(args[1:]); err != nil {
+			return err
+		}
+		reg, err := labels.Load()
+		if err != nil {
+			return err
+		}
+		printLabels(os.Stdout, reg, filter)
+		return nil
+	case "set":
+		if len(args) < 3 {
+			return errors.New("usage: labels set TOKEN_ID key=value...")
+		}
+		var set varFlag
+		for _, pair := range args[2:] {
+			if err := set.Set(pair); err != nil {
+				return err
+			}
+		}
+		if err := validateLabels(set); err != nil {
+			return err
+		}
+		return labels.Set(args[1], "", set)
+	case "unset":
+		if len(args) < 2 {
+			return errors.New("usage: labels unset TOKEN_ID [KEY...]")
+		}
+		return labels.Remove(args[1], args[2:]...)
+	default:
+		return fmt.Errorf("unknown labels subcommand %q; available: list, set, unset", args[0])
+	}
+}
+
+func validateLabels(set map[string]string) error {
+	for k := range set {
+		if !labels.ValidKey(k) {
+			return withCode(codeInvalidArgument, fmt.Errorf("invalid label key %q", k), nil)
+		}
+	}
+	return nil
+}
+
+func printLabels(w io.Writer, reg labels.Registry, filter map[string]string) {
+	ids := make([]string, 0, len(reg))
+	for id, entry := range reg {
+		if entry.Matches(filter) {
+			ids = append(ids, id)
+		}
+	}
+	if len(ids) == 0 {
+		fmt.Fprintln(w, "No labelled tokens.")
+		return
+	}
+	sort.Slice(ids, func(a, b int) bool {
+		if na, nb := reg[ids[a]].Name, reg[ids[b]].Name; na != nb {
+			return na < nb
+		}
+		return ids[a] < ids[b]
+	})
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "NAME\tID\tLABELS")
+	for _, id := range ids {
+		fmt.Fprintf(tw, "%s\t%s\t%s\n", stringOrDefault(reg[id].Name, "-"), id, reg[id])
+	}
+	tw.Flush()
+}
+
+// labelIssued attaches the run's -label flags to newly created tokens, plus
+// a ticket label from -ticket unless one was given explicitly. Failing to
+// label never fails the issuance.
+func labelIssued(results ...*cloudflare.TokenResult) {
+	set := make(map[string]string, len(tokenLabels)+1)
+	if changeTicket != "" {
+		set["ticket"] = changeTicket
+	}
+	for k, v := range tokenLabels {
+		set[k] = v
+	}
+	if len(set) == 0 {
+		return
+	}
+	for _, r := range results {
+		if err := labels.Set(r.ID, r.Name, set); err != nil {
+			log.Printf("warning: label token %s: %v", r.ID, err)
+		}
+	}
+}
+
+// filterByLabels keeps the tokens whose registry entry carries every label
+// in filter. An empty filter keeps everything.
+func filterByLabels(tokens []cloudflare.Token, reg labels.Registry, filter map[string]string) []cloudflare.Token {
+	if len(filter) == 0 {
+		return tokens
+	}
+	var out []cloudflare.Token
+	for _, token := range tokens {
+		if entry, ok := reg[token.ID]; ok && entry.Matches(filter) {
+			out = append(out, token)
+		}
+	}
+	return out
+}
+
+// describeLabels renders a token's labels for tables, or "-".
+func describeLabels(reg labels.Registry, tokenID string) string {
+	entry, ok := reg[tokenID]
+	if !ok || len(entry.Labels) == 0 {
+		return "-"
+	}
+	return entry.String()
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"cftoken/internal/cloudflare"
+	"cftoken/internal/labels"
+)
+
+func TestFilterByLabels(t *testing.T) {
+	t.Parallel()
+
+	tokens := []cloudflare.Token{{ID: "1"}, {ID: "2"}, {ID: "3"}}
+	reg := labels.Registry{
+		"1": {Labels: map[string]string{"team": "edge", "service": "purge"}},
+		"2": {Labels: map[string]string{"team": "web"}},
+	}
+
+	tests := []struct {
+		name   string
+		filter map[string]string
+		want   string
+	}{
+		{"no filter", nil, "1,2,3"},
+		{"one label", map[string]string{"team": "edge"}, "1"},
+		{"all labels must match", map[string]string{"team": "edge", "service": "dns"}, ""},
+		{"unlabelled tokens never match", map[string]string{"team": "ops"}, ""},
+	}
+	for _, tc := range tests {
+		var ids []string
+		for _, token := range filterByLabels(tokens, reg, tc.filter) {
+			ids = append(ids, token.ID)
+		}
+		if got := strings.Join(ids, ","); got != tc.want {
+			t.Errorf("%s: filterByLabels() = %s, want %s", tc.name, got, tc.want)
+		}
+	}
+}
+
+func TestPrintLabels(t *testing.T) {
+	t.Parallel()
+
+	reg := labels.Registry{
+		"t2": {Name: "web-1", Labels: map[string]string{"team": "web"}},
+		"t1": {Name: "edge-1", Labels: map[string]string{"ticket": "OPS-1", "team": "edge"}},
+	}
+	var buf bytes.Buffer
+	printLabels(&buf, reg, nil)
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 3 || !strings.HasPrefix(lines[1], "edge-1") || !strings.HasSuffix(lines[1], "team=edge,ticket=OPS-1") {
+		t.Fatalf("printLabels() =\n%s", buf.String())
+	}
+
+	buf.Reset()
+	printLabels(&buf, reg, map[string]string{"team": "ops"})
+	if strings.TrimSpace(buf.String()) != "No labelled tokens." {
+		t.Fatalf("printLabels() with unmatched filter =\n%s", buf.String())
+	}
+}
@@ -1,23 +1,57 @@
 package main
 
 import (
+	"bytes"
 	"context"
 	"errors"
 	"flag"
 	"fmt"
+	"io"
 	"io/fs"
 	"log"
 	"net"
 	"os"
+	"slices"
+	"sort"
 	"strings"
+	"sync"
 	"text/tabwriter"
 	"time"
 
+	"cftoken/internal/clock"
 	"cftoken/internal/cloudflare"
 	"cftoken/internal/config"
+	"cftoken/internal/credential"
+	"cftoken/internal/duration"
+	"cftoken/internal/guardrail"
+	"cftoken/internal/httpmw"
+	"cftoken/internal/notify"
+	"cftoken/internal/revision"
+	"cftoken/internal/sink"
 	"cftoken/internal/template"
 )
 
+var errMissingToken = errors.New("missing API token: export CLOUDFLARE_API_TOKEN or set token_source in config.json before running this command")
+
+// noCache is set by -no-cache and disables API caching for every client the
+// run builds.
+var noCache bool
+
+// readOnly refuses every call that would change Cloudflare state. It is set
+// by -read-only, CFTOKEN_READ_ONLY=1, or building with -tags readonly.
+var readOnly = buildReadOnly
+
+// outputFormat is set by -output. With "json" or "yaml", results are printed
+// as documents and a failed run is reported as a JSON document with a stable
+// error code instead of a log line; with "shell", "dotenv", or
+// "gitlab-dotenv", created tokens are printed as variable assignments.
+var outputFormat = "text"
+
+// tokenAccount is set by -token-account. When set, tokens are created,
+// listed, inspected, and revoked through accounts/ID/tokens instead of
+// user/tokens.
+var tokenAccount string
+
 // varFlag implements flag.Value for repeatable -var key=value flags.
 type varFlag map[string]string
 
@@ -26,8 +60,8 @@ func (v *varFlag) String() string {
 		return ""
 	}
 	parts := make([]string, 0, len(*v))
-	for k, val := range *v {
-		parts = append(parts, fmt.Sprintf("%s=%s", k, val))
+	for _, k := range sortedKeys(*v) {
+		parts = append(parts, fmt.Sprintf("%s=%s", k, (*v)[k]))
 	}
 	return strings.Join(parts, ", ")
 }
@@ -45,50 +79,165 @@ func (v *varFlag) Set(value string) error {
 }
 
 func main() {
-	if err := run(); err != nil {
-		log.Fatal(err)
+	// Every run gets one correlation ID. It tags verbose request logs, is sent
+	// to Cloudflare with each request, and ends up in journals, progress
+	// events, notifications, and the final error.
+	id := httpmw.NewRequestID()
+	if err := run(httpmw.WithRequestID(context.Background(), id)); err != nil {
+		// YAML readers take the JSON document as it is.
+		if structuredOutput() {
+			if jsonErr := writeErrorJSON(os.Stderr, err, id); jsonErr == nil {
+				os.Exit(1)
+			}
+		}
+		log.Fatalf("%v (correlation ID %s)", err, id)
 	}
 }
 
-func run() error {
+// runFlags holds the global and token creation flags that are not package
+// state.
+type runFlags struct {
+	tokenPrefix     string
+	zoneID          string
+	zoneName        string
+	permissions     string
+	ttl             time.Duration
+	listPermissions bool
+	listZones       bool
+	revoke          string
+	allowCIDRs      string
+	inspect         bool
+	inspectToken    string
+	raw             bool
+	dryRun          bool
+	interactive     bool
+	pickPermissions bool
+	yes             bool
+	againstTokenID  string
+	scrub           bool
+	noSink          bool
+	outFile         string
+	share           bool
+	force           bool
+	dnsCanary       bool
+	progressFormat  string
+	profileCPU      string
+	profileMem      string
+	explainConfig   bool
+	timeout         time.Duration
+	verbose         bool
+	conditions      conditionFlag
+	templateVars    *varFlag
+	// exportFormat is set by the export command, which renders the token
+	// in place of the dry-run preview.
+	exportFormat string
+}
+
+// register defines the global flags on fs: those that apply to every
+// command, including those that set package state such as -read-only and
+// -output.
+func (f *runFlags) register(fs *flag.FlagSet) {
+	fs.StringVar(&f.profileCPU, "profile-cpu", "", "Write a CPU profile of the run to this file (debugging)")
+	fs.StringVar(&f.profileMem, "profile-mem", "", "Write a heap profile at the end of the run to this file (debugging)")
+	fs.BoolVar(&readOnly, "read-only", readOnly, "Only list, inspect, and report; refuse anything that would change Cloudflare state (also CFTOKEN_READ_ONLY=1)")
+	fs.Var(&tokenLabels, "label", "Label in key=value format stored locally with every token the run creates, e.g. team=edge (can be specified multiple times)")
+	fs.StringVar(&changeTicket, "ticket", "", "Change ticket or reason for the tokens this run creates; recorded with them and required by the require_ticket guardrail")
+	fs.StringVar(&changeTicket, "reason", "", "Alias for -ticket")
+	fs.StringVar(&forceBudget, "force-budget", "", "Create tokens even beyond the issuance budget in config.json; the value is the reason, recorded with each token")
+	fs.StringVar(&tokenAccount, "token-account", "", "Create, list, inspect, and revoke the API tokens owned by this account ID instead of the user's (see whoami)")
+	fs.BoolVar(&accessible, "accessible", false, "Screen reader friendly text output: no color or box drawing, and changes labeled in words rather than +, -, and ~ (also CFTOKEN_ACCESSIBLE=1)")
+	fs.BoolVar(&noCache, "no-cache", false, "Do not read or write cached permission groups and zones; always ask the API")
+	fs.StringVar(&outputFormat, "output", outputFormat, "Output: text; json or yaml to print created tokens, inspections, and lists as documents and failures as {code, message, details, correlation_id} on stderr; shell or dotenv to print a created token as quoted variable assignments; gitlab-dotenv for a GitLab CI dotenv report; value to print only the token value on stdout and the report on stderr")
+	fs.BoolFunc("quiet", "Same as -output value: print only the new token value on stdout, e.g. for TOKEN=$(cftoken -quiet -zone prod)", func(string) error {
+		outputFormat = outputValue
+		return nil
+	})
+	fs.DurationVar(&f.timeout, "timeout", f.timeout, "Request timeout (e.g. 15s, 1m)")
+	fs.BoolVar(&f.verbose, "v", f.verbose, "Enable verbose logging")
+	registerClockFlag(fs)
+}
+
+// registerCreate defines the flags of token creation on fs. They follow
+// the create and export commands, or stand alone when no command is given.
+func (f *runFlags) registerCreate(fs *flag.FlagSet) {
+	fs.StringVar(&f.tokenPrefix, "token-prefix", "", "Prefix for the new API token (defaults to default_token_prefix_template or the zone name; timestamp appended automatically)")
+	fs.StringVar(&f.zoneID, "zone-id", "", "Zone identifier (UUID) the new token should access")
+	fs.StringVar(&f.zoneName, "zone", "", "Zone name or configured zone with extended settings")
+	fs.StringVar(&f.permissions, "permissions", "", "Comma-separated permission group names or IDs (default: Zone:Read)")
+	fs.Var((*duration.Value)(&f.ttl), "ttl", "Token TTL, e.g. 90m, 8h, 2d, or 1w (use 0 for no expiration; default_ttl in config.json replaces the default)")
+	fs.StringVar(&f.allowCIDRs, "allow-cidrs", "", "Comma-separated CIDRs allowed to use the token (overrides config.json when provided)")
+	fs.Var(&f.conditions, "condition", "Further token condition as type.operator=value[,value...], e.g. request_ip.not_in=192.0.2.0/24 (can be specified multiple times)")
+	fs.BoolVar(&f.inspect, "inspect", false, "Inspect the new token's details once it is created")
+	fs.BoolVar(&f.raw, "raw", false, "With -inspect, print the token exactly as the API returned it, as JSON")
+	fs.BoolVar(&f.dryRun, "dry-run", false, "Preview the token creation without calling the Cloudflare API")
+	fs.BoolVar(&f.interactive, "interactive", false, "Ask for the zone, permission groups, TTL, and CIDRs the flags leave open, show the preview, and create the token only once confirmed")
+	fs.BoolVar(&f.yes, "yes", false, "Do not ask for confirmation before creating a token without expiry, without IP restriction, or with write access to a whole account")
+	fs.BoolVar(&f.pickPermissions, "pick-permissions", false, "Pick the permission groups to grant from the catalog, filtering by name, key, or description")
+	fs.StringVar(&f.againstTokenID, "against-token-id", "", "With -dry-run, diff the would-be token against the live configuration of this token ID")
+	fs.BoolVar(&f.scrub, "scrub", false, "After printing the token, wait for Enter and then clear it from the terminal and its scrollback")
+	fs.BoolVar(&f.dnsCanary, "dns-canary", false, "After creating a DNS write token, prove it works by creating and deleting a _cftoken-canary TXT record with it")
+	fs.StringVar(&f.progressFormat, "progress-format", "text", "Progress output on stderr during token creation: text or ndjson (one JSON event per step)")
+	fs.BoolVar(&f.noSink, "no-sink", false, "Print the token instead of delivering it to the zone's configured sink")
+	fs.StringVar(&f.outFile, "out-file", "", "Write the new token value to this file, readable only by you, instead of printing it or delivering it to the zone's sink")
+	fs.BoolVar(&f.share, "share", false, "Store the new token value as a single-view secret on the one-time secret service in config.json and print its link instead of the value")
+	fs.BoolVar(&f.force, "force", false, "With -out-file, replace an existing file")
+	fs.BoolVar(&f.explainConfig, "explain-config", false, "Before creating a token, print each effective setting and where it came from (flag, environment, config.json, zone config, or default) on stderr")
+	fs.Var(f.templateVars, "var", "Template variable in key=value format (can be specified multiple times; overrides config variables)")
+}
+
+// registerLegacy defines the deprecated flags that select another mode
+// than creation. They are only accepted without a command.
+func (f *runFlags) registerLegacy(fs *flag.FlagSet) {
+	fs.BoolVar(&f.listPermissions, "list-permissions", false, "Deprecated: use the list-permissions command")
+	fs.BoolVar(&f.listZones, "list-zones", false, "Deprecated: use the zones command")
+	fs.StringVar(&f.revoke, "revoke", "", "Deprecated: use the revoke command")
+	fs.StringVar(&f.inspectToken, "inspect-token", "", "Deprecated: use the inspect command; with -inspect and no zone, the token value to inspect instead of the management token")
+}
+
+// checkMisplacedFlags rejects the flags of token creation when they come
+// before command, which does not take them: `cftoken -ttl 1h zones` would
+// otherwise succeed and ignore -ttl.
+func checkMisplacedFlags(command string) error {
+	createOnly := createFlagNames()
+	var misplaced []string
+	flag.Visit(func(f *flag.Flag) {
+		if createOnly[f.Name] {
+			misplaced = append(misplaced, "-"+f.Name)
+		}
+	})
+	if len(misplaced) == 0 {
+		return nil
+	}
+	err := fmt.Errorf("%s cannot be used with the %s command; the flags of token creation go after `%s create` or `%s export`", strings.Join(misplaced, ", "), command, programName, programName)
+	return withCode(codeInvalidArgument, err, map[string]any{"command": command, "flags": misplaced})
+}
+
+// createFlagNames returns the names of the flags registerCreate and
+// registerLegacy define.
+func createFlagNames() map[string]bool {
+	fs := flag.NewFlagSet("create", flag.ContinueOnError)
+	f := runFlags{templateVars: &varFlag{}}
+	f.registerCreate(fs)
+	f.registerLegacy(fs)
+	names := make(map[string]bool)
+	fs.VisitAll(func(fl *flag.Flag) { names[fl.Name] = true })
+	return names
+}
+
+func run(parent context.Context) error {
 	var templateVars varFlag
 
-	flags := struct {
-		tokenPrefix     string
-		zoneID          string
-		zoneName        string
-		permissions     string
-		ttl             time.Duration
-		listPermissions bool
-		listZones       bool
-		allowCIDRs      string
-		inspect         bool
-		inspectToken    string
-		dryRun          bool
-		timeout         time.Duration
-		verbose         bool
-		templateVars    *varFlag
-	}{
+	flags := runFlags{
 		timeout:      30 * time.Second,
 		verbose:      false,
-		ttl:          8 * time.Hour,
+		ttl:          defaultTTL,
 		templateVars: &templateVars,
 	}
-
-	flag.StringVar(&flags.tokenPrefix, "token-prefix", "", "Prefix for the new API token (defaults to zone name if not provided; timestamp appended automatically)")
-	flag.StringVar(&flags.zoneID, "zone-id", "", "Zone identifier (UUID) the new token should access")
-	flag.StringVar(&flags.zoneName, "zone", "", "Zone name or configured zone with extended settings")
-	flag.StringVar(&flags.permissions, "permissions", "", "Comma-separated permission group names or IDs (default: Zone:Read)")
-	flag.DurationVar(&flags.ttl, "ttl", flags.ttl, "Token TTL (use 0 for no expiration)")
-	flag.BoolVar(&flags.listPermissions, "list-permissions", false, "List permission groups available to the current token and exit")
-	flag.BoolVar(&flags.listZones, "list-zones", false, "List configured zones, then exit")
-	flag.StringVar(&flags.allowCIDRs, "allow-cidrs", "", "Comma-separated CIDRs allowed to use the token (overrides config.json when provided)")
-	flag.BoolVar(&flags.inspect, "inspect", false, "Inspect token details. With token creation this inspects the new token; otherwise it inspects the management token or a provided value.")
-	flag.StringVar(&flags.inspectToken, "inspect-token", "", "Token value to inspect when used with -inspect outside of token creation")
-	flag.BoolVar(&flags.dryRun, "dry-run", false, "Preview the token creation without calling the Cloudflare API")
-	flag.DurationVar(&flags.timeout, "timeout", flags.timeout, "Request timeout (e.g. 15s, 1m)")
-	flag.BoolVar(&flags.verbose, "v", flags.verbose, "Enable verbose logging")
-	flag.Var(flags.templateVars, "var", "Template variable in key=value format (can be specified multiple times; overrides config variables)")
+	// Without a command, the flags of token creation are accepted as well,
+	// as creation is the default.
+	flags.register(flag.CommandLine)
+	flags.registerCreate(flag.CommandLine)
+	flags.registerLegacy(flag.CommandLine)
 	flag.Usage = usage
 	flag.Parse()
 
@@ -97,44 +246,256 @@ func run() error {
 		return nil
 	}
 
-	token := strings.TrimSpace(os.Getenv("CLOUDFLARE_API_TOKEN"))
-	if token == "" {
-		return fmt.Errorf("missing API token: export CLOUDFLARE_API_TOKEN before running this command")
+	if !slices.Contains([]string{"text", "json", outputYAML, outputShell, outputDotenv, outputGitLabDotenv, outputValue}, outputFormat) {
+		err := fmt.Errorf("unknown -output %q; available: text, json, yaml, shell, dotenv, gitlab-dotenv, value", outputFormat)
+		outputFormat = "text"
+		return withCode(codeInvalidArgument, err, nil)
 	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), flags.timeout)
-	defer cancel()
+	if err := validateLabels(tokenLabels); err != nil {
+		return err
+	}
 
-	logger := func(string, ...interface{}) {}
-	if flags.verbose {
-		logger = log.Printf
+	if buildReadOnly || os.Getenv("CFTOKEN_READ_ONLY") == "1" {
+		readOnly = true
+	}
+	if os.Getenv("CFTOKEN_ACCESSIBLE") == "1" {
+		accessible = true
 	}
 
-	client := cloudflare.NewClient(token,
-		cloudflare.WithUserAgent("cftoken-cli/0.1"),
-		cloudflare.WithLogger(logger),
+	stopProfiling, err := startProfiling(flags.profileCPU, flags.profileMem)
+	if err != nil {
+		return err
+	}
+	defer stopProfiling()
+
+	ctx, cancel := context.WithTimeout(parent, flags.timeout)
+	defer cancel()
+
+	// The management token is resolved only by the commands that call the
+	// API: token_source may run a helper or open the keychain, which help,
+	// completion, and offline commands must never do.
+	var (
+		tokenOnce  sync.Once
+		tokenValue string
 	)
+	token := func() string {
+		tokenOnce.Do(func() { tokenValue = resolveManagementToken(ctx) })
+		return tokenValue
+	}
+
+	// createSet holds the flags of token creation: the command line
+	// without a command, or the flags after create or export.
+	createSet := flag.CommandLine
+	command := flag.Arg(0)
+	if _, known := lookupCommand(command); known {
+		if err := checkMisplacedFlags(command); err != nil {
+			return err
+		}
+	}
+
+	if flag.NArg() > 0 {
+		switch cmd := command; cmd {
+		case "create":
+			createSet = flag.NewFlagSet(programName+" create", flag.ContinueOnError)
+			flags.registerCreate(createSet)
+			if err := createSet.Parse(flag.Args()[1:]); err != nil {
+				return withCode(codeInvalidArgument, err, nil)
+			}
+			if createSet.NArg() > 0 {
+				return withCode(codeInvalidArgument, fmt.Errorf("create takes no arguments, got %q; pass the zone with -zone", createSet.Arg(0)), nil)
+			}
+		case "export":
+			var opts exportOptions
+			var err error
+			opts, createSet, err = parseExportFlags(&flags, flag.Args()[1:])
+			if err != nil {
+				return err
+			}
+			if token() == "" {
+				return errMissingToken
+			}
+			if opts.tokenID != "" {
+				return runExport(ctx, newClient(token(), flags.verbose), opts)
+			}
+			// Resolve the token the creation flags describe as -dry-run
+			// would, and render it instead of the preview.
+			flags.exportFormat = opts.format
+			flags.dryRun = true
+		case "list-permissions":
+			fset := flag.NewFlagSet("list-permissions", flag.ContinueOnError)
+			if err := fset.Parse(flag.Args()[1:]); err != nil {
+				return withCode(codeInvalidArgument, err, nil)
+			}
+			if fset.NArg() > 0 {
+				return withCode(codeInvalidArgument, errors.New("usage: list-permissions"), nil)
+			}
+			if token() == "" {
+				return errMissingToken
+			}
+			return listPermissions(ctx, newClient(token(), flags.verbose))
+		case "doctor":
+			return runDoctor(ctx, token(), flags.verbose, flag.Args()[1:])
+		case "config":
+			return runConfig(ctx, token, flags.verbose, flag.Args()[1:])
+		case "template":
+			return runTemplate(flag.Args()[1:])
+		case "check":
+			return runCheck(flag.Args()[1:])
+		case "apply-template":
+			if token() == "" {
+				return errMissingToken
+			}
+			return runApplyTemplate(ctx, newClient(token(), flags.verbose), flag.Args()[1:])
+		case "export-request":
+			// Runs offline; the token is only used to look up AccountID.
+			var client *cloudflare.Client
+			if token() != "" {
+				client = newClient(token(), flags.verbose)
+			}
+			return runExportRequest(ctx, client, flag.Args()[1:])
+		case "fulfill-request":
+			if token() == "" {
+				return errMissingToken
+			}
+			return runFulfillRequest(ctx, newClient(token(), flags.verbose), flag.Args()[1:])
+		case "permissions":
+			if token() == "" {
+				return errMissingToken
+			}
+			return runPermissions(ctx, newClient(token(), flags.verbose), flag.Args()[1:])
+		case "portal":
+			if token() == "" {
+				return errMissingToken
+			}
+			return runPortal(ctx, newClient(token(), flags.verbose), flag.Args()[1:])
+		case "narrow":
+			if token() == "" {
+				return errMissingToken
+			}
+			return runNarrow(ctx, newClient(token(), flags.verbose), flag.Args()[1:])
+		case "revoke":
+			if token() == "" {
+				return errMissingToken
+			}
+			return runRevoke(ctx, newClient(token(), flags.verbose), flag.Args()[1:])
+		case "prune":
+			if token() == "" {
+				return errMissingToken
+			}
+			return runPrune(ctx, newClient(token(), flags.verbose), flag.Args()[1:])
+		case "whoami":
+			if token() == "" {
+				return errMissingToken
+			}
+			return runWhoami(ctx, newClient(token(), flags.verbose), flag.Args()[1:])
+		case "roll":
+			if token() == "" {
+				return errMissingToken
+			}
+			return runRoll(ctx, newClient(token(), flags.verbose), flag.Args()[1:])
+		case "extend":
+			if token() == "" {
+				return errMissingToken
+			}
+			return runExtend(ctx, newClient(token(), flags.verbose), flag.Args()[1:])
+		case "cache":
+			return runCache(flag.Args()[1:])
+		case "quota":
+			if token() == "" {
+				return errMissingToken
+			}
+			return runQuota(ctx, os.Stdout, newClient(token(), flags.verbose), flag.Args()[1:])
+		case "reissue":
+			if token() == "" {
+				return errMissingToken
+			}
+			return runReissue(ctx, newClient(token(), flags.verbose), flag.Args()[1:])
+		case "inspect":
+			return runInspect(ctx, flags.verbose, flag.Args()[1:])
+		case "labels":
+			return runLabels(flag.Args()[1:])
+		case "zone", "zones":
+			if flag.Arg(1) != "onboard" {
+				return runZone(ctx, token, flags.verbose, flag.Args()[1:])
+			}
+			name, issue, err := runZoneOnboard(ctx, token(), flags.verbose, flag.Args()[2:])
+			if err != nil || !issue {
+				return err
+			}
+			// Go on to issue the new zone's first token as `cftoken -zone NAME`
+			// would, so the global flags apply to it.
+			fmt.Println()
+			flags.zoneName = name
+		case "completion":
+			return runCompletion(flag.Args()[1:])
+		case "__complete":
+			// Hidden: the protocol the completion scripts speak.
+			return runComplete(flag.Args()[1:])
+		case "schema":
+			return runSchema(flag.Args()[1:])
+		case "help":
+			return runHelp(flag.Args()[1:])
+		case "history":
+			return runHistory(flag.Args()[1:])
+		case "journal":
+			return runJournal(ctx, token, flags.verbose, flag.Args()[1:])
+		default:
+			return withCode(codeInvalidArgument, fmt.Errorf("unknown command %q; run with -h for usage", cmd), map[string]any{"command": cmd})
+		}
+	}
+
+	if token() == "" {
+		return errMissingToken
+	}
+
+	client := newClient(token(), flags.verbose)
+	progress, err := newProgressReporter(flags.progressFormat, httpmw.RequestID(ctx), os.Stderr)
+	if err != nil {
+		return withCode(codeInvalidArgument, err, nil)
+	}
 
 	if flags.listPermissions {
+		log.Printf("warning: -list-permissions is deprecated; run `cftoken list-permissions`")
 		return listPermissions(ctx, client)
 	}
 
+	if flags.revoke != "" {
+		log.Printf("warning: -revoke is deprecated; run `cftoken revoke`")
+		args := []string{flags.revoke}
+		if flags.dryRun {
+			args = []string{"-dry-run", flags.revoke}
+		}
+		return runRevoke(ctx, client, args)
+	}
+
 	if flags.listZones {
+		log.Printf("warning: -list-zones is deprecated; run `cftoken zones`")
 		return listZones()
 	}
 
 	var (
 		allowCIDRsProvided  bool
 		permissionsProvided bool
+		ttlProvided         bool
 	)
-	flag.Visit(func(f *flag.Flag) {
+	setFlags := make(map[string]bool)
+	createSet.Visit(func(f *flag.Flag) {
+		setFlags[f.Name] = true
 		switch f.Name {
 		case "allow-cidrs":
 			allowCIDRsProvided = true
 		case "permissions":
 			permissionsProvided = true
+		case "ttl":
+			ttlProvided = true
 		}
 	})
+	if !ttlProvided {
+		if flags.ttl, err = tokenDefaultTTL(); err != nil {
+			return err
+		}
+	}
 
 	flags.tokenPrefix = strings.TrimSpace(flags.tokenPrefix)
 	flags.zoneID = strings.TrimSpace(flags.zoneID)
@@ -142,17 +503,96 @@ func run() error {
 	flags.allowCIDRs = strings.TrimSpace(flags.allowCIDRs)
 	flags.inspectToken = strings.TrimSpace(flags.inspectToken)
 
+	// The wizard's answers count as flags for the defaults below, and
+	// the same prompter asks for confirmation before the token is created.
+	// -pick-permissions asks only the wizard's permissions question.
+	var ask func(question, def string) (string, error)
+	discoverZones := func() ([]*cloudflare.ZoneDetails, error) {
+		ctx, cancel := context.WithTimeout(parent, flags.timeout)
+		defer cancel()
+		return client.ListZones(ctx, zonePickerLimit)
+	}
+	if flags.interactive || flags.pickPermissions {
+		if flags.pickPermissions && setFlags["permissions"] {
+			return withCode(codeInvalidArgument, errors.New("-pick-permissions cannot be combined with -permissions"), nil)
+		}
+		if !isTerminal(os.Stdin) {
+			name := "-interactive"
+			if !flags.interactive {
+				name = "-pick-permissions"
+			}
+			return withCode(codeInvalidArgument, fmt.Errorf("%s needs a terminal on stdin", name), nil)
+		}
+		ask = newPrompter(os.Stdin, os.Stderr)
+		wizard := createWizard{
+			ask: ask,
+			out: os.Stderr,
+			catalog: func() ([]cloudflare.PermissionGroup, error) {
+				ctx, cancel := context.WithTimeout(parent, flags.timeout)
+				defer cancel()
+				return client.PermissionGroups(ctx)
+			},
+			discover: discoverZones,
+		}
+		if flags.interactive {
+			if err := wizard.run(&flags, setFlags); err != nil {
+				return err
+			}
+		} else {
+			picked, err := wizard.askPermissions()
+			if err != nil {
+				return err
+			}
+			if len(picked) > 0 {
+				flags.permissions = strings.Join(picked, ",")
+				setFlags["permissions"] = true
+			}
+		}
+		allowCIDRsProvided, permissionsProvided = setFlags["allow-cidrs"], setFlags["permissions"]
+		// Time spent answering does not count against -timeout.
+		cancel()
+		ctx, cancel = context.WithTimeout(parent, flags.timeout)
+		defer cancel()
+	}
+
+	flags.againstTokenID = strings.TrimSpace(flags.againstTokenID)
+	if flags.againstTokenID != "" && !flags.dryRun {
+		return fmt.Errorf("-against-token-id requires -dry-run")
+	}
+
 	if flags.inspectToken != "" && !flags.inspect {
 		return fmt.Errorf("-inspect-token requires -inspect")
 	}
+	if flags.raw && !flags.inspect {
+		return fmt.Errorf("-raw requires -inspect")
+	}
+
+	// Without a zone, offer the configured zones and those visible to the
+	// token on a terminal instead of failing.
+	if flags.zoneName == "" && flags.zoneID == "" && !(flags.inspect && command == "") && isTerminal(os.Stdin) {
+		if ask == nil {
+			ask = newPrompter(os.Stdin, os.Stderr)
+		}
+		picker := createWizard{ask: ask, out: os.Stderr, discover: discoverZones}
+		if err := picker.askZone(&flags); err != nil {
+			return err
+		}
+		cancel()
+		ctx, cancel = context.WithTimeout(parent, flags.timeout)
+		defer cancel()
+	}
 
 	// Determine if user intends to create a token (has zone or token-prefix)
 	createToken := flags.tokenPrefix != "" || flags.zoneName != "" || flags.zoneID != ""
 	if createToken && flags.inspectToken != "" {
 		return fmt.Errorf("-inspect-token cannot be combined with token creation; the new token is inspected automatically")
 	}
-	if flags.inspect && !createToken {
-		return runInspection(ctx, client, flags.inspectToken)
+	if flags.inspect && !createToken && command == "" {
+		log.Printf("warning: -inspect without a zone is deprecated; run `cftoken inspect`, or `cftoken whoami` for the management token")
+		return runInspection(ctx, client, flags.inspectToken, flags.raw)
+	}
+	if createToken && readOnly && !flags.dryRun {
+		return fmt.Errorf("create token: %w; use -dry-run to preview", cloudflare.ErrReadOnly)
 	}
 
 	zoneID := flags.zoneID
@@ -178,10 +618,24 @@ func run() error {
 	if zoneID == "" {
 		return fmt.Errorf("missing zone identifier: provide via -zone-id or -zone")
 	}
+	if err := checkFrozen(resolvedZoneName, zoneID, zoneConfig); err != nil {
+		return err
+	}
+	if flags.ttl, err = zoneTTL(resolvedZoneName, zoneConfig, flags.ttl); err != nil {
+		return err
+	}
 
-	// Default token-prefix to zone name if not provided
-	if flags.tokenPrefix == "" && resolvedZoneName != "" {
-		flags.tokenPrefix = resolvedZoneName
+	// The permission catalog does not depend on the zone, so fetch it while
+	// the template, account, and resource groups are resolved. A template
+	// zone only needs it to check permissions.lock.json.
+	usesTemplate := zoneConfig != nil && !permissionsProvided && (zoneConfig.TemplateFile != "" || zoneConfig.TemplateInline != "")
+	catalogFetch := startCatalogFetch(ctx, client, !usesTemplate)
+
+	// Default token-prefix to default_token_prefix_template or the zone name
+	if flags.tokenPrefix == "" {
+		if flags.tokenPrefix, err = defaultTokenPrefix(resolvedZoneName, zoneID); err != nil {
+			return err
+		}
 	}
 
 	if flags.tokenPrefix == "" {
@@ -194,28 +648,17 @@ func run() error {
 		// Render permissions template if present, otherwise use static permissions
 		if !permissionsProvided {
 			if zoneConfig.TemplateFile != "" || zoneConfig.TemplateInline != "" {
-				// Merge variables with precedence: CLI flags > zone variables > auto-injected ZoneID
-				vars := make(template.Variables)
-
-				// Auto-inject ZoneID from zone config (lowest priority)
-				if zoneConfig.ZoneID != "" {
-					vars["ZoneID"] = zoneConfig.ZoneID
-				}
-
-				// Zone config variables (middle priority)
-				for k, v := range zoneConfig.Variables {
-					vars[k] = v
-				}
-
-				// CLI variables (highest priority - override everything)
-				for k, v := range *flags.templateVars {
-					vars[k] = v
+				if err := resolveAccountID(ctx, client, zoneConfig, zoneConfig.TemplateFile, zoneConfig.TemplateInline); err != nil {
+					return fmt.Errorf("zone %q: %w", flags.zoneName, err)
 				}
-
+				vars := templateVariables(zoneConfig, *flags.templateVars)
 				policies, err := template.RenderPolicies(zoneConfig.TemplateFile, zoneConfig.TemplateInline, vars)
 				if err != nil {
 					return fmt.Errorf("render policy template for zone %q: %w", flags.zoneName, err)
 				}
+				if policies, err = resolveResourceGroups(ctx, client, zoneConfig, policies); err != nil {
+					return fmt.Errorf("zone %q: %w", flags.zoneName, err)
+				}
 				renderedPolicies = policies
 			} else if len(zoneConfig.Permissions) > 0 {
 				// Use static permissions from zone config
@@ -228,13 +671,6 @@ func run() error {
 			flags.allowCIDRs = strings.Join(zoneConfig.AllowedCIDRs, ",")
 			allowCIDRsProvided = true
 		}
-
-		// Use zone TTL if specified
-		if zoneConfig.TTL != "" {
-			if ttlDuration, err := time.ParseDuration(zoneConfig.TTL); err == nil {
-				flags.ttl = ttlDuration
-			}
-		}
 	}
 
 	var configuredPermissions []string
@@ -271,13 +707,15 @@ func run() error {
 		}
 	}
 
-	creationTime := time.Now().UTC()
-	tokenName := flags.tokenPrefix + "-" + creationTime.Format("20060102T150405Z")
+	creationTime := clock.Now().UTC()
+	tokenName, err := generateName(zoneConfig, flags.tokenPrefix, creationTime, flags.dryRun)
+	if err != nil {
+		return err
+	}
 
 	var (
 		allowedCIDRs          []string
 		ipRestrictionDisabled bool
-		err                   error
 	)
 	if allowCIDRsProvided {
 		allowedCIDRs, ipRestrictionDisabled, err = parseAllowedCIDRs(flags.allowCIDRs)
@@ -308,6 +746,22 @@ func run() error {
 		return fmt.Errorf("no allowed CIDRs configured; set -allow-cidrs or add default_allowed_cidrs to config.json")
 	}
 
+	if flags.explainConfig {
+		if err := explainTokenConfig(setFlags, flags.timeout, tokenOrigins{
+			zoneName:              resolvedZoneName,
+			zoneConfig:            zoneConfig,
+			tokenPrefix:           flags.tokenPrefix,
+			ttl:                   flags.ttl,
+			permissions:           permissionInputs,
+			usesTemplate:          usesTemplate,
+			configuredPermissions: configuredPermissions,
+			allowedCIDRs:          allowedCIDRs,
+			ipRestrictionDisabled: ipRestrictionDisabled,
+		}); err != nil {
+			return err
+		}
+	}
+
 	var expiresOn *time.Time
 	if flags.ttl > 0 {
 		exp := creationTime.Add(flags.ttl)
@@ -316,11 +770,27 @@ func run() error {
 
 	// Build policies for dry-run and actual creation
 	// If no template was rendered, build a simple zone-scoped policy from permission inputs
+	fetched := progress.start("permissions")
+	lock, catalog, err := catalogFetch.wait()
+	if err != nil {
+		fetched(err, nil)
+		return err
+	}
 	policiesToUse := renderedPolicies
 	if len(policiesToUse) == 0 {
-		matchedGroups, err := client.MatchPermissions(ctx, permissionInputs)
+		var matchedGroups []cloudflare.PermissionGroup
+		switch {
+		case lock != nil:
+			matchedGroups, err = cloudflare.ResolvePermissions(catalog, pinPermissions(lock, permissionInputs))
+		case catalog != nil:
+			matchedGroups, err = cloudflare.ResolvePermissions(catalog, permissionInputs)
+		default:
+			matchedGroups, err = client.MatchPermissions(ctx, permissionInputs)
+		}
 		if err != nil {
-			return fmt.Errorf("match permission groups: %w", err)
+			err = fmt.Errorf("match permission groups: %w", err)
+			fetched(err, nil)
+			return err
 		}
 
 		resourceKey := fmt.Sprintf("com.cloudflare.api.account.zone.%s", zoneID)
@@ -339,47 +809,447 @@ func run() error {
 		}
 		policiesToUse = []template.Policy{policy}
 	}
+	policiesToUse = mergePolicies(tokenName, policiesToUse)
+	fetched(nil, nil)
+
+	rules, err := loadGuardrails(zoneConfig)
+	if err != nil {
+		return err
+	}
+	violations := rules.Evaluate(guardrail.Request{
+		TTL:          flags.ttl,
+		AllowedCIDRs: allowedCIDRs,
+		Permissions:  policyPermissions(policiesToUse),
+		Ticket:       changeTicket,
+	})
+	if len(violations) > 0 {
+		err := fmt.Errorf("guardrails rejected the token:\n  - %s", strings.Join(violations, "\n  - "))
+		return withCode(codeGuardrail, err, map[string]any{"violations": violations})
+	}
+	tokenName = ticketedName(tokenName, rules)
+	if flags.exportFormat != "" {
+		return writeTerraform(os.Stdout, plannedTerraformToken(tokenName, expiresOn, allowedCIDRs, flags.conditions, policiesToUse))
+	}
+
+	var tokenSink sink.Sink
+	if flags.share && flags.outFile != "" {
+		return withCode(codeInvalidArgument, errors.New("-share cannot be combined with -out-file"), nil)
+	}
+	if flags.share {
+		shareCfg, err := config.LoadShareConfig()
+		if errors.Is(err, fs.ErrNotExist) {
+			return withCode(codeInvalidArgument, errors.New("-share needs a share section in config.json"), nil)
+		}
+		if err != nil {
+			return err
+		}
+		if tokenSink, err = sink.Share(*shareCfg); err != nil {
+			return err
+		}
+	} else if flags.outFile != "" {
+		// Refuse before the token exists rather than fail to write it.
+		if _, err := os.Lstat(flags.outFile); err == nil && !flags.force {
+			return withCode(codeInvalidArgument, fmt.Errorf("-out-file %s already exists; pass -force to replace it", flags.outFile), nil)
+		}
+		tokenSink = sink.File(flags.outFile, flags.force)
+	} else if zoneConfig != nil && zoneConfig.Sink != nil && !flags.noSink {
+		tokenSink, err = sink.New(*zoneConfig.Sink)
+		if err != nil {
+			return fmt.Errorf("zone %q: %w", resolvedZoneName, err)
+		}
+	}
+
+	if err := requireValueSink(tokenSink != nil); err != nil {
+		return err
+	}
+
+	// Risky tokens are confirmed on a terminal; scripts pass -yes or run
+	// without one.
+	risks := confirmationRisks(expiresOn, ipRestrictionDisabled, policiesToUse)
+	if len(risks) > 0 && !flags.yes && !flags.interactive && !flags.dryRun && isTerminal(os.Stdin) {
+		if ask == nil {
+			ask = newPrompter(os.Stdin, os.Stderr)
+		}
+		ok, err := confirmRisks(ask, os.Stderr, risks)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return errDeclined
+		}
+		cancel()
+		ctx, cancel = context.WithTimeout(parent, flags.timeout)
+		defer cancel()
+	}
+
+	if flags.interactive && !flags.dryRun {
+		if err := printDryRun(os.Stderr, tokenName, zoneID, resolvedZoneName, expiresOn, allowedCIDRs, policiesToUse); err != nil {
+			return err
+		}
+		if tokenSink != nil {
+			fmt.Fprintf(os.Stderr, "Would deliver to %s\n", tokenSink)
+		}
+		ok := false
+		if len(risks) > 0 {
+			ok, err = confirmRisks(ask, os.Stderr, risks)
+		} else {
+			var answer string
+			answer, err = ask("Create this token? (y/N)", "n")
+			ok = strings.EqualFold(answer, "y") || strings.EqualFold(answer, "yes")
+		}
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return errDeclined
+		}
+		cancel()
+		ctx, cancel = context.WithTimeout(parent, flags.timeout)
+		defer cancel()
+	}
+
+	// approvedBy is the second operator who approved a high-risk token
+	// through the approval link. With approval_link configured every
+	// high-risk creation waits for one, whatever -yes, -interactive, or
+	// stdin say.
+	var approvedBy string
+	if risks := issuanceRisks(expiresOn, ipRestrictionDisabled); len(risks) > 0 && !flags.dryRun {
+		var preview strings.Builder
+		if err := printDryRun(&preview, tokenName, zoneID, resolvedZoneName, expiresOn, allowedCIDRs, policiesToUse); err != nil {
+			return err
+		}
+		if tokenSink != nil {
+			fmt.Fprintf(&preview, "Would deliver to %s\n", tokenSink)
+		}
+		if approvedBy, err = requestApproval(parent, os.Stderr, preview.String(), risks); err != nil {
+			return err
+		}
+		if approvedBy != "" {
+			cancel()
+			ctx, cancel = context.WithTimeout(parent, flags.timeout)
+			defer cancel()
+		}
+	}
 
 	if flags.dryRun {
-		if err := printDryRun(tokenName, zoneID, resolvedZoneName, expiresOn, allowedCIDRs, policiesToUse); err != nil {
+		if err := printDryRun(reportOutput(), tokenName, zoneID, resolvedZoneName, expiresOn, allowedCIDRs, policiesToUse); err != nil {
 			return fmt.Errorf("dry run failed: %w", err)
 		}
+		if flags.againstTokenID != "" {
+			desc, err := client.DescribeToken(ctx, flags.againstTokenID)
+			if err != nil {
+				return fmt.Errorf("fetch token to compare: %w", err)
+			}
+			fmt.Fprintln(reportOutput())
+			printPayloadDiff(reportOutput(), desc, tokenName, expiresOn, allowedCIDRs, policiesToUse)
+		}
+		if tokenSink != nil {
+			fmt.Fprintf(reportOutput(), "Would deliver to %s\n", tokenSink)
+		}
 		return nil
 	}
 
-	// Convert template.Policy to cloudflare.Policy
-	cfPolicies := make([]cloudflare.Policy, len(policiesToUse))
-	for i, tplPolicy := range policiesToUse {
-		cfPolicies[i] = cloudflare.Policy{
-			ID:        tplPolicy.ID,
-			Effect:    tplPolicy.Effect,
-			Resources: tplPolicy.Resources,
-		}
-		// Convert permission groups
-		for _, pg := range tplPolicy.PermissionGroups {
-			cfPolicies[i].PermissionGroups = append(cfPolicies[i].PermissionGroups, cloudflare.PolicyPermissionGroup{
-				ID:   pg.ID,
-				Name: pg.Name,
-			})
-		}
+	cfPolicies := toCloudflarePolicies(policiesToUse)
+	var createOpts []cloudflare.CreateOption
+	if expiresOn != nil {
+		createOpts = append(createOpts, cloudflare.WithExpiry(*expiresOn))
 	}
-	result, err := client.CreateTokenWithPolicies(ctx, tokenName, cfPolicies, expiresOn, allowedCIDRs)
+	if len(allowedCIDRs) > 0 {
+		createOpts = append(createOpts, cloudflare.WithAllowedCIDRs(allowedCIDRs...))
+	}
+	for _, c := range flags.conditions {
+		createOpts = append(createOpts, cloudflare.WithCondition(c))
+	}
+	if uniqueNames(zoneConfig) {
+		createOpts = append(createOpts, cloudflare.WithUniqueName())
+	}
+	hold, err := checkBudget(1)
+	if err != nil {
+		return err
+	}
+	defer hold.release()
+	j := beginJournal(ctx, "create")
+	created := progress.start("create")
+	result, err := client.CreateToken(ctx, tokenName, cfPolicies, createOpts...)
 
 	if err != nil {
+		created(err, nil)
+		if err := j.Complete(); err != nil {
+			log.Printf("warning: %v", err)
+		}
 		return fmt.Errorf("token creation failed: %w", err)
 	}
+	created(nil, map[string]string{"token_id": result.ID, "name": result.Name})
+	recordIssued(hold, result)
+	labelIssued(result)
+	if err := j.Record(stepCreateToken, map[string]string{"token_id": result.ID, "name": result.Name}); err != nil {
+		log.Printf("warning: %v", err)
+	}
+	if len(renderedPolicies) > 0 {
+		recordRevision(resolvedZoneName, zoneID, templateSource(zoneConfig.TemplateFile), renderedPolicies, revision.Issuance{
+			TokenID:       result.ID,
+			TokenName:     result.Name,
+			AllowedCIDRs:  allowedCIDRs,
+			CorrelationID: httpmw.RequestID(ctx),
+		})
+	}
 
-	printTokenResult(result, resolvedZoneName, flags.ttl)
-	if flags.inspect {
+	var canaryErr error
+	if flags.dnsCanary {
+		switch {
+		case !grantsDNSWrite(policiesToUse):
+			fmt.Fprintln(reportOutput(), "Skipping DNS canary: the token does not grant DNS write.")
+			progress.skip("verify", "token does not grant DNS write")
+		case result.Value == "":
+			canaryErr = errors.New("dns canary: API did not return the token value")
+			progress.start("verify")(canaryErr, nil)
+		default:
+			verified := progress.start("verify")
+			canaryErr = runDNSCanary(ctx, client, result.Value, zoneID, flags.verbose)
+			verified(canaryErr, map[string]string{"zone_id": zoneID})
+		}
+		if canaryErr == nil {
+			if err := j.Record("dns_canary", map[string]string{"zone_id": zoneID}); err != nil {
+				log.Printf("warning: %v", err)
+			}
+		}
+	}
+
+	// On successful delivery the value is not echoed unless
+	// print_token_values is always and no -out-file was given; -share shows
+	// its link instead. On failure it is printed as usual so the
+	// new token is not lost. A failed canary skips
+	// delivery so automation never receives a token that cannot write.
+	var deliveryErr error
+	if tokenSink != nil && canaryErr != nil {
+		progress.skip("sink", "dns canary failed")
+	}
+	if tokenSink != nil && canaryErr == nil {
+		sent := progress.start("sink")
+		if result.Value == "" {
+			deliveryErr = fmt.Errorf("deliver to %s: API did not return the token value", tokenSink)
+		} else if deliveryErr = tokenSink.Deliver(ctx, result.Value); deliveryErr != nil {
+			deliveryErr = fmt.Errorf("deliver to %s: %w", tokenSink, deliveryErr)
+		} else {
+			if err := j.Record("deliver", map[string]string{"sink": tokenSink.String()}); err != nil {
+				log.Printf("warning: %v", err)
+			}
+			if link, ok := tokenSink.(sink.Linker); ok {
+				shared := *result
+				shared.Value = fmt.Sprintf("<single-view link: %s>", link.Link())
+				result = &shared
+			} else if policy, err := tokenValuePolicy(); err != nil || policy != printValuesAlways || flags.outFile != "" {
+				if err != nil {
+					log.Printf("warning: %v; not printing the delivered token value", err)
+				}
+				delivered := *result
+				delivered.Value = fmt.Sprintf("<delivered to %s>", tokenSink)
+				result = &delivered
+			}
+		}
+		sent(deliveryErr, map[string]string{"sink": tokenSink.String()})
+	}
+	// -out-file and -share keep the value off the terminal even when it
+	// could not be delivered; the journal keeps the token so it can be
+	// rolled back.
+	if (flags.outFile != "" || flags.share) && (canaryErr != nil || deliveryErr != nil) {
+		withheld := *result
+		withheld.Value = fmt.Sprintf("<not written to %s>", flags.outFile)
+		if flags.share {
+			withheld.Value = fmt.Sprintf("<not delivered to %s>", tokenSink)
+		}
+		result = &withheld
+	}
+
+	// Only a failed canary or delivery leaves the journal behind; every later
+	// step is informational.
+	if canaryErr == nil && deliveryErr == nil {
+		if err := j.Complete(); err != nil {
+			log.Printf("warning: %v", err)
+		}
+	}
+
+	if flags.scrub {
+		if err := printAndScrub(os.Stdin, os.Stdout, func(w io.Writer) {
+			printTokenResult(w, result, resolvedZoneName, flags.ttl)
+		}); err != nil {
+			return err
+		}
+	} else {
+		printTokenResult(os.Stdout, result, resolvedZoneName, flags.ttl)
+	}
+	if risks := issuanceRisks(expiresOn, ipRestrictionDisabled); len(risks) > 0 {
+		if approvedBy != "" {
+			risks = append(risks, "approved by "+approvedBy)
+		}
+		if err := notifyHighRisk(ctx, result, coalesce(resolvedZoneName, zoneID), risks); err != nil {
+			log.Printf("warning: high-risk issuance notification failed: %v", err)
+		}
+	}
+	// A structured -inspect document joins the result on stdout; elsewhere
+	// the inspection is part of the report.
+	inspectOut := reportOutput()
+	if structuredOutput() {
+		inspectOut = os.Stdout
+	}
+	if flags.inspect && flags.raw {
+		if err := printRawToken(ctx, inspectOut, client, result.ID); err != nil {
+			return fmt.Errorf("inspect token: %w", err)
+		}
+	} else if flags.inspect {
 		desc, err := client.DescribeToken(ctx, result.ID)
 		if err != nil {
 			return fmt.Errorf("inspect token: %w", err)
 		}
-		printTokenInspection(desc)
+		printTokenInspection(inspectOut, desc, viewList, false)
+	}
+	if err := errors.Join(canaryErr, deliveryErr); err != nil {
+		return journalError(err, j)
 	}
 	return nil
 }
 
+// resolveManagementToken is managementToken, swappable in tests.
+var resolveManagementToken = managementToken
+
+// managementToken resolves the management token from CLOUDFLARE_API_TOKEN
+// and then the configured token_source, or the platform keyring when none
+// is configured. Problems are logged and yield an empty token.
+func managementToken(ctx context.Context) string {
+	provider, err := tokenProvider()
+	if err != nil {
+		log.Printf("warning: %v", err)
+		return ""
+	}
+	token, err := provider.Token(ctx)
+	if err != nil && !errors.Is(err, credential.ErrNotFound) {
+		log.Printf("warning: management token: %v", err)
+	}
+	return token
+}
+
+func tokenProvider() (credential.Provider, error) {
+	env := credential.Env("CLOUDFLARE_API_TOKEN")
+	source, timeout, err := config.LoadTokenSource()
+	if errors.Is(err, fs.ErrNotExist) {
+		return credential.Chain(env, credential.Keyring(credential.DefaultTarget)), nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("load token_source: %w", err)
+	}
+	var opts []credential.Option
+	if timeout != "" {
+		d, err := duration.Parse(timeout)
+		if err != nil || d <= 0 {
+			return nil, fmt.Errorf("token_source_timeout %q: must be a positive duration", timeout)
+		}
+		opts = append(opts, credential.WithExecTimeout(d))
+	}
+	configured, err := credential.Parse(source, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return credential.Chain(env, configured), nil
+}
+
+// testClientOptions are appended to every client newClient builds; tests
+// use it to point the clients at a fake API.
+var testClientOptions []cloudflare.Option
+
+func newClient(token string, verbose bool) *cloudflare.Client {
+	logger := func(string, ...interface{}) {}
+	if verbose {
+		logger = log.Printf
+	}
+	opts := []cloudflare.Option{
+		cloudflare.WithUserAgent("cftoken-cli/0.1"),
+		cloudflare.WithLogger(logger),
+		cloudflare.WithTokenAccount(tokenAccount),
+	}
+	if readOnly {
+		opts = append(opts, cloudflare.WithReadOnly())
+	}
+	if noCache {
+		opts = append(opts, cloudflare.WithCacheTTL(0))
+	} else if store, err := openCache(); err == nil {
+		opts = append(opts, cloudflare.WithPersistentCache(store))
+	} else if verbose {
+		log.Printf("cache disabled: %v", err)
+	}
+	return cloudflare.NewClient(token, append(opts, testClientOptions...)...)
+}
+
+// templateVariables merges template variables with precedence:
+// CLI flags > zone variables > auto-injected ZoneID.
+func templateVariables(zoneConfig *config.ZoneConfig, cliVars map[string]string) template.Variables {
+	vars := make(template.Variables)
+	if zoneConfig.ZoneID != "" {
+		vars["ZoneID"] = zoneConfig.ZoneID
+	}
+	if zoneConfig.AccountID != "" {
+		vars["AccountID"] = zoneConfig.AccountID
+	}
+	for k, v := range zoneConfig.Variables {
+		vars[k] = v
+	}
+	for k, v := range cliVars {
+		vars[k] = v
+	}
+	return vars
+}
+
+// resolveAccountID fills in zoneConfig.AccountID when the template reads
+// AccountID and the zone does not set it: first from config.json, then from
+// the zone's owner, then from the only account the token can access. A nil
+// client, as on an offline machine, only allows the first.
+func resolveAccountID(ctx context.Context, client *cloudflare.Client, zoneConfig *config.ZoneConfig, templatePath, inlineTemplate string) error {
+	if zoneConfig.AccountID != "" {
+		return nil
+	}
+	refs, err := template.ReferencedVariables(templatePath, inlineTemplate)
+	if err != nil {
+		return err
+	}
+	if !slices.Contains(refs, "AccountID") {
+		return nil
+	}
+	return lookupAccountID(ctx, client, zoneConfig)
+}
+
+// lookupAccountID sets zoneConfig.AccountID from config.json, the zone's
+// owner, or the only account the token can access, in that order.
+func lookupAccountID(ctx context.Context, client *cloudflare.Client, zoneConfig *config.ZoneConfig) error {
+	if zoneConfig.AccountID != "" {
+		return nil
+	}
+	if id, err := config.LoadAccountID(); err == nil {
+		zoneConfig.AccountID = id
+		return nil
+	} else if !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	if client == nil {
+		return errors.New("resolve AccountID: no management token to look it up with; set account_id in config.json")
+	}
+	if zoneConfig.ZoneID != "" {
+		id, err := client.ZoneAccountID(ctx, zoneConfig.ZoneID)
+		if err != nil {
+			return fmt.Errorf("resolve AccountID: %w", err)
+		}
+		zoneConfig.AccountID = id
+		return nil
+	}
+	ids, err := client.AccountIDs(ctx)
+	if err != nil {
+		return fmt.Errorf("resolve AccountID: %w", err)
+	}
+	if len(ids) != 1 {
+		return fmt.Errorf("resolve AccountID: token can access %d accounts; set account_id in config.json", len(ids))
+	}
+	zoneConfig.AccountID = ids[0]
+	return nil
+}
+
 func looksLikeZoneID(s string) bool {
 	if len(s) != 32 {
 		return false
@@ -428,67 +1298,163 @@ func normalizeCIDRList(values []string) ([]string, bool, error) {
 	return out, false, nil
 }
 
-func printTokenResult(result *cloudflare.TokenResult, zoneName string, ttl time.Duration) {
-	fmt.Println("Token created successfully.")
-	fmt.Printf("Name:   %s\n", result.Name)
-	fmt.Printf("ID:     %s\n", result.ID)
-	fmt.Printf("Value:  %s\n", stringOrDefault(result.Value, "<redacted by API>"))
-	fmt.Printf("Status: %s\n", stringOrDefault(result.Status, "<unknown>"))
+func printTokenResult(w io.Writer, result *cloudflare.TokenResult, zoneName string, ttl time.Duration) {
+	if envOutput() {
+		writeTokenEnv(w, result, zoneName, outputFormat)
+		return
+	}
+	if outputFormat == outputValue {
+		printed := *result
+		printed.Value = writeValueOnly(w, result.Value)
+		result, w = &printed, os.Stderr
+	}
+	if structuredOutput() {
+		if err := writeDocument(w, newTokenDocument(result, zoneName)); err != nil {
+			log.Printf("warning: %v", err)
+		}
+		return
+	}
+	fmt.Fprintln(w, "Token created successfully.")
+	fmt.Fprintf(w, "Name:   %s\n", result.Name)
+	fmt.Fprintf(w, "ID:     %s\n", result.ID)
+	fmt.Fprintf(w, "Value:  %s\n", displayedValue(result.Value))
+	fmt.Fprintf(w, "Status: %s\n", stringOrDefault(result.Status, "<unknown>"))
 	zoneDisplay := result.ZoneID
 	if zoneName != "" {
 		zoneDisplay = fmt.Sprintf("%s (%s)", result.ZoneID, zoneName)
 	}
-	fmt.Printf("Zone ID: %s\n", zoneDisplay)
+	fmt.Fprintf(w, "Zone ID: %s\n", zoneDisplay)
 	expires := "none"
 	if result.ExpiresOn != "" {
 		expires = result.ExpiresOn
 	} else if ttl > 0 {
 		expires = "<not returned>"
 	}
-	fmt.Printf("Expires: %s\n", expires)
-	fmt.Printf("Allowed CIDRs: %s\n", joinOrDefault(result.AllowedCIDRs, "none"))
+	fmt.Fprintf(w, "Expires: %s\n", expires)
+	fmt.Fprintf(w, "Allowed CIDRs: %s\n", joinOrDefault(result.AllowedCIDRs, "none"))
 }
 
-func printTokenInspection(desc *cloudflare.TokenInspection) {
-	if desc == nil {
-		fmt.Println("Token details unavailable.")
-		return
-	}
-	fmt.Println("Token details:")
-	fmt.Printf("ID: %s\n", stringOrDefault(desc.ID, "<unknown>"))
-	fmt.Printf("Name: %s\n", stringOrDefault(desc.Name, "<unspecified>"))
-	fmt.Printf("Status: %s\n", stringOrDefault(desc.Status, "<unknown>"))
-	fmt.Printf("Expires: %s\n", stringOrDefault(desc.ExpiresOn, "none"))
-	if desc.NotBefore != "" {
-		fmt.Printf("Not Before: %s\n", desc.NotBefore)
+// toCloudflarePolicies converts rendered template policies to API policies.
+func toCloudflarePolicies(policies []template.Policy) []cloudflare.Policy {
+	cfPolicies := make([]cloudflare.Policy, len(policies))
+	for i, tplPolicy := range policies {
+		cfPolicies[i] = cloudflare.Policy{
+			ID:        tplPolicy.ID,
+			Effect:    tplPolicy.Effect,
+			Resources: tplPolicy.Resources,
+		}
+		for _, pg := range tplPolicy.PermissionGroups {
+			cfPolicies[i].PermissionGroups = append(cfPolicies[i].PermissionGroups, cloudflare.PolicyPermissionGroup{
+				ID:   pg.ID,
+				Name: pg.Name,
+			})
+		}
 	}
-	fmt.Printf("Allowed CIDRs: %s\n", joinOrDefault(desc.AllowedCIDRs, "none"))
-	fmt.Printf("Denied CIDRs: %s\n", joinOrDefault(desc.DeniedCIDRs, "none"))
-	if len(desc.Policies) == 0 {
-		fmt.Println("Policies: none")
-		return
+	return cfPolicies
+}
+
+// loadGuardrails returns the global guardrails tightened by the zone's own.
+func loadGuardrails(zoneConfig *config.ZoneConfig) (guardrail.Rules, error) {
+	var rules guardrail.Rules
+	global, err := config.LoadGuardrails()
+	switch {
+	case err == nil:
+		if rules, err = guardrail.Parse(*global); err != nil {
+			return guardrail.Rules{}, fmt.Errorf("guardrails: %w", err)
+		}
+	case !errors.Is(err, fs.ErrNotExist):
+		return guardrail.Rules{}, fmt.Errorf("load guardrails: %w", err)
 	}
-	fmt.Println("Policies:")
-	for idx, policy := range desc.Policies {
-		fmt.Printf("  %d. Effect: %s\n", idx+1, stringOrDefault(policy.Effect, "<unknown>"))
-		fmt.Printf("     Resources: %s\n", joinOrDefault(policy.Resources, "none"))
-		if len(policy.PermissionGroups) == 0 {
-			fmt.Println("     Permission Groups: none")
-			continue
+	if zoneConfig != nil && zoneConfig.Guardrails != nil {
+		zoneRules, err := guardrail.Parse(*zoneConfig.Guardrails)
+		if err != nil {
+			return guardrail.Rules{}, fmt.Errorf("zone guardrails: %w", err)
 		}
-		fmt.Println("     Permission Groups:")
-		for _, grp := range policy.PermissionGroups {
-			display := coalesce(grp.Name, grp.Key, grp.ID)
-			if grp.Key != "" && grp.Key != display {
-				fmt.Printf("       - %s (%s, key: %s)\n", display, grp.ID, grp.Key)
-			} else {
-				fmt.Printf("       - %s (%s)\n", display, grp.ID)
+		rules = rules.Tighten(zoneRules)
+	}
+	return rules, nil
+}
+
+// policyPermissions lists the ID and name of every permission group in policies.
+func policyPermissions(policies []template.Policy) []string {
+	var out []string
+	for _, p := range policies {
+		for _, pg := range p.PermissionGroups {
+			if pg.ID != "" {
+				out = append(out, pg.ID)
+			}
+			if pg.Name != "" {
+				out = append(out, pg.Name)
 			}
 		}
 	}
+	return out
 }
 
-func runInspection(ctx context.Context, management *cloudflare.Client, overrideToken string) error {
+// issuanceRisks lists the reasons a token configuration is considered high risk.
+func issuanceRisks(expiresOn *time.Time, ipRestrictionDisabled bool) []string {
+	var risks []string
+	if expiresOn == nil {
+		risks = append(risks, "token never expires")
+	}
+	if ipRestrictionDisabled {
+		risks = append(risks, "IP restrictions disabled")
+	}
+	return risks
+}
+
+func notifyHighRisk(ctx context.Context, result *cloudflare.TokenResult, zone string, reasons []string) error {
+	emailCfg, err := config.LoadEmailNotification()
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	notifier, err := notify.NewEmailNotifier(*emailCfg)
+	if err != nil {
+		return err
+	}
+	return notifier.Notify(ctx, notify.Event{
+		Kind:          notify.EventHighRiskIssuance,
+		Time:          clock.Now().UTC(),
+		TokenName:     result.Name,
+		TokenID:       result.ID,
+		Zone:          zone,
+		ExpiresOn:     result.ExpiresOn,
+		AllowedCIDRs:  result.AllowedCIDRs,
+		Reasons:       reasons,
+		CorrelationID: httpmw.RequestID(ctx),
+	})
+}
+
+// printTokenInspection writes a token's configuration with its policies in
+// the given view.
+func printTokenInspection(w io.Writer, desc *cloudflare.TokenInspection, view string, color bool) {
+	if desc == nil {
+		fmt.Fprintln(w, "Token details unavailable.")
+		return
+	}
+	if structuredOutput() {
+		if err := writeDocument(w, desc); err != nil {
+			log.Printf("warning: %v", err)
+		}
+		return
+	}
+	fmt.Fprintln(w, "Token details:")
+	fmt.Fprintf(w, "ID: %s\n", stringOrDefault(desc.ID, "<unknown>"))
+	fmt.Fprintf(w, "Name: %s\n", stringOrDefault(desc.Name, "<unspecified>"))
+	fmt.Fprintf(w, "Status: %s\n", stringOrDefault(desc.Status, "<unknown>"))
+	fmt.Fprintf(w, "Expires: %s\n", stringOrDefault(desc.ExpiresOn, "none"))
+	if desc.NotBefore != "" {
+		fmt.Fprintf(w, "Not Before: %s\n", desc.NotBefore)
+	}
+	fmt.Fprintf(w, "Allowed CIDRs: %s\n", joinOrDefault(desc.AllowedCIDRs, "none"))
+	fmt.Fprintf(w, "Denied CIDRs: %s\n", joinOrDefault(desc.DeniedCIDRs, "none"))
+	printPolicies(w, desc.Policies, view, color)
+}
+
+func runInspection(ctx context.Context, management *cloudflare.Client, overrideToken string, raw bool) error {
 	var (
 		verification *cloudflare.TokenVerification
 		err          error
@@ -496,6 +1462,7 @@ func runInspection(ctx context.Context, management *cloudflare.Client, overrideT
 	if overrideToken != "" {
 		verifyClient := cloudflare.NewClient(overrideToken,
 			cloudflare.WithUserAgent("cftoken-cli/0.1"),
+			cloudflare.WithTokenAccount(tokenAccount),
 		)
 		verification, err = verifyClient.VerifyToken(ctx)
 		if err != nil {
@@ -508,19 +1475,56 @@ func runInspection(ctx context.Context, management *cloudflare.Client, overrideT
 		}
 	}
 
+	if raw {
+		return printRawToken(ctx, os.Stdout, management, verification.ID)
+	}
 	desc, err := management.DescribeToken(ctx, verification.ID)
 	if err != nil {
 		return fmt.Errorf("describe token: %w", err)
 	}
-	printTokenInspection(desc)
+	printTokenInspection(os.Stdout, desc, viewList, false)
 	return nil
 }
 
+// printRawToken writes the API response for a token unchanged, for filing
+// issues and spotting fields the summary leaves out.
+func printRawToken(ctx context.Context, w io.Writer, client *cloudflare.Client, tokenID string) error {
+	raw, err := client.RawToken(ctx, tokenID)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "%s\n", bytes.TrimSpace(raw))
+	return err
+}
+
+// zoneTTL returns the TTL a zone configures, or fallback when it sets none.
+// A TTL that does not parse is an error naming the zone and the value rather
+// than a silent fallback.
+func zoneTTL(name string, zoneConfig *config.ZoneConfig, fallback time.Duration) (time.Duration, error) {
+	if zoneConfig == nil || zoneConfig.TTL == "" {
+		return fallback, nil
+	}
+	ttl, err := duration.Parse(zoneConfig.TTL)
+	if err != nil {
+		return 0, withCode(codeInvalidArgument, fmt.Errorf("zone %q: ttl: %w", name, err), map[string]any{"zone": name, "ttl": zoneConfig.TTL})
+	}
+	return ttl, nil
+}
+
 func listPermissions(ctx context.Context, client *cloudflare.Client) error {
 	perms, err := client.PermissionGroups(ctx)
 	if err != nil {
 		return fmt.Errorf("failed to fetch permission groups: %w", err)
 	}
+	sort.SliceStable(perms, func(i, j int) bool {
+		if perms[i].Name != perms[j].Name {
+			return perms[i].Name < perms[j].Name
+		}
+		return perms[i].ID < perms[j].ID
+	})
+	if structuredOutput() {
+		return writeDocument(os.Stdout, perms)
+	}
 	for _, pg := range perms {
 		fmt.Printf("%s\t%s\n", pg.ID, pg.Name)
 		desc := pg.Description
@@ -538,6 +1542,12 @@ func listPermissions(ctx context.Context, client *cloudflare.Client) error {
 }
 
 func listZones() error {
+	// Conflicting entries are left out of the list rather than failing it.
+	if conflicts, err := config.ZoneConflicts(); err == nil {
+		for _, conflict := range conflicts {
+			log.Printf("warning: %v", conflict)
+		}
+	}
 	zones, err := config.ListConfiguredZones()
 	if err != nil {
 		if errors.Is(err, os.ErrNotExist) {
@@ -548,6 +1558,9 @@ func listZones() error {
 		}
 		return fmt.Errorf("failed to load configured zones: %w", err)
 	}
+	if structuredOutput() {
+		return writeDocument(os.Stdout, zones)
+	}
 	tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
 	fmt.Fprintln(tw, "ZONE\tID\tSOURCE")
 	for _, zone := range zones {
@@ -557,39 +1570,39 @@ func listZones() error {
 	return nil
 }
 
-func printDryRun(tokenName, zoneID, zoneName string, expiresOn *time.Time, allowedCIDRs []string, policies []template.Policy) error {
-	fmt.Println("DRY RUN: no changes made.")
-	fmt.Println("Token would be created with:")
-	fmt.Printf("  Name: %s\n", tokenName)
+func printDryRun(w io.Writer, tokenName, zoneID, zoneName string, expiresOn *time.Time, allowedCIDRs []string, policies []template.Policy) error {
+	fmt.Fprintln(w, "DRY RUN: no changes made.")
+	fmt.Fprintln(w, "Token would be created with:")
+	fmt.Fprintf(w, "  Name: %s\n", tokenName)
 	if zoneName != "" {
-		fmt.Printf("  Zone: %s (%s)\n", zoneName, zoneID)
+		fmt.Fprintf(w, "  Zone: %s (%s)\n", zoneName, zoneID)
 	} else {
-		fmt.Printf("  Zone ID: %s\n", zoneID)
+		fmt.Fprintf(w, "  Zone ID: %s\n", zoneID)
 	}
 	if expiresOn != nil {
-		fmt.Printf("  Expires: %s\n", expiresOn.UTC().Format(time.RFC3339))
+		fmt.Fprintf(w, "  Expires: %s\n", expiresOn.UTC().Format(time.RFC3339))
 	} else {
-		fmt.Println("  Expires: none")
+		fmt.Fprintln(w, "  Expires: none")
 	}
-	fmt.Printf("  Allowed CIDRs: %s\n", joinOrDefault(allowedCIDRs, "none"))
+	fmt.Fprintf(w, "  Allowed CIDRs: %s\n", joinOrDefault(allowedCIDRs, "none"))
 
-	fmt.Println("  Policies:")
+	fmt.Fprintln(w, "  Policies:")
 	for idx, policy := range policies {
-		fmt.Printf("    Policy %d:\n", idx+1)
-		fmt.Printf("      Effect: %s\n", policy.Effect)
+		fmt.Fprintf(w, "    Policy %d:\n", idx+1)
+		fmt.Fprintf(w, "      Effect: %s\n", policy.Effect)
 		if len(policy.Resources) > 0 {
-			fmt.Println("      Resources:")
-			for key, val := range policy.Resources {
-				fmt.Printf("        %s: %v\n", key, val)
+			fmt.Fprintln(w, "      Resources:")
+			for _, key := range sortedKeys(policy.Resources) {
+				fmt.Fprintf(w, "        %s: %s\n", key, cloudflare.ResourceValueString(policy.Resources[key]))
 			}
 		}
 		if len(policy.PermissionGroups) > 0 {
-			fmt.Println("      Permission Groups:")
+			fmt.Fprintln(w, "      Permission Groups:")
 			for _, pg := range policy.PermissionGroups {
 				if pg.Name != "" {
-					fmt.Printf("        - %s (%s)\n", pg.Name, pg.ID)
+					fmt.Fprintf(w, "        - %s (%s)\n", pg.Name, pg.ID)
 				} else {
-					fmt.Printf("        - %s\n", pg.ID)
+					fmt.Fprintf(w, "        - %s\n", pg.ID)
 				}
 			}
 		}
@@ -597,6 +1610,17 @@ func printDryRun(tokenName, zoneID, zoneName string, expiresOn *time.Time, allow
 	return nil
 }
 
+// sortedKeys returns the keys of m in ascending order so output is stable
+// between runs.
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
 func stringOrDefault(value, fallback string) string {
 	if strings.TrimSpace(value) == "" {
 		return fallback
@@ -619,11 +1643,3 @@ func coalesce(values ...string) string {
 	}
 	return ""
 }
-
-func usage() {
-	fmt.Fprintf(flag.CommandLine.Output(), "Usage: %s [flags]\n\n", os.Args[0])
-	fmt.Fprintln(flag.CommandLine.Output(), "Environment:")
-	fmt.Fprintln(flag.CommandLine.Output(), "  CLOUDFLARE_API_TOKEN   Cloudflare API token with permission to create tokens (required).")
-	fmt.Fprintln(flag.CommandLine.Output())
-	flag.PrintDefaults()
-}
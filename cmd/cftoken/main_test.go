@@ -1,8 +1,20 @@
 package main
 
 import (
+	"bytes"
+	"context"
+	"flag"
+	"log"
+	"net"
+	"os"
+	"path/filepath"
 	"reflect"
+	"strings"
 	"testing"
+	"time"
+
+	"cftoken/internal/config"
+	"cftoken/internal/template"
 )
 
 func TestNormalizeCIDRList(t *testing.T) {
@@ -43,3 +55,347 @@ func TestNormalizeCIDRListInvalid(t *testing.T) {
 		t.Fatalf("normalizeCIDRList() error = nil, want error")
 	}
 }
+
+func TestVarFlagStringSorted(t *testing.T) {
+	t.Parallel()
+
+	v := varFlag{"zeta": "1", "alpha": "2", "mid": "3"}
+	if got, want := v.String(), "alpha=2, mid=3, zeta=1"; got != want {
+		t.Fatalf("varFlag.String() = %q, want %q", got, want)
+	}
+}
+
+func TestPrintDryRunStableOrder(t *testing.T) {
+	t.Parallel()
+
+	expires := time.Date(2024, 1, 1, 8, 0, 0, 0, time.UTC)
+	policies := []template.Policy{{
+		Effect: "allow",
+		Resources: map[string]interface{}{
+			"com.cloudflare.api.account.zone.cccc": "*",
+			"com.cloudflare.api.account.zone.aaaa": "*",
+			"com.cloudflare.api.account.zone.bbbb": "*",
+		},
+		PermissionGroups: []template.PermissionGroup{{ID: "p1", Name: "Zone Read"}, {ID: "p2"}},
+	}}
+
+	want := `DRY RUN: no changes made.
+Token would be created with:
+  Name: dev-20240101T000000Z
+  Zone: dev (aaaa)
+  Expires: 2024-01-01T08:00:00Z
+  Allowed CIDRs: 10.0.0.1/32
+  Policies:
+    Policy 1:
+      Effect: allow
+      Resources:
+        com.cloudflare.api.account.zone.aaaa: *
+        com.cloudflare.api.account.zone.bbbb: *
+        com.cloudflare.api.account.zone.cccc: *
+      Permission Groups:
+        - Zone Read (p1)
+        - p2
+`
+	for i := 0; i < 5; i++ {
+		var buf bytes.Buffer
+		if err := printDryRun(&buf, "dev-20240101T000000Z", "aaaa", "dev", &expires, []string{"10.0.0.1/32"}, policies); err != nil {
+			t.Fatalf("printDryRun() error = %v", err)
+		}
+		if got := buf.String(); got != want {
+			t.Fatalf("printDryRun() output =\n%s\nwant\n%s", got, want)
+		}
+	}
+}
+
+func TestLoadGuardrailsZoneTightensGlobal(t *testing.T) {
+	tmp := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", tmp)
+	writeConfig(t, tmp, `{
+  "guardrails": {"max_ttl": "24h"},
+  "zones": {
+    "prod": {
+      "zone_id": "aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa",
+      "guardrails": {"require_ip_restriction": true, "max_ttl": "4h"}
+    }
+  }
+}`)
+
+	_, zoneConfig, err := config.LoadZoneConfig("prod")
+	if err != nil {
+		t.Fatalf("LoadZoneConfig() error = %v", err)
+	}
+	rules, err := loadGuardrails(zoneConfig)
+	if err != nil {
+		t.Fatalf("loadGuardrails() error = %v", err)
+	}
+	if !rules.RequireIPRestriction || rules.MaxTTL != 4*time.Hour {
+		t.Fatalf("loadGuardrails() = %+v, want IP restriction and 4h cap", rules)
+	}
+
+	global, err := loadGuardrails(nil)
+	if err != nil {
+		t.Fatalf("loadGuardrails(nil) error = %v", err)
+	}
+	if global.RequireIPRestriction || global.MaxTTL != 24*time.Hour {
+		t.Fatalf("loadGuardrails(nil) = %+v, want global rules only", global)
+	}
+}
+
+func TestDescribeTemplate(t *testing.T) {
+	t.Parallel()
+
+	spec := &template.Spec{Variables: map[string]template.VariableSpec{
+		"IncludeEdit": {Description: "Grant settings write", Default: false},
+		"Team":        {Description: "Owning team", Required: true},
+	}}
+	var buf bytes.Buffer
+	if err := describeTemplate(&buf, "policy.json.tmpl", "policy.json.tmpl.vars.json", spec, []string{"IncludeEdit", "Stray", "Team", "ZoneID"}); err != nil {
+		t.Fatalf("describeTemplate() error = %v", err)
+	}
+	got := buf.String()
+	for _, want := range []string{
+		"IncludeEdit  no        false    Grant settings write",
+		"Team         yes       -        Owning team",
+		"Undeclared variables read by the template: Stray\n",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("describeTemplate() missing %q:\n%s", want, got)
+		}
+	}
+}
+
+func TestResolveAccountIDFromConfig(t *testing.T) {
+	tmp := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", tmp)
+	writeConfig(t, tmp, `{"account_id": "acc-1"}`)
+
+	zc := &config.ZoneConfig{ZoneID: "z1"}
+	if err := resolveAccountID(context.Background(), nil, zc, "", `{{ .ZoneID }}`); err != nil {
+		t.Fatalf("resolveAccountID() error = %v", err)
+	}
+	if zc.AccountID != "" {
+		t.Fatalf("AccountID = %q, want untouched when the template does not read it", zc.AccountID)
+	}
+
+	if err := resolveAccountID(context.Background(), nil, zc, "", `{{ .AccountID }}`); err != nil {
+		t.Fatalf("resolveAccountID() error = %v", err)
+	}
+	vars := templateVariables(zc, map[string]string{"Extra": "x"})
+	if vars["AccountID"] != "acc-1" || vars["ZoneID"] != "z1" {
+		t.Fatalf("templateVariables() = %v", vars)
+	}
+}
+
+func TestManagementTokenSources(t *testing.T) {
+	root := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", root)
+	tokenFile := filepath.Join(root, "token")
+	if err := os.WriteFile(tokenFile, []byte("from-file\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	writeConfig(t, root, `{"token_source": "file:`+filepath.ToSlash(tokenFile)+`"}`)
+
+	t.Setenv("CLOUDFLARE_API_TOKEN", "from-env")
+	if got := managementToken(context.Background()); got != "from-env" {
+		t.Errorf("managementToken() = %q, want the environment to win", got)
+	}
+	t.Setenv("CLOUDFLARE_API_TOKEN", "")
+	if got := managementToken(context.Background()); got != "from-file" {
+		t.Errorf("managementToken() = %q, want token_source", got)
+	}
+
+	writeConfig(t, root, `{"token_source": "file:`+filepath.ToSlash(tokenFile)+`", "token_source_timeout": "1d"}`)
+	if _, err := tokenProvider(); err != nil {
+		t.Errorf("tokenProvider() error = %v for a token_source_timeout in days", err)
+	}
+
+	writeConfig(t, root, `{"token_source": "nope"}`)
+	if _, err := tokenProvider(); err == nil {
+		t.Error("tokenProvider() error = nil for an invalid token_source")
+	}
+}
+
+// runCLI runs cftoken with args on a fresh flag set, as main would, and
+// discards what it prints on stdout and stderr.
+func runCLI(t *testing.T, args ...string) error {
+	t.Helper()
+	devNull, err := os.Create(os.DevNull)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer devNull.Close()
+	oldArgs, oldFlags, oldStdout, oldStderr := os.Args, flag.CommandLine, os.Stdout, os.Stderr
+	defer func() { os.Args, flag.CommandLine, os.Stdout, os.Stderr = oldArgs, oldFlags, oldStdout, oldStderr }()
+	os.Args = append([]string{"cftoken"}, args...)
+	flag.CommandLine = flag.NewFlagSet("cftoken", flag.ContinueOnError)
+	os.Stdout, os.Stderr = devNull, devNull
+	return run(context.Background())
+}
+
+func TestOfflineCommandsSkipTokenSource(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	resolved := 0
+	defer func(orig func(context.Context) string) { resolveManagementToken = orig }(resolveManagementToken)
+	resolveManagementToken = func(context.Context) string {
+		resolved++
+		return "tok"
+	}
+
+	for _, args := range [][]string{
+		{"help"},
+		{"help", "create"},
+		{"__complete", "-"},
+		{"__complete", "-permissions", "DNS"},
+		{"completion", "bash"},
+		{"schema"},
+	} {
+		if err := runCLI(t, args...); err != nil {
+			t.Errorf("cftoken %s: %v", strings.Join(args, " "), err)
+		}
+		if resolved != 0 {
+			t.Fatalf("cftoken %s resolved the management token", strings.Join(args, " "))
+		}
+	}
+}
+
+func TestMisplacedFlagsRejected(t *testing.T) {
+	root := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", root)
+	writeConfig(t, root, `{"zones": {"prod": "0123456789abcdef0123456789abcdef"}}`)
+	defer func(orig func(context.Context) string) { resolveManagementToken = orig }(resolveManagementToken)
+	resolveManagementToken = func(context.Context) string { return "" }
+
+	tests := []struct {
+		args    []string
+		wantErr string // empty when the command line is accepted
+	}{
+		{[]string{"-v", "zones"}, ""},
+		{[]string{"-ttl", "1h", "zones"}, "-ttl cannot be used with the zones command"},
+		{[]string{"-zone", "prod", "-dry-run", "list-permissions"}, "-dry-run, -zone cannot be used with the list-permissions command"},
+		{[]string{"-zone", "prod", "export"}, "-zone cannot be used with the export command"},
+		{[]string{"-zone", "prod", "create"}, "-zone cannot be used with the create command"},
+		{[]string{"-list-zones", "help"}, "-list-zones cannot be used with the help command"},
+		{[]string{"list-permissions", "-ttl", "1h"}, "-ttl"},
+		{[]string{"zones", "-ttl", "1h"}, "-ttl"},
+		{[]string{"inspect", "-zone", "prod"}, "-zone"},
+		{[]string{"create", "-list-zones"}, "-list-zones"},
+		{[]string{"create", "-output", "json"}, "-output"},
+		{[]string{"create", "-zone", "prod", "extra"}, "create takes no arguments"},
+		{[]string{"export", "-revoke", "t1"}, "-revoke"},
+	}
+	for _, tc := range tests {
+		err := runCLI(t, tc.args...)
+		switch {
+		case tc.wantErr == "" && err != nil:
+			t.Errorf("cftoken %s: %v", strings.Join(tc.args, " "), err)
+		case tc.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tc.wantErr)):
+			t.Errorf("cftoken %s error = %v, want %q", strings.Join(tc.args, " "), err, tc.wantErr)
+		}
+	}
+}
+
+func TestListZonesSkipsConflicts(t *testing.T) {
+	root := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", root)
+	writeConfig(t, root, `{"zones": {
+		"Example.com": "aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa",
+		"example.com.": "bbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbb",
+		"other.com": "cccccccccccccccccccccccccccccccc"
+	}}`)
+	defer func(prev *os.File) { os.Stdout = prev }(os.Stdout)
+	stdout, err := os.Create(filepath.Join(root, "stdout"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	os.Stdout = stdout
+	var logs bytes.Buffer
+	log.SetOutput(&logs)
+	defer log.SetOutput(os.Stderr)
+
+	if err := listZones(); err != nil {
+		t.Fatalf("listZones() error = %v", err)
+	}
+	out, _ := os.ReadFile(stdout.Name())
+	if !strings.Contains(string(out), "other.com") || strings.Contains(string(out), "example.com") {
+		t.Errorf("zones = %q, want other.com without the conflicting example.com", out)
+	}
+	if !strings.Contains(logs.String(), `warning: zone "example.com" is defined more than once`) {
+		t.Errorf("log = %q, want a warning about example.com", logs.String())
+	}
+}
+
+func TestZoneTTL(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		ttl     string
+		want    time.Duration
+		wantErr bool
+	}{
+		{"", 8 * time.Hour, false},
+		{"4h", 4 * time.Hour, false},
+		{"7d", 7 * 24 * time.Hour, false},
+		{"7 days", 0, true},
+	}
+	for _, tc := range tests {
+		got, err := zoneTTL("prod", &config.ZoneConfig{TTL: tc.ttl}, 8*time.Hour)
+		if tc.wantErr {
+			if err == nil || !strings.Contains(err.Error(), `zone "prod"`) || !strings.Contains(err.Error(), `"7 days"`) {
+				t.Errorf("zoneTTL(%q) error = %v, want one naming the zone and value", tc.ttl, err)
+			}
+			continue
+		}
+		if err != nil || got != tc.want {
+			t.Errorf("zoneTTL(%q) = %s, %v, want %s", tc.ttl, got, err, tc.want)
+		}
+	}
+	if got, err := zoneTTL("", nil, time.Hour); err != nil || got != time.Hour {
+		t.Errorf("zoneTTL(nil) = %s, %v, want the fallback", got, err)
+	}
+}
+
+// FuzzParseAllowedCIDRs feeds -allow-cidrs values as a pipeline might pass
+// them: every accepted range must parse and come back trimmed.
+func FuzzParseAllowedCIDRs(f *testing.F) {
+	for _, seed := range []string{"10.0.0.1/32, 2001:db8::/64", "0.0.0.0/32", " ,, ", "10.0.0.0/33", "::/0,\t192.0.2.0/24\n", "10.0.0.1"} {
+		f.Add(seed)
+	}
+	f.Fuzz(func(t *testing.T, input string) {
+		got, disabled, err := parseAllowedCIDRs(input)
+		if err != nil {
+			return
+		}
+		if disabled && got != nil {
+			t.Fatalf("parseAllowedCIDRs(%q) disabled with CIDRs %q", input, got)
+		}
+		for _, cidr := range got {
+			if cidr != strings.TrimSpace(cidr) || cidr == "" {
+				t.Fatalf("parseAllowedCIDRs(%q) returned untrimmed %q", input, cidr)
+			}
+			if _, _, err := net.ParseCIDR(cidr); err != nil {
+				t.Fatalf("parseAllowedCIDRs(%q) accepted %q: %v", input, cidr, err)
+			}
+		}
+	})
+}
+
+// FuzzVarFlag checks the key=value parser behind -var: a value is either
+// rejected or stored under its trimmed key.
+func FuzzVarFlag(f *testing.F) {
+	for _, seed := range []string{"Env=prod", " k = v ", "novalue", "a=b=c", "=x", ""} {
+		f.Add(seed)
+	}
+	f.Fuzz(func(t *testing.T, input string) {
+		var v varFlag
+		if err := v.Set(input); err != nil {
+			if strings.Contains(input, "=") {
+				t.Fatalf("Set(%q) error = %v", input, err)
+			}
+			return
+		}
+		key, value, _ := strings.Cut(input, "=")
+		if got, ok := v[strings.TrimSpace(key)]; !ok || got != strings.TrimSpace(value) {
+			t.Fatalf("Set(%q) stored %v", input, v)
+		}
+	})
+}
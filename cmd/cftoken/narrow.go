@@ -0,0 +1,214 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"cftoken/internal/clock"
+	"cftoken/internal/cloudflare"
+	"cftoken/internal/duration"
+	"cftoken/internal/naming"
+	"cftoken/internal/template"
+)
+
+// zoneResourcePrefix starts the resource key of a single zone.
+const zoneResourcePrefix = "com.cloudflare.api.account.zone."
+
+// runNarrow mints a replacement for an existing token with some of its
+// permission groups or resources removed. The original token is left alone;
+// revoke it once the narrower one is in use.
+func runNarrow(ctx context.Context, client *cloudflare.Client, args []string) error {
+	fset := flag.NewFlagSet("narrow", flag.ContinueOnError)
+	fromID := fset.String("from-token-id", "", "ID of the token to narrow (required)")
+	drop := fset.String("drop", "", "Comma-separated permission groups (names, keys, or IDs) to remove")
+	dropResources := fset.String("drop-resource", "", "Comma-separated resource keys to remove, e.g. com.cloudflare.api.account.zone.<id>")
+	tokenPrefix := fset.String("token-prefix", "", "Prefix for the new token name (defaults to the original name)")
+	ttl, err := tokenDefaultTTL()
+	if err != nil {
+		return err
+	}
+	fset.Var((*duration.Value)(&ttl), "ttl", "Token TTL, e.g. 8h or 2d (use 0 for no expiration)")
+	allowCIDRs := fset.String("allow-cidrs", "", "Comma-separated CIDRs allowed to use the token (defaults to the original's)")
+	dryRun := fset.Bool("dry-run", false, "Preview the token without creating it")
+	yes := fset.Bool("yes", false, "Do not ask for confirmation before creating a risky token")
+	if err := fset.Parse(args); err != nil {
+		return err
+	}
+	if *fromID == "" {
+		return withCode(codeInvalidArgument, errors.New("narrow requires -from-token-id"), nil)
+	}
+	dropRefs, resourceKeys := splitList(*drop), splitList(*dropResources)
+	if len(dropRefs) == 0 && len(resourceKeys) == 0 {
+		return withCode(codeInvalidArgument, errors.New("narrow requires -drop or -drop-resource"), nil)
+	}
+
+	desc, err := client.DescribeToken(ctx, *fromID)
+	if err != nil {
+		return err
+	}
+	var dropGroups []cloudflare.PermissionGroup
+	if len(dropRefs) > 0 {
+		catalog, err := liveCatalog(ctx, client)
+		if err != nil {
+			return fmt.Errorf("fetch permission groups: %w", err)
+		}
+		if dropGroups, err = cloudflare.ResolvePermissions(catalog, dropRefs); err != nil {
+			return withCode(codeInvalidArgument, err, nil)
+		}
+	}
+	policies, err := narrowPolicies(desc.Policies, dropGroups, resourceKeys)
+	if err != nil {
+		return err
+	}
+
+	p, err := planNarrow(desc, policies, *tokenPrefix, ttl, *allowCIDRs, clock.Now().UTC())
+	if err != nil {
+		return err
+	}
+	// Freezes are kept per zone; the original's zones are only known by ID.
+	for _, zoneID := range policyZoneIDs(policies) {
+		if err := checkFrozen("", zoneID, nil); err != nil {
+			return err
+		}
+	}
+	if err := guardTokenSet([]plannedToken{p}, nil); err != nil {
+		return err
+	}
+
+	if err := requireValueSink(false); err != nil {
+		return err
+	}
+
+	if *dryRun {
+		if err := printDryRun(reportOutput(), p.name, "none", "", p.expiresOn, p.allowedCIDRs, p.policies); err != nil {
+			return fmt.Errorf("dry run failed: %w", err)
+		}
+		return nil
+	}
+
+	hold, err := checkBudget(1)
+	if err != nil {
+		return err
+	}
+	defer hold.release()
+	ctx, cancel, err := approveTokenSet(ctx, []plannedToken{p}, "none", "", *yes)
+	if err != nil {
+		return err
+	}
+	defer cancel()
+	j := beginJournal(ctx, "narrow")
+	results, err := createTokenSet(ctx, client, j, []plannedToken{p})
+	if err != nil {
+		return journalError(err, j)
+	}
+	result := results[0]
+	recordIssued(hold, result)
+	labelIssued(result)
+	printTokenResult(os.Stdout, result, "", p.ttl)
+	fmt.Fprintf(reportOutput(), "\nThe original token %s (%s) is unchanged; revoke it once the new one is in use.\n", desc.Name, desc.ID)
+	return nil
+}
+
+// narrowPolicies copies policies without the dropped permission groups and
+// resource keys. Drops only apply to allow policies: removing anything from
+// a deny policy would widen the token. An allow policy left without groups
+// or resources is removed, since the API rejects it. Every drop must match
+// something, so a typo cannot silently mint a copy of the original.
+func narrowPolicies(policies []cloudflare.TokenPolicyInspection, dropGroups []cloudflare.PermissionGroup, dropResources []string) ([]template.Policy, error) {
+	dropped := make(map[string]bool)
+	out := make([]template.Policy, 0, len(policies))
+	for _, pol := range policies {
+		if len(pol.ResourceMap) == 0 {
+			return nil, errors.New("the token has a policy whose resources cannot be read; narrow it in the dashboard")
+		}
+		allow := pol.Effect != "deny"
+		policy := template.Policy{Effect: pol.Effect, Resources: make(map[string]interface{}, len(pol.ResourceMap))}
+		for key, value := range pol.ResourceMap {
+			if allow && slices.Contains(dropResources, key) {
+				dropped["resource "+key] = true
+				continue
+			}
+			policy.Resources[key] = value
+		}
+		for _, pg := range pol.PermissionGroups {
+			if i := slices.IndexFunc(dropGroups, func(g cloudflare.PermissionGroup) bool { return g.ID == pg.ID }); allow && i >= 0 {
+				dropped["group "+dropGroups[i].ID] = true
+				continue
+			}
+			policy.PermissionGroups = append(policy.PermissionGroups, template.PermissionGroup{ID: pg.ID, Name: pg.Name})
+		}
+		if len(policy.Resources) > 0 && len(policy.PermissionGroups) > 0 {
+			out = append(out, policy)
+		}
+	}
+
+	var unmatched []string
+	for _, g := range dropGroups {
+		if !dropped["group "+g.ID] {
+			unmatched = append(unmatched, g.Name)
+		}
+	}
+	for _, key := range dropResources {
+		if !dropped["resource "+key] {
+			unmatched = append(unmatched, key)
+		}
+	}
+	if len(unmatched) > 0 {
+		return nil, withCode(codeInvalidArgument, fmt.Errorf("the token does not grant %s", strings.Join(unmatched, ", ")), map[string]any{"unmatched": unmatched})
+	}
+	if len(out) == 0 {
+		return nil, withCode(codeInvalidArgument, errors.New("nothing would be left of the token; revoke it instead"), nil)
+	}
+	return out, nil
+}
+
+// planNarrow builds the replacement token. Its name keeps the original's,
+// minus any generated timestamp, and its CIDR conditions default to the
+// original's, denied ranges included, so narrowing never widens access.
+func planNarrow(desc *cloudflare.TokenInspection, policies []template.Policy, prefix string, ttl time.Duration, allowCIDRs string, now time.Time) (plannedToken, error) {
+	base := stringOrDefault(prefix, naming.Trim(desc.Name))
+	p := plannedToken{
+		name:        stringOrDefault(base, "narrowed") + "-" + now.Format("20060102T150405Z"),
+		ttl:         ttl,
+		deniedCIDRs: desc.DeniedCIDRs,
+		policies:    policies,
+	}
+	if ttl > 0 {
+		exp := now.Add(ttl)
+		p.expiresOn = &exp
+	}
+
+	cidrs := desc.AllowedCIDRs
+	if allowCIDRs != "" {
+		cidrs = strings.Split(allowCIDRs, ",")
+	}
+	allowed, disabled, err := normalizeCIDRList(cidrs)
+	if err != nil {
+		return plannedToken{}, err
+	}
+	if len(allowed) == 0 && !disabled {
+		return plannedToken{}, withCode(codeInvalidArgument, fmt.Errorf("token %s has no IP restrictions; pass -allow-cidrs", desc.ID), nil)
+	}
+	p.allowedCIDRs = allowed
+	return p, nil
+}
+
+// policyZoneIDs lists the single zones policies grant access to.
+func policyZoneIDs(policies []template.Policy) []string {
+	var ids []string
+	for _, p := range policies {
+		for key := range p.Resources {
+			if id, ok := strings.CutPrefix(key, zoneResourcePrefix); ok && id != "*" && !slices.Contains(ids, id) {
+				ids = append(ids, id)
+			}
+		}
+	}
+	slices.Sort(ids)
+	return ids
+}
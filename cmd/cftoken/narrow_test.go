@@ -0,0 +1,104 @@
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"cftoken/internal/cloudflare"
+)
+
+func TestNarrowPolicies(t *testing.T) {
+	zone := zoneResourcePrefix + "z1"
+	other := zoneResourcePrefix + "z2"
+	dns := cloudflare.PermissionGroup{ID: "g-dns", Name: "DNS Write"}
+	settings := cloudflare.PermissionGroup{ID: "g-settings", Name: "Zone Settings Write"}
+	policies := []cloudflare.TokenPolicyInspection{
+		{
+			Effect:           "allow",
+			ResourceMap:      map[string]interface{}{zone: "*", other: "*"},
+			PermissionGroups: []cloudflare.PermissionGroupSummary{{ID: dns.ID}, {ID: settings.ID}},
+		},
+		{
+			Effect:           "deny",
+			ResourceMap:      map[string]interface{}{other: "*"},
+			PermissionGroups: []cloudflare.PermissionGroupSummary{{ID: dns.ID}},
+		},
+	}
+
+	tests := []struct {
+		name          string
+		policies      []cloudflare.TokenPolicyInspection
+		dropGroups    []cloudflare.PermissionGroup
+		dropResources []string
+		wantGroups    [][]string
+		wantResources [][]string
+		wantErr       string
+	}{
+		{"drop group keeps deny", policies, []cloudflare.PermissionGroup{settings}, nil,
+			[][]string{{"g-dns"}, {"g-dns"}}, [][]string{{zone, other}, {other}}, ""},
+		{"drop resource", policies, nil, []string{other},
+			[][]string{{"g-dns", "g-settings"}, {"g-dns"}}, [][]string{{zone}, {other}}, ""},
+		{"emptied allow policy is removed", policies, []cloudflare.PermissionGroup{dns, settings}, nil,
+			[][]string{{"g-dns"}}, [][]string{{other}}, ""},
+		{"unmatched drop", policies, []cloudflare.PermissionGroup{{ID: "g-x", Name: "Workers Write"}}, nil, nil, nil, "does not grant Workers Write"},
+		{"nothing left", policies[:1], []cloudflare.PermissionGroup{dns, settings}, nil, nil, nil, "nothing would be left"},
+		{"nested resources", []cloudflare.TokenPolicyInspection{{
+			Effect:           "allow",
+			ResourceMap:      map[string]interface{}{"com.cloudflare.api.account.a1": map[string]interface{}{"com.cloudflare.api.account.zone.*": "*"}},
+			PermissionGroups: []cloudflare.PermissionGroupSummary{{ID: dns.ID}, {ID: settings.ID}},
+		}}, []cloudflare.PermissionGroup{settings}, nil,
+			[][]string{{"g-dns"}}, [][]string{{"com.cloudflare.api.account.a1"}}, ""},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := narrowPolicies(tc.policies, tc.dropGroups, tc.dropResources)
+			if tc.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+					t.Fatalf("narrowPolicies() error = %v, want %q", err, tc.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("narrowPolicies() error = %v", err)
+			}
+			var groups, resources [][]string
+			for _, p := range got {
+				var ids []string
+				for _, pg := range p.PermissionGroups {
+					ids = append(ids, pg.ID)
+				}
+				groups = append(groups, ids)
+				resources = append(resources, sortedKeys(p.Resources))
+			}
+			if !reflect.DeepEqual(groups, tc.wantGroups) || !reflect.DeepEqual(resources, tc.wantResources) {
+				t.Errorf("narrowPolicies() groups = %v resources = %v, want %v %v", groups, resources, tc.wantGroups, tc.wantResources)
+			}
+		})
+	}
+}
+
+func TestPlanNarrow(t *testing.T) {
+	now := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	desc := &cloudflare.TokenInspection{
+		ID:           "t1",
+		Name:         "edge-20231201T000000Z",
+		AllowedCIDRs: []string{"192.0.2.0/24"},
+		DeniedCIDRs:  []string{"192.0.2.7/32"},
+	}
+
+	p, err := planNarrow(desc, nil, "", time.Hour, "", now)
+	if err != nil {
+		t.Fatalf("planNarrow() error = %v", err)
+	}
+	if p.name != "edge-20240102T030405Z" || !reflect.DeepEqual(p.allowedCIDRs, desc.AllowedCIDRs) || !reflect.DeepEqual(p.deniedCIDRs, desc.DeniedCIDRs) {
+		t.Errorf("planNarrow() = %s %v %v", p.name, p.allowedCIDRs, p.deniedCIDRs)
+	}
+	if p, err := planNarrow(desc, nil, "ci", 0, "203.0.113.0/24", now); err != nil || p.name != "ci-20240102T030405Z" || p.expiresOn != nil || p.allowedCIDRs[0] != "203.0.113.0/24" {
+		t.Errorf("planNarrow() with overrides = %+v, %v", p, err)
+	}
+	if _, err := planNarrow(&cloudflare.TokenInspection{ID: "t2", Name: "open"}, nil, "", time.Hour, "", now); err == nil {
+		t.Error("planNarrow() for an unrestricted token error = nil, want -allow-cidrs error")
+	}
+}
@@ -0,0 +1,184 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"cftoken/internal/cloudflare"
+	"cftoken/internal/config"
+	"cftoken/internal/duration"
+)
+
+// zoneFinder is the part of the Cloudflare client zone onboard needs.
+type zoneFinder interface {
+	FindZone(ctx context.Context, name string) (*cloudflare.ZoneDetails, error)
+}
+
+// onboardOptions are the zone settings given as flags; prompts fill in the
+// ones that were not.
+type onboardOptions struct {
+	name  string
+	edit  config.ZoneEdit
+	issue bool
+	// prompt asks for settings on the terminal; hasSource records that
+	// -extends, -template-file, or -permissions was given.
+	prompt    bool
+	hasSource bool
+}
+
+// runZoneOnboard adds a zone found through the API to config.json. It
+// returns the configured name and whether its first token should be issued.
+func runZoneOnboard(ctx context.Context, token string, verbose bool, args []string) (string, bool, error) {
+	fset := flag.NewFlagSet("zone onboard", flag.ContinueOnError)
+	name := fset.String("name", "", "Name of the zone in config.json (defaults to the domain)")
+	allowCIDRs := fset.String("allow-cidrs", "", "Comma-separated CIDR ranges allowed to use tokens")
+	ttl := fset.String("ttl", "", "Token TTL, e.g. 8h or 2d")
+	extends := fset.String("extends", "", "Profile the zone inherits its settings from")
+	templateFile := fset.String("template-file", "", "Policy template for the zone's tokens")
+	permissions := fset.String("permissions", "", "Comma-separated permission group names or IDs")
+	issue := fset.Bool("issue", false, "Issue the zone's first token once it is configured")
+	yes := fset.Bool("yes", false, "Do not prompt; leave settings that were not given unset")
+	domain, err := parseZoneArgs(fset, args, "usage: zone onboard [-name NAME] [-allow-cidrs LIST] [-ttl D] [-extends PROFILE | -template-file PATH | -permissions LIST] [-issue] [-yes] DOMAIN")
+	if err != nil {
+		return "", false, err
+	}
+	if token == "" {
+		return "", false, errMissingToken
+	}
+
+	opts := onboardOptions{
+		name:   stringOrDefault(strings.TrimSpace(*name), strings.ToLower(strings.TrimSuffix(domain, "."))),
+		issue:  *issue,
+		prompt: !*yes && isTerminal(os.Stdin),
+	}
+	fset.Visit(func(f *flag.Flag) {
+		switch f.Name {
+		case "allow-cidrs":
+			opts.edit.AllowedCIDRs = splitList(*allowCIDRs)
+		case "ttl":
+			opts.edit.TTL = ttl
+		case "extends":
+			opts.edit.Extends, opts.hasSource = extends, true
+		case "template-file":
+			opts.edit.TemplateFile, opts.hasSource = templateFile, true
+		case "permissions":
+			opts.edit.Permissions, opts.hasSource = splitList(*permissions), true
+		}
+	})
+	return onboardZone(ctx, newClient(token, verbose), os.Stdin, os.Stdout, domain, opts)
+}
+
+// onboardZone resolves domain, asks for the settings opts leaves open when
+// prompting, and writes the zone entry.
+func onboardZone(ctx context.Context, client zoneFinder, in io.Reader, out io.Writer, domain string, opts onboardOptions) (string, bool, error) {
+	if _, _, err := config.LoadZoneConfig(opts.name); err == nil {
+		return "", false, withCode(codeInvalidArgument, fmt.Errorf("zone %s is already configured; change it with `cftoken config set-zone %s`", opts.name, opts.name), nil)
+	}
+
+	zone, err := client.FindZone(ctx, domain)
+	if err != nil {
+		return "", false, err
+	}
+	fmt.Fprintf(out, "Found %s (%s) in account %s, status %s\n", zone.Name, zone.ID, coalesce(zone.AccountName, zone.AccountID, "-"), stringOrDefault(zone.Status, "unknown"))
+
+	edit := opts.edit
+	edit.ZoneID = &zone.ID
+	if id, err := config.LoadAccountID(); err != nil || id != zone.AccountID {
+		if zone.AccountID != "" {
+			edit.AccountID = &zone.AccountID
+		}
+	}
+
+	if opts.prompt {
+		ask := newPrompter(in, out)
+		if edit.AllowedCIDRs == nil {
+			def, _ := config.LoadDefaultAllowedCIDRs()
+			answer, err := ask("Allowed CIDRs, comma-separated", strings.Join(def, ","))
+			if err != nil {
+				return "", false, err
+			}
+			if answer != strings.Join(def, ",") {
+				edit.AllowedCIDRs = splitList(answer)
+			}
+		}
+		if edit.TTL == nil {
+			def, err := tokenDefaultTTL()
+			if err != nil {
+				return "", false, err
+			}
+			answer, err := ask("Token TTL", duration.Format(def))
+			if err != nil {
+				return "", false, err
+			}
+			if answer != duration.Format(def) {
+				edit.TTL = &answer
+			}
+		}
+		if !opts.hasSource {
+			profiles, _ := config.LoadProfiles()
+			question := "Template file (empty for default permissions)"
+			if len(profiles) > 0 {
+				question = fmt.Sprintf("Profile to extend (%s) or template file (empty for default permissions)", strings.Join(sortedKeys(profiles), ", "))
+			}
+			answer, err := ask(question, "")
+			if err != nil {
+				return "", false, err
+			}
+			if _, ok := profiles[answer]; ok {
+				edit.Extends = &answer
+			} else if answer != "" {
+				edit.TemplateFile = &answer
+			}
+		}
+		if !opts.issue {
+			answer, err := ask("Issue the first token now? (y/N)", "n")
+			if err != nil {
+				return "", false, err
+			}
+			opts.issue = strings.EqualFold(answer, "y") || strings.EqualFold(answer, "yes")
+		}
+	}
+
+	if err := validateZoneEdit(edit); err != nil {
+		return "", false, withCode(codeInvalidArgument, err, nil)
+	}
+	if err := config.SetZone(opts.name, edit); err != nil {
+		return "", false, err
+	}
+	path, err := config.DefaultPath()
+	if err != nil {
+		return "", false, err
+	}
+	fmt.Fprintf(out, "Added zone %s to %s\n", opts.name, path)
+	if !opts.issue {
+		fmt.Fprintf(out, "Issue a token for it with `cftoken -zone %s`\n", opts.name)
+	}
+	return opts.name, opts.issue, nil
+}
+
+// newPrompter returns a function that asks question on out and reads the
+// answer from in, returning def for an empty answer.
+func newPrompter(in io.Reader, out io.Writer) func(question, def string) (string, error) {
+	r := bufio.NewReader(in)
+	return func(question, def string) (string, error) {
+		if def != "" {
+			fmt.Fprintf(out, "%s [%s]: ", question, def)
+		} else {
+			fmt.Fprintf(out, "%s: ", question)
+		}
+		line, err := r.ReadString('\n')
+		if err != nil && !errors.Is(err, io.EOF) {
+			return "", fmt.Errorf("read answer: %w", err)
+		}
+		if line = strings.TrimSpace(line); line != "" {
+			return line, nil
+		}
+		return def, nil
+	}
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"cftoken/internal/cloudflare"
+)
+
+type fakeZoneFinder map[string]*cloudflare.ZoneDetails
+
+func (f fakeZoneFinder) FindZone(_ context.Context, name string) (*cloudflare.ZoneDetails, error) {
+	if z, ok := f[name]; ok {
+		return z, nil
+	}
+	return nil, os.ErrNotExist
+}
+
+func TestOnboardZone(t *testing.T) {
+	zoneID := strings.Repeat("a", 32)
+	finder := fakeZoneFinder{"example.com": {ID: zoneID, Name: "example.com", Status: "active", AccountID: "acc-1", AccountName: "Acme"}}
+
+	tests := []struct {
+		name      string
+		opts      onboardOptions
+		input     string
+		wantIssue bool
+		want      []string
+		wantErr   string
+	}{
+		{
+			name:      "prompts",
+			opts:      onboardOptions{name: "example.com", prompt: true},
+			input:     "192.0.2.0/24\n1h\nedge\ny\n",
+			wantIssue: true,
+			want:      []string{`"zone_id": "` + zoneID + `"`, `"allowed_cidrs": [`, `"192.0.2.0/24"`, `"ttl": "1h"`, `"extends": "edge"`},
+		},
+		{
+			name:  "prompt defaults and template",
+			opts:  onboardOptions{name: "example.com", prompt: true},
+			input: "\n\ntemplates/site.json.tmpl\n\n",
+			want:  []string{`"example.com": {`, `"template_file": "templates/site.json.tmpl"`},
+		},
+		{
+			name:  "no prompts",
+			opts:  onboardOptions{name: "site"},
+			input: "ignored\n",
+			want:  []string{`"site": "` + zoneID + `"`},
+		},
+		{
+			name:    "already configured",
+			opts:    onboardOptions{name: "prod"},
+			wantErr: "already configured",
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			root := t.TempDir()
+			t.Setenv("XDG_CONFIG_HOME", root)
+			writeConfig(t, root, `{"account_id": "acc-1", "default_allowed_cidrs": ["198.51.100.0/24"], "profiles": {"edge": {"ttl": "2h"}}, "zones": {"prod": "`+zoneID+`"}}`)
+
+			var out bytes.Buffer
+			name, issue, err := onboardZone(context.Background(), finder, strings.NewReader(tc.input), &out, "example.com", tc.opts)
+			if tc.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+					t.Fatalf("onboardZone() error = %v, want %q", err, tc.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("onboardZone() error = %v\n%s", err, out.String())
+			}
+			if name != tc.opts.name || issue != tc.wantIssue {
+				t.Errorf("onboardZone() = %q, %v; want %q, %v", name, issue, tc.opts.name, tc.wantIssue)
+			}
+			data, err := os.ReadFile(filepath.Join(root, "cftoken", "config.json"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, want := range tc.want {
+				if !strings.Contains(string(data), want) {
+					t.Errorf("config missing %s:\n%s", want, data)
+				}
+			}
+			var cfg struct {
+				Zones map[string]any `json:"zones"`
+			}
+			if err := json.Unmarshal(data, &cfg); err != nil {
+				t.Fatal(err)
+			}
+			if entry, ok := cfg.Zones[name].(map[string]any); ok && entry["account_id"] != nil {
+				t.Errorf("zone repeats the top-level account_id: %v", entry)
+			}
+		})
+	}
+}
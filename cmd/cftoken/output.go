@@ -0,0 +1,91 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"cftoken/internal/cloudflare"
+	"cftoken/internal/yaml"
+)
+
+// outputYAML is the -output format that prints results as YAML documents.
+const outputYAML = "yaml"
+
+// outputValue is the -output format that prints only a new token's value
+// on stdout, so TOKEN=$(cftoken ...) needs no parsing; the report goes to
+// stderr.
+const outputValue = "value"
+
+// reportOutput is where commands that print a new token write everything
+// but its value or document: stdout for text output, and stderr in the
+// machine formats, whose stdout must stay parseable.
+func reportOutput() io.Writer {
+	if outputFormat != "text" {
+		return os.Stderr
+	}
+	return os.Stdout
+}
+
+// writeValueOnly writes value alone on w unless print_token_values
+// withholds it, and returns the value the report on stderr should show
+// instead, so the secret reaches only stdout.
+func writeValueOnly(w io.Writer, value string) string {
+	if shown := displayedValue(value); strings.HasPrefix(shown, "<") {
+		return value
+	}
+	fmt.Fprintln(w, value)
+	return "<printed on stdout>"
+}
+
+// structuredOutput reports whether -output asks for results as JSON or
+// YAML documents instead of the text report.
+func structuredOutput() bool {
+	return outputFormat == "json" || outputFormat == outputYAML
+}
+
+// writeDocument writes v as an indented JSON document or, with -output
+// yaml, as a YAML document that starts with "---" so several can follow
+// one another.
+func writeDocument(w io.Writer, v any) error {
+	if outputFormat == outputYAML {
+		data, err := yaml.Marshal(v)
+		if err != nil {
+			return fmt.Errorf("write yaml: %w", err)
+		}
+		_, err = fmt.Fprintf(w, "---\n%s", data)
+		return err
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
+
+// tokenDocument is a created token as -output json and yaml print it.
+type tokenDocument struct {
+	ID           string   `json:"id"`
+	Name         string   `json:"name"`
+	Status       string   `json:"status"`
+	Value        string   `json:"value"`
+	Zone         string   `json:"zone,omitempty"`
+	ZoneID       string   `json:"zone_id,omitempty"`
+	ExpiresOn    string   `json:"expires_on,omitempty"`
+	AllowedCIDRs []string `json:"allowed_cidrs,omitempty"`
+}
+
+// newTokenDocument describes result; the value is shown as printTokenResult
+// would show it.
+func newTokenDocument(result *cloudflare.TokenResult, zoneName string) tokenDocument {
+	return tokenDocument{
+		ID:           result.ID,
+		Name:         result.Name,
+		Status:       result.Status,
+		Value:        displayedValue(result.Value),
+		Zone:         zoneName,
+		ZoneID:       result.ZoneID,
+		ExpiresOn:    result.ExpiresOn,
+		AllowedCIDRs: result.AllowedCIDRs,
+	}
+}
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"cftoken/internal/cloudflare"
+)
+
+func TestWriteDocument(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	defer func(prev string) { outputFormat = prev }(outputFormat)
+
+	result := &cloudflare.TokenResult{ID: "t1", Name: "prod-20240601T000000Z", Status: "active", Value: "secret", ZoneID: "z1", ExpiresOn: "2024-06-01T08:00:00Z", AllowedCIDRs: []string{"10.0.0.0/8"}}
+	desc := &cloudflare.TokenInspection{ID: "t1", Name: "ci", Status: "active", Policies: []cloudflare.TokenPolicyInspection{{
+		ID: "p1", Effect: "allow", Resources: []string{"zone.z1=*"},
+		ResourceMap:      map[string]interface{}{"com.cloudflare.api.account.zone.z1": "*"},
+		PermissionGroups: []cloudflare.PermissionGroupSummary{{ID: "g1", Name: "DNS Write"}},
+	}}}
+
+	outputFormat = outputYAML
+	var buf bytes.Buffer
+	printTokenResult(&buf, result, "example.com", 0)
+	printTokenInspection(&buf, desc, viewList, false)
+	want := `---
+id: t1
+name: prod-20240601T000000Z
+status: active
+value: secret
+zone: example.com
+zone_id: z1
+expires_on: "2024-06-01T08:00:00Z"
+allowed_cidrs:
+  - 10.0.0.0/8
+---
+id: t1
+name: ci
+status: active
+policies:
+  - id: p1
+    effect: allow
+    permission_groups:
+      - id: g1
+        name: DNS Write
+    resources:
+      com.cloudflare.api.account.zone.z1: "*"
+`
+	if buf.String() != want {
+		t.Errorf("yaml output =\n%s\nwant\n%s", buf.String(), want)
+	}
+
+	outputFormat = "json"
+	buf.Reset()
+	printTokenResult(&buf, result, "example.com", 0)
+	var doc tokenDocument
+	if err := json.Unmarshal(buf.Bytes(), &doc); err != nil || doc.Zone != "example.com" || doc.Value != "secret" {
+		t.Errorf("json output = %s (%+v, %v)", buf.String(), doc, err)
+	}
+}
+
+func TestValueOutput(t *testing.T) {
+	root := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", root)
+	defer func(prev string) { outputFormat = prev }(outputFormat)
+	defer func(prev *os.File) { os.Stderr = prev }(os.Stderr)
+	stderr, err := os.Create(filepath.Join(root, "stderr"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	os.Stderr = stderr
+
+	outputFormat = outputValue
+	result := &cloudflare.TokenResult{ID: "t1", Name: "prod-20240601T000000Z", Status: "active", Value: "secret", ZoneID: "z1"}
+	var buf bytes.Buffer
+	printTokenResult(&buf, result, "example.com", 0)
+	printRolled(&buf, cloudflare.Token{ID: "t2", Name: "ci"}, "rolled")
+	if buf.String() != "secret\nrolled\n" {
+		t.Errorf("stdout = %q, want only the values", buf.String())
+	}
+	report, _ := os.ReadFile(stderr.Name())
+	if !strings.Contains(string(report), "ID:     t1") || !strings.Contains(string(report), "Value:  <printed on stdout>") || strings.Contains(string(report), "secret") {
+		t.Errorf("stderr = %q, want the report without the value", report)
+	}
+
+	// A withheld value leaves stdout empty rather than printing a placeholder.
+	writeConfig(t, root, `{"print_token_values": "never"}`)
+	buf.Reset()
+	printTokenResult(&buf, result, "example.com", 0)
+	if buf.Len() != 0 {
+		t.Errorf("stdout = %q with print_token_values never, want nothing", buf.String())
+	}
+}
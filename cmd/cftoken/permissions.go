@@ -0,0 +1,493 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/fs"
+	"log"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"cftoken/internal/clock"
+	"cftoken/internal/cloudflare"
+	"cftoken/internal/config"
+	"cftoken/internal/template"
+)
+
+func runPermissions(ctx context.Context, client *cloudflare.Client, args []string) error {
+	if len(args) == 0 {
+		return errors.New("permissions requires a subcommand: lock, snapshot, diff, or export")
+	}
+	switch sub := args[0]; sub {
+	case "lock":
+		return runPermissionsLock(ctx, client, args[1:])
+	case "snapshot":
+		return runPermissionsSnapshot(ctx, client, args[1:])
+	case "diff":
+		return runPermissionsDiff(ctx, client, args[1:])
+	case "export":
+		return runPermissionsExport(ctx, client, args[1:])
+	default:
+		return fmt.Errorf("unknown permissions subcommand %q; available: lock, snapshot, diff, export", sub)
+	}
+}
+
+func runPermissionsLock(ctx context.Context, client *cloudflare.Client, args []string) error {
+	fset := flag.NewFlagSet("permissions lock", flag.ContinueOnError)
+	check := fset.Bool("check", false, "Compare the existing lock with the live catalog instead of regenerating it")
+	if err := fset.Parse(args); err != nil {
+		return err
+	}
+
+	path, err := config.PermissionLockPath()
+	if err != nil {
+		return err
+	}
+	catalog, err := liveCatalog(ctx, client)
+	if err != nil {
+		return fmt.Errorf("fetch permission groups: %w", err)
+	}
+
+	if *check {
+		lock, err := config.LoadPermissionLock()
+		if err != nil {
+			return fmt.Errorf("load %s: %w", path, err)
+		}
+		drift := lockDrift(lock, catalog)
+		if len(drift) == 0 {
+			fmt.Printf("%s matches the live catalog (%d pinned)\n", path, len(lock.Permissions))
+			return nil
+		}
+		for _, d := range drift {
+			fmt.Println(d)
+		}
+		return fmt.Errorf("%s: %d reference(s) drifted; review and rerun `cftoken permissions lock`", path, len(drift))
+	}
+
+	refs, skipped, err := collectPermissionRefs()
+	if err != nil {
+		return err
+	}
+	for _, s := range skipped {
+		log.Printf("warning: %s", s)
+	}
+	lock, err := buildPermissionLock(refs, catalog, clock.Now().UTC())
+	if err != nil {
+		return err
+	}
+	if err := config.SavePermissionLock(lock); err != nil {
+		return err
+	}
+	fmt.Printf("Pinned %d permission reference(s) in %s\n", len(lock.Permissions), path)
+	return nil
+}
+
+// collectPermissionRefs gathers every permission reference the configuration
+// can use: defaults, static zone permissions, and the group IDs rendered by
+// zone templates. Templates that fail to render without -var are skipped and
+// reported.
+func collectPermissionRefs() ([]string, []string, error) {
+	seen := make(map[string]bool)
+	add := func(refs ...string) {
+		for _, r := range refs {
+			if r = strings.TrimSpace(r); r != "" {
+				seen[r] = true
+			}
+		}
+	}
+
+	defaults, err := config.LoadDefaultPermissions()
+	switch {
+	case err == nil:
+		add(defaults...)
+	case errors.Is(err, fs.ErrNotExist):
+		add(cloudflare.DefaultPermissionKeys...)
+	default:
+		return nil, nil, err
+	}
+
+	var skipped []string
+	names, err := config.ZoneNames()
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return nil, nil, err
+	}
+	for _, name := range names {
+		_, zoneConfig, err := config.LoadZoneConfig(name)
+		if err != nil {
+			skipped = append(skipped, fmt.Sprintf("zone %q: %v", name, err))
+			continue
+		}
+		if zoneConfig == nil {
+			continue
+		}
+		if zoneConfig.TemplateFile == "" && zoneConfig.TemplateInline == "" {
+			add(zoneConfig.Permissions...)
+			continue
+		}
+		policies, err := template.RenderPolicies(zoneConfig.TemplateFile, zoneConfig.TemplateInline, templateVariables(zoneConfig, nil))
+		if err != nil {
+			skipped = append(skipped, fmt.Sprintf("zone %q: template not locked: %v", name, err))
+			continue
+		}
+		for _, p := range policies {
+			for _, pg := range p.PermissionGroups {
+				add(pg.ID)
+			}
+		}
+	}
+
+	refs := make([]string, 0, len(seen))
+	for r := range seen {
+		refs = append(refs, r)
+	}
+	sort.Strings(refs)
+	return refs, skipped, nil
+}
+
+// buildPermissionLock resolves each reference against the live catalog.
+func buildPermissionLock(refs []string, catalog []cloudflare.PermissionGroup, now time.Time) (*config.PermissionLock, error) {
+	lock := &config.PermissionLock{GeneratedAt: now, Permissions: make(map[string]config.LockedPermission, len(refs))}
+	var missing []string
+	for _, ref := range refs {
+		groups, err := cloudflare.ResolvePermissions(catalog, []string{ref})
+		if err != nil {
+			missing = append(missing, ref)
+			continue
+		}
+		lock.Permissions[ref] = config.LockedPermission{ID: groups[0].ID, Name: groups[0].Name}
+	}
+	if len(missing) > 0 {
+		return nil, fmt.Errorf("permission reference(s) not in the live catalog: %s", strings.Join(missing, ", "))
+	}
+	return lock, nil
+}
+
+// lockDrift describes every pinned reference the live catalog no longer agrees with.
+func lockDrift(lock *config.PermissionLock, catalog []cloudflare.PermissionGroup) []string {
+	byID := make(map[string]cloudflare.PermissionGroup, len(catalog))
+	for _, g := range catalog {
+		byID[g.ID] = g
+	}
+
+	var drift []string
+	for _, ref := range sortedKeys(lock.Permissions) {
+		pinned := lock.Permissions[ref]
+		live, ok := byID[pinned.ID]
+		switch {
+		case !ok:
+			drift = append(drift, fmt.Sprintf("%q: pinned group %s (%s) no longer exists", ref, pinned.Name, pinned.ID))
+			continue
+		case live.Name != pinned.Name:
+			drift = append(drift, fmt.Sprintf("%q: group %s was renamed from %q to %q", ref, pinned.ID, pinned.Name, live.Name))
+		}
+		if strings.EqualFold(ref, pinned.ID) {
+			continue
+		}
+		groups, err := cloudflare.ResolvePermissions(catalog, []string{ref})
+		switch {
+		case err != nil:
+			drift = append(drift, fmt.Sprintf("%q: no longer matches any group by name; the pinned ID %s is still used", ref, pinned.ID))
+		case groups[0].ID != pinned.ID:
+			drift = append(drift, fmt.Sprintf("%q: now resolves to %s (%s) instead of the pinned %s", ref, groups[0].Name, groups[0].ID, pinned.ID))
+		}
+	}
+	return drift
+}
+
+// pinPermissions replaces references found in the lock with their pinned IDs
+// so renames upstream do not change what a token is granted.
+func pinPermissions(lock *config.PermissionLock, inputs []string) []string {
+	out := make([]string, len(inputs))
+	for i, in := range inputs {
+		out[i] = in
+		if pinned, ok := lock.Permissions[in]; ok {
+			out[i] = pinned.ID
+		}
+	}
+	return out
+}
+
+// loadCatalog returns the permission lock, nil when none has been
+// generated, and the catalog, fetched when a lock exists or needed is set.
+// A warning is logged for every drifted lock reference.
+func loadCatalog(ctx context.Context, client *cloudflare.Client, needed bool) (*config.PermissionLock, []cloudflare.PermissionGroup, error) {
+	lock, err := config.LoadPermissionLock()
+	if errors.Is(err, fs.ErrNotExist) {
+		lock = nil
+	} else if err != nil {
+		return nil, nil, err
+	}
+	if lock == nil && !needed {
+		return nil, nil, nil
+	}
+	catalog, err := client.PermissionGroups(ctx)
+	if err != nil {
+		return nil, nil, fmt.Errorf("fetch permission groups: %w", err)
+	}
+	if lock != nil {
+		for _, d := range lockDrift(lock, catalog) {
+			log.Printf("warning: permissions.lock.json: %s", d)
+		}
+	}
+	return lock, catalog, nil
+}
+
+// catalogFetch is a loadCatalog call running in the background.
+type catalogFetch struct {
+	done    chan struct{}
+	lock    *config.PermissionLock
+	catalog []cloudflare.PermissionGroup
+	err     error
+}
+
+// startCatalogFetch runs loadCatalog in the background, so the catalog is
+// fetched while the zone is resolved instead of after it.
+func startCatalogFetch(ctx context.Context, client *cloudflare.Client, needed bool) *catalogFetch {
+	f := &catalogFetch{done: make(chan struct{})}
+	go func() {
+		defer close(f.done)
+		f.lock, f.catalog, f.err = loadCatalog(ctx, client, needed)
+	}()
+	return f
+}
+
+// wait returns the result of the fetch once it has finished.
+func (f *catalogFetch) wait() (*config.PermissionLock, []cloudflare.PermissionGroup, error) {
+	<-f.done
+	return f.lock, f.catalog, f.err
+}
+
+// liveCatalog fetches the permission group catalog straight from the API.
+// Lock, snapshot, and diff exist to notice upstream changes, so they must not
+// be answered from the cache.
+func liveCatalog(ctx context.Context, client *cloudflare.Client) ([]cloudflare.PermissionGroup, error) {
+	var groups []cloudflare.PermissionGroup
+	for group, err := range client.PermissionGroupsIter(ctx) {
+		if err != nil {
+			return nil, err
+		}
+		groups = append(groups, group)
+	}
+	return groups, nil
+}
+
+func snapshotFlags(name string) (*flag.FlagSet, *string) {
+	fset := flag.NewFlagSet(name, flag.ContinueOnError)
+	file := fset.String("file", "", "Snapshot file (default: permissions.snapshot.json next to config.json)")
+	return fset, file
+}
+
+func snapshotPath(file string) (string, error) {
+	if file != "" {
+		return file, nil
+	}
+	return config.PermissionSnapshotPath()
+}
+
+func runPermissionsSnapshot(ctx context.Context, client *cloudflare.Client, args []string) error {
+	fset, file := snapshotFlags("permissions snapshot")
+	if err := fset.Parse(args); err != nil {
+		return err
+	}
+	path, err := snapshotPath(*file)
+	if err != nil {
+		return err
+	}
+	catalog, err := liveCatalog(ctx, client)
+	if err != nil {
+		return fmt.Errorf("fetch permission groups: %w", err)
+	}
+	snap := newSnapshot(catalog, clock.Now().UTC())
+	if err := config.SavePermissionSnapshot(path, snap); err != nil {
+		return err
+	}
+	fmt.Printf("Saved %d permission groups to %s\n", len(snap.Groups), path)
+	return nil
+}
+
+func runPermissionsDiff(ctx context.Context, client *cloudflare.Client, args []string) error {
+	fset, file := snapshotFlags("permissions diff")
+	exitCode := fset.Bool("exit-code", false, "Exit non-zero when the catalog changed")
+	if err := fset.Parse(args); err != nil {
+		return err
+	}
+	path, err := snapshotPath(*file)
+	if err != nil {
+		return err
+	}
+	old, err := config.LoadPermissionSnapshot(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("no snapshot at %s; run `cftoken permissions snapshot` first", path)
+	}
+	if err != nil {
+		return err
+	}
+	catalog, err := liveCatalog(ctx, client)
+	if err != nil {
+		return fmt.Errorf("fetch permission groups: %w", err)
+	}
+
+	changes := diffSnapshots(old, newSnapshot(catalog, clock.Now().UTC()))
+	since := old.TakenAt.UTC().Format(time.RFC3339)
+	if len(changes) == 0 {
+		fmt.Printf("No changes since %s\n", since)
+		return nil
+	}
+	fmt.Printf("Changes since %s:\n", since)
+	for _, c := range changes {
+		fmt.Println(diffLine(c))
+	}
+	if *exitCode {
+		return fmt.Errorf("permission catalog changed (%d change(s))", len(changes))
+	}
+	return nil
+}
+
+func newSnapshot(catalog []cloudflare.PermissionGroup, now time.Time) *config.PermissionSnapshot {
+	snap := &config.PermissionSnapshot{TakenAt: now, Groups: make([]config.SnapshotGroup, 0, len(catalog))}
+	for _, g := range catalog {
+		scopes := append([]string(nil), g.Scopes...)
+		sort.Strings(scopes)
+		snap.Groups = append(snap.Groups, config.SnapshotGroup{ID: g.ID, Name: g.Name, Scopes: scopes})
+	}
+	sort.Slice(snap.Groups, func(i, j int) bool { return snap.Groups[i].ID < snap.Groups[j].ID })
+	return snap
+}
+
+// diffSnapshots lists additions (+), removals (-), and renames or scope
+// changes (~) between two snapshots, keyed by group ID and sorted by name.
+func diffSnapshots(old, cur *config.PermissionSnapshot) []string {
+	before := make(map[string]config.SnapshotGroup, len(old.Groups))
+	for _, g := range old.Groups {
+		before[g.ID] = g
+	}
+	after := make(map[string]config.SnapshotGroup, len(cur.Groups))
+	for _, g := range cur.Groups {
+		after[g.ID] = g
+	}
+
+	type change struct{ sortKey, line string }
+	var changes []change
+	for id, g := range after {
+		prev, ok := before[id]
+		switch {
+		case !ok:
+			changes = append(changes, change{g.Name, fmt.Sprintf("+ %s (%s)", g.Name, id)})
+		case prev.Name != g.Name:
+			changes = append(changes, change{g.Name, fmt.Sprintf("~ %s (%s) renamed from %q", g.Name, id, prev.Name)})
+		case strings.Join(prev.Scopes, ",") != strings.Join(g.Scopes, ","):
+			changes = append(changes, change{g.Name, fmt.Sprintf("~ %s (%s) scopes changed: %s -> %s", g.Name, id, joinOrDefault(prev.Scopes, "none"), joinOrDefault(g.Scopes, "none"))})
+		}
+	}
+	for id, g := range before {
+		if _, ok := after[id]; !ok {
+			changes = append(changes, change{g.Name, fmt.Sprintf("- %s (%s)", g.Name, id)})
+		}
+	}
+	sort.Slice(changes, func(i, j int) bool {
+		if changes[i].sortKey != changes[j].sortKey {
+			return changes[i].sortKey < changes[j].sortKey
+		}
+		return changes[i].line < changes[j].line
+	})
+	lines := make([]string, len(changes))
+	for i, c := range changes {
+		lines[i] = c.line
+	}
+	return lines
+}
+
+// exportedGroup is one permission group as written by permissions export.
+type exportedGroup struct {
+	ID          string   `json:"id"`
+	Name        string   `json:"name"`
+	Key         string   `json:"key"`
+	Scopes      []string `json:"scopes"`
+	Description string   `json:"description"`
+}
+
+func runPermissionsExport(ctx context.Context, client *cloudflare.Client, args []string) error {
+	fset := flag.NewFlagSet("permissions export", flag.ContinueOnError)
+	format := fset.String("output", "json", "Export format: json or csv")
+	file := fset.String("file", "-", "File to write, or - for stdout")
+	if err := fset.Parse(args); err != nil {
+		return err
+	}
+	if *format != "json" && *format != "csv" {
+		return withCode(codeInvalidArgument, fmt.Errorf("unknown -output %q; available: json, csv", *format), nil)
+	}
+	catalog, err := liveCatalog(ctx, client)
+	if err != nil {
+		return fmt.Errorf("fetch permission groups: %w", err)
+	}
+
+	var buf bytes.Buffer
+	if err := writeCatalogExport(&buf, *format, exportGroups(catalog)); err != nil {
+		return err
+	}
+	if *file == "-" {
+		_, err := os.Stdout.Write(buf.Bytes())
+		return err
+	}
+	if err := os.WriteFile(*file, buf.Bytes(), 0o644); err != nil {
+		return fmt.Errorf("write export: %w", err)
+	}
+	fmt.Fprintf(os.Stderr, "Exported %d permission groups to %s\n", len(catalog), *file)
+	return nil
+}
+
+// exportGroups flattens the catalog into export records sorted by name and
+// ID. Descriptions and scopes fall back to the group's meta fields.
+func exportGroups(catalog []cloudflare.PermissionGroup) []exportedGroup {
+	out := make([]exportedGroup, 0, len(catalog))
+	for _, g := range catalog {
+		scopes := append([]string(nil), g.Scopes...)
+		if len(scopes) == 0 && g.Meta.Scope != "" {
+			scopes = []string{g.Meta.Scope}
+		}
+		sort.Strings(scopes)
+		out = append(out, exportedGroup{
+			ID:          g.ID,
+			Name:        g.Name,
+			Key:         g.Meta.Key,
+			Scopes:      scopes,
+			Description: coalesce(g.Description, g.Meta.Description),
+		})
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Name != out[j].Name {
+			return out[i].Name < out[j].Name
+		}
+		return out[i].ID < out[j].ID
+	})
+	return out
+}
+
+// writeCatalogExport writes groups as a JSON array or as CSV with a header
+// row. CSV joins multiple scopes with semicolons.
+func writeCatalogExport(w io.Writer, format string, groups []exportedGroup) error {
+	if format == "json" {
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(groups)
+	}
+	cw := csv.NewWriter(w)
+	if err := cw.Write([]string{"id", "name", "key", "scopes", "description"}); err != nil {
+		return err
+	}
+	for _, g := range groups {
+		if err := cw.Write([]string{g.ID, g.Name, g.Key, strings.Join(g.Scopes, ";"), g.Description}); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
@@ -0,0 +1,156 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"cftoken/internal/cloudflare"
+	"cftoken/internal/config"
+)
+
+func TestBuildPermissionLock(t *testing.T) {
+	t.Parallel()
+
+	catalog := []cloudflare.PermissionGroup{
+		{ID: "id-read", Name: "Zone Read"},
+		{ID: "id-dns", Name: "DNS Write"},
+	}
+	now := time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC)
+
+	lock, err := buildPermissionLock([]string{"Zone:Read", "id-dns"}, catalog, now)
+	if err != nil {
+		t.Fatalf("buildPermissionLock() error = %v", err)
+	}
+	want := map[string]config.LockedPermission{
+		"Zone:Read": {ID: "id-read", Name: "Zone Read"},
+		"id-dns":    {ID: "id-dns", Name: "DNS Write"},
+	}
+	if !reflect.DeepEqual(lock.Permissions, want) {
+		t.Fatalf("buildPermissionLock() = %v, want %v", lock.Permissions, want)
+	}
+
+	if _, err := buildPermissionLock([]string{"Nope"}, catalog, now); err == nil {
+		t.Fatalf("buildPermissionLock() with unknown reference error = nil")
+	}
+}
+
+func TestLockDrift(t *testing.T) {
+	t.Parallel()
+
+	lock := &config.PermissionLock{Permissions: map[string]config.LockedPermission{
+		"Zone:Read":  {ID: "id-read", Name: "Zone Read"},
+		"DNS:Write":  {ID: "id-dns", Name: "DNS Write"},
+		"id-gone":    {ID: "id-gone", Name: "Legacy"},
+		"Cache:Purg": {ID: "id-purge", Name: "Cache Purge"},
+	}}
+	catalog := []cloudflare.PermissionGroup{
+		{ID: "id-read", Name: "Zone Read"},
+		{ID: "id-dns", Name: "DNS Records Write"},
+		{ID: "id-purge", Name: "Cache Purge"},
+	}
+
+	got := lockDrift(lock, catalog)
+	want := []string{
+		`"Cache:Purg": no longer matches any group by name; the pinned ID id-purge is still used`,
+		`"DNS:Write": group id-dns was renamed from "DNS Write" to "DNS Records Write"`,
+		`"DNS:Write": no longer matches any group by name; the pinned ID id-dns is still used`,
+		`"id-gone": pinned group Legacy (id-gone) no longer exists`,
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("lockDrift() =\n%q\nwant\n%q", got, want)
+	}
+}
+
+func TestPinPermissions(t *testing.T) {
+	t.Parallel()
+
+	lock := &config.PermissionLock{Permissions: map[string]config.LockedPermission{
+		"DNS:Write": {ID: "id-dns", Name: "DNS Write"},
+	}}
+	got := pinPermissions(lock, []string{"DNS:Write", "Zone:Read"})
+	if want := []string{"id-dns", "Zone:Read"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("pinPermissions() = %v, want %v", got, want)
+	}
+}
+
+func TestCatalogFetchWithoutLock(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+
+	// A nil client panics if the catalog is fetched when it is not needed.
+	lock, catalog, err := startCatalogFetch(context.Background(), nil, false).wait()
+	if lock != nil || catalog != nil || err != nil {
+		t.Fatalf("wait() = %v, %v, %v, want nothing fetched", lock, catalog, err)
+	}
+}
+
+func TestDiffSnapshots(t *testing.T) {
+	t.Parallel()
+
+	old := newSnapshot([]cloudflare.PermissionGroup{
+		{ID: "1", Name: "Zone Read", Scopes: []string{"zone"}},
+		{ID: "2", Name: "DNS Write", Scopes: []string{"zone"}},
+		{ID: "3", Name: "Legacy", Scopes: []string{"zone"}},
+		{ID: "4", Name: "Workers", Scopes: []string{"account"}},
+	}, time.Time{})
+	cur := newSnapshot([]cloudflare.PermissionGroup{
+		{ID: "1", Name: "Zone Read", Scopes: []string{"zone"}},
+		{ID: "2", Name: "DNS Records Write", Scopes: []string{"zone"}},
+		{ID: "4", Name: "Workers", Scopes: []string{"account", "zone"}},
+		{ID: "5", Name: "Analytics Read", Scopes: []string{"zone"}},
+	}, time.Time{})
+
+	got := diffSnapshots(old, cur)
+	want := []string{
+		"+ Analytics Read (5)",
+		`~ DNS Records Write (2) renamed from "DNS Write"`,
+		"- Legacy (3)",
+		"~ Workers (4) scopes changed: account -> account, zone",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("diffSnapshots() =\n%q\nwant\n%q", got, want)
+	}
+	if got := diffSnapshots(cur, cur); len(got) != 0 {
+		t.Fatalf("diffSnapshots(same) = %v, want none", got)
+	}
+}
+
+func TestWriteCatalogExport(t *testing.T) {
+	catalog := []cloudflare.PermissionGroup{
+		{ID: "2", Name: "Zone Read", Scopes: []string{"com.cloudflare.api.account.zone"}, Meta: cloudflare.PermissionGroupMeta{Key: "zone_read", Description: "Read zones"}},
+		{ID: "1", Name: "DNS Write", Description: "Edit DNS, \"all\" records", Scopes: []string{"z", "a"}, Meta: cloudflare.PermissionGroupMeta{Key: "dns_write"}},
+		{ID: "3", Name: "Billing Read", Meta: cloudflare.PermissionGroupMeta{Scope: "com.cloudflare.api.account"}},
+	}
+	groups := exportGroups(catalog)
+
+	tests := []struct {
+		format string
+		want   string
+	}{
+		{"csv", "id,name,key,scopes,description\n" +
+			"3,Billing Read,,com.cloudflare.api.account,\n" +
+			"1,DNS Write,dns_write,a;z,\"Edit DNS, \"\"all\"\" records\"\n" +
+			"2,Zone Read,zone_read,com.cloudflare.api.account.zone,Read zones\n"},
+		{"json", `"key": "dns_write",`},
+	}
+	for _, tc := range tests {
+		var buf bytes.Buffer
+		if err := writeCatalogExport(&buf, tc.format, groups); err != nil {
+			t.Fatalf("writeCatalogExport(%s) error = %v", tc.format, err)
+		}
+		if !strings.Contains(buf.String(), tc.want) {
+			t.Errorf("writeCatalogExport(%s) =\n%s\nwant %q", tc.format, buf.String(), tc.want)
+		}
+	}
+
+	var decoded []exportedGroup
+	var buf bytes.Buffer
+	writeCatalogExport(&buf, "json", groups)
+	if err := json.Unmarshal(buf.Bytes(), &decoded); err != nil || !reflect.DeepEqual(decoded, groups) {
+		t.Fatalf("JSON export does not round-trip: %v\n%s", err, buf.String())
+	}
+}
@@ -0,0 +1,164 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+
+	"cftoken/internal/cloudflare"
+)
+
+// pickerMatches caps how many permission groups one filter lists.
+const pickerMatches = 20
+
+// askPermissions filters the permission group catalog until an empty
+// filter and returns the names picked from the results. Picking a name
+// again drops it. Picking none keeps the permissions the zone or
+// config.json configures. It serves -interactive and -pick-permissions.
+func (w createWizard) askPermissions() ([]string, error) {
+	fmt.Fprintln(w.out, "Filter the permission groups to grant by name, key, or description, e.g. dns wr; an empty filter finishes. Picking none keeps the configured permissions.")
+	var catalog []cloudflare.PermissionGroup
+	var picked []string
+	for {
+		term, err := w.ask("Filter permission groups", "")
+		if err != nil || term == "" {
+			return picked, err
+		}
+		if catalog == nil {
+			if catalog, err = w.catalog(); err != nil {
+				return nil, fmt.Errorf("fetch permission groups: %w", err)
+			}
+		}
+		matches := searchPermissionGroups(catalog, term)
+		if len(matches) == 0 {
+			fmt.Fprintf(w.out, "No permission group matches %q.\n", term)
+			continue
+		}
+		for i, g := range matches {
+			mark := " "
+			if slices.Contains(picked, g.Name) {
+				mark = "*"
+			}
+			fmt.Fprintf(w.out, " %s%d) %s\n", mark, i+1, describePermissionGroup(g))
+		}
+		answer, err := w.ask("Toggle which? Numbers, comma-separated", "")
+		if err != nil {
+			return nil, err
+		}
+		for _, part := range strings.Split(answer, ",") {
+			n, err := strconv.Atoi(strings.TrimSpace(part))
+			if err != nil || n < 1 || n > len(matches) {
+				continue
+			}
+			name := matches[n-1].Name
+			if i := slices.Index(picked, name); i >= 0 {
+				picked = slices.Delete(picked, i, i+1)
+			} else {
+				picked = append(picked, name)
+			}
+		}
+		if len(picked) > 0 {
+			fmt.Fprintf(w.out, "Granting: %s\n", strings.Join(picked, ", "))
+		}
+	}
+}
+
+// describePermissionGroup is a group's line in the picker: its name, then
+// its key and description when the catalog has them.
+func describePermissionGroup(g cloudflare.PermissionGroup) string {
+	line := g.Name
+	if g.Meta.Key != "" {
+		line += " [" + g.Meta.Key + "]"
+	}
+	if desc := stringOrDefault(g.Description, g.Meta.Description); desc != "" {
+		line += " - " + desc
+	}
+	return line
+}
+
+// searchPermissionGroups returns the groups matching every space-separated
+// word of term in their name, key, or description, best match first, at
+// most pickerMatches of them. Groups sharing a name are listed once, as
+// permissions are granted by name.
+func searchPermissionGroups(catalog []cloudflare.PermissionGroup, term string) []cloudflare.PermissionGroup {
+	words := strings.Fields(term)
+	type match struct {
+		group cloudflare.PermissionGroup
+		score int
+	}
+	best := make(map[string]match)
+	for _, g := range catalog {
+		total := 0
+		for _, word := range words {
+			score := -1
+			for _, field := range []string{g.Name, g.Meta.Key, g.Description, g.Meta.Description} {
+				if s, ok := fuzzyScore(word, field); ok && s > score {
+					score = s
+				}
+			}
+			if score < 0 {
+				total = -1
+				break
+			}
+			total += score
+		}
+		if total < 0 {
+			continue
+		}
+		if m, ok := best[g.Name]; !ok || total > m.score {
+			best[g.Name] = match{group: g, score: total}
+		}
+	}
+
+	matches := make([]match, 0, len(best))
+	for _, m := range best {
+		matches = append(matches, m)
+	}
+	sort.Slice(matches, func(i, j int) bool {
+		if matches[i].score != matches[j].score {
+			return matches[i].score > matches[j].score
+		}
+		return matches[i].group.Name < matches[j].group.Name
+	})
+	if len(matches) > pickerMatches {
+		matches = matches[:pickerMatches]
+	}
+	groups := make([]cloudflare.PermissionGroup, len(matches))
+	for i, m := range matches {
+		groups[i] = m.group
+	}
+	return groups
+}
+
+// fuzzyScore reports whether the characters of pattern appear in text in
+// order, ignoring case, as in fzf, and scores the match: each character
+// counts, and more when it follows the previous match directly or starts
+// a word.
+func fuzzyScore(pattern, text string) (int, bool) {
+	p := []rune(strings.ToLower(pattern))
+	t := []rune(strings.ToLower(text))
+	if len(p) == 0 {
+		return 0, true
+	}
+	score, pi, last := 0, 0, -2
+	for ti, r := range t {
+		if pi == len(p) {
+			break
+		}
+		if r != p[pi] {
+			continue
+		}
+		score++
+		if ti == last+1 {
+			score += 2
+		}
+		if ti == 0 || strings.ContainsRune(" _-.:/", t[ti-1]) {
+			score += 3
+		}
+		last = ti
+		pi++
+	}
+	return score, pi == len(p)
+}
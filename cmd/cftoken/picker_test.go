@@ -0,0 +1,96 @@
+package main
+
+import (
+	"bytes"
+	"slices"
+	"strings"
+	"testing"
+
+	"cftoken/internal/cloudflare"
+)
+
+func TestFuzzyScore(t *testing.T) {
+	tests := []struct {
+		pattern, text string
+		want          bool
+	}{
+		{"dns", "DNS Write", true},
+		{"dnw", "DNS Write", true},
+		{"wd", "DNS Write", false},
+		{"", "anything", true},
+		{"zone", "", false},
+	}
+	for _, tc := range tests {
+		if _, got := fuzzyScore(tc.pattern, tc.text); got != tc.want {
+			t.Errorf("fuzzyScore(%q, %q) matched = %v, want %v", tc.pattern, tc.text, got, tc.want)
+		}
+	}
+
+	// A contiguous match at a word start beats a scattered one.
+	prefix, _ := fuzzyScore("wr", "DNS Write")
+	scattered, _ := fuzzyScore("wr", "Workers Routes")
+	if prefix <= scattered {
+		t.Errorf("fuzzyScore(wr): DNS Write = %d, Workers Routes = %d, want the first higher", prefix, scattered)
+	}
+}
+
+func TestSearchPermissionGroups(t *testing.T) {
+	catalog := []cloudflare.PermissionGroup{
+		{ID: "g1", Name: "DNS Write", Meta: cloudflare.PermissionGroupMeta{Key: "dns_records_edit"}},
+		{ID: "g2", Name: "DNS Read", Description: "Read DNS records"},
+		{ID: "g3", Name: "Zone Read", Meta: cloudflare.PermissionGroupMeta{Key: "zone_read"}},
+		{ID: "g4", Name: "DNS Write", Meta: cloudflare.PermissionGroupMeta{Key: "dns_records_edit"}},
+		{ID: "g5", Name: "Workers Routes Write", Meta: cloudflare.PermissionGroupMeta{Description: "Edit routes to Workers"}},
+	}
+	names := func(groups []cloudflare.PermissionGroup) []string {
+		var out []string
+		for _, g := range groups {
+			out = append(out, g.Name)
+		}
+		return out
+	}
+
+	tests := []struct {
+		term string
+		want []string
+	}{
+		{"dns", []string{"DNS Read", "DNS Write"}},
+		{"records_edit", []string{"DNS Write"}},
+		{"dns wr", []string{"DNS Write"}},
+		{"edit routes", []string{"Workers Routes Write"}},
+		{"zread", []string{"Zone Read"}},
+		{"nothing", nil},
+	}
+	for _, tc := range tests {
+		if got := names(searchPermissionGroups(catalog, tc.term)); !slices.Equal(got, tc.want) {
+			t.Errorf("searchPermissionGroups(%q) = %q, want %q", tc.term, got, tc.want)
+		}
+	}
+}
+
+func TestAskPermissionsToggles(t *testing.T) {
+	catalog := func() ([]cloudflare.PermissionGroup, error) {
+		return []cloudflare.PermissionGroup{
+			{ID: "g1", Name: "DNS Write", Description: "Edit DNS records", Meta: cloudflare.PermissionGroupMeta{Key: "dns_records_edit"}},
+			{ID: "g2", Name: "DNS Read"},
+		}, nil
+	}
+	var out bytes.Buffer
+	w := createWizard{ask: newPrompter(strings.NewReader("dns\n1,2\ndns\n1\n\n"), &out), out: &out, catalog: catalog}
+	picked, err := w.askPermissions()
+	if err != nil {
+		t.Fatalf("askPermissions() error = %v", err)
+	}
+	if want := []string{"DNS Write"}; !slices.Equal(picked, want) {
+		t.Errorf("askPermissions() = %q, want %q", picked, want)
+	}
+	for _, want := range []string{
+		"  1) DNS Read\n  2) DNS Write [dns_records_edit] - Edit DNS records\n",
+		" *1) DNS Read\n *2) DNS Write [dns_records_edit] - Edit DNS records\n",
+		"Granting: DNS Read, DNS Write\n",
+	} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("output missing %q:\n%s", want, out.String())
+		}
+	}
+}
@@ -0,0 +1,174 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"cftoken/internal/cloudflare"
+)
+
+// Policy views accepted by inspect -view.
+const (
+	viewList = "list"
+	viewTree = "tree"
+	viewWide = "wide"
+)
+
+var policyViews = []string{viewList, viewTree, viewWide}
+
+const (
+	ansiGreen = "\x1b[32m"
+	ansiRed   = "\x1b[31m"
+	ansiReset = "\x1b[0m"
+)
+
+// useColor reports whether output to f may be colored: f is a terminal,
+// NO_COLOR is unset, and accessible mode is off.
+func useColor(f *os.File) bool {
+	return isTerminal(f) && os.Getenv("NO_COLOR") == "" && !accessible
+}
+
+// printPolicies writes policies in the given view. The list view numbers
+// the policies as the API returns them; tree groups permission groups under
+// each resource across policies; wide prints one table row per resource and
+// permission group, naming policies by ID where the API returned one.
+func printPolicies(w io.Writer, policies []cloudflare.TokenPolicyInspection, view string, color bool) {
+	if len(policies) == 0 {
+		fmt.Fprintln(w, "Policies: none")
+		return
+	}
+	fmt.Fprintln(w, "Policies:")
+	switch view {
+	case viewTree:
+		printPolicyTree(w, policies, color)
+	case viewWide:
+		printPolicyTable(w, policies)
+	default:
+		printPolicyList(w, policies)
+	}
+}
+
+func printPolicyList(w io.Writer, policies []cloudflare.TokenPolicyInspection) {
+	for idx, policy := range policies {
+		fmt.Fprintf(w, "  %d. Effect: %s\n", idx+1, stringOrDefault(policy.Effect, "<unknown>"))
+		if policy.ID != "" {
+			fmt.Fprintf(w, "     ID: %s\n", policy.ID)
+		}
+		fmt.Fprintf(w, "     Resources: %s\n", joinOrDefault(policy.Resources, "none"))
+		if len(policy.PermissionGroups) == 0 {
+			fmt.Fprintln(w, "     Permission Groups: none")
+			continue
+		}
+		fmt.Fprintln(w, "     Permission Groups:")
+		for _, grp := range policy.PermissionGroups {
+			display := coalesce(grp.Name, grp.Key, grp.ID)
+			if grp.Key != "" && grp.Key != display {
+				fmt.Fprintf(w, "       - %s (%s, key: %s)\n", display, grp.ID, grp.Key)
+			} else {
+				fmt.Fprintf(w, "       - %s (%s)\n", display, grp.ID)
+			}
+		}
+	}
+}
+
+// policyGrant is one permission group a policy applies to a resource.
+type policyGrant struct {
+	effect string
+	group  cloudflare.PermissionGroupSummary
+}
+
+func printPolicyTree(w io.Writer, policies []cloudflare.TokenPolicyInspection, color bool) {
+	grants := make(map[string][]policyGrant)
+	for _, policy := range policies {
+		resources := policy.Resources
+		if len(resources) == 0 {
+			resources = []string{"<no resources>"}
+		}
+		for _, resource := range resources {
+			for _, grp := range policy.PermissionGroups {
+				grants[resource] = append(grants[resource], policyGrant{effect: stringOrDefault(policy.Effect, "<unknown>"), group: grp})
+			}
+			if _, ok := grants[resource]; !ok {
+				grants[resource] = nil
+			}
+		}
+	}
+	for _, resource := range sortedKeys(grants) {
+		list := grants[resource]
+		if accessible {
+			printAccessibleGrants(w, resource, list)
+			continue
+		}
+		fmt.Fprintf(w, "  %s\n", resource)
+		if len(list) == 0 {
+			fmt.Fprintln(w, "  └─ no permission groups")
+			continue
+		}
+		for i, g := range list {
+			branch := "├─"
+			if i == len(list)-1 {
+				branch = "└─"
+			}
+			fmt.Fprintf(w, "  %s %s %s (%s)\n", branch, effectLabel(g.effect, color), coalesce(g.group.Name, g.group.Key, g.group.ID), g.group.ID)
+		}
+	}
+}
+
+// printAccessibleGrants writes the tree view of one resource with labels
+// and indentation in place of box drawing.
+func printAccessibleGrants(w io.Writer, resource string, list []policyGrant) {
+	fmt.Fprintf(w, "  Resource: %s, %d permission group(s)\n", resource, len(list))
+	for _, g := range list {
+		fmt.Fprintf(w, "    %s: %s, ID %s\n", g.effect, coalesce(g.group.Name, g.group.Key, g.group.ID), g.group.ID)
+	}
+}
+
+// effectLabel pads the effect to a fixed width, green for allow and red for
+// deny when color is set.
+func effectLabel(effect string, color bool) string {
+	label := fmt.Sprintf("%-5s", effect)
+	if !color {
+		return label
+	}
+	switch effect {
+	case "allow":
+		return ansiGreen + label + ansiReset
+	case "deny":
+		return ansiRed + label + ansiReset
+	}
+	return label
+}
+
+func printPolicyTable(w io.Writer, policies []cloudflare.TokenPolicyInspection) {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "  POLICY\tEFFECT\tRESOURCE\tPERMISSION GROUP\tID")
+	for idx, policy := range policies {
+		resources := policy.Resources
+		if len(resources) == 0 {
+			resources = []string{"-"}
+		}
+		groups := policy.PermissionGroups
+		if len(groups) == 0 {
+			groups = []cloudflare.PermissionGroupSummary{{ID: "-"}}
+		}
+		for _, resource := range resources {
+			for _, grp := range groups {
+				fmt.Fprintf(tw, "  %s\t%s\t%s\t%s\t%s\n", stringOrDefault(policy.ID, strconv.Itoa(idx+1)), stringOrDefault(policy.Effect, "<unknown>"), resource, coalesce(grp.Name, grp.Key, grp.ID), grp.ID)
+			}
+		}
+	}
+	tw.Flush()
+}
+
+// validPolicyView rejects an unknown -view value.
+func validPolicyView(view string) error {
+	if slices.Contains(policyViews, view) {
+		return nil
+	}
+	return fmt.Errorf("unknown -view %q; available: %s", view, strings.Join(policyViews, ", "))
+}
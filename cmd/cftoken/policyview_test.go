@@ -0,0 +1,68 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"cftoken/internal/cloudflare"
+)
+
+func TestPrintPolicies(t *testing.T) {
+	t.Parallel()
+
+	dns := cloudflare.PermissionGroupSummary{ID: "g1", Name: "DNS Write"}
+	purge := cloudflare.PermissionGroupSummary{ID: "g2", Name: "Cache Purge"}
+	policies := []cloudflare.TokenPolicyInspection{
+		{Effect: "allow", Resources: []string{"zone.z1=*", "zone.z2=*"}, PermissionGroups: []cloudflare.PermissionGroupSummary{dns, purge}},
+		{ID: "p2", Effect: "deny", Resources: []string{"zone.z2=*"}, PermissionGroups: []cloudflare.PermissionGroupSummary{purge}},
+	}
+
+	tests := []struct {
+		view  string
+		color bool
+		want  string
+	}{
+		{viewList, false, `Policies:
+  1. Effect: allow
+     Resources: zone.z1=*, zone.z2=*
+     Permission Groups:
+       - DNS Write (g1)
+       - Cache Purge (g2)
+  2. Effect: deny
+     ID: p2
+     Resources: zone.z2=*
+     Permission Groups:
+       - Cache Purge (g2)
+`},
+		{viewTree, false, `Policies:
+  zone.z1=*
+  ├─ allow DNS Write (g1)
+  └─ allow Cache Purge (g2)
+  zone.z2=*
+  ├─ allow DNS Write (g1)
+  ├─ allow Cache Purge (g2)
+  └─ deny  Cache Purge (g2)
+`},
+		{viewTree, true, "  └─ \x1b[31mdeny \x1b[0m Cache Purge (g2)\n"},
+		{viewWide, false, `Policies:
+  POLICY  EFFECT  RESOURCE   PERMISSION GROUP  ID
+  1       allow   zone.z1=*  DNS Write         g1
+  1       allow   zone.z1=*  Cache Purge       g2
+  1       allow   zone.z2=*  DNS Write         g1
+  1       allow   zone.z2=*  Cache Purge       g2
+  p2      deny    zone.z2=*  Cache Purge       g2
+`},
+	}
+	for _, tc := range tests {
+		var buf bytes.Buffer
+		printPolicies(&buf, policies, tc.view, tc.color)
+		if got := buf.String(); !strings.HasSuffix(got, tc.want) {
+			t.Errorf("printPolicies(%s, color %v) =\n%s\nwant suffix\n%s", tc.view, tc.color, got, tc.want)
+		}
+	}
+
+	if err := validPolicyView("json"); err == nil {
+		t.Errorf("validPolicyView(json) = nil, want error")
+	}
+}
@@ -0,0 +1,276 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	htmltemplate "html/template"
+	"io"
+	"io/fs"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"cftoken/internal/clock"
+	"cftoken/internal/cloudflare"
+	"cftoken/internal/config"
+	"cftoken/internal/template"
+)
+
+// portalPage is everything the portal page renders. It is built from the
+// live permission catalog and the configuration, never from hand-written docs.
+type portalPage struct {
+	Title       string
+	GeneratedAt time.Time
+	Guardrails  []string
+	Zones       []portalEntry
+	Profiles    []portalEntry
+	Groups      []exportedGroup
+}
+
+// portalEntry describes what tokens for one zone or profile are granted.
+type portalEntry struct {
+	Name         string
+	ZoneID       string
+	Source       string
+	Permissions  []string
+	AllowedCIDRs []string
+	TTL          string
+	Guardrails   []string
+	Notes        []string
+}
+
+func runPortal(ctx context.Context, client *cloudflare.Client, args []string) error {
+	fset := flag.NewFlagSet("portal", flag.ContinueOnError)
+	file := fset.String("file", "portal.html", "File to write, or - for stdout")
+	title := fset.String("title", "Cloudflare API tokens", "Page title")
+	if err := fset.Parse(args); err != nil {
+		return err
+	}
+
+	catalog, err := liveCatalog(ctx, client)
+	if err != nil {
+		return fmt.Errorf("fetch permission groups: %w", err)
+	}
+	page, err := buildPortal(catalog, clock.Now().UTC())
+	if err != nil {
+		return err
+	}
+	page.Title = *title
+
+	var buf bytes.Buffer
+	if err := renderPortal(&buf, page); err != nil {
+		return err
+	}
+	if *file == "-" {
+		_, err := os.Stdout.Write(buf.Bytes())
+		return err
+	}
+	if err := os.WriteFile(*file, buf.Bytes(), 0o644); err != nil {
+		return fmt.Errorf("write portal: %w", err)
+	}
+	fmt.Fprintf(os.Stderr, "Wrote portal for %d zone(s) and %d profile(s) to %s\n", len(page.Zones), len(page.Profiles), *file)
+	return nil
+}
+
+// buildPortal describes every configured zone and profile against catalog.
+// Entries that cannot be resolved fully, such as templates that need -var,
+// are still listed with a note instead of failing the page.
+func buildPortal(catalog []cloudflare.PermissionGroup, now time.Time) (*portalPage, error) {
+	page := &portalPage{GeneratedAt: now, Groups: exportGroups(catalog)}
+
+	defaults, err := config.LoadDefaultPermissions()
+	if errors.Is(err, fs.ErrNotExist) {
+		defaults = cloudflare.DefaultPermissionKeys
+	} else if err != nil {
+		return nil, err
+	}
+	defaultCIDRs, err := config.LoadDefaultAllowedCIDRs()
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return nil, err
+	}
+	global, err := config.LoadGuardrails()
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return nil, err
+	}
+	page.Guardrails = describeGuardrails(global)
+
+	names, err := config.ZoneNames()
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return nil, err
+	}
+	for _, name := range names {
+		zoneID, zoneConfig, err := config.LoadZoneConfig(name)
+		if err != nil {
+			page.Zones = append(page.Zones, portalEntry{Name: name, Notes: []string{err.Error()}})
+			continue
+		}
+		if zoneConfig == nil {
+			zoneConfig = &config.ZoneConfig{ZoneID: zoneID}
+		}
+		entry := describePortalEntry(name, zoneConfig, defaults, catalog)
+		if len(entry.AllowedCIDRs) == 0 {
+			entry.AllowedCIDRs = defaultCIDRs
+		}
+		page.Zones = append(page.Zones, entry)
+	}
+
+	profiles, err := config.LoadProfiles()
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return nil, err
+	}
+	for _, name := range sortedKeys(profiles) {
+		profile := profiles[name]
+		page.Profiles = append(page.Profiles, describePortalEntry(name, &profile, defaults, catalog))
+	}
+	return page, nil
+}
+
+// describePortalEntry resolves the permission group names zc grants. Zones
+// without permissions or a template fall back to defaults, as issuance does.
+func describePortalEntry(name string, zc *config.ZoneConfig, defaults []string, catalog []cloudflare.PermissionGroup) portalEntry {
+	entry := portalEntry{
+		Name:         name,
+		ZoneID:       zc.ZoneID,
+		AllowedCIDRs: zc.AllowedCIDRs,
+		TTL:          zc.TTL,
+		Guardrails:   describeGuardrails(zc.Guardrails),
+	}
+
+	if zc.TemplateFile != "" || zc.TemplateInline != "" {
+		entry.Source = stringOrDefault(zc.TemplateFile, "inline template")
+		policies, err := template.RenderPolicies(zc.TemplateFile, zc.TemplateInline, templateVariables(zc, nil))
+		if err != nil {
+			entry.Notes = append(entry.Notes, fmt.Sprintf("permissions depend on -var values given at issue time (%v)", err))
+			return entry
+		}
+		byID := make(map[string]string, len(catalog))
+		for _, g := range catalog {
+			byID[g.ID] = g.Name
+		}
+		seen := make(map[string]bool)
+		for _, p := range policies {
+			for _, pg := range p.PermissionGroups {
+				groupName := coalesce(byID[pg.ID], pg.Name, pg.ID)
+				if !seen[groupName] {
+					seen[groupName] = true
+					entry.Permissions = append(entry.Permissions, groupName)
+				}
+			}
+		}
+		sort.Strings(entry.Permissions)
+		return entry
+	}
+
+	refs := zc.Permissions
+	entry.Source = "permissions"
+	if len(refs) == 0 {
+		refs = defaults
+		entry.Source = "default permissions"
+	}
+	groups, err := cloudflare.ResolvePermissions(catalog, refs)
+	if err != nil {
+		entry.Permissions = append([]string(nil), refs...)
+		entry.Notes = append(entry.Notes, err.Error())
+		return entry
+	}
+	for _, g := range groups {
+		entry.Permissions = append(entry.Permissions, g.Name)
+	}
+	sort.Strings(entry.Permissions)
+	return entry
+}
+
+// describeGuardrails lists the limits g sets in plain words.
+func describeGuardrails(g *config.Guardrails) []string {
+	if g == nil {
+		return nil
+	}
+	var out []string
+	if g.RequireIPRestriction {
+		out = append(out, "IP restriction required")
+	}
+	if g.MaxTTL != "" {
+		out = append(out, "TTL at most "+g.MaxTTL)
+	}
+	if len(g.DeniedPermissions) > 0 {
+		out = append(out, "never grants "+strings.Join(g.DeniedPermissions, ", "))
+	}
+	if g.RequireTicket {
+		out = append(out, "change ticket required")
+	}
+	for _, p := range append([]string{g.TicketPattern}, g.InheritedTicketPatterns...) {
+		if p != "" {
+			out = append(out, "tickets match "+p)
+		}
+	}
+	return out
+}
+
+var portalTemplate = htmltemplate.Must(htmltemplate.New("portal").Funcs(htmltemplate.FuncMap{
+	"join": strings.Join,
+	"date": func(t time.Time) string { return t.Format(time.RFC3339) },
+}).Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>{{.Title}}</title>
+<style>
+body { font-family: system-ui, sans-serif; margin: 2rem; color: #222; }
+table { border-collapse: collapse; width: 100%; margin-bottom: 2rem; }
+th, td { border: 1px solid #ccc; padding: .4rem .6rem; text-align: left; vertical-align: top; }
+th { background: #f4f4f4; }
+.note { color: #a15c00; }
+code { font-size: .9em; }
+</style>
+</head>
+<body>
+<h1>{{.Title}}</h1>
+<p>Generated by cftoken from the live permission catalog and configuration at {{date .GeneratedAt}}.</p>
+{{- if .Guardrails}}
+<p>Every token: {{join .Guardrails "; "}}.</p>
+{{- end}}
+{{define "entries"}}
+<table>
+<tr><th>Name</th><th>Permissions</th><th>Allowed CIDRs</th><th>TTL</th><th>Guardrails</th></tr>
+{{- range .}}
+<tr>
+<td><code>{{.Name}}</code>{{if .ZoneID}}<br><small>{{.ZoneID}}</small>{{end}}</td>
+<td>{{if .Permissions}}{{join .Permissions ", "}}{{end}}{{if .Source}}<br><small>from {{.Source}}</small>{{end}}{{range .Notes}}<br><span class="note">{{.}}</span>{{end}}</td>
+<td>{{if .AllowedCIDRs}}{{join .AllowedCIDRs ", "}}{{else}}any{{end}}</td>
+<td>{{if .TTL}}{{.TTL}}{{else}}none{{end}}</td>
+<td>{{join .Guardrails "; "}}</td>
+</tr>
+{{- end}}
+</table>
+{{end}}
+<h2>Zones</h2>
+{{- if .Zones}}{{template "entries" .Zones}}{{else}}
+<p>No zones are configured.</p>
+{{- end}}
+{{- if .Profiles}}
+<h2>Profiles</h2>
+<p>Zones reference a profile with <code>"extends"</code> to inherit these settings.</p>
+{{template "entries" .Profiles}}
+{{- end}}
+<h2>Permission groups</h2>
+<table>
+<tr><th>Name</th><th>Key</th><th>Scopes</th><th>Description</th><th>ID</th></tr>
+{{- range .Groups}}
+<tr><td>{{.Name}}</td><td><code>{{.Key}}</code></td><td>{{join .Scopes ", "}}</td><td>{{.Description}}</td><td><small>{{.ID}}</small></td></tr>
+{{- end}}
+</table>
+</body>
+</html>
+`))
+
+// renderPortal writes page as a self-contained HTML document.
+func renderPortal(w io.Writer, page *portalPage) error {
+	if err := portalTemplate.Execute(w, page); err != nil {
+		return fmt.Errorf("render portal: %w", err)
+	}
+	return nil
+}
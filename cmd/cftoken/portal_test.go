@@ -0,0 +1,82 @@
+package main
+
+import (
+	"bytes"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"cftoken/internal/cloudflare"
+)
+
+func TestBuildPortal(t *testing.T) {
+	root := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", root)
+	writeConfig(t, root, `{
+		"default_permissions": ["Zone Read"],
+		"default_allowed_cidrs": ["198.51.100.0/24"],
+		"guardrails": {"require_ticket": true},
+		"profiles": {"edit": {"permissions": ["DNS Write"], "ttl": "4h"}},
+		"zones": {
+			"example.com": "aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa",
+			"prod": {"zone_id": "bbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbb", "extends": "edit", "guardrails": {"max_ttl": "8h"}},
+			"broken": {"zone_id": "cccccccccccccccccccccccccccccccc", "permissions": ["Nope"]}
+		}
+	}`)
+	catalog := []cloudflare.PermissionGroup{
+		{ID: "id-read", Name: "Zone Read", Scopes: []string{"com.cloudflare.api.account.zone"}},
+		{ID: "id-dns", Name: "DNS Write", Description: "Edit <DNS> records"},
+	}
+
+	page, err := buildPortal(catalog, time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC))
+	if err != nil {
+		t.Fatalf("buildPortal() error = %v", err)
+	}
+	if got := strings.Join(page.Guardrails, ";"); got != "change ticket required" {
+		t.Fatalf("Guardrails = %q", got)
+	}
+	zones := make(map[string]portalEntry)
+	for _, z := range page.Zones {
+		zones[z.Name] = z
+	}
+	if z := zones["example.com"]; strings.Join(z.Permissions, ",") != "Zone Read" || z.Source != "default permissions" || strings.Join(z.AllowedCIDRs, ",") != "198.51.100.0/24" {
+		t.Fatalf("example.com = %+v", z)
+	}
+	if z := zones["prod"]; strings.Join(z.Permissions, ",") != "DNS Write" || z.TTL != "4h" || strings.Join(z.Guardrails, ";") != "TTL at most 8h" {
+		t.Fatalf("prod = %+v", z)
+	}
+	if z := zones["broken"]; len(z.Notes) != 1 || strings.Join(z.Permissions, ",") != "Nope" {
+		t.Fatalf("broken = %+v", z)
+	}
+	if len(page.Profiles) != 1 || page.Profiles[0].Name != "edit" {
+		t.Fatalf("Profiles = %+v", page.Profiles)
+	}
+
+	page.Title = "Tokens & more"
+	var buf bytes.Buffer
+	if err := renderPortal(&buf, page); err != nil {
+		t.Fatalf("renderPortal() error = %v", err)
+	}
+	html := buf.String()
+	for _, want := range []string{"<title>Tokens &amp; more</title>", "<code>prod</code>", "Edit &lt;DNS&gt; records", "<h2>Profiles</h2>"} {
+		if !strings.Contains(html, want) {
+			t.Errorf("portal HTML missing %q", want)
+		}
+	}
+}
+
+func TestRenderPortalWithoutZones(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", filepath.Join(t.TempDir(), "missing"))
+	page, err := buildPortal(nil, time.Now())
+	if err != nil {
+		t.Fatalf("buildPortal() error = %v", err)
+	}
+	var buf bytes.Buffer
+	if err := renderPortal(&buf, page); err != nil {
+		t.Fatalf("renderPortal() error = %v", err)
+	}
+	if !strings.Contains(buf.String(), "No zones are configured.") {
+		t.Fatalf("portal HTML = %s", buf.String())
+	}
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+)
+
+const (
+	progressStarted   = "started"
+	progressSucceeded = "succeeded"
+	progressFailed    = "failed"
+	progressSkipped   = "skipped"
+)
+
+// progressEvent is one line of -progress-format ndjson output.
+type progressEvent struct {
+	Time       time.Time         `json:"time"`
+	Step       string            `json:"step"`
+	Status     string            `json:"status"`
+	DurationMS *int64            `json:"duration_ms,omitempty"`
+	Error      string            `json:"error,omitempty"`
+	Detail     map[string]string `json:"detail,omitempty"`
+	// CorrelationID ties the event to the run's requests and error output.
+	CorrelationID string `json:"correlation_id,omitempty"`
+}
+
+// progressReporter writes one JSON event per line for each step of token
+// creation. A nil reporter discards events, so callers need not check.
+type progressReporter struct {
+	mu            sync.Mutex
+	w             io.Writer
+	now           func() time.Time
+	correlationID string
+}
+
+// newProgressReporter returns nil for the default text format.
+func newProgressReporter(format, correlationID string, w io.Writer) (*progressReporter, error) {
+	switch format {
+	case "", "text":
+		return nil, nil
+	case "ndjson":
+		return &progressReporter{w: w, now: time.Now, correlationID: correlationID}, nil
+	default:
+		return nil, fmt.Errorf("unknown -progress-format %q; available: text, ndjson", format)
+	}
+}
+
+// start emits a started event and returns a function that emits the step's
+// outcome along with how long it took.
+func (p *progressReporter) start(step string) func(err error, detail map[string]string) {
+	if p == nil {
+		return func(error, map[string]string) {}
+	}
+	began := p.now()
+	p.emit(progressEvent{Time: began, Step: step, Status: progressStarted})
+	return func(err error, detail map[string]string) {
+		end := p.now()
+		ms := end.Sub(began).Milliseconds()
+		ev := progressEvent{Time: end, Step: step, Status: progressSucceeded, DurationMS: &ms, Detail: detail}
+		if err != nil {
+			ev.Status = progressFailed
+			ev.Error = err.Error()
+		}
+		p.emit(ev)
+	}
+}
+
+// skip records that a step did not run and why.
+func (p *progressReporter) skip(step, reason string) {
+	if p == nil {
+		return
+	}
+	p.emit(progressEvent{Time: p.now(), Step: step, Status: progressSkipped, Detail: map[string]string{"reason": reason}})
+}
+
+func (p *progressReporter) emit(ev progressEvent) {
+	ev.Time = ev.Time.UTC()
+	ev.CorrelationID = p.correlationID
+	data, err := json.Marshal(ev)
+	if err != nil {
+		return
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.w.Write(append(data, '\n'))
+}
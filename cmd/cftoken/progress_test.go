@@ -0,0 +1,83 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestProgressReporterNDJSON(t *testing.T) {
+	t.Parallel()
+
+	var buf bytes.Buffer
+	p, err := newProgressReporter("ndjson", "corr-1", &buf)
+	if err != nil {
+		t.Fatalf("newProgressReporter() error = %v", err)
+	}
+	clock := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	p.now = func() time.Time {
+		clock = clock.Add(250 * time.Millisecond)
+		return clock
+	}
+
+	p.start("create")(nil, map[string]string{"token_id": "tok-1"})
+	p.start("sink")(errors.New("vault sealed"), nil)
+	p.skip("verify", "token does not grant DNS write")
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 5 {
+		t.Fatalf("got %d lines, want 5:\n%s", len(lines), buf.String())
+	}
+	var events []progressEvent
+	for _, line := range lines {
+		var ev progressEvent
+		if err := json.Unmarshal([]byte(line), &ev); err != nil {
+			t.Fatalf("line %q is not JSON: %v", line, err)
+		}
+		events = append(events, ev)
+	}
+
+	tests := []struct {
+		step, status string
+	}{
+		{"create", progressStarted},
+		{"create", progressSucceeded},
+		{"sink", progressStarted},
+		{"sink", progressFailed},
+		{"verify", progressSkipped},
+	}
+	for i, tt := range tests {
+		if events[i].Step != tt.step || events[i].Status != tt.status {
+			t.Errorf("event %d = %s/%s, want %s/%s", i, events[i].Step, events[i].Status, tt.step, tt.status)
+		}
+	}
+	if events[1].DurationMS == nil || *events[1].DurationMS != 250 || events[1].Detail["token_id"] != "tok-1" {
+		t.Errorf("succeeded event = %+v", events[1])
+	}
+	if events[3].Error != "vault sealed" {
+		t.Errorf("failed event error = %q", events[3].Error)
+	}
+	for i, ev := range events {
+		if ev.CorrelationID != "corr-1" {
+			t.Errorf("event %d correlation_id = %q, want corr-1", i, ev.CorrelationID)
+		}
+	}
+}
+
+func TestNewProgressReporterFormats(t *testing.T) {
+	t.Parallel()
+
+	for _, format := range []string{"", "text"} {
+		p, err := newProgressReporter(format, "", &bytes.Buffer{})
+		if err != nil || p != nil {
+			t.Errorf("newProgressReporter(%q) = %v, %v; want nil reporter", format, p, err)
+		}
+		p.start("create")(nil, nil)
+	}
+	if _, err := newProgressReporter("xml", "", &bytes.Buffer{}); err == nil {
+		t.Error("newProgressReporter(\"xml\") error = nil")
+	}
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	"cftoken/internal/clock"
+	"cftoken/internal/cloudflare"
+	"cftoken/internal/duration"
+	"cftoken/internal/labels"
+	"cftoken/internal/naming"
+)
+
+// runPrune revokes the tokens cftoken created that are of no further use:
+// expired ones, and with -older-than also those issued before that age.
+func runPrune(ctx context.Context, client *cloudflare.Client, args []string) error {
+	fset := flag.NewFlagSet("prune", flag.ContinueOnError)
+	prefix := fset.String("prefix", "", "Only prune tokens with this name before the generated suffix, e.g. the zone name")
+	olderThan := fset.String("older-than", "", "Also prune unexpired tokens issued longer ago than this, e.g. 72h or 7d")
+	dryRun := fset.Bool("dry-run", false, "List the tokens that would be pruned without revoking them")
+	if err := fset.Parse(args); err != nil {
+		return err
+	}
+	if fset.NArg() > 0 {
+		return withCode(codeInvalidArgument, fmt.Errorf("prune takes no arguments, got %q; use -prefix", fset.Arg(0)), nil)
+	}
+	var maxAge time.Duration
+	if *olderThan != "" {
+		age, err := duration.Parse(*olderThan)
+		if err != nil {
+			return withCode(codeInvalidArgument, fmt.Errorf("invalid -older-than: %w", err), nil)
+		}
+		maxAge = age
+	}
+
+	// Never prune the token we are authenticating with.
+	self, err := client.VerifyToken(ctx)
+	if err != nil {
+		return err
+	}
+	tokens, err := client.ListTokens(ctx)
+	if err != nil {
+		return err
+	}
+	selected := selectPrunable(tokens, *prefix, maxAge, self.ID, clock.Now())
+	if len(selected) == 0 {
+		fmt.Println("Nothing to prune.")
+		return nil
+	}
+
+	reg, err := labels.Load()
+	if err != nil {
+		return err
+	}
+	printRevokeCandidates(os.Stdout, selected, reg)
+	if *dryRun {
+		fmt.Printf("\nDRY RUN: %d token(s) would be pruned. Nothing was changed.\n", len(selected))
+		return nil
+	}
+	fmt.Println()
+	return revokeSelected(ctx, client, selected)
+}
+
+// selectPrunable returns the tokens whose name carries a timestamp or ULID
+// suffix generated by cftoken, with prefix before it when prefix is set,
+// that have expired or, with maxAge set, were issued at least maxAge before
+// now. The token with ID selfID is skipped.
+func selectPrunable(tokens []cloudflare.Token, prefix string, maxAge time.Duration, selfID string, now time.Time) []cloudflare.Token {
+	var selected []cloudflare.Token
+	for _, token := range tokens {
+		if token.ID == selfID {
+			continue
+		}
+		base := naming.Trim(token.Name)
+		if base == token.Name || (prefix != "" && base != prefix) {
+			continue
+		}
+		expired := token.Status == "expired" || (!token.ExpiresOn.IsZero() && !token.ExpiresOn.After(now))
+		stale := maxAge > 0 && !token.IssuedOn.IsZero() && now.Sub(token.IssuedOn) >= maxAge
+		if expired || stale {
+			selected = append(selected, token)
+		}
+	}
+	return selected
+}
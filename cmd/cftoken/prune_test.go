@@ -0,0 +1,46 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"cftoken/internal/cloudflare"
+)
+
+func TestSelectPrunable(t *testing.T) {
+	t.Parallel()
+
+	now := time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)
+	tokens := []cloudflare.Token{
+		{ID: "1", Name: "prod-20240501T000000Z", Status: "expired", IssuedOn: now.AddDate(0, -1, 0)},
+		{ID: "2", Name: "prod-20240531T000000Z", Status: "active", IssuedOn: now.Add(-24 * time.Hour), ExpiresOn: now.Add(-16 * time.Hour)},
+		{ID: "3", Name: "prod-20240525T000000Z", Status: "active", IssuedOn: now.AddDate(0, 0, -7)},
+		{ID: "4", Name: "prod-20240531T230000Z", Status: "active", IssuedOn: now.Add(-time.Hour), ExpiresOn: now.Add(7 * time.Hour)},
+		{ID: "5", Name: "dev-01HK421P48Y90JT025F1K432WR", Status: "expired"},
+		{ID: "6", Name: "hand-made", Status: "expired"},
+		{ID: "self", Name: "cftoken-20230101T000000Z", Status: "active", IssuedOn: now.AddDate(-1, 0, 0)},
+	}
+
+	tests := []struct {
+		name   string
+		prefix string
+		maxAge time.Duration
+		want   []string
+	}{
+		{"expired only", "", 0, []string{"1", "2", "5"}},
+		{"prefix", "prod", 0, []string{"1", "2"}},
+		{"older than", "prod", 72 * time.Hour, []string{"1", "2", "3"}},
+		{"no match", "staging", 72 * time.Hour, nil},
+	}
+	for _, tc := range tests {
+		got := selectPrunable(tokens, tc.prefix, tc.maxAge, "self", now)
+		var ids []string
+		for _, token := range got {
+			ids = append(ids, token.ID)
+		}
+		if strings.Join(ids, ",") != strings.Join(tc.want, ",") {
+			t.Errorf("%s: selectPrunable() = %v, want %v", tc.name, ids, tc.want)
+		}
+	}
+}
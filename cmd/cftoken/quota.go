@@ -0,0 +1,48 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"text/tabwriter"
+	"time"
+
+	"cftoken/internal/cloudflare"
+)
+
+// quotaClient is the part of the Cloudflare client quota needs.
+type quotaClient interface {
+	VerifyToken(ctx context.Context) (*cloudflare.TokenVerification, error)
+	RateLimit() (cloudflare.RateLimit, bool)
+}
+
+// runQuota makes one cheap API call and prints the rate-limit budget
+// Cloudflare reported on it, so batch jobs can be sized to fit.
+func runQuota(ctx context.Context, w io.Writer, client quotaClient, args []string) error {
+	fset := flag.NewFlagSet("quota", flag.ContinueOnError)
+	if err := fset.Parse(args); err != nil {
+		return err
+	}
+	if fset.NArg() > 0 {
+		return withCode(codeInvalidArgument, errors.New("usage: quota"), nil)
+	}
+	if _, err := client.VerifyToken(ctx); err != nil {
+		return err
+	}
+	rl, ok := client.RateLimit()
+	if !ok {
+		fmt.Fprintln(w, "Cloudflare did not report a rate limit for this token.")
+		return nil
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintf(tw, "Policy:\t%s\n", rl.Policy)
+	if rl.Limit > 0 {
+		fmt.Fprintf(tw, "Limit:\t%d requests per %s\n", rl.Limit, rl.Window)
+	}
+	fmt.Fprintf(tw, "Remaining:\t%d\n", rl.Remaining)
+	fmt.Fprintf(tw, "Resets:\t%s (in %s)\n", rl.At.Add(rl.Reset).UTC().Format(time.RFC3339), rl.Reset)
+	return tw.Flush()
+}
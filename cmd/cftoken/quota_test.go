@@ -0,0 +1,53 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"cftoken/internal/cloudflare"
+)
+
+type fakeQuota struct {
+	rl cloudflare.RateLimit
+	ok bool
+}
+
+func (f fakeQuota) VerifyToken(context.Context) (*cloudflare.TokenVerification, error) {
+	return &cloudflare.TokenVerification{ID: "tok", Status: "active"}, nil
+}
+
+func (f fakeQuota) RateLimit() (cloudflare.RateLimit, bool) { return f.rl, f.ok }
+
+func TestRunQuota(t *testing.T) {
+	at := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	tests := []struct {
+		name   string
+		client fakeQuota
+		want   []string
+		absent string
+	}{
+		{"reported", fakeQuota{cloudflare.RateLimit{Policy: "default", Limit: 1200, Window: 5 * time.Minute, Remaining: 1150, Reset: 30 * time.Second, At: at}, true},
+			[]string{"1200 requests per 5m0s", "Remaining:  1150", "2024-01-02T03:04:35Z (in 30s)"}, ""},
+		{"no policy", fakeQuota{cloudflare.RateLimit{Policy: "default", Remaining: 7, At: at}, true}, []string{"Remaining:  7"}, "Limit:"},
+		{"not reported", fakeQuota{}, []string{"did not report"}, "Remaining:"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := runQuota(context.Background(), &buf, tc.client, nil); err != nil {
+				t.Fatalf("runQuota() error = %v", err)
+			}
+			for _, want := range tc.want {
+				if !strings.Contains(buf.String(), want) {
+					t.Errorf("runQuota() output missing %q:\n%s", want, buf.String())
+				}
+			}
+			if tc.absent != "" && strings.Contains(buf.String(), tc.absent) {
+				t.Errorf("runQuota() output has %q:\n%s", tc.absent, buf.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,6 @@
+//go:build readonly
+
+package main
+
+// buildReadOnly locks binaries built with -tags readonly into read-only mode.
+const buildReadOnly = true
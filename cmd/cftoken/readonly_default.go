@@ -0,0 +1,6 @@
+//go:build !readonly
+
+package main
+
+// buildReadOnly is false for regular builds; see readonly_build.go.
+const buildReadOnly = false
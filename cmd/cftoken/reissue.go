@@ -0,0 +1,156 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io/fs"
+	"os"
+	"strings"
+	"time"
+
+	"cftoken/internal/clock"
+	"cftoken/internal/cloudflare"
+	"cftoken/internal/config"
+	"cftoken/internal/duration"
+	"cftoken/internal/httpmw"
+	"cftoken/internal/revision"
+)
+
+// runReissue mints a token from a stored policy revision. The revision's
+// policies are used verbatim; templates and zone permissions are not read,
+// but guardrails still apply.
+func runReissue(ctx context.Context, client *cloudflare.Client, args []string) error {
+	fset := flag.NewFlagSet("reissue", flag.ContinueOnError)
+	revisionID := fset.String("revision", "", "Policy revision to issue from, as listed by history (required)")
+	tokenPrefix := fset.String("token-prefix", "", "Prefix for the new token name (defaults to default_token_prefix_template or the revision's zone)")
+	ttl, err := tokenDefaultTTL()
+	if err != nil {
+		return err
+	}
+	fset.Var((*duration.Value)(&ttl), "ttl", "Token TTL, e.g. 8h or 2d (use 0 for no expiration)")
+	allowCIDRs := fset.String("allow-cidrs", "", "Comma-separated CIDRs allowed to use the token (defaults to those of the revision's last issuance)")
+	dryRun := fset.Bool("dry-run", false, "Preview the token without creating it")
+	yes := fset.Bool("yes", false, "Do not ask for confirmation before creating a risky token")
+	if err := fset.Parse(args); err != nil {
+		return err
+	}
+	if *revisionID == "" {
+		return withCode(codeInvalidArgument, errors.New("reissue requires -revision"), nil)
+	}
+	rev, err := revision.Load(*revisionID)
+	if errors.Is(err, os.ErrNotExist) {
+		return withCode(codeNotFound, fmt.Errorf("policy revision %s not found; run `cftoken history -zone NAME` to list revisions", *revisionID), nil)
+	}
+	if err != nil {
+		return err
+	}
+
+	now := clock.Now().UTC()
+	p, err := planReissue(rev, *tokenPrefix, ttl, *allowCIDRs, now)
+	if err != nil {
+		return err
+	}
+
+	// The zone may have been removed from config.json since; its guardrails
+	// then no longer apply, but the global ones still do.
+	_, zoneConfig, err := config.LoadZoneConfig(rev.Zone)
+	if errors.Is(err, config.ErrZoneNotFound) || errors.Is(err, fs.ErrNotExist) {
+		zoneConfig = nil
+	} else if err != nil {
+		return err
+	}
+	if err := checkFrozen(rev.Zone, rev.ZoneID, zoneConfig); err != nil {
+		return err
+	}
+	// The name takes the zone's name_suffix, known only now.
+	prefix := *tokenPrefix
+	if prefix == "" {
+		if prefix, err = defaultTokenPrefix(rev.Zone, rev.ZoneID); err != nil {
+			return err
+		}
+	}
+	if p.name, err = generateName(zoneConfig, prefix, now, *dryRun); err != nil {
+		return err
+	}
+	p.uniqueName = uniqueNames(zoneConfig)
+	rules, err := loadGuardrails(zoneConfig)
+	if err != nil {
+		return err
+	}
+	if violations := rules.Evaluate(guardrailRequest(p)); len(violations) > 0 {
+		err := fmt.Errorf("guardrails rejected the token:\n  - %s", strings.Join(violations, "\n  - "))
+		return withCode(codeGuardrail, err, map[string]any{"violations": violations})
+	}
+	p.name = ticketedName(p.name, rules)
+
+	if err := requireValueSink(false); err != nil {
+		return err
+	}
+
+	if *dryRun {
+		if err := printDryRun(reportOutput(), p.name, stringOrDefault(rev.ZoneID, "none"), rev.Zone, p.expiresOn, p.allowedCIDRs, p.policies); err != nil {
+			return fmt.Errorf("dry run failed: %w", err)
+		}
+		return nil
+	}
+
+	hold, err := checkBudget(1)
+	if err != nil {
+		return err
+	}
+	defer hold.release()
+	ctx, cancel, err := approveTokenSet(ctx, []plannedToken{p}, stringOrDefault(rev.ZoneID, "none"), rev.Zone, *yes)
+	if err != nil {
+		return err
+	}
+	defer cancel()
+	j := beginJournal(ctx, "reissue")
+	results, err := createTokenSet(ctx, client, j, []plannedToken{p})
+	if err != nil {
+		return journalError(err, j)
+	}
+	result := results[0]
+	recordIssued(hold, result)
+	labelIssued(result)
+	recordRevision(rev.Zone, rev.ZoneID, rev.Source, rev.Policies, revision.Issuance{
+		TokenID:       result.ID,
+		TokenName:     result.Name,
+		AllowedCIDRs:  p.allowedCIDRs,
+		CorrelationID: httpmw.RequestID(ctx),
+	})
+	printTokenResult(os.Stdout, result, rev.Zone, p.ttl)
+	return nil
+}
+
+// planReissue builds the token to mint from rev. Without -allow-cidrs the
+// CIDRs of the revision's last issuance are reused; a revision last issued
+// without IP restrictions needs them spelled out again.
+func planReissue(rev *revision.Revision, prefix string, ttl time.Duration, allowCIDRs string, now time.Time) (plannedToken, error) {
+	p := plannedToken{
+		name:     stringOrDefault(prefix, rev.Zone) + "-" + now.Format("20060102T150405Z"),
+		ttl:      ttl,
+		policies: rev.Policies,
+	}
+	if ttl > 0 {
+		exp := now.Add(ttl)
+		p.expiresOn = &exp
+	}
+
+	var cidrs []string
+	if allowCIDRs != "" {
+		cidrs = strings.Split(allowCIDRs, ",")
+	} else if len(rev.Issuances) > 0 {
+		cidrs = rev.Issuances[len(rev.Issuances)-1].AllowedCIDRs
+	}
+	allowed, disabled, err := normalizeCIDRList(cidrs)
+	if err != nil {
+		return plannedToken{}, err
+	}
+	if len(allowed) == 0 && !disabled {
+		return plannedToken{}, withCode(codeInvalidArgument, fmt.Errorf("revision %s has no allowed CIDRs on record; pass -allow-cidrs", rev.ID), nil)
+	}
+	p.allowedCIDRs = allowed
+	return p, nil
+}
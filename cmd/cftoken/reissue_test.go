@@ -0,0 +1,63 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"cftoken/internal/revision"
+	"cftoken/internal/template"
+)
+
+func TestPlanReissue(t *testing.T) {
+	now := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	rev := &revision.Revision{
+		ID:       "abc123",
+		Zone:     "example.com",
+		Policies: []template.Policy{{Effect: "allow", PermissionGroups: []template.PermissionGroup{{ID: "g1"}}}},
+		Issuances: []revision.Issuance{
+			{AllowedCIDRs: []string{"192.0.2.0/24"}},
+			{AllowedCIDRs: []string{"198.51.100.0/24"}},
+		},
+	}
+
+	tests := []struct {
+		name      string
+		rev       *revision.Revision
+		prefix    string
+		ttl       time.Duration
+		cidrs     string
+		wantName  string
+		wantCIDRs []string
+		wantErr   bool
+	}{
+		{"last issuance cidrs", rev, "", 8 * time.Hour, "", "example.com-20240102T030405Z", []string{"198.51.100.0/24"}, false},
+		{"explicit cidrs and prefix", rev, "rollback", 0, "203.0.113.0/24, 192.0.2.0/24", "rollback-20240102T030405Z", []string{"203.0.113.0/24", "192.0.2.0/24"}, false},
+		{"restrictions disabled", rev, "", time.Hour, "0.0.0.0/32", "example.com-20240102T030405Z", nil, false},
+		{"invalid cidr", rev, "", time.Hour, "nope", "", nil, true},
+		{"no cidrs on record", &revision.Revision{ID: "x", Zone: "example.com", Issuances: []revision.Issuance{{}}}, "", time.Hour, "", "", nil, true},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			p, err := planReissue(tc.rev, tc.prefix, tc.ttl, tc.cidrs, now)
+			if tc.wantErr {
+				if err == nil {
+					t.Fatalf("planReissue() error = nil, want error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("planReissue() error = %v", err)
+			}
+			if p.name != tc.wantName || (len(p.allowedCIDRs) != 0 || len(tc.wantCIDRs) != 0) && !reflect.DeepEqual(p.allowedCIDRs, tc.wantCIDRs) {
+				t.Errorf("planReissue() = %s %v, want %s %v", p.name, p.allowedCIDRs, tc.wantName, tc.wantCIDRs)
+			}
+			if (tc.ttl == 0) != (p.expiresOn == nil) {
+				t.Errorf("planReissue() expiresOn = %v for ttl %s", p.expiresOn, tc.ttl)
+			}
+			if !reflect.DeepEqual(p.policies, tc.rev.Policies) {
+				t.Errorf("planReissue() policies = %+v, want the revision's", p.policies)
+			}
+		})
+	}
+}
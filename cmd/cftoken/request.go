@@ -0,0 +1,284 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"time"
+
+	"cftoken/internal/clock"
+	"cftoken/internal/cloudflare"
+	"cftoken/internal/config"
+	"cftoken/internal/duration"
+	"cftoken/internal/httpmw"
+	"cftoken/internal/request"
+	"cftoken/internal/revision"
+	"cftoken/internal/template"
+)
+
+// disabledCIDRs is the allowed_cidrs value that turns IP restrictions off.
+var disabledCIDRs = []string{"0.0.0.0/32"}
+
+// runExportRequest renders a token set into a signed request bundle without
+// touching Cloudflare, for fulfill-request to create on a machine that holds
+// the management token. client is nil when no token is available.
+func runExportRequest(ctx context.Context, client *cloudflare.Client, args []string) error {
+	fset := flag.NewFlagSet("export-request", flag.ContinueOnError)
+	source := addTokenSetFlags(fset)
+	keyFile := fset.String("key", "", "File holding the private key to sign the request with (required)")
+	keygen := fset.Bool("keygen", false, "Write a new private key to -key and print its public key for request_signers")
+	out := fset.String("file", "-", "File to write the bundle to, or - for stdout")
+	validFor := 24 * time.Hour
+	fset.Var((*duration.Value)(&validFor), "valid-for", "How long the request can be fulfilled, e.g. 12h or 2d")
+	if err := fset.Parse(args); err != nil {
+		return err
+	}
+	if *keyFile == "" {
+		return withCode(codeInvalidArgument, errors.New("export-request requires -key"), nil)
+	}
+	if *keygen {
+		return writeRequestKey(os.Stdout, *keyFile)
+	}
+	if validFor <= 0 {
+		return withCode(codeInvalidArgument, errors.New("-valid-for must be positive"), nil)
+	}
+	key, err := request.ReadPrivateKey(*keyFile)
+	if err != nil {
+		return err
+	}
+
+	specs, zoneConfig, err := source.render(ctx, client, "export-request")
+	if err != nil {
+		return err
+	}
+	defaultCIDRs, err := config.LoadDefaultAllowedCIDRs()
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("load default CIDRs: %w", err)
+	}
+	now := clock.Now().UTC()
+	plans, err := planTokenSet(specs, source.zone, zoneConfig, defaultCIDRs, now, true)
+	if err != nil {
+		return err
+	}
+	// Checked here so a request the guardrails reject is not carried across
+	// the air gap; fulfill-request checks again with its own configuration.
+	if err := guardTokenSet(plans, zoneConfig); err != nil {
+		return err
+	}
+
+	id, err := request.NewID()
+	if err != nil {
+		return err
+	}
+	req := &request.Request{
+		Version:   request.Version,
+		ID:        id,
+		CreatedAt: now,
+		ExpiresAt: now.Add(validFor),
+		Zone:      source.zone,
+		ZoneID:    zoneConfig.ZoneID,
+		Source:    templateSource(source.templatePath),
+		Ticket:    changeTicket,
+		Tokens:    requestTokens(specs, plans),
+	}
+	data, err := request.Sign(req, key)
+	if err != nil {
+		return err
+	}
+	if *out == "-" {
+		_, err := os.Stdout.Write(data)
+		return err
+	}
+	if err := os.WriteFile(*out, data, 0o600); err != nil {
+		return fmt.Errorf("write request: %w", err)
+	}
+	fmt.Fprintf(os.Stderr, "Wrote request %s for %d token(s) to %s; fulfill it before %s\n", id, len(plans), *out, req.ExpiresAt.Format(time.RFC3339))
+	return nil
+}
+
+// requestTokens pins each spec's allowed CIDRs to those resolved from the
+// exporting machine's configuration, so the fulfilling side cannot widen
+// them with its own defaults.
+func requestTokens(specs []template.TokenSpec, plans []plannedToken) []template.TokenSpec {
+	out := make([]template.TokenSpec, len(specs))
+	for i, spec := range specs {
+		spec.AllowedCIDRs = plans[i].allowedCIDRs
+		if len(spec.AllowedCIDRs) == 0 {
+			spec.AllowedCIDRs = disabledCIDRs
+		}
+		out[i] = spec
+	}
+	return out
+}
+
+// writeRequestKey creates a signing key at path, refusing to overwrite one.
+func writeRequestKey(w io.Writer, path string) error {
+	pub, priv, err := request.GenerateKey()
+	if err != nil {
+		return err
+	}
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o600)
+	if err != nil {
+		return fmt.Errorf("write signing key: %w", err)
+	}
+	if _, err := fmt.Fprintln(f, priv); err != nil {
+		f.Close()
+		return fmt.Errorf("write signing key: %w", err)
+	}
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("write signing key: %w", err)
+	}
+	fmt.Fprintf(w, "Wrote signing key to %s. Add its public key to request_signers on the machine that fulfills requests:\n%s\n", path, pub)
+	return nil
+}
+
+// runFulfillRequest creates the tokens of a request bundle signed by a key
+// in request_signers. The request's policies are used verbatim, but freezes,
+// guardrails, and the budget of this machine's configuration apply.
+func runFulfillRequest(ctx context.Context, client *cloudflare.Client, args []string) error {
+	fset := flag.NewFlagSet("fulfill-request", flag.ContinueOnError)
+	dryRun := fset.Bool("dry-run", false, "Verify the request and preview every token without creating any")
+	yes := fset.Bool("yes", false, "Do not ask for confirmation before creating risky tokens")
+	if err := fset.Parse(args); err != nil {
+		return err
+	}
+	if fset.NArg() != 1 {
+		return withCode(codeInvalidArgument, errors.New("usage: fulfill-request [-dry-run] [-yes] FILE|-"), nil)
+	}
+	data, err := readRequestFile(fset.Arg(0), os.Stdin)
+	if err != nil {
+		return err
+	}
+	signers, err := config.LoadRequestSigners()
+	if errors.Is(err, fs.ErrNotExist) {
+		return withCode(codeUntrustedRequest, errors.New("no request_signers in config.json; add the public key printed by `cftoken export-request -keygen`"), nil)
+	}
+	if err != nil {
+		return err
+	}
+	now := clock.Now().UTC()
+	req, err := request.Verify(data, signers, now)
+	if err != nil {
+		return err
+	}
+	if changeTicket == "" {
+		changeTicket = req.Ticket
+	}
+
+	zoneConfig, err := requestZoneConfig(req)
+	if err != nil {
+		return err
+	}
+	plans, err := planTokenSet(req.Tokens, req.Zone, zoneConfig, nil, now, *dryRun)
+	if err != nil {
+		return err
+	}
+	if err := singleTokenOutput(len(plans)); err != nil {
+		return err
+	}
+	if err := guardTokenSet(plans, zoneConfig); err != nil {
+		return err
+	}
+
+	if err := requireValueSink(false); err != nil {
+		return err
+	}
+
+	if *dryRun {
+		fmt.Fprintf(reportOutput(), "Request %s created %s, valid until %s\n\n", req.ID, req.CreatedAt.Format(time.RFC3339), req.ExpiresAt.Format(time.RFC3339))
+		for i, p := range plans {
+			if i > 0 {
+				fmt.Fprintln(reportOutput())
+			}
+			if err := printDryRun(reportOutput(), p.name, stringOrDefault(req.ZoneID, "none"), req.Zone, p.expiresOn, p.allowedCIDRs, p.policies); err != nil {
+				return fmt.Errorf("dry run failed: %w", err)
+			}
+		}
+		return nil
+	}
+
+	hold, err := checkBudget(len(plans))
+	if err != nil {
+		return err
+	}
+	defer hold.release()
+	ctx, cancel, err := approveTokenSet(ctx, plans, stringOrDefault(req.ZoneID, "none"), req.Zone, *yes)
+	if err != nil {
+		return err
+	}
+	defer cancel()
+	if err := request.MarkFulfilled(req, now); err != nil {
+		return err
+	}
+	j := beginJournal(ctx, "fulfill-request")
+	results, err := createTokenSet(ctx, client, j, plans)
+	if err != nil {
+		return journalError(err, j)
+	}
+	recordIssued(hold, results...)
+	labelIssued(results...)
+	for i, result := range results {
+		recordRevision(req.Zone, req.ZoneID, stringOrDefault(req.Source, "request "+req.ID), plans[i].policies, revision.Issuance{
+			TokenID:       result.ID,
+			TokenName:     result.Name,
+			AllowedCIDRs:  plans[i].allowedCIDRs,
+			CorrelationID: httpmw.RequestID(ctx),
+		})
+	}
+	for i, result := range results {
+		if i > 0 && !structuredOutput() {
+			fmt.Fprintln(reportOutput())
+		}
+		printTokenResult(os.Stdout, result, req.Zone, plans[i].ttl)
+	}
+	return nil
+}
+
+// requestZoneConfig returns this machine's configuration for the request's
+// zone, whose freeze and guardrails apply. A zone missing from config.json
+// only gets the global guardrails, as with reissue, but one configured with
+// a different zone_id is refused: its policies were rendered for another zone.
+func requestZoneConfig(req *request.Request) (*config.ZoneConfig, error) {
+	if req.Zone == "" {
+		return &config.ZoneConfig{ZoneID: req.ZoneID}, checkFrozen("", req.ZoneID, nil)
+	}
+	// Only a zone that is missing is left to the global guardrails; a
+	// config.json that cannot be read or resolved fails the request.
+	zoneID, zoneConfig, err := config.LoadZoneConfig(req.Zone)
+	if errors.Is(err, config.ErrZoneNotFound) || errors.Is(err, fs.ErrNotExist) {
+		zoneID, zoneConfig = "", nil
+	} else if err != nil {
+		return nil, err
+	}
+	if zoneID != "" && req.ZoneID != "" && zoneID != req.ZoneID {
+		return nil, withCode(codeUntrustedRequest, fmt.Errorf("request %s is for zone %s with ID %s, but config.json has %s", req.ID, req.Zone, req.ZoneID, zoneID), map[string]any{"zone": req.Zone})
+	}
+	if err := checkFrozen(req.Zone, req.ZoneID, zoneConfig); err != nil {
+		return nil, err
+	}
+	if zoneConfig == nil {
+		zoneConfig = &config.ZoneConfig{}
+	}
+	zoneConfig.ZoneID = req.ZoneID
+	return zoneConfig, nil
+}
+
+// readRequestFile reads a bundle from path, or from stdin when path is "-".
+func readRequestFile(path string, stdin io.Reader) ([]byte, error) {
+	if path == "-" {
+		data, err := io.ReadAll(stdin)
+		if err != nil {
+			return nil, fmt.Errorf("read request from stdin: %w", err)
+		}
+		return data, nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read request: %w", err)
+	}
+	return data, nil
+}
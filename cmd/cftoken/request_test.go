@@ -0,0 +1,75 @@
+package main
+
+import (
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"cftoken/internal/request"
+	"cftoken/internal/template"
+)
+
+func TestRequestTokens(t *testing.T) {
+	specs := []template.TokenSpec{{Name: "deploy", TTL: "1h"}, {Name: "open", AllowedCIDRs: []string{"0.0.0.0/32"}}}
+	plans := []plannedToken{{allowedCIDRs: []string{"192.0.2.0/24"}}, {}}
+
+	got := requestTokens(specs, plans)
+	if !reflect.DeepEqual(got[0].AllowedCIDRs, []string{"192.0.2.0/24"}) || got[0].TTL != "1h" {
+		t.Errorf("requestTokens()[0] = %+v", got[0])
+	}
+	if !reflect.DeepEqual(got[1].AllowedCIDRs, disabledCIDRs) {
+		t.Errorf("requestTokens()[1].AllowedCIDRs = %v, want %v", got[1].AllowedCIDRs, disabledCIDRs)
+	}
+	if specs[0].AllowedCIDRs != nil {
+		t.Errorf("requestTokens() modified its input")
+	}
+}
+
+func TestRequestZoneConfig(t *testing.T) {
+	root := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", root)
+	t.Setenv("XDG_STATE_HOME", filepath.Join(root, "state"))
+	writeConfig(t, root, `{"zones": {
+		"prod": {"zone_id": "zone-prod", "guardrails": {"max_ttl": "1h"}},
+		"frozen": {"zone_id": "zone-frozen", "frozen": true},
+		"broken": {"zone_id": "zone-broken", "extends": "missing"}
+	}}`)
+
+	tests := []struct {
+		name    string
+		req     request.Request
+		wantTTL string
+		wantErr string
+	}{
+		{"configured zone", request.Request{Zone: "prod", ZoneID: "zone-prod"}, "1h", ""},
+		{"unknown zone", request.Request{Zone: "other", ZoneID: "zone-other"}, "", ""},
+		{"zone id mismatch", request.Request{ID: "r1", Zone: "prod", ZoneID: "zone-x"}, "", "config.json has zone-prod"},
+		{"frozen", request.Request{Zone: "frozen", ZoneID: "zone-frozen"}, "", "frozen"},
+		{"broken zone", request.Request{Zone: "broken", ZoneID: "zone-broken"}, "", `profile "missing" not found`},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			zc, err := requestZoneConfig(&tc.req)
+			if tc.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+					t.Fatalf("requestZoneConfig() error = %v, want %q", err, tc.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("requestZoneConfig() error = %v", err)
+			}
+			if zc.ZoneID != tc.req.ZoneID {
+				t.Errorf("requestZoneConfig() zone ID = %q, want %q", zc.ZoneID, tc.req.ZoneID)
+			}
+			var maxTTL string
+			if zc.Guardrails != nil {
+				maxTTL = zc.Guardrails.MaxTTL
+			}
+			if maxTTL != tc.wantTTL {
+				t.Errorf("requestZoneConfig() max_ttl = %q, want %q", maxTTL, tc.wantTTL)
+			}
+		})
+	}
+}
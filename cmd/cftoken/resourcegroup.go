@@ -0,0 +1,88 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"cftoken/internal/cloudflare"
+	"cftoken/internal/config"
+	"cftoken/internal/template"
+)
+
+// resourceGroupPrefix marks a policy resource key that names an account
+// resource group, as in "resource_group:Web zones": "*".
+const resourceGroupPrefix = "resource_group:"
+
+// resourceGroupFinder is the part of the Cloudflare client resource group
+// expansion needs.
+type resourceGroupFinder interface {
+	FindResourceGroup(ctx context.Context, accountID, ref string) (*cloudflare.ResourceGroup, error)
+}
+
+// resolveResourceGroups expands the resource groups policies name, looking
+// up the account they belong to first. Policies without any are returned
+// as they are, without API calls.
+func resolveResourceGroups(ctx context.Context, client *cloudflare.Client, zoneConfig *config.ZoneConfig, policies []template.Policy) ([]template.Policy, error) {
+	if !usesResourceGroups(policies) {
+		return policies, nil
+	}
+	if client == nil {
+		return nil, errors.New("resource groups are resolved through the API; run with a management token")
+	}
+	if err := lookupAccountID(ctx, client, zoneConfig); err != nil {
+		return nil, err
+	}
+	return expandResourceGroups(ctx, client, zoneConfig.AccountID, policies)
+}
+
+func usesResourceGroups(policies []template.Policy) bool {
+	for _, p := range policies {
+		for key := range p.Resources {
+			if strings.HasPrefix(key, resourceGroupPrefix) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// expandResourceGroups replaces each resource_group: key with the scopes of
+// that group. A scope the policy already lists with a different value is an
+// error rather than a silent widening or narrowing.
+func expandResourceGroups(ctx context.Context, client resourceGroupFinder, accountID string, policies []template.Policy) ([]template.Policy, error) {
+	groups := make(map[string]*cloudflare.ResourceGroup)
+	out := make([]template.Policy, len(policies))
+	for i, p := range policies {
+		resources := make(map[string]interface{}, len(p.Resources))
+		for key, value := range p.Resources {
+			if !strings.HasPrefix(key, resourceGroupPrefix) {
+				resources[key] = value
+			}
+		}
+		for _, key := range sortedKeys(p.Resources) {
+			ref, ok := strings.CutPrefix(key, resourceGroupPrefix)
+			if !ok {
+				continue
+			}
+			group, ok := groups[ref]
+			if !ok {
+				var err error
+				if group, err = client.FindResourceGroup(ctx, accountID, ref); err != nil {
+					return nil, withCode(codeInvalidArgument, err, map[string]any{"resource_group": ref})
+				}
+				groups[ref] = group
+			}
+			for scope, value := range group.Resources {
+				if existing, ok := resources[scope]; ok && fmt.Sprint(existing) != fmt.Sprint(value) {
+					return nil, withCode(codeInvalidArgument, fmt.Errorf("resource group %q covers %s, which the policy already lists differently", group.Name, scope), nil)
+				}
+				resources[scope] = value
+			}
+		}
+		p.Resources = resources
+		out[i] = p
+	}
+	return out, nil
+}
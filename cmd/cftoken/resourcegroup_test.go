@@ -0,0 +1,66 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"reflect"
+	"testing"
+
+	"cftoken/internal/cloudflare"
+	"cftoken/internal/template"
+)
+
+type fakeResourceGroups map[string]map[string]interface{}
+
+func (f fakeResourceGroups) FindResourceGroup(_ context.Context, accountID, ref string) (*cloudflare.ResourceGroup, error) {
+	resources, ok := f[ref]
+	if !ok || accountID != "acc-1" {
+		return nil, fmt.Errorf("resource group %q: %w", ref, fs.ErrNotExist)
+	}
+	return &cloudflare.ResourceGroup{ID: "rg-" + ref, Name: ref, Resources: resources}, nil
+}
+
+func TestExpandResourceGroups(t *testing.T) {
+	groups := fakeResourceGroups{
+		"web": {"com.cloudflare.api.account.zone.z1": "*"},
+		"all": {"com.cloudflare.api.account.zone.z1": "*", "com.cloudflare.api.account.zone.z2": "*"},
+	}
+	dns := []template.PermissionGroup{{ID: "dns"}}
+
+	tests := []struct {
+		name    string
+		in      map[string]interface{}
+		want    map[string]interface{}
+		wantErr bool
+	}{
+		{"no groups", map[string]interface{}{"com.cloudflare.api.account.zone.z9": "*"}, map[string]interface{}{"com.cloudflare.api.account.zone.z9": "*"}, false},
+		{"group and zone", map[string]interface{}{"resource_group:web": "*", "com.cloudflare.api.account.zone.z9": "*"}, map[string]interface{}{
+			"com.cloudflare.api.account.zone.z1": "*",
+			"com.cloudflare.api.account.zone.z9": "*",
+		}, false},
+		{"unknown group", map[string]interface{}{"resource_group:nope": "*"}, nil, true},
+		{"overlapping groups", map[string]interface{}{"resource_group:web": "*", "resource_group:all": "*"}, map[string]interface{}{
+			"com.cloudflare.api.account.zone.z1": "*",
+			"com.cloudflare.api.account.zone.z2": "*",
+		}, false},
+		{"conflicting resource", map[string]interface{}{"resource_group:web": "*", "com.cloudflare.api.account.zone.z1": "read"}, nil, true},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := expandResourceGroups(context.Background(), groups, "acc-1", []template.Policy{{Effect: "allow", Resources: tc.in, PermissionGroups: dns}})
+			if tc.wantErr {
+				if err == nil {
+					t.Fatalf("expandResourceGroups() error = nil, want error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("expandResourceGroups() error = %v", err)
+			}
+			if !reflect.DeepEqual(got[0].Resources, tc.want) || !reflect.DeepEqual(got[0].PermissionGroups, dns) {
+				t.Errorf("expandResourceGroups() = %+v, want resources %v", got[0], tc.want)
+			}
+		})
+	}
+}
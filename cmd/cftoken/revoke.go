@@ -0,0 +1,194 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path"
+	"slices"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"cftoken/internal/clock"
+	"cftoken/internal/cloudflare"
+	"cftoken/internal/duration"
+	"cftoken/internal/labels"
+)
+
+func runRevoke(ctx context.Context, client *cloudflare.Client, args []string) error {
+	fset := flag.NewFlagSet("revoke", flag.ContinueOnError)
+	var labelFilter varFlag
+	match := fset.String("match", "", "Glob matched against token names, e.g. 'ci-*' (required unless -label is given)")
+	olderThan := fset.String("older-than", "", "Only revoke tokens issued longer ago than this, e.g. 30d, 2w, or 12h")
+	dryRun := fset.Bool("dry-run", false, "List the tokens that would be revoked without revoking them")
+	fset.Var(&labelFilter, "label", "Only revoke tokens with this key=value label (can be specified multiple times)")
+	if err := fset.Parse(args); err != nil {
+		return err
+	}
+
+	refs := fset.Args()
+	if len(refs) > 0 && (*match != "" || len(labelFilter) > 0 || *olderThan != "") {
+		return withCode(codeInvalidArgument, errors.New("revoke takes token IDs or names, or -match and -label, not both"), nil)
+	}
+	if len(refs) == 0 && *match == "" && len(labelFilter) == 0 {
+		return errors.New("revoke requires a token ID or name, -match, or -label")
+	}
+	if *match == "" {
+		*match = "*"
+	}
+	if _, err := path.Match(*match, ""); err != nil {
+		return fmt.Errorf("invalid -match pattern %q: %w", *match, err)
+	}
+	var minAge time.Duration
+	if *olderThan != "" {
+		age, err := duration.Parse(*olderThan)
+		if err != nil {
+			return fmt.Errorf("invalid -older-than: %w", err)
+		}
+		minAge = age
+	}
+
+	// Never revoke the token we are authenticating with, even if it matches.
+	self, err := client.VerifyToken(ctx)
+	if err != nil {
+		return err
+	}
+	tokens, err := client.ListTokens(ctx)
+	if err != nil {
+		return err
+	}
+	reg, err := labels.Load()
+	if err != nil {
+		return err
+	}
+	var selected []cloudflare.Token
+	if len(refs) > 0 {
+		if selected, err = selectTokenRefs(tokens, refs, self.ID); err != nil {
+			return withCode(codeInvalidArgument, err, nil)
+		}
+	} else {
+		selected = filterByLabels(selectTokens(tokens, *match, minAge, self.ID, clock.Now()), reg, labelFilter)
+	}
+	if len(selected) == 0 {
+		fmt.Println("No tokens match.")
+		return nil
+	}
+
+	printRevokeCandidates(os.Stdout, selected, reg)
+	if *dryRun {
+		fmt.Printf("\nDRY RUN at %s: %d token(s) would be revoked (%s). Nothing was changed.\n",
+			clock.Now().UTC().Format(time.RFC3339), len(selected), describeRevokeSelection(refs, *match, *olderThan, labelFilter))
+		return nil
+	}
+
+	fmt.Println()
+	return revokeSelected(ctx, client, selected)
+}
+
+// revokeSelected deletes every token in selected along with its labels,
+// carrying on past failures and reporting how many there were.
+func revokeSelected(ctx context.Context, client *cloudflare.Client, selected []cloudflare.Token) error {
+	failed := 0
+	for _, token := range selected {
+		if err := client.DeleteToken(ctx, token.ID); err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to revoke %s (%s): %v\n", token.Name, token.ID, err)
+			failed++
+			continue
+		}
+		fmt.Printf("Revoked %s (%s)\n", token.Name, token.ID)
+		if err := labels.Remove(token.ID); err != nil {
+			log.Printf("warning: forget labels of %s: %v", token.ID, err)
+		}
+	}
+	if failed > 0 {
+		return fmt.Errorf("failed to revoke %d of %d token(s)", failed, len(selected))
+	}
+	return nil
+}
+
+// selectTokens returns the tokens whose name matches pattern and that were
+// issued at least minAge before now, skipping the token with ID selfID.
+func selectTokens(tokens []cloudflare.Token, pattern string, minAge time.Duration, selfID string, now time.Time) []cloudflare.Token {
+	var selected []cloudflare.Token
+	for _, token := range tokens {
+		if token.ID == selfID {
+			continue
+		}
+		if ok, _ := path.Match(pattern, token.Name); !ok {
+			continue
+		}
+		if minAge > 0 && (token.IssuedOn.IsZero() || now.Sub(token.IssuedOn) < minAge) {
+			continue
+		}
+		selected = append(selected, token)
+	}
+	return selected
+}
+
+// selectTokenRefs returns the token each ref names by ID or exact name. A
+// ref that matches no token, or a name shared by several, is an error, as is
+// the token with ID selfID.
+func selectTokenRefs(tokens []cloudflare.Token, refs []string, selfID string) ([]cloudflare.Token, error) {
+	var selected []cloudflare.Token
+	for _, ref := range refs {
+		var matches []cloudflare.Token
+		for _, token := range tokens {
+			if token.ID == ref {
+				matches = []cloudflare.Token{token}
+				break
+			}
+			if token.Name == ref {
+				matches = append(matches, token)
+			}
+		}
+		switch {
+		case len(matches) == 0:
+			return nil, fmt.Errorf("no token has ID or name %q", ref)
+		case len(matches) > 1:
+			return nil, fmt.Errorf("%d tokens are named %q; use the ID", len(matches), ref)
+		case matches[0].ID == selfID:
+			return nil, fmt.Errorf("%q is the management token this run authenticates with", ref)
+		}
+		if !slices.ContainsFunc(selected, func(t cloudflare.Token) bool { return t.ID == matches[0].ID }) {
+			selected = append(selected, matches[0])
+		}
+	}
+	return selected, nil
+}
+
+// describeRevokeSelection restates the criteria revoke selected tokens by,
+// so a saved dry run shows what it was run with.
+func describeRevokeSelection(refs []string, match, olderThan string, labelFilter varFlag) string {
+	if len(refs) > 0 {
+		return "ID or name " + strings.Join(refs, ", ")
+	}
+	parts := []string{fmt.Sprintf("name matches %q", match)}
+	if olderThan != "" {
+		parts = append(parts, "issued more than "+olderThan+" ago")
+	}
+	if len(labelFilter) > 0 {
+		parts = append(parts, "labels "+labelFilter.String())
+	}
+	return strings.Join(parts, ", ")
+}
+
+func printRevokeCandidates(w io.Writer, tokens []cloudflare.Token, reg labels.Registry) {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "NAME\tID\tSTATUS\tISSUED\tEXPIRES\tLAST USED\tLABELS")
+	for _, token := range tokens {
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\t%s\n", token.Name, token.ID, token.Status, formatDate(token.IssuedOn), formatDate(token.ExpiresOn), formatDate(token.LastUsedOn), describeLabels(reg, token.ID))
+	}
+	tw.Flush()
+}
+
+func formatDate(t time.Time) string {
+	if t.IsZero() {
+		return "-"
+	}
+	return t.UTC().Format(time.RFC3339)
+}
@@ -0,0 +1,110 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"cftoken/internal/cloudflare"
+)
+
+func TestSelectTokens(t *testing.T) {
+	t.Parallel()
+
+	now := time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)
+	tokens := []cloudflare.Token{
+		{ID: "1", Name: "ci-old", IssuedOn: now.AddDate(0, 0, -45)},
+		{ID: "2", Name: "ci-new", IssuedOn: now.AddDate(0, 0, -2)},
+		{ID: "3", Name: "prod-old", IssuedOn: now.AddDate(0, 0, -45)},
+		{ID: "4", Name: "ci-unknown"},
+		{ID: "self", Name: "ci-management", IssuedOn: now.AddDate(-1, 0, 0)},
+	}
+
+	tests := []struct {
+		name    string
+		pattern string
+		minAge  time.Duration
+		want    []string
+	}{
+		{"pattern only", "ci-*", 0, []string{"1", "2", "4"}},
+		{"pattern and age", "ci-*", 30 * 24 * time.Hour, []string{"1"}},
+		{"exact name", "prod-old", 0, []string{"3"}},
+		{"no match", "staging-*", 0, nil},
+	}
+
+	for _, tc := range tests {
+		got := selectTokens(tokens, tc.pattern, tc.minAge, "self", now)
+		var ids []string
+		for _, token := range got {
+			ids = append(ids, token.ID)
+		}
+		if strings.Join(ids, ",") != strings.Join(tc.want, ",") {
+			t.Fatalf("%s: selectTokens() = %v, want %v", tc.name, ids, tc.want)
+		}
+	}
+}
+
+func TestSelectTokenRefs(t *testing.T) {
+	t.Parallel()
+
+	tokens := []cloudflare.Token{
+		{ID: "1", Name: "ci-a"},
+		{ID: "2", Name: "dup"},
+		{ID: "3", Name: "dup"},
+		{ID: "self", Name: "management"},
+	}
+
+	tests := []struct {
+		refs    []string
+		want    []string
+		wantErr string
+	}{
+		{[]string{"ci-a"}, []string{"1"}, ""},
+		{[]string{"2", "ci-a", "1"}, []string{"2", "1"}, ""},
+		{[]string{"dup"}, nil, "2 tokens are named"},
+		{[]string{"missing"}, nil, "no token has"},
+		{[]string{"management"}, nil, "management token"},
+	}
+
+	for _, tc := range tests {
+		got, err := selectTokenRefs(tokens, tc.refs, "self")
+		if tc.wantErr != "" {
+			if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+				t.Errorf("selectTokenRefs(%v) error = %v, want %q", tc.refs, err, tc.wantErr)
+			}
+			continue
+		}
+		var ids []string
+		for _, token := range got {
+			ids = append(ids, token.ID)
+		}
+		if err != nil || strings.Join(ids, ",") != strings.Join(tc.want, ",") {
+			t.Errorf("selectTokenRefs(%v) = %v, %v, want %v", tc.refs, ids, err, tc.want)
+		}
+	}
+}
+
+func TestPrintRevokeCandidates(t *testing.T) {
+	issued := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	tokens := []cloudflare.Token{
+		{ID: "t1", Name: "ci-1", Status: "active", IssuedOn: issued, LastUsedOn: issued.Add(time.Hour)},
+		{ID: "t2", Name: "ci-2", Status: "active", IssuedOn: issued},
+	}
+	var buf strings.Builder
+	printRevokeCandidates(&buf, tokens, nil)
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 3 || !strings.Contains(lines[0], "LAST USED") {
+		t.Fatalf("printRevokeCandidates() =\n%s", buf.String())
+	}
+	if !strings.Contains(lines[1], "2024-01-02T04:04:05Z") {
+		t.Errorf("last used missing from %q", lines[1])
+	}
+	if fields := strings.Fields(lines[2]); fields[5] != "-" {
+		t.Errorf("never-used token shows last used %q, want -", fields[5])
+	}
+
+	got := describeRevokeSelection(nil, "ci-*", "30d", varFlag{"team": "edge"})
+	if want := `name matches "ci-*", issued more than 30d ago, labels team=edge`; got != want {
+		t.Errorf("describeRevokeSelection() = %q, want %q", got, want)
+	}
+}
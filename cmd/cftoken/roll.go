@@ -0,0 +1,57 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"cftoken/internal/cloudflare"
+)
+
+// runRoll gives an existing token a new secret and prints it. The ID,
+// name, and policies stay the same, so systems that refer to the token by
+// ID keep working; the old value stops working at once.
+func runRoll(ctx context.Context, client *cloudflare.Client, args []string) error {
+	if len(args) != 1 {
+		return withCode(codeInvalidArgument, errors.New("roll takes exactly one token ID or name"), nil)
+	}
+	// Print nothing new under print_token_values "never"; roll has no sink.
+	if err := requireValueSink(false); err != nil {
+		return err
+	}
+
+	// Rolling the management token would lock this run out mid-way.
+	self, err := client.VerifyToken(ctx)
+	if err != nil {
+		return err
+	}
+	tokens, err := client.ListTokens(ctx)
+	if err != nil {
+		return err
+	}
+	selected, err := selectTokenRefs(tokens, args, self.ID)
+	if err != nil {
+		return withCode(codeNotFound, err, map[string]any{"token": args[0]})
+	}
+	token := selected[0]
+
+	value, err := client.RollTokenValue(ctx, token.ID)
+	if err != nil {
+		return err
+	}
+	printRolled(os.Stdout, token, value)
+	return nil
+}
+
+// printRolled reports a rolled token and its new value.
+func printRolled(w io.Writer, token cloudflare.Token, value string) {
+	if outputFormat == outputValue {
+		value, w = writeValueOnly(w, value), os.Stderr
+	}
+	fmt.Fprintln(w, "Token value rolled; the previous value no longer works.")
+	fmt.Fprintf(w, "Name:   %s\n", token.Name)
+	fmt.Fprintf(w, "ID:     %s\n", token.ID)
+	fmt.Fprintf(w, "Value:  %s\n", displayedValue(value))
+}
@@ -0,0 +1,30 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"cftoken/internal/cloudflare"
+)
+
+func TestPrintRolled(t *testing.T) {
+	root := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", root)
+
+	token := cloudflare.Token{ID: "t1", Name: "ci-deploy"}
+	var buf bytes.Buffer
+	printRolled(&buf, token, "new-secret")
+	for _, want := range []string{"ID:     t1", "Value:  new-secret"} {
+		if !strings.Contains(buf.String(), want) {
+			t.Errorf("printRolled() =\n%s\nwant it to contain %q", buf.String(), want)
+		}
+	}
+
+	writeConfig(t, root, `{"print_token_values": "never"}`)
+	buf.Reset()
+	printRolled(&buf, token, "new-secret")
+	if strings.Contains(buf.String(), "new-secret") {
+		t.Errorf("printRolled() under print_token_values never =\n%s", buf.String())
+	}
+}
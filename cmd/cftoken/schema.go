@@ -0,0 +1,59 @@
+package main
+
+import (
+	"embed"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"sort"
+	"strings"
+)
+
+// schemaFiles holds the JSON Schemas of the documents cftoken reads and
+// writes. They are embedded so `cftoken schema` always describes the exact
+// binary in use.
+//
+//go:embed schemas/*.json
+var schemaFiles embed.FS
+
+func runSchema(args []string) error {
+	names, err := schemaNames()
+	if err != nil {
+		return err
+	}
+	if len(args) == 0 {
+		for _, name := range names {
+			fmt.Println(name)
+		}
+		return nil
+	}
+	if len(args) > 1 {
+		return withCode(codeInvalidArgument, errors.New("usage: schema [NAME]"), nil)
+	}
+	return writeSchema(os.Stdout, args[0], names)
+}
+
+// schemaNames lists the embedded schemas by name, without the .json suffix.
+func schemaNames() ([]string, error) {
+	entries, err := schemaFiles.ReadDir("schemas")
+	if err != nil {
+		return nil, err
+	}
+	names := make([]string, 0, len(entries))
+	for _, e := range entries {
+		names = append(names, strings.TrimSuffix(e.Name(), ".json"))
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+func writeSchema(w io.Writer, name string, names []string) error {
+	data, err := schemaFiles.ReadFile(path.Join("schemas", name+".json"))
+	if err != nil {
+		return withCode(codeInvalidArgument, fmt.Errorf("unknown schema %q; available: %s", name, strings.Join(names, ", ")), nil)
+	}
+	_, err = w.Write(data)
+	return err
+}
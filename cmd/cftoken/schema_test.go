@@ -0,0 +1,73 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+
+	"cftoken/internal/template"
+)
+
+// TestSchemasMatchTypes keeps the embedded schemas in step with the Go types
+// that read or write the documents they describe.
+func TestSchemasMatchTypes(t *testing.T) {
+	tests := []struct {
+		name string
+		// pointer walks from the schema root to the object to compare.
+		pointer []string
+		typ     reflect.Type
+	}{
+		{"error", nil, reflect.TypeOf(errorOutput{})},
+		{"progress-event", nil, reflect.TypeOf(progressEvent{})},
+		{"permissions-export", []string{"items"}, reflect.TypeOf(exportedGroup{})},
+		{"token-set", []string{"properties", "tokens", "items"}, reflect.TypeOf(template.TokenSpec{})},
+		{"token-set", []string{"properties", "tokens", "items", "properties", "policies", "items"}, reflect.TypeOf(template.Policy{})},
+		{"policies", []string{"$defs", "policy"}, reflect.TypeOf(template.Policy{})},
+	}
+
+	names, err := schemaNames()
+	if err != nil {
+		t.Fatalf("schemaNames() error = %v", err)
+	}
+	for _, tc := range tests {
+		var buf bytes.Buffer
+		if err := writeSchema(&buf, tc.name, names); err != nil {
+			t.Fatalf("writeSchema(%q) error = %v", tc.name, err)
+		}
+		var node map[string]any
+		if err := json.Unmarshal(buf.Bytes(), &node); err != nil {
+			t.Fatalf("schema %q is not valid JSON: %v", tc.name, err)
+		}
+		for _, key := range tc.pointer {
+			node, _ = node[key].(map[string]any)
+		}
+		props, _ := node["properties"].(map[string]any)
+		var got []string
+		for k := range props {
+			got = append(got, k)
+		}
+		sort.Strings(got)
+		if want := jsonFields(tc.typ); !reflect.DeepEqual(got, want) {
+			t.Errorf("schema %q %s properties = %v, want %v", tc.name, strings.Join(tc.pointer, "/"), got, want)
+		}
+	}
+
+	if err := writeSchema(&bytes.Buffer{}, "nope", names); err == nil || !strings.Contains(err.Error(), "available: error,") {
+		t.Errorf("writeSchema(nope) error = %v", err)
+	}
+}
+
+func jsonFields(typ reflect.Type) []string {
+	var out []string
+	for i := 0; i < typ.NumField(); i++ {
+		name, _, _ := strings.Cut(typ.Field(i).Tag.Get("json"), ",")
+		if name != "" && name != "-" {
+			out = append(out, name)
+		}
+	}
+	sort.Strings(out)
+	return out
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+)
+
+const (
+	ansiCursorUp      = "\x1b[%dA"
+	ansiClearDown     = "\x1b[J"
+	ansiClearScrollbk = "\x1b[3J"
+)
+
+// printAndScrub writes the output produced by print, waits for the user to
+// press Enter, and then erases those lines and the terminal scrollback so the
+// secret does not linger on screen. When out is not a terminal the output is
+// written unchanged and nothing is erased.
+func printAndScrub(in io.Reader, out *os.File, print func(io.Writer)) error {
+	if !isTerminal(out) {
+		print(out)
+		fmt.Fprintln(os.Stderr, "warning: -scrub ignored because stdout is not a terminal")
+		return nil
+	}
+	if err := enableVirtualTerminal(out); err != nil {
+		print(out)
+		fmt.Fprintf(os.Stderr, "warning: -scrub ignored because the console cannot clear the screen: %v\n", err)
+		return nil
+	}
+
+	var buf bytes.Buffer
+	print(&buf)
+	buf.WriteString("\nCopy the token now. Press Enter to clear it from the screen...")
+	if _, err := out.Write(buf.Bytes()); err != nil {
+		return err
+	}
+
+	if _, err := bufio.NewReader(in).ReadString('\n'); err != nil && err != io.EOF {
+		return fmt.Errorf("wait for acknowledgement: %w", err)
+	}
+
+	_, err := io.WriteString(out, scrubSequence(bytes.Count(buf.Bytes(), []byte("\n"))+1))
+	return err
+}
+
+// scrubSequence moves the cursor up over the given number of lines, clears
+// everything below it, and asks the terminal to drop its scrollback buffer.
+func scrubSequence(lines int) string {
+	return fmt.Sprintf("\r"+ansiCursorUp, lines) + ansiClearDown + ansiClearScrollbk
+}
+
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	if err != nil {
+		return false
+	}
+	return info.Mode()&os.ModeCharDevice != 0
+}
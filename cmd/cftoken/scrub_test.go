@@ -0,0 +1,43 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestScrubSequence(t *testing.T) {
+	t.Parallel()
+
+	if got, want := scrubSequence(9), "\r\x1b[9A\x1b[J\x1b[3J"; got != want {
+		t.Fatalf("scrubSequence(9) = %q, want %q", got, want)
+	}
+}
+
+func TestPrintAndScrubNonTerminal(t *testing.T) {
+	t.Parallel()
+
+	out, err := os.Create(filepath.Join(t.TempDir(), "out"))
+	if err != nil {
+		t.Fatalf("create output file: %v", err)
+	}
+	defer out.Close()
+
+	err = printAndScrub(strings.NewReader(""), out, func(w io.Writer) {
+		fmt.Fprintln(w, "Value:  secret")
+	})
+	if err != nil {
+		t.Fatalf("printAndScrub() error = %v", err)
+	}
+
+	data, err := os.ReadFile(out.Name())
+	if err != nil {
+		t.Fatalf("read output: %v", err)
+	}
+	if got := string(data); got != "Value:  secret\n" {
+		t.Fatalf("printAndScrub() wrote %q, want plain output without escape codes", got)
+	}
+}
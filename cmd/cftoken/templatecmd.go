@@ -0,0 +1,109 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"cftoken/internal/config"
+	"cftoken/internal/template"
+)
+
+func runTemplate(args []string) error {
+	if len(args) == 0 {
+		return errors.New("template requires a subcommand: describe")
+	}
+	switch sub := args[0]; sub {
+	case "describe":
+		return runTemplateDescribe(args[1:])
+	default:
+		return fmt.Errorf("unknown template subcommand %q; available: describe", sub)
+	}
+}
+
+func runTemplateDescribe(args []string) error {
+	fset := flag.NewFlagSet("template describe", flag.ContinueOnError)
+	zoneName := fset.String("zone", "", "Describe the template configured for this zone")
+	if err := fset.Parse(args); err != nil {
+		return err
+	}
+
+	var templatePath string
+	switch {
+	case *zoneName != "" && fset.NArg() > 0:
+		return errors.New("template describe takes either -zone or a template path, not both")
+	case *zoneName != "":
+		_, zoneConfig, err := config.LoadZoneConfig(*zoneName)
+		if err != nil {
+			return fmt.Errorf("resolve zone %q: %w", *zoneName, err)
+		}
+		if zoneConfig == nil || zoneConfig.TemplateFile == "" {
+			return fmt.Errorf("zone %q has no template_file", *zoneName)
+		}
+		templatePath = zoneConfig.TemplateFile
+	case fset.NArg() == 1:
+		templatePath = fset.Arg(0)
+	default:
+		return errors.New("template describe requires -zone or a template path")
+	}
+
+	spec, err := template.LoadSpec(templatePath)
+	if errors.Is(err, fs.ErrNotExist) {
+		spec = &template.Spec{}
+	} else if err != nil {
+		return err
+	}
+	refs, err := template.ReferencedVariables(templatePath, "")
+	if err != nil {
+		return err
+	}
+	specPath, err := template.SpecPath(templatePath)
+	if err != nil {
+		return err
+	}
+	return describeTemplate(os.Stdout, templatePath, specPath, spec, refs)
+}
+
+func describeTemplate(w io.Writer, templatePath, specPath string, spec *template.Spec, refs []string) error {
+	fmt.Fprintf(w, "Template: %s\n", templatePath)
+	if len(spec.Variables) == 0 {
+		fmt.Fprintf(w, "Spec:     none (create %s to declare variables)\n", specPath)
+	} else {
+		fmt.Fprintf(w, "Spec:     %s\n", specPath)
+		fmt.Fprintln(w)
+		tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(tw, "VARIABLE\tREQUIRED\tDEFAULT\tDESCRIPTION")
+		for _, name := range spec.Names() {
+			v := spec.Variables[name]
+			def := "-"
+			if v.Default != nil {
+				def = fmt.Sprint(v.Default)
+			}
+			required := "no"
+			if v.Required {
+				required = "yes"
+			}
+			fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", name, required, def, stringOrDefault(v.Description, "-"))
+		}
+		tw.Flush()
+	}
+
+	var undeclared []string
+	for _, ref := range refs {
+		if _, ok := spec.Variables[ref]; !ok && !config.ImplicitVariable(ref) {
+			undeclared = append(undeclared, ref)
+		}
+	}
+	if len(undeclared) > 0 {
+		fmt.Fprintln(w)
+		fmt.Fprintf(w, "Undeclared variables read by the template: %s\n", strings.Join(undeclared, ", "))
+	}
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "ZoneID and AccountID are injected automatically from the zone's configuration.")
+	return nil
+}
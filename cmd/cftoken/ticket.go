@@ -0,0 +1,41 @@
+package main
+
+import (
+	"strings"
+
+	"cftoken/internal/guardrail"
+)
+
+// changeTicket is set by -ticket (or its alias -reason): the change ticket or
+// reason behind the tokens this run creates. Guardrails can require it; it is
+// recorded in the issuance ledger, policy revisions, and as the ticket label.
+var changeTicket string
+
+// ticketedName embeds the change ticket in a generated token name when the
+// guardrails ask for it. Generated names end in -TIMESTAMP, and the ticket
+// goes right before it: prod-CHG-42-20240102T030405Z.
+func ticketedName(name string, rules guardrail.Rules) string {
+	ticket := nameSafeTicket(changeTicket)
+	if !rules.TicketInName || ticket == "" {
+		return name
+	}
+	i := strings.LastIndex(name, "-")
+	if i < 0 {
+		return name + "-" + ticket
+	}
+	return name[:i] + "-" + ticket + name[i:]
+}
+
+// nameSafeTicket replaces everything but letters, digits, dots, and dashes so
+// free-form reasons still yield readable names.
+func nameSafeTicket(ticket string) string {
+	ticket = strings.TrimSpace(ticket)
+	return strings.Trim(strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '.', r == '-':
+			return r
+		default:
+			return '-'
+		}
+	}, ticket), "-")
+}
@@ -0,0 +1,31 @@
+package main
+
+import (
+	"testing"
+
+	"cftoken/internal/guardrail"
+)
+
+func TestTicketedName(t *testing.T) {
+	embed := guardrail.Rules{TicketInName: true}
+	tests := []struct {
+		name   string
+		ticket string
+		rules  guardrail.Rules
+		want   string
+	}{
+		{"embedded", "CHG-42", embed, "prod-CHG-42-20240102T030405Z"},
+		{"free-form reason", " rotate edge/keys ", embed, "prod-rotate-edge-keys-20240102T030405Z"},
+		{"not requested", "CHG-42", guardrail.Rules{}, "prod-20240102T030405Z"},
+		{"no ticket", "", embed, "prod-20240102T030405Z"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			changeTicket = tc.ticket
+			t.Cleanup(func() { changeTicket = "" })
+			if got := ticketedName("prod-20240102T030405Z", tc.rules); got != tc.want {
+				t.Fatalf("ticketedName() = %q, want %q", got, tc.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"errors"
+	"io/fs"
+	"strings"
+
+	"cftoken/internal/config"
+)
+
+// Values of print_token_values.
+const (
+	printValuesNever  = "never"
+	printValuesOnce   = "once"
+	printValuesAlways = "always"
+)
+
+// tokenValuePolicy returns the print_token_values setting, defaulting to
+// "once": a value is printed unless it was delivered to a sink.
+func tokenValuePolicy() (string, error) {
+	policy, err := config.LoadPrintTokenValues()
+	if errors.Is(err, fs.ErrNotExist) {
+		return printValuesOnce, nil
+	}
+	return policy, err
+}
+
+// requireValueSink refuses issuance under print_token_values "never" when
+// the new token's value has nowhere to go but the terminal. It runs before
+// anything is created, so a token is never minted only to be withheld.
+func requireValueSink(hasSink bool) error {
+	policy, err := tokenValuePolicy()
+	if err != nil {
+		return err
+	}
+	if policy == printValuesNever && !hasSink {
+		err := errors.New("print_token_values is never, so token values are only delivered to a sink; configure one for the zone")
+		return withCode(codeGuardrail, err, map[string]any{"print_token_values": policy})
+	}
+	return nil
+}
+
+// displayedValue is the Value line printTokenResult shows for value. It is
+// the one place token values reach the terminal, so print_token_values is
+// enforced here; an unreadable setting withholds the value. Placeholders
+// such as "<delivered to ...>" are shown as they are.
+func displayedValue(value string) string {
+	if value == "" {
+		return "<redacted by API>"
+	}
+	if strings.HasPrefix(value, "<") {
+		return value
+	}
+	if policy, err := tokenValuePolicy(); err != nil || policy == printValuesNever {
+		return "<withheld by print_token_values>"
+	}
+	return value
+}
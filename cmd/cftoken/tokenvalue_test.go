@@ -0,0 +1,44 @@
+package main
+
+import (
+	"testing"
+)
+
+func TestTokenValuePolicy(t *testing.T) {
+	tests := []struct {
+		name         string
+		config       string
+		wantValue    string
+		wantNoSink   bool // requireValueSink(false) fails
+		wantWithSink bool // requireValueSink(true) fails
+	}{
+		{"unset", `{}`, "secret", false, false},
+		{"once", `{"print_token_values": "once"}`, "secret", false, false},
+		{"always", `{"print_token_values": "always"}`, "secret", false, false},
+		{"never", `{"print_token_values": "Never"}`, "<withheld by print_token_values>", true, false},
+		{"unknown value", `{"print_token_values": "sometimes"}`, "<withheld by print_token_values>", true, true},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			root := t.TempDir()
+			t.Setenv("XDG_CONFIG_HOME", root)
+			writeConfig(t, root, tc.config)
+
+			if got := displayedValue("secret"); got != tc.wantValue {
+				t.Errorf("displayedValue() = %q, want %q", got, tc.wantValue)
+			}
+			if got := displayedValue("<delivered to vault secret/x>"); got != "<delivered to vault secret/x>" {
+				t.Errorf("displayedValue() changed placeholder to %q", got)
+			}
+			if got := displayedValue(""); got != "<redacted by API>" {
+				t.Errorf("displayedValue(\"\") = %q", got)
+			}
+			if err := requireValueSink(false); (err != nil) != tc.wantNoSink {
+				t.Errorf("requireValueSink(false) error = %v, want error %v", err, tc.wantNoSink)
+			}
+			if err := requireValueSink(true); (err != nil) != tc.wantWithSink {
+				t.Errorf("requireValueSink(true) error = %v, want error %v", err, tc.wantWithSink)
+			}
+		})
+	}
+}
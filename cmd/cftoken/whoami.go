@@ -0,0 +1,107 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"cftoken/internal/clock"
+	"cftoken/internal/cloudflare"
+)
+
+// runWhoami verifies the management token and prints what it is: its ID,
+// status, and expiry, the user it acts for, if any, the accounts it can
+// reach, which token store cftoken will use, and what it may do.
+func runWhoami(ctx context.Context, client *cloudflare.Client, args []string) error {
+	if len(args) > 0 {
+		return withCode(codeInvalidArgument, fmt.Errorf("whoami takes no arguments, got %q", args[0]), nil)
+	}
+	verification, err := client.VerifyToken(ctx)
+	if err != nil {
+		return err
+	}
+	printVerification(os.Stdout, verification, clock.Now())
+
+	// Tokens owned by an account act for no user; that is not a failure.
+	user, _ := client.CurrentUser(ctx)
+	accounts, err := client.Accounts(ctx)
+	if err != nil {
+		return err
+	}
+	fmt.Println()
+	printIdentity(os.Stdout, user, accounts, client.TokenAccount())
+
+	desc, err := client.DescribeToken(ctx, verification.ID)
+	fmt.Println()
+	switch {
+	case deniedSelfRead(err):
+		fmt.Println("Permissions: not shown; the token may not read its own configuration (that needs API Tokens Read).")
+		return nil
+	case err != nil:
+		return fmt.Errorf("describe token: %w", err)
+	}
+	printPermissionSummary(os.Stdout, desc)
+	return nil
+}
+
+// printIdentity writes the identity report of runWhoami. The account whose
+// tokens are managed, if any, is marked with an asterisk.
+func printIdentity(w io.Writer, user *cloudflare.User, accounts []cloudflare.Account, tokenAccount string) {
+	if user != nil {
+		fmt.Fprintf(w, "User: %s (%s)\n", stringOrDefault(user.Email, "<no email>"), user.ID)
+	} else {
+		fmt.Fprintln(w, "User: none; the token is owned by an account")
+	}
+
+	fmt.Fprintln(w, "Accounts:")
+	if len(accounts) == 0 {
+		fmt.Fprintln(w, "  none")
+	}
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	for _, account := range accounts {
+		mark := " "
+		if account.ID == tokenAccount {
+			mark = "*"
+		}
+		fmt.Fprintf(tw, "  %s %s\t%s\t%s\n", mark, account.ID, account.Name, account.Type)
+	}
+	tw.Flush()
+
+	switch {
+	case tokenAccount != "":
+		fmt.Fprintf(w, "Token store: account %s (accounts/%s/tokens)\n", tokenAccount, tokenAccount)
+	case user != nil:
+		fmt.Fprintln(w, "Token store: user (user/tokens)")
+		if len(accounts) > 1 {
+			fmt.Fprintln(w, "Pass -token-account ID to create and manage an account's tokens instead.")
+		}
+	default:
+		fmt.Fprintln(w, "Token store: none; pass -token-account ID to pick the account whose tokens to manage")
+	}
+}
+
+// printPermissionSummary writes one line per policy of desc, naming its
+// effect, permission groups, and resources, and the token's IP
+// restrictions.
+func printPermissionSummary(w io.Writer, desc *cloudflare.TokenInspection) {
+	fmt.Fprintln(w, "Permissions:")
+	if len(desc.Policies) == 0 {
+		fmt.Fprintln(w, "  none")
+	}
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	for _, policy := range desc.Policies {
+		names := make([]string, 0, len(policy.PermissionGroups))
+		for _, group := range policy.PermissionGroups {
+			names = append(names, stringOrDefault(group.Name, group.ID))
+		}
+		fmt.Fprintf(tw, "  %s\t%s\ton %s\n", policy.Effect, strings.Join(names, ", "), joinOrDefault(policy.Resources, "no resources"))
+	}
+	tw.Flush()
+	fmt.Fprintf(w, "Allowed CIDRs: %s\n", joinOrDefault(desc.AllowedCIDRs, "any"))
+	if len(desc.DeniedCIDRs) > 0 {
+		fmt.Fprintf(w, "Denied CIDRs: %s\n", strings.Join(desc.DeniedCIDRs, ", "))
+	}
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"cftoken/internal/cloudflare"
+)
+
+func TestPrintIdentity(t *testing.T) {
+	t.Parallel()
+
+	accounts := []cloudflare.Account{{ID: "a1", Name: "Acme", Type: "standard"}, {ID: "a2", Name: "Acme Staging", Type: "standard"}}
+	user := &cloudflare.User{ID: "u1", Email: "svc@example.com"}
+	tests := []struct {
+		name         string
+		user         *cloudflare.User
+		tokenAccount string
+		want         []string
+	}{
+		{"user store", user, "", []string{"User: svc@example.com (u1)", "    a2  Acme Staging", "Token store: user (user/tokens)", "Pass -token-account ID"}},
+		{"account store", user, "a2", []string{"  * a2  Acme Staging", "Token store: account a2 (accounts/a2/tokens)"}},
+		{"account-owned", nil, "", []string{"User: none", "Token store: none"}},
+	}
+	for _, tc := range tests {
+		var buf bytes.Buffer
+		printIdentity(&buf, tc.user, accounts, tc.tokenAccount)
+		for _, want := range tc.want {
+			if !strings.Contains(buf.String(), want) {
+				t.Errorf("%s: output\n%s\nwant it to contain %q", tc.name, buf.String(), want)
+			}
+		}
+	}
+}
+
+func TestPrintPermissionSummary(t *testing.T) {
+	t.Parallel()
+
+	desc := &cloudflare.TokenInspection{
+		AllowedCIDRs: []string{"10.0.0.0/8"},
+		Policies: []cloudflare.TokenPolicyInspection{
+			{Effect: "allow", Resources: []string{"zone.z1=*"}, PermissionGroups: []cloudflare.PermissionGroupSummary{{ID: "g1", Name: "DNS Write"}, {ID: "g2"}}},
+			{Effect: "deny", Resources: []string{"zone.z2=*"}, PermissionGroups: []cloudflare.PermissionGroupSummary{{ID: "g1", Name: "DNS Write"}}},
+		},
+	}
+	var buf bytes.Buffer
+	printPermissionSummary(&buf, desc)
+	want := `Permissions:
+  allow  DNS Write, g2  on zone.z1=*
+  deny   DNS Write      on zone.z2=*
+Allowed CIDRs: 10.0.0.0/8
+`
+	if buf.String() != want {
+		t.Errorf("printPermissionSummary() =\n%s\nwant\n%s", buf.String(), want)
+	}
+}
@@ -0,0 +1,187 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"slices"
+	"strconv"
+	"strings"
+
+	"cftoken/internal/cloudflare"
+	"cftoken/internal/config"
+	"cftoken/internal/duration"
+)
+
+// createWizard asks for the settings of a new token that the flags leave
+// open, for `cftoken create -interactive`.
+type createWizard struct {
+	ask     func(question, def string) (string, error)
+	out     io.Writer
+	catalog func() ([]cloudflare.PermissionGroup, error)
+	// discover lists the zones visible to the token for the zone question;
+	// without it only configured zones are offered.
+	discover func() ([]*cloudflare.ZoneDetails, error)
+}
+
+// zonePickerLimit caps how many zones visible to the token are offered.
+const zonePickerLimit = 50
+
+// run asks for the zone, permission groups, TTL, and allowed CIDRs, in
+// that order, skipping those given as flags (recorded in set) or fixed by
+// the zone's configuration. Answers are stored in f and marked in set as
+// if they had been given as flags.
+func (w createWizard) run(f *runFlags, set map[string]bool) error {
+	if f.zoneName == "" && f.zoneID == "" {
+		if err := w.askZone(f); err != nil {
+			return err
+		}
+	}
+	zoneName := stringOrDefault(f.zoneName, f.zoneID)
+	var zc *config.ZoneConfig
+	if f.zoneName != "" {
+		// A zone that is not configured is resolved later, or reported.
+		_, zc, _ = config.LoadZoneConfig(f.zoneName)
+	}
+
+	if !set["permissions"] {
+		if zc != nil && (zc.TemplateFile != "" || zc.TemplateInline != "") {
+			fmt.Fprintf(w.out, "Zone %s renders its permissions from its template.\n", zoneName)
+		} else {
+			picked, err := w.askPermissions()
+			if err != nil {
+				return err
+			}
+			if len(picked) > 0 {
+				f.permissions = strings.Join(picked, ",")
+				set["permissions"] = true
+			}
+		}
+	}
+
+	if !set["ttl"] {
+		if zc != nil && zc.TTL != "" {
+			fmt.Fprintf(w.out, "Zone %s sets the TTL to %s.\n", zoneName, zc.TTL)
+		} else {
+			for {
+				answer, err := w.ask("Token TTL (0 for no expiry)", duration.Format(f.ttl))
+				if err != nil {
+					return err
+				}
+				ttl, err := duration.Parse(answer)
+				if err == nil && ttl >= 0 {
+					f.ttl = ttl
+					set["ttl"] = true
+					break
+				}
+				fmt.Fprintf(w.out, "Invalid TTL %q; use e.g. 90m, 8h, or 2d.\n", answer)
+			}
+		}
+	}
+
+	if !set["allow-cidrs"] {
+		def, err := config.LoadDefaultAllowedCIDRs()
+		if err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return err
+		}
+		if zc != nil && len(zc.AllowedCIDRs) > 0 {
+			def = zc.AllowedCIDRs
+		}
+		for {
+			answer, err := w.ask("Allowed CIDRs, comma-separated (0.0.0.0/32 for any address)", strings.Join(def, ","))
+			if err != nil {
+				return err
+			}
+			if strings.TrimSpace(answer) == "" {
+				return withCode(codeInvalidArgument, errors.New("no allowed CIDRs chosen"), nil)
+			}
+			if _, _, err := parseAllowedCIDRs(answer); err != nil {
+				fmt.Fprintf(w.out, "Invalid CIDRs %q; use e.g. 192.0.2.0/24,2001:db8::/32.\n", answer)
+				continue
+			}
+			f.allowCIDRs = answer
+			set["allow-cidrs"] = true
+			break
+		}
+	}
+	return nil
+}
+
+// askZone lists the configured zones, then the zones visible to the token
+// that are not configured, and stores the one picked by number in f, or
+// the name or zone ID typed instead. A picked zone that is not configured
+// is used by ID and named after its domain.
+func (w createWizard) askZone(f *runFlags) error {
+	names, err := config.ZoneNames()
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	if len(names) > 0 {
+		fmt.Fprintln(w.out, "Configured zones:")
+		for i, name := range names {
+			fmt.Fprintf(w.out, "  %d) %s\n", i+1, name)
+		}
+	}
+
+	var discovered []*cloudflare.ZoneDetails
+	if w.discover != nil {
+		visible, err := w.discover()
+		if err != nil {
+			fmt.Fprintf(w.out, "Could not list the zones visible to the token: %v\n", err)
+		}
+		// Zones configured under another name are recognized by their ID.
+		configuredIDs, _ := config.ZoneMap()
+		for _, z := range visible {
+			configured := slices.ContainsFunc(names, func(name string) bool {
+				return strings.EqualFold(strings.TrimSuffix(strings.TrimSpace(name), "."), z.Name)
+			})
+			for _, id := range configuredIDs {
+				configured = configured || id == z.ID
+			}
+			if !configured {
+				discovered = append(discovered, z)
+			}
+		}
+		if len(discovered) > 0 {
+			fmt.Fprintln(w.out, "Other zones visible to the token:")
+			for i, z := range discovered {
+				fmt.Fprintf(w.out, "  %d) %s (%s)\n", len(names)+i+1, z.Name, z.ID)
+			}
+		}
+		if len(visible) == zonePickerLimit {
+			fmt.Fprintf(w.out, "Only the first %d zones visible to the token are listed; type any other by name or zone ID.\n", zonePickerLimit)
+		}
+	}
+
+	answer, err := w.ask("Zone (number, name, or zone ID)", "")
+	if err != nil {
+		return err
+	}
+	if answer == "" {
+		return withCode(codeInvalidArgument, errors.New("no zone chosen"), nil)
+	}
+	var picked *cloudflare.ZoneDetails
+	if n, err := strconv.Atoi(answer); err == nil && n >= 1 && n <= len(names) {
+		f.zoneName = names[n-1]
+		return nil
+	} else if err == nil && n > len(names) && n <= len(names)+len(discovered) {
+		picked = discovered[n-len(names)-1]
+	}
+	for _, z := range discovered {
+		if strings.EqualFold(strings.TrimSuffix(answer, "."), z.Name) {
+			picked = z
+		}
+	}
+	if picked == nil {
+		f.zoneName = answer
+		return nil
+	}
+	f.zoneID = picked.ID
+	if f.tokenPrefix == "" {
+		if f.tokenPrefix, err = defaultTokenPrefix(picked.Name, picked.ID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
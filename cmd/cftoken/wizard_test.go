@@ -0,0 +1,141 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"cftoken/internal/cloudflare"
+)
+
+func TestCreateWizard(t *testing.T) {
+	root := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", root)
+	writeConfig(t, root, `{
+		"default_allowed_cidrs": ["10.0.0.0/8"],
+		"zones": {
+			"prod": {"zone_id": "0123456789abcdef0123456789abcdef", "template_inline": "[]", "ttl": "1h"},
+			"staging": "fedcba9876543210fedcba9876543210"
+		}
+	}`)
+	catalog := func() ([]cloudflare.PermissionGroup, error) {
+		return []cloudflare.PermissionGroup{{ID: "g1", Name: "DNS Write"}, {ID: "g2", Name: "DNS Read"}, {ID: "g3", Name: "Zone Read"}, {ID: "g4", Name: "DNS Write"}}, nil
+	}
+
+	tests := []struct {
+		name    string
+		flags   runFlags
+		set     []string
+		input   string
+		want    runFlags
+		wantSet []string
+		wantOut []string
+		wantErr string
+	}{
+		{
+			name:    "every question",
+			flags:   runFlags{ttl: 8 * time.Hour},
+			input:   "2\ndns\n2, 1, 9\nnothing\nzone\n1\n\n90x\n2h\n10.0.0.0/33\n192.0.2.0/24\n",
+			want:    runFlags{zoneName: "staging", permissions: "DNS Write,DNS Read,Zone Read", ttl: 2 * time.Hour, allowCIDRs: "192.0.2.0/24"},
+			wantSet: []string{"permissions", "ttl", "allow-cidrs"},
+			wantOut: []string{"  1) prod\n  2) staging\n", "  1) DNS Read\n  2) DNS Write\n", `No permission group matches "nothing".`, `Invalid TTL "90x"`, `Invalid CIDRs "10.0.0.0/33"`},
+		},
+		{
+			name:    "zone template and ttl",
+			flags:   runFlags{zoneName: "prod", ttl: 8 * time.Hour},
+			input:   "\n",
+			want:    runFlags{zoneName: "prod", ttl: 8 * time.Hour, allowCIDRs: "10.0.0.0/8"},
+			wantSet: []string{"allow-cidrs"},
+			wantOut: []string{"Zone prod renders its permissions from its template.", "Zone prod sets the TTL to 1h."},
+		},
+		{
+			name:  "flags are not asked again",
+			flags: runFlags{zoneID: "fedcba9876543210fedcba9876543210", permissions: "Zone Read", ttl: time.Hour, allowCIDRs: "10.0.0.0/8"},
+			set:   []string{"permissions", "ttl", "allow-cidrs"},
+			want:  runFlags{zoneID: "fedcba9876543210fedcba9876543210", permissions: "Zone Read", ttl: time.Hour, allowCIDRs: "10.0.0.0/8"},
+		},
+		{
+			name:    "no zone",
+			flags:   runFlags{ttl: 8 * time.Hour},
+			input:   "\n",
+			wantErr: "no zone chosen",
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			set := make(map[string]bool)
+			for _, name := range tc.set {
+				set[name] = true
+			}
+			var out bytes.Buffer
+			w := createWizard{ask: newPrompter(strings.NewReader(tc.input), &out), out: &out, catalog: catalog}
+			f := tc.flags
+			err := w.run(&f, set)
+			if tc.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+					t.Fatalf("run() error = %v, want %q", err, tc.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("run() error = %v\n%s", err, out.String())
+			}
+			if f.zoneName != tc.want.zoneName || f.zoneID != tc.want.zoneID || f.permissions != tc.want.permissions || f.ttl != tc.want.ttl || f.allowCIDRs != tc.want.allowCIDRs {
+				t.Errorf("flags = %+v, want %+v", f, tc.want)
+			}
+			for _, name := range tc.wantSet {
+				if !set[name] {
+					t.Errorf("%s not marked as set", name)
+				}
+			}
+			for _, want := range tc.wantOut {
+				if !strings.Contains(out.String(), want) {
+					t.Errorf("output missing %q:\n%s", want, out.String())
+				}
+			}
+		})
+	}
+}
+
+func TestAskZoneDiscovered(t *testing.T) {
+	root := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", root)
+	writeConfig(t, root, `{"zones": {"prod": "0123456789abcdef0123456789abcdef", "Example.com.": "fedcba9876543210fedcba9876543210"}}`)
+	discover := func() ([]*cloudflare.ZoneDetails, error) {
+		return []*cloudflare.ZoneDetails{
+			{ID: "0123456789abcdef0123456789abcdef", Name: "prod.example"},
+			{ID: "fedcba9876543210fedcba9876543210", Name: "example.com"},
+			{ID: "11111111111111111111111111111111", Name: "new.example"},
+			{ID: "22222222222222222222222222222222", Name: "other.example"},
+		}, nil
+	}
+
+	tests := []struct {
+		name  string
+		input string
+		want  runFlags
+	}{
+		{"configured by number", "2\n", runFlags{zoneName: "prod"}},
+		{"discovered by number", "4\n", runFlags{zoneID: "22222222222222222222222222222222", tokenPrefix: "other.example"}},
+		{"discovered by name", "New.example.\n", runFlags{zoneID: "11111111111111111111111111111111", tokenPrefix: "new.example"}},
+		{"typed", "staging\n", runFlags{zoneName: "staging"}},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			var out bytes.Buffer
+			w := createWizard{ask: newPrompter(strings.NewReader(tc.input), &out), out: &out, discover: discover}
+			var f runFlags
+			if err := w.askZone(&f); err != nil {
+				t.Fatalf("askZone() error = %v", err)
+			}
+			if f.zoneName != tc.want.zoneName || f.zoneID != tc.want.zoneID || f.tokenPrefix != tc.want.tokenPrefix {
+				t.Errorf("flags = %+v, want %+v", f, tc.want)
+			}
+			// Zones configured by name or under another name are not repeated.
+			if want := "Other zones visible to the token:\n  3) new.example (11111111111111111111111111111111)\n  4) other.example (22222222222222222222222222222222)\n"; !strings.Contains(out.String(), want) {
+				t.Errorf("output missing %q:\n%s", want, out.String())
+			}
+		})
+	}
+}
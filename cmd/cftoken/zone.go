@@ -0,0 +1,212 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"cftoken/internal/clock"
+	"cftoken/internal/cloudflare"
+	"cftoken/internal/config"
+	"cftoken/internal/freeze"
+)
+
+// zoneDescriber is the part of the Cloudflare client zone describe needs.
+type zoneDescriber interface {
+	DescribeZone(ctx context.Context, zoneID string) (*cloudflare.ZoneDetails, error)
+}
+
+func runZone(ctx context.Context, token func() string, verbose bool, args []string) error {
+	if len(args) == 0 {
+		return listZones()
+	}
+	switch sub := args[0]; sub {
+	case "describe":
+		fset := flag.NewFlagSet("zone describe", flag.ContinueOnError)
+		if err := fset.Parse(args[1:]); err != nil {
+			return err
+		}
+		if token() == "" {
+			return errMissingToken
+		}
+		names := fset.Args()
+		if len(names) == 0 {
+			var err error
+			if names, err = config.ZoneNames(); err != nil {
+				return fmt.Errorf("load configured zones: %w", err)
+			}
+		}
+		return describeZones(ctx, os.Stdout, newClient(token(), verbose), names)
+	case "freeze":
+		fset := flag.NewFlagSet("zone freeze", flag.ContinueOnError)
+		note := fset.String("note", "", "Why the zone is frozen, shown when issuance is refused")
+		if err := fset.Parse(args[1:]); err != nil {
+			return err
+		}
+		if fset.NArg() != 1 {
+			return withCode(codeInvalidArgument, errors.New("usage: zone freeze [-note TEXT] NAME"), nil)
+		}
+		name := fset.Arg(0)
+		zoneID, _, err := config.LoadZoneConfig(name)
+		if err != nil {
+			return fmt.Errorf("resolve zone %q: %w", name, err)
+		}
+		entry := freeze.Entry{ZoneID: zoneID, At: clock.Now(), Note: *note, Ticket: changeTicket}
+		if err := freeze.Freeze(name, entry); err != nil {
+			return err
+		}
+		fmt.Printf("Zone %s is frozen; no tokens will be issued for it until `cftoken zone unfreeze %s`\n", name, name)
+		return nil
+	case "unfreeze":
+		if len(args) != 2 {
+			return withCode(codeInvalidArgument, errors.New("usage: zone unfreeze NAME"), nil)
+		}
+		name := args[1]
+		was, err := freeze.Unfreeze(name)
+		if err != nil {
+			return err
+		}
+		if _, zc, err := config.LoadZoneConfig(name); err == nil && zc != nil && zc.Frozen {
+			return fmt.Errorf("zone %s is frozen in config.json; remove \"frozen\" from it to unfreeze", name)
+		}
+		if !was {
+			fmt.Printf("Zone %s was not frozen\n", name)
+			return nil
+		}
+		fmt.Printf("Zone %s is unfrozen\n", name)
+		return nil
+	case "frozen":
+		store, err := freeze.Load()
+		if err != nil {
+			return err
+		}
+		return printFrozen(os.Stdout, store)
+	default:
+		return fmt.Errorf("unknown zone subcommand %q; available: describe, onboard, freeze, unfreeze, frozen", sub)
+	}
+}
+
+// printFrozen lists the zones frozen from the command line and those
+// frozen in config.json.
+func printFrozen(w io.Writer, store freeze.Store) error {
+	names, err := config.ZoneNames()
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	var inConfig []string
+	for _, name := range names {
+		if _, ok := store[freeze.Normalize(name)]; ok {
+			continue
+		}
+		if _, zc, err := config.LoadZoneConfig(name); err == nil && zc != nil && zc.Frozen {
+			inConfig = append(inConfig, name)
+		}
+	}
+	if len(store) == 0 && len(inConfig) == 0 {
+		fmt.Fprintln(w, "No frozen zones.")
+		return nil
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "ZONE\tSINCE\tTICKET\tNOTE")
+	for _, name := range sortedKeys(store) {
+		e := store[name]
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", name, e.At.UTC().Format(time.RFC3339), stringOrDefault(e.Ticket, "-"), stringOrDefault(e.Note, "-"))
+	}
+	for _, name := range inConfig {
+		fmt.Fprintf(tw, "%s\t-\t-\tfrozen in config.json\n", name)
+	}
+	return tw.Flush()
+}
+
+// configFrozenZone returns the name of a zone config.json marks frozen
+// whose zone_id is zoneID, or "" when there is none. Zones that fail to
+// load are skipped here; issuing for them by name fails anyway.
+func configFrozenZone(zoneID string) string {
+	if zoneID == "" {
+		return ""
+	}
+	names, err := config.ZoneNames()
+	if err != nil {
+		return ""
+	}
+	for _, name := range names {
+		if _, zc, err := config.LoadZoneConfig(name); err == nil && zc != nil && zc.Frozen && zc.ZoneID == zoneID {
+			return name
+		}
+	}
+	return ""
+}
+
+// checkFrozen refuses issuance for a zone frozen in config.json or with
+// `cftoken zone freeze`. zoneID catches requests that bypass the zone name
+// with -zone-id, or that come without a zone config, as narrow does.
+func checkFrozen(zone, zoneID string, zc *config.ZoneConfig) error {
+	details := map[string]any{"zone": stringOrDefault(zone, zoneID)}
+	if zc != nil && zc.Frozen {
+		return withCode(codeZoneFrozen, fmt.Errorf("zone %s is frozen in config.json; no tokens can be issued for it", zone), details)
+	}
+	if name := configFrozenZone(zoneID); name != "" {
+		details["zone"] = name
+		return withCode(codeZoneFrozen, fmt.Errorf("zone %s (%s) is frozen in config.json; no tokens can be issued for it", name, zoneID), details)
+	}
+	store, err := freeze.Load()
+	if err != nil {
+		return err
+	}
+	name, entry, ok := store.Lookup(zone, zoneID)
+	if !ok {
+		return nil
+	}
+	msg := fmt.Sprintf("zone %s is frozen since %s", name, entry.At.UTC().Format(time.RFC3339))
+	if entry.Note != "" {
+		msg += ": " + entry.Note
+	}
+	details["zone"] = name
+	return withCode(codeZoneFrozen, fmt.Errorf("%s; run `cftoken zone unfreeze %s` to issue tokens again", msg, name), details)
+}
+
+// describeZones prints each configured zone next to its live state and
+// fails when any zone could not be fetched or is not active, so it can
+// serve as a health check.
+func describeZones(ctx context.Context, w io.Writer, client zoneDescriber, names []string) error {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "ZONE\tID\tDOMAIN\tSTATUS\tPLAN\tACCOUNT\tNAME SERVERS")
+	unhealthy := 0
+	for _, name := range names {
+		zoneID, _, err := config.LoadZoneConfig(name)
+		if err != nil {
+			unhealthy++
+			fmt.Fprintf(tw, "%s\t-\t-\tconfig error: %v\t-\t-\t-\n", name, err)
+			continue
+		}
+		d, err := client.DescribeZone(ctx, zoneID)
+		if err != nil {
+			unhealthy++
+			fmt.Fprintf(tw, "%s\t%s\t-\terror: %v\t-\t-\t-\n", name, zoneID, err)
+			continue
+		}
+		status := stringOrDefault(d.Status, "unknown")
+		if d.Paused {
+			status += " (paused)"
+		}
+		if d.Status != "active" || d.Paused {
+			unhealthy++
+		}
+		account := stringOrDefault(d.AccountName, d.AccountID)
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\t%s\n", name, zoneID, stringOrDefault(d.Name, "-"), status,
+			stringOrDefault(d.Plan, "-"), stringOrDefault(account, "-"), joinOrDefault(d.NameServers, "-"))
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+	if unhealthy > 0 {
+		return fmt.Errorf("%d of %d zone(s) are not active or could not be described", unhealthy, len(names))
+	}
+	return nil
+}
@@ -0,0 +1,155 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"cftoken/internal/cloudflare"
+	"cftoken/internal/config"
+	"cftoken/internal/freeze"
+)
+
+func TestCheckFrozen(t *testing.T) {
+	root := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", root)
+	t.Setenv("XDG_STATE_HOME", filepath.Join(root, "state"))
+	writeConfig(t, root, `{"zones": {"prod": {"zone_id": "zone-prod", "frozen": true}, "dev": "zone-dev"}}`)
+
+	if err := freeze.Freeze("staging", freeze.Entry{ZoneID: "zone-staging", At: time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC), Note: "migration"}); err != nil {
+		t.Fatalf("Freeze() error = %v", err)
+	}
+
+	tests := []struct {
+		zone, zoneID string
+		zc           *config.ZoneConfig
+		want         string
+	}{
+		{"prod", "zone-prod", &config.ZoneConfig{Frozen: true}, "zone prod is frozen in config.json"},
+		{"staging", "zone-staging", nil, "zone staging is frozen since 2024-01-02T00:00:00Z: migration; run `cftoken zone unfreeze staging`"},
+		{"", "zone-staging", nil, "zone staging is frozen"},
+		{"dev", "zone-dev", nil, ""},
+	}
+	for _, tc := range tests {
+		err := checkFrozen(tc.zone, tc.zoneID, tc.zc)
+		if tc.want == "" {
+			if err != nil {
+				t.Errorf("checkFrozen(%q, %q) error = %v", tc.zone, tc.zoneID, err)
+			}
+			continue
+		}
+		if err == nil || !strings.Contains(err.Error(), tc.want) {
+			t.Errorf("checkFrozen(%q, %q) error = %v, want %q", tc.zone, tc.zoneID, err, tc.want)
+			continue
+		}
+		var coded *codedError
+		if !errors.As(err, &coded) || coded.code != codeZoneFrozen {
+			t.Errorf("checkFrozen(%q, %q) code = %v, want %s", tc.zone, tc.zoneID, err, codeZoneFrozen)
+		}
+	}
+
+	var buf bytes.Buffer
+	store, _ := freeze.Load()
+	if err := printFrozen(&buf, store); err != nil {
+		t.Fatalf("printFrozen() error = %v", err)
+	}
+	out := buf.String()
+	for _, want := range []string{"staging", "migration", "prod", "frozen in config.json"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("printFrozen() output missing %q:\n%s", want, out)
+		}
+	}
+	if strings.Contains(out, "dev") {
+		t.Errorf("printFrozen() lists unfrozen zone dev:\n%s", out)
+	}
+}
+
+type fakeZoneDescriber map[string]*cloudflare.ZoneDetails
+
+func (f fakeZoneDescriber) DescribeZone(_ context.Context, zoneID string) (*cloudflare.ZoneDetails, error) {
+	if d, ok := f[zoneID]; ok {
+		return d, nil
+	}
+	return nil, fmt.Errorf("get zone %s: not found", zoneID)
+}
+
+func TestDescribeZones(t *testing.T) {
+	root := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", root)
+	writeConfig(t, root, `{"zones": {"prod": {"zone_id": "zone-prod"}, "old": "zone-old", "paused": "zone-paused"}}`)
+	client := fakeZoneDescriber{
+		"zone-prod":   {Name: "example.com", Status: "active", Plan: "Pro Website", AccountName: "Acme", NameServers: []string{"ada.ns.cloudflare.com", "bob.ns.cloudflare.com"}},
+		"zone-paused": {Name: "paused.example.com", Status: "active", Paused: true, AccountID: "acc-1"},
+	}
+
+	var buf bytes.Buffer
+	err := describeZones(context.Background(), &buf, client, []string{"prod"})
+	if err != nil {
+		t.Fatalf("describeZones(prod) error = %v", err)
+	}
+	for _, want := range []string{"example.com", "active", "Pro Website", "Acme", "ada.ns.cloudflare.com, bob.ns.cloudflare.com"} {
+		if !strings.Contains(buf.String(), want) {
+			t.Errorf("describeZones() output missing %q:\n%s", want, buf.String())
+		}
+	}
+
+	buf.Reset()
+	err = describeZones(context.Background(), &buf, client, []string{"prod", "old", "paused"})
+	if err == nil || !strings.Contains(err.Error(), "2 of 3 zone(s)") {
+		t.Fatalf("describeZones() error = %v, want 2 of 3 unhealthy", err)
+	}
+	for _, want := range []string{"error: get zone zone-old: not found", "active (paused)", "acc-1"} {
+		if !strings.Contains(buf.String(), want) {
+			t.Errorf("describeZones() output missing %q:\n%s", want, buf.String())
+		}
+	}
+}
+
+// TestFrozenByZoneID checks that a zone frozen in config.json stays frozen
+// for the paths that only know its ID: create -zone-id and narrow.
+func TestFrozenByZoneID(t *testing.T) {
+	const frozenID, otherID = "0123456789abcdef0123456789abcdef", "fedcba9876543210fedcba9876543210"
+	root := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", root)
+	t.Setenv("XDG_STATE_HOME", filepath.Join(root, "state"))
+	t.Setenv("XDG_CACHE_HOME", filepath.Join(root, "cache"))
+	writeConfig(t, root, `{"zones": {"prod": {"zone_id": "`+frozenID+`", "frozen": true}}}`)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			t.Errorf("%s %s for a frozen zone", r.Method, r.URL.Path)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]any{"success": true, "errors": []any{}, "messages": []any{}, "result": map[string]any{
+			"id": "t1", "name": "ci", "status": "active",
+			"policies": []any{map[string]any{
+				"id":                "p1",
+				"effect":            "allow",
+				"resources":         map[string]any{"com.cloudflare.api.account.zone." + frozenID: "*", "com.cloudflare.api.account.zone." + otherID: "*"},
+				"permission_groups": []any{map[string]any{"id": "pg-dns", "name": "DNS Write"}},
+			}},
+		}})
+	}))
+	defer srv.Close()
+	defer func(prev []cloudflare.Option) { testClientOptions = prev }(testClientOptions)
+	testClientOptions = []cloudflare.Option{cloudflare.WithBaseURL(srv.URL)}
+	defer func(orig func(context.Context) string) { resolveManagementToken = orig }(resolveManagementToken)
+	resolveManagementToken = func(context.Context) string { return "tok" }
+
+	for _, args := range [][]string{
+		{"create", "-zone-id", frozenID, "-permissions", "DNS Write"},
+		{"narrow", "-from-token-id", "t1", "-drop-resource", "com.cloudflare.api.account.zone." + otherID, "-allow-cidrs", "192.0.2.0/24"},
+	} {
+		err := runCLI(t, args...)
+		if err == nil || !strings.Contains(err.Error(), "zone prod ("+frozenID+") is frozen in config.json") {
+			t.Errorf("cftoken %s error = %v, want the config freeze", strings.Join(args, " "), err)
+		}
+	}
+}
@@ -0,0 +1,185 @@
+// Package approval serves a one-time link through which a second operator
+// approves or denies a token creation the CLI is waiting on. The link
+// carries a random secret, is served only while the CLI waits, and accepts
+// a single decision; teams without a shared approval service can still keep
+// high-risk tokens behind two people.
+package approval
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"html/template"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Decision is the approver's answer. Verified is set when Approver came
+// from the authenticating proxy's header rather than the form.
+type Decision struct {
+	Approved bool
+	Approver string
+	Verified bool
+}
+
+// Link is a pending approval: the summary shown to the approver and the
+// secret path the decision must be posted to.
+type Link struct {
+	// ApproverHeader, when set, is the request header an authenticating
+	// proxy in front of the link puts the user in. The approver is taken
+	// from it, and a decision without it is refused, instead of trusting
+	// the name typed into the form.
+	ApproverHeader string
+
+	summary string
+	path    string
+
+	mu      sync.Mutex
+	done    bool
+	decided chan Decision
+}
+
+// NewLink creates a pending approval for the creation described by summary.
+func NewLink(summary string) (*Link, error) {
+	secret := make([]byte, 16)
+	if _, err := rand.Read(secret); err != nil {
+		return nil, fmt.Errorf("generate approval secret: %w", err)
+	}
+	return &Link{
+		summary: summary,
+		path:    "/approve/" + hex.EncodeToString(secret),
+		decided: make(chan Decision, 1),
+	}, nil
+}
+
+// Path is the secret path of the approval page, to be appended to the base
+// URL the approver opens.
+func (l *Link) Path() string { return l.path }
+
+var page = template.Must(template.New("approval").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head><meta charset="utf-8"><title>cftoken approval</title></head>
+<body>
+{{- if .Done }}
+<p>{{ .Done }}</p>
+{{- else }}
+<h1>Approve a Cloudflare API token?</h1>
+<pre>{{ .Summary }}</pre>
+<form method="post">
+{{- if .Problem }}<p><strong>{{ .Problem }}</strong></p>{{ end }}
+{{- if .AskName }}
+<label>Your name <input name="approver" required autofocus></label>
+{{- end }}
+<button name="decision" value="approve">Approve</button>
+<button name="decision" value="deny">Deny</button>
+</form>
+{{- end }}
+</body>
+</html>
+`))
+
+type pageData struct {
+	Summary string
+	AskName bool
+	Problem string
+	Done    string
+}
+
+// ServeHTTP shows the approval page at the secret path and records the
+// first decision posted to it. Every other path is not found, so the
+// listener reveals nothing without the secret.
+func (l *Link) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != l.path {
+		http.NotFound(w, r)
+		return
+	}
+	// The secret is in the URL; keep it out of caches and referrers.
+	w.Header().Set("Cache-Control", "no-store")
+	w.Header().Set("Referrer-Policy", "no-referrer")
+	w.Header().Set("X-Frame-Options", "DENY")
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+
+	askName := l.ApproverHeader == ""
+	switch r.Method {
+	case http.MethodGet:
+		data := pageData{Summary: l.summary, AskName: askName}
+		if l.isDone() {
+			data.Done = "This request has already been decided."
+		}
+		page.Execute(w, data)
+	case http.MethodPost:
+		approver := strings.TrimSpace(r.PostFormValue("approver"))
+		if !askName {
+			approver = strings.TrimSpace(r.Header.Get(l.ApproverHeader))
+			if approver == "" {
+				w.WriteHeader(http.StatusForbidden)
+				page.Execute(w, pageData{Done: "Your identity was not passed on by the proxy; sign in through it and open the link again."})
+				return
+			}
+		}
+		decision := r.PostFormValue("decision")
+		if approver == "" || (decision != "approve" && decision != "deny") {
+			w.WriteHeader(http.StatusBadRequest)
+			page.Execute(w, pageData{Summary: l.summary, AskName: askName, Problem: "Enter your name and choose Approve or Deny."})
+			return
+		}
+		if !l.decide(Decision{Approved: decision == "approve", Approver: approver, Verified: !askName}) {
+			w.WriteHeader(http.StatusConflict)
+			page.Execute(w, pageData{Done: "This request has already been decided."})
+			return
+		}
+		done := "Denied. The token will not be created."
+		if decision == "approve" {
+			done = "Approved. The token is being created."
+		}
+		page.Execute(w, pageData{Done: done})
+	default:
+		w.Header().Set("Allow", "GET, POST")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// isDone reports whether a decision has been recorded.
+func (l *Link) isDone() bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.done
+}
+
+// decide records d unless a decision was recorded before.
+func (l *Link) decide(d Decision) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.done {
+		return false
+	}
+	l.done = true
+	l.decided <- d
+	return true
+}
+
+// Wait serves the approval page on ln until a decision is posted or ctx
+// ends, then shuts the server down.
+func (l *Link) Wait(ctx context.Context, ln net.Listener) (Decision, error) {
+	srv := &http.Server{Handler: l, ReadHeaderTimeout: 10 * time.Second}
+	served := make(chan error, 1)
+	go func() { served <- srv.Serve(ln) }()
+	defer func() {
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		srv.Shutdown(shutdownCtx)
+	}()
+
+	select {
+	case d := <-l.decided:
+		return d, nil
+	case err := <-served:
+		return Decision{}, fmt.Errorf("serve approval link: %w", err)
+	case <-ctx.Done():
+		return Decision{}, fmt.Errorf("no decision on the approval link: %w", ctx.Err())
+	}
+}
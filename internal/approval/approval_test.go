@@ -0,0 +1,160 @@
+package approval
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLinkDecidesOnce(t *testing.T) {
+	link, err := NewLink("Name: prod-20240102T030405Z\n<script>")
+	if err != nil {
+		t.Fatalf("NewLink() error = %v", err)
+	}
+	srv := httptest.NewServer(link)
+	defer srv.Close()
+
+	get := func(path string) (int, string) {
+		t.Helper()
+		resp, err := http.Get(srv.URL + path)
+		if err != nil {
+			t.Fatalf("GET %s: %v", path, err)
+		}
+		defer resp.Body.Close()
+		body, _ := io.ReadAll(resp.Body)
+		return resp.StatusCode, string(body)
+	}
+	post := func(form url.Values) (int, string) {
+		t.Helper()
+		resp, err := http.PostForm(srv.URL+link.Path(), form)
+		if err != nil {
+			t.Fatalf("POST: %v", err)
+		}
+		defer resp.Body.Close()
+		body, _ := io.ReadAll(resp.Body)
+		return resp.StatusCode, string(body)
+	}
+
+	if code, _ := get("/approve/guess"); code != http.StatusNotFound {
+		t.Errorf("GET without the secret = %d, want 404", code)
+	}
+	code, body := get(link.Path())
+	if code != http.StatusOK || !strings.Contains(body, "prod-20240102T030405Z") || strings.Contains(body, "<script>") {
+		t.Errorf("GET page = %d:\n%s", code, body)
+	}
+	if code, _ := post(url.Values{"decision": {"approve"}}); code != http.StatusBadRequest {
+		t.Errorf("POST without a name = %d, want 400", code)
+	}
+	if code, body := post(url.Values{"approver": {" Alex "}, "decision": {"deny"}}); code != http.StatusOK || !strings.Contains(body, "Denied") {
+		t.Errorf("POST deny = %d:\n%s", code, body)
+	}
+	if code, _ := post(url.Values{"approver": {"Sam"}, "decision": {"approve"}}); code != http.StatusConflict {
+		t.Errorf("second POST = %d, want 409", code)
+	}
+	if _, body := get(link.Path()); !strings.Contains(body, "already been decided") {
+		t.Errorf("GET after the decision:\n%s", body)
+	}
+	if got, want := <-link.decided, (Decision{Approved: false, Approver: "Alex"}); got != want {
+		t.Errorf("decision = %+v, want %+v", got, want)
+	}
+}
+
+func TestLinkApproverHeader(t *testing.T) {
+	link, err := NewLink("summary")
+	if err != nil {
+		t.Fatalf("NewLink() error = %v", err)
+	}
+	link.ApproverHeader = "X-Auth-Request-Email"
+	srv := httptest.NewServer(link)
+	defer srv.Close()
+
+	post := func(user string) (int, string) {
+		t.Helper()
+		req, err := http.NewRequest(http.MethodPost, srv.URL+link.Path(), strings.NewReader(url.Values{"approver": {"Mallory"}, "decision": {"approve"}}.Encode()))
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		if user != "" {
+			req.Header.Set("X-Auth-Request-Email", user)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("POST: %v", err)
+		}
+		defer resp.Body.Close()
+		body, _ := io.ReadAll(resp.Body)
+		return resp.StatusCode, string(body)
+	}
+
+	resp, err := http.Get(srv.URL + link.Path())
+	if err != nil {
+		t.Fatalf("GET: %v", err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if strings.Contains(string(body), `name="approver"`) {
+		t.Errorf("page asks for a name although the proxy supplies it:\n%s", body)
+	}
+	if code, _ := post(""); code != http.StatusForbidden {
+		t.Errorf("POST without the header = %d, want 403", code)
+	}
+	if code, body := post("alex@example.com"); code != http.StatusOK || !strings.Contains(body, "Approved") {
+		t.Errorf("POST approve = %d:\n%s", code, body)
+	}
+	// The typed name is ignored in favor of the proxy's.
+	if got, want := <-link.decided, (Decision{Approved: true, Approver: "alex@example.com", Verified: true}); got != want {
+		t.Errorf("decision = %+v, want %+v", got, want)
+	}
+}
+
+func TestWait(t *testing.T) {
+	link, err := NewLink("summary")
+	if err != nil {
+		t.Fatalf("NewLink() error = %v", err)
+	}
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	go func() {
+		resp, err := http.PostForm("http://"+ln.Addr().String()+link.Path(), url.Values{"approver": {"Alex"}, "decision": {"approve"}})
+		if err == nil {
+			resp.Body.Close()
+		}
+	}()
+	got, err := link.Wait(context.Background(), ln)
+	if err != nil {
+		t.Fatalf("Wait() error = %v", err)
+	}
+	if want := (Decision{Approved: true, Approver: "Alex"}); got != want {
+		t.Errorf("Wait() = %+v, want %+v", got, want)
+	}
+	// The server is shut down once the decision is in.
+	if _, err := net.DialTimeout("tcp", ln.Addr().String(), time.Second); err == nil {
+		t.Errorf("approval server still listening after the decision")
+	}
+}
+
+func TestWaitTimeout(t *testing.T) {
+	link, err := NewLink("summary")
+	if err != nil {
+		t.Fatalf("NewLink() error = %v", err)
+	}
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if _, err := link.Wait(ctx, ln); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Wait() error = %v, want deadline exceeded", err)
+	}
+}
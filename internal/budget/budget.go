@@ -0,0 +1,185 @@
+// Package budget enforces the issuance budget: how many tokens one run, and
+// all runs in the last 24 hours, may create. Issued tokens are tracked in a
+// ledger in the state directory; entries older than a day are dropped
+// whenever it is written.
+package budget
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"cftoken/internal/config"
+)
+
+// window is the span the per-day limit counts over.
+const window = 24 * time.Hour
+
+// ErrExceeded is returned by Reserve when issuing would exceed the budget.
+var ErrExceeded = errors.New("issuance budget exceeded")
+
+// Entry is one issued token. Ticket is the change ticket it was issued
+// under, and Forced holds the note given when the budget was overridden.
+// Pending marks a place held by Reserve for a token not created yet.
+type Entry struct {
+	At        time.Time `json:"at"`
+	TokenID   string    `json:"token_id"`
+	TokenName string    `json:"token_name"`
+	Ticket    string    `json:"ticket,omitempty"`
+	Forced    string    `json:"forced,omitempty"`
+	Pending   string    `json:"pending,omitempty"`
+}
+
+// Path returns the ledger file.
+func Path() (string, error) {
+	state, err := config.StateDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(state, "issuance.json"), nil
+}
+
+// Reservation holds places in the ledger for tokens about to be created, so
+// that a concurrent run counts them before they exist. The zero Reservation
+// holds no places.
+type Reservation struct {
+	id string
+}
+
+// Reserve checks that n more tokens fit within limits at now and, under the
+// per-day limit, holds places for them until Commit or Release. Places that
+// are never given back expire with the rest of the day's entries.
+func Reserve(limits config.Budget, n int, now time.Time) (*Reservation, error) {
+	if limits.PerRun > 0 && n > limits.PerRun {
+		return nil, fmt.Errorf("%w: this run would create %d tokens, per_run allows %d", ErrExceeded, n, limits.PerRun)
+	}
+	if limits.PerDay <= 0 {
+		return &Reservation{}, nil
+	}
+	var b [8]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return nil, fmt.Errorf("reserve issuance budget: %w", err)
+	}
+	r := &Reservation{id: hex.EncodeToString(b[:])}
+	err := update(now, func(entries []Entry) ([]Entry, error) {
+		if used := len(entries); used+n > limits.PerDay {
+			return nil, fmt.Errorf("%w: %d tokens issued in the last 24h, per_day allows %d", ErrExceeded, used, limits.PerDay)
+		}
+		for range n {
+			entries = append(entries, Entry{At: now, Pending: r.id})
+		}
+		return entries, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// Commit replaces the places r holds with the tokens actually issued.
+func (r *Reservation) Commit(now time.Time, issued ...Entry) error {
+	err := update(now, func(entries []Entry) ([]Entry, error) {
+		return append(r.drop(entries), issued...), nil
+	})
+	if err == nil {
+		r.id = ""
+	}
+	return err
+}
+
+// Release gives back the places r still holds. It does nothing after Commit.
+func (r *Reservation) Release(now time.Time) error {
+	if r.id == "" {
+		return nil
+	}
+	err := update(now, func(entries []Entry) ([]Entry, error) {
+		return r.drop(entries), nil
+	})
+	if err == nil {
+		r.id = ""
+	}
+	return err
+}
+
+// drop removes the places r holds from entries.
+func (r *Reservation) drop(entries []Entry) []Entry {
+	if r.id == "" {
+		return entries
+	}
+	out := entries[:0]
+	for _, e := range entries {
+		if e.Pending != r.id {
+			out = append(out, e)
+		}
+	}
+	return out
+}
+
+// Record appends issued to the ledger.
+func Record(now time.Time, issued ...Entry) error {
+	return update(now, func(entries []Entry) ([]Entry, error) {
+		return append(entries, issued...), nil
+	})
+}
+
+// update rewrites the ledger with what change makes of its recent entries,
+// holding the ledger's lock so that concurrent runs take turns.
+func update(now time.Time, change func([]Entry) ([]Entry, error)) error {
+	path, err := Path()
+	if err != nil {
+		return err
+	}
+	unlock, err := config.LockFile(path)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	entries, err := load()
+	if err != nil {
+		return err
+	}
+	entries, err = change(recent(entries, now))
+	if err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(entries, "", "  ")
+	if err != nil {
+		return fmt.Errorf("encode issuance ledger: %w", err)
+	}
+	return config.WriteFileAtomic(path, append(data, '\n'))
+}
+
+func load() ([]Entry, error) {
+	path, err := Path()
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var entries []Entry
+	if err := json.Unmarshal(data, &entries); err != nil {
+		return nil, fmt.Errorf("parse issuance ledger %s: %w", path, err)
+	}
+	return entries, nil
+}
+
+// recent returns the entries issued within window of now.
+func recent(entries []Entry, now time.Time) []Entry {
+	out := make([]Entry, 0, len(entries))
+	for _, e := range entries {
+		if now.Sub(e.At) < window {
+			out = append(out, e)
+		}
+	}
+	return out
+}
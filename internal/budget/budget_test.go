@@ -0,0 +1,100 @@
+package budget
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"cftoken/internal/config"
+)
+
+func TestReserve(t *testing.T) {
+	t.Setenv("XDG_STATE_HOME", t.TempDir())
+	now := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	if err := Record(now,
+		Entry{At: now.Add(-25 * time.Hour), TokenID: "old"},
+		Entry{At: now.Add(-time.Hour), TokenID: "t1"},
+		Entry{At: now.Add(-time.Minute), TokenID: "t2", Forced: "incident 42"},
+	); err != nil {
+		t.Fatalf("Record() error = %v", err)
+	}
+
+	tests := []struct {
+		name   string
+		limits config.Budget
+		n      int
+		ok     bool
+	}{
+		{"unlimited", config.Budget{}, 100, true},
+		{"within run", config.Budget{PerRun: 3}, 3, true},
+		{"over run", config.Budget{PerRun: 3}, 4, false},
+		{"within day", config.Budget{PerDay: 3}, 1, true},
+		{"over day", config.Budget{PerDay: 3}, 2, false},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			r, err := Reserve(tc.limits, tc.n, now)
+			if tc.ok && err != nil {
+				t.Fatalf("Reserve() error = %v", err)
+			}
+			if !tc.ok && !errors.Is(err, ErrExceeded) {
+				t.Fatalf("Reserve() error = %v, want ErrExceeded", err)
+			}
+			if r != nil {
+				if err := r.Release(now); err != nil {
+					t.Fatalf("Release() error = %v", err)
+				}
+			}
+		})
+	}
+
+	if err := Record(now, Entry{At: now, TokenID: "t3"}); err != nil {
+		t.Fatalf("Record() error = %v", err)
+	}
+	entries, err := load()
+	if err != nil {
+		t.Fatalf("load() error = %v", err)
+	}
+	if len(entries) != 3 || entries[0].TokenID != "t1" || entries[1].Forced != "incident 42" {
+		t.Fatalf("ledger = %+v, want entries older than 24h pruned", entries)
+	}
+}
+
+func TestReservationHoldsPlaces(t *testing.T) {
+	t.Setenv("XDG_STATE_HOME", t.TempDir())
+	now := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	limits := config.Budget{PerDay: 3}
+
+	first, err := Reserve(limits, 2, now)
+	if err != nil {
+		t.Fatalf("Reserve(2) error = %v", err)
+	}
+	if _, err := Reserve(limits, 2, now); !errors.Is(err, ErrExceeded) {
+		t.Fatalf("Reserve(2) while 2 are held error = %v, want ErrExceeded", err)
+	}
+	second, err := Reserve(limits, 1, now)
+	if err != nil {
+		t.Fatalf("Reserve(1) error = %v", err)
+	}
+	if err := second.Release(now); err != nil {
+		t.Fatalf("Release() error = %v", err)
+	}
+
+	if err := first.Commit(now, Entry{At: now, TokenID: "t1"}); err != nil {
+		t.Fatalf("Commit() error = %v", err)
+	}
+	if err := first.Release(now); err != nil {
+		t.Fatalf("Release() after Commit() error = %v", err)
+	}
+	entries, err := load()
+	if err != nil {
+		t.Fatalf("load() error = %v", err)
+	}
+	if len(entries) != 1 || entries[0].TokenID != "t1" || entries[0].Pending != "" {
+		t.Fatalf("ledger = %+v, want only the committed token", entries)
+	}
+	if _, err := Reserve(limits, 2, now); err != nil {
+		t.Fatalf("Reserve(2) after unused places were given back error = %v", err)
+	}
+}
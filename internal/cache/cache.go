@@ -0,0 +1,214 @@
+// Package cache persists API lookups, such as the permission group catalog,
+// between runs as JSON files in the user cache directory.
+package cache
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"cftoken/internal/clock"
+)
+
+const (
+	// DefaultTTL is how long entries are served before they are refetched.
+	DefaultTTL = time.Hour
+	// DefaultMaxBytes caps the total size of the cache directory.
+	DefaultMaxBytes int64 = 10 << 20
+
+	fileSuffix = ".json"
+)
+
+// Dir returns the cftoken cache directory, following XDG_CACHE_HOME.
+func Dir() (string, error) {
+	base, err := os.UserCacheDir()
+	if err != nil {
+		return "", fmt.Errorf("determine cache directory: %w", err)
+	}
+	return filepath.Join(base, "cftoken"), nil
+}
+
+// Store is a directory of JSON entries that expire after a TTL. When the
+// directory outgrows its size limit the oldest entries are removed.
+type Store struct {
+	dir      string
+	ttl      time.Duration
+	maxBytes int64
+	now      func() time.Time
+}
+
+// New returns a Store rooted at dir. A non-positive ttl or maxBytes selects
+// the default.
+func New(dir string, ttl time.Duration, maxBytes int64) *Store {
+	if ttl <= 0 {
+		ttl = DefaultTTL
+	}
+	if maxBytes <= 0 {
+		maxBytes = DefaultMaxBytes
+	}
+	return &Store{dir: dir, ttl: ttl, maxBytes: maxBytes, now: clock.Now}
+}
+
+// Dir returns the directory the store writes to.
+func (s *Store) Dir() string { return s.dir }
+
+// TTL returns how long entries stay fresh.
+func (s *Store) TTL() time.Duration { return s.ttl }
+
+// MaxBytes returns the size limit of the store.
+func (s *Store) MaxBytes() int64 { return s.maxBytes }
+
+// Get decodes the entry for key into v. It reports false when the entry is
+// missing or expired.
+func (s *Store) Get(key string, v any) (bool, error) {
+	path, err := s.path(key)
+	if err != nil {
+		return false, err
+	}
+	info, err := os.Stat(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("read cache entry %s: %w", key, err)
+	}
+	if s.expired(info.ModTime()) {
+		return false, nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return false, fmt.Errorf("read cache entry %s: %w", key, err)
+	}
+	if err := json.Unmarshal(data, v); err != nil {
+		// A corrupt entry is a miss; the next Put replaces it.
+		return false, nil
+	}
+	return true, nil
+}
+
+// Put stores v under key and then trims the store to its size limit.
+func (s *Store) Put(key string, v any) error {
+	path, err := s.path(key)
+	if err != nil {
+		return err
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Errorf("encode cache entry %s: %w", key, err)
+	}
+	if err := os.MkdirAll(s.dir, 0o700); err != nil {
+		return fmt.Errorf("create cache directory: %w", err)
+	}
+	tmp, err := os.CreateTemp(s.dir, ".entry-*")
+	if err != nil {
+		return fmt.Errorf("write cache entry %s: %w", key, err)
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("write cache entry %s: %w", key, err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("write cache entry %s: %w", key, err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("write cache entry %s: %w", key, err)
+	}
+	return s.prune()
+}
+
+// Entry describes one cached item.
+type Entry struct {
+	Key     string
+	Size    int64
+	ModTime time.Time
+	Expired bool
+}
+
+// Entries lists the cached items, oldest first. A missing directory yields
+// no entries.
+func (s *Store) Entries() ([]Entry, error) {
+	dirEntries, err := os.ReadDir(s.dir)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("read cache directory: %w", err)
+	}
+	var entries []Entry
+	for _, de := range dirEntries {
+		name := de.Name()
+		if de.IsDir() || strings.HasPrefix(name, ".") || !strings.HasSuffix(name, fileSuffix) {
+			continue
+		}
+		info, err := de.Info()
+		if err != nil {
+			continue
+		}
+		entries = append(entries, Entry{
+			Key:     strings.TrimSuffix(name, fileSuffix),
+			Size:    info.Size(),
+			ModTime: info.ModTime(),
+			Expired: s.expired(info.ModTime()),
+		})
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].ModTime.Before(entries[j].ModTime)
+	})
+	return entries, nil
+}
+
+// Clear removes every entry and returns how many were removed.
+func (s *Store) Clear() (int, error) {
+	entries, err := s.Entries()
+	if err != nil {
+		return 0, err
+	}
+	for i, e := range entries {
+		if err := os.Remove(filepath.Join(s.dir, e.Key+fileSuffix)); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return i, fmt.Errorf("remove cache entry %s: %w", e.Key, err)
+		}
+	}
+	return len(entries), nil
+}
+
+// prune removes the oldest entries until the store fits its size limit.
+func (s *Store) prune() error {
+	entries, err := s.Entries()
+	if err != nil {
+		return err
+	}
+	var total int64
+	for _, e := range entries {
+		total += e.Size
+	}
+	for _, e := range entries {
+		if total <= s.maxBytes {
+			break
+		}
+		if err := os.Remove(filepath.Join(s.dir, e.Key+fileSuffix)); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return fmt.Errorf("remove cache entry %s: %w", e.Key, err)
+		}
+		total -= e.Size
+	}
+	return nil
+}
+
+func (s *Store) expired(modTime time.Time) bool {
+	return s.now().Sub(modTime) >= s.ttl
+}
+
+func (s *Store) path(key string) (string, error) {
+	if key == "" || strings.HasPrefix(key, ".") || strings.ContainsFunc(key, func(r rune) bool {
+		return !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '-' || r == '_' || r == '.')
+	}) {
+		return "", fmt.Errorf("invalid cache key %q", key)
+	}
+	return filepath.Join(s.dir, key+fileSuffix), nil
+}
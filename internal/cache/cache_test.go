@@ -0,0 +1,83 @@
+package cache
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestStoreGetPut(t *testing.T) {
+	t.Parallel()
+
+	s := New(t.TempDir(), time.Hour, 0)
+	var got []string
+	if ok, err := s.Get("groups", &got); ok || err != nil {
+		t.Fatalf("Get() on empty store = %v, %v; want miss", ok, err)
+	}
+	if err := s.Put("groups", []string{"a", "b"}); err != nil {
+		t.Fatalf("Put() error = %v", err)
+	}
+	if ok, err := s.Get("groups", &got); !ok || err != nil || len(got) != 2 {
+		t.Fatalf("Get() = %v, %v, %v; want hit", got, ok, err)
+	}
+
+	s.now = func() time.Time { return time.Now().Add(time.Hour) }
+	if ok, _ := s.Get("groups", &got); ok {
+		t.Fatal("Get() returned an expired entry")
+	}
+	entries, err := s.Entries()
+	if err != nil || len(entries) != 1 || !entries[0].Expired || entries[0].Key != "groups" {
+		t.Fatalf("Entries() = %+v, %v", entries, err)
+	}
+}
+
+func TestStorePrunesOldest(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	s := New(dir, time.Hour, 40)
+	if err := s.Put("old", "0123456789012345678901234567890"); err != nil {
+		t.Fatal(err)
+	}
+	past := time.Now().Add(-time.Minute)
+	if err := os.Chtimes(filepath.Join(dir, "old.json"), past, past); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Put("new", "0123456789"); err != nil {
+		t.Fatal(err)
+	}
+
+	entries, err := s.Entries()
+	if err != nil || len(entries) != 1 || entries[0].Key != "new" {
+		t.Fatalf("Entries() after prune = %+v, %v; want only the newest", entries, err)
+	}
+}
+
+func TestStoreClear(t *testing.T) {
+	t.Parallel()
+
+	s := New(filepath.Join(t.TempDir(), "missing"), 0, 0)
+	if n, err := s.Clear(); n != 0 || err != nil {
+		t.Fatalf("Clear() on missing dir = %d, %v", n, err)
+	}
+	for _, key := range []string{"a", "b"} {
+		if err := s.Put(key, key); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if n, err := s.Clear(); n != 2 || err != nil {
+		t.Fatalf("Clear() = %d, %v; want 2", n, err)
+	}
+}
+
+func TestStoreRejectsUnsafeKeys(t *testing.T) {
+	t.Parallel()
+
+	s := New(t.TempDir(), 0, 0)
+	for _, key := range []string{"", "../escape", ".hidden", "a/b"} {
+		if err := s.Put(key, 1); err == nil {
+			t.Errorf("Put(%q) error = nil, want invalid key", key)
+		}
+	}
+}
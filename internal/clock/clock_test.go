@@ -0,0 +1,41 @@
+package clock
+
+import (
+	"testing"
+	"time"
+)
+
+func TestFreeze(t *testing.T) {
+	at := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	restore := Freeze(at)
+	if got := Now(); !got.Equal(at) {
+		t.Errorf("Now() = %v, want %v", got, at)
+	}
+	inner := Freeze(at.Add(time.Hour))
+	inner()
+	if got := Now(); !got.Equal(at) {
+		t.Errorf("Now() after restoring a nested freeze = %v, want %v", got, at)
+	}
+	restore()
+	if got := Now(); got.Equal(at) || time.Since(got) > time.Minute {
+		t.Errorf("Now() after restore = %v, want the current time", got)
+	}
+}
+
+func TestValue(t *testing.T) {
+	defer Freeze(time.Time{})()
+
+	var v Value
+	if err := v.Set("2024-01-02T03:04:05+01:00"); err != nil {
+		t.Fatalf("Set() error = %v", err)
+	}
+	if got, want := Now(), time.Date(2024, 1, 2, 2, 4, 5, 0, time.UTC); !got.Equal(want) {
+		t.Errorf("Now() = %v, want %v", got, want)
+	}
+	if got := v.String(); got != "2024-01-02T03:04:05+01:00" {
+		t.Errorf("String() = %q", got)
+	}
+	if err := v.Set("yesterday"); err == nil {
+		t.Errorf("Set(yesterday) error = nil, want error")
+	}
+}
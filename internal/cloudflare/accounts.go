@@ -0,0 +1,195 @@
+package cloudflare
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"strings"
+
+	cf "github.com/cloudflare/cloudflare-go/v6"
+	"github.com/cloudflare/cloudflare-go/v6/accounts"
+	"github.com/cloudflare/cloudflare-go/v6/zones"
+)
+
+// ZoneAccountID returns the ID of the account that owns the zone.
+func (c *Client) ZoneAccountID(ctx context.Context, zoneID string) (string, error) {
+	zone, err := c.zone(ctx, zoneID)
+	if err != nil {
+		return "", err
+	}
+	if zone.AccountID == "" {
+		return "", fmt.Errorf("zone %s did not report its account", zoneID)
+	}
+	return zone.AccountID, nil
+}
+
+// ZoneName returns the domain name of the zone.
+func (c *Client) ZoneName(ctx context.Context, zoneID string) (string, error) {
+	zone, err := c.zone(ctx, zoneID)
+	if err != nil {
+		return "", err
+	}
+	return zone.Name, nil
+}
+
+func (c *Client) zone(ctx context.Context, zoneID string) (zoneInfo, error) {
+	if strings.TrimSpace(zoneID) == "" {
+		return zoneInfo{}, errors.New("zone ID is required")
+	}
+	return c.cachedZone(zoneID, func() (zoneInfo, error) {
+		zone, err := c.api.Zones.Get(ctx, zones.ZoneGetParams{ZoneID: cf.F(zoneID)})
+		if err != nil {
+			return zoneInfo{}, fmt.Errorf("get zone %s: %w", zoneID, err)
+		}
+		return zoneInfo{Name: zone.Name, AccountID: zone.Account.ID}, nil
+	})
+}
+
+// ZoneDetails is the live state of a zone as Cloudflare reports it.
+type ZoneDetails struct {
+	ID          string
+	Name        string
+	Status      string
+	Paused      bool
+	Type        string
+	Plan        string
+	AccountID   string
+	AccountName string
+	NameServers []string
+}
+
+// DescribeZone fetches the zone's status, plan, account, and name servers.
+// It always asks the API: the point is to see the zone as it is now.
+func (c *Client) DescribeZone(ctx context.Context, zoneID string) (*ZoneDetails, error) {
+	if strings.TrimSpace(zoneID) == "" {
+		return nil, errors.New("zone ID is required")
+	}
+	zone, err := c.api.Zones.Get(ctx, zones.ZoneGetParams{ZoneID: cf.F(zoneID)})
+	if err != nil {
+		return nil, fmt.Errorf("get zone %s: %w", zoneID, err)
+	}
+	return zoneDetails(zone), nil
+}
+
+// FindZone looks a zone up by domain name. A domain the token cannot see
+// is reported as fs.ErrNotExist; one present in several accounts is an
+// error, since the caller has to pick the zone ID.
+func (c *Client) FindZone(ctx context.Context, name string) (*ZoneDetails, error) {
+	name = strings.ToLower(strings.TrimSuffix(strings.TrimSpace(name), "."))
+	if name == "" {
+		return nil, errors.New("zone name is required")
+	}
+	var found []*ZoneDetails
+	pager := c.api.Zones.ListAutoPaging(ctx, zones.ZoneListParams{Name: cf.F(name)})
+	for pager.Next() {
+		zone := pager.Current()
+		found = append(found, zoneDetails(&zone))
+	}
+	if err := pager.Err(); err != nil {
+		return nil, fmt.Errorf("find zone %s: %w", name, err)
+	}
+	switch len(found) {
+	case 0:
+		return nil, fmt.Errorf("zone %s is not visible to the token: %w", name, fs.ErrNotExist)
+	case 1:
+		return found[0], nil
+	default:
+		ids := make([]string, len(found))
+		for i, z := range found {
+			ids[i] = fmt.Sprintf("%s (account %s)", z.ID, z.AccountID)
+		}
+		return nil, fmt.Errorf("zone %s exists in %d accounts: %s", name, len(found), strings.Join(ids, ", "))
+	}
+}
+
+// ListZones returns the zones visible to the token, ordered by name, at
+// most limit of them.
+func (c *Client) ListZones(ctx context.Context, limit int) ([]*ZoneDetails, error) {
+	var found []*ZoneDetails
+	pager := c.api.Zones.ListAutoPaging(ctx, zones.ZoneListParams{
+		Order:     cf.F(zones.ZoneListParamsOrderName),
+		Direction: cf.F(zones.ZoneListParamsDirectionAsc),
+	})
+	for len(found) < limit && pager.Next() {
+		zone := pager.Current()
+		found = append(found, zoneDetails(&zone))
+	}
+	if err := pager.Err(); err != nil {
+		return nil, fmt.Errorf("list zones: %w", err)
+	}
+	return found, nil
+}
+
+func zoneDetails(zone *zones.Zone) *ZoneDetails {
+	return &ZoneDetails{
+		ID:          zone.ID,
+		Name:        zone.Name,
+		Status:      string(zone.Status),
+		Paused:      zone.Paused,
+		Type:        string(zone.Type),
+		Plan:        zone.Plan.Name,
+		AccountID:   zone.Account.ID,
+		AccountName: zone.Account.Name,
+		NameServers: zone.NameServers,
+	}
+}
+
+// Account is an account the current token can access.
+type Account struct {
+	ID   string
+	Name string
+	Type string
+}
+
+// Accounts lists every account the current token can access.
+func (c *Client) Accounts(ctx context.Context) ([]Account, error) {
+	var out []Account
+	pager := c.api.Accounts.ListAutoPaging(ctx, accounts.AccountListParams{})
+	for pager.Next() {
+		account := pager.Current()
+		out = append(out, Account{ID: account.ID, Name: account.Name, Type: string(account.Type)})
+	}
+	if err := pager.Err(); err != nil {
+		return nil, fmt.Errorf("list accounts: %w", err)
+	}
+	return out, nil
+}
+
+// AccountIDs lists the IDs of every account the current token can access.
+func (c *Client) AccountIDs(ctx context.Context) ([]string, error) {
+	list, err := c.Accounts(ctx)
+	if err != nil {
+		return nil, err
+	}
+	ids := make([]string, len(list))
+	for i, account := range list {
+		ids[i] = account.ID
+	}
+	return ids, nil
+}
+
+// User is the Cloudflare user a token acts for.
+type User struct {
+	ID    string
+	Email string
+}
+
+// CurrentUser returns the user the token acts for. Tokens owned by an
+// account act for no user, and the API answers with an error.
+func (c *Client) CurrentUser(ctx context.Context) (*User, error) {
+	resp, err := c.api.User.Get(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("get user: %w", err)
+	}
+	if resp == nil {
+		return nil, errors.New("cloudflare API returned an empty user response")
+	}
+	// The SDK does not map the email address.
+	var extra struct {
+		Email string `json:"email"`
+	}
+	_ = json.Unmarshal([]byte(resp.JSON.RawJSON()), &extra)
+	return &User{ID: resp.ID, Email: extra.Email}, nil
+}
@@ -0,0 +1,155 @@
+package cloudflare
+
+import (
+	"context"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestZoneAccountID(t *testing.T) {
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/zones/z1" {
+			t.Errorf("path = %s, want /zones/z1", r.URL.Path)
+		}
+		writeEnvelope(t, w, map[string]any{"id": "z1", "account": map[string]string{"id": "acc-1", "name": "Acme"}})
+	})
+
+	got, err := client.ZoneAccountID(context.Background(), "z1")
+	if err != nil {
+		t.Fatalf("ZoneAccountID() error = %v", err)
+	}
+	if got != "acc-1" {
+		t.Fatalf("ZoneAccountID() = %q, want acc-1", got)
+	}
+}
+
+func TestDescribeZone(t *testing.T) {
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		writeEnvelope(t, w, map[string]any{
+			"id":           "z1",
+			"name":         "example.com",
+			"status":       "active",
+			"type":         "full",
+			"paused":       false,
+			"plan":         map[string]any{"name": "Pro Website"},
+			"account":      map[string]string{"id": "acc-1", "name": "Acme"},
+			"name_servers": []string{"ada.ns.cloudflare.com", "bob.ns.cloudflare.com"},
+		})
+	})
+
+	got, err := client.DescribeZone(context.Background(), "z1")
+	if err != nil {
+		t.Fatalf("DescribeZone() error = %v", err)
+	}
+	want := ZoneDetails{
+		ID: "z1", Name: "example.com", Status: "active", Type: "full", Plan: "Pro Website",
+		AccountID: "acc-1", AccountName: "Acme", NameServers: []string{"ada.ns.cloudflare.com", "bob.ns.cloudflare.com"},
+	}
+	if !reflect.DeepEqual(*got, want) {
+		t.Fatalf("DescribeZone() = %+v, want %+v", *got, want)
+	}
+}
+
+func TestAccountIDs(t *testing.T) {
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("page") == "2" {
+			writeEnvelope(t, w, []any{})
+			return
+		}
+		writeEnvelope(t, w, []map[string]string{{"id": "acc-1"}, {"id": "acc-2"}})
+	})
+
+	got, err := client.AccountIDs(context.Background())
+	if err != nil {
+		t.Fatalf("AccountIDs() error = %v", err)
+	}
+	if len(got) != 2 || got[0] != "acc-1" || got[1] != "acc-2" {
+		t.Fatalf("AccountIDs() = %v", got)
+	}
+}
+
+func TestCurrentUser(t *testing.T) {
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/user" {
+			t.Errorf("path = %s", r.URL.Path)
+		}
+		writeEnvelope(t, w, map[string]string{"id": "u1", "email": "svc@example.com"})
+	})
+
+	got, err := client.CurrentUser(context.Background())
+	if err != nil {
+		t.Fatalf("CurrentUser() error = %v", err)
+	}
+	if got.ID != "u1" || got.Email != "svc@example.com" {
+		t.Fatalf("CurrentUser() = %+v", got)
+	}
+}
+
+func TestFindZone(t *testing.T) {
+	tests := []struct {
+		name    string
+		result  []map[string]any
+		wantID  string
+		wantErr string
+	}{
+		{"one match", []map[string]any{{"id": "z1", "name": "example.com", "account": map[string]string{"id": "acc-1"}}}, "z1", ""},
+		{"not visible", []map[string]any{}, "", "not visible"},
+		{"several accounts", []map[string]any{
+			{"id": "z1", "name": "example.com", "account": map[string]string{"id": "acc-1"}},
+			{"id": "z2", "name": "example.com", "account": map[string]string{"id": "acc-2"}},
+		}, "", "exists in 2 accounts"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+				if got := r.URL.Query().Get("name"); got != "example.com" {
+					t.Errorf("name query = %q, want example.com", got)
+				}
+				if r.URL.Query().Get("page") == "2" {
+					writeEnvelope(t, w, []any{})
+					return
+				}
+				writeEnvelope(t, w, tc.result)
+			})
+			got, err := client.FindZone(context.Background(), "Example.com.")
+			if tc.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+					t.Fatalf("FindZone() error = %v, want %q", err, tc.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("FindZone() error = %v", err)
+			}
+			if got.ID != tc.wantID || got.AccountID != "acc-1" {
+				t.Errorf("FindZone() = %+v", got)
+			}
+		})
+	}
+}
+
+func TestListZones(t *testing.T) {
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if got := r.URL.Query().Get("order"); got != "name" {
+			t.Errorf("order query = %q, want name", got)
+		}
+		if r.URL.Query().Get("page") == "2" {
+			writeEnvelope(t, w, []any{})
+			return
+		}
+		writeEnvelope(t, w, []map[string]any{
+			{"id": "z1", "name": "a.example"},
+			{"id": "z2", "name": "b.example"},
+			{"id": "z3", "name": "c.example"},
+		})
+	})
+	got, err := client.ListZones(context.Background(), 2)
+	if err != nil {
+		t.Fatalf("ListZones() error = %v", err)
+	}
+	if len(got) != 2 || got[0].ID != "z1" || got[1].Name != "b.example" {
+		t.Errorf("ListZones() = %+v", got)
+	}
+}
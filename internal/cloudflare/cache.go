@@ -0,0 +1,165 @@
+package cloudflare
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"slices"
+	"sync"
+	"time"
+)
+
+// defaultCacheTTL bounds how long a Client reuses permission groups and zones
+// it has already fetched. Both change rarely, and one CLI run or service
+// request often needs them several times.
+const defaultCacheTTL = 5 * time.Minute
+
+// WithCacheTTL sets how long fetched permission groups and zones are reused.
+// Zero disables caching.
+func WithCacheTTL(ttl time.Duration) Option {
+	return func(c *Client) {
+		c.cacheTTL = ttl
+	}
+}
+
+// PersistentCache keeps fetched data across runs. Get reports false on a
+// miss or an expired entry. Implementations must be safe for concurrent use.
+type PersistentCache interface {
+	Get(key string, v any) (bool, error)
+	Put(key string, v any) error
+}
+
+// WithPersistentCache backs the in-memory cache with pc, so permission groups
+// and zones fetched by one run are reused by the next. Entries are keyed by a
+// hash of the API token, since what a token can see depends on the token.
+func WithPersistentCache(pc PersistentCache) Option {
+	return func(c *Client) {
+		c.persistent = pc
+	}
+}
+
+// persistentKey scopes name to the client's token.
+func (c *Client) persistentKey(name string) string {
+	return c.tokenHash + "-" + name
+}
+
+func tokenHash(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:6])
+}
+
+// loadPersistent and storePersistent treat the persistent cache as best
+// effort; failures are logged and the API is used instead.
+func (c *Client) loadPersistent(name string, v any) bool {
+	if c.persistent == nil {
+		return false
+	}
+	ok, err := c.persistent.Get(c.persistentKey(name), v)
+	if err != nil && c.logf != nil {
+		c.logf("cache: %v", err)
+	}
+	return ok
+}
+
+func (c *Client) storePersistent(name string, v any) {
+	if c.persistent == nil {
+		return
+	}
+	if err := c.persistent.Put(c.persistentKey(name), v); err != nil && c.logf != nil {
+		c.logf("cache: %v", err)
+	}
+}
+
+// permissionCache holds the permission group catalog. The mutex is held
+// across the fetch so concurrent callers share a single request.
+type permissionCache struct {
+	mu        sync.Mutex
+	groups    []PermissionGroup
+	fetchedAt time.Time
+}
+
+type zoneCache struct {
+	mu    sync.Mutex
+	zones map[string]cachedZone
+}
+
+// zoneInfo is the part of a zone the client needs.
+type zoneInfo struct {
+	Name      string `json:"name"`
+	AccountID string `json:"account_id"`
+}
+
+type cachedZone struct {
+	zone      zoneInfo
+	fetchedAt time.Time
+}
+
+func (c *Client) fresh(fetchedAt time.Time) bool {
+	return !fetchedAt.IsZero() && c.now().Sub(fetchedAt) < c.cacheTTL
+}
+
+func (c *Client) cachedPermissionGroups(fetch func() ([]PermissionGroup, error)) ([]PermissionGroup, error) {
+	if c.cacheTTL <= 0 {
+		return fetch()
+	}
+	c.permissions.mu.Lock()
+	defer c.permissions.mu.Unlock()
+	if c.fresh(c.permissions.fetchedAt) {
+		return slices.Clone(c.permissions.groups), nil
+	}
+	var groups []PermissionGroup
+	if !c.loadPersistent("permission-groups", &groups) {
+		var err error
+		if groups, err = fetch(); err != nil {
+			return nil, err
+		}
+		c.storePersistent("permission-groups", groups)
+	}
+	c.permissions.groups = groups
+	c.permissions.fetchedAt = c.now()
+	return slices.Clone(groups), nil
+}
+
+// CachedPermissionGroups returns the permission group catalog the
+// persistent cache holds for the client's token without calling the API,
+// reporting false when none is cached or the entry has expired.
+func (c *Client) CachedPermissionGroups() ([]PermissionGroup, bool) {
+	var groups []PermissionGroup
+	ok := c.loadPersistent("permission-groups", &groups)
+	return groups, ok
+}
+
+func (c *Client) cachedZone(zoneID string, fetch func() (zoneInfo, error)) (zoneInfo, error) {
+	if c.cacheTTL <= 0 {
+		return fetch()
+	}
+	c.zones.mu.Lock()
+	defer c.zones.mu.Unlock()
+	if entry, ok := c.zones.zones[zoneID]; ok && c.fresh(entry.fetchedAt) {
+		return entry.zone, nil
+	}
+	var zone zoneInfo
+	if !c.loadPersistent("zone-"+zoneID, &zone) {
+		var err error
+		if zone, err = fetch(); err != nil {
+			return zoneInfo{}, err
+		}
+		c.storePersistent("zone-"+zoneID, zone)
+	}
+	if c.zones.zones == nil {
+		c.zones.zones = make(map[string]cachedZone)
+	}
+	c.zones.zones[zoneID] = cachedZone{zone: zone, fetchedAt: c.now()}
+	return zone, nil
+}
+
+// ClearCache drops every permission group and zone cached in memory so the
+// next call fetches fresh data. The persistent cache is left alone.
+func (c *Client) ClearCache() {
+	c.permissions.mu.Lock()
+	c.permissions.groups, c.permissions.fetchedAt = nil, time.Time{}
+	c.permissions.mu.Unlock()
+
+	c.zones.mu.Lock()
+	c.zones.zones = nil
+	c.zones.mu.Unlock()
+}
@@ -0,0 +1,169 @@
+package cloudflare
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestPermissionGroupsCache(t *testing.T) {
+	var hits atomic.Int32
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		hits.Add(1)
+		writeEnvelope(t, w, []map[string]any{{"id": "a", "name": "Zone Read"}})
+	})
+	now := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	var mu sync.Mutex
+	client.now = func() time.Time {
+		mu.Lock()
+		defer mu.Unlock()
+		return now
+	}
+
+	var wg sync.WaitGroup
+	for range 8 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			groups, err := client.PermissionGroups(context.Background())
+			if err != nil || len(groups) != 1 {
+				t.Errorf("PermissionGroups() = %v, %v", groups, err)
+			}
+		}()
+	}
+	wg.Wait()
+	if got := hits.Load(); got != 1 {
+		t.Fatalf("concurrent calls made %d requests, want 1", got)
+	}
+
+	mu.Lock()
+	now = now.Add(defaultCacheTTL)
+	mu.Unlock()
+	if _, err := client.PermissionGroups(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if got := hits.Load(); got != 2 {
+		t.Fatalf("expired cache made %d requests in total, want 2", got)
+	}
+
+	client.ClearCache()
+	if _, err := client.PermissionGroups(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if got := hits.Load(); got != 3 {
+		t.Fatalf("cleared cache made %d requests in total, want 3", got)
+	}
+}
+
+func TestCacheDisabled(t *testing.T) {
+	var hits atomic.Int32
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		hits.Add(1)
+		writeEnvelope(t, w, map[string]any{"id": "z1", "name": "example.com", "account": map[string]string{"id": "acc"}})
+	})
+	WithCacheTTL(0)(client)
+
+	for range 2 {
+		if _, err := client.ZoneName(context.Background(), "z1"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if got := hits.Load(); got != 2 {
+		t.Fatalf("uncached lookups made %d requests, want 2", got)
+	}
+}
+
+func TestPool(t *testing.T) {
+	t.Parallel()
+
+	pool := NewPool(WithUserAgent("svc"))
+	a, b := pool.Get("token-a"), pool.Get("token-b")
+	if a == b || pool.Get("token-a") != a {
+		t.Fatal("Pool.Get() did not return one client per token")
+	}
+	if a.userAgent != "svc" {
+		t.Errorf("pooled client user agent = %q, want options applied", a.userAgent)
+	}
+	pool.Forget("token-a")
+	if pool.Get("token-a") == a {
+		t.Error("Pool.Get() after Forget() returned the old client")
+	}
+}
+
+type mapCache struct {
+	mu      sync.Mutex
+	entries map[string][]byte
+}
+
+func (m *mapCache) Get(key string, v any) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	data, ok := m.entries[key]
+	if !ok {
+		return false, nil
+	}
+	return true, json.Unmarshal(data, v)
+}
+
+func (m *mapCache) Put(key string, v any) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	m.entries[key] = data
+	return nil
+}
+
+func TestPersistentCacheSharedAcrossClients(t *testing.T) {
+	var hits atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits.Add(1)
+		writeEnvelope(t, w, map[string]any{"id": "z1", "name": "example.com", "account": map[string]string{"id": "acc"}})
+	}))
+	t.Cleanup(srv.Close)
+	store := &mapCache{entries: map[string][]byte{}}
+	newClient := func(token string) *Client {
+		return NewClient(token, WithBaseURL(srv.URL), WithHTTPClient(srv.Client()), WithPersistentCache(store))
+	}
+
+	if id, err := newClient("token-a").ZoneAccountID(context.Background(), "z1"); err != nil || id != "acc" {
+		t.Fatalf("ZoneAccountID() = %q, %v", id, err)
+	}
+	if name, err := newClient("token-a").ZoneName(context.Background(), "z1"); err != nil || name != "example.com" {
+		t.Fatalf("ZoneName() = %q, %v", name, err)
+	}
+	if got := hits.Load(); got != 1 {
+		t.Fatalf("second client made %d requests in total, want 1 from the persistent cache", got)
+	}
+	if _, err := newClient("token-b").ZoneName(context.Background(), "z1"); err != nil {
+		t.Fatal(err)
+	}
+	if got := hits.Load(); got != 2 {
+		t.Fatalf("other token made %d requests in total, want 2; entries must be per token", got)
+	}
+}
+
+func TestCachedPermissionGroups(t *testing.T) {
+	store := &mapCache{entries: map[string][]byte{}}
+	client := NewClient("token-a", WithPersistentCache(store))
+	if groups, ok := client.CachedPermissionGroups(); ok || groups != nil {
+		t.Fatalf("CachedPermissionGroups() on empty cache = %v, %v", groups, ok)
+	}
+
+	want := []PermissionGroup{{ID: "g1", Name: "DNS Write"}}
+	client.storePersistent("permission-groups", want)
+	if groups, ok := client.CachedPermissionGroups(); !ok || !reflect.DeepEqual(groups, want) {
+		t.Errorf("CachedPermissionGroups() = %v, %v, want %v", groups, ok, want)
+	}
+	if _, ok := NewClient("token-b", WithPersistentCache(store)).CachedPermissionGroups(); ok {
+		t.Errorf("CachedPermissionGroups() for another token = true, want false")
+	}
+}
@@ -5,30 +5,55 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"iter"
 	"net/http"
 	"sort"
 	"strings"
 	"time"
 
 	cf "github.com/cloudflare/cloudflare-go/v6"
+	cfaccounts "github.com/cloudflare/cloudflare-go/v6/accounts"
 	cfoption "github.com/cloudflare/cloudflare-go/v6/option"
 	"github.com/cloudflare/cloudflare-go/v6/shared"
 	cfuser "github.com/cloudflare/cloudflare-go/v6/user"
+
+	"cftoken/internal/clock"
+	"cftoken/internal/httpmw"
 )
 
+// createAttempts bounds how often CreateToken tries, as the SDK's default
+// of two retries would.
+const createAttempts = 3
+
 // DefaultPermissionKeys represents the fallback permission group names used when
 // the CLI is not provided explicit permissions. These should correspond to the
 // human-readable names returned by the Cloudflare API.
 var DefaultPermissionKeys = []string{"Zone:Read"}
 
 // Client wraps the Cloudflare SDK client with helpers needed for token
-// provisioning.
+// provisioning. A Client is safe for concurrent use by multiple goroutines;
+// its configuration is fixed by NewClient and its caches are guarded by
+// their own locks. Use a Pool to share clients across a service.
 type Client struct {
-	api        *cf.Client
-	baseURL    string
-	userAgent  string
-	httpClient *http.Client
-	logf       func(string, ...interface{})
+	api         *cf.Client
+	baseURL     string
+	userAgent   string
+	httpClient  *http.Client
+	logf        func(string, ...interface{})
+	redactors   []httpmw.Redactor
+	cacheTTL    time.Duration
+	now         func() time.Time
+	persistent  PersistentCache
+	tokenHash   string
+	readOnly    bool
+	credentials CredentialProvider
+
+	permissions permissionCache
+	zones       zoneCache
+	rateLimit   rateLimitState
+	retryDelay  time.Duration
+	// tokenAccount, when set, is the account whose tokens are managed.
+	tokenAccount string
 }
 
 // Option configures a Client.
@@ -64,11 +89,23 @@ func WithLogger(logf func(string, ...interface{})) Option {
 	}
 }
 
+// WithRedactors adds redaction rules applied to verbose log output. The
+// client always redacts bearer credentials and its own API token.
+func WithRedactors(redactors ...httpmw.Redactor) Option {
+	return func(c *Client) {
+		c.redactors = append(c.redactors, redactors...)
+	}
+}
+
 // NewClient constructs a Client backed by the official Cloudflare SDK.
 func NewClient(token string, opts ...Option) *Client {
 	c := &Client{
 		userAgent:  "cftoken-cli",
 		httpClient: &http.Client{Timeout: 30 * time.Second},
+		cacheTTL:   defaultCacheTTL,
+		retryDelay: time.Second,
+		now:        clock.Now,
+		tokenHash:  tokenHash(token),
 	}
 	for _, opt := range opts {
 		opt(c)
@@ -86,12 +123,18 @@ func NewClient(token string, opts ...Option) *Client {
 	if c.httpClient != nil {
 		requestOptions = append(requestOptions, cfoption.WithHTTPClient(c.httpClient))
 	}
+	requestOptions = append(requestOptions, cfoption.WithMiddleware(httpmw.Correlate()))
+	if c.readOnly {
+		requestOptions = append(requestOptions, cfoption.WithMiddleware(readOnlyMiddleware()))
+	}
+	if c.credentials != nil {
+		requestOptions = append(requestOptions, cfoption.WithMiddleware(credentialMiddleware(c.credentials)))
+	}
 	if c.logf != nil {
-		requestOptions = append(requestOptions, cfoption.WithMiddleware(func(req *http.Request, next cfoption.MiddlewareNext) (*http.Response, error) {
-			c.logf("cloudflare request: %s %s", req.Method, req.URL.String())
-			return next(req)
-		}))
+		redactors := append([]httpmw.Redactor{httpmw.RedactBearer(), httpmw.RedactValues(token)}, c.redactors...)
+		requestOptions = append(requestOptions, cfoption.WithMiddleware(httpmw.Logger(c.logf, redactors...)))
 	}
+	requestOptions = append(requestOptions, cfoption.WithMiddleware(rateLimitMiddleware(c)))
 
 	c.api = cf.NewClient(requestOptions...)
 	return c
@@ -127,80 +170,103 @@ type TokenResult struct {
 
 // TokenVerification captures metadata returned by the verify endpoint.
 type TokenVerification struct {
-	ID        string
-	Status    string
-	ExpiresOn string
-	NotBefore string
+	ID        string `json:"id"`
+	Status    string `json:"status"`
+	ExpiresOn string `json:"expires_on,omitempty"`
+	NotBefore string `json:"not_before,omitempty"`
 }
 
 // TokenInspection summarises a token's configuration.
 type TokenInspection struct {
-	ID           string
-	Name         string
-	Status       string
-	ExpiresOn    string
-	NotBefore    string
-	AllowedCIDRs []string
-	DeniedCIDRs  []string
-	Policies     []TokenPolicyInspection
+	ID           string                  `json:"id"`
+	Name         string                  `json:"name"`
+	Status       string                  `json:"status"`
+	ExpiresOn    string                  `json:"expires_on,omitempty"`
+	NotBefore    string                  `json:"not_before,omitempty"`
+	AllowedCIDRs []string                `json:"allowed_cidrs,omitempty"`
+	DeniedCIDRs  []string                `json:"denied_cidrs,omitempty"`
+	Policies     []TokenPolicyInspection `json:"policies"`
 }
 
 // TokenPolicyInspection captures the essential components of a token policy.
 type TokenPolicyInspection struct {
-	Effect           string
-	PermissionGroups []PermissionGroupSummary
-	Resources        []string
+	ID               string                   `json:"id,omitempty"`
+	Effect           string                   `json:"effect"`
+	PermissionGroups []PermissionGroupSummary `json:"permission_groups"`
+	Resources        []string                 `json:"-"`
+	// ResourceMap holds the resources as the API returned them: keys mapped
+	// to a string, or to a map of strings for nested account scopes.
+	ResourceMap map[string]interface{} `json:"resources"`
 }
 
 // PermissionGroupSummary exposes concise metadata for a permission group.
 type PermissionGroupSummary struct {
-	ID   string
-	Name string
-	Key  string
+	ID   string `json:"id"`
+	Name string `json:"name,omitempty"`
+	Key  string `json:"key,omitempty"`
 }
 
-// PermissionGroups fetches all permission groups available to the current token.
+// PermissionGroups fetches all permission groups available to the current
+// token, reusing the previous result while it is within the cache TTL.
 func (c *Client) PermissionGroups(ctx context.Context) ([]PermissionGroup, error) {
-	page, err := c.api.User.Tokens.PermissionGroups.List(ctx, cfuser.TokenPermissionGroupListParams{})
-	if err != nil {
-		return nil, fmt.Errorf("list permission groups: %w", err)
-	}
-	if page == nil {
-		return nil, errors.New("cloudflare API returned an empty permission group response")
-	}
+	return c.cachedPermissionGroups(func() ([]PermissionGroup, error) {
+		var groups []PermissionGroup
+		for group, err := range c.PermissionGroupsIter(ctx) {
+			if err != nil {
+				return nil, err
+			}
+			groups = append(groups, group)
+		}
+		return groups, nil
+	})
+}
 
-	items := page.Result
-	groups := make([]PermissionGroup, 0, len(items))
-	for _, item := range items {
-		group := PermissionGroup{
-			ID:   item.ID,
-			Name: item.Name,
+// PermissionGroupsIter streams the permission groups available to the current
+// token. Iteration stops at the first error, which is yielded last.
+func (c *Client) PermissionGroupsIter(ctx context.Context) iter.Seq2[PermissionGroup, error] {
+	return func(yield func(PermissionGroup, error) bool) {
+		pager := c.api.User.Tokens.PermissionGroups.ListAutoPaging(ctx, cfuser.TokenPermissionGroupListParams{})
+		for pager.Next() {
+			if !yield(newPermissionGroup(pager.Current()), nil) {
+				return
+			}
 		}
-		for _, scope := range item.Scopes {
-			group.Scopes = append(group.Scopes, string(scope))
+		if err := pager.Err(); err != nil {
+			yield(PermissionGroup{}, fmt.Errorf("list permission groups: %w", err))
 		}
-		if field, ok := item.JSON.ExtraFields["description"]; ok && !field.IsMissing() && !field.IsNull() && !field.IsInvalid() {
-			var desc string
-			if err := json.Unmarshal([]byte(field.Raw()), &desc); err == nil {
-				group.Description = desc
-			}
+	}
+}
+
+func newPermissionGroup(item cfuser.TokenPermissionGroupListResponse) PermissionGroup {
+	group := PermissionGroup{
+		ID:   item.ID,
+		Name: item.Name,
+	}
+	for _, scope := range item.Scopes {
+		group.Scopes = append(group.Scopes, string(scope))
+	}
+	// The SDK flags every untyped extra field as invalid, so only null and
+	// missing values are skipped here.
+	if field, ok := item.JSON.ExtraFields["description"]; ok && !field.IsNull() {
+		var desc string
+		if err := json.Unmarshal([]byte(field.Raw()), &desc); err == nil {
+			group.Description = desc
 		}
-		if field, ok := item.JSON.ExtraFields["meta"]; ok && !field.IsMissing() && !field.IsNull() && !field.IsInvalid() {
-			var meta PermissionGroupMeta
-			if err := json.Unmarshal([]byte(field.Raw()), &meta); err == nil {
-				group.Meta = meta
-			}
+	}
+	if field, ok := item.JSON.ExtraFields["meta"]; ok && !field.IsNull() {
+		var meta PermissionGroupMeta
+		if err := json.Unmarshal([]byte(field.Raw()), &meta); err == nil {
+			group.Meta = meta
 		}
-		groups = append(groups, group)
 	}
-	return groups, nil
+	return group
 }
 
 // Policy represents a Cloudflare API token policy ready to be converted to API parameters.
 type Policy struct {
-	ID               string                 `json:"id,omitempty"`
-	Effect           string                 `json:"effect"`
-	Resources        map[string]interface{} `json:"resources"`
+	ID               string                  `json:"id,omitempty"`
+	Effect           string                  `json:"effect"`
+	Resources        map[string]interface{}  `json:"resources"`
 	PermissionGroups []PolicyPermissionGroup `json:"permission_groups"`
 }
 
@@ -210,16 +276,74 @@ type PolicyPermissionGroup struct {
 	Name string `json:"name,omitempty"`
 }
 
-// CreateTokenWithPolicies provisions a new token using policy structures.
-func (c *Client) CreateTokenWithPolicies(ctx context.Context, tokenName string, policies []Policy, expiresOn *time.Time, allowedCIDRs []string) (*TokenResult, error) {
-	params, err := buildTokenParamsFromPolicies(tokenName, policies, expiresOn, allowedCIDRs)
+// CreateOption configures optional settings for CreateToken.
+type CreateOption func(*createSettings)
+
+type createSettings struct {
+	expiresOn  *time.Time
+	notBefore  *time.Time
+	conditions []Condition
+	uniqueName bool
+}
+
+// WithExpiry sets the time at which the new token stops being accepted.
+func WithExpiry(t time.Time) CreateOption {
+	return func(s *createSettings) {
+		s.expiresOn = &t
+	}
+}
+
+// WithUniqueName declares that the token name ends in a random suffix this
+// run generated, so no other run creates a token of that name. Only then is
+// a create whose response was lost retried; see createWithRetry.
+func WithUniqueName() CreateOption {
+	return func(s *createSettings) {
+		s.uniqueName = true
+	}
+}
+
+// WithNotBefore sets the time before which the new token is not accepted.
+func WithNotBefore(t time.Time) CreateOption {
+	return func(s *createSettings) {
+		s.notBefore = &t
+	}
+}
+
+// WithAllowedCIDRs restricts the new token to requests from the given ranges.
+func WithAllowedCIDRs(cidrs ...string) CreateOption {
+	return ipCondition("in", cidrs)
+}
+
+// WithDeniedCIDRs rejects requests made with the new token from the given ranges.
+func WithDeniedCIDRs(cidrs ...string) CreateOption {
+	return ipCondition("not_in", cidrs)
+}
+
+func ipCondition(op string, cidrs []string) CreateOption {
+	if len(cidrs) == 0 {
+		return func(*createSettings) {}
+	}
+	return WithCondition(Condition{Type: "request_ip", Operator: op, Values: cidrs})
+}
+
+// CreateToken provisions a new token with the given policies.
+func (c *Client) CreateToken(ctx context.Context, tokenName string, policies []Policy, opts ...CreateOption) (*TokenResult, error) {
+	if err := c.checkWritable("create token"); err != nil {
+		return nil, err
+	}
+	var settings createSettings
+	for _, opt := range opts {
+		opt(&settings)
+	}
+
+	params, err := buildTokenParamsFromPolicies(tokenName, policies, settings)
 	if err != nil {
 		return nil, err
 	}
 
-	resp, err := c.api.User.Tokens.New(ctx, *params)
+	resp, err := c.createWithRetry(ctx, params, settings.uniqueName)
 	if err != nil {
-		return nil, fmt.Errorf("create token: %w", err)
+		return nil, err
 	}
 	if resp == nil {
 		return nil, errors.New("cloudflare API returned an empty response")
@@ -230,7 +354,7 @@ func (c *Client) CreateTokenWithPolicies(ctx context.Context, tokenName string,
 		Name:         resp.Name,
 		Status:       string(resp.Status),
 		Value:        string(resp.Value),
-		AllowedCIDRs: append([]string(nil), allowedCIDRs...),
+		AllowedCIDRs: settings.conditionValues("request_ip", "in"),
 	}
 	if !resp.ExpiresOn.IsZero() {
 		result.ExpiresOn = resp.ExpiresOn.UTC().Format(time.RFC3339)
@@ -238,6 +362,116 @@ func (c *Client) CreateTokenWithPolicies(ctx context.Context, tokenName string,
 	return result, nil
 }
 
+// createWithRetry creates the token, retrying transport failures and
+// 429 or 5xx responses itself instead of leaving it to the SDK: a create
+// that succeeded server-side but whose response was lost would otherwise
+// be repeated, leaving a duplicate token. A 429 refused the request, so it
+// is simply retried. After any other failure the token may exist with its
+// value lost; only a name that is unique to this run (WithUniqueName) can
+// find it again, so it is revoked by that name before the retry. Other
+// names may belong to a concurrent run's live token, and the failure is
+// returned instead.
+func (c *Client) createWithRetry(ctx context.Context, params *cfuser.TokenNewParams, uniqueName bool) (*cfuser.TokenNewResponse, error) {
+	name := params.Name.Value
+	for attempt := 1; ; attempt++ {
+		resp, err := c.newToken(ctx, params, cfoption.WithMaxRetries(0))
+		if err == nil {
+			return resp, nil
+		}
+		lost := mayHaveCreated(err)
+		err = fmt.Errorf("create token: %w", err)
+		if lost {
+			err = fmt.Errorf("%w; a token named %s may have been created anyway", err, name)
+		}
+		if attempt == createAttempts || !retryable(ctx, err) || (lost && !uniqueName) {
+			return nil, err
+		}
+
+		if lost {
+			if lookupErr := c.revokeLost(ctx, name); lookupErr != nil {
+				return nil, fmt.Errorf("%w and could not be checked: %v", err, lookupErr)
+			}
+		}
+		select {
+		case <-ctx.Done():
+			return nil, fmt.Errorf("create token: %w", ctx.Err())
+		case <-time.After(time.Duration(attempt) * c.retryDelay):
+		}
+	}
+}
+
+// newToken creates a token in the user's token store or, with
+// WithTokenAccount, in the account's.
+func (c *Client) newToken(ctx context.Context, params *cfuser.TokenNewParams, opts ...cfoption.RequestOption) (*cfuser.TokenNewResponse, error) {
+	if c.tokenAccount == "" {
+		return c.api.User.Tokens.New(ctx, *params, opts...)
+	}
+	accountParams := cfaccounts.TokenNewParams{
+		AccountID: cf.F(c.tokenAccount),
+		Name:      params.Name,
+		Policies:  params.Policies,
+		ExpiresOn: params.ExpiresOn,
+		NotBefore: params.NotBefore,
+	}
+	if params.Condition.Present {
+		accountParams.Condition = cf.Raw[cfaccounts.TokenNewParamsCondition](params.Condition.Raw)
+	}
+	resp, err := c.api.Accounts.Tokens.New(ctx, accountParams, opts...)
+	if err != nil || resp == nil {
+		return nil, err
+	}
+	return &cfuser.TokenNewResponse{
+		ID:        resp.ID,
+		Name:      resp.Name,
+		Status:    cfuser.TokenNewResponseStatus(resp.Status),
+		Value:     resp.Value,
+		ExpiresOn: resp.ExpiresOn,
+	}, nil
+}
+
+// revokeLost deletes the tokens named name. Their value was lost with the
+// response that created them, so they cannot be kept.
+func (c *Client) revokeLost(ctx context.Context, name string) error {
+	for token, err := range c.Tokens(ctx) {
+		if err != nil {
+			return err
+		}
+		if token.Name != name {
+			continue
+		}
+		if c.logf != nil {
+			c.logf("revoking token %s (%s): created by an attempt whose response was lost", token.ID, token.Name)
+		}
+		if err := c.DeleteToken(ctx, token.ID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// retryable reports whether a failed create may be attempted again.
+func retryable(ctx context.Context, err error) bool {
+	if ctx.Err() != nil {
+		return false
+	}
+	var apiErr *cf.Error
+	if errors.As(err, &apiErr) {
+		return apiErr.StatusCode == http.StatusTooManyRequests || apiErr.StatusCode >= 500
+	}
+	return true
+}
+
+// mayHaveCreated reports whether a failed create may have created the token
+// anyway: its response was lost, or the API failed part-way. A 4xx response,
+// 429 included, means the request was refused.
+func mayHaveCreated(err error) bool {
+	var apiErr *cf.Error
+	if errors.As(err, &apiErr) {
+		return apiErr.StatusCode >= 500
+	}
+	return true
+}
+
 // MatchPermissions matches user-provided permission inputs to permission groups.
 func (c *Client) MatchPermissions(ctx context.Context, permissionInputs []string) ([]PermissionGroup, error) {
 	perms, err := c.PermissionGroups(ctx)
@@ -252,7 +486,31 @@ func (c *Client) MatchPermissions(ctx context.Context, permissionInputs []string
 	return matchedGroups, nil
 }
 
-func buildTokenParamsFromPolicies(tokenName string, policies []Policy, expiresOn *time.Time, allowedCIDRs []string) (*cfuser.TokenNewParams, error) {
+// ResolvePermissions matches inputs (IDs, names, or keys) against an already
+// fetched permission group catalog.
+func ResolvePermissions(groups []PermissionGroup, inputs []string) ([]PermissionGroup, error) {
+	_, matchedGroups, err := matchPermissionGroups(groups, inputs)
+	return matchedGroups, err
+}
+
+// setPolicyResources sets the resources of p. A map of strings uses the
+// SDK's type; any other shape, such as a nested account scope or a list,
+// is sent exactly as given, so resource scopes the SDK does not model yet
+// pass through.
+func setPolicyResources(p *shared.TokenPolicyParam, resources map[string]interface{}) {
+	flat := make(shared.TokenPolicyResourcesIAMResourcesTypeObjectStringParam, len(resources))
+	for key, value := range resources {
+		s, ok := value.(string)
+		if !ok {
+			p.Resources = cf.Raw[shared.TokenPolicyResourcesUnionParam](resources)
+			return
+		}
+		flat[key] = s
+	}
+	p.Resources = cf.F[shared.TokenPolicyResourcesUnionParam](flat)
+}
+
+func buildTokenParamsFromPolicies(tokenName string, policies []Policy, settings createSettings) (*cfuser.TokenNewParams, error) {
 	if len(policies) == 0 {
 		return nil, errors.New("at least one policy is required")
 	}
@@ -267,22 +525,11 @@ func buildTokenParamsFromPolicies(tokenName string, policies []Policy, expiresOn
 			})
 		}
 
-		// Convert resources map to API format
-		resourcesParam := shared.TokenPolicyResourcesIAMResourcesTypeObjectStringParam{}
-		for key, value := range policy.Resources {
-			if strValue, ok := value.(string); ok {
-				resourcesParam[key] = strValue
-			} else {
-				// Handle non-string values by converting to string
-				resourcesParam[key] = fmt.Sprintf("%v", value)
-			}
-		}
-
 		// Build policy param
 		policyParam := shared.TokenPolicyParam{
 			PermissionGroups: cf.F(permGroups),
-			Resources: cf.F[shared.TokenPolicyResourcesUnionParam](resourcesParam),
 		}
+		setPolicyResources(&policyParam, policy.Resources)
 
 		// Set effect (default to "allow" if not specified)
 		effect := policy.Effect
@@ -304,19 +551,20 @@ func buildTokenParamsFromPolicies(tokenName string, policies []Policy, expiresOn
 		Name:     cf.F(tokenName),
 		Policies: cf.F(policyParams),
 	}
-	if expiresOn != nil {
-		params.ExpiresOn = cf.F(expiresOn.UTC())
+	if settings.expiresOn != nil {
+		params.ExpiresOn = cf.F(settings.expiresOn.UTC())
+	}
+	if settings.notBefore != nil {
+		params.NotBefore = cf.F(settings.notBefore.UTC())
 	}
-	if len(allowedCIDRs) > 0 {
-		values := make([]shared.TokenConditionCIDRListParam, 0, len(allowedCIDRs))
-		for _, cidr := range allowedCIDRs {
-			values = append(values, shared.TokenConditionCIDRListParam(cidr))
+	if len(settings.conditions) > 0 {
+		// The SDK's condition params only know request_ip, so the object
+		// from the registry is sent as is.
+		condition := conditionObject{}
+		if err := condition.apply(settings.conditions); err != nil {
+			return nil, err
 		}
-		params.Condition = cf.F(cfuser.TokenNewParamsCondition{
-			RequestIP: cf.F(cfuser.TokenNewParamsConditionRequestIP{
-				In: cf.F(values),
-			}),
-		})
+		params.Condition = cf.Raw[cfuser.TokenNewParamsCondition](condition)
 	}
 
 	return params, nil
@@ -324,22 +572,38 @@ func buildTokenParamsFromPolicies(tokenName string, policies []Policy, expiresOn
 
 // VerifyToken returns metadata about the token configured on this client.
 func (c *Client) VerifyToken(ctx context.Context) (*TokenVerification, error) {
-	resp, err := c.api.User.Tokens.Verify(ctx)
-	if err != nil {
-		return nil, fmt.Errorf("verify token: %w", err)
-	}
-	if resp == nil {
-		return nil, errors.New("cloudflare API returned an empty token verification response")
+	var (
+		id, status           string
+		expiresOn, notBefore time.Time
+	)
+	if c.tokenAccount != "" {
+		resp, err := c.api.Accounts.Tokens.Verify(ctx, cfaccounts.TokenVerifyParams{AccountID: cf.F(c.tokenAccount)})
+		if err != nil {
+			return nil, fmt.Errorf("verify token: %w", err)
+		}
+		if resp == nil {
+			return nil, errors.New("cloudflare API returned an empty token verification response")
+		}
+		id, status, expiresOn, notBefore = resp.ID, string(resp.Status), resp.ExpiresOn, resp.NotBefore
+	} else {
+		resp, err := c.api.User.Tokens.Verify(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("verify token: %w", err)
+		}
+		if resp == nil {
+			return nil, errors.New("cloudflare API returned an empty token verification response")
+		}
+		id, status, expiresOn, notBefore = resp.ID, string(resp.Status), resp.ExpiresOn, resp.NotBefore
 	}
 	out := &TokenVerification{
-		ID:     resp.ID,
-		Status: string(resp.Status),
+		ID:     id,
+		Status: status,
 	}
-	if !resp.ExpiresOn.IsZero() {
-		out.ExpiresOn = resp.ExpiresOn.UTC().Format(time.RFC3339)
+	if !expiresOn.IsZero() {
+		out.ExpiresOn = expiresOn.UTC().Format(time.RFC3339)
 	}
-	if !resp.NotBefore.IsZero() {
-		out.NotBefore = resp.NotBefore.UTC().Format(time.RFC3339)
+	if !notBefore.IsZero() {
+		out.NotBefore = notBefore.UTC().Format(time.RFC3339)
 	}
 	return out, nil
 }
@@ -349,14 +613,18 @@ func (c *Client) DescribeToken(ctx context.Context, tokenID string) (*TokenInspe
 	if strings.TrimSpace(tokenID) == "" {
 		return nil, errors.New("token ID is required")
 	}
-	token, err := c.api.User.Tokens.Get(ctx, tokenID)
+	token, err := c.getToken(ctx, tokenID)
 	if err != nil {
 		return nil, fmt.Errorf("get token %s: %w", tokenID, err)
 	}
 	if token == nil {
 		return nil, errors.New("cloudflare API returned an empty token response")
 	}
+	return describeToken(token), nil
+}
 
+// describeToken extracts the permissions and restrictions of token.
+func describeToken(token *shared.Token) *TokenInspection {
 	inspection := &TokenInspection{
 		ID:     token.ID,
 		Name:   token.Name,
@@ -369,26 +637,56 @@ func (c *Client) DescribeToken(ctx context.Context, tokenID string) (*TokenInspe
 		inspection.NotBefore = token.NotBefore.UTC().Format(time.RFC3339)
 	}
 
-	for _, cidr := range token.Condition.RequestIP.In {
-		inspection.AllowedCIDRs = append(inspection.AllowedCIDRs, string(cidr))
+	inspection.AllowedCIDRs = cidrStrings(token.Condition.RequestIP.In)
+	inspection.DeniedCIDRs = cidrStrings(token.Condition.RequestIP.NotIn)
+	inspection.Policies = inspectPolicies(token.Policies)
+	return inspection
+}
+
+// RawToken returns the API response for a token exactly as it was sent,
+// envelope included, for bug reports and for fields DescribeToken drops.
+func (c *Client) RawToken(ctx context.Context, tokenID string) ([]byte, error) {
+	if strings.TrimSpace(tokenID) == "" {
+		return nil, errors.New("token ID is required")
 	}
-	for _, cidr := range token.Condition.RequestIP.NotIn {
-		inspection.DeniedCIDRs = append(inspection.DeniedCIDRs, string(cidr))
+	var raw []byte
+	if _, err := c.getToken(ctx, tokenID, cfoption.WithResponseBodyInto(&raw)); err != nil {
+		return nil, fmt.Errorf("get token %s: %w", tokenID, err)
 	}
-	sort.Strings(inspection.AllowedCIDRs)
-	sort.Strings(inspection.DeniedCIDRs)
+	return raw, nil
+}
 
-	for _, pol := range token.Policies {
+// cidrStrings converts and sorts a token condition CIDR list, returning nil
+// for an empty list.
+func cidrStrings(list []shared.TokenConditionCIDRList) []string {
+	if len(list) == 0 {
+		return nil
+	}
+	out := make([]string, len(list))
+	for i, cidr := range list {
+		out[i] = string(cidr)
+	}
+	sort.Strings(out)
+	return out
+}
+
+func inspectPolicies(policies []shared.TokenPolicy) []TokenPolicyInspection {
+	if len(policies) == 0 {
+		return nil
+	}
+	out := make([]TokenPolicyInspection, 0, len(policies))
+	for _, pol := range policies {
 		policy := TokenPolicyInspection{
-			Effect: string(pol.Effect),
+			ID:               pol.ID,
+			Effect:           string(pol.Effect),
+			PermissionGroups: summarisePermissionGroups(pol.PermissionGroups),
 		}
-		policy.PermissionGroups = append(policy.PermissionGroups, summarisePermissionGroups(pol.PermissionGroups)...)
-		policy.Resources = extractPolicyResources(pol.Resources)
+		policy.ResourceMap = policyResourceMap(pol)
+		policy.Resources = ResourceStrings(policy.ResourceMap)
 		sort.Strings(policy.Resources)
-		inspection.Policies = append(inspection.Policies, policy)
+		out = append(out, policy)
 	}
-
-	return inspection, nil
+	return out
 }
 
 func matchPermissionGroups(groups []PermissionGroup, inputs []string) ([]shared.TokenPolicyPermissionGroupParam, []PermissionGroup, error) {
@@ -397,10 +695,21 @@ func matchPermissionGroups(groups []PermissionGroup, inputs []string) ([]shared.
 	}
 	matched := make([]shared.TokenPolicyPermissionGroupParam, 0, len(inputs))
 	matchedGroups := make([]PermissionGroup, 0, len(inputs))
+
+	// Normalize each group once rather than once per input; catalogs run to
+	// hundreds of groups.
+	names := make([]string, len(groups))
+	keys := make([]string, len(groups))
+	for i, group := range groups {
+		names[i] = normalizeKey(group.Name)
+		if group.Meta.Key != "" {
+			keys[i] = normalizeKey(group.Meta.Key)
+		}
+	}
 lookup:
 	for _, in := range inputs {
 		normalized := normalizeKey(in)
-		for _, group := range groups {
+		for i, group := range groups {
 			switch {
 			case strings.EqualFold(in, group.ID):
 				matched = append(matched, shared.TokenPolicyPermissionGroupParam{
@@ -408,13 +717,13 @@ lookup:
 				})
 				matchedGroups = append(matchedGroups, group)
 				continue lookup
-			case normalizeKey(group.Name) == normalized:
+			case names[i] == normalized:
 				matched = append(matched, shared.TokenPolicyPermissionGroupParam{
 					ID: cf.F(group.ID),
 				})
 				matchedGroups = append(matchedGroups, group)
 				continue lookup
-			case group.Meta.Key != "" && normalizeKey(group.Meta.Key) == normalized:
+			case keys[i] != "" && keys[i] == normalized:
 				matched = append(matched, shared.TokenPolicyPermissionGroupParam{
 					ID: cf.F(group.ID),
 				})
@@ -427,10 +736,10 @@ lookup:
 	return matched, matchedGroups, nil
 }
 
+var keySeparators = strings.NewReplacer(" ", "", "_", "", "-", "", ":", "", ".", "")
+
 func normalizeKey(s string) string {
-	s = strings.TrimSpace(strings.ToLower(s))
-	replacer := strings.NewReplacer(" ", "", "_", "", "-", "", ":", "", ".", "")
-	return replacer.Replace(s)
+	return keySeparators.Replace(strings.TrimSpace(strings.ToLower(s)))
 }
 
 func summarisePermissionGroups(groups []shared.TokenPolicyPermissionGroup) []PermissionGroupSummary {
@@ -445,31 +754,73 @@ func summarisePermissionGroups(groups []shared.TokenPolicyPermissionGroup) []Per
 	return out
 }
 
-func extractPolicyResources(res shared.TokenPolicyResourcesUnion) []string {
-	switch v := res.(type) {
+// policyResourceMap returns the resources of pol as the API sent them. The
+// raw JSON is decoded when present, so shapes the SDK does not model, such
+// as lists or deeper nesting, come through intact.
+func policyResourceMap(pol shared.TokenPolicy) map[string]interface{} {
+	var out map[string]interface{}
+	if raw := pol.JSON.Resources.Raw(); raw != "" && json.Unmarshal([]byte(raw), &out) == nil {
+		return out
+	}
+	switch v := pol.Resources.(type) {
 	case shared.TokenPolicyResourcesIAMResourcesTypeObjectString:
-		list := make([]string, 0, len(v))
+		out := make(map[string]interface{}, len(v))
 		for key, value := range v {
-			if value == "" {
+			out[key] = value
+		}
+		return out
+	case shared.TokenPolicyResourcesIAMResourcesTypeObjectNested:
+		out := make(map[string]interface{}, len(v))
+		for key, nested := range v {
+			inner := make(map[string]interface{}, len(nested))
+			for k, value := range nested {
+				inner[k] = value
+			}
+			out[key] = inner
+		}
+		return out
+	default:
+		return nil
+	}
+}
+
+// ResourceStrings flattens resources as TokenPolicyInspection.Resources
+// shows them: key for an empty value, key=value, and prefix.key=value for each scope nested under an
+// account. Any other value is shown as JSON.
+func ResourceStrings(resources map[string]interface{}) []string {
+	list := make([]string, 0, len(resources))
+	for key, value := range resources {
+		switch v := value.(type) {
+		case string:
+			if v == "" {
 				list = append(list, key)
 				continue
 			}
-			list = append(list, fmt.Sprintf("%s=%s", key, value))
-		}
-		return list
-	case shared.TokenPolicyResourcesIAMResourcesTypeObjectNested:
-		list := make([]string, 0)
-		for prefix, nested := range v {
-			if len(nested) == 0 {
-				list = append(list, prefix)
+			list = append(list, fmt.Sprintf("%s=%s", key, v))
+		case map[string]interface{}:
+			if len(v) == 0 {
+				list = append(list, key)
 				continue
 			}
-			for key, value := range nested {
-				list = append(list, fmt.Sprintf("%s.%s=%s", prefix, key, value))
+			for nestedKey, nestedValue := range v {
+				list = append(list, fmt.Sprintf("%s.%s=%s", key, nestedKey, ResourceValueString(nestedValue)))
 			}
+		default:
+			list = append(list, fmt.Sprintf("%s=%s", key, ResourceValueString(v)))
 		}
-		return list
-	default:
-		return nil
 	}
+	return list
+}
+
+// ResourceValueString shows a resource value: a string as is, anything
+// else as JSON.
+func ResourceValueString(value interface{}) string {
+	if s, ok := value.(string); ok {
+		return s
+	}
+	data, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Sprint(value)
+	}
+	return string(data)
 }
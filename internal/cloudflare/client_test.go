@@ -0,0 +1,247 @@
+package cloudflare
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// newTestClient returns a Client wired to a test server running handler.
+func newTestClient(t testing.TB, handler http.HandlerFunc) *Client {
+	t.Helper()
+	srv := httptest.NewServer(handler)
+	t.Cleanup(srv.Close)
+	return NewClient("test-token", WithBaseURL(srv.URL), WithHTTPClient(srv.Client()))
+}
+
+// writeEnvelope writes a Cloudflare v4 API success envelope around result.
+func writeEnvelope(t testing.TB, w http.ResponseWriter, result any) {
+	t.Helper()
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(map[string]any{
+		"success":  true,
+		"errors":   []any{},
+		"messages": []any{},
+		"result":   result,
+	}); err != nil {
+		t.Errorf("encode response: %v", err)
+	}
+}
+
+func TestPermissionGroupsIter(t *testing.T) {
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/user/tokens/permission_groups" {
+			t.Errorf("unexpected path %s", r.URL.Path)
+		}
+		writeEnvelope(t, w, []map[string]any{
+			{"id": "a", "name": "Zone Read", "scopes": []string{"com.cloudflare.api.account.zone"}, "meta": map[string]string{"key": "zone_read"}},
+			{"id": "b", "name": "DNS Write", "description": "Edit DNS records"},
+		})
+	})
+
+	var got []PermissionGroup
+	for group, err := range client.PermissionGroupsIter(context.Background()) {
+		if err != nil {
+			t.Fatalf("PermissionGroupsIter() error = %v", err)
+		}
+		got = append(got, group)
+	}
+
+	if len(got) != 2 {
+		t.Fatalf("PermissionGroupsIter() yielded %d groups, want 2", len(got))
+	}
+	if got[0].Meta.Key != "zone_read" || len(got[0].Scopes) != 1 {
+		t.Errorf("first group = %+v, want meta key and scope", got[0])
+	}
+	if got[1].Description != "Edit DNS records" {
+		t.Errorf("second group description = %q", got[1].Description)
+	}
+}
+
+func TestCreateTokenOptions(t *testing.T) {
+	var body map[string]any
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.URL.Path != "/user/tokens" {
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+		}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			t.Errorf("decode body: %v", err)
+		}
+		writeEnvelope(t, w, map[string]any{"id": "new", "name": "ci", "status": "active", "value": "secret", "expires_on": "2024-01-01T08:00:00Z"})
+	})
+
+	expires := time.Date(2024, 1, 1, 8, 0, 0, 0, time.UTC)
+	notBefore := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	result, err := client.CreateToken(context.Background(), "ci", []Policy{{
+		Effect:           "allow",
+		Resources:        map[string]interface{}{"com.cloudflare.api.account.zone.z": "*"},
+		PermissionGroups: []PolicyPermissionGroup{{ID: "pg"}},
+	}},
+		WithExpiry(expires),
+		WithNotBefore(notBefore),
+		WithAllowedCIDRs("10.0.0.1/32"),
+		WithDeniedCIDRs("10.0.0.2/32"),
+	)
+	if err != nil {
+		t.Fatalf("CreateToken() error = %v", err)
+	}
+	if result.Value != "secret" || result.ExpiresOn != "2024-01-01T08:00:00Z" {
+		t.Errorf("CreateToken() result = %+v", result)
+	}
+	if len(result.AllowedCIDRs) != 1 || result.AllowedCIDRs[0] != "10.0.0.1/32" {
+		t.Errorf("result.AllowedCIDRs = %v", result.AllowedCIDRs)
+	}
+
+	if body["expires_on"] != "2024-01-01T08:00:00Z" || body["not_before"] != "2024-01-01T00:00:00Z" {
+		t.Errorf("request times = %v / %v", body["expires_on"], body["not_before"])
+	}
+	requestIP := body["condition"].(map[string]any)["request_ip"].(map[string]any)
+	if in := requestIP["in"].([]any); len(in) != 1 || in[0] != "10.0.0.1/32" {
+		t.Errorf("condition.request_ip.in = %v", in)
+	}
+	if notIn := requestIP["not_in"].([]any); len(notIn) != 1 || notIn[0] != "10.0.0.2/32" {
+		t.Errorf("condition.request_ip.not_in = %v", notIn)
+	}
+}
+
+func TestCreateTokenWithoutOptions(t *testing.T) {
+	var body map[string]any
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			t.Errorf("decode body: %v", err)
+		}
+		writeEnvelope(t, w, map[string]any{"id": "new", "name": "ci"})
+	})
+
+	if _, err := client.CreateToken(context.Background(), "ci", []Policy{{PermissionGroups: []PolicyPermissionGroup{{ID: "pg"}}}}); err != nil {
+		t.Fatalf("CreateToken() error = %v", err)
+	}
+	for _, key := range []string{"expires_on", "not_before", "condition"} {
+		if _, ok := body[key]; ok {
+			t.Errorf("request unexpectedly contains %q", key)
+		}
+	}
+}
+
+func TestCreateTokenRetry(t *testing.T) {
+	tests := []struct {
+		name        string
+		tokenName   string
+		unique      bool
+		status      int // of the first create
+		wantPosts   int
+		wantLists   bool
+		wantDeleted []string
+		wantErr     bool
+	}{
+		{"lost response", "ci-3f9a0c1d", true, http.StatusBadGateway, 2, true, []string{"lost"}, false},
+		// "other" is a concurrent run's live token of the same name.
+		{"lost response, shared name", "ci", false, http.StatusBadGateway, 1, false, nil, true},
+		{"rate limited", "ci", false, http.StatusTooManyRequests, 2, false, nil, false},
+		{"rejected", "ci-3f9a0c1d", true, http.StatusBadRequest, 1, false, nil, true},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			var posts int
+			var listed bool
+			var deleted []string
+			client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+				switch {
+				case r.Method == http.MethodPost:
+					posts++
+					if posts == 1 {
+						w.WriteHeader(tc.status)
+						writeEnvelope(t, w, nil)
+						return
+					}
+					writeEnvelope(t, w, map[string]any{"id": "new", "name": tc.tokenName, "value": "secret"})
+				case r.Method == http.MethodGet && r.URL.Query().Get("page") == "2":
+					writeEnvelope(t, w, []any{})
+				case r.Method == http.MethodGet:
+					listed = true
+					writeEnvelope(t, w, []map[string]any{
+						{"id": "lost", "name": "ci-3f9a0c1d", "issued_on": time.Now().UTC().Format(time.RFC3339)},
+						{"id": "other", "name": "ci", "issued_on": time.Now().UTC().Format(time.RFC3339)},
+						{"id": "old", "name": "ci-2", "issued_on": "2020-01-01T00:00:00Z"},
+					})
+				case r.Method == http.MethodDelete:
+					deleted = append(deleted, strings.TrimPrefix(r.URL.Path, "/user/tokens/"))
+					writeEnvelope(t, w, map[string]any{"id": "lost"})
+				}
+			})
+			client.retryDelay = 0
+
+			var opts []CreateOption
+			if tc.unique {
+				opts = append(opts, WithUniqueName())
+			}
+			result, err := client.CreateToken(context.Background(), tc.tokenName, []Policy{{PermissionGroups: []PolicyPermissionGroup{{ID: "pg"}}}}, opts...)
+			if tc.wantErr != (err != nil) {
+				t.Fatalf("CreateToken() error = %v, want error %v", err, tc.wantErr)
+			}
+			if err == nil && result.Value != "secret" {
+				t.Errorf("CreateToken() result = %+v", result)
+			}
+			if tc.status >= 500 && err != nil && !strings.Contains(err.Error(), "may have been created anyway") {
+				t.Errorf("CreateToken() error = %v, want a warning that the token may exist", err)
+			}
+			if posts != tc.wantPosts || listed != tc.wantLists || !reflect.DeepEqual(deleted, tc.wantDeleted) {
+				t.Errorf("CreateToken() made %d creates, listed %v, and deleted %v; want %d, %v, and %v", posts, listed, deleted, tc.wantPosts, tc.wantLists, tc.wantDeleted)
+			}
+		})
+	}
+}
+
+func BenchmarkMatchPermissionGroups(b *testing.B) {
+	groups := make([]PermissionGroup, 500)
+	for i := range groups {
+		groups[i] = PermissionGroup{
+			ID:   fmt.Sprintf("%032x", i),
+			Name: fmt.Sprintf("Group %d Write", i),
+			Meta: PermissionGroupMeta{Key: fmt.Sprintf("group_%d_write", i)},
+		}
+	}
+	inputs := []string{"Group 499 Write", "group_250_write", groups[10].ID}
+
+	b.ReportAllocs()
+	for b.Loop() {
+		if _, _, err := matchPermissionGroups(groups, inputs); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func TestBuildTokenParamsResources(t *testing.T) {
+	policies := []Policy{
+		{PermissionGroups: []PolicyPermissionGroup{{ID: "g1"}}, Resources: map[string]any{"com.cloudflare.api.account.zone.z1": "*"}},
+		{PermissionGroups: []PolicyPermissionGroup{{ID: "g2"}}, Resources: map[string]any{
+			"com.cloudflare.api.account.a1": map[string]any{"com.cloudflare.api.account.zone.*": "*"},
+			"com.cloudflare.api.account.zone.z2": map[string]any{
+				"com.cloudflare.api.dns.record.*": []any{"r1", map[string]any{"id": "r2", "weight": 2.5}},
+			},
+		}},
+	}
+	params, err := buildTokenParamsFromPolicies("ci", policies, createSettings{})
+	if err != nil {
+		t.Fatalf("buildTokenParamsFromPolicies() error = %v", err)
+	}
+	body, err := params.MarshalJSON()
+	if err != nil {
+		t.Fatalf("MarshalJSON() error = %v", err)
+	}
+	for _, want := range []string{
+		`"resources":{"com.cloudflare.api.account.zone.z1":"*"}`,
+		`"com.cloudflare.api.account.a1":{"com.cloudflare.api.account.zone.*":"*"}`,
+		`"com.cloudflare.api.dns.record.*":["r1",{"id":"r2","weight":2.5}]`,
+	} {
+		if !strings.Contains(string(body), want) {
+			t.Errorf("body = %s, want it to contain %s", body, want)
+		}
+	}
+}
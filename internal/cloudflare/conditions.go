@@ -0,0 +1,144 @@
+package cloudflare
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/netip"
+	"slices"
+	"strings"
+)
+
+// Condition restricts where a token is accepted: a request must satisfy
+// Operator against Values, e.g. request_ip in 10.0.0.0/8. Type and Operator
+// are the keys of the API's condition object.
+type Condition struct {
+	Type     string
+	Operator string
+	Values   []string
+}
+
+// conditionType is a condition type the API accepts. Supporting a new
+// type Cloudflare ships takes one entry in conditionTypes; creation,
+// updates, templates, and -condition all go through it.
+type conditionType struct {
+	operators []string
+	// validate checks a single value.
+	validate func(string) error
+}
+
+var conditionTypes = map[string]conditionType{
+	"request_ip": {operators: []string{"in", "not_in"}, validate: validateIPRange},
+}
+
+// ConditionKeys lists every supported condition as "type.operator".
+func ConditionKeys() []string {
+	var keys []string
+	for name, ct := range conditionTypes {
+		for _, op := range ct.operators {
+			keys = append(keys, name+"."+op)
+		}
+	}
+	slices.Sort(keys)
+	return keys
+}
+
+// ParseCondition parses "type.operator=value,value", for example
+// "request_ip.not_in=192.0.2.0/24,198.51.100.0/24".
+func ParseCondition(s string) (Condition, error) {
+	key, values, ok := strings.Cut(s, "=")
+	typ, op, dotted := strings.Cut(strings.TrimSpace(key), ".")
+	if !ok || !dotted {
+		return Condition{}, fmt.Errorf("condition %q: want type.operator=value[,value...], e.g. request_ip.not_in=192.0.2.0/24", s)
+	}
+	c := Condition{Type: typ, Operator: op}
+	for _, v := range strings.Split(values, ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			c.Values = append(c.Values, v)
+		}
+	}
+	return c, c.Validate()
+}
+
+// Validate checks that the type and operator are supported and that every
+// value is one the type accepts.
+func (c Condition) Validate() error {
+	ct, ok := conditionTypes[c.Type]
+	if !ok || !slices.Contains(ct.operators, c.Operator) {
+		return fmt.Errorf("unsupported condition %s.%s; available: %s", c.Type, c.Operator, strings.Join(ConditionKeys(), ", "))
+	}
+	if len(c.Values) == 0 {
+		return fmt.Errorf("condition %s.%s: no values", c.Type, c.Operator)
+	}
+	for _, v := range c.Values {
+		if err := ct.validate(v); err != nil {
+			return fmt.Errorf("condition %s.%s: %w", c.Type, c.Operator, err)
+		}
+	}
+	return nil
+}
+
+func validateIPRange(s string) error {
+	if _, err := netip.ParsePrefix(s); err == nil {
+		return nil
+	}
+	if _, err := netip.ParseAddr(s); err == nil {
+		return nil
+	}
+	return fmt.Errorf("invalid IP address or CIDR %q", s)
+}
+
+// WithCondition adds c to the new token's conditions. Values given for the
+// same type and operator accumulate; on update they replace the token's.
+func WithCondition(c Condition) CreateOption {
+	return func(s *createSettings) {
+		s.conditions = append(s.conditions, c)
+	}
+}
+
+// conditionValues returns every value given for typ and op.
+func (s createSettings) conditionValues(typ, op string) []string {
+	var out []string
+	for _, c := range s.conditions {
+		if c.Type == typ && c.Operator == op {
+			out = append(out, c.Values...)
+		}
+	}
+	return out
+}
+
+// conditionObject is the API's condition object, keyed by type and then
+// operator.
+type conditionObject map[string]map[string][]string
+
+// parseConditionObject reads the condition object of a fetched token, so
+// an update sends back types cftoken has no entry for unchanged.
+func parseConditionObject(raw string) (conditionObject, error) {
+	obj := conditionObject{}
+	if raw = strings.TrimSpace(raw); raw == "" || raw == "null" {
+		return obj, nil
+	}
+	if err := json.Unmarshal([]byte(raw), &obj); err != nil {
+		return nil, fmt.Errorf("read token condition: %w", err)
+	}
+	return obj, nil
+}
+
+// apply validates conditions and sets each type and operator they name
+// to their values, replacing what obj held for it.
+func (obj conditionObject) apply(conditions []Condition) error {
+	replaced := map[string]bool{}
+	for _, c := range conditions {
+		if err := c.Validate(); err != nil {
+			return err
+		}
+		if obj[c.Type] == nil {
+			obj[c.Type] = map[string][]string{}
+		}
+		if key := c.Type + "." + c.Operator; !replaced[key] {
+			obj[c.Type][c.Operator] = nil
+			replaced[key] = true
+		}
+		obj[c.Type][c.Operator] = append(obj[c.Type][c.Operator], c.Values...)
+	}
+	return nil
+}
@@ -0,0 +1,138 @@
+package cloudflare
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseCondition(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    Condition
+		wantErr string
+	}{
+		{in: "request_ip.not_in=192.0.2.0/24, 198.51.100.7", want: Condition{Type: "request_ip", Operator: "not_in", Values: []string{"192.0.2.0/24", "198.51.100.7"}}},
+		{in: "request_ip.in=2001:db8::/32", want: Condition{Type: "request_ip", Operator: "in", Values: []string{"2001:db8::/32"}}},
+		{in: "request_ip=10.0.0.0/8", wantErr: "want type.operator="},
+		{in: "request_ip.not_in", wantErr: "want type.operator="},
+		{in: "request_ip.between=10.0.0.0/8", wantErr: "available: request_ip.in, request_ip.not_in"},
+		{in: "request_asn.in=13335", wantErr: "unsupported condition request_asn.in"},
+		{in: "request_ip.not_in=", wantErr: "no values"},
+		{in: "request_ip.not_in=10.0.0.0/33", wantErr: `invalid IP address or CIDR "10.0.0.0/33"`},
+	}
+	for _, tc := range tests {
+		got, err := ParseCondition(tc.in)
+		if tc.wantErr != "" {
+			if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+				t.Errorf("ParseCondition(%q) error = %v, want %q", tc.in, err, tc.wantErr)
+			}
+			continue
+		}
+		if err != nil || !reflect.DeepEqual(got, tc.want) {
+			t.Errorf("ParseCondition(%q) = %+v, %v, want %+v", tc.in, got, err, tc.want)
+		}
+	}
+}
+
+// TestConditionRegistry checks that a type added to conditionTypes is
+// accepted and sent without further changes.
+func TestConditionRegistry(t *testing.T) {
+	conditionTypes["request_asn"] = conditionType{operators: []string{"in"}, validate: func(string) error { return nil }}
+	t.Cleanup(func() { delete(conditionTypes, "request_asn") })
+
+	c, err := ParseCondition("request_asn.in=13335")
+	if err != nil {
+		t.Fatalf("ParseCondition() error = %v", err)
+	}
+	var settings createSettings
+	for _, opt := range []CreateOption{WithAllowedCIDRs("10.0.0.0/8"), WithAllowedCIDRs(), WithCondition(c)} {
+		opt(&settings)
+	}
+	params, err := buildTokenParamsFromPolicies("ci", []Policy{{PermissionGroups: []PolicyPermissionGroup{{ID: "g1"}}, Resources: map[string]any{"com.cloudflare.api.account.zone.z1": "*"}}}, settings)
+	if err != nil {
+		t.Fatalf("buildTokenParamsFromPolicies() error = %v", err)
+	}
+	body, err := params.MarshalJSON()
+	if err != nil {
+		t.Fatalf("MarshalJSON() error = %v", err)
+	}
+	if want := `"condition":{"request_asn":{"in":["13335"]},"request_ip":{"in":["10.0.0.0/8"]}}`; !strings.Contains(string(body), want) {
+		t.Errorf("body = %s, want it to contain %s", body, want)
+	}
+}
+
+func TestUpdateTokenKeepsUnknownConditions(t *testing.T) {
+	token := map[string]any{
+		"id": "t1", "name": "ci", "status": "active",
+		"condition": map[string]any{
+			"request_ip":  map[string]any{"in": []string{"10.0.0.0/8"}, "not_in": []string{"10.1.0.0/16"}},
+			"request_asn": map[string]any{"in": []string{"13335"}},
+		},
+		"policies": []map[string]any{{
+			"id": "p1", "effect": "allow",
+			"resources":         map[string]any{"com.cloudflare.api.account.zone.z1": "*"},
+			"permission_groups": []map[string]any{{"id": "g1"}},
+		}},
+	}
+	var sent struct {
+		Condition conditionObject `json:"condition"`
+	}
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPut {
+			if err := json.NewDecoder(r.Body).Decode(&sent); err != nil {
+				t.Errorf("decode body: %v", err)
+			}
+		}
+		writeEnvelope(t, w, token)
+	})
+
+	if _, err := client.UpdateToken(context.Background(), "t1", WithDeniedCIDRs("192.0.2.0/24")); err != nil {
+		t.Fatalf("UpdateToken() error = %v", err)
+	}
+	want := conditionObject{
+		"request_ip":  {"in": {"10.0.0.0/8"}, "not_in": {"192.0.2.0/24"}},
+		"request_asn": {"in": {"13335"}},
+	}
+	if !reflect.DeepEqual(sent.Condition, want) {
+		t.Errorf("sent condition %v, want %v", sent.Condition, want)
+	}
+}
+
+// FuzzParseCondition checks -condition values: an accepted condition is
+// valid, keeps only trimmed, non-empty values, and survives a round trip
+// through the condition object the API receives.
+func FuzzParseCondition(f *testing.F) {
+	for _, seed := range []string{"request_ip.not_in=192.0.2.0/24, 198.51.100.7", "request_ip.in=2001:db8::/32", "request_ip=10.0.0.0/8", "a.b.c=d", "request_ip.not_in=,,", "=", ""} {
+		f.Add(seed)
+	}
+	f.Fuzz(func(t *testing.T, input string) {
+		c, err := ParseCondition(input)
+		if err != nil {
+			return
+		}
+		if err := c.Validate(); err != nil {
+			t.Fatalf("ParseCondition(%q) = %+v, which does not validate: %v", input, c, err)
+		}
+		for _, v := range c.Values {
+			if v == "" || v != strings.TrimSpace(v) {
+				t.Fatalf("ParseCondition(%q) kept value %q", input, v)
+			}
+		}
+		obj := conditionObject{}
+		if err := obj.apply([]Condition{c}); err != nil {
+			t.Fatalf("apply(%+v) error = %v", c, err)
+		}
+		data, err := json.Marshal(obj)
+		if err != nil {
+			t.Fatalf("Marshal() error = %v", err)
+		}
+		back, err := parseConditionObject(string(data))
+		if err != nil || !reflect.DeepEqual(back, obj) {
+			t.Fatalf("round trip of %s = %v, %v", data, back, err)
+		}
+	})
+}
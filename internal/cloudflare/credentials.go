@@ -0,0 +1,38 @@
+package cloudflare
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"cftoken/internal/httpmw"
+)
+
+// CredentialProvider supplies the management API token. The implementations
+// in internal/credential cover environment variables, files, keyrings, Vault,
+// and helper commands; embedders can provide their own.
+type CredentialProvider interface {
+	Token(ctx context.Context) (string, error)
+}
+
+// WithCredentialProvider makes the client ask p for the API token on every
+// request instead of using the token given to NewClient, so rotated
+// credentials are picked up without rebuilding the client. Wrap slow
+// providers with credential.Cached.
+func WithCredentialProvider(p CredentialProvider) Option {
+	return func(c *Client) {
+		c.credentials = p
+	}
+}
+
+// credentialMiddleware sets the Authorization header from the provider.
+func credentialMiddleware(p CredentialProvider) httpmw.Middleware {
+	return func(req *http.Request, next httpmw.Next) (*http.Response, error) {
+		token, err := p.Token(req.Context())
+		if err != nil {
+			return nil, fmt.Errorf("resolve API token: %w", err)
+		}
+		req.Header.Set("Authorization", "Bearer "+token)
+		return next(req)
+	}
+}
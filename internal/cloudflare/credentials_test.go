@@ -0,0 +1,38 @@
+package cloudflare
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+type rotatingProvider struct{ tokens []string }
+
+func (p *rotatingProvider) Token(context.Context) (string, error) {
+	token := p.tokens[0]
+	if len(p.tokens) > 1 {
+		p.tokens = p.tokens[1:]
+	}
+	return token, nil
+}
+
+func TestWithCredentialProvider(t *testing.T) {
+	var got []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = append(got, r.Header.Get("Authorization"))
+		writeEnvelope(t, w, map[string]any{"id": "tok", "status": "active"})
+	}))
+	t.Cleanup(srv.Close)
+	client := NewClient("", WithBaseURL(srv.URL), WithHTTPClient(srv.Client()),
+		WithCredentialProvider(&rotatingProvider{tokens: []string{"first", "second"}}))
+
+	for range 2 {
+		if _, err := client.VerifyToken(context.Background()); err != nil {
+			t.Fatalf("VerifyToken() error = %v", err)
+		}
+	}
+	if len(got) != 2 || got[0] != "Bearer first" || got[1] != "Bearer second" {
+		t.Errorf("Authorization headers = %v, want the provider's token per request", got)
+	}
+}
@@ -0,0 +1,43 @@
+package cloudflare
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	cf "github.com/cloudflare/cloudflare-go/v6"
+	"github.com/cloudflare/cloudflare-go/v6/dns"
+)
+
+// CreateTXTRecord creates a TXT record with automatic TTL and returns its ID.
+// name must be fully qualified, including the zone name.
+func (c *Client) CreateTXTRecord(ctx context.Context, zoneID, name, content string) (string, error) {
+	if err := c.checkWritable("create DNS record"); err != nil {
+		return "", err
+	}
+	record, err := c.api.DNS.Records.New(ctx, dns.RecordNewParams{
+		ZoneID: cf.F(zoneID),
+		Body: dns.TXTRecordParam{
+			Name:    cf.F(name),
+			Type:    cf.F(dns.TXTRecordTypeTXT),
+			TTL:     cf.F(dns.TTL1),
+			Content: cf.F(strconv.Quote(content)),
+			Comment: cf.F("created by cftoken"),
+		},
+	})
+	if err != nil {
+		return "", fmt.Errorf("create TXT record %s: %w", name, err)
+	}
+	return record.ID, nil
+}
+
+// DeleteDNSRecord deletes a DNS record from the zone.
+func (c *Client) DeleteDNSRecord(ctx context.Context, zoneID, recordID string) error {
+	if err := c.checkWritable("delete DNS record"); err != nil {
+		return err
+	}
+	if _, err := c.api.DNS.Records.Delete(ctx, recordID, dns.RecordDeleteParams{ZoneID: cf.F(zoneID)}); err != nil {
+		return fmt.Errorf("delete DNS record %s: %w", recordID, err)
+	}
+	return nil
+}
@@ -0,0 +1,46 @@
+package cloudflare
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+)
+
+func TestTXTRecordLifecycle(t *testing.T) {
+	var created map[string]any
+	var deletedPath string
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodPost:
+			if r.URL.Path != "/zones/z1/dns_records" {
+				t.Errorf("POST path = %s", r.URL.Path)
+			}
+			if err := json.NewDecoder(r.Body).Decode(&created); err != nil {
+				t.Errorf("decode body: %v", err)
+			}
+			writeEnvelope(t, w, map[string]any{"id": "rec-1", "type": "TXT", "name": created["name"]})
+		case http.MethodDelete:
+			deletedPath = r.URL.Path
+			writeEnvelope(t, w, map[string]any{"id": "rec-1"})
+		}
+	})
+
+	id, err := client.CreateTXTRecord(context.Background(), "z1", "_cftoken-canary.example.com", "probe")
+	if err != nil {
+		t.Fatalf("CreateTXTRecord() error = %v", err)
+	}
+	if id != "rec-1" {
+		t.Fatalf("CreateTXTRecord() = %q, want rec-1", id)
+	}
+	if created["type"] != "TXT" || created["name"] != "_cftoken-canary.example.com" || created["content"] != `"probe"` {
+		t.Fatalf("unexpected request body: %v", created)
+	}
+
+	if err := client.DeleteDNSRecord(context.Background(), "z1", "rec-1"); err != nil {
+		t.Fatalf("DeleteDNSRecord() error = %v", err)
+	}
+	if deletedPath != "/zones/z1/dns_records/rec-1" {
+		t.Fatalf("DeleteDNSRecord() hit %q", deletedPath)
+	}
+}
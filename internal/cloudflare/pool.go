@@ -0,0 +1,37 @@
+package cloudflare
+
+import "sync"
+
+// Pool hands out one shared Client per API token so a long-running service
+// can reuse clients, and their caches, across goroutines.
+type Pool struct {
+	opts []Option
+
+	mu      sync.Mutex
+	clients map[string]*Client
+}
+
+// NewPool returns a Pool whose clients are built with opts.
+func NewPool(opts ...Option) *Pool {
+	return &Pool{opts: opts, clients: make(map[string]*Client)}
+}
+
+// Get returns the Client for token, creating it on first use.
+func (p *Pool) Get(token string) *Client {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if c, ok := p.clients[token]; ok {
+		return c
+	}
+	c := NewClient(token, p.opts...)
+	p.clients[token] = c
+	return c
+}
+
+// Forget drops the Client for token, for example after the token is rotated
+// or revoked. Callers still holding it may keep using it.
+func (p *Pool) Forget(token string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	delete(p.clients, token)
+}
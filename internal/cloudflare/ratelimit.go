@@ -0,0 +1,110 @@
+package cloudflare
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"cftoken/internal/httpmw"
+)
+
+// RateLimit is the API request budget Cloudflare reported on a response.
+// Limit and Window are zero when the response carried no policy.
+type RateLimit struct {
+	Policy    string
+	Limit     int
+	Window    time.Duration
+	Remaining int
+	Reset     time.Duration
+	At        time.Time
+}
+
+func (r RateLimit) String() string {
+	s := fmt.Sprintf("%d requests left", r.Remaining)
+	if r.Limit > 0 {
+		s = fmt.Sprintf("%d of %d requests left", r.Remaining, r.Limit)
+	}
+	return s + fmt.Sprintf(", resets in %s", r.Reset)
+}
+
+type rateLimitState struct {
+	mu   sync.Mutex
+	last RateLimit
+	ok   bool
+}
+
+// RateLimit returns the budget reported on the most recent response that
+// carried one, and false when none has yet.
+func (c *Client) RateLimit() (RateLimit, bool) {
+	c.rateLimit.mu.Lock()
+	defer c.rateLimit.mu.Unlock()
+	return c.rateLimit.last, c.rateLimit.ok
+}
+
+// rateLimitMiddleware records the rate-limit headers of every response and
+// logs them with the request ID.
+func rateLimitMiddleware(c *Client) httpmw.Middleware {
+	return func(req *http.Request, next httpmw.Next) (*http.Response, error) {
+		resp, err := next(req)
+		if err != nil {
+			return resp, err
+		}
+		if rl, ok := parseRateLimit(resp.Header, c.now()); ok {
+			c.rateLimit.mu.Lock()
+			c.rateLimit.last, c.rateLimit.ok = rl, true
+			c.rateLimit.mu.Unlock()
+			if c.logf != nil {
+				c.logf("[%s] cloudflare rate limit: %s", httpmw.RequestID(req.Context()), rl)
+			}
+		}
+		return resp, nil
+	}
+}
+
+// parseRateLimit reads the Ratelimit and Ratelimit-Policy headers, as in
+// `"default";r=50;t=30` and `"default";q=1200;w=300`.
+func parseRateLimit(h http.Header, now time.Time) (RateLimit, bool) {
+	name, params, ok := parseRateLimitField(h.Get("Ratelimit"))
+	if !ok {
+		return RateLimit{}, false
+	}
+	remaining, ok := params["r"]
+	if !ok {
+		return RateLimit{}, false
+	}
+	rl := RateLimit{
+		Policy:    name,
+		Remaining: remaining,
+		Reset:     time.Duration(params["t"]) * time.Second,
+		At:        now,
+	}
+	if _, policy, ok := parseRateLimitField(h.Get("Ratelimit-Policy")); ok {
+		rl.Limit = policy["q"]
+		rl.Window = time.Duration(policy["w"]) * time.Second
+	}
+	return rl, true
+}
+
+// parseRateLimitField parses the first item of a structured rate-limit
+// header into its name and integer parameters.
+func parseRateLimitField(v string) (string, map[string]int, bool) {
+	item, _, _ := strings.Cut(v, ",")
+	parts := strings.Split(item, ";")
+	name := strings.Trim(strings.TrimSpace(parts[0]), `"`)
+	if name == "" || len(parts) < 2 {
+		return "", nil, false
+	}
+	params := make(map[string]int, len(parts)-1)
+	for _, p := range parts[1:] {
+		k, v, _ := strings.Cut(strings.TrimSpace(p), "=")
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			continue
+		}
+		params[k] = n
+	}
+	return name, params, true
+}
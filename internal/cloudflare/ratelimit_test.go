@@ -0,0 +1,58 @@
+package cloudflare
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestParseRateLimit(t *testing.T) {
+	now := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	tests := []struct {
+		name      string
+		ratelimit string
+		policy    string
+		want      RateLimit
+		ok        bool
+	}{
+		{"with policy", `"default";r=50;t=30`, `"default";q=1200;w=300`, RateLimit{Policy: "default", Limit: 1200, Window: 5 * time.Minute, Remaining: 50, Reset: 30 * time.Second, At: now}, true},
+		{"without policy", `"burst";r=3;t=1`, "", RateLimit{Policy: "burst", Remaining: 3, Reset: time.Second, At: now}, true},
+		{"first of several", `"a";r=1;t=2, "b";r=9;t=9`, "", RateLimit{Policy: "a", Remaining: 1, Reset: 2 * time.Second, At: now}, true},
+		{"absent", "", "", RateLimit{}, false},
+		{"no remaining", `"default";t=30`, "", RateLimit{}, false},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			h := http.Header{}
+			if tc.ratelimit != "" {
+				h.Set("Ratelimit", tc.ratelimit)
+			}
+			if tc.policy != "" {
+				h.Set("Ratelimit-Policy", tc.policy)
+			}
+			got, ok := parseRateLimit(h, now)
+			if ok != tc.ok || got != tc.want {
+				t.Errorf("parseRateLimit() = %+v, %v, want %+v, %v", got, ok, tc.want, tc.ok)
+			}
+		})
+	}
+}
+
+func TestClientRateLimit(t *testing.T) {
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Ratelimit", `"default";r=1199;t=300`)
+		w.Header().Set("Ratelimit-Policy", `"default";q=1200;w=300`)
+		writeEnvelope(t, w, map[string]any{"id": "tok", "status": "active"})
+	})
+	if _, ok := client.RateLimit(); ok {
+		t.Fatalf("RateLimit() reported a budget before any request")
+	}
+	if _, err := client.VerifyToken(context.Background()); err != nil {
+		t.Fatalf("VerifyToken() error = %v", err)
+	}
+	rl, ok := client.RateLimit()
+	if !ok || rl.Remaining != 1199 || rl.Limit != 1200 {
+		t.Errorf("RateLimit() = %+v, %v", rl, ok)
+	}
+}
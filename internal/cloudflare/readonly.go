@@ -0,0 +1,41 @@
+package cloudflare
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"cftoken/internal/httpmw"
+)
+
+// ErrReadOnly is returned for any call that would change Cloudflare state on
+// a read-only Client.
+var ErrReadOnly = errors.New("read-only mode: refusing to change Cloudflare state")
+
+// WithReadOnly makes the client refuse every mutating call. Mutating methods
+// fail before contacting the API, and any other non-GET request is stopped in
+// the transport, so a read-only binary is safe to hand to auditors.
+func WithReadOnly() Option {
+	return func(c *Client) {
+		c.readOnly = true
+	}
+}
+
+// checkWritable returns ErrReadOnly, naming the operation, on a read-only client.
+func (c *Client) checkWritable(operation string) error {
+	if c.readOnly {
+		return fmt.Errorf("%s: %w", operation, ErrReadOnly)
+	}
+	return nil
+}
+
+// readOnlyMiddleware rejects requests that are not plain reads.
+func readOnlyMiddleware() httpmw.Middleware {
+	return func(req *http.Request, next httpmw.Next) (*http.Response, error) {
+		switch req.Method {
+		case http.MethodGet, http.MethodHead, http.MethodOptions:
+			return next(req)
+		}
+		return nil, fmt.Errorf("%s %s: %w", req.Method, req.URL.Path, ErrReadOnly)
+	}
+}
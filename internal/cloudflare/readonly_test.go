@@ -0,0 +1,66 @@
+package cloudflare
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestReadOnlyClient(t *testing.T) {
+	var methods []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		methods = append(methods, r.Method)
+		writeEnvelope(t, w, map[string]any{"id": "tok", "status": "active"})
+	}))
+	t.Cleanup(srv.Close)
+	client := NewClient("test-token", WithBaseURL(srv.URL), WithHTTPClient(srv.Client()), WithReadOnly())
+	ctx := context.Background()
+
+	if _, err := client.CreateToken(ctx, "name", nil); !errors.Is(err, ErrReadOnly) {
+		t.Errorf("CreateToken() error = %v, want ErrReadOnly", err)
+	}
+	if err := client.DeleteToken(ctx, "tok"); !errors.Is(err, ErrReadOnly) {
+		t.Errorf("DeleteToken() error = %v, want ErrReadOnly", err)
+	}
+	if _, err := client.CreateTXTRecord(ctx, "zone", "name", "value"); !errors.Is(err, ErrReadOnly) {
+		t.Errorf("CreateTXTRecord() error = %v, want ErrReadOnly", err)
+	}
+	if err := client.DeleteDNSRecord(ctx, "zone", "rec"); !errors.Is(err, ErrReadOnly) {
+		t.Errorf("DeleteDNSRecord() error = %v, want ErrReadOnly", err)
+	}
+	if _, err := client.VerifyToken(ctx); err != nil {
+		t.Errorf("VerifyToken() error = %v, want reads allowed", err)
+	}
+	if len(methods) != 1 || methods[0] != http.MethodGet {
+		t.Errorf("server saw %v, want a single GET", methods)
+	}
+}
+
+func TestReadOnlyMiddleware(t *testing.T) {
+	t.Parallel()
+
+	mw := readOnlyMiddleware()
+	next := func(*http.Request) (*http.Response, error) {
+		return &http.Response{StatusCode: http.StatusOK}, nil
+	}
+	tests := []struct {
+		method  string
+		wantErr bool
+	}{
+		{http.MethodGet, false},
+		{http.MethodHead, false},
+		{http.MethodPost, true},
+		{http.MethodPut, true},
+		{http.MethodPatch, true},
+		{http.MethodDelete, true},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(tt.method, "https://api.cloudflare.com/client/v4/user/tokens", nil)
+		_, err := mw(req, next)
+		if gotErr := errors.Is(err, ErrReadOnly); gotErr != tt.wantErr {
+			t.Errorf("%s: error = %v, want read-only rejection %v", tt.method, err, tt.wantErr)
+		}
+	}
+}
@@ -0,0 +1,62 @@
+package cloudflare
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"strings"
+
+	cf "github.com/cloudflare/cloudflare-go/v6"
+	"github.com/cloudflare/cloudflare-go/v6/iam"
+)
+
+// ResourceGroup is a curated set of account resources. Resources holds its
+// scopes as flat policy resources: the objects of each scope, or the scope
+// itself when it lists none, mapped to "*".
+type ResourceGroup struct {
+	ID        string
+	Name      string
+	Resources map[string]interface{}
+}
+
+// FindResourceGroup returns the resource group of accountID whose ID is ref,
+// or whose name matches ref case-insensitively. It returns fs.ErrNotExist
+// when there is none.
+func (c *Client) FindResourceGroup(ctx context.Context, accountID, ref string) (*ResourceGroup, error) {
+	ref = strings.TrimSpace(ref)
+	if ref == "" {
+		return nil, errors.New("resource group name is required")
+	}
+	var byName []*ResourceGroup
+	pager := c.api.IAM.ResourceGroups.ListAutoPaging(ctx, iam.ResourceGroupListParams{AccountID: cf.F(accountID)})
+	for pager.Next() {
+		g := pager.Current()
+		group := &ResourceGroup{ID: g.ID, Name: g.Name, Resources: make(map[string]interface{}, len(g.Scope))}
+		for _, scope := range g.Scope {
+			if len(scope.Objects) == 0 {
+				group.Resources[scope.Key] = "*"
+			}
+			for _, obj := range scope.Objects {
+				group.Resources[obj.Key] = "*"
+			}
+		}
+		if g.ID == ref {
+			return group, nil
+		}
+		if strings.EqualFold(g.Name, ref) {
+			byName = append(byName, group)
+		}
+	}
+	if err := pager.Err(); err != nil {
+		return nil, fmt.Errorf("list resource groups: %w", err)
+	}
+	switch len(byName) {
+	case 0:
+		return nil, fmt.Errorf("resource group %q not found in account %s: %w", ref, accountID, fs.ErrNotExist)
+	case 1:
+		return byName[0], nil
+	default:
+		return nil, fmt.Errorf("%d resource groups are named %q; use the ID instead", len(byName), ref)
+	}
+}
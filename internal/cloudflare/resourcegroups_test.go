@@ -0,0 +1,63 @@
+package cloudflare
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestFindResourceGroup(t *testing.T) {
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/accounts/acc-1/iam/resource_groups" {
+			t.Errorf("path = %s, want /accounts/acc-1/iam/resource_groups", r.URL.Path)
+		}
+		writeEnvelope(t, w, []map[string]any{
+			{"id": "rg-1", "name": "Web zones", "scope": []map[string]any{{
+				"key":     "com.cloudflare.api.account.acc-1",
+				"objects": []map[string]string{{"key": "com.cloudflare.api.account.zone.z1"}, {"key": "com.cloudflare.api.account.zone.z2"}},
+			}}},
+			{"id": "rg-2", "name": "Account", "scope": []map[string]any{{"key": "com.cloudflare.api.account.acc-1", "objects": []any{}}}},
+			{"id": "rg-3", "name": "Dup", "scope": []any{}},
+			{"id": "rg-4", "name": "dup", "scope": []any{}},
+		})
+	})
+
+	tests := []struct {
+		ref     string
+		wantID  string
+		want    map[string]interface{}
+		wantErr string
+	}{
+		{"web ZONES", "rg-1", map[string]interface{}{
+			"com.cloudflare.api.account.zone.z1": "*",
+			"com.cloudflare.api.account.zone.z2": "*",
+		}, ""},
+		{"rg-2", "rg-2", map[string]interface{}{"com.cloudflare.api.account.acc-1": "*"}, ""},
+		{"dup", "", nil, "2 resource groups"},
+		{"missing", "", nil, "not found"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.ref, func(t *testing.T) {
+			got, err := client.FindResourceGroup(context.Background(), "acc-1", tc.ref)
+			if tc.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+					t.Fatalf("FindResourceGroup() error = %v, want %q", err, tc.wantErr)
+				}
+				if tc.ref == "missing" && !errors.Is(err, fs.ErrNotExist) {
+					t.Errorf("FindResourceGroup() error = %v, want fs.ErrNotExist", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("FindResourceGroup() error = %v", err)
+			}
+			if got.ID != tc.wantID || !reflect.DeepEqual(got.Resources, tc.want) {
+				t.Errorf("FindResourceGroup() = %+v", got)
+			}
+		})
+	}
+}
@@ -0,0 +1,268 @@
+package cloudflare
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"iter"
+	"strings"
+	"time"
+
+	cf "github.com/cloudflare/cloudflare-go/v6"
+	cfaccounts "github.com/cloudflare/cloudflare-go/v6/accounts"
+	cfoption "github.com/cloudflare/cloudflare-go/v6/option"
+	"github.com/cloudflare/cloudflare-go/v6/packages/pagination"
+	"github.com/cloudflare/cloudflare-go/v6/shared"
+	cfuser "github.com/cloudflare/cloudflare-go/v6/user"
+)
+
+// tokensPerPage is the largest page size the token list endpoint accepts.
+const tokensPerPage = 50
+
+// Token summarises an API token as returned by the list endpoint.
+type Token struct {
+	ID           string
+	Name         string
+	Status       string
+	IssuedOn     time.Time
+	ModifiedOn   time.Time
+	LastUsedOn   time.Time
+	ExpiresOn    time.Time
+	NotBefore    time.Time
+	AllowedCIDRs []string
+	DeniedCIDRs  []string
+	Policies     []TokenPolicyInspection
+}
+
+// WithTokenAccount makes the client create, list, get, verify, and delete
+// the API tokens owned by accountID (accounts/:id/tokens) instead of those
+// of the user. Service users that belong to several accounts use it to pick
+// the token store.
+func WithTokenAccount(accountID string) Option {
+	return func(c *Client) {
+		c.tokenAccount = strings.TrimSpace(accountID)
+	}
+}
+
+// TokenAccount returns the account whose tokens the client manages, or ""
+// for the user's own tokens.
+func (c *Client) TokenAccount() string {
+	return c.tokenAccount
+}
+
+// Tokens streams every API token owned by the current user, or by the
+// account given to WithTokenAccount, fetching pages lazily. Iteration stops
+// at the first error, which is yielded last.
+func (c *Client) Tokens(ctx context.Context) iter.Seq2[Token, error] {
+	return func(yield func(Token, error) bool) {
+		var pager *pagination.V4PagePaginationArrayAutoPager[shared.Token]
+		if c.tokenAccount != "" {
+			pager = c.api.Accounts.Tokens.ListAutoPaging(ctx, cfaccounts.TokenListParams{
+				AccountID: cf.F(c.tokenAccount),
+				PerPage:   cf.F(float64(tokensPerPage)),
+			})
+		} else {
+			pager = c.api.User.Tokens.ListAutoPaging(ctx, cfuser.TokenListParams{
+				PerPage: cf.F(float64(tokensPerPage)),
+			})
+		}
+		for pager.Next() {
+			if !yield(newToken(pager.Current()), nil) {
+				return
+			}
+		}
+		if err := pager.Err(); err != nil {
+			yield(Token{}, fmt.Errorf("list tokens: %w", err))
+		}
+	}
+}
+
+// ListTokens collects every API token owned by the current user.
+func (c *Client) ListTokens(ctx context.Context) ([]Token, error) {
+	var tokens []Token
+	for token, err := range c.Tokens(ctx) {
+		if err != nil {
+			return nil, err
+		}
+		tokens = append(tokens, token)
+	}
+	return tokens, nil
+}
+
+func newToken(t shared.Token) Token {
+	return Token{
+		ID:           t.ID,
+		Name:         t.Name,
+		Status:       string(t.Status),
+		IssuedOn:     t.IssuedOn,
+		ModifiedOn:   t.ModifiedOn,
+		LastUsedOn:   t.LastUsedOn,
+		ExpiresOn:    t.ExpiresOn,
+		NotBefore:    t.NotBefore,
+		Policies:     inspectPolicies(t.Policies),
+		AllowedCIDRs: cidrStrings(t.Condition.RequestIP.In),
+		DeniedCIDRs:  cidrStrings(t.Condition.RequestIP.NotIn),
+	}
+}
+
+// DeleteToken revokes the token with the given ID.
+func (c *Client) DeleteToken(ctx context.Context, tokenID string) error {
+	if err := c.checkWritable("delete token"); err != nil {
+		return err
+	}
+	if strings.TrimSpace(tokenID) == "" {
+		return errors.New("token ID is required")
+	}
+	var err error
+	if c.tokenAccount != "" {
+		_, err = c.api.Accounts.Tokens.Delete(ctx, tokenID, cfaccounts.TokenDeleteParams{AccountID: cf.F(c.tokenAccount)})
+	} else {
+		_, err = c.api.User.Tokens.Delete(ctx, tokenID)
+	}
+	if err != nil {
+		return fmt.Errorf("delete token %s: %w", tokenID, err)
+	}
+	return nil
+}
+
+// getToken fetches a token from the user or token account endpoint.
+func (c *Client) getToken(ctx context.Context, tokenID string, opts ...cfoption.RequestOption) (*shared.Token, error) {
+	if c.tokenAccount != "" {
+		return c.api.Accounts.Tokens.Get(ctx, tokenID, cfaccounts.TokenGetParams{AccountID: cf.F(c.tokenAccount)}, opts...)
+	}
+	return c.api.User.Tokens.Get(ctx, tokenID, opts...)
+}
+
+// UpdateToken rewrites an existing token in place; its value stays the
+// same. The token's name, policies, conditions, and validity window are sent
+// back as they are except where opts override them, so WithExpiry alone
+// moves the expiry. The updated token is returned.
+func (c *Client) UpdateToken(ctx context.Context, tokenID string, opts ...CreateOption) (*TokenInspection, error) {
+	if err := c.checkWritable("update token"); err != nil {
+		return nil, err
+	}
+	if strings.TrimSpace(tokenID) == "" {
+		return nil, errors.New("token ID is required")
+	}
+	current, err := c.getToken(ctx, tokenID)
+	if err != nil {
+		return nil, fmt.Errorf("get token %s: %w", tokenID, err)
+	}
+	if current == nil {
+		return nil, errors.New("cloudflare API returned an empty token response")
+	}
+	var settings createSettings
+	for _, opt := range opts {
+		opt(&settings)
+	}
+	body, err := updateTokenParam(current, settings)
+	if err != nil {
+		return nil, fmt.Errorf("update token %s: %w", tokenID, err)
+	}
+
+	var updated *shared.Token
+	if c.tokenAccount != "" {
+		updated, err = c.api.Accounts.Tokens.Update(ctx, tokenID, cfaccounts.TokenUpdateParams{
+			AccountID: cf.F(c.tokenAccount),
+			Token:     body,
+		})
+	} else {
+		updated, err = c.api.User.Tokens.Update(ctx, tokenID, cfuser.TokenUpdateParams{Token: body})
+	}
+	if err != nil {
+		return nil, fmt.Errorf("update token %s: %w", tokenID, err)
+	}
+	if updated == nil {
+		return nil, errors.New("cloudflare API returned an empty token response")
+	}
+	return describeToken(updated), nil
+}
+
+// updateTokenParam turns a fetched token back into a request body, applying
+// the settings that are set. An expired status is left out so the API can
+// reactivate a token whose expiry moved into the future.
+func updateTokenParam(token *shared.Token, settings createSettings) (shared.TokenParam, error) {
+	policies := make([]shared.TokenPolicyParam, 0, len(token.Policies))
+	for _, pol := range token.Policies {
+		groups := make([]shared.TokenPolicyPermissionGroupParam, 0, len(pol.PermissionGroups))
+		for _, pg := range pol.PermissionGroups {
+			groups = append(groups, shared.TokenPolicyPermissionGroupParam{ID: cf.F(pg.ID)})
+		}
+		policy := shared.TokenPolicyParam{
+			Effect:           cf.F(pol.Effect),
+			PermissionGroups: cf.F(groups),
+		}
+		// The resources are sent back as they were received, whatever
+		// their shape.
+		resources := policyResourceMap(pol)
+		if resources == nil {
+			return shared.TokenParam{}, fmt.Errorf("policy %s has resources cftoken cannot read", pol.ID)
+		}
+		setPolicyResources(&policy, resources)
+		policies = append(policies, policy)
+	}
+
+	param := shared.TokenParam{
+		Name:     cf.F(token.Name),
+		Policies: cf.F(policies),
+	}
+	if token.Status != shared.TokenStatusExpired && token.Status != "" {
+		param.Status = cf.F(token.Status)
+	}
+	expiresOn, notBefore := token.ExpiresOn, token.NotBefore
+	if settings.expiresOn != nil {
+		expiresOn = *settings.expiresOn
+	}
+	if settings.notBefore != nil {
+		notBefore = *settings.notBefore
+	}
+	if !expiresOn.IsZero() {
+		param.ExpiresOn = cf.F(expiresOn.UTC())
+	}
+	if !notBefore.IsZero() {
+		param.NotBefore = cf.F(notBefore.UTC())
+	}
+
+	condition, err := parseConditionObject(token.Condition.JSON.RawJSON())
+	if err != nil {
+		return shared.TokenParam{}, err
+	}
+	if err := condition.apply(settings.conditions); err != nil {
+		return shared.TokenParam{}, err
+	}
+	if len(condition) > 0 {
+		param.Condition = cf.Raw[shared.TokenConditionParam](condition)
+	}
+	return param, nil
+}
+
+// RollTokenValue replaces the secret of an existing token and returns the
+// new value. The token keeps its ID and settings; the old value stops
+// working at once.
+func (c *Client) RollTokenValue(ctx context.Context, tokenID string) (string, error) {
+	if err := c.checkWritable("roll token value"); err != nil {
+		return "", err
+	}
+	if strings.TrimSpace(tokenID) == "" {
+		return "", errors.New("token ID is required")
+	}
+	var (
+		value *shared.TokenValue
+		err   error
+	)
+	if c.tokenAccount != "" {
+		value, err = c.api.Accounts.Tokens.Value.Update(ctx, tokenID, cfaccounts.TokenValueUpdateParams{
+			AccountID: cf.F(c.tokenAccount),
+			Body:      map[string]any{},
+		})
+	} else {
+		value, err = c.api.User.Tokens.Value.Update(ctx, tokenID, cfuser.TokenValueUpdateParams{Body: map[string]any{}})
+	}
+	if err != nil {
+		return "", fmt.Errorf("roll token %s: %w", tokenID, err)
+	}
+	if value == nil || *value == "" {
+		return "", errors.New("cloudflare API returned an empty token value")
+	}
+	return *value, nil
+}
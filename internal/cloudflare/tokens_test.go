@@ -0,0 +1,372 @@
+package cloudflare
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"cftoken/internal/httpmw"
+)
+
+func TestTokensPaginates(t *testing.T) {
+	pages := map[string][]map[string]any{
+		"1": {
+			{"id": "t1", "name": "ci-1", "status": "active", "issued_on": "2024-01-01T00:00:00Z",
+				"condition": map[string]any{"request_ip": map[string]any{"in": []string{"10.0.0.2/32", "10.0.0.1/32"}}}},
+			{"id": "t2", "name": "ci-2", "status": "expired"},
+		},
+		"2": {
+			{"id": "t3", "name": "prod", "status": "active"},
+		},
+	}
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/user/tokens" {
+			t.Errorf("unexpected path %s", r.URL.Path)
+		}
+		page := r.URL.Query().Get("page")
+		if page == "" {
+			page = "1"
+		}
+		writeEnvelope(t, w, pages[page])
+	})
+
+	tokens, err := client.ListTokens(context.Background())
+	if err != nil {
+		t.Fatalf("ListTokens() error = %v", err)
+	}
+	if len(tokens) != 3 {
+		t.Fatalf("ListTokens() returned %d tokens, want 3", len(tokens))
+	}
+	if tokens[0].IssuedOn.IsZero() {
+		t.Errorf("IssuedOn not parsed")
+	}
+	if got := tokens[0].AllowedCIDRs; len(got) != 2 || got[0] != "10.0.0.1/32" {
+		t.Errorf("AllowedCIDRs = %v, want sorted CIDRs", got)
+	}
+	if tokens[2].ID != "t3" {
+		t.Errorf("last token = %q, want t3", tokens[2].ID)
+	}
+}
+
+func TestTokensStopsEarly(t *testing.T) {
+	requests := 0
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		writeEnvelope(t, w, []map[string]any{{"id": "t1"}, {"id": "t2"}})
+	})
+
+	for token, err := range client.Tokens(context.Background()) {
+		if err != nil {
+			t.Fatalf("Tokens() error = %v", err)
+		}
+		if token.ID == "t1" {
+			break
+		}
+	}
+	if requests != 1 {
+		t.Fatalf("Tokens() made %d requests after break, want 1", requests)
+	}
+}
+
+func TestDeleteToken(t *testing.T) {
+	var deleted string
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodDelete {
+			t.Errorf("method = %s, want DELETE", r.Method)
+		}
+		deleted = r.URL.Path
+		writeEnvelope(t, w, map[string]string{"id": "t1"})
+	})
+
+	if err := client.DeleteToken(context.Background(), "t1"); err != nil {
+		t.Fatalf("DeleteToken() error = %v", err)
+	}
+	if deleted != "/user/tokens/t1" {
+		t.Fatalf("DeleteToken() hit %q, want /user/tokens/t1", deleted)
+	}
+	if err := client.DeleteToken(context.Background(), " "); err == nil {
+		t.Fatalf("DeleteToken() with empty ID error = nil, want error")
+	}
+}
+
+func TestDescribeTokenResourceMap(t *testing.T) {
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		writeEnvelope(t, w, map[string]any{
+			"id":     "t1",
+			"name":   "edge",
+			"status": "active",
+			"policies": []map[string]any{
+				{
+					"id":                "p1",
+					"effect":            "allow",
+					"resources":         map[string]any{"com.cloudflare.api.account.zone.z1": "*"},
+					"permission_groups": []map[string]any{{"id": "g1", "name": "DNS Write"}},
+				},
+				{
+					"id":                "p2",
+					"effect":            "allow",
+					"resources":         map[string]any{"com.cloudflare.api.account.a1": map[string]any{"com.cloudflare.api.account.zone.*": "*"}},
+					"permission_groups": []map[string]any{{"id": "g2"}},
+				},
+			},
+		})
+	})
+
+	desc, err := client.DescribeToken(context.Background(), "t1")
+	if err != nil {
+		t.Fatalf("DescribeToken() error = %v", err)
+	}
+	if len(desc.Policies) != 2 {
+		t.Fatalf("DescribeToken() policies = %+v", desc.Policies)
+	}
+	if desc.Policies[0].ID != "p1" || desc.Policies[1].ID != "p2" {
+		t.Errorf("policy IDs = %q, %q, want p1, p2", desc.Policies[0].ID, desc.Policies[1].ID)
+	}
+	if got := desc.Policies[0].ResourceMap["com.cloudflare.api.account.zone.z1"]; got != "*" {
+		t.Errorf("flat ResourceMap value = %v, want *", got)
+	}
+	nested, ok := desc.Policies[1].ResourceMap["com.cloudflare.api.account.a1"].(map[string]interface{})
+	if !ok || nested["com.cloudflare.api.account.zone.*"] != "*" {
+		t.Errorf("nested ResourceMap = %v", desc.Policies[1].ResourceMap)
+	}
+}
+
+func TestRawToken(t *testing.T) {
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/user/tokens/t1" {
+			t.Errorf("path = %s", r.URL.Path)
+		}
+		writeEnvelope(t, w, map[string]any{"id": "t1", "x_unmapped": 42})
+	})
+
+	raw, err := client.RawToken(context.Background(), "t1")
+	if err != nil {
+		t.Fatalf("RawToken() error = %v", err)
+	}
+	for _, want := range []string{`"success":true`, `"x_unmapped":42`} {
+		if !strings.Contains(string(raw), want) {
+			t.Errorf("RawToken() = %s, want it to contain %s", raw, want)
+		}
+	}
+}
+
+func TestAccountOwnedTokens(t *testing.T) {
+	var paths []string
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		paths = append(paths, r.Method+" "+r.URL.Path)
+		switch r.Method + " " + r.URL.Path {
+		case "GET /accounts/a1/tokens":
+			if r.URL.Query().Get("page") != "" {
+				writeEnvelope(t, w, []any{})
+				return
+			}
+			writeEnvelope(t, w, []map[string]any{{"id": "t1", "name": "ci", "status": "active"}})
+		case "GET /accounts/a1/tokens/t1":
+			writeEnvelope(t, w, map[string]any{"id": "t1", "name": "ci", "status": "active"})
+		case "GET /accounts/a1/tokens/verify":
+			writeEnvelope(t, w, map[string]any{"id": "t1", "status": "active"})
+		case "DELETE /accounts/a1/tokens/t1":
+			writeEnvelope(t, w, map[string]any{"id": "t1"})
+		case "POST /accounts/a1/tokens":
+			writeEnvelope(t, w, map[string]any{"id": "t2", "name": "ci", "status": "active", "value": "secret"})
+		default:
+			http.NotFound(w, r)
+		}
+	})
+	WithTokenAccount("a1")(client)
+	ctx := context.Background()
+
+	tokens, err := client.ListTokens(ctx)
+	if err != nil || len(tokens) != 1 || tokens[0].ID != "t1" {
+		t.Fatalf("ListTokens() = %v, %v", tokens, err)
+	}
+	if desc, err := client.DescribeToken(ctx, "t1"); err != nil || desc.Name != "ci" {
+		t.Fatalf("DescribeToken() = %v, %v", desc, err)
+	}
+	if v, err := client.VerifyToken(ctx); err != nil || v.ID != "t1" {
+		t.Fatalf("VerifyToken() = %v, %v", v, err)
+	}
+	if err := client.DeleteToken(ctx, "t1"); err != nil {
+		t.Fatalf("DeleteToken() error = %v", err)
+	}
+	policies := []Policy{{Resources: map[string]interface{}{"com.cloudflare.api.account.a1": "*"}, PermissionGroups: []PolicyPermissionGroup{{ID: "g1"}}}}
+	if res, err := client.CreateToken(ctx, "ci", policies, WithAllowedCIDRs("10.0.0.0/8")); err != nil || res.ID != "t2" || res.Value != "secret" {
+		t.Fatalf("CreateToken() = %+v, %v", res, err)
+	}
+	for _, path := range paths {
+		if !strings.Contains(path, "/accounts/a1/tokens") {
+			t.Errorf("request %s, want only account token endpoints", path)
+		}
+	}
+}
+
+func TestUpdateTokenKeepsSettings(t *testing.T) {
+	token := map[string]any{
+		"id": "t1", "name": "ci", "status": "expired", "expires_on": "2024-01-01T00:00:00Z",
+		"condition": map[string]any{"request_ip": map[string]any{"in": []string{"10.0.0.0/8"}}},
+		"policies": []map[string]any{{
+			"id": "p1", "effect": "allow",
+			"resources":         map[string]any{"com.cloudflare.api.account.zone.z1": "*"},
+			"permission_groups": []map[string]any{{"id": "g1", "name": "DNS Write"}},
+		}},
+	}
+	var sent map[string]any
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/user/tokens/t1" {
+			t.Errorf("path = %s", r.URL.Path)
+		}
+		if r.Method == http.MethodPut {
+			if err := json.NewDecoder(r.Body).Decode(&sent); err != nil {
+				t.Errorf("decode body: %v", err)
+			}
+			writeEnvelope(t, w, sent)
+			return
+		}
+		writeEnvelope(t, w, token)
+	})
+
+	expiry := time.Date(2024, 6, 1, 8, 0, 0, 0, time.UTC)
+	desc, err := client.UpdateToken(context.Background(), "t1", WithExpiry(expiry))
+	if err != nil {
+		t.Fatalf("UpdateToken() error = %v", err)
+	}
+	if desc.ExpiresOn != "2024-06-01T08:00:00Z" {
+		t.Errorf("ExpiresOn = %q", desc.ExpiresOn)
+	}
+	if _, ok := sent["status"]; ok {
+		t.Errorf("sent status %v, want none for an expired token", sent["status"])
+	}
+	body, _ := json.Marshal(sent)
+	for _, want := range []string{`"name":"ci"`, `"in":["10.0.0.0/8"]`, `"com.cloudflare.api.account.zone.z1":"*"`, `"id":"g1"`, `"effect":"allow"`} {
+		if !strings.Contains(string(body), want) {
+			t.Errorf("sent %s, want it to contain %s", body, want)
+		}
+	}
+}
+
+// TestResourceShapesPassThrough checks that resources in shapes the SDK
+// does not model are read, shown, and sent back unchanged.
+func TestResourceShapesPassThrough(t *testing.T) {
+	resources := map[string]any{
+		"com.cloudflare.api.account.zone.z1": map[string]any{
+			"com.cloudflare.api.dns.record.*": []any{"r1", "r2"},
+		},
+	}
+	token := map[string]any{
+		"id": "t1", "name": "ci", "status": "active",
+		"policies": []map[string]any{{
+			"id": "p1", "effect": "allow",
+			"resources":         resources,
+			"permission_groups": []map[string]any{{"id": "g1"}},
+		}},
+	}
+	var sent struct {
+		Policies []struct {
+			Resources map[string]any `json:"resources"`
+		} `json:"policies"`
+	}
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPut {
+			if err := json.NewDecoder(r.Body).Decode(&sent); err != nil {
+				t.Errorf("decode body: %v", err)
+			}
+		}
+		writeEnvelope(t, w, token)
+	})
+
+	desc, err := client.UpdateToken(context.Background(), "t1", WithExpiry(time.Date(2024, 6, 1, 8, 0, 0, 0, time.UTC)))
+	if err != nil {
+		t.Fatalf("UpdateToken() error = %v", err)
+	}
+	if len(sent.Policies) != 1 || !reflect.DeepEqual(sent.Policies[0].Resources, resources) {
+		t.Errorf("sent resources %v, want %v", sent.Policies, resources)
+	}
+	if !reflect.DeepEqual(desc.Policies[0].ResourceMap, resources) {
+		t.Errorf("ResourceMap = %v, want %v", desc.Policies[0].ResourceMap, resources)
+	}
+	if want := []string{`com.cloudflare.api.account.zone.z1.com.cloudflare.api.dns.record.*=["r1","r2"]`}; !reflect.DeepEqual(desc.Policies[0].Resources, want) {
+		t.Errorf("Resources = %q, want %q", desc.Policies[0].Resources, want)
+	}
+}
+
+func TestRollTokenValue(t *testing.T) {
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPut || r.URL.Path != "/user/tokens/t1/value" {
+			t.Errorf("request = %s %s", r.Method, r.URL.Path)
+		}
+		writeEnvelope(t, w, "new-secret")
+	})
+
+	got, err := client.RollTokenValue(context.Background(), "t1")
+	if err != nil {
+		t.Fatalf("RollTokenValue() error = %v", err)
+	}
+	if got != "new-secret" {
+		t.Fatalf("RollTokenValue() = %q, want new-secret", got)
+	}
+}
+
+func TestRequestsCarryCorrelationID(t *testing.T) {
+	var got string
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		got = r.Header.Get(httpmw.CorrelationHeader)
+		writeEnvelope(t, w, map[string]string{"id": "t1"})
+	})
+
+	ctx := httpmw.WithRequestID(context.Background(), "corr-1")
+	if err := client.DeleteToken(ctx, "t1"); err != nil {
+		t.Fatalf("DeleteToken() error = %v", err)
+	}
+	if got != "corr-1" {
+		t.Fatalf("%s header = %q, want corr-1", httpmw.CorrelationHeader, got)
+	}
+}
+
+// BenchmarkListTokens lists an account with 2000 tokens, 50 per page.
+func BenchmarkListTokens(b *testing.B) {
+	const total = 2000
+	page := func(n int) []map[string]any {
+		var out []map[string]any
+		for i := (n - 1) * tokensPerPage; i < n*tokensPerPage && i < total; i++ {
+			out = append(out, map[string]any{
+				"id": fmt.Sprintf("t%d", i), "name": fmt.Sprintf("ci-%d", i), "status": "active",
+				"issued_on": "2024-01-01T00:00:00Z",
+				"condition": map[string]any{"request_ip": map[string]any{"in": []string{"10.0.0.2/32", "10.0.0.1/32"}}},
+				"policies": []map[string]any{{
+					"id": "p1", "effect": "allow",
+					"resources":         map[string]string{"com.cloudflare.api.account.zone.z1": "*"},
+					"permission_groups": []map[string]any{{"id": "g1", "name": "Zone Read"}},
+				}},
+			})
+		}
+		return out
+	}
+	pages := make(map[string][]map[string]any)
+	for n := 1; n <= total/tokensPerPage; n++ {
+		pages[strconv.Itoa(n)] = page(n)
+	}
+	client := newTestClient(b, func(w http.ResponseWriter, r *http.Request) {
+		n := r.URL.Query().Get("page")
+		if n == "" {
+			n = "1"
+		}
+		writeEnvelope(b, w, pages[n])
+	})
+
+	b.ReportAllocs()
+	for b.Loop() {
+		tokens, err := client.ListTokens(context.Background())
+		if err != nil {
+			b.Fatal(err)
+		}
+		if len(tokens) != total {
+			b.Fatalf("ListTokens() returned %d tokens, want %d", len(tokens), total)
+		}
+	}
+}
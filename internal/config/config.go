@@ -2,23 +2,112 @@ package config
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io/fs"
 	"os"
 	"path/filepath"
+	"runtime"
 	"strings"
+	"time"
+
+	"cftoken/internal/duration"
 )
 
+// goos is runtime.GOOS, swappable so tests can exercise Windows paths.
+var goos = runtime.GOOS
+
 // settings mirrors the JSON structure stored in the config file.
 type settings struct {
 	DefaultPermissions  []string               `json:"default_permissions"`
 	DefaultAllowedCIDRs []string               `json:"default_allowed_cidrs"`
+	AccountID           string                 `json:"account_id"`
+	TokenSource         string                 `json:"token_source"`
+	TokenSourceTimeout  string                 `json:"token_source_timeout"`
 	Zones               map[string]interface{} `json:"zones"`
+	Profiles            map[string]ZoneConfig  `json:"profiles"`
+	Guardrails          *Guardrails            `json:"guardrails"`
+	Notifications       *Notifications         `json:"notifications"`
+	Cache               *CacheConfig           `json:"cache"`
+	Budget              *Budget                `json:"budget"`
+	RequestSigners      []string               `json:"request_signers"`
+	PrintTokenValues    string                 `json:"print_token_values"`
+	ApprovalLink        *ApprovalLink          `json:"approval_link"`
+	Share               *ShareConfig           `json:"share"`
+	// DefaultTTL replaces the built-in 8h lifetime of tokens whose zone sets
+	// no ttl; DefaultTokenPrefixTemplate names tokens created without
+	// -token-prefix, e.g. "{{ .Zone }}-ci".
+	DefaultTTL                 string `json:"default_ttl"`
+	DefaultTokenPrefixTemplate string `json:"default_token_prefix_template"`
+
+	// zoneRepeats holds the zone ID of every occurrence of a zones key
+	// written more than once; Zones keeps only the last.
+	zoneRepeats map[string][]string
+}
+
+// Budget caps how many tokens may be issued in one run and in any 24 hours,
+// so a looping script cannot mint hundreds of tokens. Zero means no limit.
+type Budget struct {
+	PerRun int `json:"per_run"`
+	PerDay int `json:"per_day"`
+}
+
+// CacheConfig controls the on-disk API cache. TTL is a duration such as
+// "30m" or "1d"; MaxBytes caps the cache directory size. Zero values select the defaults.
+type CacheConfig struct {
+	TTL      string `json:"ttl"`
+	MaxBytes int64  `json:"max_bytes"`
+}
+
+// Notifications groups the configured notification backends.
+type Notifications struct {
+	Email *EmailNotification `json:"email"`
+}
+
+// EmailNotification configures the SMTP notifier. TLS is one of "starttls"
+// (default), "implicit", or "none". Subject and body templates use
+// text/template syntax and fall back to built-in defaults when empty.
+type EmailNotification struct {
+	Host            string   `json:"host"`
+	Port            int      `json:"port"`
+	Username        string   `json:"username"`
+	Password        string   `json:"password"`
+	From            string   `json:"from"`
+	To              []string `json:"to"`
+	TLS             string   `json:"tls"`
+	SubjectTemplate string   `json:"subject_template"`
+	BodyTemplate    string   `json:"body_template"`
+}
+
+// ApprovalLink makes high-risk creations wait for a second operator to
+// approve them through a link the CLI serves on Listen while it waits.
+// BaseURL, which must be https, names the authenticating TLS proxy in front
+// of Listen, and ApproverHeader the request header in which that proxy
+// passes the signed-in user. Both are required: a name typed into the page
+// would let whoever requested a token approve it themselves. Timeout
+// bounds the wait and defaults to 15m.
+type ApprovalLink struct {
+	Listen         string `json:"listen"`
+	BaseURL        string `json:"base_url"`
+	ApproverHeader string `json:"approver_header"`
+	Timeout        string `json:"timeout"`
+}
+
+// ShareConfig points -share at a One-Time Secret instance (the
+// onetimesecret.com API, also self-hosted). Username and APIKey
+// authenticate to it when set; TTL is how long the link stays valid and
+// defaults to the service's own limit.
+type ShareConfig struct {
+	URL      string `json:"url"`
+	Username string `json:"username"`
+	APIKey   string `json:"api_key"`
+	TTL      string `json:"ttl"`
 }
 
 // ZoneConfig defines extended configuration for a zone with optional template for permissions.
 type ZoneConfig struct {
 	ZoneID          string                 `json:"zone_id"`
+	AccountID       string                 `json:"account_id"`
 	Permissions     []string               `json:"permissions"`
 	AllowedCIDRs    []string               `json:"allowed_cidrs"`
 	TTL             string                 `json:"ttl"`
@@ -26,6 +115,45 @@ type ZoneConfig struct {
 	TemplateInline  string                 `json:"template_inline"`
 	Variables       map[string]interface{} `json:"variables"`
 	InheritDefaults bool                   `json:"inherit_defaults"`
+	Extends         string                 `json:"extends"`
+	Sink            *SinkConfig            `json:"sink"`
+	Guardrails      *Guardrails            `json:"guardrails"`
+	// NameSuffix picks how generated token names are made unique:
+	// "timestamp" (default), "ulid", "hex", or "sequence".
+	NameSuffix string `json:"name_suffix"`
+	// Frozen blocks every token issuance for the zone, for incidents or
+	// migrations. `cftoken zone freeze` freezes zones without editing config.
+	Frozen bool `json:"frozen"`
+}
+
+// Guardrails are policy limits checked before a token is created. Zone
+// guardrails can only tighten the global ones, never relax them.
+type Guardrails struct {
+	RequireIPRestriction bool     `json:"require_ip_restriction"`
+	MaxTTL               string   `json:"max_ttl"`
+	DeniedPermissions    []string `json:"denied_permissions"`
+	// RequireTicket demands a change ticket (-ticket) for every token;
+	// TicketPattern is a regular expression a given ticket must match.
+	RequireTicket bool   `json:"require_ticket"`
+	TicketPattern string `json:"ticket_pattern"`
+	// TicketInName embeds the ticket in the token name.
+	TicketInName bool `json:"ticket_in_name"`
+	// InheritedTicketPatterns are the ticket_pattern values of profiles the
+	// zone extends that differ from its own; a ticket must match them too.
+	InheritedTicketPatterns []string `json:"-"`
+}
+
+// SinkConfig names where a newly created token is delivered. Type is one of
+// "vault", "kubernetes", or "github"; the remaining fields apply per type.
+type SinkConfig struct {
+	Type        string `json:"type"`
+	Path        string `json:"path"`        // vault: KV path, e.g. secret/cloudflare/prod
+	Field       string `json:"field"`       // vault: key within the secret (default "token")
+	Namespace   string `json:"namespace"`   // kubernetes: secret namespace (default: current context)
+	Name        string `json:"name"`        // kubernetes: secret name; github: secret name
+	Key         string `json:"key"`         // kubernetes: data key (default "token")
+	Repo        string `json:"repo"`        // github: owner/repo
+	Environment string `json:"environment"` // github: optional deployment environment
 }
 
 // DefaultPath resolves the config file path according to XDG conventions.
@@ -37,10 +165,17 @@ func DefaultPath() (string, error) {
 	return filepath.Join(dir, "config.json"), nil
 }
 
+// configDir honors XDG_CONFIG_HOME everywhere, then %APPDATA% on Windows,
+// and falls back to ~/.config.
 func configDir() (string, error) {
 	if dir := strings.TrimSpace(os.Getenv("XDG_CONFIG_HOME")); dir != "" {
 		return filepath.Join(dir, "cftoken"), nil
 	}
+	if goos == "windows" {
+		if dir := strings.TrimSpace(os.Getenv("APPDATA")); dir != "" {
+			return filepath.Join(dir, "cftoken"), nil
+		}
+	}
 	home, err := os.UserHomeDir()
 	if err != nil {
 		return "", fmt.Errorf("determine home directory: %w", err)
@@ -48,6 +183,24 @@ func configDir() (string, error) {
 	return filepath.Join(home, ".config", "cftoken"), nil
 }
 
+// StateDir returns the directory for runtime state such as operation
+// journals, following XDG_STATE_HOME, or %LOCALAPPDATA% on Windows.
+func StateDir() (string, error) {
+	if dir := strings.TrimSpace(os.Getenv("XDG_STATE_HOME")); dir != "" {
+		return filepath.Join(dir, "cftoken"), nil
+	}
+	if goos == "windows" {
+		if dir := strings.TrimSpace(os.Getenv("LOCALAPPDATA")); dir != "" {
+			return filepath.Join(dir, "cftoken", "state"), nil
+		}
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("determine home directory: %w", err)
+	}
+	return filepath.Join(home, ".local", "state", "cftoken"), nil
+}
+
 // LoadDefaultPermissions reads the configuration file (if present) and returns
 // the default permission keys defined within.
 func LoadDefaultPermissions() ([]string, error) {
@@ -78,6 +231,250 @@ func LoadDefaultAllowedCIDRs() ([]string, error) {
 	return cidrs, nil
 }
 
+// LoadAccountID returns the top-level account_id from the configuration
+// file, or fs.ErrNotExist when none is set.
+func LoadAccountID() (string, error) {
+	cfg, err := loadSettings()
+	if err != nil {
+		return "", err
+	}
+	id, err := cfg.accountID(nil)
+	if err != nil {
+		return "", err
+	}
+	if id != "" {
+		return id, nil
+	}
+	return "", fs.ErrNotExist
+}
+
+// LoadGuardrails returns the global guardrails from the configuration file,
+// or fs.ErrNotExist when none are configured.
+func LoadGuardrails() (*Guardrails, error) {
+	cfg, err := loadSettings()
+	if err != nil {
+		return nil, err
+	}
+	if cfg.Guardrails == nil {
+		return nil, fs.ErrNotExist
+	}
+	return cfg.Guardrails, nil
+}
+
+// LoadTokenSource returns the token_source setting naming where the
+// management token comes from, along with token_source_timeout, or
+// fs.ErrNotExist when no source is set.
+func LoadTokenSource() (source, timeout string, err error) {
+	cfg, err := loadSettings()
+	if err != nil {
+		return "", "", err
+	}
+	if source := strings.TrimSpace(cfg.TokenSource); source != "" {
+		if source, err = cfg.interpolate(source, nil); err != nil {
+			return "", "", fmt.Errorf("token_source: %w", err)
+		}
+		return source, strings.TrimSpace(cfg.TokenSourceTimeout), nil
+	}
+	return "", "", fs.ErrNotExist
+}
+
+// LoadCacheConfig returns the cache settings from the configuration file, or
+// fs.ErrNotExist when none are set.
+func LoadCacheConfig() (*CacheConfig, error) {
+	cfg, err := loadSettings()
+	if err != nil {
+		return nil, err
+	}
+	if cfg.Cache == nil {
+		return nil, fs.ErrNotExist
+	}
+	return cfg.Cache, nil
+}
+
+// LoadBudget returns the issuance budget from the configuration file, or
+// fs.ErrNotExist when none is set.
+func LoadBudget() (*Budget, error) {
+	cfg, err := loadSettings()
+	if err != nil {
+		return nil, err
+	}
+	if cfg.Budget == nil {
+		return nil, fs.ErrNotExist
+	}
+	if cfg.Budget.PerRun < 0 || cfg.Budget.PerDay < 0 {
+		return nil, fmt.Errorf("budget: per_run and per_day must not be negative")
+	}
+	return cfg.Budget, nil
+}
+
+// LoadPrintTokenValues returns the print_token_values setting: "never",
+// "once", or "always". It returns fs.ErrNotExist when none is set.
+func LoadPrintTokenValues() (string, error) {
+	cfg, err := loadSettings()
+	if err != nil {
+		return "", err
+	}
+	switch v := strings.ToLower(strings.TrimSpace(cfg.PrintTokenValues)); v {
+	case "":
+		return "", fs.ErrNotExist
+	case "never", "once", "always":
+		return v, nil
+	default:
+		return "", fmt.Errorf("print_token_values: unknown value %q; must be never, once, or always", cfg.PrintTokenValues)
+	}
+}
+
+// LoadDefaultTTL returns the default_ttl setting, or fs.ErrNotExist when
+// none is set.
+func LoadDefaultTTL() (time.Duration, error) {
+	cfg, err := loadSettings()
+	if err != nil {
+		return 0, err
+	}
+	s := strings.TrimSpace(cfg.DefaultTTL)
+	if s == "" {
+		return 0, fs.ErrNotExist
+	}
+	ttl, err := duration.Parse(s)
+	if err != nil {
+		return 0, fmt.Errorf("default_ttl: %w", err)
+	}
+	return ttl, nil
+}
+
+// LoadTokenPrefixTemplate returns the default_token_prefix_template
+// setting, or fs.ErrNotExist when none is set.
+func LoadTokenPrefixTemplate() (string, error) {
+	cfg, err := loadSettings()
+	if err != nil {
+		return "", err
+	}
+	if strings.TrimSpace(cfg.DefaultTokenPrefixTemplate) == "" {
+		return "", fs.ErrNotExist
+	}
+	return cfg.DefaultTokenPrefixTemplate, nil
+}
+
+// LoadRequestSigners returns the public keys whose request bundles
+// fulfill-request accepts, or fs.ErrNotExist when none are trusted.
+func LoadRequestSigners() ([]string, error) {
+	cfg, err := loadSettings()
+	if err != nil {
+		return nil, err
+	}
+	keys := sanitizeStringList(cfg.RequestSigners)
+	if len(keys) == 0 {
+		return nil, fs.ErrNotExist
+	}
+	return keys, nil
+}
+
+// LoadApprovalLink returns the approval_link settings, or fs.ErrNotExist
+// when none are configured.
+func LoadApprovalLink() (*ApprovalLink, error) {
+	cfg, err := loadSettings()
+	if err != nil {
+		return nil, err
+	}
+	if cfg.ApprovalLink == nil {
+		return nil, fs.ErrNotExist
+	}
+	link := *cfg.ApprovalLink
+	link.Listen = strings.TrimSpace(link.Listen)
+	link.BaseURL = strings.TrimRight(strings.TrimSpace(link.BaseURL), "/")
+	link.ApproverHeader = strings.TrimSpace(link.ApproverHeader)
+	if link.Listen == "" {
+		return nil, fmt.Errorf("approval_link: listen is required")
+	}
+	if link.ApproverHeader == "" {
+		return nil, fmt.Errorf("approval_link: approver_header is required; without an authenticated approver, whoever requests a token could approve it themselves")
+	}
+	if link.BaseURL == "" {
+		return nil, fmt.Errorf("approval_link: base_url is required: the authenticating proxy that sets approver_header")
+	}
+	if !strings.HasPrefix(link.BaseURL, "https://") {
+		return nil, fmt.Errorf("approval_link: base_url %q must be an https URL", link.BaseURL)
+	}
+	if link.Timeout == "" {
+		link.Timeout = "15m"
+	}
+	if d, err := duration.Parse(link.Timeout); err != nil || d <= 0 {
+		return nil, fmt.Errorf("approval_link: invalid timeout %q", link.Timeout)
+	}
+	return &link, nil
+}
+
+// LoadShareConfig returns the share settings with ${...} references
+// expanded, or fs.ErrNotExist when none are configured.
+func LoadShareConfig() (*ShareConfig, error) {
+	cfg, err := loadSettings()
+	if err != nil {
+		return nil, err
+	}
+	if cfg.Share == nil {
+		return nil, fs.ErrNotExist
+	}
+	share := *cfg.Share
+	for _, field := range []*string{&share.URL, &share.Username, &share.APIKey} {
+		if *field, err = cfg.interpolate(*field, nil); err != nil {
+			return nil, fmt.Errorf("share: %w", err)
+		}
+	}
+	share.URL = strings.TrimRight(strings.TrimSpace(share.URL), "/")
+	if share.URL == "" {
+		return nil, fmt.Errorf("share: url is required")
+	}
+	if share.TTL != "" {
+		if d, err := duration.Parse(share.TTL); err != nil || d <= 0 {
+			return nil, fmt.Errorf("share: invalid ttl %q", share.TTL)
+		}
+	}
+	return &share, nil
+}
+
+// LoadEmailNotification returns the SMTP notifier settings from the
+// configuration file, or fs.ErrNotExist when none are configured.
+func LoadEmailNotification() (*EmailNotification, error) {
+	cfg, err := loadSettings()
+	if err != nil {
+		return nil, err
+	}
+	if cfg.Notifications == nil || cfg.Notifications.Email == nil {
+		return nil, fs.ErrNotExist
+	}
+
+	email := *cfg.Notifications.Email
+	email.To = append([]string(nil), email.To...)
+	fields := []*string{&email.Host, &email.Username, &email.Password, &email.From}
+	for i := range email.To {
+		fields = append(fields, &email.To[i])
+	}
+	for _, field := range fields {
+		if *field, err = cfg.interpolate(*field, nil); err != nil {
+			return nil, fmt.Errorf("notifications.email: %w", err)
+		}
+	}
+	email.Host = strings.TrimSpace(email.Host)
+	email.From = strings.TrimSpace(email.From)
+	email.To = sanitizeStringList(email.To)
+	switch {
+	case email.Host == "":
+		return nil, fmt.Errorf("notifications.email: host is required")
+	case email.From == "":
+		return nil, fmt.Errorf("notifications.email: from is required")
+	case len(email.To) == 0:
+		return nil, fmt.Errorf("notifications.email: at least one recipient is required")
+	}
+	switch email.TLS {
+	case "":
+		email.TLS = "starttls"
+	case "starttls", "implicit", "none":
+	default:
+		return nil, fmt.Errorf("notifications.email: invalid tls mode %q; must be starttls, implicit, or none", email.TLS)
+	}
+	return &email, nil
+}
+
 func loadSettings() (*settings, error) {
 	path, err := DefaultPath()
 	if err != nil {
@@ -92,6 +489,9 @@ func loadSettings() (*settings, error) {
 	if err := json.Unmarshal(data, &cfg); err != nil {
 		return nil, fmt.Errorf("parse config %s: %w", path, err)
 	}
+	if cfg.zoneRepeats, err = repeatedZoneKeys(data); err != nil {
+		return nil, fmt.Errorf("parse config %s: %w", path, err)
+	}
 
 	return &cfg, nil
 }
@@ -106,24 +506,75 @@ func sanitizeStringList(values []string) []string {
 	return out
 }
 
+// ErrZoneNotFound is wrapped by the errors of LoadZoneConfig for a zone
+// config.json does not define, as opposed to one it defines badly.
+var ErrZoneNotFound = errors.New("not found")
+
 // LoadZoneConfig loads zone configuration by name. Returns the zone ID and optional extended config.
 func LoadZoneConfig(zoneName string) (string, *ZoneConfig, error) {
 	cfg, err := loadSettings()
 	if err != nil {
 		return "", nil, err
 	}
+	if err := zoneConflict(cfg, zoneName); err != nil {
+		return "", nil, err
+	}
+
+	zoneID, zoneConfig, err := cfg.zoneConfig(zoneName)
+	if err != nil || zoneConfig == nil {
+		return zoneID, nil, err
+	}
+	if zoneConfig.AccountID == "" {
+		if zoneConfig.AccountID, err = cfg.accountID(nil); err != nil {
+			return "", nil, err
+		}
+	}
+
+	// Apply defaults if requested
+	if zoneConfig.InheritDefaults {
+		if len(zoneConfig.Permissions) == 0 && len(cfg.DefaultPermissions) > 0 {
+			zoneConfig.Permissions = append([]string(nil), cfg.DefaultPermissions...)
+		}
+		if len(zoneConfig.AllowedCIDRs) == 0 && len(cfg.DefaultAllowedCIDRs) > 0 {
+			zoneConfig.AllowedCIDRs = append([]string(nil), cfg.DefaultAllowedCIDRs...)
+		}
+	}
+
+	return zoneConfig.ZoneID, zoneConfig, nil
+}
+
+// zoneConfig decodes a zone entry, resolves its profile chain, and expands
+// ${...} references, without applying inherit_defaults. Simple string
+// entries return a nil ZoneConfig.
+func (cfg *settings) zoneConfig(zoneName string) (string, *ZoneConfig, error) {
+	return cfg.resolveZone(zoneName, nil)
+}
+
+// resolveZone implements zoneConfig. seen is the chain of zones whose
+// references are being resolved, for cycle detection.
+func (cfg *settings) resolveZone(zoneName string, seen []string) (string, *ZoneConfig, error) {
+	for _, s := range seen {
+		if s == zoneName {
+			return "", nil, fmt.Errorf("reference cycle: %s -> %s", strings.Join(seen, " -> "), zoneName)
+		}
+	}
+	seen = append(seen, zoneName)
 
 	if cfg.Zones == nil {
-		return "", nil, fmt.Errorf("no zones configured")
+		return "", nil, fmt.Errorf("zone %q %w: no zones configured", zoneName, ErrZoneNotFound)
 	}
 
 	zoneValue, ok := cfg.Zones[zoneName]
 	if !ok {
-		return "", nil, fmt.Errorf("zone %q not found", zoneName)
+		return "", nil, fmt.Errorf("zone %q %w", zoneName, ErrZoneNotFound)
 	}
 
 	// Handle simple string zone ID
 	if zoneID, ok := zoneValue.(string); ok {
+		zoneID, err := cfg.interpolate(zoneID, seen)
+		if err != nil {
+			return "", nil, fmt.Errorf("zone %q: %w", zoneName, err)
+		}
 		return zoneID, nil, nil
 	}
 
@@ -144,14 +595,15 @@ func LoadZoneConfig(zoneName string) (string, *ZoneConfig, error) {
 		return "", nil, fmt.Errorf("parse zone config: %w", err)
 	}
 
-	// Apply defaults if requested
-	if zoneConfig.InheritDefaults {
-		if len(zoneConfig.Permissions) == 0 && len(cfg.DefaultPermissions) > 0 {
-			zoneConfig.Permissions = append([]string(nil), cfg.DefaultPermissions...)
-		}
-		if len(zoneConfig.AllowedCIDRs) == 0 && len(cfg.DefaultAllowedCIDRs) > 0 {
-			zoneConfig.AllowedCIDRs = append([]string(nil), cfg.DefaultAllowedCIDRs...)
+	if zoneConfig.Extends != "" {
+		base, err := resolveProfile(cfg.Profiles, zoneConfig.Extends, nil)
+		if err != nil {
+			return "", nil, fmt.Errorf("zone %q: %w", zoneName, err)
 		}
+		zoneConfig = mergeZoneConfig(base, zoneConfig)
+	}
+	if err := cfg.interpolateZone(&zoneConfig, seen); err != nil {
+		return "", nil, fmt.Errorf("zone %q: %w", zoneName, err)
 	}
 
 	return zoneConfig.ZoneID, &zoneConfig, nil
@@ -3,6 +3,7 @@ package config
 import (
 	"errors"
 	"io/fs"
+	"path/filepath"
 	"testing"
 )
 
@@ -79,3 +80,195 @@ func TestLoadDefaultAllowedCIDRsEmpty(t *testing.T) {
 		t.Fatalf("LoadDefaultAllowedCIDRs() error = %v, want fs.ErrNotExist", err)
 	}
 }
+
+func TestLoadEmailNotification(t *testing.T) {
+	tmp := t.TempDir()
+	stubConfigDir(t, tmp)
+
+	writeJSON(t, configFilePath(t, tmp, "config.json"), map[string]any{
+		"notifications": map[string]any{
+			"email": map[string]any{
+				"host": " smtp.example.com ",
+				"from": "cftoken@example.com",
+				"to":   []string{" ops@example.com ", ""},
+			},
+		},
+	})
+
+	email, err := LoadEmailNotification()
+	if err != nil {
+		t.Fatalf("LoadEmailNotification() error = %v", err)
+	}
+	if email.Host != "smtp.example.com" {
+		t.Fatalf("Host = %q, want smtp.example.com", email.Host)
+	}
+	if email.TLS != "starttls" {
+		t.Fatalf("TLS = %q, want starttls default", email.TLS)
+	}
+	if len(email.To) != 1 || email.To[0] != "ops@example.com" {
+		t.Fatalf("To = %v, want [ops@example.com]", email.To)
+	}
+}
+
+func TestLoadEmailNotificationErrors(t *testing.T) {
+	tests := []struct {
+		name  string
+		email map[string]any
+	}{
+		{"missing host", map[string]any{"from": "a@example.com", "to": []string{"b@example.com"}}},
+		{"missing recipients", map[string]any{"host": "smtp.example.com", "from": "a@example.com"}},
+		{"invalid tls", map[string]any{"host": "smtp.example.com", "from": "a@example.com", "to": []string{"b@example.com"}, "tls": "ssl"}},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			tmp := t.TempDir()
+			stubConfigDir(t, tmp)
+			writeJSON(t, configFilePath(t, tmp, "config.json"), map[string]any{
+				"notifications": map[string]any{"email": tc.email},
+			})
+
+			if _, err := LoadEmailNotification(); err == nil {
+				t.Fatalf("LoadEmailNotification() error = nil, want error")
+			}
+		})
+	}
+}
+
+func TestLoadApprovalLink(t *testing.T) {
+	tests := []struct {
+		name    string
+		link    map[string]any
+		want    ApprovalLink
+		wantErr bool
+	}{
+		{"defaults", map[string]any{"listen": " 127.0.0.1:8765 ", "base_url": "https://approve.example.com", "approver_header": "X-Auth-Request-Email"}, ApprovalLink{Listen: "127.0.0.1:8765", BaseURL: "https://approve.example.com", ApproverHeader: "X-Auth-Request-Email", Timeout: "15m"}, false},
+		{"trimmed", map[string]any{"listen": ":8765", "base_url": "https://approve.example.com/", "approver_header": " X-Auth-Request-Email ", "timeout": "1h"}, ApprovalLink{Listen: ":8765", BaseURL: "https://approve.example.com", ApproverHeader: "X-Auth-Request-Email", Timeout: "1h"}, false},
+		{"no approver header on loopback", map[string]any{"listen": "127.0.0.1:8765"}, ApprovalLink{}, true},
+		{"no approver header", map[string]any{"listen": ":8765", "base_url": "https://approve.example.com"}, ApprovalLink{}, true},
+		{"no base url", map[string]any{"listen": "127.0.0.1:8765", "approver_header": "X-Auth-Request-Email"}, ApprovalLink{}, true},
+		{"http base url", map[string]any{"listen": ":8765", "base_url": "http://approve.example.com", "approver_header": "X-Auth-Request-Email"}, ApprovalLink{}, true},
+		{"missing listen", map[string]any{"base_url": "https://approve.example.com", "approver_header": "X-Auth-Request-Email", "timeout": "1h"}, ApprovalLink{}, true},
+		{"invalid timeout", map[string]any{"listen": "127.0.0.1:8765", "base_url": "https://approve.example.com", "approver_header": "X-Auth-Request-Email", "timeout": "soon"}, ApprovalLink{}, true},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			tmp := t.TempDir()
+			stubConfigDir(t, tmp)
+			writeJSON(t, configFilePath(t, tmp, "config.json"), map[string]any{"approval_link": tc.link})
+
+			got, err := LoadApprovalLink()
+			if tc.wantErr {
+				if err == nil {
+					t.Fatalf("LoadApprovalLink() = %+v, want error", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("LoadApprovalLink() error = %v", err)
+			}
+			if *got != tc.want {
+				t.Errorf("LoadApprovalLink() = %+v, want %+v", *got, tc.want)
+			}
+		})
+	}
+}
+
+func TestLoadShareConfig(t *testing.T) {
+	tmp := t.TempDir()
+	stubConfigDir(t, tmp)
+	t.Setenv("OTS_KEY", "secret-key")
+	writeJSON(t, configFilePath(t, tmp, "config.json"), map[string]any{
+		"share": map[string]any{"url": " https://ots.example.com/ ", "username": "ops@example.com", "api_key": "${env:OTS_KEY}", "ttl": "2d"},
+	})
+
+	got, err := LoadShareConfig()
+	if err != nil {
+		t.Fatalf("LoadShareConfig() error = %v", err)
+	}
+	if want := (ShareConfig{URL: "https://ots.example.com", Username: "ops@example.com", APIKey: "secret-key", TTL: "2d"}); *got != want {
+		t.Errorf("LoadShareConfig() = %+v, want %+v", *got, want)
+	}
+
+	for _, share := range []map[string]any{{"ttl": "1h"}, {"url": "https://ots.example.com", "ttl": "later"}} {
+		writeJSON(t, configFilePath(t, tmp, "config.json"), map[string]any{"share": share})
+		if _, err := LoadShareConfig(); err == nil {
+			t.Errorf("LoadShareConfig(%v) error = nil, want error", share)
+		}
+	}
+}
+
+func TestLoadEmailNotificationAbsent(t *testing.T) {
+	tmp := t.TempDir()
+	stubConfigDir(t, tmp)
+	writeJSON(t, configFilePath(t, tmp, "config.json"), map[string]any{})
+
+	if _, err := LoadEmailNotification(); !errors.Is(err, fs.ErrNotExist) {
+		t.Fatalf("LoadEmailNotification() error = %v, want fs.ErrNotExist", err)
+	}
+}
+
+func TestLoadZoneConfigAccountID(t *testing.T) {
+	tmp := t.TempDir()
+	stubConfigDir(t, tmp)
+
+	writeJSON(t, configFilePath(t, tmp, "config.json"), map[string]any{
+		"account_id": "acc-global",
+		"zones": map[string]any{
+			"inherits": map[string]any{"zone_id": "aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa"},
+			"own":      map[string]any{"zone_id": "bbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbb", "account_id": "acc-own"},
+		},
+	})
+
+	for zone, want := range map[string]string{"inherits": "acc-global", "own": "acc-own"} {
+		_, zc, err := LoadZoneConfig(zone)
+		if err != nil {
+			t.Fatalf("LoadZoneConfig(%q) error = %v", zone, err)
+		}
+		if zc.AccountID != want {
+			t.Errorf("LoadZoneConfig(%q) AccountID = %q, want %q", zone, zc.AccountID, want)
+		}
+	}
+	if id, err := LoadAccountID(); err != nil || id != "acc-global" {
+		t.Fatalf("LoadAccountID() = %q, %v", id, err)
+	}
+}
+
+func TestWindowsDirectories(t *testing.T) {
+	orig := goos
+	goos = "windows"
+	t.Cleanup(func() { goos = orig })
+
+	tests := []struct {
+		name      string
+		env       map[string]string
+		wantPath  string
+		wantState string
+	}{
+		{
+			name:      "appdata",
+			env:       map[string]string{"XDG_CONFIG_HOME": "", "XDG_STATE_HOME": "", "APPDATA": filepath.Join("C:", "Roaming"), "LOCALAPPDATA": filepath.Join("C:", "Local")},
+			wantPath:  filepath.Join("C:", "Roaming", "cftoken", "config.json"),
+			wantState: filepath.Join("C:", "Local", "cftoken", "state"),
+		},
+		{
+			name:      "xdg wins",
+			env:       map[string]string{"XDG_CONFIG_HOME": "xdg", "XDG_STATE_HOME": "xdgstate", "APPDATA": "roaming", "LOCALAPPDATA": "local"},
+			wantPath:  filepath.Join("xdg", "cftoken", "config.json"),
+			wantState: filepath.Join("xdgstate", "cftoken"),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for k, v := range tt.env {
+				t.Setenv(k, v)
+			}
+			if got, err := DefaultPath(); err != nil || got != tt.wantPath {
+				t.Errorf("DefaultPath() = %q, %v; want %q", got, err, tt.wantPath)
+			}
+			if got, err := StateDir(); err != nil || got != tt.wantState {
+				t.Errorf("StateDir() = %q, %v; want %q", got, err, tt.wantState)
+			}
+		})
+	}
+}
@@ -0,0 +1,338 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"strings"
+)
+
+// ZoneEdit lists the zone fields SetZone changes. A nil field is left as
+// it is; a pointer to "" or an empty, non-nil list removes the field.
+type ZoneEdit struct {
+	ZoneID       *string
+	AccountID    *string
+	TTL          *string
+	TemplateFile *string
+	Extends      *string
+	Permissions  []string
+	AllowedCIDRs []string
+}
+
+// SetZone creates or updates a zone in the config file. Every other key,
+// including unknown ones, keeps its value and position; only indentation is
+// normalized to what the file already uses. A zone that only has a zone_id
+// stays a plain "name": "id" entry.
+func SetZone(name string, edit ZoneEdit) error {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return errors.New("zone name is required")
+	}
+	return editConfig(func(root *orderedObject) (string, error) {
+		zones, err := root.object("zones")
+		if err != nil {
+			return "", err
+		}
+		key := zones.find(name)
+		if key == "" {
+			key = name
+		}
+
+		var entry orderedObject
+		current, exists := zones.get(key)
+		var simpleID string
+		switch {
+		case !exists:
+			if edit.ZoneID == nil || strings.TrimSpace(*edit.ZoneID) == "" {
+				return "", fmt.Errorf("zone %q is new; -zone-id is required", name)
+			}
+		case json.Unmarshal(current, &simpleID) == nil:
+			entry.set("zone_id", simpleID)
+		default:
+			if entry, err = parseObject(current); err != nil {
+				return "", fmt.Errorf("zone %q: %w", key, err)
+			}
+		}
+
+		for _, f := range []struct {
+			key   string
+			value *string
+		}{
+			{"zone_id", edit.ZoneID},
+			{"account_id", edit.AccountID},
+			{"ttl", edit.TTL},
+			{"template_file", edit.TemplateFile},
+			{"extends", edit.Extends},
+		} {
+			switch {
+			case f.value == nil:
+			case strings.TrimSpace(*f.value) == "":
+				entry.remove(f.key)
+			default:
+				entry.set(f.key, strings.TrimSpace(*f.value))
+			}
+		}
+		for _, f := range []struct {
+			key   string
+			value []string
+		}{
+			{"permissions", edit.Permissions},
+			{"allowed_cidrs", edit.AllowedCIDRs},
+		} {
+			switch {
+			case f.value == nil:
+			case len(f.value) == 0:
+				entry.remove(f.key)
+			default:
+				entry.set(f.key, f.value)
+			}
+		}
+
+		if len(entry) == 1 && entry[0].Key == "zone_id" {
+			zones.setRaw(key, entry[0].Value)
+		} else {
+			data, err := json.Marshal(entry)
+			if err != nil {
+				return "", err
+			}
+			zones.setRaw(key, data)
+		}
+		return key, root.setObject("zones", zones)
+	})
+}
+
+// RemoveZone deletes a zone from the config file, leaving everything else
+// as SetZone does.
+func RemoveZone(name string) error {
+	return editConfig(func(root *orderedObject) (string, error) {
+		zones, err := root.object("zones")
+		if err != nil {
+			return "", err
+		}
+		key := zones.find(name)
+		if key == "" {
+			return "", fmt.Errorf("zone %q not found: %w", name, fs.ErrNotExist)
+		}
+		zones.remove(key)
+		return "", root.setObject("zones", zones)
+	})
+}
+
+// editConfig applies change to the config file and writes it back once the
+// result still decodes. change returns the zone it edited, if any, whose
+// entry and profile chain are checked too. References are not expanded, so
+// ${env:...} values need not be set while editing. A missing file starts
+// out empty.
+func editConfig(change func(*orderedObject) (string, error)) error {
+	path, err := DefaultPath()
+	if err != nil {
+		return err
+	}
+	data, err := os.ReadFile(path)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	indent := "  "
+	var root orderedObject
+	if len(bytes.TrimSpace(data)) > 0 {
+		if root, err = parseObject(data); err != nil {
+			return fmt.Errorf("parse config %s: %w", path, err)
+		}
+		indent = detectIndent(data)
+	}
+	zone, err := change(&root)
+	if err != nil {
+		return err
+	}
+
+	compact, err := json.Marshal(root)
+	if err != nil {
+		return fmt.Errorf("encode config: %w", err)
+	}
+	var cfg settings
+	if err := json.Unmarshal(compact, &cfg); err != nil {
+		return fmt.Errorf("edited config is invalid: %w", err)
+	}
+	if err := checkEditedZone(&cfg, zone); err != nil {
+		return fmt.Errorf("edited config is invalid: %w", err)
+	}
+
+	var out bytes.Buffer
+	if err := json.Indent(&out, compact, "", indent); err != nil {
+		return fmt.Errorf("encode config: %w", err)
+	}
+	out.WriteByte('\n')
+	return WriteFileAtomic(path, out.Bytes())
+}
+
+// checkEditedZone decodes the zone entry and resolves its profile chain.
+func checkEditedZone(cfg *settings, zone string) error {
+	entry, ok := cfg.Zones[zone]
+	if zone == "" || !ok {
+		return nil
+	}
+	if _, ok := entry.(string); ok {
+		return nil
+	}
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	var zc ZoneConfig
+	if err := json.Unmarshal(data, &zc); err != nil {
+		return fmt.Errorf("zone %q: %w", zone, err)
+	}
+	if zc.Extends != "" {
+		if _, err := resolveProfile(cfg.Profiles, zc.Extends, nil); err != nil {
+			return fmt.Errorf("zone %q: %w", zone, err)
+		}
+	}
+	return nil
+}
+
+// detectIndent returns the whitespace the first indented line of data
+// starts with, or two spaces.
+func detectIndent(data []byte) string {
+	for _, line := range strings.Split(string(data), "\n")[1:] {
+		trimmed := strings.TrimLeft(line, " \t")
+		if trimmed == "" || len(trimmed) == len(line) {
+			continue
+		}
+		return line[:len(line)-len(trimmed)]
+	}
+	return "  "
+}
+
+// member is one key of a JSON object with its value kept verbatim.
+type member struct {
+	Key   string
+	Value json.RawMessage
+}
+
+// orderedObject is a JSON object that keeps its key order, so edits do not
+// reshuffle a hand-written config file.
+type orderedObject []member
+
+func parseObject(data []byte) (orderedObject, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	tok, err := dec.Token()
+	if err != nil {
+		return nil, err
+	}
+	if delim, ok := tok.(json.Delim); !ok || delim != '{' {
+		return nil, errors.New("expected a JSON object")
+	}
+	var obj orderedObject
+	for dec.More() {
+		tok, err := dec.Token()
+		if err != nil {
+			return nil, err
+		}
+		key, _ := tok.(string)
+		var value json.RawMessage
+		if err := dec.Decode(&value); err != nil {
+			return nil, err
+		}
+		obj = append(obj, member{Key: key, Value: value})
+	}
+	if _, err := dec.Token(); err != nil {
+		return nil, err
+	}
+	return obj, nil
+}
+
+func (o orderedObject) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for i, m := range o {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		key, err := json.Marshal(m.Key)
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(key)
+		buf.WriteByte(':')
+		if err := json.Compact(&buf, m.Value); err != nil {
+			return nil, err
+		}
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+func (o orderedObject) get(key string) (json.RawMessage, bool) {
+	for _, m := range o {
+		if m.Key == key {
+			return m.Value, true
+		}
+	}
+	return nil, false
+}
+
+// find returns the key naming zone, compared the way zone names are
+// resolved, or "".
+func (o orderedObject) find(zone string) string {
+	want := normalizeZoneName(zone)
+	for _, m := range o {
+		if m.Key == zone {
+			return m.Key
+		}
+	}
+	for _, m := range o {
+		if normalizeZoneName(m.Key) == want {
+			return m.Key
+		}
+	}
+	return ""
+}
+
+func (o *orderedObject) setRaw(key string, value json.RawMessage) {
+	for i, m := range *o {
+		if m.Key == key {
+			(*o)[i].Value = value
+			return
+		}
+	}
+	*o = append(*o, member{Key: key, Value: value})
+}
+
+func (o *orderedObject) set(key string, value any) {
+	data, _ := json.Marshal(value)
+	o.setRaw(key, data)
+}
+
+func (o *orderedObject) remove(key string) {
+	for i, m := range *o {
+		if m.Key == key {
+			*o = append((*o)[:i], (*o)[i+1:]...)
+			return
+		}
+	}
+}
+
+// object returns the object stored under key, or an empty one.
+func (o orderedObject) object(key string) (orderedObject, error) {
+	raw, ok := o.get(key)
+	if !ok || string(bytes.TrimSpace(raw)) == "null" {
+		return orderedObject{}, nil
+	}
+	obj, err := parseObject(raw)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", key, err)
+	}
+	return obj, nil
+}
+
+func (o *orderedObject) setObject(key string, obj orderedObject) error {
+	data, err := json.Marshal(obj)
+	if err != nil {
+		return err
+	}
+	o.setRaw(key, data)
+	return nil
+}
@@ -0,0 +1,122 @@
+package config
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestSetZone(t *testing.T) {
+	tmp := t.TempDir()
+	stubConfigDir(t, tmp)
+	path := configFilePath(t, tmp, "config.json")
+	if err := os.MkdirAll(strings.TrimSuffix(path, "config.json"), 0o700); err != nil {
+		t.Fatal(err)
+	}
+	original := "{\n\t\"zones\": {\n\t\t\"b.example.com\": \"bbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbb\",\n\t\t\"a.example.com\": {\"zone_id\": \"aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa\", \"ttl\": \"1h\", \"x_note\": \"keep\"}\n\t},\n\t\"default_permissions\": [\"Zone:Read\"]\n}\n"
+	if err := os.WriteFile(path, []byte(original), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	ttl, empty := "4h", ""
+	steps := []struct {
+		zone string
+		edit ZoneEdit
+	}{
+		{"B.example.com", ZoneEdit{TTL: &ttl, AllowedCIDRs: []string{"10.0.0.0/8"}}},
+		{"a.example.com", ZoneEdit{TTL: &empty, Permissions: []string{"DNS Write"}}},
+		{"c.example.com", ZoneEdit{ZoneID: strPtr("cccccccccccccccccccccccccccccccc")}},
+	}
+	for _, step := range steps {
+		if err := SetZone(step.zone, step.edit); err != nil {
+			t.Fatalf("SetZone(%q) error = %v", step.zone, err)
+		}
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `{
+	"zones": {
+		"b.example.com": {
+			"zone_id": "bbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbb",
+			"ttl": "4h",
+			"allowed_cidrs": [
+				"10.0.0.0/8"
+			]
+		},
+		"a.example.com": {
+			"zone_id": "aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa",
+			"x_note": "keep",
+			"permissions": [
+				"DNS Write"
+			]
+		},
+		"c.example.com": "cccccccccccccccccccccccccccccccc"
+	},
+	"default_permissions": [
+		"Zone:Read"
+	]
+}
+`
+	if string(data) != want {
+		t.Fatalf("config after SetZone:\n%s\nwant:\n%s", data, want)
+	}
+
+	if err := RemoveZone("a.example.com"); err != nil {
+		t.Fatalf("RemoveZone() error = %v", err)
+	}
+	if err := RemoveZone("a.example.com"); !errors.Is(err, fs.ErrNotExist) {
+		t.Fatalf("RemoveZone() of missing zone error = %v, want fs.ErrNotExist", err)
+	}
+	if _, _, err := LoadZoneConfig("a.example.com"); err == nil {
+		t.Fatalf("LoadZoneConfig() after RemoveZone error = nil")
+	}
+}
+
+func TestSetZoneErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		zone string
+		edit ZoneEdit
+		want string
+	}{
+		{"new without id", "new.example.com", ZoneEdit{TTL: strPtr("1h")}, "-zone-id is required"},
+		{"unknown profile", "dev", ZoneEdit{Extends: strPtr("missing")}, `profile "missing" not found`},
+		{"no name", " ", ZoneEdit{}, "zone name is required"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			tmp := t.TempDir()
+			stubConfigDir(t, tmp)
+			writeJSON(t, configFilePath(t, tmp, "config.json"), map[string]any{
+				"zones": map[string]any{"dev": "dddddddddddddddddddddddddddddddd"},
+			})
+			before, _ := os.ReadFile(configFilePath(t, tmp, "config.json"))
+
+			err := SetZone(tc.zone, tc.edit)
+			if err == nil || !strings.Contains(err.Error(), tc.want) {
+				t.Fatalf("SetZone() error = %v, want %q", err, tc.want)
+			}
+			if after, _ := os.ReadFile(configFilePath(t, tmp, "config.json")); string(after) != string(before) {
+				t.Fatalf("SetZone() changed the file after failing:\n%s", after)
+			}
+		})
+	}
+}
+
+func TestSetZoneCreatesConfig(t *testing.T) {
+	tmp := t.TempDir()
+	stubConfigDir(t, tmp)
+	if err := SetZone("example.com", ZoneEdit{ZoneID: strPtr("aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa")}); err != nil {
+		t.Fatalf("SetZone() error = %v", err)
+	}
+	if id, err := ResolveZoneID("example.com"); err != nil || id != "aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa" {
+		t.Fatalf("ResolveZoneID() = %q, %v", id, err)
+	}
+}
+
+func strPtr(s string) *string { return &s }
@@ -0,0 +1,187 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// referencePattern matches ${path} references inside config values.
+var referencePattern = regexp.MustCompile(`\$\{([^}]*)\}`)
+
+// interpolateZone expands references in every string a zone config carries,
+// including its template variables. seen is the chain of zones being
+// resolved, for cycle detection.
+func (cfg *settings) interpolateZone(zc *ZoneConfig, seen []string) error {
+	expand := func(s string) (string, error) { return cfg.interpolate(s, seen) }
+
+	var err error
+	for _, field := range []*string{&zc.ZoneID, &zc.AccountID, &zc.TTL, &zc.TemplateFile, &zc.TemplateInline} {
+		if *field, err = expand(*field); err != nil {
+			return err
+		}
+	}
+	for _, list := range [][]string{zc.Permissions, zc.AllowedCIDRs} {
+		for i := range list {
+			if list[i], err = expand(list[i]); err != nil {
+				return err
+			}
+		}
+	}
+	for k, v := range zc.Variables {
+		if zc.Variables[k], err = cfg.interpolateValue(v, seen); err != nil {
+			return fmt.Errorf("variable %q: %w", k, err)
+		}
+	}
+	return nil
+}
+
+// interpolateValue expands references in strings nested anywhere in v.
+func (cfg *settings) interpolateValue(v interface{}, seen []string) (interface{}, error) {
+	switch v := v.(type) {
+	case string:
+		return cfg.interpolate(v, seen)
+	case []interface{}:
+		out := make([]interface{}, len(v))
+		for i, item := range v {
+			expanded, err := cfg.interpolateValue(item, seen)
+			if err != nil {
+				return nil, err
+			}
+			out[i] = expanded
+		}
+		return out, nil
+	case map[string]interface{}:
+		out := make(map[string]interface{}, len(v))
+		for k, item := range v {
+			expanded, err := cfg.interpolateValue(item, seen)
+			if err != nil {
+				return nil, err
+			}
+			out[k] = expanded
+		}
+		return out, nil
+	default:
+		return v, nil
+	}
+}
+
+// interpolate replaces each ${path} in s with the config value it names.
+func (cfg *settings) interpolate(s string, seen []string) (string, error) {
+	if !strings.Contains(s, "${") {
+		return s, nil
+	}
+	var firstErr error
+	out := referencePattern.ReplaceAllStringFunc(s, func(match string) string {
+		if firstErr != nil {
+			return match
+		}
+		value, err := cfg.lookupReference(strings.TrimSpace(match[2:len(match)-1]), seen)
+		if err != nil {
+			firstErr = fmt.Errorf("resolve %s: %w", match, err)
+			return match
+		}
+		return value
+	})
+	return out, firstErr
+}
+
+// lookupReference resolves env:NAME, account_id, or zones.NAME[.FIELD...].
+// Zone names usually contain dots, so NAME is the longest configured zone
+// name the path starts with. FIELD defaults to zone_id and may walk into
+// variables, as in zones.example.com.variables.region.
+func (cfg *settings) lookupReference(ref string, seen []string) (string, error) {
+	if name, ok := strings.CutPrefix(ref, "env:"); ok {
+		return lookupEnv(name)
+	}
+	if ref == "account_id" {
+		return cfg.accountID(seen)
+	}
+	rest, ok := strings.CutPrefix(ref, "zones.")
+	if !ok {
+		return "", fmt.Errorf("unknown reference %q; use env:NAME, zones.NAME.FIELD, or account_id", ref)
+	}
+
+	names := make([]string, 0, len(cfg.Zones))
+	for name := range cfg.Zones {
+		names = append(names, name)
+	}
+	sort.Slice(names, func(a, b int) bool { return len(names[a]) > len(names[b]) })
+	for _, name := range names {
+		if rest != name && !strings.HasPrefix(rest, name+".") {
+			continue
+		}
+		path := strings.TrimPrefix(strings.TrimPrefix(rest, name), ".")
+		if path == "" {
+			path = "zone_id"
+		}
+		return cfg.zoneField(name, path, seen)
+	}
+	return "", fmt.Errorf("zone in %q not found", ref)
+}
+
+// lookupEnv reads the environment variable a ${env:NAME} reference names.
+// Set but empty variables are accepted; unset ones are an error so a missing
+// secret is noticed at load time rather than sent to an API as "".
+func lookupEnv(name string) (string, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return "", fmt.Errorf("env reference needs a variable name, as in ${env:NAME}")
+	}
+	value, ok := os.LookupEnv(name)
+	if !ok {
+		return "", fmt.Errorf("environment variable %s is not set", name)
+	}
+	return value, nil
+}
+
+// accountID returns the top-level account_id with references expanded.
+func (cfg *settings) accountID(seen []string) (string, error) {
+	id, err := cfg.interpolate(strings.TrimSpace(cfg.AccountID), seen)
+	if err != nil {
+		return "", fmt.Errorf("account_id: %w", err)
+	}
+	return id, nil
+}
+
+func (cfg *settings) zoneField(zoneName, path string, seen []string) (string, error) {
+	zoneID, zc, err := cfg.resolveZone(zoneName, seen)
+	if err != nil {
+		return "", err
+	}
+	if zc == nil {
+		if path != "zone_id" {
+			return "", fmt.Errorf("zone %q only has a zone_id", zoneName)
+		}
+		return zoneID, nil
+	}
+
+	data, err := json.Marshal(zc)
+	if err != nil {
+		return "", err
+	}
+	var value interface{}
+	if err := json.Unmarshal(data, &value); err != nil {
+		return "", err
+	}
+	for _, key := range strings.Split(path, ".") {
+		m, ok := value.(map[string]interface{})
+		if !ok {
+			return "", fmt.Errorf("zone %q has no field %q", zoneName, path)
+		}
+		if value, ok = m[key]; !ok {
+			return "", fmt.Errorf("zone %q has no field %q", zoneName, path)
+		}
+	}
+	switch v := value.(type) {
+	case string:
+		return v, nil
+	case float64, bool:
+		return fmt.Sprint(v), nil
+	default:
+		return "", fmt.Errorf("zone %q field %q is not a single value", zoneName, path)
+	}
+}
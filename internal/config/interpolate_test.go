@@ -0,0 +1,129 @@
+package config
+
+import (
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestLoadZoneConfigInterpolation(t *testing.T) {
+	tmp := t.TempDir()
+	stubConfigDir(t, tmp)
+
+	writeJSON(t, configFilePath(t, tmp, "config.json"), map[string]any{
+		"account_id": "acct",
+		"profiles": map[string]any{
+			"pair": map[string]any{"variables": map[string]any{"Failover": "${zones.failover.example.com.zone_id}"}},
+		},
+		"zones": map[string]any{
+			"example.com": map[string]any{
+				"zone_id":   "aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa",
+				"extends":   "pair",
+				"variables": map[string]any{"Zones": []any{"${account_id}", "${zones.failover.example.com}"}, "Region": "${zones.failover.example.com.variables.Region}"},
+			},
+			"failover.example.com": map[string]any{
+				"zone_id":    "bbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbb",
+				"account_id": "${account_id}",
+				"variables":  map[string]any{"Region": "eu"},
+			},
+			"alias.example.com": "${zones.example.com}",
+		},
+	})
+
+	_, zc, err := LoadZoneConfig("example.com")
+	if err != nil {
+		t.Fatalf("LoadZoneConfig() error = %v", err)
+	}
+	want := map[string]interface{}{
+		"Failover": "bbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbb",
+		"Zones":    []interface{}{"acct", "bbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbb"},
+		"Region":   "eu",
+	}
+	if !reflect.DeepEqual(zc.Variables, want) {
+		t.Errorf("Variables = %v, want %v", zc.Variables, want)
+	}
+
+	_, failover, err := LoadZoneConfig("failover.example.com")
+	if err != nil || failover.AccountID != "acct" {
+		t.Errorf("LoadZoneConfig(failover) = %+v, %v; want account_id acct", failover, err)
+	}
+	if zoneID, _, err := LoadZoneConfig("alias.example.com"); err != nil || zoneID != "aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa" {
+		t.Errorf("LoadZoneConfig(alias) = %q, %v", zoneID, err)
+	}
+}
+
+func TestLoadZoneConfigInterpolationErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		zone any
+		want string
+	}{
+		{"unknown root", map[string]any{"zone_id": "${secrets.x}"}, "unknown reference"},
+		{"unknown zone", map[string]any{"zone_id": "${zones.missing.com.zone_id}"}, "not found"},
+		{"unknown field", map[string]any{"zone_id": "${zones.other.com.variables.Nope}"}, `has no field "variables.Nope"`},
+		{"not a value", map[string]any{"zone_id": "x", "ttl": "${zones.other.com.allowed_cidrs}"}, "not a single value"},
+		{"cycle", map[string]any{"zone_id": "${zones.dev.com.ttl}", "ttl": "1h"}, "reference cycle: dev.com -> dev.com"},
+		{"unset env", map[string]any{"zone_id": "x", "variables": map[string]any{"Secret": "${env:CFTOKEN_TEST_UNSET}"}}, "environment variable CFTOKEN_TEST_UNSET is not set"},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			tmp := t.TempDir()
+			stubConfigDir(t, tmp)
+			writeJSON(t, configFilePath(t, tmp, "config.json"), map[string]any{
+				"zones": map[string]any{
+					"dev.com":   tc.zone,
+					"other.com": map[string]any{"zone_id": "bbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbb", "allowed_cidrs": []string{"10.0.0.0/8"}},
+				},
+			})
+			_, _, err := LoadZoneConfig("dev.com")
+			if err == nil || !strings.Contains(err.Error(), tc.want) {
+				t.Fatalf("LoadZoneConfig() error = %v, want %q", err, tc.want)
+			}
+		})
+	}
+}
+
+func TestEnvReferences(t *testing.T) {
+	tmp := t.TempDir()
+	stubConfigDir(t, tmp)
+	t.Setenv("CFTOKEN_TEST_ACCOUNT", "acct")
+	t.Setenv("CFTOKEN_TEST_SMTP", "s3cret")
+	t.Setenv("CFTOKEN_TEST_EMPTY", "")
+
+	writeJSON(t, configFilePath(t, tmp, "config.json"), map[string]any{
+		"account_id": "${env:CFTOKEN_TEST_ACCOUNT}",
+		"notifications": map[string]any{"email": map[string]any{
+			"host": "smtp.example.com", "from": "a@example.com", "to": []string{"b@example.com"},
+			"password": "${env:CFTOKEN_TEST_SMTP}",
+		}},
+		"zones": map[string]any{
+			"example.com": map[string]any{
+				"zone_id":   "aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa",
+				"variables": map[string]any{"Empty": "x${env:CFTOKEN_TEST_EMPTY}"},
+			},
+		},
+	})
+
+	if id, err := LoadAccountID(); err != nil || id != "acct" {
+		t.Errorf("LoadAccountID() = %q, %v; want acct", id, err)
+	}
+	email, err := LoadEmailNotification()
+	if err != nil || email.Password != "s3cret" {
+		t.Errorf("LoadEmailNotification() = %+v, %v; want password from env", email, err)
+	}
+	_, zc, err := LoadZoneConfig("example.com")
+	if err != nil {
+		t.Fatalf("LoadZoneConfig() error = %v", err)
+	}
+	if zc.AccountID != "acct" || zc.Variables["Empty"] != "x" {
+		t.Errorf("LoadZoneConfig() = %+v", zc)
+	}
+
+	t.Setenv("CFTOKEN_TEST_SMTP", "")
+	os.Unsetenv("CFTOKEN_TEST_SMTP")
+	if _, err := LoadEmailNotification(); err == nil || !strings.Contains(err.Error(), "notifications.email: resolve ${env:CFTOKEN_TEST_SMTP}: environment variable CFTOKEN_TEST_SMTP is not set") {
+		t.Errorf("LoadEmailNotification() with unset variable error = %v", err)
+	}
+}
@@ -0,0 +1,182 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"sort"
+	"strings"
+
+	"cftoken/internal/template"
+)
+
+// implicitVariables are injected into every zone template by the CLI.
+var implicitVariables = map[string]bool{"ZoneID": true, "AccountID": true}
+
+// ImplicitVariable reports whether the CLI passes name to every template,
+// so that it needs no declaration.
+func ImplicitVariable(name string) bool {
+	return implicitVariables[name]
+}
+
+// Finding is a single problem reported by Lint.
+type Finding struct {
+	// Subject names what the finding is about, e.g. `zone "prod"`.
+	Subject string
+	Message string
+}
+
+func (f Finding) String() string {
+	return f.Subject + ": " + f.Message
+}
+
+// Lint inspects the configuration file for entries that are shadowed, unused,
+// or inconsistent with their templates. Findings are sorted by subject.
+func Lint() ([]Finding, error) {
+	cfg, err := loadSettings()
+	if err != nil {
+		return nil, err
+	}
+
+	var findings []Finding
+	add := func(subject, format string, args ...any) {
+		findings = append(findings, Finding{Subject: subject, Message: fmt.Sprintf(format, args...)})
+	}
+
+	names := make([]string, 0, len(cfg.Zones))
+	for name := range cfg.Zones {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	// Zones whose names normalize to the same key, or are written twice,
+	// shadow each other in -list-zones.
+	byNormalized := make(map[string][]string)
+	for _, name := range names {
+		n := normalizeZoneName(name)
+		for range cfg.zoneIDs(name) {
+			byNormalized[n] = append(byNormalized[n], name)
+		}
+	}
+	for _, n := range sortedKeys(byNormalized) {
+		group := byNormalized[n]
+		switch {
+		case len(group) < 2:
+		case zoneConflict(cfg, n) != nil:
+			add(fmt.Sprintf("zone %q", n), "defined more than once as %s with different zone IDs; it cannot be used until all but one are removed", quoteAll(group))
+		default:
+			add(fmt.Sprintf("zone %q", n), "defined more than once as %s; only one is listed", quoteAll(group))
+		}
+	}
+
+	usedProfiles := make(map[string]bool)
+	for _, p := range cfg.Profiles {
+		if p.Extends != "" {
+			usedProfiles[p.Extends] = true
+		}
+	}
+
+	defaultPermsUsed, defaultCIDRsUsed := false, false
+	for _, name := range names {
+		subject := fmt.Sprintf("zone %q", name)
+		if m, ok := cfg.Zones[name].(map[string]interface{}); ok {
+			if extends, ok := m["extends"].(string); ok {
+				usedProfiles[extends] = true
+			}
+		}
+		_, zc, err := cfg.zoneConfig(name)
+		if err != nil {
+			add(subject, "%v", err)
+			continue
+		}
+		if zc == nil {
+			defaultPermsUsed, defaultCIDRsUsed = true, true
+			continue
+		}
+
+		hasTemplate := zc.TemplateFile != "" || zc.TemplateInline != ""
+		if !hasTemplate && len(zc.Permissions) == 0 {
+			defaultPermsUsed = true
+		}
+		if len(zc.AllowedCIDRs) == 0 {
+			defaultCIDRsUsed = true
+		}
+		if zc.InheritDefaults && len(cfg.DefaultPermissions) == 0 && len(cfg.DefaultAllowedCIDRs) == 0 {
+			add(subject, "inherit_defaults is set but no defaults are configured")
+		}
+		if hasTemplate && len(zc.Permissions) > 0 {
+			add(subject, "permissions are ignored because a template is configured")
+		}
+		if !hasTemplate {
+			if len(zc.Variables) > 0 {
+				add(subject, "variables are set but no template uses them")
+			}
+			continue
+		}
+
+		refs, err := template.ReferencedVariables(zc.TemplateFile, zc.TemplateInline)
+		if err != nil {
+			add(subject, "%v", err)
+			continue
+		}
+		var spec template.Spec
+		if zc.TemplateInline == "" {
+			if loaded, err := template.LoadSpec(zc.TemplateFile); err == nil {
+				spec = *loaded
+			} else if !errors.Is(err, fs.ErrNotExist) {
+				add(subject, "%v", err)
+			}
+		}
+		referenced := make(map[string]bool, len(refs))
+		for _, ref := range refs {
+			referenced[ref] = true
+			_, inSpec := spec.Variables[ref]
+			if _, declared := zc.Variables[ref]; !declared && !inSpec && !ImplicitVariable(ref) {
+				add(subject, "template references undeclared variable %q; it must be passed with -var", ref)
+			}
+		}
+		for _, v := range sortedKeys(zc.Variables) {
+			if !referenced[v] {
+				add(subject, "variable %q is never referenced by the template", v)
+			}
+		}
+	}
+
+	for _, name := range sortedKeys(cfg.Profiles) {
+		if !usedProfiles[name] {
+			add(fmt.Sprintf("profile %q", name), "not extended by any zone or profile")
+		}
+	}
+
+	// Defaults still apply to -zone-id, so only flag them once zones exist.
+	if len(names) > 0 {
+		if len(cfg.DefaultPermissions) > 0 && !defaultPermsUsed {
+			add("default_permissions", "every zone sets its own permissions or template; defaults only apply to -zone-id")
+		}
+		if len(cfg.DefaultAllowedCIDRs) > 0 && !defaultCIDRsUsed {
+			add("default_allowed_cidrs", "every zone sets its own allowed_cidrs; defaults only apply to -zone-id")
+		}
+	}
+
+	sort.SliceStable(findings, func(i, j int) bool {
+		return findings[i].Subject < findings[j].Subject
+	})
+	return findings, nil
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func quoteAll(values []string) string {
+	quoted := make([]string, len(values))
+	for i, v := range values {
+		quoted[i] = fmt.Sprintf("%q", v)
+	}
+	return strings.Join(quoted, ", ")
+}
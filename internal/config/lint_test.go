@@ -0,0 +1,74 @@
+package config
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestLint(t *testing.T) {
+	tmp := t.TempDir()
+	stubConfigDir(t, tmp)
+
+	writeFile(t, configFilePath(t, tmp, "config.json"), `{
+  "default_allowed_cidrs": ["10.0.0.1/32"],
+  "profiles": {
+    "unused": {"ttl": "1h"}
+  },
+  "zones": {
+    "Example.com": "aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa",
+    "example.com.": "bbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbb",
+    "twice.com": "dddddddddddddddddddddddddddddddd",
+    "twice.com": "dddddddddddddddddddddddddddddddd",
+    "templated": {
+      "zone_id": "cccccccccccccccccccccccccccccccc",
+      "allowed_cidrs": ["10.0.1.0/24"],
+      "template_inline": "[{{ if .IncludeEdit }}{{ .ZoneID }}{{ .Missing }}{{ end }}]",
+      "variables": {"IncludeEdit": true, "Stale": 1}
+    }
+  }
+}`)
+
+	findings, err := Lint()
+	if err != nil {
+		t.Fatalf("Lint() error = %v", err)
+	}
+	var got []string
+	for _, f := range findings {
+		got = append(got, f.String())
+	}
+	want := []string{
+		`profile "unused": not extended by any zone or profile`,
+		`zone "example.com": defined more than once as "Example.com", "example.com." with different zone IDs; it cannot be used until all but one are removed`,
+		`zone "templated": template references undeclared variable "Missing"; it must be passed with -var`,
+		`zone "templated": variable "Stale" is never referenced by the template`,
+		`zone "twice.com": defined more than once as "twice.com", "twice.com"; only one is listed`,
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Fatalf("Lint() =\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+}
+
+func TestLintUnreachableDefaults(t *testing.T) {
+	tmp := t.TempDir()
+	stubConfigDir(t, tmp)
+
+	writeFile(t, configFilePath(t, tmp, "config.json"), `{
+  "default_permissions": ["Zone:Read"],
+  "default_allowed_cidrs": ["10.0.0.1/32"],
+  "zones": {
+    "readonly": {
+      "zone_id": "aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa",
+      "permissions": ["Zone:Read"],
+      "allowed_cidrs": ["10.0.0.1/32"]
+    }
+  }
+}`)
+
+	findings, err := Lint()
+	if err != nil {
+		t.Fatalf("Lint() error = %v", err)
+	}
+	if len(findings) != 2 || findings[0].Subject != "default_allowed_cidrs" || findings[1].Subject != "default_permissions" {
+		t.Fatalf("Lint() = %v, want both defaults flagged", findings)
+	}
+}
@@ -0,0 +1,178 @@
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+const permissionLockFile = "permissions.lock.json"
+
+// PermissionLock pins the permission group references used by the
+// configuration to the IDs they resolved to when the lock was generated.
+type PermissionLock struct {
+	GeneratedAt time.Time                   `json:"generated_at"`
+	Permissions map[string]LockedPermission `json:"permissions"`
+}
+
+// LockedPermission is the group a reference resolved to.
+type LockedPermission struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
+
+// PermissionLockPath returns the location of permissions.lock.json, next to
+// config.json.
+func PermissionLockPath() (string, error) {
+	dir, err := configDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, permissionLockFile), nil
+}
+
+// LoadPermissionLock reads the lock file. It returns an error wrapping
+// fs.ErrNotExist when no lock has been generated.
+func LoadPermissionLock() (*PermissionLock, error) {
+	path, err := PermissionLockPath()
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var lock PermissionLock
+	if err := json.Unmarshal(data, &lock); err != nil {
+		return nil, fmt.Errorf("parse %s: %w", path, err)
+	}
+	return &lock, nil
+}
+
+// SavePermissionLock writes the lock file atomically.
+func SavePermissionLock(lock *PermissionLock) error {
+	path, err := PermissionLockPath()
+	if err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(lock, "", "  ")
+	if err != nil {
+		return fmt.Errorf("encode permission lock: %w", err)
+	}
+	return WriteFileAtomic(path, append(data, '\n'))
+}
+
+// WriteFileAtomic replaces path with data via a temporary file in the same
+// directory, creating the directory if needed. Files that several runs
+// read, change, and write back also need LockFile.
+func WriteFileAtomic(path string, data []byte) error {
+	dir := filepath.Dir(path)
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return fmt.Errorf("create %s: %w", dir, err)
+	}
+	tmp, err := os.CreateTemp(dir, "."+filepath.Base(path)+"-*")
+	if err != nil {
+		return fmt.Errorf("write %s: %w", path, err)
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("write %s: %w", path, err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("write %s: %w", path, err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("write %s: %w", path, err)
+	}
+	return nil
+}
+
+// lockWait is how long LockFile waits for another run to let go of a file.
+// lockStale is the age past which a lock file is taken to be left behind by
+// a run that crashed; nothing holds one for more than a read and a write.
+const (
+	lockWait  = 10 * time.Second
+	lockStale = time.Minute
+)
+
+// LockFile takes an exclusive lock on path by creating path.lock, so that
+// concurrent runs read, change, and write path one at a time. The returned
+// function releases the lock.
+func LockFile(path string) (unlock func(), err error) {
+	lock := path + ".lock"
+	if err := os.MkdirAll(filepath.Dir(lock), 0o700); err != nil {
+		return nil, fmt.Errorf("create %s: %w", filepath.Dir(lock), err)
+	}
+	deadline := time.Now().Add(lockWait)
+	for {
+		f, err := os.OpenFile(lock, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o600)
+		if err == nil {
+			f.Close()
+			return func() { os.Remove(lock) }, nil
+		}
+		if !errors.Is(err, fs.ErrExist) {
+			return nil, fmt.Errorf("lock %s: %w", path, err)
+		}
+		if info, err := os.Stat(lock); err == nil && time.Since(info.ModTime()) > lockStale {
+			os.Remove(lock)
+			continue
+		}
+		if time.Now().After(deadline) {
+			return nil, fmt.Errorf("lock %s: another cftoken run holds %s; remove it if none is running", path, lock)
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+}
+
+const permissionSnapshotFile = "permissions.snapshot.json"
+
+// PermissionSnapshot is a saved copy of the permission group catalog.
+type PermissionSnapshot struct {
+	TakenAt time.Time       `json:"taken_at"`
+	Groups  []SnapshotGroup `json:"permission_groups"`
+}
+
+// SnapshotGroup is one permission group in a snapshot.
+type SnapshotGroup struct {
+	ID     string   `json:"id"`
+	Name   string   `json:"name"`
+	Scopes []string `json:"scopes,omitempty"`
+}
+
+// PermissionSnapshotPath returns the default snapshot location, next to
+// config.json.
+func PermissionSnapshotPath() (string, error) {
+	dir, err := configDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, permissionSnapshotFile), nil
+}
+
+// LoadPermissionSnapshot reads a snapshot from path. It returns an error
+// wrapping fs.ErrNotExist when the file does not exist.
+func LoadPermissionSnapshot(path string) (*PermissionSnapshot, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var snap PermissionSnapshot
+	if err := json.Unmarshal(data, &snap); err != nil {
+		return nil, fmt.Errorf("parse %s: %w", path, err)
+	}
+	return &snap, nil
+}
+
+// SavePermissionSnapshot writes a snapshot to path atomically.
+func SavePermissionSnapshot(path string, snap *PermissionSnapshot) error {
+	data, err := json.MarshalIndent(snap, "", "  ")
+	if err != nil {
+		return fmt.Errorf("encode permission snapshot: %w", err)
+	}
+	return WriteFileAtomic(path, append(data, '\n'))
+}
@@ -0,0 +1,102 @@
+package config
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestPermissionLockRoundTrip(t *testing.T) {
+	stubConfigDir(t, t.TempDir())
+
+	if _, err := LoadPermissionLock(); !errors.Is(err, fs.ErrNotExist) {
+		t.Fatalf("LoadPermissionLock() error = %v, want fs.ErrNotExist", err)
+	}
+
+	want := &PermissionLock{
+		GeneratedAt: time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC),
+		Permissions: map[string]LockedPermission{"Zone:Read": {ID: "c8fed203ed3043cba015a93ad1616f1f", Name: "Zone Read"}},
+	}
+	if err := SavePermissionLock(want); err != nil {
+		t.Fatalf("SavePermissionLock() error = %v", err)
+	}
+	got, err := LoadPermissionLock()
+	if err != nil {
+		t.Fatalf("LoadPermissionLock() error = %v", err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("LoadPermissionLock() = %+v, want %+v", got, want)
+	}
+}
+
+func TestPermissionSnapshotRoundTrip(t *testing.T) {
+	stubConfigDir(t, t.TempDir())
+
+	path, err := PermissionSnapshotPath()
+	if err != nil {
+		t.Fatalf("PermissionSnapshotPath() error = %v", err)
+	}
+	if _, err := LoadPermissionSnapshot(path); !errors.Is(err, fs.ErrNotExist) {
+		t.Fatalf("LoadPermissionSnapshot() error = %v, want fs.ErrNotExist", err)
+	}
+
+	want := &PermissionSnapshot{
+		TakenAt: time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC),
+		Groups:  []SnapshotGroup{{ID: "a", Name: "Zone Read", Scopes: []string{"com.cloudflare.api.account.zone"}}},
+	}
+	if err := SavePermissionSnapshot(path, want); err != nil {
+		t.Fatalf("SavePermissionSnapshot() error = %v", err)
+	}
+	got, err := LoadPermissionSnapshot(path)
+	if err != nil {
+		t.Fatalf("LoadPermissionSnapshot() error = %v", err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("LoadPermissionSnapshot() = %+v, want %+v", got, want)
+	}
+}
+
+func TestLockFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state", "ledger.json")
+
+	unlock, err := LockFile(path)
+	if err != nil {
+		t.Fatalf("LockFile() error = %v", err)
+	}
+	acquired := make(chan func())
+	go func() {
+		unlock, err := LockFile(path)
+		if err != nil {
+			t.Errorf("second LockFile() error = %v", err)
+			unlock = func() {}
+		}
+		acquired <- unlock
+	}()
+	select {
+	case <-acquired:
+		t.Fatal("second LockFile() returned while the lock was held")
+	case <-time.After(100 * time.Millisecond):
+	}
+	unlock()
+	(<-acquired)()
+
+	if err := os.WriteFile(path+".lock", nil, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	old := time.Now().Add(-2 * lockStale)
+	if err := os.Chtimes(path+".lock", old, old); err != nil {
+		t.Fatal(err)
+	}
+	unlock, err = LockFile(path)
+	if err != nil {
+		t.Fatalf("LockFile() over a stale lock error = %v", err)
+	}
+	unlock()
+	if _, err := os.Stat(path + ".lock"); !errors.Is(err, fs.ErrNotExist) {
+		t.Fatalf("lock file after unlock: %v, want removed", err)
+	}
+}
@@ -0,0 +1,156 @@
+package config
+
+import (
+	"fmt"
+	"io/fs"
+	"slices"
+	"strings"
+
+	"cftoken/internal/duration"
+)
+
+// maxExtendsDepth bounds profile chains so a typo cannot recurse forever.
+const maxExtendsDepth = 16
+
+// LoadProfiles returns every configured profile with its extends chain
+// resolved, or fs.ErrNotExist when none are configured.
+func LoadProfiles() (map[string]ZoneConfig, error) {
+	cfg, err := loadSettings()
+	if err != nil {
+		return nil, err
+	}
+	if len(cfg.Profiles) == 0 {
+		return nil, fs.ErrNotExist
+	}
+	out := make(map[string]ZoneConfig, len(cfg.Profiles))
+	for name := range cfg.Profiles {
+		profile, err := resolveProfile(cfg.Profiles, name, nil)
+		if err != nil {
+			return nil, err
+		}
+		out[name] = profile
+	}
+	return out, nil
+}
+
+// resolveProfile flattens the named profile and everything it extends into a
+// single ZoneConfig. seen tracks the chain walked so far for cycle detection.
+func resolveProfile(profiles map[string]ZoneConfig, name string, seen []string) (ZoneConfig, error) {
+	for _, s := range seen {
+		if s == name {
+			return ZoneConfig{}, fmt.Errorf("profile cycle: %s -> %s", strings.Join(seen, " -> "), name)
+		}
+	}
+	if len(seen) >= maxExtendsDepth {
+		return ZoneConfig{}, fmt.Errorf("profile chain deeper than %d: %s", maxExtendsDepth, strings.Join(seen, " -> "))
+	}
+	profile, ok := profiles[name]
+	if !ok {
+		return ZoneConfig{}, fmt.Errorf("profile %q not found", name)
+	}
+	if profile.Extends == "" {
+		return profile, nil
+	}
+	base, err := resolveProfile(profiles, profile.Extends, append(seen, name))
+	if err != nil {
+		return ZoneConfig{}, err
+	}
+	return mergeZoneConfig(base, profile), nil
+}
+
+// mergeZoneConfig overlays child on base field by field. Set fields in child
+// win; variables are merged key by key. The template file and inline text are
+// treated as one setting, guardrails only tighten, zone_id is never
+// inherited, and freezing a profile freezes every zone extending it.
+func mergeZoneConfig(base, child ZoneConfig) ZoneConfig {
+	out := child
+	out.Extends = ""
+	if len(out.Permissions) == 0 {
+		out.Permissions = append([]string(nil), base.Permissions...)
+	}
+	if len(out.AllowedCIDRs) == 0 {
+		out.AllowedCIDRs = append([]string(nil), base.AllowedCIDRs...)
+	}
+	if out.AccountID == "" {
+		out.AccountID = base.AccountID
+	}
+	if out.TTL == "" {
+		out.TTL = base.TTL
+	}
+	if out.TemplateFile == "" && out.TemplateInline == "" {
+		out.TemplateFile = base.TemplateFile
+		out.TemplateInline = base.TemplateInline
+	}
+	if len(base.Variables) > 0 {
+		vars := make(map[string]interface{}, len(base.Variables)+len(child.Variables))
+		for k, v := range base.Variables {
+			vars[k] = v
+		}
+		for k, v := range child.Variables {
+			vars[k] = v
+		}
+		out.Variables = vars
+	}
+	if out.Sink == nil {
+		out.Sink = base.Sink
+	}
+	out.Guardrails = tightenGuardrails(base.Guardrails, child.Guardrails)
+	if out.NameSuffix == "" {
+		out.NameSuffix = base.NameSuffix
+	}
+	out.InheritDefaults = base.InheritDefaults || child.InheritDefaults
+	out.Frozen = base.Frozen || child.Frozen
+	return out
+}
+
+// tightenGuardrails combines the guardrails of a profile and a zone
+// extending it, keeping the stricter value of each rule as
+// guardrail.Rules.Tighten does, so a zone setting some guardrails cannot
+// drop the profile's others. A max_ttl that does not parse is kept over a
+// valid one, for the error to surface when the rules are parsed.
+func tightenGuardrails(base, child *Guardrails) *Guardrails {
+	if base == nil || child == nil {
+		if child != nil {
+			return child
+		}
+		return base
+	}
+	out := Guardrails{
+		RequireIPRestriction:    base.RequireIPRestriction || child.RequireIPRestriction,
+		MaxTTL:                  stricterMaxTTL(base.MaxTTL, child.MaxTTL),
+		DeniedPermissions:       append(append([]string(nil), base.DeniedPermissions...), child.DeniedPermissions...),
+		RequireTicket:           base.RequireTicket || child.RequireTicket,
+		TicketPattern:           child.TicketPattern,
+		TicketInName:            base.TicketInName || child.TicketInName,
+		InheritedTicketPatterns: append([]string(nil), child.InheritedTicketPatterns...),
+	}
+	if strings.TrimSpace(out.TicketPattern) == "" {
+		out.TicketPattern = base.TicketPattern
+	}
+	for _, p := range append([]string{base.TicketPattern}, base.InheritedTicketPatterns...) {
+		if strings.TrimSpace(p) != "" && p != out.TicketPattern && !slices.Contains(out.InheritedTicketPatterns, p) {
+			out.InheritedTicketPatterns = append(out.InheritedTicketPatterns, p)
+		}
+	}
+	return &out
+}
+
+// stricterMaxTTL returns the shorter of two max_ttl values, treating an
+// empty one as no cap.
+func stricterMaxTTL(a, b string) string {
+	if strings.TrimSpace(a) == "" {
+		return b
+	}
+	if strings.TrimSpace(b) == "" {
+		return a
+	}
+	da, errA := duration.Parse(a)
+	if errA != nil {
+		return a
+	}
+	db, errB := duration.Parse(b)
+	if errB != nil || db < da {
+		return b
+	}
+	return a
+}
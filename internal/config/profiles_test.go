@@ -0,0 +1,145 @@
+package config
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestLoadZoneConfigExtends(t *testing.T) {
+	tmp := t.TempDir()
+	stubConfigDir(t, tmp)
+
+	writeJSON(t, configFilePath(t, tmp, "config.json"), map[string]any{
+		"default_permissions": []string{"Zone:Read"},
+		"profiles": map[string]any{
+			"base": map[string]any{
+				"allowed_cidrs":    []string{"10.0.0.0/8"},
+				"ttl":              "8h",
+				"template_file":    "policy.json.tmpl",
+				"variables":        map[string]any{"IncludeEdit": false, "Team": "web"},
+				"inherit_defaults": true,
+			},
+			"production": map[string]any{
+				"extends":   "base",
+				"ttl":       "4h",
+				"variables": map[string]any{"IncludeEdit": true},
+			},
+		},
+		"zones": map[string]any{
+			"prod": map[string]any{
+				"zone_id":       "aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa",
+				"extends":       "production",
+				"allowed_cidrs": []string{"203.0.113.1/32"},
+			},
+		},
+	})
+
+	zoneID, zc, err := LoadZoneConfig("prod")
+	if err != nil {
+		t.Fatalf("LoadZoneConfig() error = %v", err)
+	}
+	if zoneID != "aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa" {
+		t.Fatalf("zone ID = %q", zoneID)
+	}
+	if zc.TTL != "4h" {
+		t.Errorf("TTL = %q, want 4h from production", zc.TTL)
+	}
+	if zc.TemplateFile != "policy.json.tmpl" {
+		t.Errorf("TemplateFile = %q, want inherited from base", zc.TemplateFile)
+	}
+	if !reflect.DeepEqual(zc.AllowedCIDRs, []string{"203.0.113.1/32"}) {
+		t.Errorf("AllowedCIDRs = %v, want zone override", zc.AllowedCIDRs)
+	}
+	if !reflect.DeepEqual(zc.Permissions, []string{"Zone:Read"}) {
+		t.Errorf("Permissions = %v, want defaults via inherited inherit_defaults", zc.Permissions)
+	}
+	wantVars := map[string]interface{}{"IncludeEdit": true, "Team": "web"}
+	if !reflect.DeepEqual(zc.Variables, wantVars) {
+		t.Errorf("Variables = %v, want %v", zc.Variables, wantVars)
+	}
+}
+
+func TestLoadZoneConfigExtendsGuardrails(t *testing.T) {
+	tmp := t.TempDir()
+	stubConfigDir(t, tmp)
+	writeJSON(t, configFilePath(t, tmp, "config.json"), map[string]any{
+		"profiles": map[string]any{
+			"audited": map[string]any{"guardrails": map[string]any{
+				"require_ticket": true,
+				"ticket_pattern": "^CHG-",
+				"max_ttl":        "4h",
+			}},
+		},
+		"zones": map[string]any{
+			"prod": map[string]any{
+				"zone_id":    "aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa",
+				"extends":    "audited",
+				"guardrails": map[string]any{"max_ttl": "8h", "ticket_pattern": "[0-9]$"},
+			},
+		},
+	})
+
+	_, zc, err := LoadZoneConfig("prod")
+	if err != nil {
+		t.Fatalf("LoadZoneConfig() error = %v", err)
+	}
+	want := Guardrails{RequireTicket: true, MaxTTL: "4h", TicketPattern: "[0-9]$", InheritedTicketPatterns: []string{"^CHG-"}}
+	if zc.Guardrails == nil || !reflect.DeepEqual(*zc.Guardrails, want) {
+		t.Errorf("Guardrails = %+v, want the profile's kept where stricter: %+v", zc.Guardrails, want)
+	}
+}
+
+func TestLoadZoneConfigExtendsErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		profiles map[string]any
+		want     string
+	}{
+		{"missing", map[string]any{}, `profile "base" not found`},
+		{"cycle", map[string]any{
+			"base":  map[string]any{"extends": "other"},
+			"other": map[string]any{"extends": "base"},
+		}, "profile cycle: base -> other -> base"},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			tmp := t.TempDir()
+			stubConfigDir(t, tmp)
+			writeJSON(t, configFilePath(t, tmp, "config.json"), map[string]any{
+				"profiles": tc.profiles,
+				"zones": map[string]any{
+					"dev": map[string]any{"zone_id": "bbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbb", "extends": "base"},
+				},
+			})
+			_, _, err := LoadZoneConfig("dev")
+			if err == nil || !strings.Contains(err.Error(), tc.want) {
+				t.Fatalf("LoadZoneConfig() error = %v, want %q", err, tc.want)
+			}
+		})
+	}
+}
+
+func TestLoadProfiles(t *testing.T) {
+	tmp := t.TempDir()
+	stubConfigDir(t, tmp)
+	writeJSON(t, configFilePath(t, tmp, "config.json"), map[string]any{
+		"profiles": map[string]any{
+			"base":       map[string]any{"ttl": "8h", "permissions": []string{"Zone:Read"}},
+			"production": map[string]any{"extends": "base", "ttl": "4h"},
+		},
+	})
+
+	profiles, err := LoadProfiles()
+	if err != nil {
+		t.Fatalf("LoadProfiles() error = %v", err)
+	}
+	prod := profiles["production"]
+	if prod.TTL != "4h" || !reflect.DeepEqual(prod.Permissions, []string{"Zone:Read"}) || prod.Extends != "" {
+		t.Fatalf("LoadProfiles()[production] = %+v, want resolved chain", prod)
+	}
+	if len(profiles) != 2 {
+		t.Fatalf("LoadProfiles() returned %d profiles, want 2", len(profiles))
+	}
+}
@@ -1,11 +1,14 @@
 package config
 
 import (
+	"bytes"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"os"
 	"sort"
 	"strings"
+	"unicode"
 )
 
 // ZoneSource indicates where a zone entry originated from.
@@ -18,19 +21,20 @@ const (
 
 // ZoneEntry is a normalized zone name and ID paired with its source.
 type ZoneEntry struct {
-	Name   string
-	ID     string
-	Source ZoneSource
+	Name   string     `json:"name"`
+	ID     string     `json:"id"`
+	Source ZoneSource `json:"source"`
 }
 
-// LoadZoneOverrides reads user-defined zones and extracts zone IDs.
+// LoadZoneOverrides reads user-defined zones and extracts zone IDs. Zones
+// whose keys disagree on the ID are left out; see ZoneConflicts.
 func LoadZoneOverrides() (map[string]string, error) {
 	cfg, err := loadSettings()
 	if err != nil {
 		return nil, err
 	}
 
-	out := sanitizeZones(cfg.Zones)
+	out, _ := sanitizeZones(cfg)
 	if len(out) == 0 {
 		return nil, errors.New("config zones contains no valid entries")
 	}
@@ -82,6 +86,36 @@ func ListConfiguredZones() ([]ZoneEntry, error) {
 	return out, nil
 }
 
+// ZoneConflicts reports every zone defined more than once with different
+// zone IDs, one error each in name order. ZoneMap and ListConfiguredZones
+// leave these zones out, and looking one up fails with its error.
+func ZoneConflicts() ([]error, error) {
+	cfg, err := loadSettings()
+	if err != nil {
+		return nil, err
+	}
+	_, conflicts := sanitizeZones(cfg)
+	errs := make([]error, len(conflicts))
+	for i, name := range conflicts {
+		errs[i] = zoneConflict(cfg, name)
+	}
+	return errs, nil
+}
+
+// ZoneNames returns the zone keys exactly as written in the configuration file.
+func ZoneNames() ([]string, error) {
+	cfg, err := loadSettings()
+	if err != nil {
+		return nil, err
+	}
+	names := make([]string, 0, len(cfg.Zones))
+	for name := range cfg.Zones {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
 // ResolveZoneID returns the zone ID for the supplied zone name using the merged map.
 func ResolveZoneID(zoneName string) (string, error) {
 	zones, err := ZoneMap()
@@ -96,47 +130,162 @@ func ResolveZoneID(zoneName string) (string, error) {
 	if id, ok := zones[name]; ok && id != "" {
 		return id, nil
 	}
+	if cfg, err := loadSettings(); err == nil {
+		if err := zoneConflict(cfg, name); err != nil {
+			return "", err
+		}
+	}
 	return "", fmt.Errorf("zone %q not found in default or configured zones", zoneName)
 }
 
-func sanitizeZones(values map[string]interface{}) map[string]string {
-	if len(values) == 0 {
-		return nil
+// sanitizeZones maps the normalized zone names to their zone IDs. Keys
+// that normalize to the same name, or are written more than once, must
+// agree on the ID; a name whose keys do not is left out of the map and
+// returned, sorted, as a conflict, so it only fails the lookups that ask
+// for it. See zoneConflict.
+func sanitizeZones(cfg *settings) (map[string]string, []string) {
+	if len(cfg.Zones) == 0 {
+		return nil, nil
 	}
 
-	out := make(map[string]string, len(values))
-	for name, value := range values {
+	out := make(map[string]string, len(cfg.Zones))
+	conflicted := make(map[string]bool)
+	for name := range cfg.Zones {
 		n := normalizeZoneName(name)
 		if n == "" {
 			continue
 		}
+		for _, zoneID := range cfg.zoneIDs(name) {
+			if zoneID == "" || conflicted[n] {
+				continue
+			}
+			if prev, ok := out[n]; ok && prev != zoneID {
+				conflicted[n] = true
+				delete(out, n)
+				continue
+			}
+			out[n] = zoneID
+		}
+	}
+	conflicts := make([]string, 0, len(conflicted))
+	for n := range conflicted {
+		conflicts = append(conflicts, n)
+	}
+	sort.Strings(conflicts)
+	if len(out) == 0 {
+		return nil, conflicts
+	}
+	return out, conflicts
+}
+
+// rawZoneID returns the zone ID of a zone entry, either a simple string or
+// an object with a zone_id field, as written in the configuration file.
+func rawZoneID(value interface{}) string {
+	if zoneMap, ok := value.(map[string]interface{}); ok {
+		value = zoneMap["zone_id"]
+	}
+	zoneID, _ := value.(string)
+	return strings.TrimSpace(zoneID)
+}
+
+// zoneIDs returns the zone ID of every entry written under key, in file
+// order: one, unless the key is repeated.
+func (cfg *settings) zoneIDs(key string) []string {
+	if ids, ok := cfg.zoneRepeats[key]; ok {
+		return ids
+	}
+	return []string{rawZoneID(cfg.Zones[key])}
+}
 
-		// Handle simple string zone ID
-		if zoneID, ok := value.(string); ok {
-			if trimmed := strings.TrimSpace(zoneID); trimmed != "" {
-				out[n] = trimmed
+// repeatedZoneKeys scans the zones object of config.json for keys written
+// more than once, such as "example.com" twice, which json.Unmarshal
+// collapses to the last one, and returns the zone ID of every occurrence
+// of each. data must already have parsed.
+func repeatedZoneKeys(data []byte) (map[string][]string, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	if tok, err := dec.Token(); err != nil || tok != json.Delim('{') {
+		return nil, err
+	}
+	seen := make(map[string][]string)
+	for dec.More() {
+		key, err := dec.Token()
+		if err != nil {
+			return nil, err
+		}
+		if key != "zones" {
+			var skip json.RawMessage
+			if err := dec.Decode(&skip); err != nil {
+				return nil, err
 			}
 			continue
 		}
+		tok, err := dec.Token()
+		if err != nil {
+			return nil, err
+		}
+		if tok != json.Delim('{') {
+			continue
+		}
+		for dec.More() {
+			name, err := dec.Token()
+			if err != nil {
+				return nil, err
+			}
+			var value interface{}
+			if err := dec.Decode(&value); err != nil {
+				return nil, err
+			}
+			seen[name.(string)] = append(seen[name.(string)], rawZoneID(value))
+		}
+		if _, err := dec.Token(); err != nil {
+			return nil, err
+		}
+	}
+	var repeats map[string][]string
+	for name, ids := range seen {
+		if len(ids) > 1 {
+			if repeats == nil {
+				repeats = make(map[string][]string)
+			}
+			repeats[name] = ids
+		}
+	}
+	return repeats, nil
+}
 
-		// Handle complex zone object with zone_id field
-		if zoneMap, ok := value.(map[string]interface{}); ok {
-			if zoneIDValue, exists := zoneMap["zone_id"]; exists {
-				if zoneID, ok := zoneIDValue.(string); ok {
-					if trimmed := strings.TrimSpace(zoneID); trimmed != "" {
-						out[n] = trimmed
-					}
-				}
+// zoneConflict reports the keys of zones that normalize to the same name
+// as zoneName but carry different zone IDs, such as "Example.com" and
+// "example.com.", or a key written twice with different IDs. Picking
+// either would risk minting a token for the wrong zone, so the conflict
+// must be resolved in the configuration file.
+func zoneConflict(cfg *settings, zoneName string) error {
+	n := normalizeZoneName(zoneName)
+	var keys []string
+	for key := range cfg.Zones {
+		if normalizeZoneName(key) == n {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	var described []string
+	ids := make(map[string]bool)
+	for _, key := range keys {
+		for _, zoneID := range cfg.zoneIDs(key) {
+			if zoneID != "" {
+				described = append(described, fmt.Sprintf("%q (%s)", key, zoneID))
+				ids[zoneID] = true
 			}
 		}
 	}
-	if len(out) == 0 {
+	if len(ids) < 2 {
 		return nil
 	}
-	return out
+	return fmt.Errorf("zone %q is defined more than once with different zone IDs: %s; remove or rename all but one", n, strings.Join(described, ", "))
 }
 
+// normalizeZoneName lower-cases a zone name and trims surrounding space and
+// the trailing dots of a fully qualified name.
 func normalizeZoneName(s string) string {
 	s = strings.TrimSpace(strings.ToLower(s))
-	return strings.TrimSuffix(s, ".")
+	return strings.TrimRightFunc(s, func(r rune) bool { return r == '.' || unicode.IsSpace(r) })
 }
@@ -2,8 +2,10 @@ package config
 
 import (
 	"encoding/json"
+	"errors"
 	"os"
 	"path/filepath"
+	"reflect"
 	"strings"
 	"testing"
 )
@@ -129,6 +131,97 @@ func TestLoadZoneOverridesEmpty(t *testing.T) {
 	}
 }
 
+func TestDuplicateZoneConflict(t *testing.T) {
+	tests := []struct {
+		name    string
+		zones   map[string]any
+		raw     string
+		wantErr string
+	}{
+		{
+			name: "different IDs",
+			zones: map[string]any{
+				"Example.com":  "aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa",
+				"example.com.": map[string]any{"zone_id": "bbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbb"},
+				"other.com":    "cccccccccccccccccccccccccccccccc",
+			},
+			wantErr: `zone "example.com" is defined more than once with different zone IDs: "Example.com" (aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa), "example.com." (bbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbb); remove or rename all but one`,
+		},
+		{
+			name: "same key twice",
+			raw: `{"zones": {
+				"example.com": {"zone_id": "aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa"},
+				"other.com": "cccccccccccccccccccccccccccccccc",
+				"example.com": {"zone_id": "bbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbb"}
+			}}`,
+			wantErr: `zone "example.com" is defined more than once with different zone IDs: "example.com" (aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa), "example.com" (bbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbb); remove or rename all but one`,
+		},
+		{
+			name: "same ID",
+			zones: map[string]any{
+				"Example.com": "aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa",
+				"example.com": map[string]any{"zone_id": "aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa", "ttl": "1h"},
+				"other.com":   "cccccccccccccccccccccccccccccccc",
+			},
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			tmp := t.TempDir()
+			if tc.raw != "" {
+				writeFile(t, configFilePath(t, tmp, "config.json"), tc.raw)
+			} else {
+				writeJSON(t, configFilePath(t, tmp, "config.json"), map[string]any{"zones": tc.zones})
+			}
+			stubConfigDir(t, tmp)
+
+			errText := func(err error) string {
+				if err == nil {
+					return ""
+				}
+				return err.Error()
+			}
+			_, err := ResolveZoneID("example.com")
+			if got := errText(err); got != tc.wantErr {
+				t.Errorf("ResolveZoneID() error = %q, want %q", got, tc.wantErr)
+			}
+			_, _, err = LoadZoneConfig("example.com")
+			if got := errText(err); got != tc.wantErr {
+				t.Errorf("LoadZoneConfig() error = %q, want %q", got, tc.wantErr)
+			}
+			// A conflict elsewhere does not block unrelated zones.
+			if _, _, err := LoadZoneConfig("other.com"); err != nil {
+				t.Errorf("LoadZoneConfig(other.com) error = %v", err)
+			}
+			if id, err := ResolveZoneID("other.com"); err != nil || id != "cccccccccccccccccccccccccccccccc" {
+				t.Errorf("ResolveZoneID(other.com) = %q, %v", id, err)
+			}
+			zones, err := ListConfiguredZones()
+			if err != nil {
+				t.Fatalf("ListConfiguredZones() error = %v", err)
+			}
+			var names []string
+			for _, z := range zones {
+				names = append(names, z.Name)
+			}
+			wantNames := []string{"example.com", "other.com"}
+			if tc.wantErr != "" {
+				wantNames = []string{"other.com"}
+			}
+			if !reflect.DeepEqual(names, wantNames) {
+				t.Errorf("ListConfiguredZones() names = %v, want %v", names, wantNames)
+			}
+			conflicts, err := ZoneConflicts()
+			if err != nil {
+				t.Fatalf("ZoneConflicts() error = %v", err)
+			}
+			if got := errText(errors.Join(conflicts...)); got != tc.wantErr {
+				t.Errorf("ZoneConflicts() = %q, want %q", got, tc.wantErr)
+			}
+		})
+	}
+}
+
 func TestZoneMapErrorPropagation(t *testing.T) {
 	tmp := t.TempDir()
 	writeFile(t, configFilePath(t, tmp, "config.json"), "{invalid json")
@@ -183,3 +276,21 @@ func configFilePath(t *testing.T, root, name string) string {
 	t.Helper()
 	return filepath.Join(root, "cftoken", name)
 }
+
+// FuzzNormalizeZoneName checks that zone names from the command line or a
+// pipeline normalize to one stable key: lower case, trimmed, and without
+// the trailing dots of a fully qualified name.
+func FuzzNormalizeZoneName(f *testing.F) {
+	for _, seed := range []string{"Example.COM.", " example.com ", "a. .", "", "ÉXAMPLE.org", "\texample.com.\n"} {
+		f.Add(seed)
+	}
+	f.Fuzz(func(t *testing.T, name string) {
+		got := normalizeZoneName(name)
+		if again := normalizeZoneName(got); again != got {
+			t.Fatalf("normalizeZoneName(%q) = %q, but normalizing that gives %q", name, got, again)
+		}
+		if got != strings.TrimSpace(got) || strings.HasSuffix(got, ".") {
+			t.Fatalf("normalizeZoneName(%q) = %q, want it trimmed", name, got)
+		}
+	})
+}
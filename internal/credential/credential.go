@@ -0,0 +1,59 @@
+// Package credential resolves the management API token from wherever an
+// organization keeps it: environment variables, files, the platform keyring,
+// Vault, or a helper command.
+package credential
+
+import (
+	"context"
+	"errors"
+	"unicode/utf16"
+)
+
+// DefaultTarget is the name the token is stored under in keyrings.
+const DefaultTarget = "cftoken"
+
+var (
+	// ErrNotFound is returned when a source holds no token. Chain moves on to
+	// the next provider only for this error.
+	ErrNotFound = errors.New("credential not found")
+	// ErrUnsupported is returned on platforms without a supported keyring.
+	ErrUnsupported = errors.New("credential store not supported on this platform")
+)
+
+// Provider supplies the management API token. It satisfies
+// cloudflare.CredentialProvider, so library users can plug in their own.
+type Provider interface {
+	Token(ctx context.Context) (string, error)
+	// String describes the source for diagnostics; it never includes the token.
+	String() string
+}
+
+// Lookup returns the secret stored for target in the platform keyring:
+// Credential Manager on Windows (as created by `cmdkey /generic:cftoken
+// /user:cloudflare /pass:TOKEN`), the login keychain on macOS, and the
+// Secret Service via secret-tool elsewhere.
+func Lookup(ctx context.Context, target string) (string, error) {
+	return lookup(ctx, target)
+}
+
+// decodeBlob handles both encodings found in the wild: cmdkey and the
+// Credential Manager UI store UTF-16LE, while most other tools store UTF-8.
+func decodeBlob(blob []byte) string {
+	if len(blob)%2 == 0 {
+		utf16le := true
+		for i := 1; i < len(blob); i += 2 {
+			if blob[i] != 0 {
+				utf16le = false
+				break
+			}
+		}
+		if utf16le {
+			units := make([]uint16, len(blob)/2)
+			for i := range units {
+				units[i] = uint16(blob[2*i]) | uint16(blob[2*i+1])<<8
+			}
+			return string(utf16.Decode(units))
+		}
+	}
+	return string(blob)
+}
@@ -0,0 +1,37 @@
+//go:build !windows
+
+package credential
+
+import (
+	"context"
+	"errors"
+	"os/exec"
+	"runtime"
+	"strings"
+)
+
+// lookup shells out to the platform keyring CLI: security(1) on macOS and
+// secret-tool(1) from libsecret elsewhere. Both exit non-zero when the item
+// does not exist.
+func lookup(ctx context.Context, target string) (string, error) {
+	name, args := "secret-tool", []string{"lookup", "service", target}
+	if runtime.GOOS == "darwin" {
+		name, args = "security", []string{"find-generic-password", "-s", target, "-w"}
+	}
+	if _, err := exec.LookPath(name); err != nil {
+		return "", ErrUnsupported
+	}
+	out, err := runOutput(ctx, nil, name, args...)
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		return "", ErrNotFound
+	}
+	if err != nil {
+		return "", err
+	}
+	token := strings.TrimSpace(string(out))
+	if token == "" {
+		return "", ErrNotFound
+	}
+	return token, nil
+}
@@ -0,0 +1,25 @@
+package credential
+
+import "testing"
+
+func TestDecodeBlob(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name string
+		blob []byte
+		want string
+	}{
+		{name: "utf-16le from cmdkey", blob: []byte{'a', 0, 'b', 0, 'c', 0, '1', 0}, want: "abc1"},
+		{name: "utf-8", blob: []byte("abc1"), want: "abc1"},
+		{name: "odd length utf-8", blob: []byte("abc"), want: "abc"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			if got := decodeBlob(tt.blob); got != tt.want {
+				t.Errorf("decodeBlob() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,334 @@
+package credential
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Option configures providers built by Parse.
+type Option func(*options)
+
+type options struct {
+	execTimeout time.Duration
+}
+
+// WithExecTimeout overrides DefaultExecTimeout for exec sources.
+func WithExecTimeout(d time.Duration) Option {
+	return func(o *options) {
+		o.execTimeout = d
+	}
+}
+
+// outputRunner runs name with args, adding env to the inherited
+// environment, and returns its stdout.
+type outputRunner func(ctx context.Context, env []string, name string, args ...string) ([]byte, error)
+
+// Parse builds a provider from a token_source setting:
+//
+//	env:NAME            environment variable NAME
+//	file:PATH           first line of the file at PATH
+//	keyring:TARGET      platform keyring item TARGET (default "cftoken")
+//	vault:PATH#FIELD    `vault kv get` of FIELD (default "token") at PATH
+//	exec:COMMAND ARGS   stdout of a helper command; see Exec
+func Parse(source string, opts ...Option) (Provider, error) {
+	var o options
+	for _, opt := range opts {
+		opt(&o)
+	}
+	scheme, rest, ok := strings.Cut(strings.TrimSpace(source), ":")
+	if !ok {
+		return nil, fmt.Errorf("token source %q: expected scheme:value (env, file, keyring, vault, exec)", source)
+	}
+	rest = strings.TrimSpace(rest)
+	switch scheme {
+	case "env":
+		if rest == "" {
+			return nil, errors.New("token source env: variable name is required")
+		}
+		return Env(rest), nil
+	case "file":
+		if rest == "" {
+			return nil, errors.New("token source file: path is required")
+		}
+		return File(rest), nil
+	case "keyring":
+		if rest == "" {
+			rest = DefaultTarget
+		}
+		return Keyring(rest), nil
+	case "vault":
+		path, field, _ := strings.Cut(rest, "#")
+		if path == "" {
+			return nil, errors.New("token source vault: path is required")
+		}
+		if field == "" {
+			field = "token"
+		}
+		return Vault(path, field), nil
+	case "exec":
+		argv := strings.Fields(rest)
+		if len(argv) == 0 {
+			return nil, errors.New("token source exec: command is required")
+		}
+		p := Exec(argv[0], argv[1:]...).(*execProvider)
+		if o.execTimeout > 0 {
+			p.timeout = o.execTimeout
+		}
+		return p, nil
+	default:
+		return nil, fmt.Errorf("token source %q: unknown scheme %q; must be env, file, keyring, vault, or exec", source, scheme)
+	}
+}
+
+type envProvider struct{ name string }
+
+// Env reads the token from an environment variable.
+func Env(name string) Provider { return envProvider{name: name} }
+
+func (p envProvider) Token(context.Context) (string, error) {
+	if token := strings.TrimSpace(os.Getenv(p.name)); token != "" {
+		return token, nil
+	}
+	return "", ErrNotFound
+}
+
+func (p envProvider) String() string { return "environment variable " + p.name }
+
+type fileProvider struct{ path string }
+
+// File reads the token from the first line of a file.
+func File(path string) Provider { return fileProvider{path: path} }
+
+func (p fileProvider) Token(context.Context) (string, error) {
+	data, err := os.ReadFile(p.path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return "", ErrNotFound
+	}
+	if err != nil {
+		return "", fmt.Errorf("read token file: %w", err)
+	}
+	line, _, _ := strings.Cut(string(data), "\n")
+	if token := strings.TrimSpace(line); token != "" {
+		return token, nil
+	}
+	return "", ErrNotFound
+}
+
+func (p fileProvider) String() string { return "file " + p.path }
+
+type keyringProvider struct{ target string }
+
+// Keyring reads the token from the platform keyring; see Lookup.
+func Keyring(target string) Provider { return keyringProvider{target: target} }
+
+func (p keyringProvider) Token(ctx context.Context) (string, error) {
+	token, err := Lookup(ctx, p.target)
+	if errors.Is(err, ErrUnsupported) {
+		return "", ErrNotFound
+	}
+	return token, err
+}
+
+func (p keyringProvider) String() string { return "keyring item " + p.target }
+
+type vaultProvider struct {
+	path, field string
+	run         outputRunner
+}
+
+// Vault reads the token with `vault kv get -field=FIELD PATH`, using the
+// vault CLI's own login and VAULT_ADDR.
+func Vault(path, field string) Provider {
+	return &vaultProvider{path: path, field: field, run: runOutput}
+}
+
+func (p *vaultProvider) Token(ctx context.Context) (string, error) {
+	out, err := p.run(ctx, nil, "vault", "kv", "get", "-field="+p.field, p.path)
+	if err != nil {
+		return "", err
+	}
+	return nonEmpty(out)
+}
+
+func (p *vaultProvider) String() string { return fmt.Sprintf("vault %s (%s)", p.path, p.field) }
+
+// DefaultExecTimeout bounds how long an exec helper may run.
+const DefaultExecTimeout = 30 * time.Second
+
+// execInfo is passed to helpers in KUBERNETES_EXEC_INFO, as kubectl does, so
+// helpers written for kubectl exec plugins work unchanged.
+const execInfo = `{"apiVersion":"client.authentication.k8s.io/v1","kind":"ExecCredential","spec":{"interactive":false}}`
+
+type execProvider struct {
+	name    string
+	args    []string
+	timeout time.Duration
+	run     outputRunner
+	now     func() time.Time
+
+	mu      sync.Mutex
+	token   string
+	expires time.Time
+}
+
+// Exec runs a helper command and uses its output as the token. Like a
+// kubectl exec credential plugin, the helper may print either the bare token
+// or an ExecCredential object whose status carries the token and an optional
+// expirationTimestamp. The token is reused until it expires, or for the life
+// of the process when the helper gives no expiry.
+func Exec(name string, args ...string) Provider {
+	return &execProvider{name: name, args: args, timeout: DefaultExecTimeout, run: runOutput, now: time.Now}
+}
+
+func (p *execProvider) Token(ctx context.Context) (string, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.token != "" && (p.expires.IsZero() || p.now().Before(p.expires)) {
+		return p.token, nil
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, p.timeout)
+	defer cancel()
+	out, err := p.run(ctx, []string{"KUBERNETES_EXEC_INFO=" + execInfo}, p.name, p.args...)
+	if err != nil {
+		if ctx.Err() == context.DeadlineExceeded {
+			return "", fmt.Errorf("%s did not finish within %s", p.name, p.timeout)
+		}
+		return "", err
+	}
+	token, expires, err := parseExecOutput(out)
+	if err != nil {
+		return "", fmt.Errorf("%s: %w", p.name, err)
+	}
+	p.token, p.expires = token, expires
+	return token, nil
+}
+
+func (p *execProvider) String() string { return "command " + p.name }
+
+// execCredential is the subset of client.authentication.k8s.io ExecCredential
+// that carries a bearer token.
+type execCredential struct {
+	Kind   string `json:"kind"`
+	Status *struct {
+		Token               string `json:"token"`
+		ExpirationTimestamp string `json:"expirationTimestamp"`
+	} `json:"status"`
+}
+
+func parseExecOutput(out []byte) (string, time.Time, error) {
+	trimmed := bytes.TrimSpace(out)
+	if !bytes.HasPrefix(trimmed, []byte("{")) {
+		token, err := nonEmpty(trimmed)
+		return token, time.Time{}, err
+	}
+	var cred execCredential
+	if err := json.Unmarshal(trimmed, &cred); err != nil {
+		return "", time.Time{}, fmt.Errorf("parse ExecCredential: %w", err)
+	}
+	if cred.Kind != "ExecCredential" || cred.Status == nil || strings.TrimSpace(cred.Status.Token) == "" {
+		return "", time.Time{}, errors.New("output is JSON but not an ExecCredential with status.token")
+	}
+	var expires time.Time
+	if ts := cred.Status.ExpirationTimestamp; ts != "" {
+		var err error
+		if expires, err = time.Parse(time.RFC3339, ts); err != nil {
+			return "", time.Time{}, fmt.Errorf("parse expirationTimestamp: %w", err)
+		}
+	}
+	return strings.TrimSpace(cred.Status.Token), expires, nil
+}
+
+type chain []Provider
+
+// Chain tries each provider in order and returns the first token found.
+// Providers reporting ErrNotFound are skipped; any other error stops the
+// chain, since a broken source should not silently fall through to another.
+func Chain(providers ...Provider) Provider { return chain(providers) }
+
+func (c chain) Token(ctx context.Context) (string, error) {
+	for _, p := range c {
+		token, err := p.Token(ctx)
+		if errors.Is(err, ErrNotFound) {
+			continue
+		}
+		if err != nil {
+			return "", fmt.Errorf("%s: %w", p, err)
+		}
+		return token, nil
+	}
+	return "", ErrNotFound
+}
+
+func (c chain) String() string {
+	names := make([]string, len(c))
+	for i, p := range c {
+		names[i] = p.String()
+	}
+	return strings.Join(names, ", then ")
+}
+
+type cached struct {
+	p   Provider
+	ttl time.Duration
+	now func() time.Time
+
+	mu        sync.Mutex
+	token     string
+	fetchedAt time.Time
+}
+
+// Cached remembers the token p returns for ttl, so slow sources such as
+// Vault or helper commands run once rather than per request.
+func Cached(p Provider, ttl time.Duration) Provider {
+	return &cached{p: p, ttl: ttl, now: time.Now}
+}
+
+func (c *cached) Token(ctx context.Context) (string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.token != "" && c.now().Sub(c.fetchedAt) < c.ttl {
+		return c.token, nil
+	}
+	token, err := c.p.Token(ctx)
+	if err != nil {
+		return "", err
+	}
+	c.token, c.fetchedAt = token, c.now()
+	return token, nil
+}
+
+func (c *cached) String() string { return c.p.String() }
+
+func nonEmpty(out []byte) (string, error) {
+	if token := strings.TrimSpace(string(out)); token != "" {
+		return token, nil
+	}
+	return "", ErrNotFound
+}
+
+func runOutput(ctx context.Context, env []string, name string, args ...string) ([]byte, error) {
+	cmd := exec.CommandContext(ctx, name, args...)
+	if len(env) > 0 {
+		cmd.Env = append(os.Environ(), env...)
+	}
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return nil, fmt.Errorf("%s: %w: %s", name, err, msg)
+		}
+		return nil, fmt.Errorf("%s: %w", name, err)
+	}
+	return out, nil
+}
@@ -0,0 +1,233 @@
+package credential
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParse(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		source  string
+		want    string
+		wantErr bool
+	}{
+		{source: "env:MY_TOKEN", want: "environment variable MY_TOKEN"},
+		{source: "file:/run/secrets/cf", want: "file /run/secrets/cf"},
+		{source: "keyring:", want: "keyring item cftoken"},
+		{source: "keyring:ops", want: "keyring item ops"},
+		{source: "vault:secret/cf", want: "vault secret/cf (token)"},
+		{source: "vault:secret/cf#api", want: "vault secret/cf (api)"},
+		{source: "exec:/usr/local/bin/helper --profile prod", want: "command /usr/local/bin/helper"},
+		{source: "env:", wantErr: true},
+		{source: "exec:", wantErr: true},
+		{source: "plain-token", wantErr: true},
+		{source: "ssm:/cf", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.source, func(t *testing.T) {
+			t.Parallel()
+			p, err := Parse(tt.source)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("Parse() = %v, want error", p)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Parse() error = %v", err)
+			}
+			if p.String() != tt.want {
+				t.Errorf("Parse() = %q, want %q", p.String(), tt.want)
+			}
+		})
+	}
+}
+
+func TestFileProvider(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	path := filepath.Join(dir, "token")
+	if err := os.WriteFile(path, []byte("  secret \nignored\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if got, err := File(path).Token(context.Background()); err != nil || got != "secret" {
+		t.Errorf("Token() = %q, %v; want first line", got, err)
+	}
+	if _, err := File(filepath.Join(dir, "missing")).Token(context.Background()); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Token() on missing file error = %v, want ErrNotFound", err)
+	}
+}
+
+func TestExecProvider(t *testing.T) {
+	t.Parallel()
+
+	p := Exec("helper", "--profile", "prod").(*execProvider)
+	var got []string
+	p.run = func(_ context.Context, _ []string, name string, args ...string) ([]byte, error) {
+		got = append([]string{name}, args...)
+		return []byte("tok\n"), nil
+	}
+	if token, err := p.Token(context.Background()); err != nil || token != "tok" {
+		t.Fatalf("Token() = %q, %v", token, err)
+	}
+	if want := []string{"helper", "--profile", "prod"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("ran %v, want %v", got, want)
+	}
+}
+
+type staticProvider struct {
+	token string
+	err   error
+	calls int
+}
+
+func (p *staticProvider) Token(context.Context) (string, error) {
+	p.calls++
+	return p.token, p.err
+}
+
+func (p *staticProvider) String() string { return "static" }
+
+func TestChain(t *testing.T) {
+	t.Parallel()
+
+	missing := &staticProvider{err: ErrNotFound}
+	found := &staticProvider{token: "tok"}
+	never := &staticProvider{token: "other"}
+	if got, err := Chain(missing, found, never).Token(context.Background()); err != nil || got != "tok" {
+		t.Fatalf("Token() = %q, %v; want tok", got, err)
+	}
+	if never.calls != 0 {
+		t.Error("Chain() consulted providers after the first hit")
+	}
+
+	broken := &staticProvider{err: errors.New("vault sealed")}
+	if _, err := Chain(broken, found).Token(context.Background()); err == nil || errors.Is(err, ErrNotFound) {
+		t.Errorf("Token() error = %v, want the broken source's error", err)
+	}
+	if _, err := Chain(missing).Token(context.Background()); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Token() error = %v, want ErrNotFound", err)
+	}
+}
+
+func TestCached(t *testing.T) {
+	t.Parallel()
+
+	src := &staticProvider{token: "tok"}
+	p := Cached(src, time.Minute).(*cached)
+	now := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	p.now = func() time.Time { return now }
+
+	for range 3 {
+		if _, err := p.Token(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if src.calls != 1 {
+		t.Fatalf("source called %d times, want 1", src.calls)
+	}
+	now = now.Add(time.Minute)
+	if _, err := p.Token(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if src.calls != 2 {
+		t.Fatalf("source called %d times after expiry, want 2", src.calls)
+	}
+}
+
+func TestParseExecOutput(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name        string
+		out         string
+		wantToken   string
+		wantExpires time.Time
+		wantErr     bool
+	}{
+		{name: "bare token", out: "tok\n", wantToken: "tok"},
+		{
+			name:        "exec credential",
+			out:         `{"apiVersion":"client.authentication.k8s.io/v1","kind":"ExecCredential","status":{"token":"tok","expirationTimestamp":"2024-01-02T04:00:00Z"}}`,
+			wantToken:   "tok",
+			wantExpires: time.Date(2024, 1, 2, 4, 0, 0, 0, time.UTC),
+		},
+		{name: "exec credential without expiry", out: `{"kind":"ExecCredential","status":{"token":"tok"}}`, wantToken: "tok"},
+		{name: "client certificate only", out: `{"kind":"ExecCredential","status":{"clientCertificateData":"x"}}`, wantErr: true},
+		{name: "bad expiry", out: `{"kind":"ExecCredential","status":{"token":"tok","expirationTimestamp":"soon"}}`, wantErr: true},
+		{name: "empty", out: "\n", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			token, expires, err := parseExecOutput([]byte(tt.out))
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("parseExecOutput() = %q, want error", token)
+				}
+				return
+			}
+			if err != nil || token != tt.wantToken || !expires.Equal(tt.wantExpires) {
+				t.Errorf("parseExecOutput() = %q, %v, %v; want %q, %v", token, expires, err, tt.wantToken, tt.wantExpires)
+			}
+		})
+	}
+}
+
+func TestExecProviderCachesUntilExpiry(t *testing.T) {
+	t.Parallel()
+
+	p := Exec("helper").(*execProvider)
+	now := time.Date(2024, 1, 2, 3, 0, 0, 0, time.UTC)
+	p.now = func() time.Time { return now }
+	calls := 0
+	p.run = func(_ context.Context, env []string, _ string, _ ...string) ([]byte, error) {
+		calls++
+		if len(env) != 1 || env[0] != "KUBERNETES_EXEC_INFO="+execInfo {
+			t.Errorf("env = %v, want KUBERNETES_EXEC_INFO", env)
+		}
+		return []byte(`{"kind":"ExecCredential","status":{"token":"tok","expirationTimestamp":"2024-01-02T04:00:00Z"}}`), nil
+	}
+
+	for range 2 {
+		if _, err := p.Token(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if calls != 1 {
+		t.Fatalf("helper ran %d times before expiry, want 1", calls)
+	}
+	now = now.Add(time.Hour)
+	if _, err := p.Token(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if calls != 2 {
+		t.Fatalf("helper ran %d times after expiry, want 2", calls)
+	}
+}
+
+func TestExecProviderTimeout(t *testing.T) {
+	t.Parallel()
+
+	p, err := Parse("exec:slow-helper", WithExecTimeout(10*time.Millisecond))
+	if err != nil {
+		t.Fatal(err)
+	}
+	exec := p.(*execProvider)
+	exec.run = func(ctx context.Context, _ []string, _ string, _ ...string) ([]byte, error) {
+		<-ctx.Done()
+		return nil, ctx.Err()
+	}
+	if _, err := exec.Token(context.Background()); err == nil || !strings.Contains(err.Error(), "did not finish within 10ms") {
+		t.Fatalf("Token() error = %v, want timeout", err)
+	}
+}
@@ -0,0 +1,99 @@
+// Package duration parses the TTLs and ages people type: Go durations plus
+// whole days and weeks, such as 2d, 1w, or 1d12h.
+package duration
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	Day  = 24 * time.Hour
+	Week = 7 * Day
+)
+
+// units splits a value into whole weeks, whole days, and the rest, which
+// must be a Go duration.
+var units = regexp.MustCompile(`^(?:(\d+)w)?(?:(\d+)d)?(.*)$`)
+
+// Parse reads s as a non-negative duration. Besides everything
+// time.ParseDuration accepts it takes whole weeks (w) and days (d), which
+// must come first: 1w, 2d, 1d12h. Case and surrounding space are ignored.
+func Parse(s string) (time.Duration, error) {
+	in := strings.ToLower(strings.TrimSpace(s))
+	if in == "" {
+		return 0, errors.New("empty duration")
+	}
+	m := units.FindStringSubmatch(in)
+	var total time.Duration
+	for i, unit := range []time.Duration{Week, Day} {
+		if m[i+1] == "" {
+			continue
+		}
+		n, err := strconv.ParseInt(m[i+1], 10, 64)
+		if err != nil || n > int64((math.MaxInt64-total)/unit) {
+			return 0, errTooLong(s)
+		}
+		total += time.Duration(n) * unit
+	}
+	if rest := m[3]; rest != "" {
+		d, err := time.ParseDuration(rest)
+		if err != nil {
+			return 0, fmt.Errorf("invalid duration %q: want e.g. 90m, 8h, 2d, or 1w (weeks and days must be whole and come first)", s)
+		}
+		if d < 0 {
+			return 0, fmt.Errorf("invalid duration %q: must not be negative", s)
+		}
+		if d > math.MaxInt64-total {
+			return 0, errTooLong(s)
+		}
+		total += d
+	}
+	return total, nil
+}
+
+// errTooLong reports a duration that does not fit in a time.Duration,
+// about 292 years, rather than letting it wrap around to a negative one.
+func errTooLong(s string) error {
+	return fmt.Errorf("invalid duration %q: too long", s)
+}
+
+// Format writes d in the units Parse reads, largest first: 48h is 2d and
+// 36h is 1d12h. Durations with a fraction of a second use d.String.
+func Format(d time.Duration) string {
+	if d <= 0 || d%time.Second != 0 {
+		return d.String()
+	}
+	var b strings.Builder
+	for _, u := range []struct {
+		name string
+		size time.Duration
+	}{{"w", Week}, {"d", Day}, {"h", time.Hour}, {"m", time.Minute}, {"s", time.Second}} {
+		if n := d / u.size; n > 0 {
+			fmt.Fprintf(&b, "%d%s", n, u.name)
+			d -= n * u.size
+		}
+	}
+	return b.String()
+}
+
+// Value is a flag.Value holding a duration in Parse's syntax.
+type Value time.Duration
+
+func (v *Value) String() string {
+	return Format(time.Duration(*v))
+}
+
+func (v *Value) Set(s string) error {
+	d, err := Parse(s)
+	if err != nil {
+		return err
+	}
+	*v = Value(d)
+	return nil
+}
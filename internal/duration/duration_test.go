@@ -0,0 +1,82 @@
+package duration
+
+import (
+	"flag"
+	"testing"
+	"time"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    time.Duration
+		wantErr bool
+	}{
+		{"8h", 8 * time.Hour, false},
+		{"90m", 90 * time.Minute, false},
+		{"0", 0, false},
+		{"2d", 48 * time.Hour, false},
+		{"30d", 30 * Day, false},
+		{"0d", 0, false},
+		{"1h30m", 90 * time.Minute, false},
+		{"1w", 7 * Day, false},
+		{"1w2d", 9 * Day, false},
+		{"1d12h", 36 * time.Hour, false},
+		{" 7D ", 7 * Day, false},
+		{"1.5d", 0, true},
+		{"2h1d", 0, true},
+		{"-5m", 0, true},
+		{"-1d", 0, true},
+		{"7 days", 0, true},
+		{"soon", 0, true},
+		{"", 0, true},
+		{"20000w", 0, true},
+		{"15250w", 15250 * Week, false},
+		{"15250w2d", 0, true},
+		{"15250w2540000h", 0, true},
+		{"99999999999999999999d", 0, true},
+	}
+	for _, tc := range tests {
+		got, err := Parse(tc.in)
+		if (err != nil) != tc.wantErr {
+			t.Errorf("Parse(%q) error = %v, wantErr %v", tc.in, err, tc.wantErr)
+			continue
+		}
+		if got != tc.want {
+			t.Errorf("Parse(%q) = %s, want %s", tc.in, got, tc.want)
+		}
+	}
+}
+
+func TestFormat(t *testing.T) {
+	tests := map[time.Duration]string{
+		0:                       "0s",
+		90 * time.Minute:        "1h30m",
+		48 * time.Hour:          "2d",
+		36 * time.Hour:          "1d12h",
+		8 * Day:                 "1w1d",
+		1500 * time.Millisecond: "1.5s",
+	}
+	for in, want := range tests {
+		if got := Format(in); got != want {
+			t.Errorf("Format(%s) = %q, want %q", in, got, want)
+		}
+		if in%time.Second == 0 {
+			if back, err := Parse(want); err != nil || back != in {
+				t.Errorf("Parse(Format(%s)) = %s, %v", in, back, err)
+			}
+		}
+	}
+}
+
+func TestValue(t *testing.T) {
+	ttl := 8 * time.Hour
+	fset := flag.NewFlagSet("test", flag.ContinueOnError)
+	fset.Var((*Value)(&ttl), "ttl", "")
+	if err := fset.Parse([]string{"-ttl", "2d"}); err != nil || ttl != 48*time.Hour {
+		t.Fatalf("-ttl 2d = %s, %v", ttl, err)
+	}
+	if err := fset.Parse([]string{"-ttl", "soon"}); err == nil {
+		t.Fatalf("-ttl soon error = nil")
+	}
+}
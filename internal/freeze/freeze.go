@@ -0,0 +1,127 @@
+// Package freeze keeps the zones that were frozen with `cftoken zone freeze`,
+// during incidents or migrations, so no tokens are issued for them until
+// they are unfrozen. Zones can also be frozen permanently in config.json;
+// this store only holds the ones toggled from the command line.
+package freeze
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"cftoken/internal/config"
+)
+
+// Entry records when and why a zone was frozen. ZoneID is kept so tokens
+// requested with -zone-id are refused too.
+type Entry struct {
+	ZoneID string    `json:"zone_id,omitempty"`
+	At     time.Time `json:"at"`
+	Note   string    `json:"note,omitempty"`
+	Ticket string    `json:"ticket,omitempty"`
+}
+
+// Store maps normalized zone names to their freeze.
+type Store map[string]Entry
+
+// Lookup returns the freeze covering the zone name or zone ID.
+func (s Store) Lookup(zone, zoneID string) (string, Entry, bool) {
+	if e, ok := s[Normalize(zone)]; ok && zone != "" {
+		return Normalize(zone), e, true
+	}
+	for name, e := range s {
+		if zoneID != "" && e.ZoneID == zoneID {
+			return name, e, true
+		}
+	}
+	return "", Entry{}, false
+}
+
+// Path returns the store file.
+func Path() (string, error) {
+	state, err := config.StateDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(state, "frozen.json"), nil
+}
+
+// Load reads the store; a missing file means no zone is frozen.
+func Load() (Store, error) {
+	path, err := Path()
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return Store{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	store := Store{}
+	if err := json.Unmarshal(data, &store); err != nil {
+		return nil, fmt.Errorf("parse frozen zones %s: %w", path, err)
+	}
+	return store, nil
+}
+
+// Freeze records zone as frozen, replacing any earlier entry.
+func Freeze(zone string, entry Entry) error {
+	zone = Normalize(zone)
+	if zone == "" {
+		return errors.New("zone name is required")
+	}
+	store, err := Load()
+	if err != nil {
+		return err
+	}
+	entry.At = entry.At.UTC()
+	store[zone] = entry
+	return save(store)
+}
+
+// Unfreeze removes zone from the store and reports whether it was frozen.
+func Unfreeze(zone string) (bool, error) {
+	store, err := Load()
+	if err != nil {
+		return false, err
+	}
+	zone = Normalize(zone)
+	if _, ok := store[zone]; !ok {
+		return false, nil
+	}
+	delete(store, zone)
+	return true, save(store)
+}
+
+// Normalize lower-cases zone and drops a trailing dot.
+func Normalize(zone string) string {
+	return strings.ToLower(strings.TrimSuffix(strings.TrimSpace(zone), "."))
+}
+
+func save(store Store) error {
+	path, err := Path()
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return fmt.Errorf("create state directory: %w", err)
+	}
+	data, err := json.MarshalIndent(store, "", "  ")
+	if err != nil {
+		return fmt.Errorf("encode frozen zones: %w", err)
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o600); err != nil {
+		return fmt.Errorf("write frozen zones: %w", err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		return fmt.Errorf("write frozen zones: %w", err)
+	}
+	return nil
+}
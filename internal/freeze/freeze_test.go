@@ -0,0 +1,53 @@
+package freeze
+
+import (
+	"testing"
+	"time"
+)
+
+func TestStore(t *testing.T) {
+	t.Setenv("XDG_STATE_HOME", t.TempDir())
+
+	if store, err := Load(); err != nil || len(store) != 0 {
+		t.Fatalf("Load() on empty state = %v, %v", store, err)
+	}
+	at := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	if err := Freeze("Example.com.", Entry{ZoneID: "zone-1", At: at, Note: "incident"}); err != nil {
+		t.Fatalf("Freeze() error = %v", err)
+	}
+	if err := Freeze(" ", Entry{}); err == nil {
+		t.Error("Freeze() without zone error = nil")
+	}
+
+	store, err := Load()
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	tests := []struct {
+		zone, zoneID string
+		want         bool
+	}{
+		{"example.com", "", true},
+		{"EXAMPLE.COM", "other", true},
+		{"", "zone-1", true},
+		{"other.com", "zone-1", true},
+		{"other.com", "zone-2", false},
+		{"", "", false},
+	}
+	for _, tc := range tests {
+		name, entry, ok := store.Lookup(tc.zone, tc.zoneID)
+		if ok != tc.want {
+			t.Errorf("Lookup(%q, %q) = %v, want %v", tc.zone, tc.zoneID, ok, tc.want)
+		}
+		if ok && (name != "example.com" || entry.Note != "incident" || !entry.At.Equal(at)) {
+			t.Errorf("Lookup(%q, %q) = %q, %+v", tc.zone, tc.zoneID, name, entry)
+		}
+	}
+
+	if was, err := Unfreeze("example.com"); err != nil || !was {
+		t.Fatalf("Unfreeze() = %v, %v; want true", was, err)
+	}
+	if was, err := Unfreeze("example.com"); err != nil || was {
+		t.Fatalf("second Unfreeze() = %v, %v; want false", was, err)
+	}
+}
@@ -0,0 +1,124 @@
+// Package guardrail checks a token request against configured policy limits
+// before anything is sent to Cloudflare.
+package guardrail
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"cftoken/internal/config"
+	"cftoken/internal/duration"
+)
+
+// Rules is the parsed form of config.Guardrails. The zero value allows
+// everything.
+type Rules struct {
+	RequireIPRestriction bool
+	// MaxTTL caps the token lifetime; zero means no cap.
+	MaxTTL            time.Duration
+	DeniedPermissions []string
+	RequireTicket     bool
+	// TicketPatterns must all match a given ticket.
+	TicketPatterns []*regexp.Regexp
+	TicketInName   bool
+}
+
+// Request describes the token about to be created.
+type Request struct {
+	// TTL is the token lifetime; zero means the token never expires.
+	TTL          time.Duration
+	AllowedCIDRs []string
+	// Permissions holds the IDs and names of every requested permission group.
+	Permissions []string
+	// Ticket is the change ticket or reason given for the token.
+	Ticket string
+}
+
+// Parse converts configured guardrails into Rules.
+func Parse(g config.Guardrails) (Rules, error) {
+	rules := Rules{
+		RequireIPRestriction: g.RequireIPRestriction,
+		RequireTicket:        g.RequireTicket,
+		TicketInName:         g.TicketInName,
+	}
+	for _, pattern := range append([]string{g.TicketPattern}, g.InheritedTicketPatterns...) {
+		s := strings.TrimSpace(pattern)
+		if s == "" {
+			continue
+		}
+		re, err := regexp.Compile(s)
+		if err != nil {
+			return Rules{}, fmt.Errorf("invalid ticket_pattern %q: %w", pattern, err)
+		}
+		rules.TicketPatterns = append(rules.TicketPatterns, re)
+	}
+	if s := strings.TrimSpace(g.MaxTTL); s != "" {
+		d, err := duration.Parse(s)
+		if err != nil {
+			return Rules{}, fmt.Errorf("invalid max_ttl: %w", err)
+		}
+		if d <= 0 {
+			return Rules{}, fmt.Errorf("invalid max_ttl %q: must be positive", g.MaxTTL)
+		}
+		rules.MaxTTL = d
+	}
+	for _, p := range g.DeniedPermissions {
+		if p = strings.TrimSpace(p); p != "" {
+			rules.DeniedPermissions = append(rules.DeniedPermissions, p)
+		}
+	}
+	return rules, nil
+}
+
+// Tighten combines r with other, keeping the stricter value of each rule.
+func (r Rules) Tighten(other Rules) Rules {
+	out := Rules{
+		RequireIPRestriction: r.RequireIPRestriction || other.RequireIPRestriction,
+		MaxTTL:               r.MaxTTL,
+		RequireTicket:        r.RequireTicket || other.RequireTicket,
+		TicketInName:         r.TicketInName || other.TicketInName,
+	}
+	if other.MaxTTL > 0 && (out.MaxTTL == 0 || other.MaxTTL < out.MaxTTL) {
+		out.MaxTTL = other.MaxTTL
+	}
+	out.DeniedPermissions = append(append([]string(nil), r.DeniedPermissions...), other.DeniedPermissions...)
+	out.TicketPatterns = append(append([]*regexp.Regexp(nil), r.TicketPatterns...), other.TicketPatterns...)
+	return out
+}
+
+// Evaluate returns one message per rule the request breaks.
+func (r Rules) Evaluate(req Request) []string {
+	var violations []string
+	if r.RequireIPRestriction && len(req.AllowedCIDRs) == 0 {
+		violations = append(violations, "IP restriction is required but the token allows any address")
+	}
+	if r.MaxTTL > 0 {
+		switch {
+		case req.TTL <= 0:
+			violations = append(violations, fmt.Sprintf("token must expire within %s but has no expiry", r.MaxTTL))
+		case req.TTL > r.MaxTTL:
+			violations = append(violations, fmt.Sprintf("TTL %s exceeds the maximum of %s", req.TTL, r.MaxTTL))
+		}
+	}
+	if r.RequireTicket && strings.TrimSpace(req.Ticket) == "" {
+		violations = append(violations, "a change ticket is required; pass -ticket")
+	}
+	if req.Ticket != "" {
+		for _, re := range r.TicketPatterns {
+			if !re.MatchString(req.Ticket) {
+				violations = append(violations, fmt.Sprintf("ticket %q does not match %s", req.Ticket, re))
+			}
+		}
+	}
+	for _, denied := range r.DeniedPermissions {
+		for _, p := range req.Permissions {
+			if strings.EqualFold(denied, p) {
+				violations = append(violations, fmt.Sprintf("permission %q is not allowed", p))
+				break
+			}
+		}
+	}
+	return violations
+}
@@ -0,0 +1,108 @@
+package guardrail
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"cftoken/internal/config"
+)
+
+func TestParse(t *testing.T) {
+	t.Parallel()
+
+	rules, err := Parse(config.Guardrails{MaxTTL: "4h", DeniedPermissions: []string{" DNS Write ", ""}})
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	want := Rules{MaxTTL: 4 * time.Hour, DeniedPermissions: []string{"DNS Write"}}
+	if !reflect.DeepEqual(rules, want) {
+		t.Fatalf("Parse() = %+v, want %+v", rules, want)
+	}
+
+	for _, bad := range []string{"soon", "-1h", "0s"} {
+		if _, err := Parse(config.Guardrails{MaxTTL: bad}); err == nil {
+			t.Errorf("Parse(max_ttl=%q) error = nil, want error", bad)
+		}
+	}
+}
+
+func TestTightenNeverLoosens(t *testing.T) {
+	t.Parallel()
+
+	global := Rules{MaxTTL: 2 * time.Hour, DeniedPermissions: []string{"A"}}
+	zone := Rules{RequireIPRestriction: true, MaxTTL: 8 * time.Hour, DeniedPermissions: []string{"B"}}
+
+	got := global.Tighten(zone)
+	want := Rules{RequireIPRestriction: true, MaxTTL: 2 * time.Hour, DeniedPermissions: []string{"A", "B"}}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("Tighten() = %+v, want %+v", got, want)
+	}
+	if got := (Rules{}).Tighten(Rules{MaxTTL: time.Hour}); got.MaxTTL != time.Hour {
+		t.Fatalf("Tighten() MaxTTL = %s, want 1h when only the zone sets it", got.MaxTTL)
+	}
+}
+
+func TestEvaluate(t *testing.T) {
+	t.Parallel()
+
+	rules := Rules{RequireIPRestriction: true, MaxTTL: 4 * time.Hour, DeniedPermissions: []string{"dns write"}}
+	tests := []struct {
+		name string
+		req  Request
+		want int
+	}{
+		{"compliant", Request{TTL: time.Hour, AllowedCIDRs: []string{"10.0.0.1/32"}, Permissions: []string{"Zone Read"}}, 0},
+		{"no restriction", Request{TTL: time.Hour, Permissions: []string{"Zone Read"}}, 1},
+		{"too long", Request{TTL: 8 * time.Hour, AllowedCIDRs: []string{"10.0.0.1/32"}}, 1},
+		{"never expires", Request{AllowedCIDRs: []string{"10.0.0.1/32"}}, 1},
+		{"denied permission", Request{TTL: time.Hour, AllowedCIDRs: []string{"10.0.0.1/32"}, Permissions: []string{"DNS Write"}}, 1},
+		{"everything wrong", Request{Permissions: []string{"DNS Write"}}, 3},
+	}
+
+	for _, tc := range tests {
+		if got := rules.Evaluate(tc.req); len(got) != tc.want {
+			t.Errorf("%s: Evaluate() = %v, want %d violation(s)", tc.name, got, tc.want)
+		}
+	}
+	if got := (Rules{}).Evaluate(Request{}); len(got) != 0 {
+		t.Errorf("zero Rules Evaluate() = %v, want none", got)
+	}
+}
+
+func TestTicketRules(t *testing.T) {
+	t.Parallel()
+
+	global, err := Parse(config.Guardrails{TicketPattern: `^[A-Z]+-\d+$`})
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	zone, err := Parse(config.Guardrails{RequireTicket: true, TicketInName: true, TicketPattern: `^CHG-`})
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	if _, err := Parse(config.Guardrails{TicketPattern: "("}); err == nil {
+		t.Error("Parse(ticket_pattern=\"(\") error = nil, want error")
+	}
+
+	tests := []struct {
+		name  string
+		rules Rules
+		req   Request
+		want  int
+	}{
+		{"optional ticket absent", global, Request{}, 0},
+		{"optional ticket malformed", global, Request{Ticket: "fix dns"}, 1},
+		{"required ticket absent", global.Tighten(zone), Request{}, 1},
+		{"required ticket matches both", global.Tighten(zone), Request{Ticket: "CHG-42"}, 0},
+		{"required ticket matches one", global.Tighten(zone), Request{Ticket: "OPS-42"}, 1},
+	}
+	for _, tc := range tests {
+		if got := tc.rules.Evaluate(tc.req); len(got) != tc.want {
+			t.Errorf("%s: Evaluate() = %v, want %d violation(s)", tc.name, got, tc.want)
+		}
+	}
+	if got := global.Tighten(zone); !got.RequireTicket || !got.TicketInName || len(got.TicketPatterns) != 2 {
+		t.Errorf("Tighten() = %+v, want ticket rules combined", got)
+	}
+}
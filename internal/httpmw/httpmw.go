@@ -0,0 +1,86 @@
+package httpmw
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// Next sends the request to the next handler in the chain. It matches the
+// Cloudflare SDK's option.MiddlewareNext so middleware can be passed as-is.
+type Next = func(*http.Request) (*http.Response, error)
+
+// Middleware wraps an outgoing request. It matches option.Middleware.
+type Middleware = func(*http.Request, Next) (*http.Response, error)
+
+// CorrelationHeader carries the operation's request ID to Cloudflare so a
+// failed call can be matched with our logs when raised with support.
+const CorrelationHeader = "X-Correlation-ID"
+
+type requestIDKey struct{}
+
+// WithRequestID returns a context carrying id, which Logger uses instead of
+// generating a fresh request ID.
+func WithRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestIDKey{}, id)
+}
+
+// RequestID returns the request ID stored in ctx, if any.
+func RequestID(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey{}).(string)
+	return id
+}
+
+// NewRequestID returns a short random identifier suitable for log correlation.
+func NewRequestID() string {
+	var b [6]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return fmt.Sprintf("%x", time.Now().UnixNano())
+	}
+	return hex.EncodeToString(b[:])
+}
+
+// Correlate returns middleware that sends the request ID from the request
+// context in the CorrelationHeader. Requests without one pass unchanged.
+func Correlate() Middleware {
+	return func(req *http.Request, next Next) (*http.Response, error) {
+		if id := RequestID(req.Context()); id != "" {
+			req.Header.Set(CorrelationHeader, id)
+		}
+		return next(req)
+	}
+}
+
+// Logger returns middleware that logs each request and its outcome through
+// logf, tagged with a request ID. Every logged string passes through the
+// redactors first. Transport errors are wrapped with the request ID so they
+// can be matched to their log lines.
+func Logger(logf func(string, ...interface{}), redactors ...Redactor) Middleware {
+	redact := chain(redactors)
+	return func(req *http.Request, next Next) (*http.Response, error) {
+		id := RequestID(req.Context())
+		if id == "" {
+			id = NewRequestID()
+			req = req.WithContext(WithRequestID(req.Context(), id))
+		}
+
+		logf("[%s] cloudflare request: %s %s", id, req.Method, redact(req.URL.String()))
+		start := time.Now()
+		resp, err := next(req)
+		elapsed := time.Since(start).Round(time.Millisecond)
+		if err != nil {
+			logf("[%s] cloudflare request failed after %s: %s", id, elapsed, redact(err.Error()))
+			return resp, fmt.Errorf("request %s: %w", id, err)
+		}
+
+		if ray := resp.Header.Get("Cf-Ray"); ray != "" {
+			logf("[%s] cloudflare response: %s in %s (cf-ray %s)", id, resp.Status, elapsed, ray)
+		} else {
+			logf("[%s] cloudflare response: %s in %s", id, resp.Status, elapsed)
+		}
+		return resp, nil
+	}
+}
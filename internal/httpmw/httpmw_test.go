@@ -0,0 +1,118 @@
+package httpmw
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+func TestLoggerTagsRequestsAndErrors(t *testing.T) {
+	t.Parallel()
+
+	var lines []string
+	logf := func(format string, args ...interface{}) {
+		lines = append(lines, fmt.Sprintf(format, args...))
+	}
+	mw := Logger(logf, RedactQueryParams("secret"))
+
+	req, _ := http.NewRequest(http.MethodGet, "https://api.example.com/user?secret=abc", nil)
+	req = req.WithContext(WithRequestID(req.Context(), "req-1"))
+
+	_, err := mw(req, func(r *http.Request) (*http.Response, error) {
+		if got := RequestID(r.Context()); got != "req-1" {
+			t.Errorf("RequestID() = %q, want req-1", got)
+		}
+		return nil, errors.New("connection reset")
+	})
+	if err == nil || !strings.Contains(err.Error(), "request req-1: connection reset") {
+		t.Fatalf("error = %v, want request ID prefix", err)
+	}
+	if len(lines) != 2 {
+		t.Fatalf("logged %d lines, want 2: %v", len(lines), lines)
+	}
+	if !strings.HasPrefix(lines[0], "[req-1] ") || strings.Contains(lines[0], "abc") {
+		t.Errorf("request line = %q, want request ID and redacted query", lines[0])
+	}
+}
+
+func TestLoggerGeneratesRequestID(t *testing.T) {
+	t.Parallel()
+
+	var seen string
+	mw := Logger(func(string, ...interface{}) {})
+	req, _ := http.NewRequest(http.MethodGet, "https://api.example.com/", nil)
+	resp, err := mw(req, func(r *http.Request) (*http.Response, error) {
+		seen = RequestID(r.Context())
+		return &http.Response{Status: "200 OK", Header: http.Header{}}, nil
+	})
+	if err != nil || resp == nil {
+		t.Fatalf("middleware returned %v, %v", resp, err)
+	}
+	if len(seen) != 12 {
+		t.Fatalf("generated request ID = %q, want 12 hex characters", seen)
+	}
+}
+
+func TestCorrelate(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name string
+		id   string
+	}{
+		{"with request ID", "req-1"},
+		{"without request ID", ""},
+	}
+	for _, tc := range tests {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			req, _ := http.NewRequest(http.MethodGet, "https://api.example.com/", nil)
+			if tc.id != "" {
+				req = req.WithContext(WithRequestID(req.Context(), tc.id))
+			}
+			var got []string
+			Correlate()(req, func(r *http.Request) (*http.Response, error) {
+				got = r.Header.Values(CorrelationHeader)
+				return &http.Response{}, nil
+			})
+			if tc.id == "" && len(got) != 0 {
+				t.Fatalf("%s = %v, want unset", CorrelationHeader, got)
+			}
+			if tc.id != "" && (len(got) != 1 || got[0] != tc.id) {
+				t.Fatalf("%s = %v, want [%s]", CorrelationHeader, got, tc.id)
+			}
+		})
+	}
+}
+
+func TestRedactors(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		redactor Redactor
+		input    string
+		want     string
+	}{
+		{"bearer", RedactBearer(), "Authorization: Bearer abc.def-123", "Authorization: Bearer [REDACTED]"},
+		{"values", RedactValues("s3cr3t", ""), "token s3cr3t leaked twice: s3cr3t", "token [REDACTED] leaked twice: [REDACTED]"},
+		{"no values", RedactValues(), "unchanged", "unchanged"},
+		{"pattern", RedactPattern(regexp.MustCompile(`\d{4}`)), "pin 1234", "pin [REDACTED]"},
+		{"query", RedactQueryParams("token"), "https://x/?a=1&token=abc", "https://x/?a=1&token=%5BREDACTED%5D"},
+		{"query absent", RedactQueryParams("token"), "https://x/?a=1", "https://x/?a=1"},
+	}
+
+	for _, tc := range tests {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			if got := tc.redactor(tc.input); got != tc.want {
+				t.Fatalf("redactor(%q) = %q, want %q", tc.input, got, tc.want)
+			}
+		})
+	}
+}
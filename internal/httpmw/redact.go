@@ -0,0 +1,78 @@
+package httpmw
+
+import (
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+// Redacted replaces sensitive values in log output.
+const Redacted = "[REDACTED]"
+
+// Redactor rewrites a log string to hide sensitive values.
+type Redactor func(string) string
+
+var bearerPattern = regexp.MustCompile(`(?i)(bearer\s+)[A-Za-z0-9._~+/=-]+`)
+
+// RedactBearer hides credentials following a "Bearer" keyword.
+func RedactBearer() Redactor {
+	return func(s string) string {
+		return bearerPattern.ReplaceAllString(s, "${1}"+Redacted)
+	}
+}
+
+// RedactValues hides every occurrence of the given literal secrets.
+func RedactValues(secrets ...string) Redactor {
+	var pairs []string
+	for _, secret := range secrets {
+		if secret != "" {
+			pairs = append(pairs, secret, Redacted)
+		}
+	}
+	replacer := strings.NewReplacer(pairs...)
+	return func(s string) string {
+		if len(pairs) == 0 {
+			return s
+		}
+		return replacer.Replace(s)
+	}
+}
+
+// RedactPattern replaces every match of pattern with Redacted.
+func RedactPattern(pattern *regexp.Regexp) Redactor {
+	return func(s string) string {
+		return pattern.ReplaceAllString(s, Redacted)
+	}
+}
+
+// RedactQueryParams hides the values of the named query parameters in URLs.
+func RedactQueryParams(names ...string) Redactor {
+	return func(s string) string {
+		u, err := url.Parse(s)
+		if err != nil || u.RawQuery == "" {
+			return s
+		}
+		query := u.Query()
+		changed := false
+		for _, name := range names {
+			if query.Has(name) {
+				query.Set(name, Redacted)
+				changed = true
+			}
+		}
+		if !changed {
+			return s
+		}
+		u.RawQuery = query.Encode()
+		return u.String()
+	}
+}
+
+func chain(redactors []Redactor) Redactor {
+	return func(s string) string {
+		for _, r := range redactors {
+			s = r(s)
+		}
+		return s
+	}
+}
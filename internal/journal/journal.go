@@ -0,0 +1,173 @@
+// Package journal records the completed steps of multi-step operations so a
+// run that fails part way can be rolled back later instead of leaving
+// unknown state behind. Each operation is one JSON file that is rewritten
+// after every step and removed once the operation completes.
+package journal
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"cftoken/internal/config"
+)
+
+// Step is one completed action. Resource holds whatever is needed to undo it,
+// such as a token ID.
+type Step struct {
+	Name     string            `json:"name"`
+	At       time.Time         `json:"at"`
+	Resource map[string]string `json:"resource,omitempty"`
+	Undone   bool              `json:"undone,omitempty"`
+}
+
+// Journal is an unfinished operation.
+type Journal struct {
+	ID        string `json:"id"`
+	Operation string `json:"operation"`
+	// CorrelationID is the request ID of the run that started the operation.
+	CorrelationID string    `json:"correlation_id,omitempty"`
+	StartedAt     time.Time `json:"started_at"`
+	Steps         []Step    `json:"steps"`
+
+	path      string
+	completed bool
+}
+
+// Dir returns the directory holding journal files.
+func Dir() (string, error) {
+	state, err := config.StateDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(state, "journal"), nil
+}
+
+// Begin starts a journal for operation and persists it immediately.
+func Begin(operation, correlationID string) (*Journal, error) {
+	dir, err := Dir()
+	if err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, fmt.Errorf("create journal directory: %w", err)
+	}
+	var b [4]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return nil, fmt.Errorf("generate journal id: %w", err)
+	}
+	now := time.Now().UTC()
+	j := &Journal{
+		ID:            now.Format("20060102T150405Z") + "-" + hex.EncodeToString(b[:]),
+		Operation:     operation,
+		CorrelationID: correlationID,
+		StartedAt:     now,
+	}
+	j.path = filepath.Join(dir, j.ID+".json")
+	return j, j.save()
+}
+
+// Record appends a completed step and persists the journal. Record, MarkUndone,
+// and Complete are no-ops on a nil Journal so callers can run unjournaled.
+func (j *Journal) Record(name string, resource map[string]string) error {
+	if j == nil {
+		return nil
+	}
+	j.Steps = append(j.Steps, Step{Name: name, At: time.Now().UTC(), Resource: resource})
+	return j.save()
+}
+
+// MarkUndone flags step i as rolled back and persists the journal.
+func (j *Journal) MarkUndone(i int) error {
+	if j == nil {
+		return nil
+	}
+	j.Steps[i].Undone = true
+	return j.save()
+}
+
+// Complete removes the journal; the operation needs no further attention.
+func (j *Journal) Complete() error {
+	if j == nil {
+		return nil
+	}
+	if err := os.Remove(j.path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("remove journal %s: %w", j.ID, err)
+	}
+	j.completed = true
+	return nil
+}
+
+// Completed reports whether Complete has removed the journal.
+func (j *Journal) Completed() bool {
+	return j == nil || j.completed
+}
+
+func (j *Journal) save() error {
+	data, err := json.MarshalIndent(j, "", "  ")
+	if err != nil {
+		return fmt.Errorf("encode journal: %w", err)
+	}
+	tmp := j.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o600); err != nil {
+		return fmt.Errorf("write journal %s: %w", j.ID, err)
+	}
+	if err := os.Rename(tmp, j.path); err != nil {
+		return fmt.Errorf("write journal %s: %w", j.ID, err)
+	}
+	return nil
+}
+
+// Load reads the journal with the given ID.
+func Load(id string) (*Journal, error) {
+	if strings.ContainsAny(id, `/\`) || id == "" {
+		return nil, fmt.Errorf("invalid journal id %q", id)
+	}
+	dir, err := Dir()
+	if err != nil {
+		return nil, err
+	}
+	return load(filepath.Join(dir, id+".json"))
+}
+
+func load(path string) (*Journal, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var j Journal
+	if err := json.Unmarshal(data, &j); err != nil {
+		return nil, fmt.Errorf("parse journal %s: %w", path, err)
+	}
+	j.path = path
+	return &j, nil
+}
+
+// Pending returns every unfinished journal, oldest first.
+func Pending() ([]*Journal, error) {
+	dir, err := Dir()
+	if err != nil {
+		return nil, err
+	}
+	paths, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		return nil, err
+	}
+	var out []*Journal
+	for _, path := range paths {
+		j, err := load(path)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, j)
+	}
+	sort.Slice(out, func(a, b int) bool { return out[a].ID < out[b].ID })
+	return out, nil
+}
@@ -0,0 +1,57 @@
+package journal
+
+import (
+	"errors"
+	"io/fs"
+	"testing"
+)
+
+func TestJournalLifecycle(t *testing.T) {
+	t.Setenv("XDG_STATE_HOME", t.TempDir())
+
+	j, err := Begin("apply-template", "corr-1")
+	if err != nil {
+		t.Fatalf("Begin() error = %v", err)
+	}
+	if err := j.Record("create_token", map[string]string{"token_id": "t1"}); err != nil {
+		t.Fatalf("Record() error = %v", err)
+	}
+	if err := j.Record("create_token", map[string]string{"token_id": "t2"}); err != nil {
+		t.Fatalf("Record() error = %v", err)
+	}
+	if err := j.MarkUndone(1); err != nil {
+		t.Fatalf("MarkUndone() error = %v", err)
+	}
+
+	pending, err := Pending()
+	if err != nil {
+		t.Fatalf("Pending() error = %v", err)
+	}
+	if len(pending) != 1 || pending[0].ID != j.ID || pending[0].Operation != "apply-template" || pending[0].CorrelationID != "corr-1" {
+		t.Fatalf("Pending() = %+v", pending)
+	}
+	got, err := Load(j.ID)
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if len(got.Steps) != 2 || got.Steps[0].Resource["token_id"] != "t1" || got.Steps[0].Undone || !got.Steps[1].Undone {
+		t.Fatalf("Load() steps = %+v", got.Steps)
+	}
+
+	if err := got.Complete(); err != nil {
+		t.Fatalf("Complete() error = %v", err)
+	}
+	if _, err := Load(j.ID); !errors.Is(err, fs.ErrNotExist) {
+		t.Fatalf("Load() after Complete error = %v, want fs.ErrNotExist", err)
+	}
+	if pending, _ := Pending(); len(pending) != 0 {
+		t.Fatalf("Pending() after Complete = %v", pending)
+	}
+}
+
+func TestLoadRejectsPaths(t *testing.T) {
+	t.Setenv("XDG_STATE_HOME", t.TempDir())
+	if _, err := Load("../config"); err == nil {
+		t.Fatalf("Load() with a path error = nil")
+	}
+}
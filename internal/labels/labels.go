@@ -0,0 +1,147 @@
+// Package labels keeps free-form labels (team, service, ticket, ...) for API
+// tokens, which Cloudflare has no place for. Labels are stored locally in
+// the state directory, keyed by token ID.
+package labels
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"cftoken/internal/config"
+)
+
+// Entry holds the labels of one token along with its name, so the registry
+// can be read without asking the API.
+type Entry struct {
+	Name   string            `json:"name,omitempty"`
+	Labels map[string]string `json:"labels"`
+}
+
+// Matches reports whether the entry carries every label in filter.
+func (e Entry) Matches(filter map[string]string) bool {
+	for k, v := range filter {
+		if got, ok := e.Labels[k]; !ok || got != v {
+			return false
+		}
+	}
+	return true
+}
+
+// String renders the labels as sorted key=value pairs.
+func (e Entry) String() string {
+	pairs := make([]string, 0, len(e.Labels))
+	for k, v := range e.Labels {
+		pairs = append(pairs, k+"="+v)
+	}
+	sort.Strings(pairs)
+	return strings.Join(pairs, ",")
+}
+
+// Registry maps token IDs to their labels.
+type Registry map[string]Entry
+
+// Path returns the registry file.
+func Path() (string, error) {
+	state, err := config.StateDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(state, "labels.json"), nil
+}
+
+// Load reads the registry; a missing file is an empty registry.
+func Load() (Registry, error) {
+	path, err := Path()
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return Registry{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	reg := Registry{}
+	if err := json.Unmarshal(data, &reg); err != nil {
+		return nil, fmt.Errorf("parse label registry %s: %w", path, err)
+	}
+	return reg, nil
+}
+
+// Set merges labels into the token's entry, recording name when given.
+func Set(tokenID, name string, labels map[string]string) error {
+	if strings.TrimSpace(tokenID) == "" {
+		return errors.New("token ID is required")
+	}
+	reg, err := Load()
+	if err != nil {
+		return err
+	}
+	entry := reg[tokenID]
+	if entry.Labels == nil {
+		entry.Labels = make(map[string]string, len(labels))
+	}
+	if name != "" {
+		entry.Name = name
+	}
+	for k, v := range labels {
+		entry.Labels[k] = v
+	}
+	reg[tokenID] = entry
+	return save(reg)
+}
+
+// Remove drops keys from the token's labels, or the whole entry when no
+// keys are given.
+func Remove(tokenID string, keys ...string) error {
+	reg, err := Load()
+	if err != nil {
+		return err
+	}
+	entry, ok := reg[tokenID]
+	if !ok {
+		return nil
+	}
+	for _, k := range keys {
+		delete(entry.Labels, k)
+	}
+	if len(keys) == 0 || len(entry.Labels) == 0 {
+		delete(reg, tokenID)
+	} else {
+		reg[tokenID] = entry
+	}
+	return save(reg)
+}
+
+// ValidKey reports whether k can be used as a label key.
+func ValidKey(k string) bool {
+	return k != "" && !strings.ContainsAny(k, "=, \t\n")
+}
+
+func save(reg Registry) error {
+	path, err := Path()
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return fmt.Errorf("create state directory: %w", err)
+	}
+	data, err := json.MarshalIndent(reg, "", "  ")
+	if err != nil {
+		return fmt.Errorf("encode label registry: %w", err)
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o600); err != nil {
+		return fmt.Errorf("write label registry: %w", err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		return fmt.Errorf("write label registry: %w", err)
+	}
+	return nil
+}
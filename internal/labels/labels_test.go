@@ -0,0 +1,60 @@
+package labels
+
+import (
+	"testing"
+)
+
+func TestRegistry(t *testing.T) {
+	t.Setenv("XDG_STATE_HOME", t.TempDir())
+
+	if reg, err := Load(); err != nil || len(reg) != 0 {
+		t.Fatalf("Load() on empty state = %v, %v", reg, err)
+	}
+	if err := Set("t1", "edge-1", map[string]string{"team": "edge", "ticket": "OPS-1"}); err != nil {
+		t.Fatalf("Set() error = %v", err)
+	}
+	if err := Set("t1", "", map[string]string{"service": "purge"}); err != nil {
+		t.Fatalf("Set() error = %v", err)
+	}
+	if err := Set("t2", "web-1", map[string]string{"team": "web"}); err != nil {
+		t.Fatalf("Set() error = %v", err)
+	}
+	if err := Set(" ", "", nil); err == nil {
+		t.Error("Set() without token ID error = nil")
+	}
+
+	reg, err := Load()
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if got := reg["t1"]; got.Name != "edge-1" || got.String() != "service=purge,team=edge,ticket=OPS-1" {
+		t.Fatalf("t1 = %+v", got)
+	}
+
+	tests := []struct {
+		filter map[string]string
+		want   bool
+	}{
+		{nil, true},
+		{map[string]string{"team": "edge"}, true},
+		{map[string]string{"team": "edge", "service": "purge"}, true},
+		{map[string]string{"team": "web"}, false},
+		{map[string]string{"owner": "edge"}, false},
+	}
+	for _, tc := range tests {
+		if got := reg["t1"].Matches(tc.filter); got != tc.want {
+			t.Errorf("Matches(%v) = %v, want %v", tc.filter, got, tc.want)
+		}
+	}
+
+	if err := Remove("t1", "ticket"); err != nil {
+		t.Fatalf("Remove() error = %v", err)
+	}
+	if err := Remove("t2"); err != nil {
+		t.Fatalf("Remove() error = %v", err)
+	}
+	reg, _ = Load()
+	if _, ok := reg["t2"]; ok || reg["t1"].String() != "service=purge,team=edge" {
+		t.Fatalf("after Remove() registry = %+v", reg)
+	}
+}
@@ -0,0 +1,179 @@
+// Package naming generates the suffix that makes token names unique. Zones
+// pick a strategy with name_suffix, since systems that index tokens by name
+// sort and deduplicate each scheme differently.
+package naming
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"text/template"
+	"time"
+
+	"cftoken/internal/config"
+)
+
+// Strategies for the generated suffix.
+const (
+	// Timestamp appends the UTC creation time, e.g. 20240102T030405Z.
+	Timestamp = "timestamp"
+	// ULID appends a lexically sortable ULID, e.g. 01HKA4ZC5G3N8Q1M2V7W9X0Y4T.
+	ULID = "ulid"
+	// Hex appends eight random hex digits, e.g. 3f9a0c1d.
+	Hex = "hex"
+	// Sequence appends a counter kept per base name in the state directory,
+	// e.g. 0001.
+	Sequence = "sequence"
+)
+
+// Strategies lists the valid name_suffix values.
+var Strategies = []string{Timestamp, ULID, Hex, Sequence}
+
+// crockford is the ULID alphabet.
+const crockford = "0123456789ABCDEFGHJKMNPQRSTVWXYZ"
+
+// generated matches the suffixes Trim removes. Hex and sequence suffixes
+// cannot be told apart from names that end in digits, so they are kept.
+var generated = regexp.MustCompile(`-(\d{8}T\d{6}Z|[0-9A-HJKMNP-TV-Z]{26})$`)
+
+// Random reports whether strategy's suffix is random, so that names it
+// generates are not produced by any other run.
+func Random(strategy string) bool {
+	switch strings.ToLower(strategy) {
+	case ULID, Hex:
+		return true
+	}
+	return false
+}
+
+// Name returns base with a suffix generated by strategy at now; an empty
+// strategy means Timestamp. With peek set, a Sequence number is read but not
+// taken, so dry runs do not advance the counter.
+func Name(strategy, base string, now time.Time, peek bool) (string, error) {
+	var suffix string
+	switch strings.ToLower(strategy) {
+	case "", Timestamp:
+		suffix = now.UTC().Format("20060102T150405Z")
+	case ULID:
+		id, err := newULID(now)
+		if err != nil {
+			return "", err
+		}
+		suffix = id
+	case Hex:
+		var b [4]byte
+		if _, err := rand.Read(b[:]); err != nil {
+			return "", fmt.Errorf("generate name suffix: %w", err)
+		}
+		suffix = hex.EncodeToString(b[:])
+	case Sequence:
+		n, err := nextSequence(base, peek)
+		if err != nil {
+			return "", err
+		}
+		suffix = fmt.Sprintf("%04d", n)
+	default:
+		return "", fmt.Errorf("unknown name_suffix %q; must be one of %s", strategy, strings.Join(Strategies, ", "))
+	}
+	return base + "-" + suffix, nil
+}
+
+// PrefixData is what a default_token_prefix_template can read.
+type PrefixData struct {
+	Zone   string
+	ZoneID string
+}
+
+// Prefix renders a default_token_prefix_template such as "{{ .Zone }}-ci"
+// into the base of a token name.
+func Prefix(tmpl string, data PrefixData) (string, error) {
+	t, err := template.New("default_token_prefix_template").Option("missingkey=error").Parse(tmpl)
+	if err != nil {
+		return "", fmt.Errorf("default_token_prefix_template: %w", err)
+	}
+	var b strings.Builder
+	if err := t.Execute(&b, data); err != nil {
+		return "", fmt.Errorf("default_token_prefix_template: %w", err)
+	}
+	return strings.TrimSpace(b.String()), nil
+}
+
+// Trim removes a generated timestamp or ULID suffix from name.
+func Trim(name string) string {
+	return generated.ReplaceAllString(name, "")
+}
+
+// newULID encodes the millisecond time and 80 random bits as 26 Crockford
+// base32 characters.
+func newULID(now time.Time) (string, error) {
+	var b [16]byte
+	binary.BigEndian.PutUint64(b[:8], uint64(now.UnixMilli())<<16)
+	if _, err := rand.Read(b[6:]); err != nil {
+		return "", fmt.Errorf("generate name suffix: %w", err)
+	}
+	hi, lo := binary.BigEndian.Uint64(b[:8]), binary.BigEndian.Uint64(b[8:])
+	var out [26]byte
+	for i := len(out) - 1; i >= 0; i-- {
+		out[i] = crockford[lo&31]
+		lo = lo>>5 | hi<<59
+		hi >>= 5
+	}
+	return string(out[:]), nil
+}
+
+// sequencePath returns the file holding the counters.
+func sequencePath() (string, error) {
+	state, err := config.StateDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(state, "name-sequence.json"), nil
+}
+
+// nextSequence returns the next number for base, starting at 1, and records
+// it as taken unless peek is set. Taking a number holds the counters' lock
+// from the read to the write, so concurrent runs never share one.
+func nextSequence(base string, peek bool) (int, error) {
+	path, err := sequencePath()
+	if err != nil {
+		return 0, err
+	}
+	if !peek {
+		unlock, err := config.LockFile(path)
+		if err != nil {
+			return 0, err
+		}
+		defer unlock()
+	}
+	counters := map[string]int{}
+	data, err := os.ReadFile(path)
+	switch {
+	case err == nil:
+		if err := json.Unmarshal(data, &counters); err != nil {
+			return 0, fmt.Errorf("parse name sequences %s: %w", path, err)
+		}
+	case !errors.Is(err, os.ErrNotExist):
+		return 0, err
+	}
+	n := counters[base] + 1
+	if peek {
+		return n, nil
+	}
+	counters[base] = n
+
+	out, err := json.MarshalIndent(counters, "", "  ")
+	if err != nil {
+		return 0, fmt.Errorf("encode name sequences: %w", err)
+	}
+	if err := config.WriteFileAtomic(path, append(out, '\n')); err != nil {
+		return 0, fmt.Errorf("write name sequences: %w", err)
+	}
+	return n, nil
+}
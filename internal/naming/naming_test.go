@@ -0,0 +1,110 @@
+package naming
+
+import (
+	"regexp"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestName(t *testing.T) {
+	t.Setenv("XDG_STATE_HOME", t.TempDir())
+	now := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	tests := []struct {
+		strategy string
+		peek     bool
+		want     string // regular expression
+	}{
+		{"", false, `^web-20240102T030405Z$`},
+		{"timestamp", false, `^web-20240102T030405Z$`},
+		{"ULID", false, `^web-01HK421P48[0-9A-HJKMNP-TV-Z]{16}$`},
+		{"hex", false, `^web-[0-9a-f]{8}$`},
+		{"sequence", true, `^web-0001$`},
+		{"sequence", false, `^web-0001$`},
+		{"sequence", true, `^web-0002$`},
+		{"sequence", false, `^web-0002$`},
+	}
+	for _, tc := range tests {
+		got, err := Name(tc.strategy, "web", now, tc.peek)
+		if err != nil {
+			t.Fatalf("Name(%q) error = %v", tc.strategy, err)
+		}
+		if !regexp.MustCompile(tc.want).MatchString(got) {
+			t.Errorf("Name(%q, peek %v) = %q, want match for %s", tc.strategy, tc.peek, got, tc.want)
+		}
+	}
+
+	if got, err := Name("sequence", "api", now, false); err != nil || got != "api-0001" {
+		t.Errorf("Name(sequence) for another base = %q, %v, want api-0001", got, err)
+	}
+	if _, err := Name("uuid", "web", now, false); err == nil {
+		t.Errorf("Name(uuid) error = nil, want unknown strategy")
+	}
+}
+
+func TestTrim(t *testing.T) {
+	tests := map[string]string{
+		"web-20240102T030405Z":           "web",
+		"web-01HK421P48Y90JT025F1K432WR": "web",
+		"web-0001":                       "web-0001",
+		"web-3f9a0c1d":                   "web-3f9a0c1d",
+		"web":                            "web",
+	}
+	for in, want := range tests {
+		if got := Trim(in); got != want {
+			t.Errorf("Trim(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestPrefix(t *testing.T) {
+	data := PrefixData{Zone: "prod", ZoneID: "abc"}
+	if got, err := Prefix("{{ .Zone }}-{{ .ZoneID }}", data); err != nil || got != "prod-abc" {
+		t.Errorf("Prefix() = %q, %v, want prod-abc", got, err)
+	}
+	for _, tmpl := range []string{"{{ .Zone", "{{ .Team }}"} {
+		if _, err := Prefix(tmpl, data); err == nil {
+			t.Errorf("Prefix(%q) error = nil", tmpl)
+		}
+	}
+}
+
+func TestRandom(t *testing.T) {
+	for strategy, want := range map[string]bool{"": false, Timestamp: false, Sequence: false, ULID: true, "HEX": true} {
+		if got := Random(strategy); got != want {
+			t.Errorf("Random(%q) = %v, want %v", strategy, got, want)
+		}
+	}
+}
+
+func TestSequenceConcurrent(t *testing.T) {
+	t.Setenv("XDG_STATE_HOME", t.TempDir())
+
+	const runs = 20
+	got := make(chan int, runs)
+	var wg sync.WaitGroup
+	for range runs {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			n, err := nextSequence("edge", false)
+			if err != nil {
+				t.Errorf("nextSequence() error = %v", err)
+			}
+			got <- n
+		}()
+	}
+	wg.Wait()
+	close(got)
+	seen := map[int]bool{}
+	for n := range got {
+		if seen[n] {
+			t.Fatalf("nextSequence() handed out %d twice", n)
+		}
+		seen[n] = true
+	}
+	if n, _ := nextSequence("edge", true); n != runs+1 {
+		t.Fatalf("next sequence after %d runs = %d, want %d", runs, n, runs+1)
+	}
+}
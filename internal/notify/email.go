@@ -0,0 +1,171 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"net/smtp"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+
+	"cftoken/internal/config"
+)
+
+const (
+	defaultSubjectTemplate = `[cftoken] {{ .Kind }}: {{ .TokenName }}`
+	defaultBodyTemplate    = `Event:   {{ .Kind }}
+Time:    {{ .Time.UTC.Format "2006-01-02T15:04:05Z07:00" }}
+Token:   {{ .TokenName }}{{ if .TokenID }} ({{ .TokenID }}){{ end }}
+{{- if .Zone }}
+Zone:    {{ .Zone }}{{ end }}
+Expires: {{ if .ExpiresOn }}{{ .ExpiresOn }}{{ else }}none{{ end }}
+Allowed CIDRs: {{ if .AllowedCIDRs }}{{ join .AllowedCIDRs ", " }}{{ else }}none{{ end }}
+{{- if .CorrelationID }}
+Correlation ID: {{ .CorrelationID }}{{ end }}
+{{- if .Reasons }}
+
+Reasons:
+{{- range .Reasons }}
+  - {{ . }}
+{{- end }}
+{{- end }}
+`
+)
+
+// EmailNotifier sends events as plain-text emails over SMTP.
+type EmailNotifier struct {
+	cfg     config.EmailNotification
+	subject *template.Template
+	body    *template.Template
+}
+
+// NewEmailNotifier validates the templates and returns an SMTP notifier.
+func NewEmailNotifier(cfg config.EmailNotification) (*EmailNotifier, error) {
+	funcs := template.FuncMap{"join": strings.Join}
+
+	subjectText := cfg.SubjectTemplate
+	if subjectText == "" {
+		subjectText = defaultSubjectTemplate
+	}
+	subject, err := template.New("subject").Funcs(funcs).Parse(subjectText)
+	if err != nil {
+		return nil, fmt.Errorf("parse subject template: %w", err)
+	}
+
+	bodyText := cfg.BodyTemplate
+	if bodyText == "" {
+		bodyText = defaultBodyTemplate
+	}
+	body, err := template.New("body").Funcs(funcs).Parse(bodyText)
+	if err != nil {
+		return nil, fmt.Errorf("parse body template: %w", err)
+	}
+
+	if cfg.Port == 0 {
+		switch cfg.TLS {
+		case "implicit":
+			cfg.Port = 465
+		case "none":
+			cfg.Port = 25
+		default:
+			cfg.Port = 587
+		}
+	}
+	return &EmailNotifier{cfg: cfg, subject: subject, body: body}, nil
+}
+
+// Notify renders the event and delivers it to every configured recipient.
+func (n *EmailNotifier) Notify(ctx context.Context, ev Event) error {
+	msg, err := n.message(ev)
+	if err != nil {
+		return err
+	}
+
+	addr := net.JoinHostPort(n.cfg.Host, strconv.Itoa(n.cfg.Port))
+	tlsConfig := &tls.Config{ServerName: n.cfg.Host}
+	dialer := &net.Dialer{}
+
+	var conn net.Conn
+	if n.cfg.TLS == "implicit" {
+		conn, err = (&tls.Dialer{NetDialer: dialer, Config: tlsConfig}).DialContext(ctx, "tcp", addr)
+	} else {
+		conn, err = dialer.DialContext(ctx, "tcp", addr)
+	}
+	if err != nil {
+		return fmt.Errorf("connect to %s: %w", addr, err)
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+
+	client, err := smtp.NewClient(conn, n.cfg.Host)
+	if err != nil {
+		conn.Close()
+		return fmt.Errorf("smtp handshake: %w", err)
+	}
+	defer client.Close()
+
+	if n.cfg.TLS == "starttls" {
+		if ok, _ := client.Extension("STARTTLS"); !ok {
+			return errors.New("smtp server does not support STARTTLS; set tls to \"implicit\" or \"none\"")
+		}
+		if err := client.StartTLS(tlsConfig); err != nil {
+			return fmt.Errorf("smtp starttls: %w", err)
+		}
+	}
+	if n.cfg.Username != "" {
+		if err := client.Auth(smtp.PlainAuth("", n.cfg.Username, n.cfg.Password, n.cfg.Host)); err != nil {
+			return fmt.Errorf("smtp auth: %w", err)
+		}
+	}
+
+	if err := client.Mail(n.cfg.From); err != nil {
+		return fmt.Errorf("smtp sender %s: %w", n.cfg.From, err)
+	}
+	for _, rcpt := range n.cfg.To {
+		if err := client.Rcpt(rcpt); err != nil {
+			return fmt.Errorf("smtp recipient %s: %w", rcpt, err)
+		}
+	}
+	w, err := client.Data()
+	if err != nil {
+		return fmt.Errorf("smtp data: %w", err)
+	}
+	if _, err := w.Write(msg); err != nil {
+		return fmt.Errorf("smtp write: %w", err)
+	}
+	if err := w.Close(); err != nil {
+		return fmt.Errorf("smtp write: %w", err)
+	}
+	return client.Quit()
+}
+
+func (n *EmailNotifier) message(ev Event) ([]byte, error) {
+	if ev.Time.IsZero() {
+		ev.Time = time.Now()
+	}
+
+	var subject, body bytes.Buffer
+	if err := n.subject.Execute(&subject, ev); err != nil {
+		return nil, fmt.Errorf("render subject: %w", err)
+	}
+	if err := n.body.Execute(&body, ev); err != nil {
+		return nil, fmt.Errorf("render body: %w", err)
+	}
+
+	var msg bytes.Buffer
+	fmt.Fprintf(&msg, "From: %s\r\n", n.cfg.From)
+	fmt.Fprintf(&msg, "To: %s\r\n", strings.Join(n.cfg.To, ", "))
+	fmt.Fprintf(&msg, "Subject: %s\r\n", strings.Join(strings.Fields(subject.String()), " "))
+	fmt.Fprintf(&msg, "Date: %s\r\n", ev.Time.Format(time.RFC1123Z))
+	msg.WriteString("MIME-Version: 1.0\r\n")
+	msg.WriteString("Content-Type: text/plain; charset=utf-8\r\n\r\n")
+	text := strings.ReplaceAll(body.String(), "\r\n", "\n")
+	msg.WriteString(strings.ReplaceAll(text, "\n", "\r\n"))
+	return msg.Bytes(), nil
+}
@@ -0,0 +1,92 @@
+package notify
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"cftoken/internal/config"
+)
+
+func TestEmailMessageDefaultTemplates(t *testing.T) {
+	t.Parallel()
+
+	n, err := NewEmailNotifier(config.EmailNotification{
+		Host: "smtp.example.com",
+		From: "cftoken@example.com",
+		To:   []string{"ops@example.com", "sec@example.com"},
+		TLS:  "starttls",
+	})
+	if err != nil {
+		t.Fatalf("NewEmailNotifier() error = %v", err)
+	}
+	if n.cfg.Port != 587 {
+		t.Fatalf("default port = %d, want 587", n.cfg.Port)
+	}
+
+	msg, err := n.message(Event{
+		Kind:          EventHighRiskIssuance,
+		Time:          time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC),
+		TokenName:     "prod-20240102T030405Z",
+		TokenID:       "tok-123",
+		Zone:          "prod",
+		Reasons:       []string{"no expiry"},
+		CorrelationID: "corr-1",
+	})
+	if err != nil {
+		t.Fatalf("message() error = %v", err)
+	}
+
+	got := string(msg)
+	for _, want := range []string{
+		"To: ops@example.com, sec@example.com\r\n",
+		"Subject: [cftoken] high_risk_issuance: prod-20240102T030405Z\r\n",
+		"Token:   prod-20240102T030405Z (tok-123)\r\n",
+		"Expires: none\r\n",
+		"  - no expiry",
+		"Correlation ID: corr-1",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("message missing %q:\n%s", want, got)
+		}
+	}
+}
+
+func TestEmailMessageCustomTemplates(t *testing.T) {
+	t.Parallel()
+
+	n, err := NewEmailNotifier(config.EmailNotification{
+		Host:            "smtp.example.com",
+		From:            "cftoken@example.com",
+		To:              []string{"ops@example.com"},
+		TLS:             "implicit",
+		SubjectTemplate: "{{ .Zone }}\n{{ .TokenName }}",
+		BodyTemplate:    "reasons={{ join .Reasons \"|\" }}",
+	})
+	if err != nil {
+		t.Fatalf("NewEmailNotifier() error = %v", err)
+	}
+	if n.cfg.Port != 465 {
+		t.Fatalf("default port = %d, want 465", n.cfg.Port)
+	}
+
+	msg, err := n.message(Event{Zone: "dev", TokenName: "tok", Reasons: []string{"a", "b"}})
+	if err != nil {
+		t.Fatalf("message() error = %v", err)
+	}
+	got := string(msg)
+	if !strings.Contains(got, "Subject: dev tok\r\n") {
+		t.Errorf("subject not flattened onto one line:\n%s", got)
+	}
+	if !strings.HasSuffix(got, "\r\n\r\nreasons=a|b") {
+		t.Errorf("unexpected body:\n%s", got)
+	}
+}
+
+func TestNewEmailNotifierInvalidTemplate(t *testing.T) {
+	t.Parallel()
+
+	if _, err := NewEmailNotifier(config.EmailNotification{SubjectTemplate: "{{ .Kind "}); err == nil {
+		t.Fatalf("NewEmailNotifier() error = nil, want template parse error")
+	}
+}
@@ -0,0 +1,35 @@
+package notify
+
+import (
+	"context"
+	"time"
+)
+
+// EventKind identifies why a notification is sent.
+type EventKind string
+
+const (
+	// EventHighRiskIssuance marks a token created without an expiry or IP restriction.
+	EventHighRiskIssuance EventKind = "high_risk_issuance"
+	// EventExpiryWarning marks a token that is about to expire.
+	EventExpiryWarning EventKind = "expiry_warning"
+)
+
+// Event describes a token lifecycle event worth alerting on.
+type Event struct {
+	Kind         EventKind
+	Time         time.Time
+	TokenName    string
+	TokenID      string
+	Zone         string
+	ExpiresOn    string
+	AllowedCIDRs []string
+	Reasons      []string
+	// CorrelationID ties the event to the CLI run and its API requests.
+	CorrelationID string
+}
+
+// Notifier delivers events to an alerting channel.
+type Notifier interface {
+	Notify(ctx context.Context, ev Event) error
+}
@@ -0,0 +1,209 @@
+// Package request carries token requests across an air gap. A request is
+// rendered and signed on a machine that never sees the management token,
+// then verified and fulfilled on one that holds it. Requests are signed
+// with Ed25519 keys; the fulfilling side only accepts keys it trusts, and
+// each request can be fulfilled once before it expires.
+package request
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"cftoken/internal/config"
+	"cftoken/internal/template"
+)
+
+// Version is the bundle format written by Sign.
+const Version = 1
+
+// Request is the token set a bundle asks for. Tokens keep their base names
+// and TTLs; names get their timestamp and expiry starts when the request
+// is fulfilled.
+type Request struct {
+	Version   int                  `json:"version"`
+	ID        string               `json:"id"`
+	CreatedAt time.Time            `json:"created_at"`
+	ExpiresAt time.Time            `json:"expires_at"`
+	Zone      string               `json:"zone,omitempty"`
+	ZoneID    string               `json:"zone_id,omitempty"`
+	Source    string               `json:"source,omitempty"`
+	Ticket    string               `json:"ticket,omitempty"`
+	Tokens    []template.TokenSpec `json:"tokens"`
+}
+
+// Bundle is a signed request as stored on disk. Signature covers the
+// compact JSON encoding of Request.
+type Bundle struct {
+	Request   json.RawMessage `json:"request"`
+	PublicKey string          `json:"public_key"`
+	Signature string          `json:"signature"`
+}
+
+// ErrUntrusted reports a bundle that is not signed by a trusted key, was
+// tampered with, or has expired.
+var ErrUntrusted = errors.New("untrusted request")
+
+// GenerateKey returns a new key pair: the public key to list in
+// request_signers, and the private key to sign requests with.
+func GenerateKey() (publicKey, privateKey string, err error) {
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return "", "", fmt.Errorf("generate key: %w", err)
+	}
+	return base64.StdEncoding.EncodeToString(pub), base64.StdEncoding.EncodeToString(priv.Seed()), nil
+}
+
+// ReadPrivateKey reads a private key written by GenerateKey from path.
+func ReadPrivateKey(path string) (ed25519.PrivateKey, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read signing key: %w", err)
+	}
+	seed, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(data)))
+	if err != nil || len(seed) != ed25519.SeedSize {
+		return nil, fmt.Errorf("signing key %s is not a base64 Ed25519 key", path)
+	}
+	return ed25519.NewKeyFromSeed(seed), nil
+}
+
+// NewID returns a random request ID.
+func NewID() (string, error) {
+	var b [8]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return "", fmt.Errorf("generate request id: %w", err)
+	}
+	return hex.EncodeToString(b[:]), nil
+}
+
+// Sign encodes req as a bundle signed with key.
+func Sign(req *Request, key ed25519.PrivateKey) ([]byte, error) {
+	data, err := json.Marshal(req)
+	if err != nil {
+		return nil, fmt.Errorf("encode request: %w", err)
+	}
+	bundle := Bundle{
+		Request:   data,
+		PublicKey: base64.StdEncoding.EncodeToString(key.Public().(ed25519.PublicKey)),
+		Signature: base64.StdEncoding.EncodeToString(ed25519.Sign(key, data)),
+	}
+	out, err := json.MarshalIndent(bundle, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("encode request: %w", err)
+	}
+	return append(out, '\n'), nil
+}
+
+// Verify decodes a bundle and returns its request when it is signed by one
+// of trusted and has not expired at now.
+func Verify(data []byte, trusted []string, now time.Time) (*Request, error) {
+	var bundle Bundle
+	if err := json.Unmarshal(data, &bundle); err != nil {
+		return nil, fmt.Errorf("parse request bundle: %w", err)
+	}
+	if !slices.Contains(trusted, bundle.PublicKey) {
+		return nil, fmt.Errorf("%w: signing key %s is not in request_signers", ErrUntrusted, stringOrUnset(bundle.PublicKey))
+	}
+	pub, err := base64.StdEncoding.DecodeString(bundle.PublicKey)
+	if err != nil || len(pub) != ed25519.PublicKeySize {
+		return nil, fmt.Errorf("%w: malformed public key", ErrUntrusted)
+	}
+	sig, err := base64.StdEncoding.DecodeString(bundle.Signature)
+	if err != nil {
+		return nil, fmt.Errorf("%w: malformed signature", ErrUntrusted)
+	}
+	// The bundle may have been reindented since it was signed.
+	var signed bytes.Buffer
+	if err := json.Compact(&signed, bundle.Request); err != nil {
+		return nil, fmt.Errorf("parse request bundle: %w", err)
+	}
+	if !ed25519.Verify(pub, signed.Bytes(), sig) {
+		return nil, fmt.Errorf("%w: signature does not match", ErrUntrusted)
+	}
+
+	var req Request
+	if err := json.Unmarshal(signed.Bytes(), &req); err != nil {
+		return nil, fmt.Errorf("parse request: %w", err)
+	}
+	if req.Version != Version {
+		return nil, fmt.Errorf("request format version %d is not supported", req.Version)
+	}
+	if req.ID == "" {
+		return nil, errors.New("request has no id")
+	}
+	if !now.Before(req.ExpiresAt) {
+		return nil, fmt.Errorf("%w: request %s expired at %s", ErrUntrusted, req.ID, req.ExpiresAt.UTC().Format(time.RFC3339))
+	}
+	return &req, nil
+}
+
+// ledgerPath returns the file recording fulfilled requests.
+func ledgerPath() (string, error) {
+	state, err := config.StateDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(state, "fulfilled-requests.json"), nil
+}
+
+// MarkFulfilled records req as fulfilled, or fails when it already was, so
+// a copied bundle cannot mint the same tokens twice. Entries are dropped
+// once their request has expired and could no longer be verified anyway.
+func MarkFulfilled(req *Request, now time.Time) error {
+	path, err := ledgerPath()
+	if err != nil {
+		return err
+	}
+	ledger := map[string]time.Time{}
+	data, err := os.ReadFile(path)
+	switch {
+	case err == nil:
+		if err := json.Unmarshal(data, &ledger); err != nil {
+			return fmt.Errorf("parse fulfilled requests %s: %w", path, err)
+		}
+	case !errors.Is(err, os.ErrNotExist):
+		return err
+	}
+	if _, ok := ledger[req.ID]; ok {
+		return fmt.Errorf("%w: request %s was already fulfilled", ErrUntrusted, req.ID)
+	}
+	for id, expires := range ledger {
+		if !now.Before(expires) {
+			delete(ledger, id)
+		}
+	}
+	ledger[req.ID] = req.ExpiresAt.UTC()
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return fmt.Errorf("create state directory: %w", err)
+	}
+	out, err := json.MarshalIndent(ledger, "", "  ")
+	if err != nil {
+		return fmt.Errorf("encode fulfilled requests: %w", err)
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, out, 0o600); err != nil {
+		return fmt.Errorf("write fulfilled requests: %w", err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		return fmt.Errorf("write fulfilled requests: %w", err)
+	}
+	return nil
+}
+
+func stringOrUnset(s string) string {
+	if s == "" {
+		return "(none)"
+	}
+	return s
+}
@@ -0,0 +1,102 @@
+package request
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"cftoken/internal/template"
+)
+
+func TestSignVerify(t *testing.T) {
+	dir := t.TempDir()
+	pub, priv, err := GenerateKey()
+	if err != nil {
+		t.Fatalf("GenerateKey() error = %v", err)
+	}
+	keyFile := filepath.Join(dir, "key")
+	if err := os.WriteFile(keyFile, []byte(priv+"\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	key, err := ReadPrivateKey(keyFile)
+	if err != nil {
+		t.Fatalf("ReadPrivateKey() error = %v", err)
+	}
+	otherPub, _, _ := GenerateKey()
+
+	now := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	req := &Request{
+		Version:   Version,
+		ID:        "0123456789abcdef",
+		CreatedAt: now,
+		ExpiresAt: now.Add(time.Hour),
+		Zone:      "example.com",
+		Tokens:    []template.TokenSpec{{Name: "deploy", TTL: "1h", Policies: []template.Policy{{Effect: "allow"}}}},
+	}
+	bundle, err := Sign(req, key)
+	if err != nil {
+		t.Fatalf("Sign() error = %v", err)
+	}
+	var reindented bytes.Buffer
+	if err := json.Indent(&reindented, bundle, "", "\t"); err != nil {
+		t.Fatal(err)
+	}
+	tampered := bytes.Replace(bundle, []byte(`deploy`), []byte(`admin`), 1)
+
+	tests := []struct {
+		name      string
+		data      []byte
+		trusted   []string
+		at        time.Time
+		untrusted bool
+	}{
+		{"valid", bundle, []string{otherPub, pub}, now, false},
+		{"reindented", reindented.Bytes(), []string{pub}, now, false},
+		{"untrusted key", bundle, []string{otherPub}, now, true},
+		{"tampered", tampered, []string{pub}, now, true},
+		{"expired", bundle, []string{pub}, now.Add(time.Hour), true},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := Verify(tc.data, tc.trusted, tc.at)
+			if tc.untrusted {
+				if !errors.Is(err, ErrUntrusted) {
+					t.Fatalf("Verify() error = %v, want ErrUntrusted", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Verify() error = %v", err)
+			}
+			if got.ID != req.ID || got.Zone != req.Zone || len(got.Tokens) != 1 || got.Tokens[0].Name != "deploy" {
+				t.Errorf("Verify() = %+v", got)
+			}
+		})
+	}
+}
+
+func TestMarkFulfilled(t *testing.T) {
+	t.Setenv("XDG_STATE_HOME", t.TempDir())
+	now := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	old := &Request{ID: "old", ExpiresAt: now.Add(time.Minute)}
+	req := &Request{ID: "new", ExpiresAt: now.Add(time.Hour)}
+
+	if err := MarkFulfilled(old, now); err != nil {
+		t.Fatalf("MarkFulfilled(old) error = %v", err)
+	}
+	later := now.Add(2 * time.Minute)
+	if err := MarkFulfilled(req, later); err != nil {
+		t.Fatalf("MarkFulfilled(new) error = %v", err)
+	}
+	if err := MarkFulfilled(req, later); !errors.Is(err, ErrUntrusted) {
+		t.Fatalf("second MarkFulfilled() error = %v, want ErrUntrusted", err)
+	}
+	// The expired entry was pruned, so only the live one is still held.
+	if err := MarkFulfilled(old, later); err != nil {
+		t.Errorf("MarkFulfilled(old) after expiry error = %v", err)
+	}
+}
@@ -0,0 +1,187 @@
+// Package revision keeps every rendered policy document a zone has issued
+// tokens with, so the evolution of granted permissions can be reviewed after
+// the templates that produced them have changed. A revision is identified by
+// the hash of its zone and policies: issuing the same document again adds
+// an issuance to the existing revision instead of creating a new one.
+package revision
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"cftoken/internal/config"
+	"cftoken/internal/template"
+)
+
+// idLength is the number of hex characters of the content hash used as ID.
+const idLength = 12
+
+// Issuance is one token minted from a revision.
+type Issuance struct {
+	At            time.Time `json:"at"`
+	TokenID       string    `json:"token_id"`
+	TokenName     string    `json:"token_name"`
+	AllowedCIDRs  []string  `json:"allowed_cidrs,omitempty"`
+	Ticket        string    `json:"ticket,omitempty"`
+	CorrelationID string    `json:"correlation_id,omitempty"`
+}
+
+// Revision is a rendered policy document and the tokens issued from it.
+type Revision struct {
+	ID     string `json:"id"`
+	Zone   string `json:"zone"`
+	ZoneID string `json:"zone_id,omitempty"`
+	// Source names the template the policies were rendered from.
+	Source    string            `json:"source,omitempty"`
+	Policies  []template.Policy `json:"policies"`
+	Issuances []Issuance        `json:"issuances"`
+}
+
+// FirstIssued returns when the revision was first used.
+func (r *Revision) FirstIssued() time.Time {
+	if len(r.Issuances) == 0 {
+		return time.Time{}
+	}
+	return r.Issuances[0].At
+}
+
+// LastIssued returns when the revision was most recently used.
+func (r *Revision) LastIssued() time.Time {
+	if len(r.Issuances) == 0 {
+		return time.Time{}
+	}
+	return r.Issuances[len(r.Issuances)-1].At
+}
+
+// Dir returns the directory holding revision files.
+func Dir() (string, error) {
+	state, err := config.StateDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(state, "revisions"), nil
+}
+
+// Record stores policies as a revision of zone, or finds the identical
+// existing revision, and appends issued to it.
+func Record(zone, zoneID, source string, policies []template.Policy, issued Issuance) (*Revision, error) {
+	zone = normalizeZone(zone)
+	if zone == "" {
+		return nil, errors.New("record revision: zone is required")
+	}
+	id, err := revisionID(zone, policies)
+	if err != nil {
+		return nil, err
+	}
+	dir, err := Dir()
+	if err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, fmt.Errorf("create revision directory: %w", err)
+	}
+
+	path := filepath.Join(dir, id+".json")
+	rev, err := load(path)
+	switch {
+	case errors.Is(err, os.ErrNotExist):
+		rev = &Revision{ID: id, Zone: zone, Policies: policies}
+	case err != nil:
+		return nil, err
+	}
+	if zoneID != "" {
+		rev.ZoneID = zoneID
+	}
+	if source != "" {
+		rev.Source = source
+	}
+	issued.At = issued.At.UTC()
+	rev.Issuances = append(rev.Issuances, issued)
+	return rev, save(path, rev)
+}
+
+// Load reads the revision with the given ID.
+func Load(id string) (*Revision, error) {
+	if strings.ContainsAny(id, `/\`) || id == "" {
+		return nil, fmt.Errorf("invalid revision id %q", id)
+	}
+	dir, err := Dir()
+	if err != nil {
+		return nil, err
+	}
+	return load(filepath.Join(dir, id+".json"))
+}
+
+// History returns the revisions of zone, oldest first issuance first.
+func History(zone string) ([]*Revision, error) {
+	zone = normalizeZone(zone)
+	dir, err := Dir()
+	if err != nil {
+		return nil, err
+	}
+	paths, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		return nil, err
+	}
+	var out []*Revision
+	for _, path := range paths {
+		rev, err := load(path)
+		if err != nil {
+			return nil, err
+		}
+		if rev.Zone == zone {
+			out = append(out, rev)
+		}
+	}
+	sort.Slice(out, func(a, b int) bool { return out[a].FirstIssued().Before(out[b].FirstIssued()) })
+	return out, nil
+}
+
+// revisionID hashes the zone and the canonical JSON of policies.
+func revisionID(zone string, policies []template.Policy) (string, error) {
+	data, err := json.Marshal(policies)
+	if err != nil {
+		return "", fmt.Errorf("encode policies: %w", err)
+	}
+	sum := sha256.Sum256(append([]byte(zone+"\n"), data...))
+	return hex.EncodeToString(sum[:])[:idLength], nil
+}
+
+func normalizeZone(zone string) string {
+	return strings.ToLower(strings.TrimSuffix(strings.TrimSpace(zone), "."))
+}
+
+func load(path string) (*Revision, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var rev Revision
+	if err := json.Unmarshal(data, &rev); err != nil {
+		return nil, fmt.Errorf("parse revision %s: %w", path, err)
+	}
+	return &rev, nil
+}
+
+func save(path string, rev *Revision) error {
+	data, err := json.MarshalIndent(rev, "", "  ")
+	if err != nil {
+		return fmt.Errorf("encode revision: %w", err)
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o600); err != nil {
+		return fmt.Errorf("write revision %s: %w", rev.ID, err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		return fmt.Errorf("write revision %s: %w", rev.ID, err)
+	}
+	return nil
+}
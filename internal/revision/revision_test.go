@@ -0,0 +1,75 @@
+package revision
+
+import (
+	"testing"
+	"time"
+
+	"cftoken/internal/template"
+)
+
+func policy(group string) []template.Policy {
+	return []template.Policy{{
+		Effect:           "allow",
+		Resources:        map[string]interface{}{"com.cloudflare.api.account.zone.z1": "*"},
+		PermissionGroups: []template.PermissionGroup{{ID: group}},
+	}}
+}
+
+func TestRecordAndHistory(t *testing.T) {
+	t.Setenv("XDG_STATE_HOME", t.TempDir())
+	day := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	first, err := Record("Example.com.", "z1", "site.json.tmpl", policy("read"), Issuance{At: day, TokenID: "t1"})
+	if err != nil {
+		t.Fatalf("Record() error = %v", err)
+	}
+	again, err := Record("example.com", "", "", policy("read"), Issuance{At: day.Add(time.Hour), TokenID: "t2"})
+	if err != nil {
+		t.Fatalf("Record() error = %v", err)
+	}
+	if again.ID != first.ID || len(again.Issuances) != 2 || again.ZoneID != "z1" || again.Source != "site.json.tmpl" {
+		t.Fatalf("identical policies produced %+v, want issuance added to %s", again, first.ID)
+	}
+	changed, err := Record("example.com", "z1", "", policy("write"), Issuance{At: day.Add(2 * time.Hour), TokenID: "t3"})
+	if err != nil {
+		t.Fatalf("Record() error = %v", err)
+	}
+	if changed.ID == first.ID {
+		t.Fatalf("changed policies reused revision %s", first.ID)
+	}
+	if _, err := Record("other.com", "z2", "", policy("read"), Issuance{At: day, TokenID: "t4"}); err != nil {
+		t.Fatalf("Record() error = %v", err)
+	}
+
+	history, err := History("EXAMPLE.COM")
+	if err != nil {
+		t.Fatalf("History() error = %v", err)
+	}
+	if len(history) != 2 || history[0].ID != first.ID || history[1].ID != changed.ID {
+		t.Fatalf("History() = %+v, want %s then %s", history, first.ID, changed.ID)
+	}
+	if !history[0].LastIssued().Equal(day.Add(time.Hour)) {
+		t.Errorf("LastIssued() = %s", history[0].LastIssued())
+	}
+
+	loaded, err := Load(changed.ID)
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if loaded.Policies[0].PermissionGroups[0].ID != "write" {
+		t.Errorf("Load() policies = %+v", loaded.Policies)
+	}
+}
+
+func TestRecordAndLoadValidate(t *testing.T) {
+	t.Setenv("XDG_STATE_HOME", t.TempDir())
+
+	if _, err := Record(" ", "", "", policy("read"), Issuance{}); err == nil {
+		t.Error("Record() without zone error = nil")
+	}
+	for _, id := range []string{"", "../x", `a\b`} {
+		if _, err := Load(id); err == nil {
+			t.Errorf("Load(%q) error = nil", id)
+		}
+	}
+}
@@ -0,0 +1,283 @@
+// Package sink delivers newly created tokens to secret stores by driving
+// their official CLIs, to a local file, or to a one-time secret service.
+// Token values are always passed on stdin or in a request body, never as
+// command-line arguments, so they do not show up in process listings.
+package sink
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"cftoken/internal/config"
+	"cftoken/internal/duration"
+)
+
+// Sink stores a token value somewhere other than the terminal.
+type Sink interface {
+	Deliver(ctx context.Context, value string) error
+	// String describes the destination for log output.
+	String() string
+}
+
+// Linker is implemented by sinks whose delivery yields a link to the value,
+// such as a one-time secret URL. The link is shown in place of the value.
+type Linker interface {
+	Link() string
+}
+
+// runner executes name with args, feeding stdin to the process.
+type runner func(ctx context.Context, stdin []byte, name string, args ...string) error
+
+// New validates cfg and returns the matching sink.
+func New(cfg config.SinkConfig) (Sink, error) {
+	switch strings.ToLower(strings.TrimSpace(cfg.Type)) {
+	case "vault":
+		if cfg.Path == "" {
+			return nil, fmt.Errorf("vault sink: path is required")
+		}
+		return &vaultSink{path: cfg.Path, field: stringOr(cfg.Field, "token"), run: runCommand}, nil
+	case "kubernetes", "k8s":
+		if cfg.Name == "" {
+			return nil, fmt.Errorf("kubernetes sink: name is required")
+		}
+		return &kubernetesSink{namespace: cfg.Namespace, name: cfg.Name, key: stringOr(cfg.Key, "token"), run: runCommand}, nil
+	case "github", "gh":
+		if cfg.Repo == "" || cfg.Name == "" {
+			return nil, fmt.Errorf("github sink: repo and name are required")
+		}
+		return &githubSink{repo: cfg.Repo, name: cfg.Name, environment: cfg.Environment, run: runCommand}, nil
+	case "":
+		return nil, fmt.Errorf("sink type is required")
+	default:
+		return nil, fmt.Errorf("unknown sink type %q; must be vault, kubernetes, or github", cfg.Type)
+	}
+}
+
+type vaultSink struct {
+	path, field string
+	run         runner
+}
+
+func (s *vaultSink) Deliver(ctx context.Context, value string) error {
+	// "field=-" makes vault read the value from stdin.
+	return s.run(ctx, []byte(value), "vault", "kv", "put", s.path, s.field+"=-")
+}
+
+func (s *vaultSink) String() string {
+	return fmt.Sprintf("vault %s (%s)", s.path, s.field)
+}
+
+type kubernetesSink struct {
+	namespace, name, key string
+	run                  runner
+}
+
+func (s *kubernetesSink) Deliver(ctx context.Context, value string) error {
+	manifest, err := s.manifest(value)
+	if err != nil {
+		return err
+	}
+	return s.run(ctx, manifest, "kubectl", "apply", "-f", "-")
+}
+
+func (s *kubernetesSink) manifest(value string) ([]byte, error) {
+	metadata := map[string]string{"name": s.name}
+	if s.namespace != "" {
+		metadata["namespace"] = s.namespace
+	}
+	return json.Marshal(map[string]any{
+		"apiVersion": "v1",
+		"kind":       "Secret",
+		"type":       "Opaque",
+		"metadata":   metadata,
+		"data":       map[string]string{s.key: base64.StdEncoding.EncodeToString([]byte(value))},
+	})
+}
+
+func (s *kubernetesSink) String() string {
+	if s.namespace == "" {
+		return fmt.Sprintf("kubernetes secret %s (%s)", s.name, s.key)
+	}
+	return fmt.Sprintf("kubernetes secret %s/%s (%s)", s.namespace, s.name, s.key)
+}
+
+type githubSink struct {
+	repo, name, environment string
+	run                     runner
+}
+
+func (s *githubSink) Deliver(ctx context.Context, value string) error {
+	// Without --body, gh reads the secret value from stdin.
+	args := []string{"secret", "set", s.name, "--repo", s.repo}
+	if s.environment != "" {
+		args = append(args, "--env", s.environment)
+	}
+	return s.run(ctx, []byte(value), "gh", args...)
+}
+
+func (s *githubSink) String() string {
+	if s.environment != "" {
+		return fmt.Sprintf("github secret %s in %s (environment %s)", s.name, s.repo, s.environment)
+	}
+	return fmt.Sprintf("github secret %s in %s", s.name, s.repo)
+}
+
+// File returns a sink that writes the value to path, readable and writable
+// only by the current user. Unless overwrite is set, an existing file is
+// left alone and delivery fails.
+func File(path string, overwrite bool) Sink {
+	return &fileSink{path: path, overwrite: overwrite}
+}
+
+type fileSink struct {
+	path      string
+	overwrite bool
+}
+
+func (s *fileSink) Deliver(_ context.Context, value string) error {
+	if !s.overwrite {
+		f, err := os.OpenFile(s.path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o600)
+		if err != nil {
+			return err
+		}
+		_, err = f.WriteString(value)
+		return errors.Join(err, f.Close())
+	}
+	// Write a new file and rename it over the old one, so the value is
+	// never stored under the old file's permissions.
+	f, err := os.CreateTemp(filepath.Dir(s.path), "."+filepath.Base(s.path)+".*")
+	if err != nil {
+		return err
+	}
+	_, err = f.WriteString(value)
+	if err = errors.Join(err, f.Close()); err == nil {
+		err = os.Rename(f.Name(), s.path)
+	}
+	if err != nil {
+		os.Remove(f.Name())
+	}
+	return err
+}
+
+func (s *fileSink) String() string {
+	return "file " + s.path
+}
+
+// Share returns a sink that stores the value as a single-view secret on the
+// One-Time Secret instance cfg names, using its v1 API. The instance must
+// be reached over HTTPS, except on a loopback address.
+func Share(cfg config.ShareConfig) (Sink, error) {
+	u, err := url.Parse(cfg.URL)
+	if err != nil || u.Host == "" {
+		return nil, fmt.Errorf("share: invalid url %q", cfg.URL)
+	}
+	if u.Scheme != "https" && !(u.Scheme == "http" && isLoopback(u.Hostname())) {
+		return nil, fmt.Errorf("share: url %q must use https", cfg.URL)
+	}
+	var ttl time.Duration
+	if cfg.TTL != "" {
+		if ttl, err = duration.Parse(cfg.TTL); err != nil {
+			return nil, fmt.Errorf("share: %w", err)
+		}
+	}
+	return &shareSink{base: strings.TrimRight(cfg.URL, "/"), username: cfg.Username, apiKey: cfg.APIKey, ttl: ttl, client: http.DefaultClient}, nil
+}
+
+type shareSink struct {
+	base, username, apiKey string
+	ttl                    time.Duration
+	client                 *http.Client
+	link                   string
+}
+
+func (s *shareSink) Deliver(ctx context.Context, value string) error {
+	form := url.Values{"secret": {value}}
+	if s.ttl > 0 {
+		form.Set("ttl", strconv.Itoa(int(s.ttl.Seconds())))
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.base+"/api/v1/share", strings.NewReader(form.Encode()))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	if s.username != "" || s.apiKey != "" {
+		req.SetBasicAuth(s.username, s.apiKey)
+	}
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode != http.StatusOK {
+		var apiErr struct {
+			Message string `json:"message"`
+		}
+		if json.Unmarshal(body, &apiErr) == nil && apiErr.Message != "" {
+			return fmt.Errorf("one-time secret service: %s: %s", resp.Status, apiErr.Message)
+		}
+		return fmt.Errorf("one-time secret service: %s", resp.Status)
+	}
+	var shared struct {
+		SecretKey string `json:"secret_key"`
+	}
+	if err := json.Unmarshal(body, &shared); err != nil || shared.SecretKey == "" {
+		return errors.New("one-time secret service: response has no secret_key")
+	}
+	s.link = s.base + "/secret/" + url.PathEscape(shared.SecretKey)
+	return nil
+}
+
+func (s *shareSink) String() string {
+	return "one-time secret at " + s.base
+}
+
+func (s *shareSink) Link() string {
+	return s.link
+}
+
+func isLoopback(host string) bool {
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
+func runCommand(ctx context.Context, stdin []byte, name string, args ...string) error {
+	cmd := exec.CommandContext(ctx, name, args...)
+	cmd.Stdin = bytes.NewReader(stdin)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return fmt.Errorf("%s: %w: %s", name, err, msg)
+		}
+		return fmt.Errorf("%s: %w", name, err)
+	}
+	return nil
+}
+
+func stringOr(value, fallback string) string {
+	if value = strings.TrimSpace(value); value != "" {
+		return value
+	}
+	return fallback
+}
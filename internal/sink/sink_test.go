@@ -0,0 +1,208 @@
+package sink
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"cftoken/internal/config"
+)
+
+type recorded struct {
+	stdin []byte
+	argv  string
+}
+
+func record(s Sink, rec *recorded) {
+	run := func(_ context.Context, stdin []byte, name string, args ...string) error {
+		rec.stdin = stdin
+		rec.argv = strings.Join(append([]string{name}, args...), " ")
+		return nil
+	}
+	switch s := s.(type) {
+	case *vaultSink:
+		s.run = run
+	case *kubernetesSink:
+		s.run = run
+	case *githubSink:
+		s.run = run
+	}
+}
+
+func TestDeliver(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		cfg      config.SinkConfig
+		wantArgv string
+		rawStdin bool
+	}{
+		{"vault", config.SinkConfig{Type: "vault", Path: "secret/cf/prod"}, "vault kv put secret/cf/prod token=-", true},
+		{"github", config.SinkConfig{Type: "github", Repo: "acme/site", Name: "CF_TOKEN", Environment: "prod"}, "gh secret set CF_TOKEN --repo acme/site --env prod", true},
+		{"kubernetes", config.SinkConfig{Type: "kubernetes", Namespace: "ops", Name: "cf"}, "kubectl apply -f -", false},
+	}
+
+	for _, tc := range tests {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			s, err := New(tc.cfg)
+			if err != nil {
+				t.Fatalf("New() error = %v", err)
+			}
+			var rec recorded
+			record(s, &rec)
+			if err := s.Deliver(context.Background(), "secret-value"); err != nil {
+				t.Fatalf("Deliver() error = %v", err)
+			}
+			if rec.argv != tc.wantArgv {
+				t.Fatalf("argv = %q, want %q", rec.argv, tc.wantArgv)
+			}
+			if strings.Contains(rec.argv, "secret-value") {
+				t.Fatalf("token value leaked into argv: %q", rec.argv)
+			}
+			if tc.rawStdin && string(rec.stdin) != "secret-value" {
+				t.Fatalf("stdin = %q, want token value", rec.stdin)
+			}
+		})
+	}
+}
+
+func TestKubernetesManifest(t *testing.T) {
+	t.Parallel()
+
+	s := &kubernetesSink{namespace: "ops", name: "cf", key: "token"}
+	data, err := s.manifest("secret-value")
+	if err != nil {
+		t.Fatalf("manifest() error = %v", err)
+	}
+	var got struct {
+		Kind     string            `json:"kind"`
+		Metadata map[string]string `json:"metadata"`
+		Data     map[string]string `json:"data"`
+	}
+	if err := json.Unmarshal(data, &got); err != nil {
+		t.Fatalf("unmarshal manifest: %v", err)
+	}
+	if got.Kind != "Secret" || got.Metadata["namespace"] != "ops" || got.Metadata["name"] != "cf" {
+		t.Fatalf("unexpected manifest: %s", data)
+	}
+	if got.Data["token"] != base64.StdEncoding.EncodeToString([]byte("secret-value")) {
+		t.Fatalf("data.token = %q", got.Data["token"])
+	}
+}
+
+func TestNewErrors(t *testing.T) {
+	t.Parallel()
+
+	for _, cfg := range []config.SinkConfig{
+		{},
+		{Type: "s3"},
+		{Type: "vault"},
+		{Type: "kubernetes"},
+		{Type: "github", Name: "CF_TOKEN"},
+	} {
+		if _, err := New(cfg); err == nil {
+			t.Errorf("New(%+v) error = nil, want error", cfg)
+		}
+	}
+}
+
+func TestFileSink(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "token")
+	if err := File(path, false).Deliver(context.Background(), "first"); err != nil {
+		t.Fatalf("Deliver() error = %v", err)
+	}
+	if err := File(path, false).Deliver(context.Background(), "second"); !errors.Is(err, fs.ErrExist) {
+		t.Errorf("Deliver() to an existing file error = %v, want fs.ErrExist", err)
+	}
+	if err := os.Chmod(path, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := File(path, true).Deliver(context.Background(), "third"); err != nil {
+		t.Fatalf("Deliver() with overwrite error = %v", err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil || string(data) != "third" {
+		t.Errorf("file = %q, %v, want the overwritten value", data, err)
+	}
+	if runtime.GOOS != "windows" {
+		info, err := os.Stat(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if perm := info.Mode().Perm(); perm != 0o600 {
+			t.Errorf("file mode = %v, want 0600", perm)
+		}
+	}
+	if entries, _ := os.ReadDir(filepath.Dir(path)); len(entries) != 1 {
+		t.Errorf("directory holds %d files, want only the token", len(entries))
+	}
+}
+
+func TestShareSink(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.URL.Path != "/api/v1/share" {
+			t.Errorf("request = %s %s", r.Method, r.URL.Path)
+		}
+		if user, key, ok := r.BasicAuth(); !ok || user != "ops@example.com" || key != "api-key" {
+			t.Errorf("basic auth = %q, %q, %v", user, key, ok)
+		}
+		if got := r.PostFormValue("secret"); got != "token-value" {
+			t.Errorf("secret = %q", got)
+		}
+		if got := r.PostFormValue("ttl"); got != "3600" {
+			t.Errorf("ttl = %q, want 3600", got)
+		}
+		w.Write([]byte(`{"secret_key": "abc123", "metadata_key": "meta"}`))
+	}))
+	defer srv.Close()
+
+	s, err := Share(config.ShareConfig{URL: srv.URL + "/", Username: "ops@example.com", APIKey: "api-key", TTL: "1h"})
+	if err != nil {
+		t.Fatalf("Share() error = %v", err)
+	}
+	if err := s.Deliver(context.Background(), "token-value"); err != nil {
+		t.Fatalf("Deliver() error = %v", err)
+	}
+	if got, want := s.(Linker).Link(), srv.URL+"/secret/abc123"; got != want {
+		t.Errorf("Link() = %q, want %q", got, want)
+	}
+	if strings.Contains(s.String(), "abc123") {
+		t.Errorf("String() = %q reveals the link", s.String())
+	}
+}
+
+func TestShareSinkErrors(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+		w.Write([]byte(`{"message": "Not authorized"}`))
+	}))
+	defer srv.Close()
+	s, err := Share(config.ShareConfig{URL: srv.URL})
+	if err != nil {
+		t.Fatalf("Share() error = %v", err)
+	}
+	if err := s.Deliver(context.Background(), "token-value"); err == nil || !strings.Contains(err.Error(), "Not authorized") {
+		t.Errorf("Deliver() error = %v, want the service's message", err)
+	}
+
+	for _, u := range []string{"http://ots.example.com", "ots.example.com", "ftp://127.0.0.1"} {
+		if _, err := Share(config.ShareConfig{URL: u}); err == nil {
+			t.Errorf("Share(%q) error = nil, want error", u)
+		}
+	}
+}
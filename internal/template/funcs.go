@@ -0,0 +1,101 @@
+package template
+
+import (
+	"fmt"
+	"math/big"
+	"net/netip"
+	"text/template"
+	"time"
+
+	"cftoken/internal/duration"
+)
+
+// now is replaced in tests for deterministic output.
+var now = time.Now
+
+// funcMap returns the helper functions available to every policy template.
+func funcMap() template.FuncMap {
+	return template.FuncMap{
+		"now":         func() time.Time { return now().UTC() },
+		"addDuration": addDuration,
+		"rfc3339":     func(t time.Time) string { return t.UTC().Format(time.RFC3339) },
+		"formatTime":  func(layout string, t time.Time) string { return t.UTC().Format(layout) },
+
+		"cidrhost":      cidrHost,
+		"cidrContains":  cidrContains,
+		"normalizeCIDR": normalizeCIDR,
+	}
+}
+
+// newTemplate creates a named template with the helper functions installed.
+func newTemplate(name string) *template.Template {
+	return template.New(name).Funcs(funcMap())
+}
+
+// addDuration adds a duration such as "8h" or "2d" to t, so templates can
+// write {{ now | addDuration "8h" | rfc3339 }}.
+func addDuration(d string, t time.Time) (time.Time, error) {
+	dur, err := duration.Parse(d)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("addDuration: %w", err)
+	}
+	return t.Add(dur), nil
+}
+
+// cidrHost returns the hostnum-th address in prefix; negative numbers count
+// back from the end of the range, so -1 is the last address.
+func cidrHost(prefix string, hostnum int) (string, error) {
+	p, err := netip.ParsePrefix(prefix)
+	if err != nil {
+		return "", fmt.Errorf("cidrhost: %w", err)
+	}
+	p = p.Masked()
+
+	size := new(big.Int).Lsh(big.NewInt(1), uint(p.Addr().BitLen()-p.Bits()))
+	offset := big.NewInt(int64(hostnum))
+	if hostnum < 0 {
+		offset.Add(offset, size)
+	}
+	if offset.Sign() < 0 || offset.Cmp(size) >= 0 {
+		return "", fmt.Errorf("cidrhost: host number %d is outside %s", hostnum, p)
+	}
+
+	raw := p.Addr().As16()
+	n := new(big.Int).SetBytes(raw[:])
+	n.Add(n, offset).FillBytes(raw[:])
+	addr := netip.AddrFrom16(raw)
+	if p.Addr().Is4() {
+		addr = addr.Unmap()
+	}
+	return addr.String(), nil
+}
+
+// cidrContains reports whether prefix contains ipOrPrefix, which may be a
+// single address or a narrower prefix.
+func cidrContains(prefix, ipOrPrefix string) (bool, error) {
+	p, err := netip.ParsePrefix(prefix)
+	if err != nil {
+		return false, fmt.Errorf("cidrContains: %w", err)
+	}
+	if addr, err := netip.ParseAddr(ipOrPrefix); err == nil {
+		return p.Contains(addr), nil
+	}
+	inner, err := netip.ParsePrefix(ipOrPrefix)
+	if err != nil {
+		return false, fmt.Errorf("cidrContains: %q is neither an address nor a prefix", ipOrPrefix)
+	}
+	return inner.Bits() >= p.Bits() && p.Contains(inner.Addr()), nil
+}
+
+// normalizeCIDR clears host bits from a prefix and turns a bare address into
+// a single-host prefix, e.g. "10.0.0.5/24" becomes "10.0.0.0/24".
+func normalizeCIDR(s string) (string, error) {
+	if addr, err := netip.ParseAddr(s); err == nil {
+		return netip.PrefixFrom(addr, addr.BitLen()).String(), nil
+	}
+	p, err := netip.ParsePrefix(s)
+	if err != nil {
+		return "", fmt.Errorf("normalizeCIDR: %w", err)
+	}
+	return p.Masked().String(), nil
+}
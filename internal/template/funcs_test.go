@@ -0,0 +1,105 @@
+package template
+
+import (
+	"testing"
+	"time"
+)
+
+func TestTimeFunctions(t *testing.T) {
+	fixed := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	orig := now
+	now = func() time.Time { return fixed }
+	t.Cleanup(func() { now = orig })
+
+	inline := `[{"effect": "allow",
+  "resources": {"com.cloudflare.api.account.zone.{{ .ZoneID }}": "issued {{ now | rfc3339 }} until {{ now | addDuration .TTL | rfc3339 }} on {{ now | formatTime "2006-01-02" }}"},
+  "permission_groups": []}]`
+
+	policies, err := RenderPolicies("", inline, Variables{"ZoneID": "z", "TTL": "1d8h"})
+	if err != nil {
+		t.Fatalf("RenderPolicies() error = %v", err)
+	}
+	got := policies[0].Resources["com.cloudflare.api.account.zone.z"]
+	want := "issued 2024-03-01T12:00:00Z until 2024-03-02T20:00:00Z on 2024-03-01"
+	if got != want {
+		t.Fatalf("rendered = %q, want %q", got, want)
+	}
+}
+
+func TestAddDurationInvalid(t *testing.T) {
+	t.Parallel()
+
+	if _, err := RenderPolicies("", `{{ now | addDuration "soon" }}`, nil); err == nil {
+		t.Fatalf("RenderPolicies() error = nil, want addDuration error")
+	}
+}
+
+func TestCIDRFunctions(t *testing.T) {
+	t.Parallel()
+
+	host := func(prefix string, n int) string {
+		t.Helper()
+		got, err := cidrHost(prefix, n)
+		if err != nil {
+			t.Fatalf("cidrHost(%q, %d) error = %v", prefix, n, err)
+		}
+		return got
+	}
+	if got := host("10.0.1.0/24", 5); got != "10.0.1.5" {
+		t.Errorf("cidrhost = %s, want 10.0.1.5", got)
+	}
+	if got := host("10.0.1.7/24", -1); got != "10.0.1.255" {
+		t.Errorf("cidrhost last = %s, want 10.0.1.255", got)
+	}
+	if got := host("2001:db8::/64", 16); got != "2001:db8::10" {
+		t.Errorf("cidrhost v6 = %s, want 2001:db8::10", got)
+	}
+	if _, err := cidrHost("10.0.0.0/30", 4); err == nil {
+		t.Errorf("cidrhost out of range error = nil")
+	}
+
+	containsTests := []struct {
+		prefix, inner string
+		want          bool
+	}{
+		{"10.0.0.0/8", "10.1.2.3", true},
+		{"10.0.0.0/8", "10.1.0.0/16", true},
+		{"10.1.0.0/16", "10.0.0.0/8", false},
+		{"10.0.0.0/8", "192.168.0.1", false},
+	}
+	for _, tc := range containsTests {
+		got, err := cidrContains(tc.prefix, tc.inner)
+		if err != nil || got != tc.want {
+			t.Errorf("cidrContains(%q, %q) = %v, %v; want %v", tc.prefix, tc.inner, got, err, tc.want)
+		}
+	}
+	if _, err := cidrContains("10.0.0.0/8", "nope"); err == nil {
+		t.Errorf("cidrContains invalid error = nil")
+	}
+
+	normalizeTests := map[string]string{
+		"10.0.0.5/24": "10.0.0.0/24",
+		"10.0.0.5":    "10.0.0.5/32",
+		"2001:db8::1": "2001:db8::1/128",
+	}
+	for in, want := range normalizeTests {
+		if got, err := normalizeCIDR(in); err != nil || got != want {
+			t.Errorf("normalizeCIDR(%q) = %q, %v; want %q", in, got, err, want)
+		}
+	}
+}
+
+func TestCIDRFunctionsInTemplate(t *testing.T) {
+	t.Parallel()
+
+	inline := `[{"effect": "allow",
+  "resources": {"{{ normalizeCIDR .Net }}": "{{ cidrhost .Net 1 }} {{ cidrContains "10.0.0.0/8" .Net }}"},
+  "permission_groups": []}]`
+	policies, err := RenderPolicies("", inline, Variables{"Net": "10.2.0.9/16"})
+	if err != nil {
+		t.Fatalf("RenderPolicies() error = %v", err)
+	}
+	if got := policies[0].Resources["10.2.0.0/16"]; got != "10.2.0.1 true" {
+		t.Fatalf("rendered = %v, want \"10.2.0.1 true\"", got)
+	}
+}
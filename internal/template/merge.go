@@ -0,0 +1,156 @@
+package template
+
+import (
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// MergePolicies reduces policies to a minimal equivalent set before they
+// are submitted: duplicate permission groups are dropped, policies with the
+// same effect and resources share one policy, and policies with the same
+// effect and permission groups share their resources. Policies with an ID
+// are existing API policies and are only deduplicated, never combined. The
+// returned warnings name permission groups that are both allowed and denied
+// on the same resource.
+func MergePolicies(policies []Policy) ([]Policy, []string) {
+	out := make([]Policy, 0, len(policies))
+	for _, p := range policies {
+		p.PermissionGroups = uniqueGroups(p.PermissionGroups)
+		out = append(out, p)
+	}
+	out = combine(out, resourcesKey, func(into *Policy, p Policy) bool {
+		into.PermissionGroups = uniqueGroups(append(into.PermissionGroups, p.PermissionGroups...))
+		return true
+	})
+	out = combine(out, groupsKey, func(into *Policy, p Policy) bool {
+		for key, value := range p.Resources {
+			if existing, ok := into.Resources[key]; ok && encode(existing) != encode(value) {
+				return false
+			}
+		}
+		resources := make(map[string]interface{}, len(into.Resources)+len(p.Resources))
+		for key, value := range into.Resources {
+			resources[key] = value
+		}
+		for key, value := range p.Resources {
+			resources[key] = value
+		}
+		into.Resources = resources
+		return true
+	})
+	return out, conflicts(out)
+}
+
+// combine folds each policy into the first earlier one with the same effect
+// and key, as long as merge accepts it.
+func combine(policies []Policy, key func(Policy) string, merge func(into *Policy, p Policy) bool) []Policy {
+	out := make([]Policy, 0, len(policies))
+	index := make(map[string]int)
+	for _, p := range policies {
+		if p.ID != "" {
+			out = append(out, p)
+			continue
+		}
+		k := p.Effect + "\x00" + key(p)
+		if i, ok := index[k]; ok && merge(&out[i], p) {
+			continue
+		}
+		index[k] = len(out)
+		out = append(out, p)
+	}
+	return out
+}
+
+// conflicts lists permission groups a deny policy takes back from an allow
+// policy on the same resource key.
+func conflicts(policies []Policy) []string {
+	var warnings []string
+	for _, allow := range policies {
+		if allow.Effect == "deny" {
+			continue
+		}
+		for _, deny := range policies {
+			if deny.Effect != "deny" {
+				continue
+			}
+			for _, key := range sortedResourceKeys(allow.Resources) {
+				if _, ok := deny.Resources[key]; !ok {
+					continue
+				}
+				for _, g := range allow.PermissionGroups {
+					if !slices.ContainsFunc(deny.PermissionGroups, func(d PermissionGroup) bool { return groupKey(d) == groupKey(g) }) {
+						continue
+					}
+					w := fmt.Sprintf("permission group %s is both allowed and denied on %s", groupLabel(g), key)
+					if !slices.Contains(warnings, w) {
+						warnings = append(warnings, w)
+					}
+				}
+			}
+		}
+	}
+	return warnings
+}
+
+// uniqueGroups drops repeated permission groups, keeping the first.
+func uniqueGroups(groups []PermissionGroup) []PermissionGroup {
+	out := make([]PermissionGroup, 0, len(groups))
+	for _, g := range groups {
+		if !slices.ContainsFunc(out, func(o PermissionGroup) bool { return groupKey(o) == groupKey(g) }) {
+			out = append(out, g)
+		}
+	}
+	return out
+}
+
+// resourcesKey identifies a policy's resources.
+func resourcesKey(p Policy) string {
+	return encode(p.Resources)
+}
+
+// encode returns a canonical form of a resource value; encoding/json sorts
+// map keys.
+func encode(v interface{}) string {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprint(v)
+	}
+	return string(data)
+}
+
+// groupsKey identifies a policy's set of permission groups.
+func groupsKey(p Policy) string {
+	keys := make([]string, len(p.PermissionGroups))
+	for i, g := range p.PermissionGroups {
+		keys[i] = groupKey(g)
+	}
+	slices.Sort(keys)
+	return strings.Join(keys, ",")
+}
+
+// groupKey identifies a permission group by ID, or by name when a template
+// only names it.
+func groupKey(g PermissionGroup) string {
+	if g.ID != "" {
+		return g.ID
+	}
+	return "name:" + g.Name
+}
+
+func groupLabel(g PermissionGroup) string {
+	if g.Name != "" {
+		return fmt.Sprintf("%q", g.Name)
+	}
+	return g.ID
+}
+
+func sortedResourceKeys(resources map[string]interface{}) []string {
+	keys := make([]string, 0, len(resources))
+	for key := range resources {
+		keys = append(keys, key)
+	}
+	slices.Sort(keys)
+	return keys
+}
@@ -0,0 +1,81 @@
+package template
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestMergePolicies(t *testing.T) {
+	zone := map[string]interface{}{"com.cloudflare.api.account.zone.z1": "*"}
+	other := map[string]interface{}{"com.cloudflare.api.account.zone.z2": "*"}
+	dns := PermissionGroup{ID: "dns", Name: "DNS Write"}
+	purge := PermissionGroup{ID: "purge", Name: "Cache Purge"}
+
+	tests := []struct {
+		name     string
+		in       []Policy
+		want     []Policy
+		warnings int
+	}{
+		{
+			name: "already minimal",
+			in:   []Policy{{Effect: "allow", Resources: zone, PermissionGroups: []PermissionGroup{dns}}},
+			want: []Policy{{Effect: "allow", Resources: zone, PermissionGroups: []PermissionGroup{dns}}},
+		},
+		{
+			name: "duplicate groups",
+			in:   []Policy{{Effect: "allow", Resources: zone, PermissionGroups: []PermissionGroup{dns, purge, {ID: "dns"}}}},
+			want: []Policy{{Effect: "allow", Resources: zone, PermissionGroups: []PermissionGroup{dns, purge}}},
+		},
+		{
+			name: "same resources",
+			in: []Policy{
+				{Effect: "allow", Resources: zone, PermissionGroups: []PermissionGroup{dns}},
+				{Effect: "allow", Resources: map[string]interface{}{"com.cloudflare.api.account.zone.z1": "*"}, PermissionGroups: []PermissionGroup{dns, purge}},
+			},
+			want: []Policy{{Effect: "allow", Resources: zone, PermissionGroups: []PermissionGroup{dns, purge}}},
+		},
+		{
+			name: "same groups",
+			in: []Policy{
+				{Effect: "allow", Resources: zone, PermissionGroups: []PermissionGroup{dns, purge}},
+				{Effect: "allow", Resources: other, PermissionGroups: []PermissionGroup{purge, dns}},
+			},
+			want: []Policy{{Effect: "allow", Resources: map[string]interface{}{
+				"com.cloudflare.api.account.zone.z1": "*",
+				"com.cloudflare.api.account.zone.z2": "*",
+			}, PermissionGroups: []PermissionGroup{dns, purge}}},
+		},
+		{
+			name: "effects and ids kept apart",
+			in: []Policy{
+				{Effect: "allow", Resources: zone, PermissionGroups: []PermissionGroup{dns, purge}},
+				{Effect: "deny", Resources: zone, PermissionGroups: []PermissionGroup{dns}},
+				{ID: "p1", Effect: "allow", Resources: zone, PermissionGroups: []PermissionGroup{purge}},
+			},
+			want: []Policy{
+				{Effect: "allow", Resources: zone, PermissionGroups: []PermissionGroup{dns, purge}},
+				{Effect: "deny", Resources: zone, PermissionGroups: []PermissionGroup{dns}},
+				{ID: "p1", Effect: "allow", Resources: zone, PermissionGroups: []PermissionGroup{purge}},
+			},
+			warnings: 1,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got, warnings := MergePolicies(tc.in)
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("MergePolicies() = %+v, want %+v", got, tc.want)
+			}
+			if len(warnings) != tc.warnings {
+				t.Errorf("MergePolicies() warnings = %q, want %d", warnings, tc.warnings)
+			}
+			for _, w := range warnings {
+				if !strings.Contains(w, `"DNS Write"`) {
+					t.Errorf("warning %q does not name the group", w)
+				}
+			}
+		})
+	}
+}
@@ -3,11 +3,12 @@ package template
 import (
 	"bytes"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io/fs"
 	"os"
 	"path/filepath"
 	"strings"
-	"text/template"
 )
 
 // Variables holds the context for template rendering.
@@ -28,53 +29,76 @@ type PermissionGroup struct {
 }
 
 // RenderPolicies renders a template and returns Cloudflare API token policies.
-// The template must render to a JSON array of policy objects.
+// The template must render to a JSON array of policy objects. Template files
+// with a sidecar spec have their variables validated and defaulted first.
 func RenderPolicies(templatePath, inlineTemplate string, vars Variables) ([]Policy, error) {
-	if templatePath == "" && inlineTemplate == "" {
-		return nil, fmt.Errorf("either template_file or template_inline must be specified")
+	rendered, err := render(templatePath, inlineTemplate, vars)
+	if err != nil {
+		return nil, err
 	}
 
-	var templateContent string
-	var templateName string
+	// Parse as policy array
+	var policies []Policy
+	if err := json.Unmarshal([]byte(rendered), &policies); err != nil {
+		return nil, fmt.Errorf("parse rendered template as policies: %w\nRendered content:\n%s", err, rendered)
+	}
 
-	if inlineTemplate != "" {
-		templateContent = inlineTemplate
-		templateName = "inline"
-	} else {
-		expandedPath, err := expandPath(templatePath)
-		if err != nil {
-			return nil, fmt.Errorf("expand template path: %w", err)
-		}
+	return policies, nil
+}
 
-		data, err := os.ReadFile(expandedPath)
-		if err != nil {
-			return nil, fmt.Errorf("read template file %s: %w", expandedPath, err)
+// render validates vars against any sidecar spec and executes the template.
+func render(templatePath, inlineTemplate string, vars Variables) (string, error) {
+	templateName, templateContent, err := loadTemplate(templatePath, inlineTemplate)
+	if err != nil {
+		return "", err
+	}
+
+	if inlineTemplate == "" {
+		spec, err := LoadSpec(templatePath)
+		switch {
+		case err == nil:
+			if vars, err = spec.Apply(vars); err != nil {
+				return "", err
+			}
+		case !errors.Is(err, fs.ErrNotExist):
+			return "", err
 		}
-		templateContent = string(data)
-		templateName = filepath.Base(expandedPath)
 	}
 
 	// Create template with plain Go template syntax
-	tmpl, err := template.New(templateName).Parse(templateContent)
+	tmpl, err := newTemplate(templateName).Parse(templateContent)
 	if err != nil {
-		return nil, fmt.Errorf("parse template: %w", err)
+		return "", fmt.Errorf("parse template: %w", err)
 	}
 
 	// Render template
 	var buf bytes.Buffer
 	if err := tmpl.Execute(&buf, vars); err != nil {
-		return nil, fmt.Errorf("execute template: %w", err)
+		return "", fmt.Errorf("execute template: %w", err)
 	}
 
-	rendered := strings.TrimSpace(buf.String())
+	return strings.TrimSpace(buf.String()), nil
+}
 
-	// Parse as policy array
-	var policies []Policy
-	if err := json.Unmarshal([]byte(rendered), &policies); err != nil {
-		return nil, fmt.Errorf("parse rendered template as policies: %w\nRendered content:\n%s", err, rendered)
+// loadTemplate returns the name and source of the inline template, or of the
+// template file when no inline template is given.
+func loadTemplate(templatePath, inlineTemplate string) (string, string, error) {
+	if templatePath == "" && inlineTemplate == "" {
+		return "", "", fmt.Errorf("either template_file or template_inline must be specified")
+	}
+	if inlineTemplate != "" {
+		return "inline", inlineTemplate, nil
 	}
 
-	return policies, nil
+	expandedPath, err := expandPath(templatePath)
+	if err != nil {
+		return "", "", fmt.Errorf("expand template path: %w", err)
+	}
+	data, err := os.ReadFile(expandedPath)
+	if err != nil {
+		return "", "", fmt.Errorf("read template file %s: %w", expandedPath, err)
+	}
+	return filepath.Base(expandedPath), string(data), nil
 }
 
 // expandPath expands ~ and environment variables in a file path.
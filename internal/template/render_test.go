@@ -3,6 +3,7 @@ package template
 import (
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 )
 
@@ -218,3 +219,19 @@ func TestRenderPolicies_AutoInjectedZoneID(t *testing.T) {
 		t.Errorf("expected resource key %s not found in resources: %v", expectedKey, policy.Resources)
 	}
 }
+
+func TestReferencedVariables(t *testing.T) {
+	inline := `{{ if .IncludeEdit }}{{ .ZoneID }}{{ end }}` +
+		`{{ range .Zones }}{{ .Name }}{{ $.Account }}{{ end }}` +
+		`{{ with .Extra }}{{ .Ignored }}{{ else }}{{ .Fallback }}{{ end }}` +
+		`{{ printf "%s" .Printed.Nested }}`
+
+	got, err := ReferencedVariables("", inline)
+	if err != nil {
+		t.Fatalf("ReferencedVariables() error = %v", err)
+	}
+	want := []string{"Account", "Extra", "Fallback", "IncludeEdit", "Printed", "ZoneID", "Zones"}
+	if strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Fatalf("ReferencedVariables() = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,89 @@
+package template
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+)
+
+// specSuffix is appended to a template file path to locate its sidecar
+// variable declarations, e.g. policy.json.tmpl.vars.json.
+const specSuffix = ".vars.json"
+
+// VariableSpec documents one template variable.
+type VariableSpec struct {
+	Description string      `json:"description"`
+	Required    bool        `json:"required"`
+	Default     interface{} `json:"default"`
+}
+
+// Spec is the variable contract declared next to a template file.
+type Spec struct {
+	Variables map[string]VariableSpec `json:"variables"`
+}
+
+// SpecPath returns the sidecar path for a template file.
+func SpecPath(templatePath string) (string, error) {
+	expanded, err := expandPath(templatePath)
+	if err != nil {
+		return "", fmt.Errorf("expand template path: %w", err)
+	}
+	return expanded + specSuffix, nil
+}
+
+// LoadSpec reads the sidecar declarations for a template file. It returns an
+// error wrapping fs.ErrNotExist when the template has no sidecar.
+func LoadSpec(templatePath string) (*Spec, error) {
+	path, err := SpecPath(templatePath)
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var spec Spec
+	if err := json.Unmarshal(data, &spec); err != nil {
+		return nil, fmt.Errorf("parse %s: %w", path, err)
+	}
+	return &spec, nil
+}
+
+// Apply returns vars with declared defaults filled in, or an error naming
+// every required variable that has no value.
+func (s *Spec) Apply(vars Variables) (Variables, error) {
+	out := make(Variables, len(vars)+len(s.Variables))
+	for k, v := range vars {
+		out[k] = v
+	}
+
+	var missing []string
+	for name, v := range s.Variables {
+		if _, ok := out[name]; ok {
+			continue
+		}
+		switch {
+		case v.Default != nil:
+			out[name] = v.Default
+		case v.Required:
+			missing = append(missing, name)
+		}
+	}
+	if len(missing) > 0 {
+		sort.Strings(missing)
+		return nil, fmt.Errorf("missing required template variable(s): %s; set them in config.json or with -var", strings.Join(missing, ", "))
+	}
+	return out, nil
+}
+
+// Names returns the declared variable names in sorted order.
+func (s *Spec) Names() []string {
+	names := make([]string, 0, len(s.Variables))
+	for name := range s.Variables {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
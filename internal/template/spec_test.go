@@ -0,0 +1,63 @@
+package template
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRenderPolicies_SidecarSpec(t *testing.T) {
+	tmpDir := t.TempDir()
+	templatePath := filepath.Join(tmpDir, "policy.json.tmpl")
+
+	templateContent := `[{"effect": "allow",
+  "resources": {"com.cloudflare.api.account.zone.{{ .ZoneID }}": "{{ .Scope }}"},
+  "permission_groups": []}]`
+	spec := `{"variables": {
+  "ZoneID": {"description": "Target zone", "required": true},
+  "Scope": {"description": "Resource scope", "default": "*"}
+}}`
+	if err := os.WriteFile(templatePath, []byte(templateContent), 0o644); err != nil {
+		t.Fatalf("failed to write template file: %v", err)
+	}
+	if err := os.WriteFile(templatePath+".vars.json", []byte(spec), 0o644); err != nil {
+		t.Fatalf("failed to write spec file: %v", err)
+	}
+
+	policies, err := RenderPolicies(templatePath, "", Variables{"ZoneID": "z1"})
+	if err != nil {
+		t.Fatalf("RenderPolicies failed: %v", err)
+	}
+	if got := policies[0].Resources["com.cloudflare.api.account.zone.z1"]; got != "*" {
+		t.Errorf("expected default scope '*', got %v", got)
+	}
+
+	_, err = RenderPolicies(templatePath, "", Variables{})
+	if err == nil || !strings.Contains(err.Error(), "missing required template variable(s): ZoneID") {
+		t.Fatalf("expected missing required variable error, got %v", err)
+	}
+}
+
+func TestLoadSpecMissing(t *testing.T) {
+	templatePath := filepath.Join(t.TempDir(), "policy.json.tmpl")
+	if _, err := LoadSpec(templatePath); !errors.Is(err, fs.ErrNotExist) {
+		t.Fatalf("LoadSpec() error = %v, want fs.ErrNotExist", err)
+	}
+}
+
+func TestSpecApplyKeepsProvidedValues(t *testing.T) {
+	spec := &Spec{Variables: map[string]VariableSpec{
+		"A": {Required: true},
+		"B": {Default: false},
+	}}
+	got, err := spec.Apply(Variables{"A": 1, "B": true, "Extra": "x"})
+	if err != nil {
+		t.Fatalf("Apply() error = %v", err)
+	}
+	if got["A"] != 1 || got["B"] != true || got["Extra"] != "x" {
+		t.Fatalf("Apply() = %v", got)
+	}
+}
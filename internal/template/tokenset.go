@@ -0,0 +1,57 @@
+package template
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// TokenSpec describes one token in a multi-token template.
+type TokenSpec struct {
+	// Name is used as the token name prefix; a timestamp is appended.
+	Name         string   `json:"name"`
+	TTL          string   `json:"ttl"`
+	AllowedCIDRs []string `json:"allowed_cidrs"`
+	// Conditions holds further token conditions keyed by type and then
+	// operator, e.g. {"request_ip": {"not_in": ["192.0.2.0/24"]}}.
+	Conditions map[string]map[string][]string `json:"conditions,omitempty"`
+	Policies   []Policy                       `json:"policies"`
+}
+
+// TokenSet is the document a multi-token template renders to.
+type TokenSet struct {
+	Tokens []TokenSpec `json:"tokens"`
+}
+
+// RenderTokenSet renders a template that describes several tokens at once.
+// Every token needs a unique name and at least one policy.
+func RenderTokenSet(templatePath, inlineTemplate string, vars Variables) ([]TokenSpec, error) {
+	rendered, err := render(templatePath, inlineTemplate, vars)
+	if err != nil {
+		return nil, err
+	}
+
+	var set TokenSet
+	if err := json.Unmarshal([]byte(rendered), &set); err != nil {
+		return nil, fmt.Errorf("parse rendered template as token set: %w\nRendered content:\n%s", err, rendered)
+	}
+	if len(set.Tokens) == 0 {
+		return nil, fmt.Errorf("token set template defines no tokens")
+	}
+
+	seen := make(map[string]bool, len(set.Tokens))
+	for i, tok := range set.Tokens {
+		name := strings.TrimSpace(tok.Name)
+		switch {
+		case name == "":
+			return nil, fmt.Errorf("token %d: name is required", i+1)
+		case seen[name]:
+			return nil, fmt.Errorf("token %q: defined more than once", name)
+		case len(tok.Policies) == 0:
+			return nil, fmt.Errorf("token %q: at least one policy is required", name)
+		}
+		seen[name] = true
+		set.Tokens[i].Name = name
+	}
+	return set.Tokens, nil
+}
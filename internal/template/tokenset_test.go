@@ -0,0 +1,87 @@
+package template
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRenderTokenSet(t *testing.T) {
+	inline := `{"tokens": [
+  {"name": "{{ .Env }}-deploy", "ttl": "4h", "policies": [
+    {"effect": "allow", "resources": {"com.cloudflare.api.account.zone.{{ .ZoneID }}": "*"}, "permission_groups": [{"id": "dns-edit"}]}
+  ]},
+  {"name": "{{ .Env }}-purge", "allowed_cidrs": ["10.0.0.1/32"], "policies": [
+    {"effect": "allow", "resources": {"com.cloudflare.api.account.zone.{{ .ZoneID }}": "*"}, "permission_groups": [{"id": "cache-purge"}]}
+  ]}
+]}`
+
+	tokens, err := RenderTokenSet("", inline, Variables{"Env": "prod", "ZoneID": "z1"})
+	if err != nil {
+		t.Fatalf("RenderTokenSet failed: %v", err)
+	}
+	if len(tokens) != 2 {
+		t.Fatalf("expected 2 tokens, got %d", len(tokens))
+	}
+	if tokens[0].Name != "prod-deploy" || tokens[0].TTL != "4h" {
+		t.Errorf("unexpected first token: %+v", tokens[0])
+	}
+	if tokens[1].AllowedCIDRs[0] != "10.0.0.1/32" || tokens[1].Policies[0].PermissionGroups[0].ID != "cache-purge" {
+		t.Errorf("unexpected second token: %+v", tokens[1])
+	}
+}
+
+func TestRenderTokenSet_Invalid(t *testing.T) {
+	policy := `{"effect": "allow", "resources": {}, "permission_groups": []}`
+	tests := []struct {
+		name   string
+		inline string
+		want   string
+	}{
+		{"empty", `{"tokens": []}`, "defines no tokens"},
+		{"unnamed", `{"tokens": [{"policies": [` + policy + `]}]}`, "token 1: name is required"},
+		{"duplicate", `{"tokens": [{"name": "a", "policies": [` + policy + `]}, {"name": "a", "policies": [` + policy + `]}]}`, `token "a": defined more than once`},
+		{"no policies", `{"tokens": [{"name": "a"}]}`, `token "a": at least one policy is required`},
+		{"not json", `[`, "parse rendered template as token set"},
+	}
+
+	for _, tc := range tests {
+		if _, err := RenderTokenSet("", tc.inline, nil); err == nil || !strings.Contains(err.Error(), tc.want) {
+			t.Errorf("%s: RenderTokenSet() error = %v, want %q", tc.name, err, tc.want)
+		}
+	}
+}
+
+// FuzzRenderTokenSet renders token sets such as generators pipe to
+// apply-template -template -. A set that is accepted has uniquely named
+// tokens, each with at least one policy.
+func FuzzRenderTokenSet(f *testing.F) {
+	for _, seed := range []string{
+		`{"tokens": [{"name": "{{ .Env }}-deploy", "ttl": "4h", "policies": [{"effect": "allow", "resources": {"com.cloudflare.api.account.zone.z1": "*"}, "permission_groups": [{"id": "g1"}]}]}]}`,
+		`{"tokens": [{"name": " a ", "policies": [{}]}, {"name": "a", "policies": [{}]}]}`,
+		`{"tokens": [{"name": "x", "conditions": {"request_ip": {"not_in": ["10.0.0.0/8"]}}, "policies": [{}]}]}`,
+		`{"tokens": []}`,
+		`[{"effect": "allow"}]`,
+		`{{ .Missing.Field }}`,
+	} {
+		f.Add(seed)
+	}
+	f.Fuzz(func(t *testing.T, inline string) {
+		if strings.TrimSpace(inline) == "" {
+			return
+		}
+		specs, err := RenderTokenSet("", inline, Variables{"Env": "prod"})
+		if err != nil {
+			return
+		}
+		seen := make(map[string]bool)
+		for _, spec := range specs {
+			if spec.Name == "" || spec.Name != strings.TrimSpace(spec.Name) || seen[spec.Name] {
+				t.Fatalf("RenderTokenSet(%q) accepted token name %q", inline, spec.Name)
+			}
+			if len(spec.Policies) == 0 {
+				t.Fatalf("RenderTokenSet(%q) accepted token %q without policies", inline, spec.Name)
+			}
+			seen[spec.Name] = true
+		}
+	})
+}
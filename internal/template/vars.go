@@ -0,0 +1,85 @@
+package template
+
+import (
+	"fmt"
+	"sort"
+	"text/template/parse"
+)
+
+// ReferencedVariables returns the top-level variables a template reads, such
+// as ZoneID for {{ .ZoneID }} or {{ $.ZoneID }}. Fields accessed inside range
+// and with blocks are relative to a different dot and are not reported.
+func ReferencedVariables(templatePath, inlineTemplate string) ([]string, error) {
+	name, content, err := loadTemplate(templatePath, inlineTemplate)
+	if err != nil {
+		return nil, err
+	}
+	tmpl, err := newTemplate(name).Parse(content)
+	if err != nil {
+		return nil, fmt.Errorf("parse template: %w", err)
+	}
+
+	seen := make(map[string]struct{})
+	for _, t := range tmpl.Templates() {
+		if t.Tree != nil {
+			collectFields(t.Tree.Root, true, seen)
+		}
+	}
+	out := make([]string, 0, len(seen))
+	for v := range seen {
+		out = append(out, v)
+	}
+	sort.Strings(out)
+	return out, nil
+}
+
+// collectFields records root fields referenced under node. rootDot reports
+// whether dot still refers to the template data at this point.
+func collectFields(node parse.Node, rootDot bool, seen map[string]struct{}) {
+	switch n := node.(type) {
+	case *parse.ListNode:
+		if n == nil {
+			return
+		}
+		for _, child := range n.Nodes {
+			collectFields(child, rootDot, seen)
+		}
+	case *parse.ActionNode:
+		collectFields(n.Pipe, rootDot, seen)
+	case *parse.PipeNode:
+		if n == nil {
+			return
+		}
+		for _, cmd := range n.Cmds {
+			collectFields(cmd, rootDot, seen)
+		}
+	case *parse.CommandNode:
+		for _, arg := range n.Args {
+			collectFields(arg, rootDot, seen)
+		}
+	case *parse.FieldNode:
+		if rootDot && len(n.Ident) > 0 {
+			seen[n.Ident[0]] = struct{}{}
+		}
+	case *parse.VariableNode:
+		if len(n.Ident) > 1 && n.Ident[0] == "$" {
+			seen[n.Ident[1]] = struct{}{}
+		}
+	case *parse.ChainNode:
+		collectFields(n.Node, rootDot, seen)
+	case *parse.IfNode:
+		collectFields(n.Pipe, rootDot, seen)
+		collectFields(n.List, rootDot, seen)
+		collectFields(n.ElseList, rootDot, seen)
+	case *parse.RangeNode:
+		collectFields(n.Pipe, rootDot, seen)
+		collectFields(n.List, false, seen)
+		collectFields(n.ElseList, rootDot, seen)
+	case *parse.WithNode:
+		collectFields(n.Pipe, rootDot, seen)
+		collectFields(n.List, false, seen)
+		collectFields(n.ElseList, rootDot, seen)
+	case *parse.TemplateNode:
+		collectFields(n.Pipe, rootDot, seen)
+	}
+}
@@ -0,0 +1,170 @@
+// Package yaml writes values as block-style YAML. It covers what cftoken
+// prints: anything that encodes to JSON, with the same field names, field
+// order, and omitempty rules. It does not read YAML.
+package yaml
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+)
+
+// node is a decoded JSON value with object keys in their encoded order.
+type node struct {
+	scalar string // JSON text of a string, number, bool, or null
+	keys   []string
+	values []*node
+	items  []*node
+	kind   byte // '{', '[', or 0 for scalars
+}
+
+// Marshal encodes v to JSON and rewrites the result as a YAML document.
+func Marshal(v any) ([]byte, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	root, err := decode(dec)
+	if err != nil {
+		return nil, fmt.Errorf("yaml: %w", err)
+	}
+	var b strings.Builder
+	switch {
+	case root.kind == 0:
+		b.WriteString(scalar(root.scalar) + "\n")
+	case root.empty():
+		b.WriteString(root.emptyForm() + "\n")
+	default:
+		write(&b, root, 0)
+	}
+	return []byte(b.String()), nil
+}
+
+func decode(dec *json.Decoder) (*node, error) {
+	tok, err := dec.Token()
+	if err != nil {
+		return nil, err
+	}
+	switch t := tok.(type) {
+	case json.Delim:
+		n := &node{kind: byte(t)}
+		for dec.More() {
+			if n.kind == '{' {
+				key, err := dec.Token()
+				if err != nil {
+					return nil, err
+				}
+				n.keys = append(n.keys, key.(string))
+			}
+			child, err := decode(dec)
+			if err != nil {
+				return nil, err
+			}
+			if n.kind == '{' {
+				n.values = append(n.values, child)
+			} else {
+				n.items = append(n.items, child)
+			}
+		}
+		if _, err := dec.Token(); err != nil && err != io.EOF {
+			return nil, err
+		}
+		return n, nil
+	case string:
+		return &node{scalar: quote(t)}, nil
+	case nil:
+		return &node{scalar: "null"}, nil
+	default:
+		return &node{scalar: fmt.Sprint(t)}, nil
+	}
+}
+
+func (n *node) empty() bool {
+	return n.kind != 0 && len(n.keys) == 0 && len(n.items) == 0
+}
+
+func (n *node) emptyForm() string {
+	if n.kind == '{' {
+		return "{}"
+	}
+	return "[]"
+}
+
+// write writes the mapping or sequence n, each line indented by indent
+// spaces.
+func write(b *strings.Builder, n *node, indent int) {
+	pad := strings.Repeat(" ", indent)
+	if n.kind == '{' {
+		for i, key := range n.keys {
+			b.WriteString(pad + scalar(quote(key)) + ":")
+			writeValue(b, n.values[i], indent+2)
+		}
+		return
+	}
+	for _, item := range n.items {
+		b.WriteString(pad + "-")
+		if item.kind == 0 || item.empty() {
+			writeValue(b, item, indent+2)
+			continue
+		}
+		// Nested collections start on the dash's line.
+		var nested strings.Builder
+		write(&nested, item, indent+2)
+		b.WriteString(" " + strings.TrimPrefix(nested.String(), pad+"  "))
+	}
+}
+
+// writeValue writes the value of a mapping entry or sequence item after
+// its key or dash.
+func writeValue(b *strings.Builder, n *node, indent int) {
+	switch {
+	case n.kind == 0:
+		b.WriteString(" " + scalar(n.scalar) + "\n")
+	case n.empty():
+		b.WriteString(" " + n.emptyForm() + "\n")
+	default:
+		b.WriteString("\n")
+		write(b, n, indent)
+	}
+}
+
+// quote returns s as a JSON string, leaving <, >, and & unescaped.
+func quote(s string) string {
+	var b bytes.Buffer
+	enc := json.NewEncoder(&b)
+	enc.SetEscapeHTML(false)
+	_ = enc.Encode(s)
+	return strings.TrimSuffix(b.String(), "\n")
+}
+
+// plain matches strings that read back as the same string without quotes:
+// they start with a letter, digit, slash, or dot, hold no characters YAML
+// treats specially, and do not end in a space.
+var plain = regexp.MustCompile(`^[A-Za-z0-9_./][A-Za-z0-9_./ ()+=@-]*$`)
+
+// ambiguous matches plain strings YAML would read as something other than
+// a string: booleans, nulls, and numbers.
+var ambiguous = regexp.MustCompile(`(?i)^(y|yes|n|no|true|false|on|off|null|~|[-+]?(\.inf|\.nan|[0-9][0-9_]*(\.[0-9_]*)?(e[-+]?[0-9]+)?|0x[0-9a-f_]+|0o[0-7_]+|[0-9][0-9:]*|\.[0-9_]+(e[-+]?[0-9]+)?))$`)
+
+// scalar writes the JSON text of a scalar as YAML. Numbers, booleans, and
+// null stay as they are. Strings are written plain when that reads back
+// as the same string and double-quoted otherwise; JSON string escapes are
+// valid in YAML double quotes.
+func scalar(text string) string {
+	if !strings.HasPrefix(text, `"`) {
+		return text
+	}
+	var s string
+	if err := json.Unmarshal([]byte(text), &s); err != nil {
+		return text
+	}
+	if s == "" || !plain.MatchString(s) || strings.HasSuffix(s, " ") || ambiguous.MatchString(s) {
+		return text
+	}
+	return s
+}
@@ -0,0 +1,70 @@
+package yaml
+
+import "testing"
+
+func TestMarshal(t *testing.T) {
+	type group struct {
+		ID   string `json:"id"`
+		Name string `json:"name,omitempty"`
+	}
+	type doc struct {
+		Name      string            `json:"name"`
+		Count     int               `json:"count"`
+		Enabled   bool              `json:"enabled"`
+		Note      string            `json:"note,omitempty"`
+		Tags      []string          `json:"tags"`
+		Empty     []string          `json:"empty"`
+		Groups    []group           `json:"groups"`
+		Resources map[string]string `json:"resources"`
+		Nested    [][]int           `json:"nested"`
+	}
+	in := doc{
+		Name:      "ci-deploy",
+		Count:     2,
+		Enabled:   true,
+		Tags:      []string{"yes", "", "10.0.0.0/8", "a: b", "#x", "2024-01-01T00:00:00Z", "0123", "<delivered to vault>", "line\nbreak", "trailing "},
+		Empty:     []string{},
+		Groups:    []group{{ID: "g1", Name: "DNS Write"}, {ID: "g2"}},
+		Resources: map[string]string{"com.cloudflare.api.account.zone.z1": "*"},
+		Nested:    [][]int{{1, 2}, {}},
+	}
+	want := `name: ci-deploy
+count: 2
+enabled: true
+tags:
+  - "yes"
+  - ""
+  - 10.0.0.0/8
+  - "a: b"
+  - "#x"
+  - "2024-01-01T00:00:00Z"
+  - "0123"
+  - "<delivered to vault>"
+  - "line\nbreak"
+  - "trailing "
+empty: []
+groups:
+  - id: g1
+    name: DNS Write
+  - id: g2
+resources:
+  com.cloudflare.api.account.zone.z1: "*"
+nested:
+  - - 1
+    - 2
+  - []
+`
+	got, err := Marshal(in)
+	if err != nil {
+		t.Fatalf("Marshal() error = %v", err)
+	}
+	if string(got) != want {
+		t.Errorf("Marshal() =\n%s\nwant\n%s", got, want)
+	}
+
+	for in, want := range map[any]string{"null": "\"null\"\n", "text": "text\n", 3: "3\n", nil: "null\n"} {
+		if got, err := Marshal(in); err != nil || string(got) != want {
+			t.Errorf("Marshal(%v) = %q, %v; want %q", in, got, err, want)
+		}
+	}
+}